@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+func boardFromHistory(size int, moves []client.HistoryEntry) [][]int {
+	board := make([][]int, size)
+	for y := range board {
+		board[y] = make([]int, size)
+	}
+	for _, move := range moves {
+		for _, change := range move.Changes {
+			if change.X >= 0 && change.Y >= 0 && change.X < size && change.Y < size {
+				board[change.Y][change.X] = change.Value
+			}
+		}
+	}
+	return board
+}
+
+func renderBoard(w io.Writer, board [][]int, highlight *client.Move) {
+	size := len(board)
+	var header strings.Builder
+	header.WriteString("    ")
+	for x := 0; x < size; x++ {
+		fmt.Fprintf(&header, "%2d", x)
+	}
+	fmt.Fprintln(w, header.String())
+	for y := 0; y < size; y++ {
+		var row strings.Builder
+		fmt.Fprintf(&row, "%3d ", y)
+		for x := 0; x < size; x++ {
+			symbol := "."
+			switch board[y][x] {
+			case 1:
+				symbol = "X"
+			case 2:
+				symbol = "O"
+			}
+			if highlight != nil && highlight.X == x && highlight.Y == y && board[y][x] == 0 {
+				symbol = "*"
+			}
+			row.WriteString(" " + symbol)
+		}
+		fmt.Fprintln(w, row.String())
+	}
+}
+
+func playerName(player int) string {
+	if player == 2 {
+		return "white (O)"
+	}
+	return "black (X)"
+}
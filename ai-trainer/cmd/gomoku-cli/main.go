@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+const usage = `usage: gomoku-cli [-backend URL] <command> [flags]
+
+commands:
+  play     play against the backend AI with an ASCII board
+  analyse  ask the engine for the best move in a position (SGF or move list)
+  load     load an SGF game into the backend
+  save     save the current backend game as SGF
+  match    run quick AI-vs-AI games and report the results
+  duel     compare two heuristic files on the backend and report the Elo gap
+  render   save the current board as an SVG or PNG image
+  annotate attach a comment to a ply of the current (or an archived) game
+  review   grade every move of an SGF file or an archived game
+`
+
+type cli struct {
+	api          *client.Client
+	pollInterval time.Duration
+}
+
+func main() {
+	backend := flag.String("backend", getenv("BACKEND_URL", "http://localhost:8080"), "backend base URL")
+	timeout := flag.Duration("timeout", 2*time.Minute, "HTTP request timeout")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	c := &cli{
+		api:          client.New(*backend, client.WithTimeout(*timeout)),
+		pollInterval: 250 * time.Millisecond,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	args := flag.Args()[1:]
+	var err error
+	switch flag.Arg(0) {
+	case "play":
+		err = c.play(ctx, args)
+	case "analyse", "analyze":
+		err = c.analyse(ctx, args)
+	case "load":
+		err = c.load(ctx, args)
+	case "save":
+		err = c.save(ctx, args)
+	case "match":
+		err = c.match(ctx, args)
+	case "duel":
+		err = c.duel(ctx, args)
+	case "render":
+		err = c.render(ctx, args)
+	case "annotate":
+		err = c.annotate(ctx, args)
+	case "review":
+		err = c.review(ctx, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "gomoku-cli: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (c *cli) play(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	human := fs.Int("player", 1, "human player (1 = black, 2 = white)")
+	sgfPath := fs.String("sgf", "", "optional SGF file to continue from")
+	_ = fs.Parse(args)
+	if *human != 1 && *human != 2 {
+		return fmt.Errorf("player must be 1 or 2")
+	}
+	if *sgfPath != "" {
+		if err := c.loadFile(ctx, *sgfPath); err != nil {
+			return err
+		}
+		if _, err := c.api.UpdateSettings(ctx, client.SettingsUpdate{
+			Settings: &client.GameSettings{Mode: "ai_vs_human", HumanPlayer: *human},
+		}); err != nil {
+			return err
+		}
+	} else if _, err := c.api.Start(ctx, client.GameSettings{Mode: "ai_vs_human", HumanPlayer: *human}); err != nil {
+		return err
+	}
+
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		status, err := c.waitForTurn(ctx, *human)
+		if err != nil {
+			return err
+		}
+		if err := printStatus(status); err != nil {
+			return err
+		}
+		if !status.Running() {
+			return nil
+		}
+		fmt.Printf("%s to move (x y, 'hint', 'quit'): ", playerName(status.NextPlayer))
+		if !input.Scan() {
+			return input.Err()
+		}
+		line := strings.TrimSpace(input.Text())
+		switch line {
+		case "":
+			continue
+		case "quit", "exit":
+			return nil
+		case "hint":
+			if err := c.hint(ctx, status); err != nil {
+				fmt.Printf("hint failed: %v\n", err)
+			}
+			continue
+		}
+		move, err := parseMoveInput(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if _, err := c.api.Move(ctx, move.X, move.Y); err != nil {
+			var statusErr *client.StatusError
+			if errors.As(err, &statusErr) {
+				reason := statusErr.Message
+				if reason == "" {
+					reason = strings.TrimSpace(statusErr.Body)
+				}
+				fmt.Printf("move rejected: %s\n", reason)
+				continue
+			}
+			return err
+		}
+	}
+}
+
+func (c *cli) waitForTurn(ctx context.Context, human int) (client.Status, error) {
+	for {
+		status, err := c.api.Status(ctx)
+		if err != nil {
+			return client.Status{}, err
+		}
+		if !status.Running() || status.NextPlayer == human {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return client.Status{}, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+func (c *cli) hint(ctx context.Context, status client.Status) error {
+	moves, err := status.Moves()
+	if err != nil {
+		return err
+	}
+	analysis, err := c.api.Analyse(ctx, client.AnalyseRequest{Moves: historyMoves(moves)})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("engine suggests %d %d (score %.0f, depth %d)\n", analysis.BestMove.X, analysis.BestMove.Y, analysis.Score, analysis.Depth)
+	return nil
+}
+
+func (c *cli) analyse(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("analyse", flag.ExitOnError)
+	sgfPath := fs.String("sgf", "", "SGF file holding the position")
+	movesRaw := fs.String("moves", "", "move list as x,y;x,y;...")
+	current := fs.Bool("current", false, "analyse the backend's current game")
+	depth := fs.Int("depth", 0, "search depth (0 = backend config)")
+	timeoutMs := fs.Int("timeout-ms", 0, "search time budget in ms (0 = backend config)")
+	_ = fs.Parse(args)
+
+	var moves []client.Move
+	switch {
+	case *sgfPath != "":
+		_, parsed, err := readSGFFile(*sgfPath)
+		if err != nil {
+			return err
+		}
+		moves = parsed
+	case *movesRaw != "":
+		parsed, err := parseMoveList(*movesRaw)
+		if err != nil {
+			return err
+		}
+		moves = parsed
+	case *current:
+		status, err := c.api.Status(ctx)
+		if err != nil {
+			return err
+		}
+		history, err := status.Moves()
+		if err != nil {
+			return err
+		}
+		moves = historyMoves(history)
+	}
+	analysis, err := c.api.Analyse(ctx, client.AnalyseRequest{Moves: moves, Depth: *depth, TimeoutMs: *timeoutMs})
+	if err != nil {
+		return err
+	}
+	best := analysis.BestMove
+	var highlight *client.Move
+	if analysis.Status == "running" {
+		highlight = &best
+	}
+	renderBoard(os.Stdout, analysis.Board, highlight)
+	if analysis.Status != "running" {
+		fmt.Printf("position is final: %s\n", analysis.Status)
+		return nil
+	}
+	fmt.Printf("%s to move\n", playerName(analysis.NextPlayer))
+	fmt.Printf("best move: %d %d (sgf %s)\n", best.X, best.Y, sgfCoord(best))
+	fmt.Printf("score: %.0f depth: %d nodes: %d time: %.0fms\n", analysis.Score, analysis.Depth, analysis.Nodes, analysis.ElapsedMs)
+	return nil
+}
+
+func (c *cli) load(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gomoku-cli load <file.sgf>")
+	}
+	if err := c.loadFile(ctx, args[0]); err != nil {
+		return err
+	}
+	status, err := c.api.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return printStatus(status)
+}
+
+func (c *cli) loadFile(ctx context.Context, path string) error {
+	boardSize, moves, err := readSGFFile(path)
+	if err != nil {
+		return err
+	}
+	status, err := c.api.Start(ctx, client.GameSettings{Mode: "human_vs_human", HumanPlayer: 1})
+	if err != nil {
+		return err
+	}
+	if status.BoardSize != boardSize {
+		return fmt.Errorf("sgf board size %d does not match backend board size %d", boardSize, status.BoardSize)
+	}
+	for i, move := range moves {
+		if _, err := c.api.Move(ctx, move.X, move.Y); err != nil {
+			return fmt.Errorf("move %d (%s): %w", i+1, sgfCoord(move), err)
+		}
+	}
+	return nil
+}
+
+func (c *cli) save(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gomoku-cli save <file.sgf>")
+	}
+	status, err := c.api.Status(ctx)
+	if err != nil {
+		return err
+	}
+	moves, err := status.Moves()
+	if err != nil {
+		return err
+	}
+	annotations, err := c.api.Annotations(ctx, status.GameID)
+	var statusErr *client.StatusError
+	if err != nil && !(errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound) {
+		return err
+	}
+	if err := os.WriteFile(args[0], []byte(encodeSGF(status.BoardSize, moves, annotations)), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("saved %d moves and %d annotations to %s\n", len(moves), len(annotations), args[0])
+	return nil
+}
+
+func (c *cli) render(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	ply := fs.Int("ply", -1, "render the position after this many moves (default: latest)")
+	cell := fs.Int("cell", 0, "cell size in pixels (default 32)")
+	noNumbers := fs.Bool("no-numbers", false, "leave move numbers off the stones")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: gomoku-cli render [-ply N] [-cell PX] [-no-numbers] <file.svg|file.png>")
+	}
+	path := fs.Arg(0)
+	opts := client.RenderOptions{Format: "svg", CellSize: *cell, HideNumbers: *noNumbers}
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		opts.Format = "png"
+	}
+	if *ply >= 0 {
+		opts.Ply = ply
+	}
+	image, err := c.api.Render(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, image, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("saved %s (%d bytes)\n", path, len(image))
+	return nil
+}
+
+func (c *cli) annotate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	gameID := fs.Uint64("game", 0, "game id (default: current game)")
+	ply := fs.Int("ply", -1, "ply to annotate, 0 for the start position (default: latest)")
+	author := fs.String("author", os.Getenv("USER"), "author shown with the comment")
+	_ = fs.Parse(args)
+	comment := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("usage: gomoku-cli annotate [-game ID] [-ply N] [-author NAME] <comment>")
+	}
+	if *gameID == 0 {
+		status, err := c.api.Status(ctx)
+		if err != nil {
+			return err
+		}
+		*gameID = status.GameID
+		if *ply < 0 {
+			*ply = len(status.History)
+		}
+	} else if *ply < 0 {
+		return fmt.Errorf("-ply is required with -game")
+	}
+	annotations, err := c.api.Annotate(ctx, *gameID, client.Annotation{Ply: *ply, Author: *author, Comment: comment})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("game %d now has %d annotations\n", *gameID, len(annotations))
+	return nil
+}
+
+func (c *cli) review(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	gameID := fs.Uint64("game", 0, "review a finished game kept by the backend instead of a file")
+	depth := fs.Int("depth", 0, "search depth per position (default: live depth)")
+	timeoutMs := fs.Int("timeout-ms", 0, "search time per position in ms (default 500)")
+	_ = fs.Parse(args)
+	var review client.Review
+	var err error
+	switch {
+	case *gameID != 0 && fs.NArg() == 0:
+		review, err = c.api.ReviewGame(ctx, *gameID, *depth, *timeoutMs)
+	case *gameID == 0 && fs.NArg() == 1:
+		var data []byte
+		data, err = os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		review, err = c.api.ImportReview(ctx, client.ReviewRequest{SGF: string(data), Depth: *depth, TimeoutMs: *timeoutMs, Source: filepath.Base(fs.Arg(0))})
+	default:
+		return fmt.Errorf("usage: gomoku-cli review [-depth N] [-timeout-ms MS] <file.sgf> | -game ID")
+	}
+	if err != nil {
+		return err
+	}
+	graded := 0
+	for !review.Finished() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+		if review, err = c.api.Review(ctx, review.ID); err != nil {
+			return err
+		}
+		for ; graded < len(review.Moves); graded++ {
+			move := review.Moves[graded]
+			line := fmt.Sprintf("%3d. %s %2d %2d  %-10s loss %.2f", move.Ply, playerName(move.Player), move.Move.X, move.Move.Y, move.Class, move.Loss)
+			if move.BestMove.X != move.Move.X || move.BestMove.Y != move.Move.Y {
+				line += fmt.Sprintf("  best %d %d", move.BestMove.X, move.BestMove.Y)
+			}
+			fmt.Println(line)
+		}
+	}
+	if review.Status == "failed" {
+		return fmt.Errorf("review %d failed: %s", review.ID, review.Error)
+	}
+	for _, side := range []struct {
+		name    string
+		summary client.ReviewSideSummary
+	}{{"black", review.Black}, {"white", review.White}} {
+		fmt.Printf("%s: %d moves, %d best, %d good, %d inaccuracies, %d mistakes, %d blunders, average loss %.3f\n",
+			side.name, side.summary.Moves, side.summary.Best, side.summary.Good, side.summary.Inaccuracies, side.summary.Mistakes, side.summary.Blunders, side.summary.AverageLoss)
+	}
+	return nil
+}
+
+func (c *cli) match(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("match", flag.ExitOnError)
+	games := fs.Int("games", 4, "number of games to play")
+	gameTimeout := fs.Duration("game-timeout", 5*time.Minute, "per-game timeout")
+	_ = fs.Parse(args)
+
+	results := map[string]int{}
+	for i := 0; i < *games; i++ {
+		if _, err := c.api.Start(ctx, client.GameSettings{Mode: "ai_vs_ai", HumanPlayer: 1}); err != nil {
+			return err
+		}
+		started := time.Now()
+		var status client.Status
+		for {
+			var err error
+			status, err = c.api.Status(ctx)
+			if err != nil {
+				return err
+			}
+			if !status.Running() {
+				break
+			}
+			if time.Since(started) > *gameTimeout {
+				_, _ = c.api.Stop(ctx)
+				status.Status = "timeout"
+				break
+			}
+			select {
+			case <-ctx.Done():
+				_, _ = c.api.Stop(context.Background())
+				return ctx.Err()
+			case <-time.After(c.pollInterval):
+			}
+		}
+		results[status.Status]++
+		fmt.Printf("game %d/%d: %s in %d moves (%s)\n", i+1, *games, status.Status, len(status.History), time.Since(started).Round(time.Second))
+	}
+	fmt.Printf("black wins: %d, white wins: %d, draws: %d, timeouts: %d\n",
+		results["black_won"], results["white_won"], results["draw"], results["timeout"])
+	return nil
+}
+
+func (c *cli) duel(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("duel", flag.ExitOnError)
+	aPath := fs.String("a", "", "heuristics JSON file for side A (default: engine defaults)")
+	bPath := fs.String("b", "", "heuristics JSON file for side B (default: engine defaults)")
+	openings := fs.Int("openings", 4, "number of generated openings (each played with both colours)")
+	plies := fs.Int("plies", 4, "stones per generated opening")
+	seed := fs.Int64("seed", 1, "opening suite seed")
+	budget := fs.Int("budget", 0, "per-move time budget in ms (default: backend setting)")
+	depth := fs.Int("depth", 0, "search depth override")
+	workers := fs.Int("workers", 1, "parallel games on the backend")
+	_ = fs.Parse(args)
+
+	req := client.DuelRequest{
+		OpeningCount: *openings,
+		OpeningPlies: *plies,
+		Seed:         *seed,
+		MoveBudgetMs: *budget,
+		Depth:        *depth,
+		Workers:      *workers,
+	}
+	var err error
+	if req.A, err = readHeuristicsFile(*aPath); err != nil {
+		return err
+	}
+	if req.B, err = readHeuristicsFile(*bPath); err != nil {
+		return err
+	}
+	result, err := c.api.Duel(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, game := range result.Records {
+		color := playerName(game.AColor)
+		if game.Error != "" {
+			fmt.Printf("game %d (opening %d, A %s): error: %s\n", game.Index+1, game.OpeningIndex+1, color, game.Error)
+			continue
+		}
+		fmt.Printf("game %d (opening %d, A %s): %s in %d moves, A scores %.1f\n", game.Index+1, game.OpeningIndex+1, color, game.Status, game.Plies, game.Score)
+	}
+	fmt.Printf("A vs B: +%d =%d -%d (%.1f/%d), elo %+.0f [%+.0f, %+.0f]\n",
+		result.Wins, result.Draws+result.Unfinished, result.Losses, result.Score, result.Games-result.Errors, result.Elo, result.EloLow, result.EloHigh)
+	return nil
+}
+
+func readHeuristicsFile(path string) (*client.HeuristicConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var heuristics client.HeuristicConfig
+	if err := json.Unmarshal(raw, &heuristics); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &heuristics, nil
+}
+
+func printStatus(status client.Status) error {
+	moves, err := status.Moves()
+	if err != nil {
+		return err
+	}
+	renderBoard(os.Stdout, boardFromHistory(status.BoardSize, moves), nil)
+	if len(moves) > 0 {
+		last := moves[len(moves)-1]
+		fmt.Printf("last move: %s at %d %d\n", playerName(last.Player), last.X, last.Y)
+	}
+	switch status.Status {
+	case "black_won", "white_won":
+		fmt.Printf("%s wins by %s\n", playerName(status.Winner), status.WinReason)
+	case "draw":
+		fmt.Println("draw")
+	}
+	if wc := status.WinConditions; wc != nil {
+		fmt.Printf("captures: black %d/%d, white %d/%d\n", wc.CapturedBlack, wc.CaptureWinStones, wc.CapturedWhite, wc.CaptureWinStones)
+		if wc.Summary != "" && !status.MustCapture {
+			fmt.Println(wc.Summary)
+		}
+	}
+	if pending := status.PendingWin; pending != nil {
+		fmt.Println(pending.Explanation)
+		for _, option := range pending.Breaks {
+			taken := make([]string, 0, len(option.Captures))
+			for _, move := range option.Captures {
+				taken = append(taken, fmt.Sprintf("%d %d", move.X, move.Y))
+			}
+			fmt.Printf("  %d %d takes %s\n", option.Move.X, option.Move.Y, strings.Join(taken, ", "))
+		}
+	} else if status.MustCapture {
+		cells := make([]string, 0, len(status.ForcedCaptureMoves))
+		for _, move := range status.ForcedCaptureMoves {
+			cells = append(cells, fmt.Sprintf("%d %d", move.X, move.Y))
+		}
+		fmt.Printf("%s must capture to break %s's alignment: %s\n", playerName(status.NextPlayer), playerName(status.PendingAlignmentPlayer), strings.Join(cells, ", "))
+	}
+	return nil
+}
+
+func historyMoves(history []client.HistoryEntry) []client.Move {
+	moves := make([]client.Move, 0, len(history))
+	for _, entry := range history {
+		moves = append(moves, client.Move{X: entry.X, Y: entry.Y})
+	}
+	return moves
+}
+
+func parseMoveInput(line string) (client.Move, error) {
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 1 && len(fields[0]) == 2 {
+		return parseSGFCoord(fields[0])
+	}
+	if len(fields) != 2 {
+		return client.Move{}, fmt.Errorf("expected 'x y' or an sgf coordinate, got %q", line)
+	}
+	x, errX := strconv.Atoi(fields[0])
+	y, errY := strconv.Atoi(fields[1])
+	if errX != nil || errY != nil {
+		return client.Move{}, fmt.Errorf("invalid coordinates %q", line)
+	}
+	return client.Move{X: x, Y: y}, nil
+}
+
+func parseMoveList(raw string) ([]client.Move, error) {
+	moves := []client.Move{}
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		move, err := parseMoveInput(part)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+func getenv(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
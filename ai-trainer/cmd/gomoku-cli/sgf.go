@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+func sgfCoord(move client.Move) string {
+	return string([]byte{byte('a' + move.X), byte('a' + move.Y)})
+}
+
+func parseSGFCoord(raw string) (client.Move, error) {
+	if len(raw) != 2 || raw[0] < 'a' || raw[0] > 'z' || raw[1] < 'a' || raw[1] > 'z' {
+		return client.Move{}, fmt.Errorf("invalid sgf coordinate %q", raw)
+	}
+	return client.Move{X: int(raw[0] - 'a'), Y: int(raw[1] - 'a')}, nil
+}
+
+func encodeSGF(boardSize int, moves []client.HistoryEntry, annotations []client.Annotation) string {
+	byPly := map[int][]client.Annotation{}
+	for _, annotation := range annotations {
+		byPly[annotation.Ply] = append(byPly[annotation.Ply], annotation)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "(;GM[4]FF[4]CA[UTF-8]AP[gomoku-cli]SZ[%d]", boardSize)
+	writeSGFAnnotations(&b, byPly[0])
+	for i, move := range moves {
+		color := "B"
+		if move.Player == 2 {
+			color = "W"
+		}
+		fmt.Fprintf(&b, "\n;%s[%s]", color, sgfCoord(client.Move{X: move.X, Y: move.Y}))
+		writeSGFAnnotations(&b, byPly[i+1])
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+// writeSGFAnnotations adds one node's comments (C), arrows (AR) and marks
+// (CR, SQ, TR, MA, LB).
+func writeSGFAnnotations(b *strings.Builder, annotations []client.Annotation) {
+	if len(annotations) == 0 {
+		return
+	}
+	comments := []string{}
+	props := map[string][]string{}
+	for _, annotation := range annotations {
+		if comment := strings.TrimSpace(annotation.Comment); comment != "" {
+			if annotation.Author != "" {
+				comment = annotation.Author + ": " + comment
+			}
+			comments = append(comments, comment)
+		}
+		for _, arrow := range annotation.Arrows {
+			props["AR"] = append(props["AR"], sgfCoord(arrow.From)+":"+sgfCoord(arrow.To))
+		}
+		for _, mark := range annotation.Marks {
+			point := sgfCoord(client.Move{X: mark.X, Y: mark.Y})
+			switch mark.Shape {
+			case "circle":
+				props["CR"] = append(props["CR"], point)
+			case "square":
+				props["SQ"] = append(props["SQ"], point)
+			case "triangle":
+				props["TR"] = append(props["TR"], point)
+			case "cross":
+				props["MA"] = append(props["MA"], point)
+			case "label":
+				props["LB"] = append(props["LB"], point+":"+sgfEscape(mark.Label))
+			}
+		}
+	}
+	if len(comments) > 0 {
+		fmt.Fprintf(b, "C[%s]", sgfEscape(strings.Join(comments, "\n\n")))
+	}
+	for _, name := range []string{"AR", "CR", "SQ", "TR", "MA", "LB"} {
+		if len(props[name]) == 0 {
+			continue
+		}
+		b.WriteString(name)
+		for _, value := range props[name] {
+			fmt.Fprintf(b, "[%s]", value)
+		}
+	}
+}
+
+func sgfEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "]", `\]`).Replace(text)
+}
+
+func decodeSGF(data string) (int, []client.Move, error) {
+	boardSize := 19
+	moves := []client.Move{}
+	for i := 0; i < len(data); i++ {
+		if data[i] != '[' {
+			continue
+		}
+		end := sgfValueEnd(data[i:])
+		if end < 0 {
+			return 0, nil, fmt.Errorf("unterminated property at offset %d", i)
+		}
+		value := data[i+1 : i+end]
+		prop := propertyName(data, i)
+		switch prop {
+		case "SZ":
+			if _, err := fmt.Sscanf(value, "%d", &boardSize); err != nil {
+				return 0, nil, fmt.Errorf("invalid board size %q", value)
+			}
+		case "B", "W":
+			move, err := parseSGFCoord(value)
+			if err != nil {
+				return 0, nil, err
+			}
+			moves = append(moves, move)
+		}
+		i += end
+	}
+	return boardSize, moves, nil
+}
+
+// sgfValueEnd finds the bracket closing the value that opens value[0],
+// skipping backslash-escaped characters.
+func sgfValueEnd(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func propertyName(data string, bracket int) string {
+	start := bracket
+	for start > 0 && data[start-1] >= 'A' && data[start-1] <= 'Z' {
+		start--
+	}
+	return data[start:bracket]
+}
+
+func readSGFFile(path string) (int, []client.Move, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	return decodeSGF(string(raw))
+}
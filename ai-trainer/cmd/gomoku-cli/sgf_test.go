@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+func TestSGFRoundTrip(t *testing.T) {
+	history := []client.HistoryEntry{
+		{X: 9, Y: 9, Player: 1},
+		{X: 10, Y: 9, Player: 2},
+		{X: 0, Y: 18, Player: 1},
+	}
+	size, moves, err := decodeSGF(encodeSGF(19, history, nil))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if size != 19 {
+		t.Fatalf("expected board size 19, got %d", size)
+	}
+	if len(moves) != len(history) {
+		t.Fatalf("expected %d moves, got %d", len(history), len(moves))
+	}
+	for i, move := range moves {
+		if move.X != history[i].X || move.Y != history[i].Y {
+			t.Fatalf("move %d mismatch: got %+v want %d,%d", i, move, history[i].X, history[i].Y)
+		}
+	}
+}
+
+func TestDecodeSGFIgnoresNonMoveProperties(t *testing.T) {
+	size, moves, err := decodeSGF("(;GM[4]SZ[15]PB[alice]C[opening];B[hh];W[ih])")
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if size != 15 || len(moves) != 2 {
+		t.Fatalf("expected size 15 with 2 moves, got size %d with %d moves", size, len(moves))
+	}
+	if moves[1] != (client.Move{X: 8, Y: 7}) {
+		t.Fatalf("unexpected second move %+v", moves[1])
+	}
+}
+
+func TestSGFAnnotations(t *testing.T) {
+	history := []client.HistoryEntry{
+		{X: 9, Y: 9, Player: 1},
+		{X: 10, Y: 9, Player: 2},
+	}
+	annotations := []client.Annotation{
+		{Ply: 0, Comment: "opening [classic]"},
+		{Ply: 2, Author: "ana", Comment: "threat", Arrows: []client.AnnotationArrow{{From: client.Move{X: 9, Y: 9}, To: client.Move{X: 11, Y: 9}}}},
+		{Ply: 2, Marks: []client.AnnotationMark{{X: 11, Y: 9, Shape: "label", Label: "A"}, {X: 8, Y: 9, Shape: "cross"}}},
+	}
+	sgf := encodeSGF(19, history, annotations)
+	for _, want := range []string{`SZ[19]C[opening [classic\]]`, ";W[kj]C[ana: threat]AR[jj:lj]MA[ij]LB[lj:A]"} {
+		if !strings.Contains(sgf, want) {
+			t.Fatalf("expected %q in %s", want, sgf)
+		}
+	}
+	_, moves, err := decodeSGF(sgf)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if len(moves) != len(history) {
+		t.Fatalf("comments should not add moves, got %+v", moves)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const orderConstraintMargin = 0.01
+
+// heuristicConstraints keeps evolved weights in sane ranges. Bounds are keyed
+// by the heuristic JSON names; Order entries read "open_3 > open_2" (or ">=",
+// "<", "<="). Constraints are applied after every mutation and crossover.
+type heuristicConstraints struct {
+	Bounds map[string]weightBounds `json:"bounds"`
+	Order  []string                `json:"order"`
+
+	orders []orderConstraint
+}
+
+type weightBounds struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+type orderConstraint struct {
+	greater string
+	lesser  string
+	strict  bool
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func defaultHeuristicConstraints() heuristicConstraints {
+	c := heuristicConstraints{
+		Bounds: map[string]weightBounds{
+			"capture_scale_final":  {Min: floatPtr(0.05), Max: floatPtr(1)},
+			"capture_scale_late":   {Max: floatPtr(10)},
+			"defensive_capture":    {Min: floatPtr(0.01), Max: floatPtr(1)},
+			"capture_in_two_limit": {Min: floatPtr(1), Max: floatPtr(32)},
+		},
+		Order: []string{
+			"open_4 > closed_4",
+			"open_4 > broken_4",
+			"open_4 > open_3",
+			"open_3 > closed_3",
+			"open_3 > open_2",
+			"broken_3 > broken_2",
+			"open_2 > broken_2",
+			"capture_scale_mid >= capture_scale_early",
+			"capture_scale_late >= capture_scale_mid",
+		},
+	}
+	_ = c.compile()
+	return c
+}
+
+func loadHeuristicConstraints(path string) (heuristicConstraints, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return heuristicConstraints{}, err
+	}
+	return parseHeuristicConstraints(data)
+}
+
+func parseHeuristicConstraints(data []byte) (heuristicConstraints, error) {
+	var c heuristicConstraints
+	if err := json.Unmarshal(data, &c); err != nil {
+		return heuristicConstraints{}, err
+	}
+	if err := c.compile(); err != nil {
+		return heuristicConstraints{}, err
+	}
+	return c, nil
+}
+
+func (c *heuristicConstraints) compile() error {
+	var probe heuristicConfig
+	for name, bounds := range c.Bounds {
+		if _, ok := heuristicField(&probe, name); !ok {
+			return fmt.Errorf("unknown heuristic weight %q", name)
+		}
+		if bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max {
+			return fmt.Errorf("bounds for %q have min above max", name)
+		}
+	}
+	c.orders = c.orders[:0]
+	for _, expr := range c.Order {
+		order, err := parseOrderConstraint(expr)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{order.greater, order.lesser} {
+			if _, ok := heuristicField(&probe, name); !ok {
+				return fmt.Errorf("unknown heuristic weight %q in %q", name, expr)
+			}
+		}
+		c.orders = append(c.orders, order)
+	}
+	return nil
+}
+
+func parseOrderConstraint(expr string) (orderConstraint, error) {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		left, right, found := strings.Cut(expr, op)
+		if !found {
+			continue
+		}
+		left = strings.TrimSpace(left)
+		right = strings.TrimSpace(right)
+		if left == "" || right == "" {
+			break
+		}
+		order := orderConstraint{greater: left, lesser: right, strict: len(op) == 1}
+		if op[0] == '<' {
+			order.greater, order.lesser = right, left
+		}
+		return order, nil
+	}
+	return orderConstraint{}, fmt.Errorf("invalid order constraint %q", expr)
+}
+
+func heuristicField(h *heuristicConfig, name string) (reflect.Value, bool) {
+	value := reflect.ValueOf(h).Elem()
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func getWeight(field reflect.Value) float64 {
+	if field.Kind() == reflect.Int {
+		return float64(field.Int())
+	}
+	return field.Float()
+}
+
+func setWeight(field reflect.Value, v float64) {
+	if field.Kind() == reflect.Int {
+		field.SetInt(int64(math.Round(v)))
+		return
+	}
+	field.SetFloat(v)
+}
+
+func (c heuristicConstraints) clamp(h *heuristicConfig, name string) bool {
+	bounds, ok := c.Bounds[name]
+	if !ok {
+		return false
+	}
+	field, _ := heuristicField(h, name)
+	v := getWeight(field)
+	next := v
+	if bounds.Min != nil && next < *bounds.Min {
+		next = *bounds.Min
+	}
+	if bounds.Max != nil && next > *bounds.Max {
+		next = *bounds.Max
+	}
+	if next == v {
+		return false
+	}
+	setWeight(field, next)
+	return true
+}
+
+func (c heuristicConstraints) apply(h heuristicConfig) heuristicConfig {
+	out := h
+	for pass := 0; pass < 8; pass++ {
+		changed := false
+		for name := range c.Bounds {
+			if c.clamp(&out, name) {
+				changed = true
+			}
+		}
+		for _, order := range c.orders {
+			greater, _ := heuristicField(&out, order.greater)
+			lesser, _ := heuristicField(&out, order.lesser)
+			g := getWeight(greater)
+			l := getWeight(lesser)
+			if g > l || !order.strict && g == l {
+				continue
+			}
+			target := g
+			if order.strict {
+				target = g - math.Max(math.Abs(g)*orderConstraintMargin, 1e-6)
+			}
+			if bounds, ok := c.Bounds[order.lesser]; ok && bounds.Min != nil && target < *bounds.Min {
+				setWeight(greater, l+math.Max(math.Abs(l)*orderConstraintMargin, 1e-6))
+			} else {
+				setWeight(lesser, target)
+			}
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+	return out
+}
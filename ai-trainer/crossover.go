@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	crossoverUniform = "uniform"
+	crossoverBlend   = "blend"
+	crossoverMixed   = "mixed"
+
+	blendCrossoverAlpha = 0.25
+)
+
+func heuristicWeights(h *heuristicConfig) []*float64 {
+	return []*float64{
+		&h.Open4, &h.Closed4, &h.Broken4,
+		&h.Open3, &h.Broken3, &h.Closed3,
+		&h.Open2, &h.Broken2,
+		&h.ForkOpen3, &h.ForkFourPlus,
+		&h.CaptureNow, &h.CaptureDoubleThreat, &h.CaptureNearWin, &h.CaptureInTwo,
+		&h.HangingPair,
+		&h.CaptureScaleEarly, &h.CaptureScaleMid, &h.CaptureScaleLate, &h.CaptureScaleFinal,
+		&h.DefensiveCapture,
+	}
+}
+
+func normalizeCrossoverMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case crossoverUniform:
+		return crossoverUniform
+	case crossoverBlend:
+		return crossoverBlend
+	default:
+		return crossoverMixed
+	}
+}
+
+// tournamentSelect expects ranked to be sorted best first, so the lowest
+// sampled index wins the tournament.
+func (t *trainer) tournamentSelect(ranked []contender) contender {
+	best := t.rng.Intn(len(ranked))
+	for i := 1; i < t.tournamentSize; i++ {
+		if idx := t.rng.Intn(len(ranked)); idx < best {
+			best = idx
+		}
+	}
+	return ranked[best]
+}
+
+func (t *trainer) crossoverHeuristics(a, b heuristicConfig) heuristicConfig {
+	mode := t.crossoverMode
+	if mode == crossoverMixed {
+		mode = crossoverUniform
+		if t.rng.Intn(2) == 1 {
+			mode = crossoverBlend
+		}
+	}
+	child := a
+	childWeights := heuristicWeights(&child)
+	otherWeights := heuristicWeights(&b)
+	for i, weight := range childWeights {
+		other := *otherWeights[i]
+		if mode == crossoverUniform {
+			if t.rng.Intn(2) == 1 {
+				*weight = other
+			}
+			continue
+		}
+		u := -blendCrossoverAlpha + t.rng.Float64()*(1+2*blendCrossoverAlpha)
+		next := *weight + u*(other-*weight)
+		if math.IsNaN(next) || math.IsInf(next, 0) || next < 1 {
+			next = math.Min(*weight, other)
+		}
+		*weight = next
+	}
+	if t.rng.Intn(2) == 1 {
+		child.CaptureInTwoLimit = b.CaptureInTwoLimit
+	}
+	return t.currentConstraints().apply(child)
+}
+
+func (t *trainer) breedHeuristics(ranked []contender) (heuristicConfig, string) {
+	first := t.tournamentSelect(ranked)
+	child := first.Heuristics
+	crossed := false
+	if len(ranked) > 1 && t.rng.Float64() < t.crossoverRate {
+		second := t.tournamentSelect(ranked)
+		for tries := 0; tries < 3 && heuristicsEqual(second.Heuristics, first.Heuristics); tries++ {
+			second = t.tournamentSelect(ranked)
+		}
+		child = t.crossoverHeuristics(first.Heuristics, second.Heuristics)
+		crossed = true
+	}
+	switch {
+	case !crossed:
+		return t.mutateHeuristics(child), "mut"
+	case t.rng.Float64() < t.mutationRate:
+		return t.mutateHeuristics(child), "cross-mut"
+	default:
+		return child, "cross"
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+// fitnessWeights blends strength, speed and style into a single ranking
+// score expressed in Elo-like points. Speed and length are penalized by
+// 100 points per reference unit (SpeedRefMs per move, LengthRefPlies per
+// game) times their weight; a negative LengthWeight rewards long games.
+type fitnessWeights struct {
+	Elo            float64 `json:"elo"`
+	Speed          float64 `json:"speed"`
+	Length         float64 `json:"length"`
+	SpeedRefMs     float64 `json:"speed_ref_ms"`
+	LengthRefPlies float64 `json:"length_ref_plies"`
+}
+
+type gameMetrics struct {
+	Games      int
+	Moves      int
+	Plies      int
+	MoveTimeMs float64
+}
+
+func (m *gameMetrics) add(other gameMetrics) {
+	m.Games += other.Games
+	m.Moves += other.Moves
+	m.Plies += other.Plies
+	m.MoveTimeMs += other.MoveTimeMs
+}
+
+func (m gameMetrics) avgMoveMs() float64 {
+	if m.Moves == 0 {
+		return 0
+	}
+	return m.MoveTimeMs / float64(m.Moves)
+}
+
+func (m gameMetrics) avgPlies() float64 {
+	if m.Games == 0 {
+		return 0
+	}
+	return float64(m.Plies) / float64(m.Games)
+}
+
+func sideMetrics(status client.Status, player int) gameMetrics {
+	metrics := gameMetrics{Games: 1, Plies: len(status.History)}
+	moves, err := status.Moves()
+	if err != nil {
+		return metrics
+	}
+	for _, entry := range moves {
+		if !entry.IsAi || entry.Player != player {
+			continue
+		}
+		metrics.Moves++
+		metrics.MoveTimeMs += entry.ElapsedMs
+	}
+	return metrics
+}
+
+func (w fitnessWeights) score(c contender) float64 {
+	fitness := w.Elo * (c.Elo - 1500)
+	if w.SpeedRefMs > 0 {
+		fitness -= w.Speed * 100 * c.Metrics.avgMoveMs() / w.SpeedRefMs
+	}
+	if w.LengthRefPlies > 0 {
+		fitness -= w.Length * 100 * c.Metrics.avgPlies() / w.LengthRefPlies
+	}
+	return fitness
+}
+
+func (w fitnessWeights) rank(list []contender) {
+	for i := range list {
+		list[i].Fitness = w.score(list[i])
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Fitness > list[j].Fitness
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+const (
+	gauntletHistoryFile  = "gauntlet_history.jsonl"
+	gauntletStatusLimit  = 50
+	gauntletOpeningsSalt = 1777
+)
+
+// gauntletReference is a frozen opponent the champion is measured against
+// every few generations. A zero Depth keeps the backend search depth as is;
+// otherwise both sides search at that depth for the reference games.
+type gauntletReference struct {
+	ID         string           `json:"id"`
+	Heuristics *heuristicConfig `json:"heuristics,omitempty"`
+	Depth      int              `json:"depth,omitempty"`
+}
+
+type gauntletResult struct {
+	Reference string  `json:"reference"`
+	Depth     int     `json:"depth,omitempty"`
+	Points    float64 `json:"points"`
+	Games     int     `json:"games"`
+	Rate      float64 `json:"rate"`
+}
+
+type gauntletRecord struct {
+	Generation int              `json:"generation"`
+	Champion   string           `json:"champion"`
+	PlayedAt   string           `json:"played_at"`
+	Rate       float64          `json:"rate"`
+	Results    []gauntletResult `json:"results"`
+}
+
+func defaultGauntlet() []gauntletReference {
+	return []gauntletReference{
+		{ID: "default-d4", Depth: 4},
+		{ID: "default-d6", Depth: 6},
+		{ID: "default-d8", Depth: 8},
+	}
+}
+
+func loadGauntlet(path string) ([]gauntletReference, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var refs []gauntletReference
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		return nil, err
+	}
+	for i, ref := range refs {
+		if ref.ID == "" {
+			return nil, fmt.Errorf("gauntlet reference %d has no id", i)
+		}
+	}
+	return refs, nil
+}
+
+func (t *trainer) runGauntlet(ctx context.Context, generation int, champion contender, openings [][]openingMove) (gauntletRecord, error) {
+	record := gauntletRecord{Generation: generation, Champion: champion.ID}
+	status, err := t.fetchStatus(ctx)
+	if err != nil {
+		return record, err
+	}
+	original := status.Config
+	defer func() {
+		if original == nil {
+			return
+		}
+		if _, err := t.api.UpdateSettings(context.Background(), client.SettingsUpdate{Config: original}); err != nil {
+			t.logf("failed to restore backend depth after gauntlet: %v", err)
+		}
+	}()
+
+	points := 0.0
+	games := 0
+	for _, ref := range t.gauntlet {
+		opponent := defaultHeuristics()
+		if ref.Heuristics != nil {
+			opponent = *ref.Heuristics
+		}
+		if ref.Depth > 0 && original != nil {
+			cfg := make(map[string]any, len(original))
+			for key, value := range original {
+				cfg[key] = value
+			}
+			cfg["ai_depth"] = ref.Depth
+			cfg["ai_max_depth"] = ref.Depth
+			if _, err := t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: cfg}); err != nil {
+				return record, err
+			}
+		}
+		result := gauntletResult{Reference: ref.ID, Depth: ref.Depth}
+		for openingIdx, opening := range openings {
+			if ctx.Err() != nil {
+				return record, ctx.Err()
+			}
+			t.updateStatus(func(s *trainerStatus) {
+				s.CurrentMatch = &trainerMatch{
+					BlackID:      champion.ID,
+					WhiteID:      ref.ID,
+					OpeningIndex: openingIdx,
+					Stage:        "gauntlet",
+				}
+			})
+			score, _, _, err := t.playHeadToHead(ctx, champion.Heuristics, opponent, opening)
+			if err != nil {
+				return record, err
+			}
+			result.Points += score * 2
+			result.Games += 2
+		}
+		if result.Games > 0 {
+			result.Rate = result.Points / float64(result.Games)
+		}
+		points += result.Points
+		games += result.Games
+		record.Results = append(record.Results, result)
+		t.logf("Gen %d gauntlet vs %s: %.1f/%d", generation, ref.ID, result.Points, result.Games)
+	}
+	if games > 0 {
+		record.Rate = points / float64(games)
+	}
+	return record, nil
+}
+
+func (t *trainer) persistGauntletRecord(record gauntletRecord) error {
+	if err := os.MkdirAll(t.dataDir, 0o755); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(t.dataDir, gauntletHistoryFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(raw, '\n'))
+	return err
+}
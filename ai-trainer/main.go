@@ -1,8 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,26 +13,38 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"gomoku-ai-trainer/pkg/client"
 )
 
 type trainer struct {
-	client       *http.Client
+	api          *client.Client
+	simAPI       *client.Client
+	batchAPI     *client.Client
 	baseURL      string
 	pollInterval time.Duration
 	logger       *log.Logger
 	totalBoards  int
 	mode         string
 	apiAddr      string
+	apiTLS       *tls.Config
 	rng          *rand.Rand
 
 	matchesPerRound    int
 	mutationStrength   float64
+	mutationRate       float64
+	crossoverRate      float64
+	crossoverMode      string
+	tournamentSize     int
 	heuristicTimeout   time.Duration
 	aiTimeBudgetMs     int
+	cacheTemperature   float64
+	cacheTempHalfLife  int
 	populationSize     int
 	eliteCount         int
 	trainingOpenings   int
@@ -42,6 +54,28 @@ type trainer struct {
 	validationPassRate float64
 	originalConfig     map[string]any
 	configOverridden   bool
+	notifier           *webhookNotifier
+	useSimulate        bool
+	useSeeding         bool
+	useHistoryDiff     bool
+	boardSize          int
+	useBatch           bool
+	promoteLive        bool
+	batchWorkers       int
+	mock               *mockBackend
+	dataDir            string
+	fitness            fitnessWeights
+
+	gauntlet         []gauntletReference
+	gauntletEvery    int
+	gauntletOpenings int
+
+	constraintsMu sync.RWMutex
+	constraints   heuristicConstraints
+
+	scheduleMu   sync.RWMutex
+	schedule     trainingSchedule
+	scheduleSpec string
 
 	statusMu  sync.RWMutex
 	status    trainerStatus
@@ -50,25 +84,6 @@ type trainer struct {
 	jobDone   chan struct{}
 }
 
-type statusResponse struct {
-	Status    string            `json:"status"`
-	Winner    int               `json:"winner"`
-	History   []json.RawMessage `json:"history"`
-	BoardSize int               `json:"board_size"`
-	Config    map[string]any    `json:"config"`
-}
-
-type queueResponse struct {
-	TotalInQueue int `json:"total_in_queue"`
-}
-
-type ttCacheStatusResponse struct {
-	Count    int     `json:"count"`
-	Capacity int     `json:"capacity"`
-	Usage    float64 `json:"usage"`
-	Full     bool    `json:"full"`
-}
-
 type trainerStatus struct {
 	Running             bool    `json:"running"`
 	Mode                string  `json:"mode"`
@@ -86,12 +101,21 @@ type trainerStatus struct {
 	GenerationStartedAt string  `json:"generation_started_at"`
 	RoundMatchesTotal   int     `json:"round_matches_total"`
 	EtaSeconds          int     `json:"eta_seconds"`
-
-	CurrentMatch        *trainerMatch     `json:"current_match,omitempty"`
-	TopContenders       []trainerStanding `json:"top_contenders,omitempty"`
-	ChampionHeuristic   heuristicConfig   `json:"champion_heuristic"`
-	ChallengerHeuristic heuristicConfig   `json:"challenger_heuristic"`
-	ChallengerDetails   []trainerDetail   `json:"challenger_details,omitempty"`
+	Schedule            string  `json:"schedule"`
+	ResumesAt           string  `json:"resumes_at,omitempty"`
+	// AuthError is set while the backend refuses the trainer's token.
+	AuthError string `json:"auth_error,omitempty"`
+
+	CurrentMatch        *trainerMatch       `json:"current_match,omitempty"`
+	TopContenders       []trainerStanding   `json:"top_contenders,omitempty"`
+	ChampionHeuristic   heuristicConfig     `json:"champion_heuristic"`
+	ChampionHash        string              `json:"champion_hash,omitempty"`
+	ChallengerHeuristic heuristicConfig     `json:"challenger_heuristic"`
+	ChallengerDetails   []trainerDetail     `json:"challenger_details,omitempty"`
+	LastGauntletRate    float64             `json:"last_gauntlet_rate"`
+	GauntletHistory     []gauntletRecord    `json:"gauntlet_history,omitempty"`
+	FitnessWeights      fitnessWeights      `json:"fitness_weights"`
+	SearchParams        client.SearchParams `json:"search_params,omitempty"`
 }
 
 type trainerMatch struct {
@@ -102,39 +126,21 @@ type trainerMatch struct {
 }
 
 type trainerStanding struct {
-	ID  string  `json:"id"`
-	Elo float64 `json:"elo"`
+	ID        string  `json:"id"`
+	Elo       float64 `json:"elo"`
+	Fitness   float64 `json:"fitness"`
+	AvgMoveMs float64 `json:"avg_move_ms"`
+	AvgPlies  float64 `json:"avg_plies"`
 }
 
 type trainerDetail struct {
 	ID         string          `json:"id"`
 	Elo        float64         `json:"elo"`
+	Fitness    float64         `json:"fitness"`
 	Heuristics heuristicConfig `json:"heuristics"`
 }
 
-type heuristicConfig struct {
-	Open4               float64 `json:"open_4"`
-	Closed4             float64 `json:"closed_4"`
-	Broken4             float64 `json:"broken_4"`
-	Open3               float64 `json:"open_3"`
-	Broken3             float64 `json:"broken_3"`
-	Closed3             float64 `json:"closed_3"`
-	Open2               float64 `json:"open_2"`
-	Broken2             float64 `json:"broken_2"`
-	ForkOpen3           float64 `json:"fork_open_3"`
-	ForkFourPlus        float64 `json:"fork_four_plus"`
-	CaptureNow          float64 `json:"capture_now"`
-	CaptureDoubleThreat float64 `json:"capture_double_threat"`
-	CaptureNearWin      float64 `json:"capture_near_win"`
-	CaptureInTwo        float64 `json:"capture_in_two"`
-	HangingPair         float64 `json:"hanging_pair"`
-	CaptureWinSoonScale float64 `json:"capture_win_soon_scale"`
-	CaptureInTwoLimit   int     `json:"capture_in_two_limit"`
-}
-
-type heuristicsResponse struct {
-	Heuristics heuristicConfig `json:"heuristics"`
-}
+type heuristicConfig = client.HeuristicConfig
 
 type openingMove struct {
 	X int
@@ -145,6 +151,8 @@ type contender struct {
 	ID         string
 	Heuristics heuristicConfig
 	Elo        float64
+	Fitness    float64
+	Metrics    gameMetrics
 }
 
 func main() {
@@ -170,6 +178,16 @@ func main() {
 	if mutationStrength <= 0 {
 		mutationStrength = 0.08
 	}
+	crossoverRate := getenvFloat("HEURISTIC_CROSSOVER_RATE", 0.5)
+	if crossoverRate < 0 || crossoverRate > 1 {
+		crossoverRate = 0.5
+	}
+	mutationRate := getenvFloat("HEURISTIC_MUTATION_RATE", 0.3)
+	if mutationRate < 0 || mutationRate > 1 {
+		mutationRate = 0.3
+	}
+	crossoverMode := normalizeCrossoverMode(getenv("HEURISTIC_CROSSOVER_MODE", crossoverMixed))
+	tournamentSize := getenvInt("HEURISTIC_TOURNAMENT_SIZE", 3)
 	heuristicTimeoutSec := getenvInt("HEURISTIC_GAME_TIMEOUT_SEC", 180)
 	aiTimeBudgetMs := getenvInt("TRAINER_AI_TIME_BUDGET_MS", 800)
 	populationSize := getenvInt("HEURISTIC_POPULATION_SIZE", 8)
@@ -203,20 +221,95 @@ func main() {
 	if validationPassRate <= 0 || validationPassRate > 1 {
 		validationPassRate = 0.52
 	}
-	t := &trainer{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+	fitness := fitnessWeights{
+		Elo:            getenvFloat("FITNESS_WEIGHT_ELO", 1),
+		Speed:          getenvFloat("FITNESS_WEIGHT_SPEED", 0),
+		Length:         getenvFloat("FITNESS_WEIGHT_LENGTH", 0),
+		SpeedRefMs:     getenvFloat("FITNESS_SPEED_REF_MS", float64(aiTimeBudgetMs)),
+		LengthRefPlies: getenvFloat("FITNESS_LENGTH_REF_PLIES", 60),
+	}
+	constraints := defaultHeuristicConstraints()
+	if path := getenv("HEURISTIC_CONSTRAINTS_PATH", ""); path != "" {
+		constraints, err = loadHeuristicConstraints(path)
+		if err != nil {
+			log.Fatalf("invalid HEURISTIC_CONSTRAINTS_PATH: %v", err)
+		}
+	}
+	gauntlet := defaultGauntlet()
+	if path := getenv("GAUNTLET_PATH", ""); path != "" {
+		gauntlet, err = loadGauntlet(path)
+		if err != nil {
+			log.Fatalf("invalid GAUNTLET_PATH: %v", err)
+		}
+	}
+	if enabled := strings.ToLower(getenv("GAUNTLET_ENABLED", "true")); enabled == "0" || enabled == "false" || enabled == "no" {
+		gauntlet = nil
+	}
+	scheduleSpec := getenv("TRAINER_SCHEDULE", "")
+	schedule, err := parseSchedule(scheduleSpec)
+	if err != nil {
+		log.Fatalf("invalid TRAINER_SCHEDULE: %v", err)
+	}
+	backendTLS, err := backendTLSConfig()
+	if err != nil {
+		log.Fatalf("invalid backend TLS settings: %v", err)
+	}
+	apiTLS, err := apiTLSConfig()
+	if err != nil {
+		log.Fatalf("invalid trainer API TLS settings: %v", err)
+	}
+	token := client.StaticToken(getenv("BACKEND_TOKEN", ""))
+	if path := getenv("BACKEND_TOKEN_FILE", ""); path != "" {
+		token = client.FileToken(path)
+	}
+	var mock *mockBackend
+	dataDir := "/logs"
+	if strings.EqualFold(mode, "dryrun") {
+		dataDir = filepath.Join(dataDir, "dryrun")
+		mock, err = startMockBackend(time.Now().UnixNano())
+		if err != nil {
+			log.Fatalf("failed to start mock backend: %v", err)
+		}
+		baseURL = mock.url
+		pollMs = 5
+	}
+	var t *trainer
+	backendClient := func(opts ...client.Option) *client.Client {
+		return client.New(baseURL, append([]client.Option{
+			client.WithTLSConfig(backendTLS),
+			client.WithTokenSource(token),
+			client.WithAuthHook(func(err error) { t.noteAuth(err) }),
+		}, opts...)...)
+	}
+	t = &trainer{
+		api:                backendClient(),
+		simAPI:             backendClient(client.WithTimeout(time.Duration(heuristicTimeoutSec)*time.Second), client.WithRetries(0, 0)),
+		batchAPI:           backendClient(client.WithTimeout(0), client.WithRetries(0, 0)),
+		useSimulate:        !strings.EqualFold(getenv("HEURISTIC_USE_SIMULATE", "true"), "false"),
+		useSeeding:         true,
+		useHistoryDiff:     true,
+		useBatch:           !strings.EqualFold(getenv("HEURISTIC_USE_SIMULATE", "true"), "false"),
+		batchWorkers:       getenvInt("HEURISTIC_BATCH_WORKERS", 1),
+		promoteLive:        !strings.EqualFold(getenv("HEURISTIC_PROMOTE_LIVE", "true"), "false"),
+		mock:               mock,
+		dataDir:            dataDir,
 		baseURL:            baseURL,
 		pollInterval:       time.Duration(pollMs) * time.Millisecond,
 		logger:             logger,
 		mode:               mode,
 		apiAddr:            apiAddr,
+		apiTLS:             apiTLS,
 		rng:                rand.New(rand.NewSource(time.Now().UnixNano())),
 		matchesPerRound:    matchesPerRound,
 		mutationStrength:   mutationStrength,
+		mutationRate:       mutationRate,
+		crossoverRate:      crossoverRate,
+		crossoverMode:      crossoverMode,
+		tournamentSize:     tournamentSize,
 		heuristicTimeout:   time.Duration(heuristicTimeoutSec) * time.Second,
 		aiTimeBudgetMs:     aiTimeBudgetMs,
+		cacheTemperature:   getenvFloat("CACHE_SELFPLAY_TEMPERATURE", 0.05),
+		cacheTempHalfLife:  getenvInt("CACHE_SELFPLAY_HALF_LIFE", 20),
 		populationSize:     populationSize,
 		eliteCount:         eliteCount,
 		trainingOpenings:   trainingOpenings,
@@ -224,16 +317,30 @@ func main() {
 		openingPlies:       openingPlies,
 		eloK:               eloK,
 		validationPassRate: validationPassRate,
+		fitness:            fitness,
+		constraints:        constraints,
+		gauntlet:           gauntlet,
+		gauntletEvery:      getenvInt("GAUNTLET_EVERY_GENERATIONS", 1),
+		gauntletOpenings:   getenvInt("GAUNTLET_OPENINGS", 2),
+		schedule:           schedule,
+		scheduleSpec:       scheduleSpec,
 		status: trainerStatus{
-			Running:   false,
-			Mode:      mode,
-			Phase:     "idle",
-			Message:   "service ready",
-			StartedAt: time.Now().UTC().Format(time.RFC3339),
-			UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+			Running:        false,
+			Mode:           mode,
+			Phase:          "idle",
+			Message:        "service ready",
+			StartedAt:      time.Now().UTC().Format(time.RFC3339),
+			UpdatedAt:      time.Now().UTC().Format(time.RFC3339),
+			Schedule:       scheduleSpec,
+			FitnessWeights: fitness,
 		},
 	}
 
+	t.notifier = newWebhookNotifier(getenv("NOTIFY_WEBHOOK_URL", ""), map[string]string{
+		notifyGenerationComplete: getenv("NOTIFY_TEMPLATE_GENERATION", ""),
+		notifyChampionPromoted:   getenv("NOTIFY_TEMPLATE_PROMOTION", ""),
+	}, t.logf)
+
 	t.logf("AI trainer service started. backend=%s mode=%s poll_interval=%s", t.baseURL, t.mode, t.pollInterval)
 	t.startStatusAPI()
 
@@ -264,7 +371,7 @@ func (t *trainer) startStatusAPI() {
 	})
 	mux.HandleFunc("/api/trainer/start", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
 			return
 		}
 		var payload struct {
@@ -276,36 +383,220 @@ func (t *trainer) startStatusAPI() {
 			mode = t.mode
 		}
 		if err := t.startTraining(mode); err != nil {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			writeError(w, http.StatusConflict, "conflict", err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, t.getStatus())
 	})
 	mux.HandleFunc("/api/trainer/stop", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
 			return
 		}
 		if err := t.stopTraining("requested via api"); err != nil {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			writeError(w, http.StatusConflict, "conflict", err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, t.getStatus())
 	})
-	server := &http.Server{Addr: t.apiAddr, Handler: mux}
+	mux.HandleFunc("/api/trainer/constraints", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+				return
+			}
+			constraints, err := parseHeuristicConstraints(data)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+				return
+			}
+			t.setConstraints(constraints)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, t.currentConstraints())
+	})
+	mux.HandleFunc("/api/trainer/schedule", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var payload struct {
+				Schedule        *string `json:"schedule"`
+				BacklogSchedule *string `json:"backlog_schedule"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+				return
+			}
+			if payload.Schedule != nil {
+				if err := t.setSchedule(*payload.Schedule); err != nil {
+					writeError(w, http.StatusBadRequest, "validation_failed", err.Error())
+					return
+				}
+			}
+			if payload.BacklogSchedule != nil {
+				if err := t.setBacklogSchedule(r.Context(), *payload.BacklogSchedule); err != nil {
+					writeError(w, http.StatusBadGateway, "upstream_error", err.Error())
+					return
+				}
+			}
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, t.scheduleStatus(r.Context()))
+	})
+	server := &http.Server{Addr: t.apiAddr, Handler: mux, TLSConfig: t.apiTLS}
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		serve := server.ListenAndServe
+		if t.apiTLS != nil {
+			serve = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			t.logf("trainer api server error: %v", err)
 		}
 	}()
 }
 
+func (t *trainer) currentConstraints() heuristicConstraints {
+	t.constraintsMu.RLock()
+	defer t.constraintsMu.RUnlock()
+	return t.constraints
+}
+
+func (t *trainer) setConstraints(constraints heuristicConstraints) {
+	t.constraintsMu.Lock()
+	t.constraints = constraints
+	t.constraintsMu.Unlock()
+	t.logf("Heuristic constraints updated: %d bounds, %d order rules", len(constraints.Bounds), len(constraints.Order))
+}
+
+type trainerScheduleStatus struct {
+	Schedule        string `json:"schedule"`
+	Open            bool   `json:"open"`
+	NextWindowAt    string `json:"next_window_at,omitempty"`
+	BacklogSchedule string `json:"backlog_schedule"`
+}
+
+func (t *trainer) currentSchedule() (trainingSchedule, string) {
+	t.scheduleMu.RLock()
+	defer t.scheduleMu.RUnlock()
+	return t.schedule, t.scheduleSpec
+}
+
+func (t *trainer) setSchedule(spec string) error {
+	schedule, err := parseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	t.scheduleMu.Lock()
+	t.schedule = schedule
+	t.scheduleSpec = spec
+	t.scheduleMu.Unlock()
+	t.updateStatus(func(s *trainerStatus) {
+		s.Schedule = spec
+	})
+	t.logf("Training schedule set to %q", spec)
+	return nil
+}
+
+func (t *trainer) setBacklogSchedule(ctx context.Context, spec string) error {
+	status, err := t.api.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Config == nil {
+		return fmt.Errorf("backend did not return its config")
+	}
+	status.Config["ai_queue_schedule"] = spec
+	if _, err := t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: status.Config}); err != nil {
+		return err
+	}
+	t.logf("Backlog schedule set to %q", spec)
+	return nil
+}
+
+func (t *trainer) scheduleStatus(ctx context.Context) trainerScheduleStatus {
+	schedule, spec := t.currentSchedule()
+	now := time.Now()
+	result := trainerScheduleStatus{
+		Schedule: spec,
+		Open:     schedule.Active(now),
+	}
+	if !result.Open {
+		result.NextWindowAt = schedule.NextStart(now).UTC().Format(time.RFC3339)
+	}
+	if status, err := t.api.Status(ctx); err == nil {
+		result.BacklogSchedule, _ = status.Config["ai_queue_schedule"].(string)
+	}
+	return result
+}
+
+func (t *trainer) waitForWindow(ctx context.Context) error {
+	schedule, _ := t.currentSchedule()
+	if schedule.Active(time.Now()) {
+		return nil
+	}
+	previous := t.getStatus()
+	logged := false
+	for {
+		schedule, _ := t.currentSchedule()
+		now := time.Now()
+		if schedule.Active(now) {
+			break
+		}
+		resumesAt := schedule.NextStart(now)
+		if !logged {
+			t.logf("Outside training window, pausing until %s", resumesAt.Format(time.RFC3339))
+			logged = true
+		}
+		t.updateStatus(func(s *trainerStatus) {
+			s.Phase = "waiting_window"
+			s.Message = "outside training window"
+			s.ResumesAt = resumesAt.UTC().Format(time.RFC3339)
+		})
+		if !sleepWithContext(ctx, t.pollInterval) {
+			return ctx.Err()
+		}
+	}
+	t.logf("Training window open, resuming")
+	t.updateStatus(func(s *trainerStatus) {
+		s.Phase = previous.Phase
+		s.Message = previous.Message
+		s.ResumesAt = ""
+	})
+	return nil
+}
+
 func (t *trainer) getStatus() trainerStatus {
 	t.statusMu.RLock()
 	defer t.statusMu.RUnlock()
 	return t.status
 }
 
+// noteAuth keeps AuthError in step with the backend's answers: set, and
+// logged once, when it refuses the token, cleared when it accepts a request.
+func (t *trainer) noteAuth(err error) {
+	message := ""
+	if err != nil {
+		message = fmt.Sprintf("backend refused the trainer (%v); check BACKEND_TOKEN or BACKEND_TOKEN_FILE", err)
+	}
+	t.statusMu.Lock()
+	changed := t.status.AuthError != message
+	if changed {
+		t.status.AuthError = message
+		t.status.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	t.statusMu.Unlock()
+	if changed && message != "" {
+		t.logf("%s", message)
+	}
+}
+
 func (t *trainer) updateStatus(mutator func(*trainerStatus)) {
 	t.statusMu.Lock()
 	defer t.statusMu.Unlock()
@@ -320,10 +611,14 @@ func (t *trainer) startTraining(mode string) error {
 		return fmt.Errorf("training already running")
 	}
 	switch mode {
-	case "", "heuristic", "cache":
+	case "", "heuristic", "cache", "search":
 		if mode == "" {
 			mode = t.mode
 		}
+	case "dryrun":
+		if t.mock == nil {
+			return fmt.Errorf("dryrun mode requires TRAINER_MODE=dryrun at startup")
+		}
 	default:
 		return fmt.Errorf("unknown mode %q", mode)
 	}
@@ -395,6 +690,11 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
+// writeError answers with the same {code, error} body the backend uses.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "error": message})
+}
+
 func buildLogger(path string) (*log.Logger, func(), error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, nil, err
@@ -408,7 +708,10 @@ func buildLogger(path string) (*log.Logger, func(), error) {
 }
 
 func (t *trainer) runMode(ctx context.Context, mode string) error {
-	if strings.EqualFold(mode, "heuristic") {
+	if strings.EqualFold(mode, "search") {
+		return t.runSearchTuning(ctx)
+	}
+	if strings.EqualFold(mode, "heuristic") || strings.EqualFold(mode, "dryrun") {
 		return t.runHeuristicTraining(ctx)
 	}
 	return t.runCacheTraining(ctx)
@@ -423,16 +726,25 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 		s.TopContenders = nil
 		s.ChallengerDetails = nil
 		s.ChampionHeuristic = heuristicConfig{}
+		s.ChampionHash = ""
 		s.ChallengerHeuristic = heuristicConfig{}
 		s.CurrentMatch = nil
 	})
+	restore, err := t.applyCacheTemperature(ctx)
+	if err != nil {
+		return err
+	}
+	defer restore()
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
-		full, err := t.ttIsFull()
+		if err := t.waitForWindow(ctx); err != nil {
+			return err
+		}
+		full, err := t.ttIsFull(ctx)
 		if err != nil {
 			return err
 		}
@@ -441,12 +753,12 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 			return nil
 		}
 
-		queueBefore, err := t.getQueueCount()
+		queueBefore, err := t.getQueueCount(ctx)
 		if err != nil {
 			return err
 		}
 
-		if err := t.startAIVsAIGame(nil, nil); err != nil {
+		if err := t.startAIVsAIGame(ctx, nil, nil); err != nil {
 			return err
 		}
 		t.updateStatus(func(s *trainerStatus) {
@@ -456,17 +768,17 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 		t.logf("Waiting the game to finish...")
 
 		for {
-			full, err := t.ttIsFull()
+			full, err := t.ttIsFull(ctx)
 			if err != nil {
 				return err
 			}
 			if full {
 				t.logf("TT cache is full during game. Stopping trainer.")
-				_ = t.stopGame()
+				_ = t.stopGame(ctx)
 				return nil
 			}
 
-			running, err := t.gameRunning()
+			running, err := t.gameRunning(ctx)
 			if err != nil {
 				return err
 			}
@@ -481,7 +793,7 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 		t.logf("Game is over.")
 		t.logf("Waiting the analyze queue to be empty...")
 
-		queueAfterGame, err := t.getQueueCount()
+		queueAfterGame, err := t.getQueueCount(ctx)
 		if err != nil {
 			return err
 		}
@@ -493,7 +805,7 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 
 		lastLogged := -1
 		for {
-			full, err := t.ttIsFull()
+			full, err := t.ttIsFull(ctx)
 			if err != nil {
 				return err
 			}
@@ -502,7 +814,7 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 				return nil
 			}
 
-			count, err := t.getQueueCount()
+			count, err := t.getQueueCount(ctx)
 			if err != nil {
 				return err
 			}
@@ -528,7 +840,7 @@ func (t *trainer) runCacheTraining(ctx context.Context) error {
 }
 
 func (t *trainer) runHeuristicTraining(ctx context.Context) error {
-	if err := t.applyHeuristicConfigOverride(); err != nil {
+	if err := t.applyHeuristicConfigOverride(ctx); err != nil {
 		return err
 	}
 	defer func() {
@@ -537,16 +849,18 @@ func (t *trainer) runHeuristicTraining(ctx context.Context) error {
 		}
 	}()
 
-	base, err := t.getBaseHeuristics()
+	base, err := t.getBaseHeuristics(ctx)
 	if err != nil {
 		return err
 	}
 	boardSize := 19
-	if st, err := t.fetchStatus(); err == nil && st.BoardSize > 0 {
+	if st, err := t.fetchStatus(ctx); err == nil && st.BoardSize > 0 {
 		boardSize = st.BoardSize
 	}
+	t.boardSize = boardSize
 	trainOpenings := t.buildOpeningSuite(boardSize, t.trainingOpenings, 41)
 	valOpenings := t.buildOpeningSuite(boardSize, t.validationOpenings, 911)
+	gauntletOpenings := t.buildOpeningSuite(boardSize, t.gauntletOpenings, gauntletOpeningsSalt)
 	champion := contender{ID: "champion", Heuristics: base, Elo: 1500}
 	population := t.initializePopulation(champion.Heuristics)
 	_ = t.persistHeuristicPair(champion.Heuristics, population[1].Heuristics)
@@ -564,9 +878,12 @@ func (t *trainer) runHeuristicTraining(ctx context.Context) error {
 		s.RoundMatchesTotal = 0
 		s.EtaSeconds = 0
 		s.ChampionHeuristic = champion.Heuristics
+		s.ChampionHash = champion.Heuristics.Hash()
 		s.ChallengerHeuristic = population[1].Heuristics
 		s.TopContenders = toStandings(population, 8)
 		s.ChallengerDetails = toChallengerDetails(population, champion.Heuristics, 8)
+		s.LastGauntletRate = 0
+		s.GauntletHistory = nil
 	})
 
 	generation := 1
@@ -589,7 +906,7 @@ func (t *trainer) runHeuristicTraining(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		sortContendersByElo(population)
+		t.fitness.rank(population)
 		best := population[0]
 		challenger := population[1]
 
@@ -611,10 +928,42 @@ func (t *trainer) runHeuristicTraining(ctx context.Context) error {
 				promoted = true
 			}
 		}
+		if len(t.gauntlet) > 0 && generation%t.gauntletEvery == 0 {
+			record, err := t.runGauntlet(ctx, generation, champion, gauntletOpenings)
+			if err != nil {
+				return err
+			}
+			record.PlayedAt = time.Now().UTC().Format(time.RFC3339)
+			if err := t.persistGauntletRecord(record); err != nil {
+				t.logf("failed to persist gauntlet record: %v", err)
+			}
+			t.logf("Gen %d gauntlet rate %.2f", generation, record.Rate)
+			t.registerWithBackend(ctx, generation, champion, record.Rate)
+			t.updateStatus(func(s *trainerStatus) {
+				s.LastGauntletRate = record.Rate
+				s.GauntletHistory = append(s.GauntletHistory, record)
+				if len(s.GauntletHistory) > gauntletStatusLimit {
+					s.GauntletHistory = s.GauntletHistory[len(s.GauntletHistory)-gauntletStatusLimit:]
+				}
+			})
+		}
+		notifyFields := map[string]string{
+			"generation":      strconv.Itoa(generation),
+			"games":           strconv.Itoa(gamesPlayed),
+			"champion":        champion.ID,
+			"champion_hash":   champion.Heuristics.Hash(),
+			"validation_rate": fmt.Sprintf("%.2f", t.getStatus().LastValidationRate),
+			"gauntlet_rate":   fmt.Sprintf("%.2f", t.getStatus().LastGauntletRate),
+		}
 		if promoted {
-			t.logf("Gen %d champion promoted", generation)
+			t.logf("Gen %d champion promoted (heuristic hash %s)", generation, champion.Heuristics.Hash())
+			if t.promoteLive {
+				t.promoteToBackend(ctx, generation, champion)
+			}
+			t.notifier.notify(notifyChampionPromoted, notifyFields)
 		} else {
 			t.logf("Gen %d champion retained", generation)
+			t.notifier.notify(notifyGenerationComplete, notifyFields)
 		}
 
 		_ = t.persistHeuristicPair(champion.Heuristics, challenger.Heuristics)
@@ -624,6 +973,7 @@ func (t *trainer) runHeuristicTraining(ctx context.Context) error {
 			s.CurrentMatch = nil
 			s.EtaSeconds = 0
 			s.ChampionHeuristic = champion.Heuristics
+			s.ChampionHash = champion.Heuristics.Hash()
 			s.ChallengerHeuristic = challenger.Heuristics
 			s.TopContenders = toStandings(population, 8)
 			s.ChallengerDetails = toChallengerDetails(population, champion.Heuristics, 8)
@@ -650,21 +1000,24 @@ func (t *trainer) runPopulationRound(ctx context.Context, population []contender
 					}
 					s.GamesPlayed = games
 				})
-				result, stones, err := t.playHeadToHead(ctx, population[i].Heuristics, population[j].Heuristics, opening)
+				result, stones, metrics, err := t.playHeadToHead(ctx, population[i].Heuristics, population[j].Heuristics, opening)
 				if err != nil {
 					return games, err
 				}
 				updateElo(&population[i], &population[j], result, t.eloK)
+				population[i].Metrics.add(metrics[0])
+				population[j].Metrics.add(metrics[1])
 				games++
 				ranked := make([]contender, len(population))
 				copy(ranked, population)
-				sortContendersByElo(ranked)
+				t.fitness.rank(ranked)
 				t.updateStatus(func(s *trainerStatus) {
 					s.GamesPlayed = games
 					s.TopContenders = toStandings(ranked, 8)
 					s.ChallengerDetails = toChallengerDetails(ranked, s.ChampionHeuristic, 8)
 					if len(ranked) > 0 {
 						s.ChampionHeuristic = ranked[0].Heuristics
+						s.ChampionHash = ranked[0].Heuristics.Hash()
 					}
 					if len(ranked) > 1 {
 						s.ChallengerHeuristic = ranked[1].Heuristics
@@ -691,13 +1044,22 @@ func (t *trainer) runPopulationRound(ctx context.Context, population []contender
 }
 
 func (t *trainer) runValidation(ctx context.Context, candidate heuristicConfig, champion heuristicConfig, openings [][]openingMove) (float64, float64, error) {
+	if t.useBatch && len(openings) > 0 {
+		points, total, err := t.runValidationBatch(ctx, candidate, champion, openings)
+		if client.IsMissingEndpoint(err) {
+			t.logf("Backend has no /api/simulate/batch, validating game by game")
+			t.useBatch = false
+		} else {
+			return points, total, err
+		}
+	}
 	points := 0.0
 	total := 0.0
 	for _, opening := range openings {
 		if ctx.Err() != nil {
 			return points, total, ctx.Err()
 		}
-		result, _, err := t.playHeadToHead(ctx, candidate, champion, opening)
+		result, _, _, err := t.playHeadToHead(ctx, candidate, champion, opening)
 		if err != nil {
 			return points, total, err
 		}
@@ -707,9 +1069,47 @@ func (t *trainer) runValidation(ctx context.Context, candidate heuristicConfig,
 	return points, total, nil
 }
 
-func (t *trainer) playHeadToHead(ctx context.Context, first, second heuristicConfig, opening []openingMove) (float64, int, error) {
+// runValidationBatch plays every validation opening with both colours in one
+// backend batch; each opening counts as one point, as in playHeadToHead.
+func (t *trainer) runValidationBatch(ctx context.Context, candidate heuristicConfig, champion heuristicConfig, openings [][]openingMove) (float64, float64, error) {
+	if err := t.waitForWindow(ctx); err != nil {
+		return 0, 0, err
+	}
+	req := client.SimulateBatchRequest{
+		BlackHeuristics: &candidate,
+		WhiteHeuristics: &champion,
+		AlternateColors: true,
+		MoveBudgetMs:    t.aiTimeBudgetMs,
+		Workers:         t.batchWorkers,
+	}
+	for _, opening := range openings {
+		moves := make([]client.Move, 0, len(opening))
+		for _, move := range opening {
+			moves = append(moves, client.Move{X: move.X, Y: move.Y})
+		}
+		req.Openings = append(req.Openings, moves)
+	}
+	summary, err := t.batchAPI.SimulateBatch(ctx, req, func(game client.SimulateBatchGame) {
+		if game.Error != "" {
+			t.logf("Validation game %d failed: %s", game.Index, game.Error)
+		}
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if summary.Errors > 0 {
+		return 0, 0, fmt.Errorf("%d of %d validation games failed", summary.Errors, summary.Games)
+	}
+	if summary.Games < len(openings)*2 {
+		return 0, 0, fmt.Errorf("validation batch returned %d of %d games", summary.Games, len(openings)*2)
+	}
+	return summary.Score / 2, float64(summary.Games) / 2, nil
+}
+
+func (t *trainer) playHeadToHead(ctx context.Context, first, second heuristicConfig, opening []openingMove) (float64, int, [2]gameMetrics, error) {
 	points := 0.0
 	stones := 0
+	var metrics [2]gameMetrics
 	for _, firstBlack := range []bool{true, false} {
 		var black, white heuristicConfig
 		if firstBlack {
@@ -721,9 +1121,16 @@ func (t *trainer) playHeadToHead(ctx context.Context, first, second heuristicCon
 		}
 		status, matchStones, err := t.playConfiguredGame(ctx, black, white, opening)
 		if err != nil {
-			return 0, 0, err
+			return 0, 0, metrics, err
 		}
 		stones += matchStones
+		if firstBlack {
+			metrics[0].add(sideMetrics(status, 1))
+			metrics[1].add(sideMetrics(status, 2))
+		} else {
+			metrics[0].add(sideMetrics(status, 2))
+			metrics[1].add(sideMetrics(status, 1))
+		}
 		switch status.Winner {
 		case 1:
 			if firstBlack {
@@ -737,68 +1144,178 @@ func (t *trainer) playHeadToHead(ctx context.Context, first, second heuristicCon
 			points += 0.5
 		}
 	}
-	return points / 2.0, stones / 2, nil
+	return points / 2.0, stones / 2, metrics, nil
 }
 
-func (t *trainer) playConfiguredGame(ctx context.Context, black heuristicConfig, white heuristicConfig, opening []openingMove) (statusResponse, int, error) {
-	if err := t.startSeededGame(opening, &black, &white); err != nil {
-		return statusResponse{}, 0, err
+func (t *trainer) playConfiguredGame(ctx context.Context, black heuristicConfig, white heuristicConfig, opening []openingMove) (client.Status, int, error) {
+	if err := t.waitForWindow(ctx); err != nil {
+		return client.Status{}, 0, err
+	}
+	if t.useSimulate {
+		status, stones, err := t.simulateGame(ctx, black, white, opening)
+		if client.IsMissingEndpoint(err) {
+			t.logf("Backend has no /api/simulate, falling back to real-time games")
+			t.useSimulate = false
+		} else {
+			return status, stones, err
+		}
+	}
+	if err := t.startSeededGame(ctx, opening, &black, &white); err != nil {
+		return client.Status{}, 0, err
 	}
 	deadline := time.Now().Add(t.heuristicTimeout)
+	var follower gameFollower
 	for {
 		if ctx.Err() != nil {
-			return statusResponse{}, 0, ctx.Err()
+			return client.Status{}, 0, ctx.Err()
 		}
-		status, err := t.fetchStatus()
+		status, err := t.pollGame(ctx, &follower)
 		if err != nil {
-			return statusResponse{}, 0, err
+			return client.Status{}, 0, err
 		}
 		if status.Status != "running" {
 			return status, len(status.History), nil
 		}
 		if t.heuristicTimeout > 0 && time.Now().After(deadline) {
-			_ = t.stopGame()
-			return statusResponse{}, 0, fmt.Errorf("heuristic game timeout after %s", t.heuristicTimeout)
+			_ = t.stopGame(ctx)
+			return client.Status{}, 0, fmt.Errorf("heuristic game timeout after %s", t.heuristicTimeout)
 		}
 		if !sleepWithContext(ctx, t.pollInterval) {
-			return statusResponse{}, 0, ctx.Err()
+			return client.Status{}, 0, ctx.Err()
 		}
 	}
 }
 
-func (t *trainer) startSeededGame(opening []openingMove, black *heuristicConfig, white *heuristicConfig) error {
-	if err := t.postJSON("/api/start", map[string]any{
-		"settings": map[string]any{
-			"mode":         "human_vs_human",
-			"human_player": 1,
-		},
-	}, nil); err != nil {
+func (t *trainer) simulateGame(ctx context.Context, black heuristicConfig, white heuristicConfig, opening []openingMove) (client.Status, int, error) {
+	req := client.SimulateRequest{
+		BlackHeuristics: &black,
+		WhiteHeuristics: &white,
+		MoveBudgetMs:    t.aiTimeBudgetMs,
+	}
+	for _, move := range opening {
+		req.Opening = append(req.Opening, client.Move{X: move.X, Y: move.Y})
+	}
+	result, err := t.simAPI.Simulate(ctx, req)
+	if err != nil {
+		return client.Status{}, 0, err
+	}
+	status := client.Status{Status: result.Status, Winner: result.Winner, WinningLine: result.WinningLine}
+	for _, move := range result.Moves {
+		raw, err := json.Marshal(move)
+		if err != nil {
+			return client.Status{}, 0, err
+		}
+		status.History = append(status.History, raw)
+	}
+	return status, len(result.Moves), nil
+}
+
+func (t *trainer) startSeededGame(ctx context.Context, opening []openingMove, black *heuristicConfig, white *heuristicConfig) error {
+	if t.useSeeding && t.boardSize > 0 {
+		settings := client.GameSettings{
+			Mode:            "ai_vs_ai",
+			HumanPlayer:     1,
+			BlackHeuristics: black,
+			WhiteHeuristics: white,
+		}
+		status, err := t.api.StartFromPosition(ctx, settings, openingPosition(t.boardSize, opening))
+		if err != nil || status.StartPosition != nil {
+			return err
+		}
+		t.logf("Backend ignored the start position, replaying openings move by move")
+		t.useSeeding = false
+	}
+	if _, err := t.api.Start(ctx, client.GameSettings{Mode: "human_vs_human", HumanPlayer: 1}); err != nil {
 		return err
 	}
 	for _, move := range opening {
-		if err := t.postJSON("/api/move", map[string]any{
-			"x": move.X,
-			"y": move.Y,
-		}, nil); err != nil {
+		if _, err := t.api.Move(ctx, move.X, move.Y); err != nil {
 			return err
 		}
 	}
-	return t.postJSON("/api/settings", map[string]any{
-		"settings": map[string]any{
-			"mode":             "ai_vs_ai",
-			"human_player":     1,
-			"black_heuristics": black,
-			"white_heuristics": white,
+	_, err := t.api.UpdateSettings(ctx, client.SettingsUpdate{
+		Settings: &client.GameSettings{
+			Mode:            "ai_vs_ai",
+			HumanPlayer:     1,
+			BlackHeuristics: black,
+			WhiteHeuristics: white,
 		},
-	}, nil)
+	})
+	return err
 }
 
-func (t *trainer) fetchStatus() (statusResponse, error) {
-	var status statusResponse
-	if err := t.getJSON("/api/status", &status); err != nil {
-		return statusResponse{}, err
+// openingPosition plays the opening onto an empty board, black first, with
+// pair captures applied as the backend would.
+func openingPosition(boardSize int, opening []openingMove) client.StartPosition {
+	board := make([][]int, boardSize)
+	for y := range board {
+		board[y] = make([]int, boardSize)
+	}
+	inBounds := func(x, y int) bool { return x >= 0 && y >= 0 && x < boardSize && y < boardSize }
+	captured := [3]int{}
+	for i, move := range opening {
+		player := i%2 + 1
+		board[move.Y][move.X] = player
+		for _, d := range [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {-1, -1}, {1, -1}, {-1, 1}} {
+			x1, y1 := move.X+d[0], move.Y+d[1]
+			x2, y2 := move.X+2*d[0], move.Y+2*d[1]
+			x3, y3 := move.X+3*d[0], move.Y+3*d[1]
+			if !inBounds(x3, y3) || board[y3][x3] != player {
+				continue
+			}
+			if board[y1][x1] == 3-player && board[y2][x2] == 3-player {
+				board[y1][x1] = 0
+				board[y2][x2] = 0
+				captured[player] += 2
+			}
+		}
+	}
+	return client.StartPosition{
+		Board:         board,
+		NextPlayer:    len(opening)%2 + 1,
+		CapturedBlack: captured[1],
+		CapturedWhite: captured[2],
 	}
-	return status, nil
+}
+
+// gameFollower accumulates the history of the game being polled so each
+// poll only transfers the new entries.
+type gameFollower struct {
+	gameID  uint64
+	entries []json.RawMessage
+}
+
+func (t *trainer) pollGame(ctx context.Context, follower *gameFollower) (client.Status, error) {
+	if t.useHistoryDiff {
+		diff, err := t.api.HistorySince(ctx, len(follower.entries), follower.gameID)
+		if client.IsMissingEndpoint(err) {
+			t.logf("Backend has no /api/history, polling full status")
+			t.useHistoryDiff = false
+		} else if err != nil {
+			return client.Status{}, err
+		} else {
+			if diff.Reset {
+				follower.entries = nil
+			}
+			follower.gameID = diff.GameID
+			follower.entries = append(follower.entries, diff.Entries...)
+			return client.Status{
+				Status:      diff.Status,
+				NextPlayer:  diff.NextPlayer,
+				Winner:      diff.Winner,
+				WinReason:   diff.WinReason,
+				WinningLine: diff.WinningLine,
+				History:     append([]json.RawMessage(nil), follower.entries...),
+				GameID:      diff.GameID,
+				Hash:        diff.Hash,
+			}, nil
+		}
+	}
+	return t.fetchStatus(ctx)
+}
+
+func (t *trainer) fetchStatus(ctx context.Context) (client.Status, error) {
+	return t.api.Status(ctx)
 }
 
 func (t *trainer) buildOpeningSuite(boardSize, count int, salt int64) [][]openingMove {
@@ -856,15 +1373,11 @@ func (t *trainer) nextGenerationPopulation(champion heuristicConfig, ranked []co
 			Elo:        1500,
 		})
 	}
-	parentPool := ranked
-	if len(parentPool) > t.eliteCount+1 {
-		parentPool = parentPool[:t.eliteCount+1]
-	}
 	for len(next) < t.populationSize {
-		parent := parentPool[t.rng.Intn(len(parentPool))]
+		child, kind := t.breedHeuristics(ranked)
 		next = append(next, contender{
-			ID:         fmt.Sprintf("mut-%d", len(next)),
-			Heuristics: t.mutateHeuristics(parent.Heuristics),
+			ID:         fmt.Sprintf("%s-%d", kind, len(next)),
+			Heuristics: child,
 			Elo:        1500,
 		})
 	}
@@ -874,7 +1387,13 @@ func (t *trainer) nextGenerationPopulation(champion heuristicConfig, ranked []co
 func toStandings(list []contender, limit int) []trainerStanding {
 	out := make([]trainerStanding, 0, minInt(len(list), limit))
 	for i := 0; i < len(list) && i < limit; i++ {
-		out = append(out, trainerStanding{ID: list[i].ID, Elo: list[i].Elo})
+		out = append(out, trainerStanding{
+			ID:        list[i].ID,
+			Elo:       list[i].Elo,
+			Fitness:   list[i].Fitness,
+			AvgMoveMs: list[i].Metrics.avgMoveMs(),
+			AvgPlies:  list[i].Metrics.avgPlies(),
+		})
 	}
 	return out
 }
@@ -885,21 +1404,11 @@ func toChallengerDetails(list []contender, champion heuristicConfig, limit int)
 		if heuristicsEqual(list[i].Heuristics, champion) {
 			continue
 		}
-		out = append(out, trainerDetail{ID: list[i].ID, Elo: list[i].Elo, Heuristics: list[i].Heuristics})
+		out = append(out, trainerDetail{ID: list[i].ID, Elo: list[i].Elo, Fitness: list[i].Fitness, Heuristics: list[i].Heuristics})
 	}
 	return out
 }
 
-func sortContendersByElo(list []contender) {
-	for i := 0; i < len(list); i++ {
-		for j := i + 1; j < len(list); j++ {
-			if list[j].Elo > list[i].Elo {
-				list[i], list[j] = list[j], list[i]
-			}
-		}
-	}
-}
-
 func updateElo(a *contender, b *contender, resultForA float64, k float64) {
 	expA := 1.0 / (1.0 + math.Pow(10, (b.Elo-a.Elo)/400.0))
 	expB := 1.0 / (1.0 + math.Pow(10, (a.Elo-b.Elo)/400.0))
@@ -918,10 +1427,10 @@ func minInt(a, b int) int {
 	return b
 }
 
-func (t *trainer) getBaseHeuristics() (heuristicConfig, error) {
-	var payload heuristicsResponse
-	if err := t.getJSON("/api/heuristics", &payload); err == nil {
-		return payload.Heuristics, nil
+func (t *trainer) getBaseHeuristics(ctx context.Context) (heuristicConfig, error) {
+	if active, err := t.api.ActiveHeuristics(ctx); err == nil {
+		t.logf("Base heuristics from backend (heuristic hash %s)", active.HeuristicHash)
+		return active.Heuristics, nil
 	}
 	if fromLogs, err := t.readHeuristicFile("current_best_heuristic.json"); err == nil {
 		return fromLogs, nil
@@ -957,11 +1466,60 @@ func (t *trainer) mutateHeuristics(base heuristicConfig) heuristicConfig {
 	out.CaptureNearWin = mutate(out.CaptureNearWin)
 	out.CaptureInTwo = mutate(out.CaptureInTwo)
 	out.HangingPair = mutate(out.HangingPair)
-	out.CaptureWinSoonScale = mutate(out.CaptureWinSoonScale)
+	out.CaptureScaleEarly = mutate(out.CaptureScaleEarly)
+	out.CaptureScaleMid = mutate(out.CaptureScaleMid)
+	out.CaptureScaleLate = mutate(out.CaptureScaleLate)
+	out.CaptureScaleFinal = mutate(out.CaptureScaleFinal)
+	out.DefensiveCapture = mutate(out.DefensiveCapture)
 	if out.CaptureInTwoLimit <= 0 {
 		out.CaptureInTwoLimit = base.CaptureInTwoLimit
 	}
-	return out
+	return t.currentConstraints().apply(out)
+}
+
+// promoteToBackend makes the champion's weights the backend's live ones.
+// Training goes on when the backend refuses: the champion is still in the
+// heuristic files.
+func (t *trainer) promoteToBackend(ctx context.Context, generation int, champion contender) {
+	promotion, err := t.api.PromoteHeuristics(ctx, client.PromoteHeuristicsRequest{
+		Heuristics: champion.Heuristics,
+		Source:     trainerHeuristicSource(generation),
+	})
+	if err != nil {
+		t.logf("failed to promote %s to live play: %v", champion.ID, err)
+		return
+	}
+	t.logf("%s is live (heuristic hash %s, replaced %s, %d cache entries pruned)", champion.ID, promotion.HeuristicHash, promotion.PreviousHash, promotion.TTEntriesPruned)
+}
+
+// registerWithBackend records the champion and its gauntlet result in the
+// backend's heuristic registry, so the weights can be compared with and
+// rolled back to later. A set registered before keeps its first source.
+func (t *trainer) registerWithBackend(ctx context.Context, generation int, champion contender, rate float64) {
+	elo := gauntletElo(rate)
+	record, err := t.api.RegisterHeuristics(ctx, client.RegisterHeuristicsRequest{
+		Heuristics:   champion.Heuristics,
+		Source:       trainerHeuristicSource(generation),
+		GauntletRate: &rate,
+		GauntletElo:  &elo,
+	})
+	if err != nil {
+		t.logf("failed to register %s with the backend: %v", champion.ID, err)
+		return
+	}
+	t.logf("%s registered as %s (source %s, gauntlet elo %+.0f)", champion.ID, record.Hash, record.Source, elo)
+}
+
+// trainerHeuristicSource is the registry source of weights from generation.
+func trainerHeuristicSource(generation int) string {
+	return fmt.Sprintf("trainer-gen%d", generation)
+}
+
+// gauntletElo is the Elo difference to the gauntlet opponents that scoring
+// rate implies, clamped so a clean sweep stays finite.
+func gauntletElo(rate float64) float64 {
+	rate = math.Max(0.01, math.Min(0.99, rate))
+	return 400 * math.Log10(rate/(1-rate))
 }
 
 func (t *trainer) persistHeuristicPair(champion, challenger heuristicConfig) error {
@@ -978,15 +1536,19 @@ func (t *trainer) persistHeuristicPair(champion, challenger heuristicConfig) err
 }
 
 func (t *trainer) writeHeuristicFile(name string, heuristics heuristicConfig) error {
-	if err := os.MkdirAll("/logs", 0o755); err != nil {
+	if err := os.MkdirAll(t.dataDir, 0o755); err != nil {
 		return err
 	}
-	raw, err := json.MarshalIndent(heuristics, "", "  ")
+	// The hash tells which backend cache entries these weights wrote.
+	raw, err := json.MarshalIndent(struct {
+		client.HeuristicConfig
+		HeuristicHash string `json:"heuristic_hash"`
+	}{heuristics, heuristics.Hash()}, "", "  ")
 	if err != nil {
 		return err
 	}
 	raw = append(raw, '\n')
-	path := filepath.Join("/logs", name)
+	path := filepath.Join(t.dataDir, name)
 	tmp := path + ".tmp"
 	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
 		return err
@@ -995,7 +1557,7 @@ func (t *trainer) writeHeuristicFile(name string, heuristics heuristicConfig) er
 }
 
 func (t *trainer) readHeuristicFile(name string) (heuristicConfig, error) {
-	path := filepath.Join("/logs", name)
+	path := filepath.Join(t.dataDir, name)
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		return heuristicConfig{}, err
@@ -1024,14 +1586,18 @@ func defaultHeuristics() heuristicConfig {
 		CaptureNearWin:      12000,
 		CaptureInTwo:        700,
 		HangingPair:         2400,
-		CaptureWinSoonScale: 0.95,
+		CaptureScaleEarly:   1,
+		CaptureScaleMid:     1.25,
+		CaptureScaleLate:    2,
+		CaptureScaleFinal:   0.95,
+		DefensiveCapture:    0.5,
 		CaptureInTwoLimit:   8,
 	}
 }
 
-func (t *trainer) applyHeuristicConfigOverride() error {
-	var status statusResponse
-	if err := t.getJSON("/api/status", &status); err != nil {
+func (t *trainer) applyHeuristicConfigOverride(ctx context.Context) error {
+	status, err := t.api.Status(ctx)
+	if err != nil {
 		return err
 	}
 	cfg := status.Config
@@ -1040,13 +1606,55 @@ func (t *trainer) applyHeuristicConfigOverride() error {
 	}
 	cfg["ai_use_tt_cache"] = false
 	cfg["ai_time_budget_ms"] = t.aiTimeBudgetMs
-	return t.postJSON("/api/settings", map[string]any{"config": cfg}, nil)
+	_, err = t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: cfg})
+	return err
 }
 
 func (t *trainer) restoreHeuristicConfigOverride() error {
 	return nil
 }
 
+// applyCacheTemperature makes the backend's AI-vs-AI games explore with
+// the cache temperature, so cache training keeps reaching new positions.
+// The returned func puts the backend's previous values back.
+func (t *trainer) applyCacheTemperature(ctx context.Context) (func(), error) {
+	noop := func() {}
+	if t.cacheTemperature <= 0 {
+		return noop, nil
+	}
+	status, err := t.api.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg := status.Config
+	previous, ok := cfg["ai_self_play_temperature"]
+	if !ok {
+		t.logf("Backend has no ai_self_play_temperature; cache games will not explore")
+		return noop, nil
+	}
+	previousHalfLife := cfg["ai_self_play_temperature_half_life"]
+	cfg["ai_self_play_temperature"] = t.cacheTemperature
+	cfg["ai_self_play_temperature_half_life"] = t.cacheTempHalfLife
+	if _, err := t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: cfg}); err != nil {
+		return nil, err
+	}
+	t.logf("Self-play temperature %.3f (half-life %d plies)", t.cacheTemperature, t.cacheTempHalfLife)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		status, err := t.api.Status(ctx)
+		if err != nil || status.Config == nil {
+			t.logf("failed to restore self-play temperature: %v", err)
+			return
+		}
+		status.Config["ai_self_play_temperature"] = previous
+		status.Config["ai_self_play_temperature_half_life"] = previousHalfLife
+		if _, err := t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: status.Config}); err != nil {
+			t.logf("failed to restore self-play temperature: %v", err)
+		}
+	}, nil
+}
+
 func (t *trainer) waitBackendReady(ctx context.Context) error {
 	deadline := time.Now().Add(60 * time.Second)
 	for time.Now().Before(deadline) {
@@ -1055,7 +1663,7 @@ func (t *trainer) waitBackendReady(ctx context.Context) error {
 			return ctx.Err()
 		default:
 		}
-		if err := t.ping(); err == nil {
+		if err := t.api.Ping(ctx); err == nil {
 			return nil
 		}
 		if !sleepWithContext(ctx, 1*time.Second) {
@@ -1065,104 +1673,45 @@ func (t *trainer) waitBackendReady(ctx context.Context) error {
 	return fmt.Errorf("timeout after 60s")
 }
 
-func (t *trainer) ping() error {
-	req, err := http.NewRequest(http.MethodGet, t.baseURL+"/api/ping", nil)
-	if err != nil {
-		return err
-	}
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ping status %d", resp.StatusCode)
-	}
-	return nil
-}
-
-func (t *trainer) startAIVsAIGame(blackHeuristics *heuristicConfig, whiteHeuristics *heuristicConfig) error {
-	payload := map[string]any{
-		"settings": map[string]any{
-			"mode":             "ai_vs_ai",
-			"human_player":     1,
-			"black_heuristics": blackHeuristics,
-			"white_heuristics": whiteHeuristics,
-		},
-	}
-	return t.postJSON("/api/start", payload, nil)
+func (t *trainer) startAIVsAIGame(ctx context.Context, blackHeuristics *heuristicConfig, whiteHeuristics *heuristicConfig) error {
+	_, err := t.api.Start(ctx, client.GameSettings{
+		Mode:            "ai_vs_ai",
+		HumanPlayer:     1,
+		BlackHeuristics: blackHeuristics,
+		WhiteHeuristics: whiteHeuristics,
+	})
+	return err
 }
 
-func (t *trainer) stopGame() error {
-	return t.postJSON("/api/stop", map[string]any{}, nil)
+func (t *trainer) stopGame(ctx context.Context) error {
+	_, err := t.api.Stop(ctx)
+	return err
 }
 
-func (t *trainer) gameRunning() (bool, error) {
-	var status statusResponse
-	if err := t.getJSON("/api/status", &status); err != nil {
+func (t *trainer) gameRunning(ctx context.Context) (bool, error) {
+	status, err := t.api.Status(ctx)
+	if err != nil {
 		return false, err
 	}
-	return status.Status == "running", nil
+	return status.Running(), nil
 }
 
-func (t *trainer) getQueueCount() (int, error) {
-	var queue queueResponse
-	if err := t.getJSON("/api/analitics/queue", &queue); err != nil {
+func (t *trainer) getQueueCount(ctx context.Context) (int, error) {
+	queue, err := t.api.AnalyticsQueue(ctx)
+	if err != nil {
 		return 0, err
 	}
 	return queue.TotalInQueue, nil
 }
 
-func (t *trainer) ttIsFull() (bool, error) {
-	var tt ttCacheStatusResponse
-	if err := t.getJSON("/api/cache/tt", &tt); err != nil {
+func (t *trainer) ttIsFull(ctx context.Context) (bool, error) {
+	tt, err := t.api.TTCacheStatus(ctx)
+	if err != nil {
 		return false, err
 	}
 	return tt.Full, nil
 }
 
-func (t *trainer) getJSON(path string, out any) error {
-	req, err := http.NewRequest(http.MethodGet, t.baseURL+path, nil)
-	if err != nil {
-		return err
-	}
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("GET %s -> %d: %s", path, resp.StatusCode, string(body))
-	}
-	return json.NewDecoder(resp.Body).Decode(out)
-}
-
-func (t *trainer) postJSON(path string, payload any, out any) error {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequest(http.MethodPost, t.baseURL+path, bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return fmt.Errorf("POST %s -> %d: %s", path, resp.StatusCode, string(respBody))
-	}
-	if out == nil {
-		return nil
-	}
-	return json.NewDecoder(resp.Body).Decode(out)
-}
-
 func (t *trainer) logf(format string, args ...any) {
 	ts := time.Now().Format("2006-01-02 15:04:05")
 	t.logger.Printf("[%s] %s", ts, fmt.Sprintf(format, args...))
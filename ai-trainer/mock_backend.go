@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+const mockBoardSize = 19
+
+// mockBackend is an in-process stand-in for the game backend used by
+// TRAINER_MODE=dryrun. AI vs AI games finish instantly: the winner is drawn
+// from how close each side's heuristics are to a hidden target, so the
+// evolutionary loop has something to converge on without real searches.
+type mockBackend struct {
+	mu       sync.Mutex
+	rng      *rand.Rand
+	target   heuristicConfig
+	config   map[string]any
+	settings client.GameSettings
+	status   string
+	winner   int
+	history  []client.HistoryEntry
+	occupied map[[2]int]bool
+	start    *client.StartPosition
+	gameID   uint64
+	url      string
+}
+
+func startMockBackend(seed int64) (*mockBackend, error) {
+	rng := rand.New(rand.NewSource(seed))
+	target := defaultHeuristics()
+	for _, weight := range heuristicWeights(&target) {
+		*weight *= 0.6 + rng.Float64()*0.8
+	}
+	m := &mockBackend{
+		rng:    rng,
+		target: target,
+		config: map[string]any{
+			"ai_depth":          10,
+			"ai_max_depth":      10,
+			"ai_time_budget_ms": 500,
+			"ai_use_tt_cache":   true,
+			"ai_queue_schedule": "",
+		},
+		settings: client.GameSettings{Mode: "ai_vs_ai", HumanPlayer: 1},
+		status:   "not_started",
+		occupied: map[[2]int]bool{},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	m.url = "http://" + listener.Addr().String()
+	go func() {
+		_ = http.Serve(listener, m.routes())
+	}()
+	return m, nil
+}
+
+func (m *mockBackend) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ping", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.snapshot())
+	})
+	mux.HandleFunc("/api/history", func(w http.ResponseWriter, r *http.Request) {
+		since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+		gameID, _ := strconv.ParseUint(r.URL.Query().Get("game_id"), 10, 64)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		diff := client.HistoryDiff{GameID: m.gameID, Since: since, Total: len(m.history), Status: m.status, NextPlayer: len(m.history)%2 + 1, Winner: m.winner}
+		if since < 0 || since > len(m.history) || (gameID > 0 && gameID != m.gameID) {
+			diff.Reset = true
+			diff.Since = 0
+		}
+		for _, entry := range m.history[diff.Since:] {
+			raw, _ := json.Marshal(entry)
+			diff.Entries = append(diff.Entries, raw)
+		}
+		writeJSON(w, http.StatusOK, diff)
+	})
+	mux.HandleFunc("/api/start", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Settings client.GameSettings `json:"settings"`
+			client.StartPosition
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		m.mu.Lock()
+		m.settings = payload.Settings
+		m.reset("running")
+		if payload.Board != nil {
+			position := payload.StartPosition
+			m.start = &position
+			for y, row := range payload.Board {
+				for x, value := range row {
+					if value != 0 {
+						m.occupied[[2]int{x, y}] = true
+					}
+				}
+			}
+		}
+		m.playOutLocked()
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, m.snapshot())
+	})
+	mux.HandleFunc("/api/stop", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.reset("not_started")
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, m.snapshot())
+	})
+	mux.HandleFunc("/api/move", func(w http.ResponseWriter, r *http.Request) {
+		var move client.Move
+		if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		m.mu.Lock()
+		ok := m.status == "running" && m.placeLocked(move.X, move.Y, false, 0)
+		m.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusBadRequest, "illegal_move", "illegal move")
+			return
+		}
+		writeJSON(w, http.StatusOK, m.snapshot())
+	})
+	mux.HandleFunc("/api/settings", func(w http.ResponseWriter, r *http.Request) {
+		var payload client.SettingsUpdate
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		m.mu.Lock()
+		if payload.Config != nil {
+			m.config = payload.Config
+		}
+		if payload.Settings != nil {
+			m.settings = *payload.Settings
+			m.playOutLocked()
+		}
+		m.mu.Unlock()
+		writeJSON(w, http.StatusOK, m.snapshot())
+	})
+	mux.HandleFunc("/api/simulate", func(w http.ResponseWriter, r *http.Request) {
+		var req client.SimulateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		writeJSON(w, http.StatusOK, m.simulateLocked(req.Opening, req.BlackHeuristics, req.WhiteHeuristics))
+	})
+	mux.HandleFunc("/api/simulate/batch", func(w http.ResponseWriter, r *http.Request) {
+		var req client.SimulateBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		games := req.Games
+		if games <= 0 {
+			games = len(req.Openings)
+			if req.AlternateColors {
+				games *= 2
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		var summary client.SimulateBatchSummary
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i := 0; i < games; i++ {
+			slot, swapped := i, false
+			if req.AlternateColors {
+				slot, swapped = i/2, i%2 == 1
+			}
+			var opening []client.Move
+			if len(req.Openings) > 0 {
+				opening = req.Openings[slot%len(req.Openings)]
+			}
+			black, white, first := req.BlackHeuristics, req.WhiteHeuristics, 1
+			if swapped {
+				black, white, first = white, black, 2
+			}
+			result := m.simulateLocked(opening, black, white)
+			game := client.SimulateBatchGame{Index: i, OpeningIndex: slot % max(len(req.Openings), 1), Swapped: swapped, Score: 0.5, Result: result}
+			summary.Games++
+			switch result.Winner {
+			case 0:
+				summary.Draws++
+			case first:
+				summary.Wins++
+				game.Score = 1
+			default:
+				summary.Losses++
+				game.Score = 0
+			}
+			summary.Score += game.Score
+			_ = encoder.Encode(map[string]any{"type": "game", "game": game})
+		}
+		if summary.Games > 0 {
+			summary.ScoreRate = summary.Score / float64(summary.Games)
+		}
+		_ = encoder.Encode(map[string]any{"type": "summary", "summary": summary})
+	})
+	mux.HandleFunc("/api/heuristics", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"heuristics": defaultHeuristics()})
+	})
+	mux.HandleFunc("/api/admin/heuristics/promote", func(w http.ResponseWriter, r *http.Request) {
+		var req client.PromoteHeuristicsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		writeJSON(w, http.StatusOK, client.HeuristicPromotion{Heuristics: req.Heuristics, HeuristicHash: req.Heuristics.Hash(), Source: req.Source, Changed: true})
+	})
+	mux.HandleFunc("/api/heuristics/registry", func(w http.ResponseWriter, r *http.Request) {
+		var req client.RegisterHeuristicsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_payload", "invalid payload")
+			return
+		}
+		writeJSON(w, http.StatusOK, client.HeuristicRecord{Hash: req.Heuristics.Hash(), Heuristics: req.Heuristics, Source: req.Source, CreatedAt: time.Now().UTC(), GauntletRate: req.GauntletRate, GauntletElo: req.GauntletElo})
+	})
+	mux.HandleFunc("/api/analitics/queue", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, client.AnalyticsQueue{Queue: []client.AnalyticsQueueEntry{}})
+	})
+	mux.HandleFunc("/api/cache/tt", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, client.TTCacheStatus{Capacity: 1})
+	})
+	return mux
+}
+
+func (m *mockBackend) simulateLocked(opening []client.Move, black, white *heuristicConfig) client.SimulateResult {
+	m.settings = client.GameSettings{Mode: "ai_vs_ai", HumanPlayer: 1, BlackHeuristics: black, WhiteHeuristics: white}
+	m.reset("running")
+	for _, move := range opening {
+		m.placeLocked(move.X, move.Y, false, 0)
+	}
+	m.playOutLocked()
+	return client.SimulateResult{
+		Status: m.status,
+		Winner: m.winner,
+		Moves:  append([]client.HistoryEntry(nil), m.history...),
+	}
+}
+
+func (m *mockBackend) reset(status string) {
+	m.status = status
+	m.winner = 0
+	m.history = nil
+	m.occupied = map[[2]int]bool{}
+	m.start = nil
+	m.gameID++
+}
+
+func (m *mockBackend) placeLocked(x, y int, isAi bool, elapsedMs float64) bool {
+	key := [2]int{x, y}
+	if x < 0 || y < 0 || x >= mockBoardSize || y >= mockBoardSize || m.occupied[key] {
+		return false
+	}
+	m.occupied[key] = true
+	m.history = append(m.history, client.HistoryEntry{
+		X:         x,
+		Y:         y,
+		Player:    len(m.history)%2 + 1,
+		ElapsedMs: elapsedMs,
+		IsAi:      isAi,
+		Changes:   []client.CellChange{},
+	})
+	return true
+}
+
+func (m *mockBackend) playOutLocked() {
+	if m.status != "running" || m.settings.Mode != "ai_vs_ai" {
+		return
+	}
+	black := defaultHeuristics()
+	if m.settings.BlackHeuristics != nil {
+		black = *m.settings.BlackHeuristics
+	}
+	white := defaultHeuristics()
+	if m.settings.WhiteHeuristics != nil {
+		white = *m.settings.WhiteHeuristics
+	}
+	diff := m.strength(black) - m.strength(white)
+	pBlack := 1 / (1 + math.Exp(-(diff+0.1)*2))
+	budget := 500.0
+	if v, ok := m.config["ai_time_budget_ms"].(float64); ok && v > 0 {
+		budget = v
+	}
+	plies := 9 + m.rng.Intn(40)
+	for len(m.history) < plies {
+		elapsed := budget * (0.2 + m.rng.Float64()*0.8)
+		m.placeLocked(m.rng.Intn(mockBoardSize), m.rng.Intn(mockBoardSize), true, elapsed)
+	}
+	roll := m.rng.Float64()
+	switch {
+	case roll < 0.03:
+		m.status = "draw"
+	case roll < 0.03+0.97*pBlack:
+		m.status = "black_won"
+		m.winner = 1
+	default:
+		m.status = "white_won"
+		m.winner = 2
+	}
+}
+
+func (m *mockBackend) strength(h heuristicConfig) float64 {
+	target := m.target
+	targetWeights := heuristicWeights(&target)
+	total := 0.0
+	for i, weight := range heuristicWeights(&h) {
+		if *weight <= 0 || *targetWeights[i] <= 0 {
+			total += 4
+			continue
+		}
+		d := math.Log(*weight / *targetWeights[i])
+		total += d * d
+	}
+	return -total
+}
+
+func (m *mockBackend) snapshot() map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	history := make([]client.HistoryEntry, len(m.history))
+	copy(history, m.history)
+	return map[string]any{
+		"settings":       m.settings,
+		"config":         m.config,
+		"next_player":    len(m.history)%2 + 1,
+		"winner":         m.winner,
+		"board_size":     mockBoardSize,
+		"status":         m.status,
+		"history":        history,
+		"start_position": m.start,
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	notifyGenerationComplete = "generation_complete"
+	notifyChampionPromoted   = "champion_promoted"
+)
+
+var defaultNotifyTemplates = map[string]string{
+	notifyGenerationComplete: "Trainer generation {generation} complete: {games} games, champion {champion} retained.",
+	notifyChampionPromoted:   "Trainer generation {generation}: new champion {champion} promoted (validation {validation_rate}).",
+}
+
+type webhookNotifier struct {
+	url       string
+	templates map[string]string
+	client    *http.Client
+	logf      func(format string, args ...any)
+}
+
+func newWebhookNotifier(url string, templates map[string]string, logf func(format string, args ...any)) *webhookNotifier {
+	merged := make(map[string]string, len(defaultNotifyTemplates))
+	for event, template := range defaultNotifyTemplates {
+		merged[event] = template
+	}
+	for event, template := range templates {
+		if strings.TrimSpace(template) != "" {
+			merged[event] = template
+		}
+	}
+	return &webhookNotifier{
+		url:       strings.TrimSpace(url),
+		templates: merged,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logf:      logf,
+	}
+}
+
+func (n *webhookNotifier) notify(event string, fields map[string]string) {
+	if n == nil || n.url == "" {
+		return
+	}
+	go n.send(event, fields)
+}
+
+func (n *webhookNotifier) render(event string, fields map[string]string) string {
+	template, ok := n.templates[event]
+	if !ok {
+		template = event
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys)*2+2)
+	pairs = append(pairs, "{event}", event)
+	for _, key := range keys {
+		pairs = append(pairs, "{"+key+"}", fields[key])
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func (n *webhookNotifier) send(event string, fields map[string]string) {
+	message := n.render(event, fields)
+	payload := map[string]string{"text": message}
+	if strings.Contains(n.url, "discord") {
+		payload = map[string]string{"content": message}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		n.logf("%s webhook failed: %v", event, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		n.logf("%s webhook returned %d", event, resp.StatusCode)
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource returns the API token sent as "Authorization: Bearer". It is
+// called for every request with refresh false, so it should cache the token,
+// and with refresh true after the backend answered 401 to it.
+type TokenSource func(refresh bool) (string, error)
+
+// TokenError is a failure to get the API token, or to refresh it after a
+// 401; the request was not sent, or not sent again.
+type TokenError struct {
+	Err error
+}
+
+func (e *TokenError) Error() string {
+	return "api token: " + e.Err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// WithTokenSource authenticates every request with the token of source. A
+// request refused with 401 is sent once more when the refreshed token
+// differs.
+func WithTokenSource(source TokenSource) Option {
+	return func(c *Client) {
+		c.token = source
+	}
+}
+
+// WithAuthHook calls hook with the error of every request the backend
+// refused with 401 or 403, or that had no token, and with nil after every
+// request it accepted, so callers can report the token state.
+func WithAuthHook(hook func(error)) Option {
+	return func(c *Client) {
+		c.authHook = hook
+	}
+}
+
+// StaticToken always sends token; it returns nil for an empty token.
+func StaticToken(token string) TokenSource {
+	if token == "" {
+		return nil
+	}
+	return func(bool) (string, error) { return token, nil }
+}
+
+// FileToken reads the token from path, such as a mounted secret, and reads
+// it again when the file changes or the backend refused it, so rotated
+// secrets are picked up without a restart.
+func FileToken(path string) TokenSource {
+	var (
+		mu      sync.Mutex
+		token   string
+		modTime time.Time
+	)
+	return func(refresh bool) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if token != "" && !refresh && info.ModTime().Equal(modTime) {
+			return token, nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return "", fmt.Errorf("%s is empty", path)
+		}
+		token, modTime = value, info.ModTime()
+		return token, nil
+	}
+}
+
+// IsAuthError reports whether err is a 401 or 403 from the backend, or a
+// request that could not get its token.
+func IsAuthError(err error) bool {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return true
+	}
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && (statusErr.Code == http.StatusUnauthorized || statusErr.Code == http.StatusForbidden)
+}
+
+// send does req with the API token, and once more with the refreshed token
+// when the backend answers 401 to the one it had. A refresh that fails, or a
+// body that cannot be sent again, is a TokenError.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.token == nil {
+		return c.http.Do(req)
+	}
+	token, err := c.token(false)
+	if err != nil {
+		return nil, &TokenError{Err: err}
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.http.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	fresh, err := c.token(true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, &TokenError{Err: err}
+	}
+	if fresh == token || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			resp.Body.Close()
+			return nil, &TokenError{Err: err}
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	retry.Header.Set("Authorization", "Bearer "+fresh)
+	return c.http.Do(retry)
+}
+
+// noteAuth reports the outcome of a request to the auth hook. Network errors
+// say nothing about the token.
+func (c *Client) noteAuth(err error) {
+	if c.authHook == nil {
+		return
+	}
+	var statusErr *StatusError
+	switch {
+	case IsAuthError(err):
+		c.authHook(err)
+	case err == nil || errors.As(err, &statusErr):
+		c.authHook(nil)
+	}
+}
@@ -0,0 +1,485 @@
+// Package client is a typed HTTP client for the gomoku backend API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	retries    int
+	retryDelay time.Duration
+	token      TokenSource
+	authHook   func(error)
+}
+
+type Option func(*Client)
+
+// StatusError is a non-200 answer. When the body is a structured API error,
+// ErrorCode, Message and Details hold its code, error and details fields.
+type StatusError struct {
+	Method    string
+	Path      string
+	Code      int
+	Body      string
+	ErrorCode string
+	Message   string
+	Details   []ErrorDetail
+}
+
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s -> %d: %s", e.Method, e.Path, e.Code, e.Body)
+}
+
+// ErrorCode returns the API error code carried by err, or "" when err is not
+// a structured API error.
+func ErrorCode(err error) string {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.ErrorCode
+	}
+	return ""
+}
+
+// IsMissingEndpoint reports whether err is a 404 or 405 from the router
+// rather than from a handler, i.e. the backend does not have the endpoint.
+func IsMissingEndpoint(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.ErrorCode != "" {
+		return false
+	}
+	return statusErr.Code == http.StatusNotFound || statusErr.Code == http.StatusMethodNotAllowed
+}
+
+func newStatusError(method, path string, code int, body []byte) *StatusError {
+	statusErr := &StatusError{Method: method, Path: path, Code: code, Body: string(body)}
+	var apiErr struct {
+		Code    string        `json:"code"`
+		Error   string        `json:"error"`
+		Details []ErrorDetail `json:"details"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil {
+		statusErr.ErrorCode = apiErr.Code
+		statusErr.Message = apiErr.Error
+		statusErr.Details = apiErr.Details
+	}
+	return statusErr
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: 10 * time.Second},
+		retries:    2,
+		retryDelay: 250 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.http = httpClient
+		}
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.http.Timeout = timeout
+	}
+}
+
+// WithTLSConfig calls an HTTPS backend with config: the CA of its
+// certificate and, when it requires one, a client certificate. HTTP/2 is
+// used when the backend offers it.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		if config == nil {
+			return
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		c.http.Transport = transport
+	}
+}
+
+// WithRetries sends a GET or HEAD request up to retries more times after a
+// network error or a 5xx, waiting delay longer before each. Other methods
+// are never retried: a lost answer to a move or a start must not replay it.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		if retries < 0 {
+			retries = 0
+		}
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/api/ping", nil, nil)
+}
+
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodGet, "/api/status", nil, &status)
+	return status, err
+}
+
+// HistorySince returns the history entries after index since. Pass the
+// GameID of the previous answer (0 when unknown) so a restarted game comes
+// back as a full Reset instead of a bogus tail.
+func (c *Client) HistorySince(ctx context.Context, since int, gameID uint64) (HistoryDiff, error) {
+	var diff HistoryDiff
+	path := fmt.Sprintf("/api/history?since=%d", since)
+	if gameID > 0 {
+		path += fmt.Sprintf("&game_id=%d", gameID)
+	}
+	err := c.do(ctx, http.MethodGet, path, nil, &diff)
+	return diff, err
+}
+
+func (c *Client) Start(ctx context.Context, settings GameSettings) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodPost, "/api/start", map[string]any{"settings": settings}, &status)
+	return status, err
+}
+
+// StartFromPosition starts a game from an arbitrary position in one call.
+// Backends without seeding support ignore the position and start from an
+// empty board; their Status has no StartPosition.
+func (c *Client) StartFromPosition(ctx context.Context, settings GameSettings, position StartPosition) (Status, error) {
+	var status Status
+	payload := struct {
+		Settings GameSettings `json:"settings"`
+		StartPosition
+	}{Settings: settings, StartPosition: position}
+	err := c.do(ctx, http.MethodPost, "/api/start", payload, &status)
+	return status, err
+}
+
+func (c *Client) Stop(ctx context.Context) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodPost, "/api/stop", map[string]any{}, &status)
+	return status, err
+}
+
+func (c *Client) Move(ctx context.Context, x, y int) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodPost, "/api/move", map[string]int{"x": x, "y": y}, &status)
+	return status, err
+}
+
+func (c *Client) UpdateSettings(ctx context.Context, update SettingsUpdate) (Status, error) {
+	var status Status
+	err := c.do(ctx, http.MethodPost, "/api/settings", update, &status)
+	return status, err
+}
+
+func (c *Client) Analyse(ctx context.Context, req AnalyseRequest) (Analysis, error) {
+	var analysis Analysis
+	err := c.do(ctx, http.MethodPost, "/api/analyse", req, &analysis)
+	return analysis, err
+}
+
+// Simulate plays a whole AI-vs-AI game server-side and returns once it is
+// over. Games can take minutes, so build the client WithTimeout accordingly.
+func (c *Client) Simulate(ctx context.Context, req SimulateRequest) (SimulateResult, error) {
+	var result SimulateResult
+	err := c.do(ctx, http.MethodPost, "/api/simulate", req, &result)
+	return result, err
+}
+
+// SimulateBatch runs a batch of games server-side, calling onGame for each
+// NDJSON game line as it streams in. It is never retried, since games already
+// reported would be played again.
+func (c *Client) SimulateBatch(ctx context.Context, req SimulateBatchRequest, onGame func(SimulateBatchGame)) (SimulateBatchSummary, error) {
+	var summary SimulateBatchSummary
+	body, err := json.Marshal(req)
+	if err != nil {
+		return summary, err
+	}
+	const path = "/api/simulate/batch"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return summary, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.send(httpReq)
+	if err != nil {
+		c.noteAuth(err)
+		return summary, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := newStatusError(http.MethodPost, path, resp.StatusCode, respBody)
+		c.noteAuth(err)
+		return summary, err
+	}
+	c.noteAuth(nil)
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line struct {
+			Type    string                `json:"type"`
+			Game    *SimulateBatchGame    `json:"game"`
+			Summary *SimulateBatchSummary `json:"summary"`
+		}
+		if err := decoder.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return summary, io.ErrUnexpectedEOF
+			}
+			return summary, err
+		}
+		switch line.Type {
+		case "game":
+			if line.Game != nil && onGame != nil {
+				onGame(*line.Game)
+			}
+		case "summary":
+			if line.Summary != nil {
+				summary = *line.Summary
+			}
+			return summary, nil
+		}
+	}
+}
+
+// Duel compares two heuristic configs server-side with alternating colours.
+// Like Simulate it blocks for the whole match.
+func (c *Client) Duel(ctx context.Context, req DuelRequest) (DuelResult, error) {
+	var result DuelResult
+	err := c.do(ctx, http.MethodPost, "/api/duel", req, &result)
+	return result, err
+}
+
+// Annotations lists the comments, arrows and marks attached to a game.
+func (c *Client) Annotations(ctx context.Context, gameID uint64) ([]Annotation, error) {
+	var payload GameAnnotations
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/games/%d/annotations", gameID), nil, &payload)
+	return payload.Annotations, err
+}
+
+// Annotate attaches an annotation to a ply of a game and returns the game's
+// annotations, ordered by ply.
+func (c *Client) Annotate(ctx context.Context, gameID uint64, annotation Annotation) ([]Annotation, error) {
+	var payload GameAnnotations
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/games/%d/annotations", gameID), annotation, &payload)
+	return payload.Annotations, err
+}
+
+// ImportReview queues a review of a game from another source.
+func (c *Client) ImportReview(ctx context.Context, req ReviewRequest) (Review, error) {
+	var review Review
+	err := c.do(ctx, http.MethodPost, "/api/review/import", req, &review)
+	return review, err
+}
+
+// ReviewGame queues a review of a finished game kept by the backend.
+func (c *Client) ReviewGame(ctx context.Context, gameID uint64, depth, timeoutMs int) (Review, error) {
+	payload := map[string]int{"depth": depth, "timeout_ms": timeoutMs}
+	var review Review
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/games/%d/review", gameID), payload, &review)
+	return review, err
+}
+
+func (c *Client) Review(ctx context.Context, id uint64) (Review, error) {
+	var review Review
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/review/%d", id), nil, &review)
+	return review, err
+}
+
+// GameReport fetches the engine statistics of a finished game: per-move
+// search cost, cache hit rates, the evaluation trajectory and blunders.
+func (c *Client) GameReport(ctx context.Context, gameID uint64) (GameReport, error) {
+	var report GameReport
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/games/%d/report", gameID), nil, &report)
+	return report, err
+}
+
+// Render fetches the current game drawn as SVG or PNG and returns the raw
+// image bytes.
+func (c *Client) Render(ctx context.Context, opts RenderOptions) ([]byte, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Ply != nil {
+		query.Set("ply", strconv.Itoa(*opts.Ply))
+	}
+	if opts.CellSize > 0 {
+		query.Set("cell", strconv.Itoa(opts.CellSize))
+	}
+	if opts.HideNumbers {
+		query.Set("numbers", "false")
+	}
+	path := "/api/render"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var image []byte
+	err := c.do(ctx, http.MethodGet, path, nil, &image)
+	return image, err
+}
+
+func (c *Client) Heuristics(ctx context.Context) (HeuristicConfig, error) {
+	active, err := c.ActiveHeuristics(ctx)
+	return active.Heuristics, err
+}
+
+// ActiveHeuristics returns the backend's live weights with their heuristic
+// hash.
+func (c *Client) ActiveHeuristics(ctx context.Context) (ActiveHeuristics, error) {
+	var active ActiveHeuristics
+	err := c.do(ctx, http.MethodGet, "/api/heuristics", nil, &active)
+	return active, err
+}
+
+// PromoteHeuristics makes heuristics the backend's live weight set.
+func (c *Client) PromoteHeuristics(ctx context.Context, req PromoteHeuristicsRequest) (HeuristicPromotion, error) {
+	var promotion HeuristicPromotion
+	err := c.do(ctx, http.MethodPost, "/api/admin/heuristics/promote", req, &promotion)
+	return promotion, err
+}
+
+// RegisterHeuristics records heuristics in the backend's registry.
+func (c *Client) RegisterHeuristics(ctx context.Context, req RegisterHeuristicsRequest) (HeuristicRecord, error) {
+	var record HeuristicRecord
+	err := c.do(ctx, http.MethodPost, "/api/heuristics/registry", req, &record)
+	return record, err
+}
+
+func (c *Client) AnalyticsQueue(ctx context.Context) (AnalyticsQueue, error) {
+	var queue AnalyticsQueue
+	err := c.do(ctx, http.MethodGet, "/api/analitics/queue", nil, &queue)
+	return queue, err
+}
+
+func (c *Client) TTCacheStatus(ctx context.Context) (TTCacheStatus, error) {
+	var status TTCacheStatus
+	err := c.do(ctx, http.MethodGet, "/api/cache/tt", nil, &status)
+	return status, err
+}
+
+func (c *Client) TTCacheEntries(ctx context.Context, offset, limit int) (TTCacheEntries, error) {
+	var entries TTCacheEntries
+	path := fmt.Sprintf("/api/cache/tt/entries?offset=%d&limit=%d", offset, limit)
+	err := c.do(ctx, http.MethodGet, path, nil, &entries)
+	return entries, err
+}
+
+func (c *Client) FlushTTCache(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/cache/tt", nil, nil)
+}
+
+func (c *Client) DeleteTTCacheEntry(ctx context.Context, hash string) (bool, error) {
+	var payload struct {
+		Deleted bool `json:"deleted"`
+	}
+	err := c.do(ctx, http.MethodDelete, "/api/cache/tt/entries/"+hash, nil, &payload)
+	return payload.Deleted, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, payload any, out any) error {
+	var body []byte
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = raw
+	}
+	retries := c.retries
+	if method != http.MethodGet && method != http.MethodHead {
+		retries = 0
+	}
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 && !sleepWithContext(ctx, c.retryDelay*time.Duration(attempt)) {
+			return ctx.Err()
+		}
+		retry, err := c.once(ctx, method, path, body, out)
+		if err == nil {
+			c.noteAuth(nil)
+			return nil
+		}
+		lastErr = err
+		if !retry || ctx.Err() != nil {
+			break
+		}
+	}
+	c.noteAuth(lastErr)
+	return lastErr
+}
+
+func (c *Client) once(ctx context.Context, method, path string, body []byte, out any) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		var tokenErr *TokenError
+		return !errors.Is(err, context.Canceled) && !errors.As(err, &tokenErr), err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		statusErr := newStatusError(method, path, resp.StatusCode, respBody)
+		return resp.StatusCode >= http.StatusInternalServerError, statusErr
+	}
+	if out == nil {
+		return false, nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = io.ReadAll(resp.Body)
+		return false, err
+	}
+	return false, json.NewDecoder(resp.Body).Decode(out)
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
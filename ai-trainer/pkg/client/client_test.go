@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"running","board_size":19}`))
+	}))
+	defer server.Close()
+
+	api := New(server.URL, WithRetries(2, time.Millisecond))
+	status, err := api.Status(context.Background())
+	if err != nil {
+		t.Fatalf("expected status after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+	if !status.Running() || status.BoardSize != 19 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestClientDoesNotRetryPosts(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	api := New(server.URL, WithRetries(2, time.Millisecond))
+	if _, err := api.Move(context.Background(), 9, 9); err == nil {
+		t.Fatalf("expected the move to fail")
+	}
+	if calls != 1 {
+		t.Fatalf("expected a move to be sent once, got %d calls", calls)
+	}
+}
+
+func TestClientCallsHTTPSBackendOverHTTP2(t *testing.T) {
+	proto := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.ProtoMajor
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"running","board_size":19}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	if _, err := New(server.URL, WithRetries(0, 0)).Status(context.Background()); err == nil {
+		t.Fatalf("expected the self-signed certificate to be refused without its CA")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	api := New(server.URL, WithTLSConfig(&tls.Config{RootCAs: roots}), WithTimeout(5*time.Second))
+	if _, err := api.Status(context.Background()); err != nil {
+		t.Fatalf("expected status over TLS, got %v", err)
+	}
+	if proto != 2 {
+		t.Fatalf("expected HTTP/2, got HTTP/%d", proto)
+	}
+}
+
+func TestClientRefreshesRejectedTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"unauthorized","error":"unauthorized"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"running","board_size":19}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	var authErr error
+	api := New(server.URL, WithTokenSource(FileToken(path)), WithAuthHook(func(err error) { authErr = err }), WithRetries(0, 0))
+	if _, err := api.Status(context.Background()); !IsAuthError(err) || !IsAuthError(authErr) {
+		t.Fatalf("expected a rejected token to be reported, got %v and %v", err, authErr)
+	}
+
+	// The rotated secret keeps its modification time, so only the 401 makes
+	// the client read it again.
+	if err := os.WriteFile(path, []byte("new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Status(context.Background()); err != nil || authErr != nil {
+		t.Fatalf("expected the refreshed token to be accepted, got %v and %v", err, authErr)
+	}
+
+	os.Remove(path)
+	if _, err := New(server.URL, WithTokenSource(FileToken(path))).Status(context.Background()); !IsAuthError(err) {
+		t.Fatalf("expected a missing token file to be an auth error, got %v", err)
+	}
+}
+
+func TestClientReportsFailedTokenRefreshes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	refreshFailed := errors.New("secret unreadable")
+	source := func(refresh bool) (string, error) {
+		if refresh {
+			return "", refreshFailed
+		}
+		return "old", nil
+	}
+	_, err := New(server.URL, WithTokenSource(source), WithRetries(0, 0)).Status(context.Background())
+	var tokenErr *TokenError
+	if !errors.As(err, &tokenErr) || !errors.Is(err, refreshFailed) {
+		t.Fatalf("expected the failed refresh as a token error, got %v", err)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad move", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	api := New(server.URL, WithRetries(3, time.Millisecond))
+	_, err := api.Move(context.Background(), 1, 2)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 status error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single call, got %d", calls)
+	}
+}
+
+func TestClientParsesStructuredErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/move" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"illegal_move","error":"Illegal move: occupied","details":[{"field":"x","message":"occupied"}]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	api := New(server.URL)
+	_, err := api.Move(context.Background(), 1, 2)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || ErrorCode(err) != "illegal_move" || statusErr.Message != "Illegal move: occupied" {
+		t.Fatalf("expected illegal_move error, got %v", err)
+	}
+	if len(statusErr.Details) != 1 || statusErr.Details[0].Field != "x" {
+		t.Fatalf("unexpected details %+v", statusErr.Details)
+	}
+	if IsMissingEndpoint(err) {
+		t.Fatalf("structured error reported as missing endpoint")
+	}
+	if _, err := api.Status(context.Background()); !IsMissingEndpoint(err) {
+		t.Fatalf("expected missing endpoint, got %v", err)
+	}
+}
+
+func TestClientRenderReturnsRawImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/render" || r.URL.Query().Get("format") != "png" || r.URL.Query().Get("ply") != "3" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	}))
+	defer server.Close()
+
+	ply := 3
+	image, err := New(server.URL).Render(context.Background(), RenderOptions{Format: "png", Ply: &ply})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if string(image) != "\x89PNG" {
+		t.Fatalf("unexpected image bytes %q", image)
+	}
+}
+
+func TestHeuristicConfigHashMatchesTheBackend(t *testing.T) {
+	heuristics := HeuristicConfig{
+		Open4: 120000, Closed4: 24000, Broken4: 16000,
+		Open3: 18000, Broken3: 11000, Closed3: 800,
+		Open2: 400, Broken2: 220,
+		ForkOpen3: 40000, ForkFourPlus: 130000,
+		CaptureNow: 2200, CaptureDoubleThreat: 2600, CaptureNearWin: 12000, CaptureInTwo: 700,
+		HangingPair: 2400, CaptureScaleEarly: 1, CaptureScaleMid: 1.25, CaptureScaleLate: 2,
+		CaptureScaleFinal: 0.95, DefensiveCapture: 0.5, CaptureInTwoLimit: 8,
+	}
+	// The backend's heuristic_hash for the same weights.
+	if got := heuristics.Hash(); got != "668551002ec6ed55" {
+		t.Fatalf("hash = %s, want the backend's 668551002ec6ed55", got)
+	}
+}
@@ -0,0 +1,608 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+type GameSettings struct {
+	Mode            string           `json:"mode"`
+	HumanPlayer     int              `json:"human_player"`
+	BlackHeuristics *HeuristicConfig `json:"black_heuristics,omitempty"`
+	WhiteHeuristics *HeuristicConfig `json:"white_heuristics,omitempty"`
+}
+
+type SettingsUpdate struct {
+	Settings *GameSettings  `json:"settings,omitempty"`
+	Config   map[string]any `json:"config,omitempty"`
+}
+
+type Move struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type CellChange struct {
+	X     int `json:"x"`
+	Y     int `json:"y"`
+	Value int `json:"value"`
+}
+
+type HistoryEntry struct {
+	X                 int          `json:"x"`
+	Y                 int          `json:"y"`
+	Player            int          `json:"player"`
+	ElapsedMs         float64      `json:"elapsed_ms"`
+	IsAi              bool         `json:"is_ai"`
+	CapturedCount     int          `json:"captured_count"`
+	CapturedPositions []Move       `json:"captured_positions"`
+	Changes           []CellChange `json:"changes"`
+	Depth             int          `json:"depth"`
+	AutoPlayed        bool         `json:"auto_played,omitempty"`
+}
+
+type Status struct {
+	Settings         GameSettings      `json:"settings"`
+	Config           map[string]any    `json:"config"`
+	NextPlayer       int               `json:"next_player"`
+	Winner           int               `json:"winner"`
+	BoardSize        int               `json:"board_size"`
+	Status           string            `json:"status"`
+	History          []json.RawMessage `json:"history"`
+	WinReason        string            `json:"win_reason"`
+	WinningLine      []Move            `json:"winning_line"`
+	CaptureWinStones int               `json:"capture_win_stones"`
+	TurnStartedAtMs  int64             `json:"turn_started_at_ms"`
+	StartPosition    *StartPosition    `json:"start_position,omitempty"`
+	// MustCapture is set when the side to move may only play one of
+	// ForcedCaptureMoves, to break PendingAlignment.
+	MustCapture            bool           `json:"must_capture"`
+	ForcedCaptureMoves     []Move         `json:"forced_capture_moves"`
+	PendingAlignment       []Move         `json:"pending_alignment"`
+	PendingAlignmentPlayer int            `json:"pending_alignment_player"`
+	WinConditions          *WinConditions `json:"win_conditions,omitempty"`
+	PendingWin             *PendingWin    `json:"pending_win,omitempty"`
+	GameID                 uint64         `json:"game_id"`
+	Hash                   string         `json:"hash"`
+	// HeuristicHash identifies the live weight set; a side playing with its
+	// own weights has its hash in BlackHeuristicHash or WhiteHeuristicHash.
+	HeuristicHash      string `json:"heuristic_hash"`
+	BlackHeuristicHash string `json:"black_heuristic_hash,omitempty"`
+	WhiteHeuristicHash string `json:"white_heuristic_hash,omitempty"`
+	// Seed drives the game's random choices; passing it back to /api/start
+	// replays them.
+	Seed int64 `json:"seed"`
+}
+
+// HistoryDiff is the answer to GET /api/history: entries after Since, or the
+// whole history with Reset set when the client was following another game.
+type HistoryDiff struct {
+	GameID          uint64            `json:"game_id"`
+	Since           int               `json:"since"`
+	Total           int               `json:"total"`
+	Reset           bool              `json:"reset"`
+	Entries         []json.RawMessage `json:"entries"`
+	Hash            string            `json:"hash"`
+	Status          string            `json:"status"`
+	NextPlayer      int               `json:"next_player"`
+	Winner          int               `json:"winner"`
+	WinReason       string            `json:"win_reason"`
+	WinningLine     []Move            `json:"winning_line"`
+	PendingWin      *PendingWin       `json:"pending_win,omitempty"`
+	TurnStartedAtMs int64             `json:"turn_started_at_ms"`
+}
+
+type WinConditions struct {
+	CapturedBlack          int    `json:"captured_black"`
+	CapturedWhite          int    `json:"captured_white"`
+	CaptureWinStones       int    `json:"capture_win_stones"`
+	BlackCapturesToWin     int    `json:"black_captures_to_win"`
+	WhiteCapturesToWin     int    `json:"white_captures_to_win"`
+	PendingAlignmentPlayer int    `json:"pending_alignment_player"`
+	PendingAlignment       []Move `json:"pending_alignment"`
+	BreakingCaptures       []Move `json:"breaking_captures"`
+	Summary                string `json:"summary"`
+}
+
+// PendingWin is a five that wins unless the side to move breaks it with one
+// of Breaks.
+type PendingWin struct {
+	Player         int            `json:"player"`
+	Line           []Move         `json:"line"`
+	BreakingPlayer int            `json:"breaking_player"`
+	Breaks         []PendingBreak `json:"breaks"`
+	TurnsRemaining int            `json:"turns_remaining"`
+	Explanation    string         `json:"explanation"`
+}
+
+type PendingBreak struct {
+	Move     Move   `json:"move"`
+	Captures []Move `json:"captures"`
+}
+
+// StartPosition is a position a game is seeded from. Board rows are indexed
+// [y][x] with 0 empty, 1 black, 2 white.
+type StartPosition struct {
+	Board         [][]int `json:"board"`
+	NextPlayer    int     `json:"next_player"`
+	CapturedBlack int     `json:"captured_black"`
+	CapturedWhite int     `json:"captured_white"`
+}
+
+func (s Status) Running() bool {
+	return s.Status == "running"
+}
+
+func (s Status) Moves() ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0, len(s.History))
+	for _, raw := range s.History {
+		var entry HistoryEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+type AnalyseRequest struct {
+	Moves     []Move `json:"moves"`
+	Depth     int    `json:"depth,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+	MaxNodes  int64  `json:"max_nodes,omitempty"`
+}
+
+type Analysis struct {
+	BestMove   Move    `json:"best_move"`
+	Score      float64 `json:"score"`
+	Depth      int     `json:"depth"`
+	Nodes      int64   `json:"nodes"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+	NextPlayer int     `json:"next_player"`
+	Status     string  `json:"status"`
+	Board      [][]int `json:"board"`
+	// Resources is nil when the position needed no search.
+	Resources *SearchResources `json:"resources,omitempty"`
+}
+
+// SearchResources is what the backend process used during an analysis.
+type SearchResources struct {
+	AllocBytes      uint64  `json:"alloc_bytes"`
+	Mallocs         uint64  `json:"mallocs"`
+	PeakHeapBytes   uint64  `json:"peak_heap_bytes"`
+	GoroutinesStart int     `json:"goroutines_start"`
+	GoroutinesEnd   int     `json:"goroutines_end"`
+	GCCycles        uint32  `json:"gc_cycles"`
+	GCPauseMs       float64 `json:"gc_pause_ms"`
+}
+
+// SearchParams overrides search knobs for one side of a simulated game,
+// keyed by backend config key (e.g. "ai_max_candidates_root"). Integer knobs
+// must hold whole numbers.
+type SearchParams map[string]float64
+
+type SimulateRequest struct {
+	Opening         []Move           `json:"opening"`
+	BlackHeuristics *HeuristicConfig `json:"black_heuristics,omitempty"`
+	WhiteHeuristics *HeuristicConfig `json:"white_heuristics,omitempty"`
+	BlackSearch     SearchParams     `json:"black_search,omitempty"`
+	WhiteSearch     SearchParams     `json:"white_search,omitempty"`
+	MoveBudgetMs    int              `json:"move_budget_ms,omitempty"`
+	Depth           int              `json:"depth,omitempty"`
+	MaxMoves        int              `json:"max_moves,omitempty"`
+	MaxNodes        int64            `json:"max_nodes,omitempty"`
+}
+
+type SimulateResult struct {
+	Status             string         `json:"status"`
+	Winner             int            `json:"winner"`
+	Moves              []HistoryEntry `json:"moves"`
+	WinningLine        []Move         `json:"winning_line"`
+	WinningCapturePair []Move         `json:"winning_capture_pair"`
+	CapturedBlack      int            `json:"captured_black"`
+	CapturedWhite      int            `json:"captured_white"`
+	ElapsedMs          float64        `json:"elapsed_ms"`
+}
+
+type SimulateBatchRequest struct {
+	Games           int              `json:"games,omitempty"`
+	Openings        [][]Move         `json:"openings"`
+	BlackHeuristics *HeuristicConfig `json:"black_heuristics,omitempty"`
+	WhiteHeuristics *HeuristicConfig `json:"white_heuristics,omitempty"`
+	BlackSearch     SearchParams     `json:"black_search,omitempty"`
+	WhiteSearch     SearchParams     `json:"white_search,omitempty"`
+	AlternateColors bool             `json:"alternate_colors"`
+	MoveBudgetMs    int              `json:"move_budget_ms,omitempty"`
+	Depth           int              `json:"depth,omitempty"`
+	MaxMoves        int              `json:"max_moves,omitempty"`
+	MaxNodes        int64            `json:"max_nodes,omitempty"`
+	Workers         int              `json:"workers,omitempty"`
+	DisableCache    bool             `json:"disable_cache,omitempty"`
+}
+
+type SimulateBatchGame struct {
+	Index        int            `json:"index"`
+	OpeningIndex int            `json:"opening_index"`
+	Swapped      bool           `json:"swapped"`
+	Score        float64        `json:"score"`
+	Result       SimulateResult `json:"result"`
+	Error        string         `json:"error,omitempty"`
+}
+
+type SimulateBatchSummary struct {
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Draws      int     `json:"draws"`
+	Losses     int     `json:"losses"`
+	Unfinished int     `json:"unfinished"`
+	Errors     int     `json:"errors"`
+	BlackWins  int     `json:"black_wins"`
+	WhiteWins  int     `json:"white_wins"`
+	Score      float64 `json:"score"`
+	ScoreRate  float64 `json:"score_rate"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+}
+
+type DuelRequest struct {
+	A            *HeuristicConfig `json:"a,omitempty"`
+	B            *HeuristicConfig `json:"b,omitempty"`
+	Openings     [][]Move         `json:"openings,omitempty"`
+	OpeningCount int              `json:"opening_count,omitempty"`
+	OpeningPlies int              `json:"opening_plies,omitempty"`
+	Seed         int64            `json:"seed,omitempty"`
+	Games        int              `json:"games,omitempty"`
+	MoveBudgetMs int              `json:"move_budget_ms,omitempty"`
+	Depth        int              `json:"depth,omitempty"`
+	MaxMoves     int              `json:"max_moves,omitempty"`
+	Workers      int              `json:"workers,omitempty"`
+}
+
+type DuelGame struct {
+	Index        int     `json:"index"`
+	OpeningIndex int     `json:"opening_index"`
+	AColor       int     `json:"a_color"`
+	Status       string  `json:"status"`
+	Winner       int     `json:"winner"`
+	Score        float64 `json:"score"`
+	Plies        int     `json:"plies"`
+	ElapsedMs    float64 `json:"elapsed_ms"`
+	Error        string  `json:"error,omitempty"`
+}
+
+type DuelResult struct {
+	Games      int        `json:"games"`
+	Wins       int        `json:"wins"`
+	Draws      int        `json:"draws"`
+	Losses     int        `json:"losses"`
+	Unfinished int        `json:"unfinished"`
+	Errors     int        `json:"errors"`
+	Score      float64    `json:"score"`
+	ScoreRate  float64    `json:"score_rate"`
+	Elo        float64    `json:"elo"`
+	EloLow     float64    `json:"elo_low"`
+	EloHigh    float64    `json:"elo_high"`
+	Openings   [][]Move   `json:"openings"`
+	Records    []DuelGame `json:"records"`
+	ElapsedMs  float64    `json:"elapsed_ms"`
+}
+
+type AnalyticsQueueEntry struct {
+	ID           string  `json:"id"`
+	Board        [][]int `json:"board"`
+	CurrentDepth int     `json:"current_depth"`
+	TargetDepth  int     `json:"target_depth"`
+	Hits         int     `json:"hits"`
+	Frequency    int     `json:"frequency"`
+	Variants     int     `json:"variants"`
+	Analyzing    bool    `json:"analyzing"`
+}
+
+type AnalyticsQueue struct {
+	Queue        []AnalyticsQueueEntry `json:"queue"`
+	TotalInQueue int                   `json:"total_in_queue"`
+}
+
+type TTCacheStatus struct {
+	Count          int     `json:"count"`
+	Capacity       int     `json:"capacity"`
+	Usage          float64 `json:"usage"`
+	Full           bool    `json:"full"`
+	UsedBytes      uint64  `json:"used_bytes"`
+	CapacityBytes  uint64  `json:"capacity_bytes"`
+	MaxMemoryBytes uint64  `json:"max_memory_bytes"`
+	MemoryUsage    float64 `json:"memory_usage"`
+	// HeuristicHash is the live weight set's; HeuristicHashes counts the
+	// entries stored under each weight set.
+	HeuristicHash   string               `json:"heuristic_hash"`
+	HeuristicHashes []HeuristicHashCount `json:"heuristic_hashes"`
+}
+
+type HeuristicHashCount struct {
+	Hash    string `json:"hash"`
+	Entries int    `json:"entries"`
+	Active  bool   `json:"active"`
+}
+
+type TTCacheEntry struct {
+	Hash          string `json:"hash"`
+	HeuristicHash string `json:"heuristic_hash"`
+	Hits          uint32 `json:"hits"`
+	Depth         int    `json:"depth"`
+	Score         int32  `json:"score"`
+	Flag          string `json:"flag"`
+	BestMove      Move   `json:"best_move"`
+}
+
+type TTCacheEntries struct {
+	Items  []TTCacheEntry `json:"items"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
+	Total  int            `json:"total"`
+}
+
+// Annotation attaches a comment, arrows and marks to one ply of a game: ply
+// 0 is the start position and ply N the position after move N. Mark shapes
+// are circle, square, triangle, cross and label.
+type Annotation struct {
+	Ply         int               `json:"ply"`
+	Author      string            `json:"author,omitempty"`
+	Comment     string            `json:"comment,omitempty"`
+	Arrows      []AnnotationArrow `json:"arrows,omitempty"`
+	Marks       []AnnotationMark  `json:"marks,omitempty"`
+	CreatedAtMs int64             `json:"created_at_ms,omitempty"`
+}
+
+type AnnotationArrow struct {
+	From Move `json:"from"`
+	To   Move `json:"to"`
+}
+
+type AnnotationMark struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Shape string `json:"shape"`
+	Label string `json:"label,omitempty"`
+}
+
+type GameAnnotations struct {
+	GameID      uint64       `json:"game_id"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// ReviewRequest imports a game from another source for review: either an
+// SGF record or a move list on top of an optional start position.
+type ReviewRequest struct {
+	SGF       string         `json:"sgf,omitempty"`
+	Moves     []Move         `json:"moves,omitempty"`
+	Position  *StartPosition `json:"position,omitempty"`
+	BoardSize int            `json:"board_size,omitempty"`
+	Depth     int            `json:"depth"`
+	TimeoutMs int            `json:"timeout_ms"`
+	Source    string         `json:"source,omitempty"`
+}
+
+// ReviewMove grades one move; scores and win probabilities are from the
+// mover's side.
+type ReviewMove struct {
+	Ply           int     `json:"ply"`
+	Player        int     `json:"player"`
+	Move          Move    `json:"move"`
+	BestMove      Move    `json:"best_move"`
+	Score         float64 `json:"score"`
+	PlayedScore   float64 `json:"played_score"`
+	WinProb       float64 `json:"win_prob"`
+	PlayedWinProb float64 `json:"played_win_prob"`
+	Loss          float64 `json:"loss"`
+	Class         string  `json:"class"`
+	Depth         int     `json:"depth"`
+}
+
+type ReviewSideSummary struct {
+	Moves        int     `json:"moves"`
+	Best         int     `json:"best"`
+	Good         int     `json:"good"`
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+	AverageLoss  float64 `json:"average_loss"`
+	Accuracy     float64 `json:"accuracy"`
+}
+
+type Review struct {
+	ID           uint64            `json:"id"`
+	GameID       uint64            `json:"game_id,omitempty"`
+	Source       string            `json:"source"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	BoardSize    int               `json:"board_size"`
+	TotalMoves   int               `json:"total_moves"`
+	Moves        []ReviewMove      `json:"moves"`
+	Black        ReviewSideSummary `json:"black"`
+	White        ReviewSideSummary `json:"white"`
+	CreatedAtMs  int64             `json:"created_at_ms"`
+	FinishedAtMs int64             `json:"finished_at_ms,omitempty"`
+}
+
+func (r Review) Finished() bool {
+	return r.Status == "done" || r.Status == "failed"
+}
+
+type MoveSearchStats struct {
+	Nodes           int64 `json:"nodes"`
+	TTProbes        int64 `json:"tt_probes"`
+	TTHits          int64 `json:"tt_hits"`
+	EvalCacheProbes int64 `json:"eval_cache_probes"`
+	EvalCacheHits   int64 `json:"eval_cache_hits"`
+}
+
+// GameReportMove is one ply of a game report. WinProb is black's chance of
+// winning after the move, when it was valued.
+type GameReportMove struct {
+	Ply       int              `json:"ply"`
+	Player    int              `json:"player"`
+	Move      Move             `json:"move"`
+	IsAi      bool             `json:"is_ai"`
+	Depth     int              `json:"depth,omitempty"`
+	ElapsedMs float64          `json:"elapsed_ms"`
+	ComputeMs float64          `json:"compute_ms"`
+	Captures  int              `json:"captures,omitempty"`
+	Search    *MoveSearchStats `json:"search,omitempty"`
+	WinProb   *float64         `json:"win_prob,omitempty"`
+	Class     string           `json:"class,omitempty"`
+	Blunder   bool             `json:"blunder,omitempty"`
+}
+
+type GameReportSide struct {
+	Player         int      `json:"player"`
+	Type           string   `json:"type"`
+	Moves          int      `json:"moves"`
+	TimeMs         float64  `json:"time_ms"`
+	ComputeMs      float64  `json:"compute_ms"`
+	AverageDepth   float64  `json:"average_depth"`
+	Nodes          int64    `json:"nodes"`
+	NodesPerSecond float64  `json:"nodes_per_second"`
+	Captures       int      `json:"captures"`
+	Blunders       int      `json:"blunders"`
+	Accuracy       *float64 `json:"accuracy,omitempty"`
+}
+
+type GameReportCache struct {
+	TTProbes         int64   `json:"tt_probes"`
+	TTHits           int64   `json:"tt_hits"`
+	TTHitRate        float64 `json:"tt_hit_rate"`
+	EvalCacheProbes  int64   `json:"eval_cache_probes"`
+	EvalCacheHits    int64   `json:"eval_cache_hits"`
+	EvalCacheHitRate float64 `json:"eval_cache_hit_rate"`
+}
+
+type GameReportPoint struct {
+	Ply     int     `json:"ply"`
+	WinProb float64 `json:"win_prob"`
+}
+
+// GameReport is GET /api/games/{id}/report: the engine statistics of one
+// finished game.
+type GameReport struct {
+	GameID     uint64            `json:"game_id"`
+	Status     string            `json:"status"`
+	Winner     int               `json:"winner"`
+	BoardSize  int               `json:"board_size"`
+	Plies      int               `json:"plies"`
+	Opening    []Move            `json:"opening"`
+	Black      GameReportSide    `json:"black"`
+	White      GameReportSide    `json:"white"`
+	Cache      GameReportCache   `json:"cache"`
+	Trajectory []GameReportPoint `json:"trajectory"`
+	Blunders   []int             `json:"blunders"`
+	Moves      []GameReportMove  `json:"moves"`
+	ReviewID   uint64            `json:"review_id,omitempty"`
+}
+
+// RenderOptions selects how GET /api/render draws the current game. Format
+// is "svg" (default) or "png"; a nil Ply draws the latest position.
+type RenderOptions struct {
+	Format      string
+	Ply         *int
+	CellSize    int
+	HideNumbers bool
+}
+
+type HeuristicConfig struct {
+	Open4               float64 `json:"open_4"`
+	Closed4             float64 `json:"closed_4"`
+	Broken4             float64 `json:"broken_4"`
+	Open3               float64 `json:"open_3"`
+	Broken3             float64 `json:"broken_3"`
+	Closed3             float64 `json:"closed_3"`
+	Open2               float64 `json:"open_2"`
+	Broken2             float64 `json:"broken_2"`
+	ForkOpen3           float64 `json:"fork_open_3"`
+	ForkFourPlus        float64 `json:"fork_four_plus"`
+	CaptureNow          float64 `json:"capture_now"`
+	CaptureDoubleThreat float64 `json:"capture_double_threat"`
+	CaptureNearWin      float64 `json:"capture_near_win"`
+	CaptureInTwo        float64 `json:"capture_in_two"`
+	HangingPair         float64 `json:"hanging_pair"`
+	CaptureScaleEarly   float64 `json:"capture_scale_early"`
+	CaptureScaleMid     float64 `json:"capture_scale_mid"`
+	CaptureScaleLate    float64 `json:"capture_scale_late"`
+	CaptureScaleFinal   float64 `json:"capture_scale_final"`
+	DefensiveCapture    float64 `json:"defensive_capture"`
+	CaptureInTwoLimit   int     `json:"capture_in_two_limit"`
+}
+
+// ActiveHeuristics is the answer to GET /api/heuristics.
+type ActiveHeuristics struct {
+	Heuristics    HeuristicConfig `json:"heuristics"`
+	HeuristicHash string          `json:"heuristic_hash"`
+}
+
+// PromoteHeuristicsRequest is the body of POST /api/admin/heuristics/promote.
+// KeepPrevious keeps the cache entries of the weights being replaced.
+type PromoteHeuristicsRequest struct {
+	Heuristics   HeuristicConfig `json:"heuristics"`
+	Source       string          `json:"source,omitempty"`
+	KeepPrevious bool            `json:"keep_previous,omitempty"`
+}
+
+// HeuristicPromotion is the backend's record of a promotion.
+type HeuristicPromotion struct {
+	Heuristics       HeuristicConfig `json:"heuristics"`
+	HeuristicHash    string          `json:"heuristic_hash"`
+	PreviousHash     string          `json:"previous_hash"`
+	Source           string          `json:"source,omitempty"`
+	Changed          bool            `json:"changed"`
+	TTEntriesPruned  int             `json:"tt_entries_pruned"`
+	EvalCacheCleared bool            `json:"eval_cache_cleared"`
+}
+
+// RegisterHeuristicsRequest is the body of POST /api/heuristics/registry.
+// It records a weight set in the backend's registry without making it live.
+type RegisterHeuristicsRequest struct {
+	Heuristics   HeuristicConfig `json:"heuristics"`
+	Source       string          `json:"source,omitempty"`
+	GauntletRate *float64        `json:"gauntlet_rate,omitempty"`
+	GauntletElo  *float64        `json:"gauntlet_elo,omitempty"`
+}
+
+// HeuristicRecord is a weight set in the backend's heuristic registry.
+type HeuristicRecord struct {
+	Hash            string          `json:"hash"`
+	Heuristics      HeuristicConfig `json:"heuristics"`
+	Source          string          `json:"source"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastActivatedAt time.Time       `json:"last_activated_at,omitempty"`
+	Activations     int             `json:"activations"`
+	GauntletRate    *float64        `json:"gauntlet_rate,omitempty"`
+	GauntletElo     *float64        `json:"gauntlet_elo,omitempty"`
+	Active          bool            `json:"active"`
+}
+
+// Hash is the backend's heuristic hash of a complete weight set, as shown
+// in heuristic_hash: transposition table entries written with these weights
+// carry it. The backend fills unset (zero) weights with its defaults before
+// hashing, so a partial set hashes differently there.
+func (h HeuristicConfig) Hash() string {
+	hash := uint64(1469598103934665603)
+	mix := func(value float64) {
+		if value == 0 {
+			value = 0
+		}
+		bits := math.Float64bits(value)
+		for i := 0; i < 8; i++ {
+			hash ^= uint64(byte(bits >> (8 * i)))
+			hash *= 1099511628211
+		}
+	}
+	for _, value := range []float64{
+		h.Open4, h.Closed4, h.Broken4, h.Open3, h.Broken3, h.Closed3, h.Open2, h.Broken2,
+		h.ForkOpen3, h.ForkFourPlus, h.CaptureNow, h.CaptureDoubleThreat, h.CaptureNearWin,
+		h.CaptureInTwo, h.HangingPair, h.CaptureScaleEarly, h.CaptureScaleMid, h.CaptureScaleLate,
+		h.CaptureScaleFinal, h.DefensiveCapture, float64(h.CaptureInTwoLimit),
+	} {
+		mix(value)
+	}
+	return fmt.Sprintf("%016x", hash)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gomoku-ai-trainer/pkg/client"
+)
+
+const (
+	searchTuneProfileFile = "best_search_params.json"
+	searchTuneHistoryFile = "search_tuning.jsonl"
+	searchTuneOpeningSalt = 4099
+)
+
+// searchKnob is one search parameter the tuner moves. Step is the size of
+// the first perturbation, in the knob's own units.
+type searchKnob struct {
+	Key     string
+	Min     float64
+	Max     float64
+	Step    float64
+	Integer bool
+}
+
+func defaultSearchKnobs() []searchKnob {
+	return []searchKnob{
+		{Key: "ai_max_candidates_root", Min: 6, Max: 48, Step: 3, Integer: true},
+		{Key: "ai_max_candidates_mid", Min: 4, Max: 48, Step: 3, Integer: true},
+		{Key: "ai_max_candidates_deep", Min: 4, Max: 48, Step: 3, Integer: true},
+		{Key: "ai_lmr_late_move_start", Min: 2, Max: 16, Step: 1, Integer: true},
+		{Key: "ai_lmr_min_depth", Min: 2, Max: 8, Step: 1, Integer: true},
+		{Key: "ai_lmr_reduction", Min: 1, Max: 3, Step: 1, Integer: true},
+		{Key: "ai_asp_window", Min: 100, Max: 20000, Step: 400},
+		{Key: "ai_killer_boost", Min: 500, Max: 50000, Step: 1500, Integer: true},
+		{Key: "ai_history_boost", Min: 1, Max: 128, Step: 4, Integer: true},
+	}
+}
+
+func (k searchKnob) clamp(value float64) float64 {
+	value = math.Max(k.Min, math.Min(k.Max, value))
+	if k.Integer {
+		value = math.Round(value)
+	}
+	return value
+}
+
+// searchTuneRecord is one line of search_tuning.jsonl.
+type searchTuneRecord struct {
+	Iteration int                 `json:"iteration"`
+	Time      string              `json:"time"`
+	Plus      client.SearchParams `json:"plus"`
+	Minus     client.SearchParams `json:"minus"`
+	PlusRate  float64             `json:"plus_rate"`
+	Games     int                 `json:"games"`
+	Params    client.SearchParams `json:"params"`
+}
+
+// runSearchTuning tunes the search knobs with SPSA: every iteration plays
+// the current values nudged up and down along a random sign vector against
+// each other at a fixed depth, and moves the values toward the side that
+// scored better. Heuristics stay at the backend's live set.
+func (t *trainer) runSearchTuning(ctx context.Context) error {
+	iterations := getenvInt("SEARCH_TUNE_ITERATIONS", 200)
+	openingCount := getenvInt("SEARCH_TUNE_OPENINGS", 4)
+	if openingCount < 1 {
+		openingCount = 1
+	}
+	depth := getenvInt("SEARCH_TUNE_DEPTH", 4)
+	if depth < 1 {
+		depth = 4
+	}
+	gain := getenvFloat("SEARCH_TUNE_GAIN", 1)
+	if gain <= 0 {
+		gain = 1
+	}
+	apply := strings.EqualFold(getenv("SEARCH_TUNE_APPLY", "false"), "true")
+
+	status, err := t.api.Status(ctx)
+	if err != nil {
+		return err
+	}
+	boardSize := status.BoardSize
+	if boardSize <= 0 {
+		boardSize = t.boardSize
+	}
+	knobs := defaultSearchKnobs()
+	theta := make([]float64, len(knobs))
+	for i, knob := range knobs {
+		theta[i] = (knob.Min + knob.Max) / 2
+		if value, ok := status.Config[knob.Key].(float64); ok {
+			theta[i] = knob.clamp(value)
+		}
+	}
+	openings := t.buildOpeningSuite(boardSize, openingCount, searchTuneOpeningSalt)
+	t.logf("Search tuning started: %d knobs, %d iterations, depth %d, %d openings", len(knobs), iterations, depth, openingCount)
+	t.updateStatus(func(s *trainerStatus) {
+		s.Phase = "running"
+		s.Message = "search tuning running"
+		s.Generation = 0
+		s.PopulationSize = 0
+		s.TrainingOpenings = openingCount
+		s.SearchParams = searchParamsFrom(knobs, theta)
+	})
+
+	// Gain sequences from Spall's guidelines: a_k decays with exponent
+	// 0.602 and the perturbation c_k with 0.101, with the stability
+	// constant at a tenth of the run.
+	stability := float64(iterations) / 10
+	for k := 0; iterations <= 0 || k < iterations; k++ {
+		if err := t.waitForWindow(ctx); err != nil {
+			return err
+		}
+		ak := gain / math.Pow(float64(k+1)+stability, 0.602)
+		ck := 1 / math.Pow(float64(k+1), 0.101)
+
+		delta := make([]float64, len(knobs))
+		plus := make([]float64, len(knobs))
+		minus := make([]float64, len(knobs))
+		for i, knob := range knobs {
+			delta[i] = 1
+			if t.rng.Intn(2) == 0 {
+				delta[i] = -1
+			}
+			shift := ck * knob.Step
+			if knob.Integer {
+				shift = math.Max(1, math.Round(shift))
+			}
+			plus[i] = knob.clamp(theta[i] + shift*delta[i])
+			minus[i] = knob.clamp(theta[i] - shift*delta[i])
+		}
+
+		plusParams := searchParamsFrom(knobs, plus)
+		minusParams := searchParamsFrom(knobs, minus)
+		rate, games, err := t.playSearchPair(ctx, plusParams, minusParams, openings, depth)
+		if err != nil {
+			return err
+		}
+		for i, knob := range knobs {
+			if plus[i] == minus[i] {
+				continue
+			}
+			theta[i] += ak * (2*rate - 1) / (ck * delta[i]) * knob.Step
+			theta[i] = math.Max(knob.Min, math.Min(knob.Max, theta[i]))
+		}
+
+		params := searchParamsFrom(knobs, theta)
+		if err := t.writeSearchProfile(params); err != nil {
+			t.logf("Failed to persist search params: %v", err)
+		}
+		t.appendSearchTuneRecord(searchTuneRecord{
+			Iteration: k + 1,
+			Time:      time.Now().UTC().Format(time.RFC3339),
+			Plus:      plusParams,
+			Minus:     minusParams,
+			PlusRate:  rate,
+			Games:     games,
+			Params:    params,
+		})
+		t.logf("Search tuning iteration %d: plus scored %.3f over %d games", k+1, rate, games)
+		t.updateStatus(func(s *trainerStatus) {
+			s.Generation = k + 1
+			s.GamesPlayed += games
+			s.SearchParams = params
+			s.Message = fmt.Sprintf("search tuning iteration %d: plus scored %.3f", k+1, rate)
+		})
+	}
+
+	if apply {
+		if err := t.applySearchParams(ctx, searchParamsFrom(knobs, theta)); err != nil {
+			return err
+		}
+		t.logf("Search tuning applied the tuned params to the backend config")
+	}
+	t.updateStatus(func(s *trainerStatus) {
+		s.Message = fmt.Sprintf("search tuning finished after %d iterations", iterations)
+	})
+	return nil
+}
+
+// playSearchPair plays plus against minus on every opening with colours
+// alternated and returns plus's score rate.
+func (t *trainer) playSearchPair(ctx context.Context, plus, minus client.SearchParams, openings [][]openingMove, depth int) (float64, int, error) {
+	req := client.SimulateBatchRequest{
+		BlackSearch:     plus,
+		WhiteSearch:     minus,
+		AlternateColors: true,
+		Depth:           depth,
+		MoveBudgetMs:    10000,
+		DisableCache:    true,
+		Workers:         t.batchWorkers,
+	}
+	for _, opening := range openings {
+		moves := make([]client.Move, 0, len(opening))
+		for _, move := range opening {
+			moves = append(moves, client.Move{X: move.X, Y: move.Y})
+		}
+		req.Openings = append(req.Openings, moves)
+	}
+	summary, err := t.batchAPI.SimulateBatch(ctx, req, func(game client.SimulateBatchGame) {
+		if game.Error != "" {
+			t.logf("Search tuning game %d failed: %s", game.Index, game.Error)
+		}
+	})
+	if client.IsMissingEndpoint(err) {
+		return 0, 0, errors.New("search tuning needs the backend's /api/simulate/batch endpoint")
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	played := summary.Games - summary.Errors
+	if played <= 0 {
+		return 0, 0, fmt.Errorf("all %d search tuning games failed", summary.Games)
+	}
+	return summary.ScoreRate, played, nil
+}
+
+func searchParamsFrom(knobs []searchKnob, values []float64) client.SearchParams {
+	params := make(client.SearchParams, len(knobs))
+	for i, knob := range knobs {
+		params[knob.Key] = knob.clamp(values[i])
+	}
+	return params
+}
+
+// writeSearchProfile stores params as a config profile, keyed by config
+// key so it can be merged into the "config" of /api/settings.
+func (t *trainer) writeSearchProfile(params client.SearchParams) error {
+	if err := os.MkdirAll(t.dataDir, 0o755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+	path := filepath.Join(t.dataDir, searchTuneProfileFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (t *trainer) appendSearchTuneRecord(record searchTuneRecord) {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(t.dataDir, searchTuneHistoryFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.logf("Failed to append search tuning history: %v", err)
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(raw, '\n'))
+}
+
+// applySearchParams writes params into the backend's live config.
+func (t *trainer) applySearchParams(ctx context.Context, params client.SearchParams) error {
+	status, err := t.api.Status(ctx)
+	if err != nil {
+		return err
+	}
+	cfg := status.Config
+	if cfg == nil {
+		cfg = map[string]any{}
+	}
+	for key, value := range params {
+		cfg[key] = value
+	}
+	_, err = t.api.UpdateSettings(ctx, client.SettingsUpdate{Config: cfg})
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// loadCertPool reads the PEM certificates of path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no PEM certificate", path)
+	}
+	return pool, nil
+}
+
+// backendTLSConfig is how the trainer calls an HTTPS backend: the CA of its
+// certificate from BACKEND_CA_FILE (else the system roots) and, for a
+// backend requiring client certificates, BACKEND_CLIENT_CERT_FILE and
+// BACKEND_CLIENT_KEY_FILE. It is nil when none is set.
+func backendTLSConfig() (*tls.Config, error) {
+	caFile := getenv("BACKEND_CA_FILE", "")
+	certFile := getenv("BACKEND_CLIENT_CERT_FILE", "")
+	keyFile := getenv("BACKEND_CLIENT_KEY_FILE", "")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// apiTLSConfig is the TLS of the trainer API, served over HTTPS and HTTP/2
+// with TRAINER_TLS_CERT_FILE and TRAINER_TLS_KEY_FILE. With
+// TRAINER_TLS_CLIENT_CA_FILE every request needs a client certificate signed
+// by that CA. It is nil when no certificate is set.
+func apiTLSConfig() (*tls.Config, error) {
+	certFile := getenv("TRAINER_TLS_CERT_FILE", "")
+	keyFile := getenv("TRAINER_TLS_KEY_FILE", "")
+	caFile := getenv("TRAINER_TLS_CLIENT_CA_FILE", "")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("TRAINER_TLS_CLIENT_CA_FILE needs TRAINER_TLS_CERT_FILE and TRAINER_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
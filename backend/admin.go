@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gomoku-backend/pkg/engine"
+)
+
+const recentErrorsLimit = 50
+
+type recentError struct {
+	AtMs    int64  `json:"at_ms"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// recentErrors keeps the last errors seen in the log and in HTTP responses
+// for the admin overview.
+type recentErrors struct {
+	mu      sync.Mutex
+	entries []recentError
+}
+
+func (e *recentErrors) add(source, message string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries = append(e.entries, recentError{AtMs: time.Now().UnixMilli(), Source: source, Message: message})
+	if len(e.entries) > recentErrorsLimit {
+		e.entries = append([]recentError(nil), e.entries[len(e.entries)-recentErrorsLimit:]...)
+	}
+}
+
+// list returns the errors, newest first.
+func (e *recentErrors) list() []recentError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]recentError, len(e.entries))
+	for i, entry := range e.entries {
+		list[len(e.entries)-1-i] = entry
+	}
+	return list
+}
+
+// logWriter returns a writer for log.SetOutput that forwards to out and
+// records lines reporting a failure.
+func (e *recentErrors) logWriter(out io.Writer) io.Writer {
+	return recentErrorsLogWriter{errors: e, out: out}
+}
+
+type recentErrorsLogWriter struct {
+	errors *recentErrors
+	out    io.Writer
+}
+
+func (w recentErrorsLogWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		lower := strings.ToLower(string(line))
+		if strings.Contains(lower, "failed") || strings.Contains(lower, "error") || strings.Contains(lower, "panic") {
+			w.errors.add("log", string(line))
+		}
+	}
+	return w.out.Write(p)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack and Flush pass through so websocket upgrades keep working.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// middleware records responses with a 5xx status.
+func (e *recentErrors) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status >= 500 {
+			e.add("http", fmt.Sprintf("%s %s returned %d", r.Method, r.URL.Path, recorder.status))
+		}
+	})
+}
+
+// adminAuth is what the admin API asks for: the ADMIN_TOKEN bearer token
+// and, when the server verifies client certificates, one of those.
+type adminAuth struct {
+	token       string
+	requireCert bool
+}
+
+// adminOnly guards h with auth. Without a token the admin API is disabled.
+func adminOnly(auth adminAuth, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.token == "" {
+			writeError(w, http.StatusForbidden, errCodeForbidden, "admin API disabled, set ADMIN_TOKEN")
+			return
+		}
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(auth.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+			return
+		}
+		if auth.requireCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			writeError(w, http.StatusForbidden, errCodeForbidden, "client certificate required")
+			return
+		}
+		h(w, r)
+	}
+}
+
+type adminGamesOverview struct {
+	MainGameID           uint64 `json:"main_game_id"`
+	MainStatus           string `json:"main_status"`
+	MainMode             string `json:"main_mode"`
+	MainMoves            int    `json:"main_moves"`
+	MainUser             string `json:"main_user,omitempty"`
+	ActiveMatches        int    `json:"active_matches"`
+	MatchmakingQueue     int    `json:"matchmaking_queue"`
+	ActiveCorrespondence int    `json:"active_correspondence"`
+	ReviewsQueued        int    `json:"reviews_queued"`
+	ReviewsRunning       int    `json:"reviews_running"`
+}
+
+type adminWorkersOverview struct {
+	AiThinking bool                   `json:"ai_thinking"`
+	Backlog    engine.BacklogOverview `json:"backlog"`
+	SearchPool engine.SearchPoolStats `json:"search_pool"`
+}
+
+type adminCachesOverview struct {
+	TT          ttCacheStatusResponse `json:"tt"`
+	StoredGames int                   `json:"stored_games"`
+	Users       int                   `json:"users"`
+}
+
+type adminMemoryOverview struct {
+	AllocBytes     uint64 `json:"alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	Goroutines     int    `json:"goroutines"`
+	CPUs           int    `json:"cpus"`
+}
+
+type adminOverviewResponse struct {
+	UptimeMs     int64                `json:"uptime_ms"`
+	Games        adminGamesOverview   `json:"games"`
+	Workers      adminWorkersOverview `json:"workers"`
+	Caches       adminCachesOverview  `json:"caches"`
+	Memory       adminMemoryOverview  `json:"memory"`
+	RecentErrors []recentError        `json:"recent_errors"`
+}
+
+func adminOverview(started time.Time, controller *engine.GameController, matchmaker *engine.Matchmaker, reviews *engine.ReviewQueue, errs *recentErrors) adminOverviewResponse {
+	state, history, gameID := controller.Snapshot()
+	games := adminGamesOverview{
+		MainGameID:           gameID,
+		MainStatus:           engine.StatusToString(state.Status),
+		MainMode:             controllerSettingsDTO(controller.Settings()).Mode,
+		MainMoves:            history.Size(),
+		MainUser:             controller.User(),
+		ActiveMatches:        matchmaker.ActiveMatches(),
+		MatchmakingQueue:     matchmaker.QueueLen(),
+		ActiveCorrespondence: engine.CorrespondenceGames.Running(),
+	}
+	for _, review := range reviews.List() {
+		switch review.Status {
+		case "queued":
+			games.ReviewsQueued++
+		case "running":
+			games.ReviewsRunning++
+		}
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return adminOverviewResponse{
+		UptimeMs: time.Since(started).Milliseconds(),
+		Games:    games,
+		Workers: adminWorkersOverview{
+			AiThinking: controller.AiThinking(),
+			Backlog:    engine.SearchBacklogManager.Overview(),
+			SearchPool: engine.SearchPool.Stats(),
+		},
+		Caches: adminCachesOverview{
+			TT:          ttCacheStatus(),
+			StoredGames: engine.StoredGameCount(),
+			Users:       engine.UserProfiles.Len(),
+		},
+		Memory: adminMemoryOverview{
+			AllocBytes:     mem.Alloc,
+			HeapInuseBytes: mem.HeapInuse,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+			Goroutines:     runtime.NumGoroutine(),
+			CPUs:           runtime.NumCPU(),
+		},
+		RecentErrors: errs.list(),
+	}
+}
+
+type adminComponentsResponse struct {
+	Goroutines int                      `json:"goroutines"`
+	Stuck      int                      `json:"stuck"`
+	Components []engine.ComponentStatus `json:"components"`
+}
+
+func adminComponents() adminComponentsResponse {
+	response := adminComponentsResponse{
+		Goroutines: runtime.NumGoroutine(),
+		Components: engine.Components.List(),
+	}
+	for _, component := range response.Components {
+		if component.Stuck {
+			response.Stuck++
+		}
+	}
+	return response
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gomoku-backend/pkg/engine"
+)
+
+// Error codes returned in the "code" field of every error response.
+const (
+	errCodeInvalidPayload   = "invalid_payload"
+	errCodeInvalidParameter = "invalid_parameter"
+	errCodeValidation       = "validation_failed"
+	errCodeIllegalMove      = "illegal_move"
+	errCodeNotYourTurn      = "not_your_turn"
+	errCodeGameOver         = "game_over"
+	errCodeGameNotFinished  = "game_not_finished"
+	errCodeNotFound         = "not_found"
+	errCodeAlreadyExists    = "already_exists"
+	errCodeConflict         = "conflict"
+	errCodeQueueFull        = "queue_full"
+	errCodeInvalidToken     = "invalid_token"
+	errCodeUnauthorized     = "unauthorized"
+	errCodeForbidden        = "forbidden"
+	errCodeInternal         = "internal"
+)
+
+type apiErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// apiError is the body of every error response. Error keeps the human
+// readable message under the key clients already read.
+type apiError struct {
+	Code    string           `json:"code"`
+	Error   string           `json:"error"`
+	Details []apiErrorDetail `json:"details,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string, details ...apiErrorDetail) {
+	writeJSON(w, status, apiError{Code: code, Error: message, Details: details})
+}
+
+// writeErr reports an engine error, picking the code from the error when it
+// is one the engine names and from status otherwise.
+func writeErr(w http.ResponseWriter, status int, err error) {
+	var fieldErr *engine.FieldError
+	switch {
+	case errors.As(err, &fieldErr):
+		writeError(w, status, errCodeValidation, err.Error(), apiErrorDetail{Field: fieldErr.Field, Message: fieldErr.Message})
+	case errors.Is(err, engine.ErrIllegalMove):
+		writeError(w, status, errCodeIllegalMove, err.Error())
+	case errors.Is(err, engine.ErrNotYourTurn):
+		writeError(w, status, errCodeNotYourTurn, err.Error())
+	case errors.Is(err, engine.ErrGameOver):
+		writeError(w, status, errCodeGameOver, err.Error())
+	case errors.Is(err, engine.ErrInvalidToken):
+		writeError(w, status, errCodeInvalidToken, err.Error())
+	case errors.Is(err, engine.ErrUserExists):
+		writeError(w, status, errCodeAlreadyExists, err.Error())
+	default:
+		writeError(w, status, errorCodeForStatus(status), err.Error())
+	}
+}
+
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errCodeValidation
+	case http.StatusUnauthorized:
+		return errCodeUnauthorized
+	case http.StatusForbidden:
+		return errCodeForbidden
+	case http.StatusNotFound:
+		return errCodeNotFound
+	case http.StatusConflict:
+		return errCodeConflict
+	case http.StatusServiceUnavailable:
+		return errCodeQueueFull
+	default:
+		return errCodeInternal
+	}
+}
+
+// moveRejectionCode classifies the reasons GameController.ApplyHumanMove
+// gives for refusing a move.
+func moveRejectionCode(reason string) string {
+	switch {
+	case reason == "not human turn":
+		return errCodeNotYourTurn
+	case reason == "game not running":
+		return errCodeGameOver
+	default:
+		return errCodeIllegalMove
+	}
+}
+
+// writeInvalidParameter reports a malformed URL or query parameter.
+func writeInvalidParameter(w http.ResponseWriter, field, message string) {
+	writeError(w, http.StatusBadRequest, errCodeInvalidParameter, message, apiErrorDetail{Field: field, Message: message})
+}
+
+// decodeJSON decodes the request body into out, answering 400 with the
+// offending field when it can be told.
+func decodeJSON(w http.ResponseWriter, r *http.Request, out any) bool {
+	err := json.NewDecoder(r.Body).Decode(out)
+	if err == nil {
+		return true
+	}
+	writeDecodeError(w, err)
+	return false
+}
+
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload",
+			apiErrorDetail{Field: typeErr.Field, Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)})
+		return
+	}
+	writeError(w, http.StatusBadRequest, errCodeInvalidPayload, "invalid payload")
+}
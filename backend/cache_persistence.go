@@ -1,9 +0,0 @@
-package main
-
-func persistCaches() {
-	persistTTPersistence(GetConfig(), SharedSearchCache())
-}
-
-func loadPersistedCaches() {
-	loadTTPersistence(GetConfig(), SharedSearchCache())
-}
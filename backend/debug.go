@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strconv"
+	"time"
+
+	"gomoku-backend/pkg/engine"
+)
+
+const (
+	defaultProfileSeconds = 10
+	maxProfileSeconds     = 120
+	// profileMutexFraction samples one mutex contention event in that many
+	// once the profiles can be reached.
+	profileMutexFraction = 10
+)
+
+// pprofMux serves the net/http/pprof handlers under /debug/pprof/.
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// startPprofServer serves the profiles without authentication on their own
+// address, meant for a port that is not published. It returns nil when addr
+// is empty.
+func startPprofServer(addr string) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	server := &http.Server{Addr: addr, Handler: pprofMux()}
+	go func() {
+		log.Printf("[backend] pprof listening on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[backend] pprof server error: %v", err)
+		}
+	}()
+	return server
+}
+
+// enableContentionProfiles turns on the mutex and block profiles, which are
+// empty unless sampled.
+func enableContentionProfiles() {
+	runtime.SetMutexProfileFraction(profileMutexFraction)
+	runtime.SetBlockProfileRate(int(time.Millisecond))
+}
+
+// serveCPUProfile captures a CPU profile for ?seconds=N (default 10). With
+// ai_move=true it waits up to N seconds for the AI of the main game to start
+// thinking and profiles until the move is played, for at most N seconds.
+func serveCPUProfile(controller *engine.GameController) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seconds := defaultProfileSeconds
+		if raw := r.URL.Query().Get("seconds"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxProfileSeconds {
+				writeInvalidParameter(w, "seconds", fmt.Sprintf("seconds must be between 1 and %d", maxProfileSeconds))
+				return
+			}
+			seconds = parsed
+		}
+		aiMove := r.URL.Query().Get("ai_move") == "true"
+		limit := time.Duration(seconds) * time.Second
+		ctx := r.Context()
+		if aiMove && !waitFor(ctx, limit, controller.AiThinking) {
+			writeError(w, http.StatusConflict, errCodeConflict, "the AI did not start a move")
+			return
+		}
+		var profile bytes.Buffer
+		if err := runtimepprof.StartCPUProfile(&profile); err != nil {
+			writeError(w, http.StatusConflict, errCodeConflict, "a CPU profile is already running")
+			return
+		}
+		started := time.Now()
+		if aiMove {
+			waitFor(ctx, limit, func() bool { return !controller.AiThinking() })
+		} else {
+			waitFor(ctx, limit, func() bool { return false })
+		}
+		runtimepprof.StopCPUProfile()
+		if ctx.Err() != nil {
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
+		w.Header().Set("X-Profile-Duration-Ms", strconv.FormatInt(time.Since(started).Milliseconds(), 10))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(profile.Bytes())
+	}
+}
+
+// waitFor polls done until it holds, limit elapses or ctx ends, and reports
+// whether it held.
+func waitFor(ctx context.Context, limit time.Duration, done func() bool) bool {
+	deadline := time.NewTimer(limit)
+	defer deadline.Stop()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if done() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
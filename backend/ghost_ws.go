@@ -1,89 +1,142 @@
 package main
 
 import (
-	"encoding/json"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/websocket"
+	"gomoku-backend/pkg/engine"
 )
 
-type ghostCell struct {
-	X      int `json:"x"`
-	Y      int `json:"y"`
-	Player int `json:"player"`
-}
-
-type ghostPayload struct {
-	Mode       string      `json:"mode,omitempty"`
-	Positions  []ghostCell `json:"positions,omitempty"`
-	Best       *ghostCell  `json:"best,omitempty"`
-	Depth      int         `json:"depth,omitempty"`
-	Score      float64     `json:"score,omitempty"`
-	NextPlayer int         `json:"next_player,omitempty"`
-	HistoryLen int         `json:"history_len,omitempty"`
-	Active     bool        `json:"active"`
-	Final      bool        `json:"final,omitempty"`
-}
-
 type GhostClient struct {
+	wsClient
 	hub  *GhostHub
 	conn *websocket.Conn
-	send chan []byte
+	// synced is set once the client holds the latest preview frame, so it
+	// can be sent deltas instead of keyframes.
+	synced bool
 }
 
 type GhostHub struct {
-	mu        sync.Mutex
-	clients   map[*GhostClient]struct{}
-	broadcast chan ghostPayload
+	mu          sync.Mutex
+	clients     map[*GhostClient]struct{}
+	broadcast   chan engine.GhostPayload
+	previews    *engine.GhostDeltaEncoder
+	component   *engine.Component
+	compression wsCompression
 }
 
 func NewGhostHub() *GhostHub {
-	return &GhostHub{
+	h := &GhostHub{
 		clients:   make(map[*GhostClient]struct{}),
-		broadcast: make(chan ghostPayload, 32),
+		broadcast: make(chan engine.GhostPayload, 32),
+		previews:  engine.NewGhostDeltaEncoder(),
 	}
+	h.component = engine.Components.Register("ghost_hub", "hub", hubStaleAfter, func() (int, int) { return len(h.broadcast), cap(h.broadcast) })
+	return h
 }
 
 func (h *GhostHub) Run(done <-chan struct{}) {
+	defer engine.Components.Unregister(h.component)
 	for {
 		select {
 		case <-done:
 			return
 		case payload := <-h.broadcast:
+			h.component.Beat()
 			h.mu.Lock()
 			if len(h.clients) == 0 {
 				h.mu.Unlock()
 				continue
 			}
+			if payload.Mode == engine.GhostModePreviewBoard {
+				h.sendPreview(payload)
+				h.mu.Unlock()
+				continue
+			}
+			frame := newWSFrame(wsMessage{Type: "ghost", Payload: mustMarshal(payload)})
 			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "ghost", Payload: mustMarshal(payload)})
+				h.send(client, frame)
 			}
 			h.mu.Unlock()
 		}
 	}
 }
 
+// sendPreview sends each client either the delta from the previous preview
+// frame or, when it missed that frame, the full keyframe. Callers hold h.mu.
+func (h *GhostHub) sendPreview(payload engine.GhostPayload) {
+	delta, keyframe := h.previews.Next(payload)
+	deltaFrame := newWSFrame(wsMessage{Type: "ghost", Payload: mustMarshal(delta)})
+	var keyFrame *wsFrame
+	if delta.Keyframe {
+		keyFrame = deltaFrame
+	}
+	for client := range h.clients {
+		h.makeRoom(client)
+		frame := deltaFrame
+		if !client.synced {
+			if keyFrame == nil {
+				keyFrame = newWSFrame(wsMessage{Type: "ghost", Payload: mustMarshal(keyframe)})
+			}
+			frame = keyFrame
+		}
+		client.synced = h.send(client, frame)
+	}
+}
+
+// makeRoom drops every ghost frame a client that fell behind has queued,
+// oldest first, rather than just the oldest: the frames after a dropped
+// delta would not apply, so the client restarts from the next keyframe.
+// Callers hold h.mu.
+func (h *GhostHub) makeRoom(client *GhostClient) {
+	if !client.queue.full() {
+		return
+	}
+	h.component.Add("dropped_frames", int64(client.queue.dropDroppable()))
+	client.synced = false
+}
+
+// send queues a ghost frame for client and reports whether it was queued.
+// Callers hold h.mu.
+func (h *GhostHub) send(client *GhostClient, frame *wsFrame) bool {
+	h.makeRoom(client)
+	dropped, evicted := client.sendFrame(frame, true)
+	countSend(h.component, "ghost_hub", dropped, evicted)
+	if evicted {
+		delete(h.clients, client)
+	}
+	return dropped == 0 && !evicted
+}
+
 func (h *GhostHub) Register(c *GhostClient) {
 	h.mu.Lock()
 	h.clients[c] = struct{}{}
 	h.mu.Unlock()
 }
 
-func (h *GhostHub) Publish(payload ghostPayload) {
-	select {
-	case h.broadcast <- payload:
-	default:
+// Publish queues a ghost frame for the clients. When the hub is behind, the
+// oldest frame waiting is dropped to make room.
+func (h *GhostHub) Publish(payload engine.GhostPayload) {
+	for {
+		select {
+		case h.broadcast <- payload:
+			return
+		default:
+		}
+		select {
+		case <-h.broadcast:
+			h.component.Add("dropped_frames", 1)
+		default:
+		}
 	}
 }
 
 func (h *GhostHub) Unregister(c *GhostClient) {
 	h.mu.Lock()
-	if _, ok := h.clients[c]; ok {
-		delete(h.clients, c)
-		close(c.send)
-	}
+	delete(h.clients, c)
 	h.mu.Unlock()
+	c.queue.close()
 }
 
 func (h *GhostHub) HasClients() bool {
@@ -92,29 +145,19 @@ func (h *GhostHub) HasClients() bool {
 	return len(h.clients) > 0
 }
 
-func (c *GhostClient) sendJSON(msg wsMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return
-	}
-	select {
-	case c.send <- data:
-	default:
-	}
-}
-
 func serveGhostWS(hub *GhostHub, w http.ResponseWriter, r *http.Request) {
-	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	upgrader := newWSUpgrader(hub.compression)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-	client := &GhostClient{hub: hub, conn: conn, send: make(chan []byte, 16)}
+	hub.compression.apply(conn)
+	client := &GhostClient{wsClient: newWSClient(wsBinary(conn)), hub: hub, conn: conn}
 	hub.Register(client)
 
 	go func() {
 		defer conn.Close()
-		if err := writeWSWithHeartbeat(conn, client.send); err != nil {
+		if err := writeWSWithHeartbeat(conn, client.queue, client.binary); err != nil {
 			return
 		}
 	}()
@@ -126,18 +169,3 @@ func serveGhostWS(hub *GhostHub, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
-
-func ghostPositionsFromBoard(board Board) []ghostCell {
-	positions := []ghostCell{}
-	size := board.Size()
-	for y := 0; y < size; y++ {
-		for x := 0; x < size; x++ {
-			cell := board.At(x, y)
-			if cell == CellEmpty {
-				continue
-			}
-			positions = append(positions, ghostCell{X: x, Y: y, Player: cellToInt(cell)})
-		}
-	}
-	return positions
-}
@@ -3,21 +3,39 @@ package main
 import (
 	"encoding/json"
 	"sync"
+	"time"
+
+	"gomoku-backend/pkg/engine"
 )
 
+// hubStaleAfter is how long a hub may leave messages queued before the
+// admin components view reports it stuck.
+const hubStaleAfter = 5 * time.Second
+
+// hubQueueSize is how many messages a Hub holds for its clients. It only
+// fills when the hub itself falls behind: a slow client is evicted instead.
+const hubQueueSize = 256
+
 type Hub struct {
-	mu                sync.Mutex
-	clients           map[*Client]struct{}
-	broadcastBoard    chan boardPayload
-	broadcastHistory  chan historyPayload
-	broadcastStatus   chan StatusResponse
-	broadcastReset    chan resetPayload
-	broadcastSettings chan settingsPayload
+	mu        sync.Mutex
+	clients   map[*Client]struct{}
+	broadcast chan hubMessage
+	component *engine.Component
+	// compression is the permessage-deflate setting of the hub's endpoint.
+	compression wsCompression
+}
+
+// hubMessage is one message for every client of a Hub. Messages reach the
+// clients in the order they were published; a droppable one, superseded by
+// the next of its kind, may be skipped by a client that fell behind.
+type hubMessage struct {
+	frame     *wsFrame
+	droppable bool
 }
 
 type Client struct {
-	hub  *Hub
-	send chan []byte
+	wsClient
+	hub *Hub
 }
 
 type wsMessage struct {
@@ -25,66 +43,57 @@ type wsMessage struct {
 	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
-type boardPayload struct {
-	Board      [][]int           `json:"board"`
-	NextPlayer int               `json:"next_player"`
-	Winner     int               `json:"winner"`
-	MoveCount  int               `json:"move_count"`
-	Status     string            `json:"status"`
-	AiThinking bool              `json:"ai_thinking"`
-	History    []historyEntryDTO `json:"history"`
+type annotationsPayload struct {
+	GameID      uint64              `json:"game_id"`
+	Annotations []engine.Annotation `json:"annotations"`
 }
 
 func NewHub() *Hub {
-	return &Hub{
-		clients:           make(map[*Client]struct{}),
-		broadcastBoard:    make(chan boardPayload, 16),
-		broadcastHistory:  make(chan historyPayload, 32),
-		broadcastStatus:   make(chan StatusResponse, 32),
-		broadcastReset:    make(chan resetPayload, 8),
-		broadcastSettings: make(chan settingsPayload, 8),
+	h := &Hub{
+		clients:   make(map[*Client]struct{}),
+		broadcast: make(chan hubMessage, hubQueueSize),
 	}
+	h.component = engine.Components.Register("hub", "hub", hubStaleAfter, func() (int, int) { return len(h.broadcast), cap(h.broadcast) })
+	return h
 }
 
 func (h *Hub) Run(done <-chan struct{}) {
+	defer engine.Components.Unregister(h.component)
 	for {
 		select {
 		case <-done:
 			return
-		case payload := <-h.broadcastBoard:
-			h.mu.Lock()
-			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "board", Payload: mustMarshal(payload)})
-			}
-			h.mu.Unlock()
-		case payload := <-h.broadcastHistory:
+		case msg := <-h.broadcast:
+			h.component.Beat()
 			h.mu.Lock()
 			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "history", Payload: mustMarshal(payload)})
-			}
-			h.mu.Unlock()
-		case payload := <-h.broadcastStatus:
-			h.mu.Lock()
-			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "status", Payload: mustMarshal(payload)})
-			}
-			h.mu.Unlock()
-		case payload := <-h.broadcastReset:
-			h.mu.Lock()
-			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "reset", Payload: mustMarshal(payload)})
-			}
-			h.mu.Unlock()
-		case payload := <-h.broadcastSettings:
-			h.mu.Lock()
-			for client := range h.clients {
-				client.sendJSON(wsMessage{Type: "settings", Payload: mustMarshal(payload)})
+				dropped, evicted := client.sendFrame(msg.frame, msg.droppable)
+				countSend(h.component, "hub", dropped, evicted)
+				if evicted {
+					delete(h.clients, client)
+				}
 			}
 			h.mu.Unlock()
 		}
 	}
 }
 
+// publish sends a message to every client. It waits for room in the hub's
+// queue, so callers that must not block use offer.
+func (h *Hub) publish(typ string, payload any) {
+	h.broadcast <- hubMessage{frame: newWSFrame(wsMessage{Type: typ, Payload: mustMarshal(payload)})}
+}
+
+// offer is publish without waiting: the message is dropped when the hub's
+// queue is full.
+func (h *Hub) offer(typ string, payload any, droppable bool) {
+	select {
+	case h.broadcast <- hubMessage{frame: newWSFrame(wsMessage{Type: typ, Payload: mustMarshal(payload)}), droppable: droppable}:
+	default:
+		h.component.Add("dropped_frames", 1)
+	}
+}
+
 func (h *Hub) Register(c *Client) {
 	h.mu.Lock()
 	h.clients[c] = struct{}{}
@@ -93,11 +102,9 @@ func (h *Hub) Register(c *Client) {
 
 func (h *Hub) Unregister(c *Client) {
 	h.mu.Lock()
-	if _, ok := h.clients[c]; ok {
-		delete(h.clients, c)
-		close(c.send)
-	}
+	delete(h.clients, c)
 	h.mu.Unlock()
+	c.queue.close()
 }
 
 func (h *Hub) HasClients() bool {
@@ -106,13 +113,51 @@ func (h *Hub) HasClients() bool {
 	return len(h.clients) > 0
 }
 
-func (c *Client) sendJSON(msg wsMessage) {
-	data, err := json.Marshal(msg)
-	if err != nil {
+// publishPremove acknowledges a premove change to every client. It may be
+// called with the controller locked, so it never blocks.
+func (h *Hub) publishPremove(event engine.PremoveEvent) {
+	h.offer("premove", event, false)
+}
+
+// publishScore streams an engine evaluation. It is called from the search
+// goroutine, so it never blocks.
+func (h *Hub) publishScore(update engine.ScoreUpdate) {
+	h.offer("score_update", update, true)
+}
+
+// publishSimul reports a move on a simul board. It is called with the simul
+// locked, so it never blocks.
+func (h *Hub) publishSimul(event engine.SimulEvent) {
+	h.offer("simul_update", event, false)
+}
+
+// publishThreats sends the threats of the current position in teaching mode.
+func (h *Hub) publishThreats(controller *engine.GameController, tracker *engine.ThreatTracker) {
+	if !engine.GetConfig().TeachingMode {
 		return
 	}
-	select {
-	case c.send <- data:
-	default:
+	state, history, gameID := controller.Snapshot()
+	report := tracker.Update(gameID, history.Size(), state, controller.Settings())
+	h.offer("threats", report, true)
+}
+
+// publishCommentary describes the last move of an AI-vs-AI game.
+func (h *Hub) publishCommentary(controller *engine.GameController, tracker *engine.CommentaryTracker) {
+	settings := controller.Settings()
+	if !engine.GetConfig().Commentary || settings.BlackType != engine.PlayerAI || settings.WhiteType != engine.PlayerAI {
+		return
+	}
+	state, history, gameID := controller.Snapshot()
+	commentary, ok := tracker.Update(gameID, history.All(), state, settings)
+	if !ok {
+		return
+	}
+	h.offer("commentary", commentary, false)
+}
+
+// publishTurnEvents sends the human turn events due now.
+func (h *Hub) publishTurnEvents(controller *engine.GameController, watch *engine.TurnWatch) {
+	for _, event := range watch.Update(controller, engine.GetConfig(), time.Now()) {
+		h.offer(event.Type, event, false)
 	}
 }
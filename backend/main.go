@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -17,22 +20,48 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/gorilla/websocket"
+	"gomoku-backend/pkg/engine"
 )
 
 type StatusResponse struct {
-	Settings           GameSettingsDTO   `json:"settings"`
-	Config             Config            `json:"config"`
-	NextPlayer         int               `json:"next_player"`
-	Winner             int               `json:"winner"`
-	BoardSize          int               `json:"board_size"`
-	Status             string            `json:"status"`
-	History            []historyEntryDTO `json:"history"`
-	WinReason          string            `json:"win_reason"`
-	WinningLine        []Move            `json:"winning_line"`
-	WinningCapturePair []Move            `json:"winning_capture_pair"`
-	CaptureWinStones   int               `json:"capture_win_stones"`
-	TurnStartedAtMs    int64             `json:"turn_started_at_ms"`
+	Settings           GameSettingsDTO       `json:"settings"`
+	Config             engine.Config         `json:"config"`
+	NextPlayer         int                   `json:"next_player"`
+	Winner             int                   `json:"winner"`
+	BoardSize          int                   `json:"board_size"`
+	Status             string                `json:"status"`
+	History            []historyEntryDTO     `json:"history"`
+	WinReason          string                `json:"win_reason"`
+	WinningLine        []engine.Move         `json:"winning_line"`
+	WinningCapturePair []engine.Move         `json:"winning_capture_pair"`
+	CaptureWinStones   int                   `json:"capture_win_stones"`
+	TurnStartedAtMs    int64                 `json:"turn_started_at_ms"`
+	StartPosition      *engine.StartPosition `json:"start_position,omitempty"`
+	MustCapture        bool                  `json:"must_capture"`
+	ForcedCaptureMoves []engine.Move         `json:"forced_capture_moves"`
+	PendingAlignment   []engine.Move         `json:"pending_alignment"`
+	PendingPlayer      int                   `json:"pending_alignment_player"`
+	WinConditions      engine.WinConditions  `json:"win_conditions"`
+	PendingWin         *engine.PendingWin    `json:"pending_win"`
+	GameID             uint64                `json:"game_id"`
+	Hash               string                `json:"hash"`
+	PositionKey        string                `json:"position_key"`
+	User               string                `json:"user,omitempty"`
+	Seed               int64                 `json:"seed"`
+	// Premove is the human's queued move while the engine thinks.
+	Premove *engine.Move `json:"premove,omitempty"`
+	// CaptureWin is the move that wins by capture for the side to move,
+	// left to it when auto_play_capture_win is off.
+	CaptureWin *engine.Move `json:"capture_win,omitempty"`
+	// HeuristicHash identifies the live weight set; a side playing with its
+	// own weights has its hash in BlackHeuristicHash or WhiteHeuristicHash.
+	HeuristicHash      string                 `json:"heuristic_hash"`
+	BlackHeuristicHash string                 `json:"black_heuristic_hash,omitempty"`
+	WhiteHeuristicHash string                 `json:"white_heuristic_hash,omitempty"`
+	Phase              engine.GamePhaseReport `json:"phase"`
+	// ForcedLine is the rest of the forcing sequence an engine side is
+	// playing after proving a win or a loss, numbered from its first move.
+	ForcedLine *engine.ForcedLine `json:"forced_line,omitempty"`
 }
 
 type GameSettingsDTO struct {
@@ -47,15 +76,45 @@ type apiMove struct {
 }
 
 type historyEntryDTO struct {
-	X                 int          `json:"x"`
-	Y                 int          `json:"y"`
-	Player            int          `json:"player"`
-	ElapsedMs         float64      `json:"elapsed_ms"`
-	IsAi              bool         `json:"is_ai"`
-	CapturedCount     int          `json:"captured_count"`
-	CapturedPositions []Move       `json:"captured_positions"`
-	Changes           []cellChange `json:"changes"`
-	Depth             int          `json:"depth"`
+	X                 int           `json:"x"`
+	Y                 int           `json:"y"`
+	Player            int           `json:"player"`
+	ElapsedMs         float64       `json:"elapsed_ms"`
+	ComputeMs         float64       `json:"compute_ms"`
+	IsAi              bool          `json:"is_ai"`
+	CapturedCount     int           `json:"captured_count"`
+	CapturedPositions []engine.Move `json:"captured_positions"`
+	Changes           []cellChange  `json:"changes"`
+	Depth             int           `json:"depth"`
+	AutoPlayed        bool          `json:"auto_played,omitempty"`
+}
+
+type historyDiffResponse struct {
+	GameID          uint64             `json:"game_id"`
+	Since           int                `json:"since"`
+	Total           int                `json:"total"`
+	Reset           bool               `json:"reset"`
+	Entries         []historyEntryDTO  `json:"entries"`
+	Hash            string             `json:"hash"`
+	Status          string             `json:"status"`
+	NextPlayer      int                `json:"next_player"`
+	Winner          int                `json:"winner"`
+	WinReason       string             `json:"win_reason"`
+	WinningLine     []engine.Move      `json:"winning_line"`
+	PendingWin      *engine.PendingWin `json:"pending_win"`
+	TurnStartedAtMs int64              `json:"turn_started_at_ms"`
+}
+
+// /api/events returns at most this many events per page.
+const (
+	defaultEventsLimit = 200
+	maxEventsLimit     = 1000
+)
+
+type eventsResponse struct {
+	Events  []engine.Event `json:"events"`
+	More    bool           `json:"more"`
+	LastSeq uint64         `json:"last_seq"`
 }
 
 type changesPayload struct {
@@ -67,16 +126,17 @@ type historyPayload struct {
 }
 
 type resetPayload struct {
-	History            []historyEntryDTO `json:"history"`
-	NextPlayer         int               `json:"next_player"`
-	Winner             int               `json:"winner"`
-	Status             string            `json:"status"`
-	BoardSize          int               `json:"board_size"`
-	WinReason          string            `json:"win_reason"`
-	WinningLine        []Move            `json:"winning_line"`
-	WinningCapturePair []Move            `json:"winning_capture_pair"`
-	CaptureWinStones   int               `json:"capture_win_stones"`
-	TurnStartedAtMs    int64             `json:"turn_started_at_ms"`
+	History            []historyEntryDTO     `json:"history"`
+	NextPlayer         int                   `json:"next_player"`
+	Winner             int                   `json:"winner"`
+	Status             string                `json:"status"`
+	BoardSize          int                   `json:"board_size"`
+	WinReason          string                `json:"win_reason"`
+	WinningLine        []engine.Move         `json:"winning_line"`
+	WinningCapturePair []engine.Move         `json:"winning_capture_pair"`
+	CaptureWinStones   int                   `json:"capture_win_stones"`
+	TurnStartedAtMs    int64                 `json:"turn_started_at_ms"`
+	StartPosition      *engine.StartPosition `json:"start_position,omitempty"`
 }
 
 type cellChange struct {
@@ -87,7 +147,7 @@ type cellChange struct {
 
 type settingsPayload struct {
 	Settings GameSettingsDTO `json:"settings"`
-	Config   Config          `json:"config"`
+	Config   engine.Config   `json:"config"`
 }
 
 type ttCacheStatusResponse struct {
@@ -100,27 +160,40 @@ type ttCacheStatusResponse struct {
 	CapacityBytes  uint64  `json:"capacity_bytes"`
 	MaxMemoryBytes uint64  `json:"max_memory_bytes"`
 	MemoryUsage    float64 `json:"memory_usage"`
+	// HeuristicHash is the live weight set's; HeuristicHashes counts the
+	// entries stored under each weight set, most first.
+	HeuristicHash   string                 `json:"heuristic_hash"`
+	HeuristicHashes []ttHeuristicHashCount `json:"heuristic_hashes"`
+	// Restored is what loading the persisted caches did at startup.
+	Restored []engine.CacheLoadReport `json:"restored"`
+}
+
+type ttHeuristicHashCount struct {
+	Hash    string `json:"hash"`
+	Entries int    `json:"entries"`
+	Active  bool   `json:"active"`
 }
 
 type ttCacheEntryDTO struct {
-	Hash        string `json:"hash"`
-	Hits        uint32 `json:"hits"`
-	Depth       int    `json:"depth"`
-	Score       int32  `json:"score"`
-	Flag        string `json:"flag"`
-	BestMove    Move   `json:"best_move"`
-	GenWritten  uint32 `json:"gen_written"`
-	GenLastUsed uint32 `json:"gen_last_used"`
-	GrowthLeft  uint8  `json:"growth_left"`
-	GrowthRight uint8  `json:"growth_right"`
-	GrowthTop   uint8  `json:"growth_top"`
-	GrowthBot   uint8  `json:"growth_bottom"`
-	HitLeft     bool   `json:"hit_left"`
-	HitRight    bool   `json:"hit_right"`
-	HitTop      bool   `json:"hit_top"`
-	HitBottom   bool   `json:"hit_bottom"`
-	FrameW      uint8  `json:"frame_w"`
-	FrameH      uint8  `json:"frame_h"`
+	Hash          string      `json:"hash"`
+	HeuristicHash string      `json:"heuristic_hash"`
+	Hits          uint32      `json:"hits"`
+	Depth         int         `json:"depth"`
+	Score         int32       `json:"score"`
+	Flag          string      `json:"flag"`
+	BestMove      engine.Move `json:"best_move"`
+	GenWritten    uint32      `json:"gen_written"`
+	GenLastUsed   uint32      `json:"gen_last_used"`
+	GrowthLeft    uint8       `json:"growth_left"`
+	GrowthRight   uint8       `json:"growth_right"`
+	GrowthTop     uint8       `json:"growth_top"`
+	GrowthBot     uint8       `json:"growth_bottom"`
+	HitLeft       bool        `json:"hit_left"`
+	HitRight      bool        `json:"hit_right"`
+	HitTop        bool        `json:"hit_top"`
+	HitBottom     bool        `json:"hit_bottom"`
+	FrameW        uint8       `json:"frame_w"`
+	FrameH        uint8       `json:"frame_h"`
 }
 
 type ttCacheEntriesResponse struct {
@@ -130,12 +203,35 @@ type ttCacheEntriesResponse struct {
 	Total  int               `json:"total"`
 }
 
+type similarTTMatchDTO struct {
+	OffsetX  int             `json:"offset_x"`
+	OffsetY  int             `json:"offset_y"`
+	BestMove *engine.Move    `json:"best_move,omitempty"`
+	Entry    ttCacheEntryDTO `json:"entry"`
+}
+
+type similarPositionsResponse struct {
+	Pattern string                    `json:"pattern"`
+	Stones  int                       `json:"stones"`
+	Moves   []engine.SimilarMove      `json:"moves"`
+	Games   []engine.SimilarGameMatch `json:"games"`
+	TT      []similarTTMatchDTO       `json:"tt"`
+}
+
 func main() {
+	started := time.Now()
+	errs := &recentErrors{}
+	log.SetOutput(errs.logWriter(os.Stderr))
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		log.Fatalf("[backend] invalid TLS settings: %v", err)
+	}
+	admin := adminAuth{token: os.Getenv("ADMIN_TOKEN"), requireCert: tlsConfig != nil && tlsConfig.ClientCAs != nil}
 	var persistOnce sync.Once
 	persistOnShutdown := func(reason string) {
 		persistOnce.Do(func() {
 			log.Printf("[backend] persisting caches on %s", reason)
-			persistCaches()
+			engine.PersistCaches()
 		})
 	}
 	defer func() {
@@ -145,136 +241,1389 @@ func main() {
 		}
 	}()
 
-	controller := NewGameController(DefaultGameSettings())
-	loadPersistedCaches()
+	controller := engine.NewGameController(engine.DefaultGameSettings())
+	for _, err := range engine.LoadConfigEnv(os.Environ()) {
+		log.Printf("[backend] ignoring %v", err)
+	}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := engine.LoadConfigFile(path); err != nil {
+			log.Printf("[backend] config file %s not applied: %v", path, err)
+		}
+	}
+	engine.LoadPersistedCaches()
+	if err := engine.Events.Open(engine.GetConfig().AiEventLogPath); err != nil {
+		log.Printf("[backend] event log kept in memory only: %v", err)
+	}
+	defer engine.Events.Close()
+	if raw := os.Getenv("EVAL_VERIFY"); raw != "" {
+		samples, _ := strconv.Atoi(raw)
+		report := engine.VerifyEvaluation(engine.EvalVerifyOptions{Samples: samples, Seed: time.Now().UnixNano()}, engine.GetConfig())
+		log.Printf("[backend] evaluation verified on %d positions (seed %d): %d mismatches", report.Positions, report.Seed, report.MismatchCount)
+		for _, mismatch := range report.Mismatches {
+			log.Printf("[backend] eval mismatch: sample %d %s transform %d: want %.0f, got %.0f %s", mismatch.Sample, mismatch.Kind, mismatch.Transform, mismatch.Want, mismatch.Got, mismatch.Detail)
+		}
+	}
+	engine.SetWebhookNotifier(engine.NewWebhookNotifier(os.Getenv("NOTIFY_WEBHOOK_URL"), map[string]string{
+		engine.NotifyQueueDrained:       os.Getenv("NOTIFY_TEMPLATE_QUEUE_DRAINED"),
+		engine.NotifyTTFull:             os.Getenv("NOTIFY_TEMPLATE_TT_FULL"),
+		engine.NotifyCorrespondenceTurn: os.Getenv("NOTIFY_TEMPLATE_CORRESPONDENCE_TURN"),
+	}))
+	engine.SetMailer(engine.NewSMTPMailer(os.Getenv("NOTIFY_SMTP_ADDR"), os.Getenv("NOTIFY_SMTP_FROM"), os.Getenv("NOTIFY_SMTP_USER"), os.Getenv("NOTIFY_SMTP_PASSWORD")))
 	defer persistOnShutdown("exit")
 	hub := NewHub()
+	hub.compression = wsCompressionFromEnv("WS_COMPRESSION_GAME")
+	threats := &engine.ThreatTracker{}
+	commentary := &engine.CommentaryTracker{}
+	turns := &engine.TurnWatch{}
 	ghostHub := NewGhostHub()
+	ghostHub.compression = wsCompressionFromEnv("WS_COMPRESSION_GHOST")
 	analiticsHub := NewAnaliticsHub()
-	searchBacklogManager.SetAnaliticsHub(analiticsHub)
-	startSearchBacklogWorker(controller)
+	analiticsHub.compression = wsCompressionFromEnv("WS_COMPRESSION_ANALITICS")
+	replays := newReplayExports()
+	reviews := engine.NewReviewQueue()
+	matchHub := NewMatchHub()
+	matchHub.compression = wsCompressionFromEnv("WS_COMPRESSION_MATCH")
+	matchmaker := engine.NewMatchmaker()
+	matchmaker.SetPublisher(matchHub.Publish)
+	matchmaker.SetReviewer(func(record engine.GameRecord) {
+		if _, err := reviews.SubmitGame(record, 0, 0); err != nil {
+			log.Printf("[backend] match %s not reviewed: %v", record.MatchID, err)
+		}
+	})
+	reviews.SetFinishedHandler(func(review engine.Review) {
+		if review.MatchID != "" {
+			if report, ok := matchmaker.RecordFairPlay(review); ok && report.Flagged {
+				log.Printf("[backend] match %s flagged for engine agreement", report.MatchID)
+			}
+			return
+		}
+		if accuracy, ok := review.Accuracy(); ok && review.GameID != 0 {
+			controller.RecordAccuracy(review.GameID, accuracy)
+		}
+	})
+	simuls := engine.NewSimulManager()
+	simuls.SetPublisher(hub.publishSimul)
+	engine.SearchBacklogManager.SetAnaliticsPublisher(analiticsHub.Publish)
+	engine.StartSearchBacklogWorker(controller)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	controller.SetGhostPublisher(
-		func() bool { return ghostHub.HasClients() && GetConfig().GhostMode },
-		func(payload ghostPayload) {
+		func() bool { return ghostHub.HasClients() && engine.GetConfig().GhostMode },
+		func(payload engine.GhostPayload) {
 			ghostHub.Publish(payload)
 		},
 	)
+	controller.SetPremovePublisher(hub.publishPremove)
+	controller.SetScorePublisher(hub.publishScore)
 
 	go hub.Run(ctx.Done())
 	go ghostHub.Run(ctx.Done())
 	go analiticsHub.Run(ctx.Done())
+	go matchHub.Run(ctx.Done())
+	go reviews.Run(ctx)
+	go engine.Calibrations.Run(ctx)
+	go engine.ColorAudits.Run(ctx)
+	go engine.WatchConfigFile(ctx, func(_, next engine.Config) {
+		controller.ResetForConfigChange()
+		if engine.NoteLiveHeuristics(engine.HeuristicSourceManual) {
+			engine.SaveHeuristicRegistry()
+		}
+		hub.publish("settings", settingsPayload{
+			Settings: controllerSettingsDTO(controller.Settings()),
+			Config:   next,
+		})
+	})
+	go engine.RunGlobalStatsSampler(ctx)
+	go engine.RunBookReports(ctx, controller.Settings)
 	go func() {
 		ticker := time.NewTicker(50 * time.Millisecond)
 		defer ticker.Stop()
+		component := engine.Components.Register("ticker", "ticker", 2*time.Second, nil)
+		component.SetState("ticking", true)
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
+				component.Beat()
 				if controller.Tick() {
 					if entry, ok := controller.LatestHistoryEntry(); ok {
-						hub.broadcastHistory <- historyPayload{History: []historyEntryDTO{historyEntryToDTO(entry)}}
+						hub.publish("history", historyPayload{History: []historyEntryDTO{historyEntryToDTO(entry)}})
 					}
-					hub.broadcastStatus <- controllerStatus(controller)
+					hub.publish("status", controllerStatus(controller))
+					hub.publishThreats(controller, threats)
+					hub.publishCommentary(controller, commentary)
 				}
+				hub.publishTurnEvents(controller, turns)
+				matchmaker.ExpireSeats(time.Now())
 			}
 		}
 	}()
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
+	r.Use(errs.middleware)
 	r.Use(middleware.Recoverer)
 
 	r.Get("/api/ping", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 	})
 
+	r.Get("/api/admin/overview", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, adminOverview(started, controller, matchmaker, reviews, errs))
+	}))
+	r.Get("/api/admin/components", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, adminComponents())
+	}))
+	r.Get("/api/admin/fair-play", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"reports": engine.FairPlayReports(r.URL.Query().Get("flagged") == "true")})
+	}))
+	r.Handle("/debug/pprof/*", adminOnly(admin, pprofMux().ServeHTTP))
+	r.Get("/api/admin/verify", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, controller.Verify())
+	}))
+	r.Get("/api/admin/verify/eval", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		opts := engine.EvalVerifyOptions{Seed: time.Now().UnixNano()}
+		if raw := query.Get("samples"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				writeInvalidParameter(w, "samples", "invalid samples")
+				return
+			}
+			opts.Samples = parsed
+		}
+		if raw := query.Get("seed"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				writeInvalidParameter(w, "seed", "invalid seed")
+				return
+			}
+			opts.Seed = parsed
+		}
+		if raw := query.Get("size"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 5 || parsed > 19 {
+				writeInvalidParameter(w, "size", "invalid size")
+				return
+			}
+			opts.BoardSize = parsed
+		}
+		writeJSON(w, http.StatusOK, engine.VerifyEvaluation(opts, engine.GetConfig()))
+	}))
+	r.Get("/api/debug/profile", adminOnly(admin, serveCPUProfile(controller)))
+	pprofServer := startPprofServer(os.Getenv("PPROF_ADDR"))
+	if pprofServer != nil || admin.token != "" {
+		enableContentionProfiles()
+	}
+
 	r.Get("/api/status", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, controllerStatus(controller))
 	})
 
+	r.Get("/api/history", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		since := 0
+		if raw := query.Get("since"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 0 {
+				writeInvalidParameter(w, "since", "invalid since")
+				return
+			}
+			since = parsed
+		}
+		var gameID *uint64
+		if raw := query.Get("game_id"); raw != "" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				writeInvalidParameter(w, "game_id", "invalid game_id")
+				return
+			}
+			gameID = &parsed
+		}
+		writeJSON(w, http.StatusOK, historyDiff(controller, since, gameID))
+	})
+
+	r.Get("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		eventQuery := engine.EventQuery{
+			Limit: defaultEventsLimit,
+			Kind:  engine.EventKind(query.Get("kind")),
+			Actor: query.Get("actor"),
+		}
+		if raw := query.Get("since"); raw != "" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				writeInvalidParameter(w, "since", "invalid since")
+				return
+			}
+			eventQuery.Since = parsed
+		}
+		if raw := query.Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxEventsLimit {
+				writeInvalidParameter(w, "limit", fmt.Sprintf("limit must be between 1 and %d", maxEventsLimit))
+				return
+			}
+			eventQuery.Limit = parsed
+		}
+		if raw := query.Get("game_id"); raw != "" {
+			parsed, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				writeInvalidParameter(w, "game_id", "invalid game_id")
+				return
+			}
+			eventQuery.GameID = parsed
+		}
+		events, more := engine.Events.Query(eventQuery)
+		writeJSON(w, http.StatusOK, eventsResponse{Events: events, More: more, LastSeq: engine.Events.LastSeq()})
+	})
+
+	r.Get("/api/render", func(w http.ResponseWriter, r *http.Request) {
+		options, err := renderOptionsFromQuery(r)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		state, history, _ := controller.Snapshot()
+		entries := history.All()
+		ply := len(entries)
+		if raw := r.URL.Query().Get("ply"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				writeInvalidParameter(w, "ply", "invalid ply")
+				return
+			}
+			ply = parsed
+		}
+		img, err := engine.BoardImageFromHistory(controller.Settings(), controllerStartPosition(controller), entries, ply, state.WinningLine)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeBoardImage(w, img, options)
+	})
+	r.Post("/api/render", func(w http.ResponseWriter, r *http.Request) {
+		options, err := renderOptionsFromQuery(r)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		var payload engine.RenderRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		img, err := engine.BoardImageFromMoves(controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeBoardImage(w, img, options)
+	})
+
+	r.Get("/api/games", func(w http.ResponseWriter, r *http.Request) {
+		records := controller.GameRecords()
+		games := make([]gameSummaryDTO, 0, len(records))
+		for _, record := range records {
+			games = append(games, gameSummaryFromRecord(record))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"games": games})
+	})
+	r.Get("/api/games/{id}/annotations", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid game id")
+			return
+		}
+		record, ok := controller.GameRecord(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		writeJSON(w, http.StatusOK, annotationsFromRecord(record))
+	})
+	r.Post("/api/games/{id}/annotations", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid game id")
+			return
+		}
+		var payload engine.Annotation
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		annotations, ok, err := controller.Annotate(id, payload)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		response := annotationsPayload{GameID: id, Annotations: annotations}
+		hub.publish("annotations", response)
+		writeJSON(w, http.StatusOK, response)
+	})
+	r.Get("/api/games/{id}/report", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid game id")
+			return
+		}
+		record, ok := controller.GameRecord(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		if !record.Finished() {
+			writeError(w, http.StatusConflict, errCodeGameNotFinished, "game not finished")
+			return
+		}
+		var review *engine.Review
+		if latest, ok := reviews.LatestForGame(id); ok {
+			review = &latest
+		}
+		writeJSON(w, http.StatusOK, engine.BuildGameReport(record, review))
+	})
+	r.Get("/api/games/{id}/replay.gif", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid game id")
+			return
+		}
+		options, err := renderOptionsFromQuery(r)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		delay := engine.DefaultReplayFrameDelay
+		if raw := r.URL.Query().Get("delay"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 20 || parsed > 10000 {
+				writeInvalidParameter(w, "delay", "delay must be 20..10000 ms")
+				return
+			}
+			delay = time.Duration(parsed) * time.Millisecond
+		}
+		record, ok := controller.GameRecord(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		if !record.Finished() {
+			writeError(w, http.StatusConflict, errCodeGameNotFinished, "game not finished")
+			return
+		}
+		data, err := replays.GIF(record, options.cellSize, delay, options.numbers)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+
+	r.Post("/api/games/{id}/review", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid game id")
+			return
+		}
+		var payload struct {
+			Depth     int `json:"depth"`
+			TimeoutMs int `json:"timeout_ms"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			writeDecodeError(w, err)
+			return
+		}
+		record, ok := controller.GameRecord(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		if !record.Finished() {
+			writeError(w, http.StatusConflict, errCodeGameNotFinished, "game not finished")
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		review, err := reviews.SubmitGame(record, payload.Depth, payload.TimeoutMs)
+		if err != nil {
+			writeErr(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, review)
+	})
+	r.Post("/api/review/import", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.ReviewRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		review, err := reviews.SubmitImport(controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, review)
+	})
+	r.Get("/api/review", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"reviews": reviews.List()})
+	})
+	r.Get("/api/review/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid review id")
+			return
+		}
+		review, ok := reviews.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown review")
+			return
+		}
+		writeJSON(w, http.StatusOK, review)
+	})
+
+	r.Post("/api/calibration", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.CalibrationRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		calibration, err := engine.Calibrations.Submit(controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, calibration)
+	})
+	r.Get("/api/calibration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"calibrations": engine.Calibrations.List()})
+	})
+	r.Get("/api/calibration/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid calibration id")
+			return
+		}
+		calibration, ok := engine.Calibrations.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown calibration")
+			return
+		}
+		writeJSON(w, http.StatusOK, calibration)
+	})
+
+	r.Post("/api/experiments/color-balance", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.ColorAuditRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		audit, err := engine.ColorAudits.Submit(controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, audit)
+	})
+	r.Get("/api/experiments", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"experiments": engine.ColorAudits.List()})
+	})
+	r.Get("/api/experiments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			writeInvalidParameter(w, "id", "invalid experiment id")
+			return
+		}
+		audit, ok := engine.ColorAudits.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown experiment")
+			return
+		}
+		writeJSON(w, http.StatusOK, audit)
+	})
+
+	r.Post("/api/explorer", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.ExplorerRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		var extra []engine.GameRecord
+		if current, ok := controller.GameRecord(controller.GameID()); ok {
+			extra = append(extra, current)
+		}
+		tt := engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig())
+		explorer, err := engine.Explore(controller.Settings(), payload, extra, tt)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, explorer)
+	})
+	r.Get("/api/positions/{hash}/human-moves", func(w http.ResponseWriter, r *http.Request) {
+		hash, err := parseTTKey(chi.URLParam(r, "hash"))
+		if err != nil {
+			writeInvalidParameter(w, "hash", "invalid hash")
+			return
+		}
+		size := controller.Settings().BoardSize
+		if raw := r.URL.Query().Get("board_size"); raw != "" {
+			size, err = strconv.Atoi(raw)
+			if err != nil || size < 5 || size > 19 {
+				writeInvalidParameter(w, "board_size", "board_size must be between 5 and 19")
+				return
+			}
+		}
+		moves := engine.HumanMoves(hash, size)
+		total := 0
+		for _, move := range moves {
+			total += move.Count
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"hash":       fmt.Sprintf("0x%016x", hash),
+			"board_size": size,
+			"total":      total,
+			"moves":      moves,
+		})
+	})
+	r.Post("/api/positions/similar", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.SimilarRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		tt := engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig())
+		result, err := engine.FindSimilarPositions(controller.Settings(), payload, controller.GameRecords(), tt)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		response := similarPositionsResponse{
+			Pattern: result.Pattern,
+			Stones:  result.Stones,
+			Moves:   result.Moves,
+			Games:   result.Games,
+			TT:      make([]similarTTMatchDTO, 0, len(result.TT)),
+		}
+		for _, match := range result.TT {
+			response.TT = append(response.TT, similarTTMatchDTO{
+				OffsetX:  match.OffsetX,
+				OffsetY:  match.OffsetY,
+				BestMove: match.BestMove,
+				Entry:    ttEntryToDTO(match.Entry),
+			})
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	r.Get("/api/stats/global", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.GlobalStatistics())
+	})
+
+	r.Get("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"users": engine.UserProfiles.List()})
+	})
+	r.Post("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Name        string                 `json:"name"`
+			Preferences engine.UserPreferences `json:"preferences"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		profile, err := engine.UserProfiles.Create(payload.Name, payload.Preferences)
+		if errors.Is(err, engine.ErrUserExists) {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, profile)
+	})
+	r.Get("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		profile, ok := engine.UserProfiles.Get(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown user")
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	})
+	r.Put("/api/users/{id}/preferences", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.UserPreferences
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		profile, ok, err := engine.UserProfiles.SetPreferences(chi.URLParam(r, "id"), payload)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown user")
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	})
+	r.Delete("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !engine.UserProfiles.Delete(chi.URLParam(r, "id")) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown user")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+	})
+
+	r.Post("/api/matchmaking", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			User        string                  `json:"user"`
+			Preferences *engine.UserPreferences `json:"preferences"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		profile, ok := engine.UserProfiles.Get(payload.User)
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown user")
+			return
+		}
+		preferences := profile.Preferences
+		if payload.Preferences != nil {
+			preferences = *payload.Preferences
+		}
+		ticket, err := matchmaker.Join(profile.ID, preferences)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ticket)
+	})
+	r.Get("/api/matchmaking/{ticket}", func(w http.ResponseWriter, r *http.Request) {
+		ticket, ok := matchmaker.Ticket(chi.URLParam(r, "ticket"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown ticket")
+			return
+		}
+		writeJSON(w, http.StatusOK, ticket)
+	})
+	r.Delete("/api/matchmaking/{ticket}", func(w http.ResponseWriter, r *http.Request) {
+		if !matchmaker.Leave(chi.URLParam(r, "ticket")) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "ticket is not waiting")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"left": true})
+	})
+	r.Get("/api/matches/{id}", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := matchmaker.Match(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown match")
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+	r.Post("/api/matches/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Ticket string `json:"ticket"`
+			X      int    `json:"x"`
+			Y      int    `json:"y"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		if ticket, ok := matchmaker.Ticket(payload.Ticket); !ok || ticket.MatchID != chi.URLParam(r, "id") {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown match or ticket")
+			return
+		}
+		info, ok, err := matchmaker.Play(payload.Ticket, engine.Move{X: payload.X, Y: payload.Y})
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown match or ticket")
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+
+	r.Get("/api/simuls", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"simuls": simuls.List()})
+	})
+	r.Post("/api/simuls", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.SimulRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		info, tokens, err := simuls.Create(ctx, payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"simul": info, "tokens": tokens})
+	})
+	r.Get("/api/simuls/{id}", func(w http.ResponseWriter, r *http.Request) {
+		simul, ok := simuls.Get(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown simul")
+			return
+		}
+		writeJSON(w, http.StatusOK, simul.Info())
+	})
+	r.Delete("/api/simuls/{id}", func(w http.ResponseWriter, r *http.Request) {
+		simul, ok := simuls.Get(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown simul")
+			return
+		}
+		simul.Stop()
+		writeJSON(w, http.StatusOK, simul.Info())
+	})
+	r.Post("/api/simuls/{id}/boards/{board}/move", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Token string `json:"token"`
+			X     int    `json:"x"`
+			Y     int    `json:"y"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		simul, ok := simuls.Get(chi.URLParam(r, "id"))
+		index, err := strconv.Atoi(chi.URLParam(r, "board"))
+		if !ok || err != nil {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown simul or board")
+			return
+		}
+		board, ok, err := simul.Play(index, payload.Token, engine.Move{X: payload.X, Y: payload.Y})
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown simul or board")
+			return
+		}
+		if errors.Is(err, engine.ErrInvalidToken) {
+			writeErr(w, http.StatusForbidden, err)
+			return
+		}
+		if err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, board)
+	})
+
+	r.Get("/api/correspondence", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"games": engine.CorrespondenceGames.List()})
+	})
+	r.Post("/api/correspondence", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Black correspondencePlayerDTO `json:"black"`
+			White correspondencePlayerDTO `json:"white"`
+			Rules engine.UserPreferences  `json:"rules"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		game, err := engine.CorrespondenceGames.Create(payload.Black.player(), payload.White.player(), payload.Rules)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		engine.PersistCorrespondenceGames()
+		view, _ := engine.ViewCorrespondence(game, nil)
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"game":        view,
+			"black_token": game.Black.Token,
+			"white_token": game.White.Token,
+		})
+	})
+	r.Get("/api/correspondence/{id}", func(w http.ResponseWriter, r *http.Request) {
+		game, ok := engine.CorrespondenceGames.Get(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+			return
+		}
+		view, err := engine.ViewCorrespondence(game, engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig()))
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	r.Post("/api/correspondence/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Token string `json:"token"`
+			X     int    `json:"x"`
+			Y     int    `json:"y"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		game, ok, err := engine.CorrespondenceGames.Play(chi.URLParam(r, "id"), payload.Token, engine.Move{X: payload.X, Y: payload.Y})
+		writeCorrespondenceUpdate(w, game, ok, err)
+	})
+	r.Post("/api/correspondence/{id}/resign", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Token string `json:"token"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		game, ok, err := engine.CorrespondenceGames.Resign(chi.URLParam(r, "id"), payload.Token)
+		writeCorrespondenceUpdate(w, game, ok, err)
+	})
+
+	r.Get("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": engine.AnalysisSessions.List()})
+	})
+	r.Post("/api/sessions", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Name     string                `json:"name"`
+			GameID   uint64                `json:"game_id"`
+			Position *engine.StartPosition `json:"position"`
+			Moves    []engine.Move         `json:"moves"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		settings, start, moves := controller.Settings(), payload.Position, payload.Moves
+		if payload.GameID != 0 {
+			record, ok := controller.GameRecord(payload.GameID)
+			if !ok {
+				writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+				return
+			}
+			settings, start, moves = record.Settings, record.Start, make([]engine.Move, 0, len(record.Entries))
+			for _, entry := range record.Entries {
+				moves = append(moves, entry.Move)
+			}
+		}
+		session, err := engine.AnalysisSessions.Create(payload.Name, settings, start, moves, payload.GameID)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		engine.PersistAnalysisSessions()
+		writeJSON(w, http.StatusCreated, session)
+	})
+	r.Get("/api/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		session, ok := engine.AnalysisSessions.Get(chi.URLParam(r, "id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown session")
+			return
+		}
+		writeJSON(w, http.StatusOK, session)
+	})
+	r.Delete("/api/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !engine.AnalysisSessions.Delete(chi.URLParam(r, "id")) {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown session")
+			return
+		}
+		engine.PersistAnalysisSessions()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Get("/api/sessions/{id}/nodes/{node}", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		tt := engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig())
+		view, found, err := engine.AnalysisSessions.Node(chi.URLParam(r, "id"), node, tt)
+		if !found {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown session")
+			return
+		}
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	r.Post("/api/sessions/{id}/nodes/{node}/move", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		var payload apiMove
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		session, found, err := engine.AnalysisSessions.Play(chi.URLParam(r, "id"), node, engine.Move{X: payload.X, Y: payload.Y})
+		writeSessionUpdate(w, session, found, err)
+	})
+	r.Post("/api/sessions/{id}/nodes/{node}/select", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		session, found, err := engine.AnalysisSessions.Select(chi.URLParam(r, "id"), node)
+		writeSessionUpdate(w, session, found, err)
+	})
+	r.Post("/api/sessions/{id}/nodes/{node}/comment", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		var payload struct {
+			Comment string `json:"comment"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		session, found, err := engine.AnalysisSessions.Comment(chi.URLParam(r, "id"), node, payload.Comment)
+		writeSessionUpdate(w, session, found, err)
+	})
+	r.Post("/api/sessions/{id}/nodes/{node}/promote", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		session, found, err := engine.AnalysisSessions.Promote(chi.URLParam(r, "id"), node)
+		writeSessionUpdate(w, session, found, err)
+	})
+	r.Delete("/api/sessions/{id}/nodes/{node}", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		session, found, err := engine.AnalysisSessions.DeleteNode(chi.URLParam(r, "id"), node)
+		writeSessionUpdate(w, session, found, err)
+	})
+	r.Post("/api/sessions/{id}/nodes/{node}/evaluate", func(w http.ResponseWriter, r *http.Request) {
+		node, ok := sessionNodeParam(w, r)
+		if !ok {
+			return
+		}
+		var payload struct {
+			Depth     int   `json:"depth"`
+			TimeoutMs int   `json:"timeout_ms"`
+			MaxNodes  int64 `json:"max_nodes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			writeDecodeError(w, err)
+			return
+		}
+		eval, found, err := engine.AnalysisSessions.Evaluate(chi.URLParam(r, "id"), node, payload.Depth, payload.TimeoutMs, payload.MaxNodes)
+		if !found {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown session")
+			return
+		}
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		engine.PersistAnalysisSessions()
+		writeJSON(w, http.StatusOK, eval)
+	})
+
 	r.Post("/api/start", func(w http.ResponseWriter, r *http.Request) {
 		var payload struct {
-			Settings GameSettingsDTO `json:"settings"`
+			Settings      GameSettingsDTO `json:"settings"`
+			Board         [][]int         `json:"board"`
+			NextPlayer    int             `json:"next_player"`
+			CapturedBlack int             `json:"captured_black"`
+			CapturedWhite int             `json:"captured_white"`
+			User          string          `json:"user"`
+			Seed          int64           `json:"seed"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		if !decodeJSON(w, r, &payload) {
 			return
 		}
-		settings := settingsFromDTO(payload.Settings, DefaultGameSettings())
-		searchBacklogManager.RequestStop()
-		controller.StartGame(settings)
+		settings := settingsFromDTO(payload.Settings, engine.DefaultGameSettings())
+		if payload.User != "" {
+			profile, ok := engine.UserProfiles.Get(payload.User)
+			if !ok {
+				writeError(w, http.StatusNotFound, errCodeNotFound, "unknown user")
+				return
+			}
+			if payload.Settings.Mode != "ai_vs_human" {
+				writeError(w, http.StatusBadRequest, errCodeValidation, "only games against the AI can be linked to a user", apiErrorDetail{Field: "user", Message: "only games against the AI can be linked to a user"})
+				return
+			}
+			if payload.Settings.HumanPlayer == 0 && profile.Preferences.Color != 0 {
+				payload.Settings.HumanPlayer = profile.Preferences.Color
+				settings = settingsFromDTO(payload.Settings, engine.DefaultGameSettings())
+			}
+			settings = profile.Preferences.Apply(settings, controllerSettingsDTO(settings).HumanPlayer)
+		}
+		settings.Seed = payload.Seed
+		engine.SearchBacklogManager.RequestStop()
+		if payload.Board != nil {
+			position := engine.StartPosition{
+				Board:         payload.Board,
+				NextPlayer:    payload.NextPlayer,
+				CapturedBlack: payload.CapturedBlack,
+				CapturedWhite: payload.CapturedWhite,
+			}
+			if err := controller.StartGameFromPosition(settings, position); err != nil {
+				writeErr(w, http.StatusBadRequest, err)
+				return
+			}
+		} else {
+			controller.StartGame(settings)
+		}
+		if payload.User != "" {
+			_ = controller.SetUser(payload.User)
+		}
 		writeJSON(w, http.StatusOK, controllerStatus(controller))
-		hub.broadcastReset <- resetFromController(controller)
+		hub.publish("reset", resetFromController(controller))
 	})
 
 	r.Post("/api/stop", func(w http.ResponseWriter, r *http.Request) {
 		settings := controller.Settings()
-		searchBacklogManager.RequestStop()
+		settings.Seed = 0
+		engine.SearchBacklogManager.RequestStop()
 		controller.Reset(settings)
 		writeJSON(w, http.StatusOK, controllerStatus(controller))
-		hub.broadcastReset <- resetFromController(controller)
+		hub.publish("reset", resetFromController(controller))
+	})
+
+	r.Get("/api/config/source", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.ConfigSources())
 	})
 
 	r.Post("/api/settings", func(w http.ResponseWriter, r *http.Request) {
 		var payload struct {
 			Settings *GameSettingsDTO `json:"settings"`
-			Config   *Config          `json:"config"`
+			Config   *engine.Config   `json:"config"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		if !decodeJSON(w, r, &payload) {
 			return
 		}
 		if payload.Config != nil {
-			configStore.Update(*payload.Config)
+			if _, err := engine.ParseSchedule(payload.Config.AiQueueSchedule); err != nil {
+				writeErr(w, http.StatusBadRequest, err)
+				return
+			}
+			engine.UpdateConfig(*payload.Config)
 			controller.ResetForConfigChange()
+			if engine.NoteLiveHeuristics(engine.HeuristicSourceManual) {
+				engine.SaveHeuristicRegistry()
+			}
 		}
 		if payload.Settings != nil {
 			settings := settingsFromDTO(*payload.Settings, controller.Settings())
 			controller.UpdateSettings(settings, false)
 		}
-		hub.broadcastSettings <- settingsPayload{
+		hub.publish("settings", settingsPayload{
 			Settings: controllerSettingsDTO(controller.Settings()),
-			Config:   GetConfig(),
-		}
+			Config:   engine.GetConfig(),
+		})
 		writeJSON(w, http.StatusOK, controllerStatus(controller))
 	})
 
 	r.Post("/api/move", func(w http.ResponseWriter, r *http.Request) {
 		var payload apiMove
-		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		if !decodeJSON(w, r, &payload) {
 			return
 		}
-		applied, errMsg := controller.ApplyHumanMove(Move{X: payload.X, Y: payload.Y})
+		applied, errMsg := controller.ApplyHumanMove(engine.Move{X: payload.X, Y: payload.Y})
 		if !applied {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": errMsg})
+			writeError(w, http.StatusBadRequest, moveRejectionCode(errMsg), errMsg, pendingWinDetails(controller, errMsg)...)
 			return
 		}
-		searchBacklogManager.RequestStop()
+		engine.SearchBacklogManager.RequestStop()
 		if entry, ok := controller.LatestHistoryEntry(); ok {
-			hub.broadcastHistory <- historyPayload{History: []historyEntryDTO{historyEntryToDTO(entry)}}
+			hub.publish("history", historyPayload{History: []historyEntryDTO{historyEntryToDTO(entry)}})
+		}
+		hub.publish("status", controllerStatus(controller))
+		hub.publishThreats(controller, threats)
+		writeJSON(w, http.StatusOK, controllerStatus(controller))
+	})
+
+	r.Post("/api/premove", func(w http.ResponseWriter, r *http.Request) {
+		var payload apiMove
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		event, err := queuePremove(hub, controller, threats, engine.Move{X: payload.X, Y: payload.Y})
+		if err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, event)
+	})
+	r.Delete("/api/premove", func(w http.ResponseWriter, r *http.Request) {
+		event, ok := controller.CancelPremove()
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "no premove queued")
+			return
+		}
+		hub.publishPremove(event)
+		writeJSON(w, http.StatusOK, event)
+	})
+
+	r.Post("/api/move/preview", func(w http.ResponseWriter, r *http.Request) {
+		var payload apiMove
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		preview, err := controller.PreviewMove(engine.Move{X: payload.X, Y: payload.Y})
+		if err != nil {
+			writeErr(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, preview)
+	})
+
+	r.Post("/api/analyse", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.AnalyzeRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		response, err := engine.Analyze(controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	r.Get("/api/editor", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.Editor.View(controller.Settings()))
+	})
+	r.Post("/api/editor/clear", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.Editor.Clear(controller.Settings()))
+	})
+	r.Post("/api/editor/load", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.Editor.Load(controller.Settings(), controller.State()))
+	})
+	r.Post("/api/editor/stone", func(w http.ResponseWriter, r *http.Request) {
+		var payload apiMove
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		view, err := engine.Editor.SetStone(controller.Settings(), engine.Move{X: payload.X, Y: payload.Y}, payload.Player)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	r.Post("/api/editor/captures", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			CapturedBlack int `json:"captured_black"`
+			CapturedWhite int `json:"captured_white"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		view, err := engine.Editor.SetCaptures(controller.Settings(), payload.CapturedBlack, payload.CapturedWhite)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	r.Post("/api/editor/next_player", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			NextPlayer int `json:"next_player"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		view, err := engine.Editor.SetNextPlayer(controller.Settings(), payload.NextPlayer)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	})
+	r.Post("/api/editor/start", func(w http.ResponseWriter, r *http.Request) {
+		settings := controller.Settings()
+		settings.Seed = 0
+		engine.SearchBacklogManager.RequestStop()
+		if err := controller.StartGameFromPosition(settings, engine.Editor.Position(settings)); err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
 		}
-		hub.broadcastStatus <- controllerStatus(controller)
 		writeJSON(w, http.StatusOK, controllerStatus(controller))
+		hub.publish("reset", resetFromController(controller))
+	})
+	r.Post("/api/editor/analyse", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.AnalyzeRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		settings := controller.Settings()
+		position := engine.Editor.Position(settings)
+		payload.Position = &position
+		payload.Moves = nil
+		response, err := engine.Analyze(settings, payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	r.Post("/api/simulate", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.SimulateRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		result, err := engine.Simulate(r.Context(), controller.Settings(), payload)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+	r.Post("/api/simulate/batch", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.SimulateBatchRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		if err := payload.Validate(); err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		summary := engine.SimulateBatch(r.Context(), controller.Settings(), payload, func(game engine.SimulateBatchGame) {
+			_ = encoder.Encode(map[string]any{"type": "game", "game": game})
+			if flusher != nil {
+				flusher.Flush()
+			}
+		})
+		_ = encoder.Encode(map[string]any{"type": "summary", "summary": summary})
+	})
+	r.Post("/api/duel", func(w http.ResponseWriter, r *http.Request) {
+		var payload engine.DuelRequest
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		engine.SearchBacklogManager.RequestStop()
+		writeJSON(w, http.StatusOK, engine.Duel(r.Context(), controller.Settings(), payload))
 	})
 
 	r.Get("/api/analitics/queue", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, http.StatusOK, analiticsQueueResponse{
-			Queue:        searchBacklogManager.TopAnaliticsQueue(analiticsTopBoardsLimit()),
-			TotalInQueue: searchBacklogManager.TotalAnaliticsQueue(),
+		writeJSON(w, http.StatusOK, engine.AnaliticsQueueResponse{
+			Queue:        engine.SearchBacklogManager.TopAnaliticsQueue(engine.AnaliticsTopBoardsLimit()),
+			TotalInQueue: engine.SearchBacklogManager.TotalAnaliticsQueue(),
+		})
+	})
+	// announcePromotion applies a change of the live heuristics to the game
+	// and records it.
+	announcePromotion := func(promotion engine.HeuristicPromotion) {
+		if !promotion.Changed {
+			return
+		}
+		controller.ResetForConfigChange()
+		engine.Events.Record(engine.Event{Kind: engine.EventHeuristicsPromoted, Actor: engine.ActorAPI, Promotion: &promotion})
+		engine.SaveHeuristicRegistry()
+		hub.publish("settings", settingsPayload{
+			Settings: controllerSettingsDTO(controller.Settings()),
+			Config:   engine.GetConfig(),
+		})
+	}
+	r.Get("/api/heuristics", func(w http.ResponseWriter, r *http.Request) {
+		config := engine.GetConfig()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"heuristics":     engine.ResolvedHeuristics(config),
+			"heuristic_hash": engine.HeuristicHash(config),
 		})
 	})
+	r.Post("/api/admin/heuristics/promote", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Heuristics   *engine.HeuristicConfig `json:"heuristics"`
+			Source       string                  `json:"source"`
+			KeepPrevious bool                    `json:"keep_previous"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		if payload.Heuristics == nil {
+			writeInvalidParameter(w, "heuristics", "heuristics is required")
+			return
+		}
+		promotion, err := engine.PromoteHeuristics(*payload.Heuristics, payload.Source, payload.KeepPrevious)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		announcePromotion(promotion)
+		writeJSON(w, http.StatusOK, promotion)
+	}))
+	r.Get("/api/heuristics/registry", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, engine.HeuristicRegistry.List())
+	})
+	r.Post("/api/heuristics/registry", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Heuristics   *engine.HeuristicConfig `json:"heuristics"`
+			Source       string                  `json:"source"`
+			GauntletRate *float64                `json:"gauntlet_rate"`
+			GauntletElo  *float64                `json:"gauntlet_elo"`
+		}
+		if !decodeJSON(w, r, &payload) {
+			return
+		}
+		if payload.Heuristics == nil {
+			writeInvalidParameter(w, "heuristics", "heuristics is required")
+			return
+		}
+		if err := engine.ValidateHeuristics(*payload.Heuristics); err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		record := engine.HeuristicRegistry.Register(*payload.Heuristics, payload.Source)
+		if payload.GauntletRate != nil || payload.GauntletElo != nil {
+			record, _ = engine.HeuristicRegistry.SetGauntlet(record.Hash, payload.GauntletRate, payload.GauntletElo)
+		}
+		engine.SaveHeuristicRegistry()
+		writeJSON(w, http.StatusOK, record)
+	})
+	r.Get("/api/heuristics/registry/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		record, ok := engine.HeuristicRegistry.Get(chi.URLParam(r, "hash"))
+		if !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown heuristics")
+			return
+		}
+		writeJSON(w, http.StatusOK, record)
+	})
+	r.Post("/api/admin/heuristics/registry/{hash}/activate", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := engine.HeuristicRegistry.Get(chi.URLParam(r, "hash")); !ok {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "unknown heuristics")
+			return
+		}
+		promotion, err := engine.ActivateHeuristics(chi.URLParam(r, "hash"), "activate")
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		announcePromotion(promotion)
+		writeJSON(w, http.StatusOK, promotion)
+	}))
+	r.Post("/api/admin/heuristics/rollback", adminOnly(admin, func(w http.ResponseWriter, r *http.Request) {
+		promotion, err := engine.RollbackHeuristics()
+		if err != nil {
+			writeError(w, http.StatusConflict, errCodeConflict, err.Error())
+			return
+		}
+		announcePromotion(promotion)
+		writeJSON(w, http.StatusOK, promotion)
+	}))
+	r.Get("/api/heuristics/compare", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		comparison, err := engine.HeuristicRegistry.Compare(query.Get("a"), query.Get("b"))
+		if err != nil {
+			writeErr(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, comparison)
+	})
 	r.Get("/api/cache/tt", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, ttCacheStatus())
 	})
 	r.Delete("/api/cache/tt", func(w http.ResponseWriter, r *http.Request) {
-		FlushGlobalCaches()
+		engine.FlushGlobalCaches()
+		engine.Events.Record(engine.Event{Kind: engine.EventCachesFlushed, Actor: engine.ActorAPI})
 		writeJSON(w, http.StatusOK, map[string]any{
 			"cleared": true,
 		})
@@ -293,16 +1642,89 @@ func main() {
 		}
 		writeJSON(w, http.StatusOK, ttCacheEntries(offset, limit))
 	})
+	r.Get("/api/book/report", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format != "" && format != "json" && format != "text" && format != "sgf" {
+			writeInvalidParameter(w, "format", "format must be json, text or sgf")
+			return
+		}
+		refresh := false
+		if raw := query.Get("refresh"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeInvalidParameter(w, "refresh", "invalid refresh")
+				return
+			}
+			refresh = parsed
+		}
+		report, ok := engine.LatestBookReport()
+		if refresh || !ok {
+			report = engine.RefreshBookReport(controller.Settings())
+		}
+		switch format {
+		case "text":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_ = report.WriteText(w)
+		case "sgf":
+			w.Header().Set("Content-Type", "application/x-go-sgf")
+			w.Header().Set("Content-Disposition", `attachment; filename="solved_openings.sgf"`)
+			w.WriteHeader(http.StatusOK)
+			_ = report.WriteSGF(w)
+		default:
+			writeJSON(w, http.StatusOK, report)
+		}
+	})
+	r.Get("/api/cache/tt/export", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = engine.TTExportCSV
+		}
+		if format != engine.TTExportCSV && format != engine.TTExportJSONL {
+			writeInvalidParameter(w, "format", "format must be csv or jsonl")
+			return
+		}
+		opts := engine.TTExportOptions{HeuristicHash: strings.ToLower(query.Get("heuristic_hash"))}
+		if raw := query.Get("min_depth"); raw != "" {
+			depth, err := strconv.Atoi(raw)
+			if err != nil || depth < 0 {
+				writeInvalidParameter(w, "min_depth", "invalid min_depth")
+				return
+			}
+			opts.MinDepth = depth
+		}
+		if raw := query.Get("include_unknown"); raw != "" {
+			include, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeInvalidParameter(w, "include_unknown", "invalid include_unknown")
+				return
+			}
+			opts.IncludeUnknown = include
+		}
+		tt := engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig())
+		if format == engine.TTExportCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tt_exact.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+		if _, err := engine.ExportTT(w, format, tt, controller.GameRecords(), opts); err != nil {
+			log.Printf("[backend] tt export interrupted: %v", err)
+		}
+	})
 	r.Delete("/api/cache/tt/entries/{hash}", func(w http.ResponseWriter, r *http.Request) {
 		hashRaw := chi.URLParam(r, "hash")
 		hash, err := parseTTKey(hashRaw)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid hash"})
+			writeInvalidParameter(w, "hash", "invalid hash")
 			return
 		}
-		config := GetConfig()
-		cache := SharedSearchCache()
-		tt := ensureTT(cache, config)
+		config := engine.GetConfig()
+		cache := engine.SharedSearchCache()
+		tt := engine.EnsureTT(cache, config)
 		if tt == nil {
 			writeJSON(w, http.StatusOK, map[string]any{"deleted": false, "hash": fmt.Sprintf("0x%016x", hash)})
 			return
@@ -315,7 +1737,7 @@ func main() {
 	})
 
 	r.Get("/ws/", func(w http.ResponseWriter, r *http.Request) {
-		serveWS(hub, controller, w, r)
+		serveWS(hub, controller, threats, w, r)
 	})
 	r.Get("/ws/ghost", func(w http.ResponseWriter, r *http.Request) {
 		serveGhostWS(ghostHub, w, r)
@@ -323,14 +1745,23 @@ func main() {
 	r.Get("/ws/analitics", func(w http.ResponseWriter, r *http.Request) {
 		serveAnaliticsWS(analiticsHub, w, r)
 	})
+	r.Get("/ws/match", func(w http.ResponseWriter, r *http.Request) {
+		serveMatchWS(matchHub, matchmaker, w, r)
+	})
 
+	proxy := proxyConfigFromEnv()
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: r,
+		Addr:      ":8080",
+		Handler:   proxy.handler(r),
+		TLSConfig: tlsConfig,
 	}
 	serverErrCh := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		serve := server.ListenAndServe
+		if tlsConfig != nil {
+			serve = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErrCh <- err
 		}
 		close(serverErrCh)
@@ -339,7 +1770,11 @@ func main() {
 	sigCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stopSignals()
 
-	log.Println("backend listening on :8080")
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	log.Printf("backend listening on %s://:8080%s", scheme, proxy.basePath)
 	var runErr error
 	select {
 	case <-sigCtx.Done():
@@ -353,6 +1788,9 @@ func main() {
 
 	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelShutdown()
+	if pprofServer != nil {
+		_ = pprofServer.Shutdown(shutdownCtx)
+	}
 	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Printf("[backend] graceful shutdown failed: %v", err)
 		if closeErr := server.Close(); closeErr != nil && !errors.Is(closeErr, http.ErrServerClosed) {
@@ -361,28 +1799,49 @@ func main() {
 	}
 
 	cancel()
-	searchBacklogManager.RequestStop()
+	engine.SearchBacklogManager.RequestStop()
 	persistOnShutdown("shutdown")
 	if runErr != nil {
 		log.Printf("[backend] exiting after server error: %v", runErr)
 	}
 }
 
-func serveWS(hub *Hub, controller *GameController, w http.ResponseWriter, r *http.Request) {
-	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+// queuePremove queues move for the human and tells every client; a move
+// played at once because the human was already to move is broadcast like
+// any other move.
+func queuePremove(hub *Hub, controller *engine.GameController, threats *engine.ThreatTracker, move engine.Move) (engine.PremoveEvent, error) {
+	event, err := controller.QueuePremove(move)
+	if err != nil {
+		return event, err
+	}
+	hub.publishPremove(event)
+	if event.State == engine.PremoveApplied {
+		engine.SearchBacklogManager.RequestStop()
+		if entry, ok := controller.LatestHistoryEntry(); ok {
+			hub.publish("history", historyPayload{History: []historyEntryDTO{historyEntryToDTO(entry)}})
+		}
+		hub.publish("status", controllerStatus(controller))
+		hub.publishThreats(controller, threats)
+	}
+	return event, nil
+}
+
+func serveWS(hub *Hub, controller *engine.GameController, threats *engine.ThreatTracker, w http.ResponseWriter, r *http.Request) {
+	upgrader := newWSUpgrader(hub.compression)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-	client := &Client{hub: hub, send: make(chan []byte, 16)}
+	hub.compression.apply(conn)
+	client := &Client{wsClient: newWSClient(wsBinary(conn)), hub: hub}
 	hub.Register(client)
 
 	status := controllerStatus(controller)
-	client.sendJSON(wsMessage{Type: "status", Payload: mustMarshal(status)})
+	client.sendFrame(newWSFrame(wsMessage{Type: "status", Payload: mustMarshal(status)}), false)
 
 	go func() {
 		defer conn.Close()
-		if err := writeWSWithHeartbeat(conn, client.send); err != nil {
+		if err := writeWSWithHeartbeat(conn, client.queue, client.binary); err != nil {
 			return
 		}
 	}()
@@ -400,33 +1859,181 @@ func serveWS(hub *Hub, controller *GameController, w http.ResponseWriter, r *htt
 		switch msg.Type {
 		case "request_status":
 			status := controllerStatus(controller)
-			client.sendJSON(wsMessage{Type: "status", Payload: mustMarshal(status)})
+			client.sendFrame(newWSFrame(wsMessage{Type: "status", Payload: mustMarshal(status)}), false)
+		case "premove":
+			var move apiMove
+			if err := json.Unmarshal(msg.Payload, &move); err != nil {
+				continue
+			}
+			event, err := queuePremove(hub, controller, threats, engine.Move{X: move.X, Y: move.Y})
+			if err != nil {
+				event.State, event.Reason = engine.PremoveRejected, err.Error()
+				client.sendFrame(newWSFrame(wsMessage{Type: "premove", Payload: mustMarshal(event)}), false)
+			}
+		case "cancel_premove":
+			if event, ok := controller.CancelPremove(); ok {
+				hub.publishPremove(event)
+			}
 		}
 	}
 }
 
-func controllerStatus(controller *GameController) StatusResponse {
+type correspondencePlayerDTO struct {
+	Name    string `json:"name"`
+	Webhook string `json:"webhook"`
+	Email   string `json:"email"`
+}
+
+func (p correspondencePlayerDTO) player() engine.CorrespondencePlayer {
+	return engine.CorrespondencePlayer{Name: p.Name, Webhook: p.Webhook, Email: p.Email}
+}
+
+func writeCorrespondenceUpdate(w http.ResponseWriter, game engine.CorrespondenceGame, found bool, err error) {
+	if !found {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "unknown game")
+		return
+	}
+	if errors.Is(err, engine.ErrInvalidToken) {
+		writeErr(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		writeErr(w, http.StatusConflict, err)
+		return
+	}
+	engine.PersistCorrespondenceGames()
+	view, err := engine.ViewCorrespondence(game, engine.EnsureTT(engine.SharedSearchCache(), engine.GetConfig()))
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+func sessionNodeParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	node, err := strconv.Atoi(chi.URLParam(r, "node"))
+	if err != nil || node < 0 {
+		writeInvalidParameter(w, "node", "invalid node id")
+		return 0, false
+	}
+	return node, true
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	var fieldErr *engine.FieldError
+	switch {
+	case errors.Is(err, engine.ErrUnknownSessionNode):
+		writeErr(w, http.StatusNotFound, err)
+	case errors.As(err, &fieldErr):
+		writeErr(w, http.StatusBadRequest, err)
+	default:
+		writeErr(w, http.StatusConflict, err)
+	}
+}
+
+func writeSessionUpdate(w http.ResponseWriter, session engine.AnalysisSession, found bool, err error) {
+	if !found {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "unknown session")
+		return
+	}
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	engine.PersistAnalysisSessions()
+	writeJSON(w, http.StatusOK, session)
+}
+
+func controllerStatus(controller *engine.GameController) StatusResponse {
 	state := controller.State()
 	settings := controllerSettingsDTO(controller.Settings())
 	gameSettings := controller.Settings()
+	winConditions := engine.WinConditionsFromState(state, gameSettings)
 	return StatusResponse{
 		Settings:           settings,
-		Config:             GetConfig(),
-		NextPlayer:         playerToInt(state.ToMove),
-		Winner:             winnerFromStatus(state.Status),
+		Config:             engine.GetConfig(),
+		NextPlayer:         engine.PlayerToInt(state.ToMove),
+		Winner:             engine.WinnerFromStatus(state.Status),
 		BoardSize:          state.Board.Size(),
-		Status:             statusToString(state.Status),
+		Status:             engine.StatusToString(state.Status),
 		History:            historyToDTO(controller.History()),
 		WinReason:          winReasonFromState(state),
-		WinningLine:        append([]Move(nil), state.WinningLine...),
-		WinningCapturePair: append([]Move(nil), state.WinningCapturePair...),
+		WinningLine:        append([]engine.Move(nil), state.WinningLine...),
+		WinningCapturePair: append([]engine.Move(nil), state.WinningCapturePair...),
 		CaptureWinStones:   gameSettings.CaptureWinStones,
 		TurnStartedAtMs:    controller.CurrentTurnStartedAtMs(),
+		StartPosition:      controllerStartPosition(controller),
+		MustCapture:        state.MustCapture,
+		ForcedCaptureMoves: append([]engine.Move{}, state.ForcedCaptureMoves...),
+		PendingAlignment:   append([]engine.Move{}, state.PendingAlignment...),
+		PendingPlayer:      winConditions.PendingAlignmentPlayer,
+		WinConditions:      winConditions,
+		PendingWin:         engine.PendingWinFromState(state, gameSettings),
+		CaptureWin:         winConditions.CaptureWinMove,
+		GameID:             controller.GameID(),
+		Hash:               fmt.Sprintf("%016x", state.Hash),
+		PositionKey:        fmt.Sprintf("0x%016x", engine.PositionKey(state)),
+		User:               controller.User(),
+		Seed:               gameSettings.Seed,
+		Premove:            controllerPremove(controller),
+		HeuristicHash:      engine.HeuristicHash(engine.GetConfig()),
+		BlackHeuristicHash: overrideHeuristicHash(gameSettings.BlackHeuristics),
+		WhiteHeuristicHash: overrideHeuristicHash(gameSettings.WhiteHeuristics),
+		Phase:              engine.ClassifyGamePhase(state, engine.NewRules(gameSettings), engine.GetConfig()),
+		ForcedLine:         controllerForcedLine(controller),
+	}
+}
+
+func controllerForcedLine(controller *engine.GameController) *engine.ForcedLine {
+	line, ok := controller.ForcedLine()
+	if !ok {
+		return nil
+	}
+	return &line
+}
+
+// overrideHeuristicHash is the hash of a side's own weights, empty when it
+// plays with the live ones.
+func overrideHeuristicHash(heuristics *engine.HeuristicConfig) string {
+	if heuristics == nil {
+		return ""
+	}
+	config := engine.GetConfig()
+	config.Heuristics = *heuristics
+	return engine.HeuristicHash(config)
+}
+
+func controllerPremove(controller *engine.GameController) *engine.Move {
+	move, ok := controller.Premove()
+	if !ok {
+		return nil
+	}
+	return &move
+}
+
+func controllerStartPosition(controller *engine.GameController) *engine.StartPosition {
+	position, ok := controller.StartPosition()
+	if !ok {
+		return nil
+	}
+	return &position
+}
+
+// pendingWinDetails explains a move refused because a five must be broken
+// by capture.
+func pendingWinDetails(controller *engine.GameController, reason string) []apiErrorDetail {
+	if reason != "must capture" {
+		return nil
 	}
+	pending := engine.PendingWinFromState(controller.State(), controller.Settings())
+	if pending == nil {
+		return nil
+	}
+	return []apiErrorDetail{{Field: "move", Message: pending.Explanation}}
 }
 
-func winReasonFromState(state GameState) string {
-	if winnerFromStatus(state.Status) == 0 {
+func winReasonFromState(state engine.GameState) string {
+	if engine.WinnerFromStatus(state.Status) == 0 {
 		return ""
 	}
 	if len(state.WinningLine) > 0 {
@@ -435,123 +2042,163 @@ func winReasonFromState(state GameState) string {
 	return "capture"
 }
 
-func settingsFromDTO(dto GameSettingsDTO, base GameSettings) GameSettings {
+func settingsFromDTO(dto GameSettingsDTO, base engine.GameSettings) engine.GameSettings {
 	settings := base
 	switch dto.Mode {
 	case "ai_vs_ai":
-		settings.BlackType = PlayerAI
-		settings.WhiteType = PlayerAI
+		settings.BlackType = engine.PlayerAI
+		settings.WhiteType = engine.PlayerAI
 	case "human_vs_human":
-		settings.BlackType = PlayerHuman
-		settings.WhiteType = PlayerHuman
+		settings.BlackType = engine.PlayerHuman
+		settings.WhiteType = engine.PlayerHuman
 	case "ai_vs_human":
 		if dto.HumanPlayer == 2 {
-			settings.BlackType = PlayerAI
-			settings.WhiteType = PlayerHuman
+			settings.BlackType = engine.PlayerAI
+			settings.WhiteType = engine.PlayerHuman
 		} else {
-			settings.BlackType = PlayerHuman
-			settings.WhiteType = PlayerAI
+			settings.BlackType = engine.PlayerHuman
+			settings.WhiteType = engine.PlayerAI
 		}
 	}
 	return settings
 }
 
-func controllerSettingsDTO(settings GameSettings) GameSettingsDTO {
+func controllerSettingsDTO(settings engine.GameSettings) GameSettingsDTO {
 	mode := "ai_vs_human"
-	if settings.BlackType == PlayerAI && settings.WhiteType == PlayerAI {
+	if settings.BlackType == engine.PlayerAI && settings.WhiteType == engine.PlayerAI {
 		mode = "ai_vs_ai"
-	} else if settings.BlackType == PlayerHuman && settings.WhiteType == PlayerHuman {
+	} else if settings.BlackType == engine.PlayerHuman && settings.WhiteType == engine.PlayerHuman {
 		mode = "human_vs_human"
 	} else if settings.BlackType != settings.WhiteType {
 		mode = "ai_vs_human"
 	}
 	humanPlayer := 0
-	if settings.BlackType == PlayerHuman && settings.WhiteType != PlayerHuman {
+	if settings.BlackType == engine.PlayerHuman && settings.WhiteType != engine.PlayerHuman {
 		humanPlayer = 1
-	} else if settings.WhiteType == PlayerHuman && settings.BlackType != PlayerHuman {
+	} else if settings.WhiteType == engine.PlayerHuman && settings.BlackType != engine.PlayerHuman {
 		humanPlayer = 2
-	} else if settings.BlackType == PlayerHuman && settings.WhiteType == PlayerHuman {
+	} else if settings.BlackType == engine.PlayerHuman && settings.WhiteType == engine.PlayerHuman {
 		humanPlayer = 1
 	}
 	return GameSettingsDTO{Mode: mode, HumanPlayer: humanPlayer}
 }
 
-func boardToSlice(board Board) [][]int {
-	size := board.Size()
-	rows := make([][]int, size)
-	for y := 0; y < size; y++ {
-		rows[y] = make([]int, size)
-		for x := 0; x < size; x++ {
-			cell := board.At(x, y)
-			rows[y][x] = cellToInt(cell)
-		}
+// historyDiff returns the entries after index since. When the client was
+// following another game (a different game id, or a since beyond the current
+// history) it gets the whole history back with Reset set.
+func historyDiff(controller *engine.GameController, since int, gameID *uint64) historyDiffResponse {
+	state, history, currentID := controller.Snapshot()
+	entries := history.All()
+	response := historyDiffResponse{
+		GameID:          currentID,
+		Since:           since,
+		Total:           len(entries),
+		Entries:         []historyEntryDTO{},
+		Hash:            fmt.Sprintf("%016x", state.Hash),
+		Status:          engine.StatusToString(state.Status),
+		NextPlayer:      engine.PlayerToInt(state.ToMove),
+		Winner:          engine.WinnerFromStatus(state.Status),
+		WinReason:       winReasonFromState(state),
+		WinningLine:     append([]engine.Move(nil), state.WinningLine...),
+		PendingWin:      engine.PendingWinFromState(state, controller.Settings()),
+		TurnStartedAtMs: controller.CurrentTurnStartedAtMs(),
+	}
+	if since > len(entries) || (gameID != nil && *gameID != currentID) {
+		response.Reset = true
+		response.Since = 0
+		since = 0
 	}
-	return rows
+	for _, entry := range entries[since:] {
+		response.Entries = append(response.Entries, historyEntryToDTO(entry))
+	}
+	return response
 }
 
-func cellToInt(cell Cell) int {
-	switch cell {
-	case CellBlack:
-		return 1
-	case CellWhite:
-		return 2
-	default:
-		return 0
-	}
+type gameSummaryDTO struct {
+	ID          uint64 `json:"id"`
+	Status      string `json:"status"`
+	Winner      int    `json:"winner"`
+	Moves       int    `json:"moves"`
+	BoardSize   int    `json:"board_size"`
+	Seeded      bool   `json:"seeded"`
+	Annotations int    `json:"annotations"`
+	Seed        int64  `json:"seed"`
+	// Accuracy is set once the game was reviewed.
+	Accuracy *engine.GameAccuracy `json:"accuracy,omitempty"`
 }
 
-func intToCell(value int) Cell {
-	switch value {
-	case 1:
-		return CellBlack
-	case 2:
-		return CellWhite
-	default:
-		return CellEmpty
+func gameSummaryFromRecord(record engine.GameRecord) gameSummaryDTO {
+	return gameSummaryDTO{
+		ID:          record.ID,
+		Status:      engine.StatusToString(record.Status),
+		Winner:      engine.WinnerFromStatus(record.Status),
+		Moves:       len(record.Entries),
+		BoardSize:   record.Settings.BoardSize,
+		Seeded:      record.Start != nil,
+		Annotations: len(record.Annotations),
+		Seed:        record.Settings.Seed,
+		Accuracy:    record.Accuracy,
 	}
 }
 
-func playerToInt(player PlayerColor) int {
-	if player == PlayerBlack {
-		return 1
+func annotationsFromRecord(record engine.GameRecord) annotationsPayload {
+	annotations := record.Annotations
+	if annotations == nil {
+		annotations = []engine.Annotation{}
 	}
-	return 2
+	return annotationsPayload{GameID: record.ID, Annotations: annotations}
 }
 
-func intToPlayer(value int) PlayerColor {
-	if value == 2 {
-		return PlayerWhite
-	}
-	return PlayerBlack
+type renderOptions struct {
+	format   string
+	cellSize int
+	numbers  bool
 }
 
-func winnerFromStatus(status GameStatus) int {
-	switch status {
-	case StatusBlackWon:
-		return 1
-	case StatusWhiteWon:
-		return 2
-	default:
-		return 0
+func renderOptionsFromQuery(r *http.Request) (renderOptions, error) {
+	query := r.URL.Query()
+	options := renderOptions{format: "svg", cellSize: engine.DefaultRenderCellSize, numbers: true}
+	if raw := query.Get("format"); raw != "" {
+		if raw != "svg" && raw != "png" {
+			return options, fmt.Errorf("format must be svg or png")
+		}
+		options.format = raw
+	}
+	if raw := query.Get("cell"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return options, fmt.Errorf("invalid cell")
+		}
+		options.cellSize = parsed
 	}
+	if raw := query.Get("numbers"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return options, fmt.Errorf("invalid numbers")
+		}
+		options.numbers = parsed
+	}
+	return options, nil
 }
 
-func statusToString(status GameStatus) string {
-	switch status {
-	case StatusNotStarted:
-		return "not_started"
-	case StatusBlackWon:
-		return "black_won"
-	case StatusWhiteWon:
-		return "white_won"
-	case StatusDraw:
-		return "draw"
-	default:
-		return "running"
+func writeBoardImage(w http.ResponseWriter, img engine.BoardImage, options renderOptions) {
+	if options.format == "png" {
+		data, err := img.PNG(options.cellSize, options.numbers)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+		return
 	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(img.SVG(options.cellSize, options.numbers))
 }
 
-func historyToDTO(history MoveHistory) []historyEntryDTO {
+func historyToDTO(history engine.MoveHistory) []historyEntryDTO {
 	entries := history.All()
 	result := make([]historyEntryDTO, 0, len(entries))
 	for _, entry := range entries {
@@ -561,21 +2208,25 @@ func historyToDTO(history MoveHistory) []historyEntryDTO {
 }
 
 func ttCacheStatus() ttCacheStatusResponse {
-	config := GetConfig()
-	cache := SharedSearchCache()
-	tt := ensureTT(cache, config)
+	config := engine.GetConfig()
+	cache := engine.SharedSearchCache()
+	tt := engine.EnsureTT(cache, config)
 	maxMemoryBytes := uint64(0)
 	if config.AiTtMaxMemoryBytes > 0 {
 		maxMemoryBytes = uint64(config.AiTtMaxMemoryBytes)
 	}
+	heuristicHash := engine.HeuristicHash(config)
 	if tt == nil {
 		return ttCacheStatusResponse{
-			MaxMemoryBytes: maxMemoryBytes,
+			MaxMemoryBytes:  maxMemoryBytes,
+			HeuristicHash:   heuristicHash,
+			HeuristicHashes: []ttHeuristicHashCount{},
+			Restored:        engine.CacheLoadReports(),
 		}
 	}
 	count := tt.Count()
 	capacity := tt.Capacity()
-	entryBytes := uint64(unsafe.Sizeof(TTEntry{}))
+	entryBytes := uint64(unsafe.Sizeof(engine.TTEntry{}))
 	usedBytes := uint64(count) * entryBytes
 	capacityBytes := uint64(capacity) * entryBytes
 	usage := 0.0
@@ -591,22 +2242,40 @@ func ttCacheStatus() ttCacheStatusResponse {
 		memoryUsage = float64(usedBytes) / float64(capacityBytes)
 	}
 	return ttCacheStatusResponse{
-		Count:          count,
-		Capacity:       capacity,
-		Usage:          usage,
-		Full:           full,
-		EntryBytes:     entryBytes,
-		UsedBytes:      usedBytes,
-		CapacityBytes:  capacityBytes,
-		MaxMemoryBytes: maxMemoryBytes,
-		MemoryUsage:    memoryUsage,
+		Count:           count,
+		Capacity:        capacity,
+		Usage:           usage,
+		Full:            full,
+		EntryBytes:      entryBytes,
+		UsedBytes:       usedBytes,
+		CapacityBytes:   capacityBytes,
+		MaxMemoryBytes:  maxMemoryBytes,
+		MemoryUsage:     memoryUsage,
+		HeuristicHash:   heuristicHash,
+		HeuristicHashes: ttHeuristicHashCounts(tt, heuristicHash),
+		Restored:        engine.CacheLoadReports(),
 	}
 }
 
+func ttHeuristicHashCounts(tt *engine.TranspositionTable, active string) []ttHeuristicHashCount {
+	counts := []ttHeuristicHashCount{}
+	for hash, entries := range tt.CountByHeuristicHash() {
+		formatted := engine.FormatHeuristicHash(hash)
+		counts = append(counts, ttHeuristicHashCount{Hash: formatted, Entries: entries, Active: formatted == active})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Entries != counts[j].Entries {
+			return counts[i].Entries > counts[j].Entries
+		}
+		return counts[i].Hash < counts[j].Hash
+	})
+	return counts
+}
+
 func ttCacheEntries(offset int, limit int) ttCacheEntriesResponse {
-	config := GetConfig()
-	cache := SharedSearchCache()
-	tt := ensureTT(cache, config)
+	config := engine.GetConfig()
+	cache := engine.SharedSearchCache()
+	tt := engine.EnsureTT(cache, config)
 	if tt == nil {
 		return ttCacheEntriesResponse{
 			Items:  []ttCacheEntryDTO{},
@@ -628,36 +2297,37 @@ func ttCacheEntries(offset int, limit int) ttCacheEntriesResponse {
 	}
 }
 
-func ttEntryToDTO(entry TTEntry) ttCacheEntryDTO {
+func ttEntryToDTO(entry engine.TTEntry) ttCacheEntryDTO {
 	return ttCacheEntryDTO{
-		Hash:        fmt.Sprintf("0x%016x", entry.Key),
-		Hits:        entry.Hits,
-		Depth:       entry.Depth,
-		Score:       entry.Score,
-		Flag:        ttFlagString(entry.Flag),
-		BestMove:    entry.BestMove,
-		GenWritten:  entry.GenWritten,
-		GenLastUsed: entry.GenLastUsed,
-		GrowthLeft:  entry.GrowLeft,
-		GrowthRight: entry.GrowRight,
-		GrowthTop:   entry.GrowTop,
-		GrowthBot:   entry.GrowBottom,
-		HitLeft:     entry.HitLeft,
-		HitRight:    entry.HitRight,
-		HitTop:      entry.HitTop,
-		HitBottom:   entry.HitBottom,
-		FrameW:      entry.FrameW,
-		FrameH:      entry.FrameH,
-	}
-}
-
-func ttFlagString(flag TTFlag) string {
+		Hash:          fmt.Sprintf("0x%016x", entry.Key),
+		HeuristicHash: engine.FormatHeuristicHash(entry.HeuristicHash),
+		Hits:          entry.Hits,
+		Depth:         entry.Depth,
+		Score:         entry.Score,
+		Flag:          ttFlagString(entry.Flag),
+		BestMove:      entry.BestMove,
+		GenWritten:    entry.GenWritten,
+		GenLastUsed:   entry.GenLastUsed,
+		GrowthLeft:    entry.GrowLeft,
+		GrowthRight:   entry.GrowRight,
+		GrowthTop:     entry.GrowTop,
+		GrowthBot:     entry.GrowBottom,
+		HitLeft:       entry.HitLeft,
+		HitRight:      entry.HitRight,
+		HitTop:        entry.HitTop,
+		HitBottom:     entry.HitBottom,
+		FrameW:        entry.FrameW,
+		FrameH:        entry.FrameH,
+	}
+}
+
+func ttFlagString(flag engine.TTFlag) string {
 	switch flag {
-	case TTExact:
+	case engine.TTExact:
 		return "EXACT"
-	case TTLower:
+	case engine.TTLower:
 		return "LOWER"
-	case TTUpper:
+	case engine.TTUpper:
 		return "UPPER"
 	default:
 		return "UNKNOWN"
@@ -671,25 +2341,27 @@ func parseTTKey(raw string) (uint64, error) {
 	return strconv.ParseUint(raw, 0, 64)
 }
 
-func historyEntryToDTO(entry HistoryEntry) historyEntryDTO {
+func historyEntryToDTO(entry engine.HistoryEntry) historyEntryDTO {
 	return historyEntryDTO{
 		X:                 entry.Move.X,
 		Y:                 entry.Move.Y,
-		Player:            playerToInt(entry.Player),
+		Player:            engine.PlayerToInt(entry.Player),
 		ElapsedMs:         entry.ElapsedMs,
+		ComputeMs:         entry.ComputeMs,
 		IsAi:              entry.IsAi,
 		CapturedCount:     entry.CapturedCount,
-		CapturedPositions: append([]Move(nil), entry.CapturedPositions...),
+		CapturedPositions: append([]engine.Move(nil), entry.CapturedPositions...),
 		Changes:           changesFromEntry(entry),
 		Depth:             entry.Depth,
+		AutoPlayed:        entry.AutoPlayed,
 	}
 }
 
-func changesFromEntry(entry HistoryEntry) []cellChange {
+func changesFromEntry(entry engine.HistoryEntry) []cellChange {
 	changes := []cellChange{{
 		X:     entry.Move.X,
 		Y:     entry.Move.Y,
-		Value: playerToInt(entry.Player),
+		Value: engine.PlayerToInt(entry.Player),
 	}}
 	for _, captured := range entry.CapturedPositions {
 		changes = append(changes, cellChange{
@@ -701,20 +2373,21 @@ func changesFromEntry(entry HistoryEntry) []cellChange {
 	return changes
 }
 
-func resetFromController(controller *GameController) resetPayload {
+func resetFromController(controller *engine.GameController) resetPayload {
 	state := controller.State()
 	settings := controller.Settings()
 	return resetPayload{
 		History:            historyToDTO(controller.History()),
-		NextPlayer:         playerToInt(state.ToMove),
-		Winner:             winnerFromStatus(state.Status),
-		Status:             statusToString(state.Status),
+		NextPlayer:         engine.PlayerToInt(state.ToMove),
+		Winner:             engine.WinnerFromStatus(state.Status),
+		Status:             engine.StatusToString(state.Status),
 		BoardSize:          state.Board.Size(),
 		WinReason:          winReasonFromState(state),
-		WinningLine:        append([]Move(nil), state.WinningLine...),
-		WinningCapturePair: append([]Move(nil), state.WinningCapturePair...),
+		WinningLine:        append([]engine.Move(nil), state.WinningLine...),
+		WinningCapturePair: append([]engine.Move(nil), state.WinningCapturePair...),
 		CaptureWinStones:   settings.CaptureWinStones,
 		TurnStartedAtMs:    controller.CurrentTurnStartedAtMs(),
+		StartPosition:      controllerStartPosition(controller),
 	}
 }
 
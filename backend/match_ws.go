@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"gomoku-backend/pkg/engine"
+)
+
+// MatchClient listens for the events of one matchmaking ticket.
+type MatchClient struct {
+	wsClient
+	ticket string
+}
+
+type MatchHub struct {
+	mu          sync.Mutex
+	clients     map[*MatchClient]struct{}
+	broadcast   chan engine.MatchEvent
+	component   *engine.Component
+	compression wsCompression
+}
+
+func NewMatchHub() *MatchHub {
+	h := &MatchHub{
+		clients:   make(map[*MatchClient]struct{}),
+		broadcast: make(chan engine.MatchEvent, 64),
+	}
+	h.component = engine.Components.Register("match_hub", "hub", hubStaleAfter, func() (int, int) { return len(h.broadcast), cap(h.broadcast) })
+	return h
+}
+
+func (h *MatchHub) Run(done <-chan struct{}) {
+	defer engine.Components.Unregister(h.component)
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-h.broadcast:
+			h.component.Beat()
+			frame := newWSFrame(wsMessage{Type: event.Type, Payload: mustMarshal(event)})
+			h.mu.Lock()
+			for client := range h.clients {
+				if client.ticket != event.Ticket {
+					continue
+				}
+				dropped, evicted := client.sendFrame(frame, false)
+				countSend(h.component, "match_hub", dropped, evicted)
+				if evicted {
+					delete(h.clients, client)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+func (h *MatchHub) Publish(event engine.MatchEvent) {
+	select {
+	case h.broadcast <- event:
+	default:
+		h.component.Add("dropped_frames", 1)
+	}
+}
+
+func (h *MatchHub) Register(c *MatchClient) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *MatchHub) Unregister(c *MatchClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	c.queue.close()
+}
+
+// serveMatchWS subscribes to a ticket's events. A ticket that was already
+// matched gets its match straight away, so a client connecting after the
+// pairing does not miss it. The socket also holds the ticket's seat:
+// closing the last one starts its grace period and reconnecting with the
+// same ticket reclaims it.
+func serveMatchWS(hub *MatchHub, matchmaker *engine.Matchmaker, w http.ResponseWriter, r *http.Request) {
+	if _, ok := matchmaker.Ticket(r.URL.Query().Get("ticket")); !ok {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "unknown ticket")
+		return
+	}
+	upgrader := newWSUpgrader(hub.compression)
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	hub.compression.apply(conn)
+	ticket, ok := matchmaker.Attach(r.URL.Query().Get("ticket"), time.Now())
+	if !ok {
+		conn.Close()
+		return
+	}
+	client := &MatchClient{wsClient: newWSClient(wsBinary(conn)), ticket: ticket.ID}
+	hub.Register(client)
+	if ticket.MatchID != "" {
+		if info, ok := matchmaker.Match(ticket.MatchID); ok {
+			event := engine.MatchEvent{Type: "match_found", Ticket: ticket.ID, Color: ticket.Color, Match: info}
+			client.sendFrame(newWSFrame(wsMessage{Type: event.Type, Payload: mustMarshal(event)}), false)
+		}
+	}
+
+	go func() {
+		defer conn.Close()
+		if err := writeWSWithHeartbeat(conn, client.queue, client.binary); err != nil {
+			return
+		}
+	}()
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			hub.Unregister(client)
+			matchmaker.Detach(ticket.ID, time.Now())
+			return
+		}
+	}
+}
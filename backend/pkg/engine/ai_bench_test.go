@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Set GOMOKU_BENCH_JSON to a path to get the results of
+//
+//	go test ./pkg/engine -run '^$' -bench .
+//
+// as JSON, for comparing runs before and after a change to the search.
+const benchJSONEnv = "GOMOKU_BENCH_JSON"
+
+type benchPosition struct {
+	name  string
+	moves []Move
+}
+
+// benchPositions are fixed 19x19 positions: a quiet opening, a crowded
+// middle game and a tactical one with an open three and a hanging pair.
+var benchPositions = []benchPosition{
+	{name: "opening", moves: []Move{{X: 9, Y: 9}, {X: 10, Y: 10}, {X: 10, Y: 8}}},
+	{name: "midgame", moves: []Move{
+		{X: 9, Y: 9}, {X: 10, Y: 10}, {X: 10, Y: 8}, {X: 8, Y: 10}, {X: 11, Y: 9}, {X: 9, Y: 11},
+		{X: 8, Y: 8}, {X: 11, Y: 11}, {X: 12, Y: 10}, {X: 7, Y: 9}, {X: 10, Y: 11}, {X: 9, Y: 8},
+	}},
+	{name: "tactical", moves: []Move{
+		{X: 9, Y: 9}, {X: 10, Y: 9}, {X: 9, Y: 10}, {X: 11, Y: 9}, {X: 9, Y: 11}, {X: 12, Y: 12},
+		{X: 8, Y: 8}, {X: 10, Y: 10},
+	}},
+}
+
+type benchResult struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	NodesPerOp  float64 `json:"nodes_per_op,omitempty"`
+	NodesPerSec float64 `json:"nodes_per_sec,omitempty"`
+}
+
+var benchResults = struct {
+	sync.Mutex
+	byName map[string]benchResult
+}{byName: map[string]benchResult{}}
+
+// recordBench reports nodes per op and per second when nodes is set and, with
+// GOMOKU_BENCH_JSON set, rewrites the results file with this run included.
+// It is called once the timer is stopped; the last round of a benchmark wins.
+func recordBench(b *testing.B, nodes int64) {
+	b.Helper()
+	result := benchResult{Name: b.Name(), Iterations: b.N}
+	if b.N > 0 {
+		result.NsPerOp = float64(b.Elapsed().Nanoseconds()) / float64(b.N)
+	}
+	if nodes > 0 && b.N > 0 {
+		result.NodesPerOp = float64(nodes) / float64(b.N)
+		b.ReportMetric(result.NodesPerOp, "nodes/op")
+		if seconds := b.Elapsed().Seconds(); seconds > 0 {
+			result.NodesPerSec = float64(nodes) / seconds
+			b.ReportMetric(result.NodesPerSec, "nodes/s")
+		}
+	}
+	path := os.Getenv(benchJSONEnv)
+	if path == "" {
+		return
+	}
+	benchResults.Lock()
+	defer benchResults.Unlock()
+	benchResults.byName[result.Name] = result
+	results := make([]benchResult, 0, len(benchResults.byName))
+	for _, entry := range benchResults.byName {
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		b.Fatalf("encode bench results: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatalf("write bench results: %v", err)
+	}
+}
+
+func benchState(b *testing.B, position benchPosition) (GameState, Rules) {
+	b.Helper()
+	state, rules, err := replayMoves(DefaultGameSettings(), position.moves)
+	if err != nil {
+		b.Fatalf("%s: %v", position.name, err)
+	}
+	return state, rules
+}
+
+func BenchmarkCollectCandidateMoves(b *testing.B) {
+	for _, position := range benchPositions {
+		b.Run(position.name, func(b *testing.B) {
+			state, _ := benchState(b, position)
+			size := state.Board.Size()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				collectCandidateMoves(state, state.ToMove, size)
+			}
+			b.StopTimer()
+			recordBench(b, 0)
+		})
+	}
+}
+
+func BenchmarkEvaluateBoard(b *testing.B) {
+	config := DefaultConfig()
+	for _, position := range benchPositions {
+		b.Run(position.name, func(b *testing.B) {
+			state, _ := benchState(b, position)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				EvaluateBoard(state.Board, state.ToMove, config)
+			}
+			b.StopTimer()
+			recordBench(b, 0)
+		})
+	}
+}
+
+func BenchmarkApplyUndo(b *testing.B) {
+	for _, position := range benchPositions {
+		b.Run(position.name, func(b *testing.B) {
+			state, rules := benchState(b, position)
+			candidates := collectCandidateMoves(state, state.ToMove, state.Board.Size())
+			if len(candidates) == 0 {
+				b.Fatalf("no candidate moves")
+			}
+			var undo searchMoveUndo
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				move := candidates[i%len(candidates)].move
+				if applyMoveWithUndo(&state, rules, move, state.ToMove, &undo) {
+					undoMoveWithUndo(&state, undo)
+				}
+			}
+			b.StopTimer()
+			recordBench(b, 0)
+		})
+	}
+}
+
+// BenchmarkSearchDepth6 runs a full depth-6 search with fresh caches on each
+// iteration, so every op does the same work.
+func BenchmarkSearchDepth6(b *testing.B) {
+	config := DefaultConfig()
+	config.AiDepth = 6
+	config.AiMinDepth = 6
+	config.AiMaxDepth = 6
+	config.AiTimeoutMs = 0
+	config.AiTimeBudgetMs = 0
+	config.AiQuickWinExit = false
+	for _, position := range benchPositions {
+		b.Run(position.name, func(b *testing.B) {
+			state, rules := benchState(b, position)
+			var nodes int64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cache := newAISearchCache()
+				stats := &SearchStats{}
+				b.StartTimer()
+				ScoreBoard(state, rules, AIScoreSettings{
+					Depth:           6,
+					BoardSize:       state.Board.Size(),
+					Player:          state.ToMove,
+					Cache:           &cache,
+					Config:          config,
+					Stats:           stats,
+					DirectDepthOnly: true,
+				})
+				nodes += stats.Nodes
+			}
+			b.StopTimer()
+			recordBench(b, nodes)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package engine
 
 import "sync"
 
@@ -153,10 +153,10 @@ func EvaluateBoard(board Board, sideToMove PlayerColor, config Config) float64 {
 		return -evalInf
 	}
 	if totalsOpp.Open4 > 0 {
-		return -900000.0
+		return -evalOpenFour
 	}
 	if totalsMe.Open4 > 0 {
-		return 900000.0
+		return evalOpenFour
 	}
 
 	scoreMe := weightedSum(totalsMe, weights)
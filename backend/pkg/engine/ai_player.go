@@ -1,6 +1,7 @@
-package main
+package engine
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
@@ -18,14 +19,21 @@ type AIPlayer struct {
 	moveMutex     sync.Mutex
 	configMutex   sync.RWMutex
 	workerDone    chan struct{}
+	workerJob     *searchJob
+	priority      searchJobPriority
 	thinking      atomic.Bool
 	moveReady     atomic.Bool
 	ghostActive   atomic.Bool
 	stopSignal    atomic.Bool
+	searchStop    atomic.Pointer[atomic.Bool]
 	readyMove     Move
+	readyScore    float64
+	readyScored   bool
+	readyLine     *ForcedLine
+	readyStats    *MoveSearchStats
 	ghostBoard    Board
 	ponderMu      sync.Mutex
-	ponderCond    *sync.Cond
+	ponderJob     *searchJob
 	ponderState   GameState
 	ponderRules   Rules
 	ponderVersion atomic.Uint64
@@ -34,9 +42,23 @@ type AIPlayer struct {
 	ponderReady   atomic.Bool
 	ponderStop    atomic.Bool
 	heuristics    *HeuristicConfig
+	rngMu         sync.Mutex
+	rng           *rand.Rand
+	opponentMu    sync.Mutex
+	opponent      *OpponentModel
+	selfPlay      atomic.Bool
+	// component is the player's entry in Components, for the players of
+	// the live game.
+	component *Component
 }
 
-var moveRandomizer = rand.New(rand.NewSource(time.Now().UnixNano()))
+// aiStopDeadline is how long StopThinking waits for a search to unwind
+// before it gives up on it; the search then ends on its own, unseen.
+const aiStopDeadline = 250 * time.Millisecond
+
+// aiStaleAfter is how long a search may go without completing a depth
+// before the admin components view reports the player stuck.
+const aiStaleAfter = 2 * time.Minute
 
 func liveAIConfig(config Config) Config {
 	if config.AiUseTtCache {
@@ -51,10 +73,19 @@ func liveAIConfig(config Config) Config {
 }
 
 func NewAIPlayer() *AIPlayer {
-	player := &AIPlayer{}
-	player.ponderCond = sync.NewCond(&player.ponderMu)
-	player.startPonderWorker()
-	return player
+	return &AIPlayer{}
+}
+
+// newSuggestionAIPlayer returns a player whose searches queue behind live
+// moves and pondering in the shared pool.
+func newSuggestionAIPlayer() *AIPlayer {
+	return &AIPlayer{priority: searchJobSuggestion}
+}
+
+// poolOwner names the player in the search pool, which takes turns between
+// owners of the same priority.
+func (a *AIPlayer) poolOwner() string {
+	return fmt.Sprintf("ai:%p", a)
 }
 
 func (a *AIPlayer) IsHuman() bool {
@@ -62,17 +93,25 @@ func (a *AIPlayer) IsHuman() bool {
 }
 
 func (a *AIPlayer) ChooseMove(state GameState, rules Rules) Move {
+	move, _ := a.chooseMoveContext(context.Background(), state, rules)
+	return move
+}
+
+// chooseMoveContext searches state in the calling goroutine, stopping when
+// ctx is cancelled, and reports whether a move was found.
+func (a *AIPlayer) chooseMoveContext(ctx context.Context, state GameState, rules Rules) (Move, bool) {
 	config := a.effectiveConfig()
 	stats := &SearchStats{Start: time.Now()}
 	cache := SharedSearchCache()
 	settings := AIScoreSettings{
-		Depth:     config.AiDepth,
-		TimeoutMs: config.AiTimeoutMs,
-		BoardSize: state.Board.Size(),
-		Player:    state.ToMove,
-		Cache:     cache,
-		Config:    config,
-		Stats:     stats,
+		Depth:      config.AiDepth,
+		TimeoutMs:  config.AiTimeoutMs,
+		BoardSize:  state.Board.Size(),
+		Player:     state.ToMove,
+		Cache:      cache,
+		Config:     config,
+		Stats:      stats,
+		ShouldStop: func() bool { return ctx.Err() != nil },
 	}
 	scores := ScoreBoard(state, rules, settings)
 	bestMove, ok := a.selectBestMove(state, rules, settings, stats, scores)
@@ -82,16 +121,16 @@ func (a *AIPlayer) ChooseMove(state GameState, rules Rules) Move {
 	if ok {
 		logMoveSelection(state.ToMove, bestMove, stats.CompletedDepths, settings.BoardSize)
 		bestMove.Depth = stats.CompletedDepths
-		return bestMove
+		return bestMove, true
 	}
-	return Move{}
+	return Move{}, false
 }
 
-func (a *AIPlayer) StartThinking(state GameState, rules Rules, ghostSink func(GameState), depthSink func(move Move, depth int, score float64)) {
+func (a *AIPlayer) StartThinking(state GameState, rules Rules, ghostSink func(GameState), depthSink func(move Move, depth int, score float64, scores []float64, stability RootStability)) {
 	a.StartThinkingWithConfig(state, rules, ghostSink, depthSink, a.effectiveConfig())
 }
 
-func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSink func(GameState), depthSink func(move Move, depth int, score float64), config Config) {
+func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSink func(GameState), depthSink func(move Move, depth int, score float64, scores []float64, stability RootStability), config Config) {
 	config = liveAIConfig(config)
 	if a.thinking.Load() {
 		return
@@ -103,13 +142,23 @@ func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSi
 	a.moveReady.Store(false)
 	a.ghostActive.Store(false)
 	a.stopSignal.Store(false)
+	// Each search has its own stop flag, so one given up on by StopThinking
+	// stays stopped and never publishes into the next one.
+	stop := &atomic.Bool{}
+	a.searchStop.Store(stop)
 
 	stateCopy := state.Clone()
 	rulesCopy := rules
 	done := make(chan struct{})
 	a.workerDone = done
-	go func() {
+	a.component.SetState("queued", true)
+	a.workerJob = SearchPool.Submit(a.priority, a.poolOwner(), func() {
 		defer close(done)
+		defer a.component.SetState("idle", false)
+		if stop.Load() {
+			return
+		}
+		a.component.SetState("thinking", true)
 		stats := &SearchStats{Start: time.Now()}
 		cache := SharedSearchCache()
 		settings := AIScoreSettings{
@@ -119,13 +168,16 @@ func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSi
 			Player:     stateCopy.ToMove,
 			Cache:      cache,
 			Config:     config,
-			ShouldStop: func() bool { return a.stopSignal.Load() },
+			ShouldStop: func() bool { return stop.Load() || a.stopSignal.Load() },
 			Stats:      stats,
 		}
 		if config.GhostMode && ghostSink != nil {
 			throttleMs := config.AiGhostThrottleMs
 			var lastPublish time.Time
 			settings.OnGhostUpdate = func(gs GameState) {
+				if stop.Load() {
+					return
+				}
 				if throttleMs > 0 {
 					now := time.Now()
 					if !lastPublish.IsZero() && now.Sub(lastPublish) < time.Duration(throttleMs)*time.Millisecond {
@@ -140,15 +192,20 @@ func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSi
 				ghostSink(gs)
 			}
 		}
-		if depthSink != nil {
-			settings.OnDepthComplete = func(depth int, move Move, score float64) {
-				if a.stopSignal.Load() {
+		if depthSink != nil || a.component != nil {
+			settings.OnDepthComplete = func(depth int, move Move, score float64, scores []float64) {
+				a.component.Beat()
+				if depthSink == nil || stop.Load() || a.stopSignal.Load() {
 					return
 				}
-				depthSink(move, depth, score)
+				depthSink(move, depth, score, scores, stats.RootStability())
 			}
 		}
 		scores := ScoreBoard(stateCopy, rulesCopy, settings)
+		if stop.Load() {
+			// StopThinking already reset the player's flags.
+			return
+		}
 		if a.stopSignal.Load() {
 			a.moveReady.Store(false)
 			a.ghostActive.Store(false)
@@ -160,33 +217,82 @@ func (a *AIPlayer) StartThinkingWithConfig(state GameState, rules Rules, ghostSi
 			logSearchStats("think", settings.Stats, settings)
 		}
 		a.moveMutex.Lock()
+		// Checked under moveMutex: StopThinking clears the flags under it too,
+		// so a search given up on cannot publish after that.
+		if stop.Load() {
+			a.moveMutex.Unlock()
+			return
+		}
 		if ok {
 			logMoveSelection(stateCopy.ToMove, bestMove, stats.CompletedDepths, settings.BoardSize)
 			bestMove.Depth = stats.CompletedDepths
+			score := scores[bestMove.Y*settings.BoardSize+bestMove.X]
 			if depthSink != nil {
-				score := scores[bestMove.Y*settings.BoardSize+bestMove.X]
-				depthSink(bestMove, stats.CompletedDepths, score)
+				depthSink(bestMove, stats.CompletedDepths, score, scores, stats.RootStability())
 			}
 			a.readyMove = bestMove
+			a.readyScore, a.readyScored = scoreSign(stateCopy.ToMove)*score, true
+			a.readyLine = forcedLineFor(stateCopy, rulesCopy, config, bestMove, score)
+			a.readyStats = moveSearchStats(stats)
 		} else {
 			a.readyMove = Move{}
+			a.readyScored = false
+			a.readyLine = nil
+			a.readyStats = nil
 		}
-		a.moveMutex.Unlock()
 		a.moveReady.Store(true)
 		a.ghostActive.Store(false)
 		a.thinking.Store(false)
-	}()
+		a.moveMutex.Unlock()
+	})
 }
 
+// StopThinking stops the current search and waits up to aiStopDeadline for
+// it to unwind. A search still running by then is left to end on its own:
+// its results are dropped and the player is free for the next search.
 func (a *AIPlayer) StopThinking() {
-	a.stopSignal.Store(true)
-	if a.workerDone != nil {
-		<-a.workerDone
+	a.stopThinking(aiStopDeadline)
+}
+
+func (a *AIPlayer) stopThinking(wait time.Duration) {
+	if stop := a.searchStop.Load(); stop != nil {
+		stop.Store(true)
+	}
+	if SearchPool.Cancel(a.workerJob) {
+		// Still queued: it will never run, so there is nothing to wait for.
+		a.workerDone = nil
+		a.component.SetState("idle", false)
+	}
+	a.workerJob = nil
+	if a.workerDone != nil && wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-a.workerDone:
+		case <-timer.C:
+			log.Printf("[ai] search did not stop within %v, leaving it to unwind", wait)
+		}
+		timer.Stop()
 	}
+	a.workerDone = nil
+	a.moveMutex.Lock()
 	a.moveReady.Store(false)
 	a.ghostActive.Store(false)
 	a.thinking.Store(false)
-	a.stopSignal.Store(false)
+	a.moveMutex.Unlock()
+}
+
+// Close stops the player's search and its pondering without waiting, for a
+// player being replaced by a new game.
+func (a *AIPlayer) Close() {
+	a.stopThinking(0)
+	a.ponderMu.Lock()
+	a.ponderStop.Store(true)
+	a.ponderReady.Store(false)
+	a.ponderVersion.Add(1)
+	SearchPool.Cancel(a.ponderJob)
+	a.ponderJob = nil
+	a.ponderMu.Unlock()
+	Components.Unregister(a.component)
 }
 
 func (a *AIPlayer) IsThinking() bool {
@@ -198,10 +304,36 @@ func (a *AIPlayer) HasMoveReady() bool {
 }
 
 func (a *AIPlayer) TakeMove() Move {
+	move, _, _ := a.TakeScoredMove()
+	return move
+}
+
+// TakeScoredMove is TakeMove with the search score of the move, from the
+// mover's side, when the search found one.
+func (a *AIPlayer) TakeScoredMove() (Move, float64, bool) {
 	a.moveMutex.Lock()
 	defer a.moveMutex.Unlock()
 	a.moveReady.Store(false)
-	return a.readyMove
+	return a.readyMove, a.readyScore, a.readyScored
+}
+
+// TakeForcedLine returns the forcing sequence behind the ready move when
+// its search proved a win or a loss.
+func (a *AIPlayer) TakeForcedLine() *ForcedLine {
+	a.moveMutex.Lock()
+	defer a.moveMutex.Unlock()
+	line := a.readyLine
+	a.readyLine = nil
+	return line
+}
+
+// TakeSearchStats returns what the search of the ready move cost.
+func (a *AIPlayer) TakeSearchStats() *MoveSearchStats {
+	a.moveMutex.Lock()
+	defer a.moveMutex.Unlock()
+	stats := a.readyStats
+	a.readyStats = nil
+	return stats
 }
 
 func (a *AIPlayer) HasGhostBoard() bool {
@@ -215,7 +347,7 @@ func (a *AIPlayer) GhostBoardCopy() Board {
 }
 
 func (a *AIPlayer) OnMoveApplied(state GameState, rules Rules) {
-	ensureTT(SharedSearchCache(), GetConfig())
+	EnsureTT(SharedSearchCache(), GetConfig())
 	a.updatePonderState(state, rules)
 }
 
@@ -229,62 +361,61 @@ func (a *AIPlayer) ResetForConfigChange() {
 	a.stopSignal.Store(false)
 }
 
-func (a *AIPlayer) startPonderWorker() {
-	go func() {
-		var lastVersion uint64
-		for {
-			a.ponderMu.Lock()
-			for a.ponderVersion.Load() == lastVersion {
-				a.ponderCond.Wait()
-			}
-			state := a.ponderState.Clone()
-			rules := a.ponderRules
-			version := a.ponderVersion.Load()
-			lastVersion = version
-			a.ponderMu.Unlock()
-
-			config := a.effectiveConfig()
-			if !config.AiPonderingEnabled {
-				continue
-			}
-			if state.Hash == 0 {
-				state.recomputeHashes()
-			}
-			stats := &SearchStats{Start: time.Now()}
-			cache := SharedSearchCache()
-			settings := AIScoreSettings{
-				Depth:      config.AiDepth,
-				TimeoutMs:  config.AiTimeoutMs,
-				BoardSize:  state.Board.Size(),
-				Player:     state.ToMove,
-				Cache:      cache,
-				Config:     config,
-				ShouldStop: func() bool { return a.stopSignal.Load() || a.ponderVersion.Load() != version },
-				Stats:      stats,
-			}
-			scores := ScoreBoard(state, rules, settings)
-			if a.stopSignal.Load() || a.ponderVersion.Load() != version {
-				continue
-			}
-			bestMove, ok := a.selectBestMove(state, rules, settings, stats, scores)
-			if settings.Config.AiLogSearchStats {
-				logSearchStats("ponder", stats, settings)
-			}
-			if ok {
-				bestMove.Depth = stats.CompletedDepths
-				key := ttKeyFor(state, settings.BoardSize)
-				a.ponderMu.Lock()
-				if a.ponderVersion.Load() == version {
-					a.ponderKey = key
-					a.ponderMove = bestMove
-					a.ponderReady.Store(true)
-				}
-				a.ponderMu.Unlock()
-			}
+// ponder searches the latest position handed to updatePonderState, until
+// it completes or a newer one arrives.
+func (a *AIPlayer) ponder() {
+	a.ponderMu.Lock()
+	if a.ponderStop.Load() {
+		a.ponderMu.Unlock()
+		return
+	}
+	state := a.ponderState.Clone()
+	rules := a.ponderRules
+	version := a.ponderVersion.Load()
+	a.ponderMu.Unlock()
+
+	config := a.effectiveConfig()
+	if !config.AiPonderingEnabled {
+		return
+	}
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	stats := &SearchStats{Start: time.Now()}
+	cache := SharedSearchCache()
+	settings := AIScoreSettings{
+		Depth:      config.AiDepth,
+		TimeoutMs:  config.AiTimeoutMs,
+		BoardSize:  state.Board.Size(),
+		Player:     state.ToMove,
+		Cache:      cache,
+		Config:     config,
+		ShouldStop: func() bool { return a.stopSignal.Load() || a.ponderVersion.Load() != version },
+		Stats:      stats,
+	}
+	scores := ScoreBoard(state, rules, settings)
+	if a.stopSignal.Load() || a.ponderVersion.Load() != version {
+		return
+	}
+	bestMove, ok := a.selectBestMove(state, rules, settings, stats, scores)
+	if settings.Config.AiLogSearchStats {
+		logSearchStats("ponder", stats, settings)
+	}
+	if ok {
+		bestMove.Depth = stats.CompletedDepths
+		key := ttKeyFor(state, settings.BoardSize)
+		a.ponderMu.Lock()
+		if a.ponderVersion.Load() == version {
+			a.ponderKey = key
+			a.ponderMove = bestMove
+			a.ponderReady.Store(true)
 		}
-	}()
+		a.ponderMu.Unlock()
+	}
 }
 
+// updatePonderState hands the pondering a new root. A ponder job still
+// queued is replaced; one running sees the new version and stops.
 func (a *AIPlayer) updatePonderState(state GameState, rules Rules) {
 	config := a.effectiveConfig()
 	if !config.AiPonderingEnabled {
@@ -294,12 +425,16 @@ func (a *AIPlayer) updatePonderState(state GameState, rules Rules) {
 		state.recomputeHashes()
 	}
 	a.ponderMu.Lock()
+	defer a.ponderMu.Unlock()
+	if a.ponderStop.Load() {
+		return
+	}
 	a.ponderState = state.Clone()
 	a.ponderRules = rules
 	a.ponderVersion.Add(1)
 	a.ponderReady.Store(false)
-	a.ponderCond.Signal()
-	a.ponderMu.Unlock()
+	SearchPool.Cancel(a.ponderJob)
+	a.ponderJob = SearchPool.Submit(searchJobPonder, a.poolOwner(), a.ponder)
 }
 
 func (a *AIPlayer) SetHeuristicsOverride(heuristics *HeuristicConfig) {
@@ -308,6 +443,45 @@ func (a *AIPlayer) SetHeuristicsOverride(heuristics *HeuristicConfig) {
 	a.configMutex.Unlock()
 }
 
+// SetSeed makes the player's random choices (weakened moves, fallback
+// moves) repeat for the same seed.
+func (a *AIPlayer) SetSeed(seed int64) {
+	a.rngMu.Lock()
+	a.rng = rand.New(rand.NewSource(seed))
+	a.rngMu.Unlock()
+}
+
+// SetOpponentModel gives the player what is known about its human
+// opponent, used when ai_opponent_model is on; nil forgets it.
+func (a *AIPlayer) SetOpponentModel(model *OpponentModel) {
+	a.opponentMu.Lock()
+	a.opponent = model
+	a.opponentMu.Unlock()
+}
+
+// SetSelfPlay tells the player its opponent is an engine too, which lets
+// ai_self_play_temperature vary its moves.
+func (a *AIPlayer) SetSelfPlay(selfPlay bool) {
+	a.selfPlay.Store(selfPlay)
+}
+
+func (a *AIPlayer) opponentModel() *OpponentModel {
+	a.opponentMu.Lock()
+	defer a.opponentMu.Unlock()
+	return a.opponent
+}
+
+// randomSource returns a source for one random choice: a copy drawn from
+// the player's seeded source, or a fresh one for players without a seed.
+func (a *AIPlayer) randomSource() *rand.Rand {
+	a.rngMu.Lock()
+	defer a.rngMu.Unlock()
+	if a.rng == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(a.rng.Int63()))
+}
+
 func (a *AIPlayer) effectiveConfig() Config {
 	config := GetConfig()
 	a.configMutex.RLock()
@@ -426,6 +600,20 @@ func (a *AIPlayer) selectBestMove(state GameState, rules Rules, settings AIScore
 			}
 		}
 	}
+	if modeledMove, changed := maybeSelectModeledMove(scores, state, rules, settings, bestMove, a.opponentModel()); changed {
+		bestMove = modeledMove
+	}
+	if humanMove, changed := maybeSelectHumanMove(scores, state, rules, settings, bestMove, a.randomSource()); changed {
+		bestMove = humanMove
+	}
+	if limitedMove, changed := maybeSelectLimitedMove(scores, state, rules, settings, bestMove, a.randomSource()); changed {
+		bestMove = limitedMove
+	}
+	if a.selfPlay.Load() {
+		if explored, changed := maybeSelectTemperatureMove(scores, state, rules, settings, bestMove, a.randomSource()); changed {
+			bestMove = explored
+		}
+	}
 	return a.ensureLegalOrFallback(state, rules, settings, fallbackUsed, bestMove)
 }
 
@@ -439,7 +627,7 @@ func (a *AIPlayer) ensureLegalOrFallback(state GameState, rules Rules, settings
 			return fallback, true
 		}
 	}
-	if fallback, ok := randomAdjacentMove(state, rules); ok {
+	if fallback, ok := randomAdjacentMove(state, rules, a.randomSource()); ok {
 		log.Printf("[ai-player] using random adjacent fallback move %v", fallback)
 		return fallback, true
 	}
@@ -572,7 +760,7 @@ func scoreForMove(scores []float64, move Move, boardSize int) float64 {
 	return scores[idx]
 }
 
-func randomAdjacentMove(state GameState, rules Rules) (Move, bool) {
+func randomAdjacentMove(state GameState, rules Rules, rng *rand.Rand) (Move, bool) {
 	size := state.Board.Size()
 	if size <= 0 {
 		return Move{}, false
@@ -610,7 +798,7 @@ func randomAdjacentMove(state GameState, rules Rules) (Move, bool) {
 	if len(moves) == 0 {
 		return Move{}, false
 	}
-	moveRandomizer.Shuffle(len(moves), func(i, j int) {
+	rng.Shuffle(len(moves), func(i, j int) {
 		moves[i], moves[j] = moves[j], moves[i]
 	})
 	for _, move := range moves {
@@ -817,7 +1005,7 @@ func logSearchStats(tag string, stats *SearchStats, settings AIScoreSettings) {
 	ttSize = TranspositionSize(settings.Cache)
 	var mem runtime.MemStats
 	runtime.ReadMemStats(&mem)
-	fmt.Printf("[ai:%s] t=%dms depth=%d completed=%d nodes=%d nps=%.0f tt_size=%d tt_probe=%d tt_hit=%d tt_hit_rate=%.1f%% tt_hit_flag=(e:%d l:%d u:%d) tt_store=%d tt_replace=%d tt_replace_rate=%.1f%% cutoffs=%d tt_cutoff=%d ab_cutoff=%d tt_cutoff_rate=%.1f%% avg_branch=%.2f avg_root=%.2f avg_deep=%.2f eval_probe=%d eval_hit=%d eval_hit_rate=%.1f%% mem_alloc=%s mem_heap=%s mem_total=%s mem_sys=%s depth_times=[%s]\\n",
+	fmt.Printf("[ai:%s] t=%dms depth=%d completed=%d nodes=%d nps=%.0f tt_size=%d tt_probe=%d tt_hit=%d tt_hit_rate=%.1f%% tt_hit_flag=(e:%d l:%d u:%d) tt_store=%d tt_replace=%d tt_replace_rate=%.1f%% cutoffs=%d tt_cutoff=%d ab_cutoff=%d tt_cutoff_rate=%.1f%% asp_fail=(h:%d l:%d) asp_window=%.0f iid=%d iid_move=%d first_cutoff=%d fork=%d avg_branch=%.2f avg_root=%.2f avg_deep=%.2f eval_probe=%d eval_hit=%d eval_hit_rate=%.1f%% mem_alloc=%s mem_heap=%s mem_total=%s mem_sys=%s depth_times=[%s]\\n",
 		tag,
 		elapsed.Milliseconds(),
 		settings.Depth,
@@ -838,6 +1026,13 @@ func logSearchStats(tag string, stats *SearchStats, settings AIScoreSettings) {
 		stats.TTCutoffs,
 		stats.ABCutoffs,
 		ttCutoffRate,
+		stats.AspFailHigh,
+		stats.AspFailLow,
+		stats.AspWindow,
+		stats.IIDSearches,
+		stats.IIDMoves,
+		stats.FirstMoveCutoffs,
+		stats.ForkProofs,
 		avgBranch,
 		avgRoot,
 		avgDeep,
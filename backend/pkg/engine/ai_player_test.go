@@ -1,6 +1,10 @@
-package main
+package engine
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+	"time"
+)
 
 func TestBestMoveFromScoresWhiteIgnoresUnscoredCells(t *testing.T) {
 	settings := DefaultGameSettings()
@@ -224,3 +228,83 @@ func TestMaybeSelectLostModeMoveHandlesShortScoreSlice(t *testing.T) {
 		t.Fatalf("expected lost mode to skip short score slice")
 	}
 }
+
+func TestSeededPlayersRepeatRandomChoices(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.Board.Set(4, 4, CellBlack)
+	state.ToMove = PlayerWhite
+
+	pick := func(seed int64) []Move {
+		player := &AIPlayer{}
+		player.SetSeed(seed)
+		moves := []Move{}
+		for i := 0; i < 5; i++ {
+			move, ok := randomAdjacentMove(state, rules, player.randomSource())
+			if !ok {
+				t.Fatalf("expected an adjacent move")
+			}
+			moves = append(moves, move)
+		}
+		return moves
+	}
+	if !reflect.DeepEqual(pick(42), pick(42)) {
+		t.Fatalf("expected the same seed to repeat the same choices")
+	}
+}
+
+func TestGameResetDrawsSeedUnlessGiven(t *testing.T) {
+	settings := DefaultGameSettings()
+	game := NewGame(settings)
+	if game.settings.Seed == 0 {
+		t.Fatalf("expected a fresh seed")
+	}
+	settings.Seed = 1234
+	game.Reset(settings)
+	if game.settings.Seed != 1234 {
+		t.Fatalf("expected the given seed to be kept, got %d", game.settings.Seed)
+	}
+}
+
+func TestStopThinkingDoesNotWaitForADeepSearch(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.AiDepth = 12
+	cfg.AiMaxDepth = 12
+	cfg.AiTimeoutMs = 0
+	cfg.AiTimeBudgetMs = 0
+	cfg.AiQueueEnabled = false
+	cfg.AiPonderingEnabled = false
+	configStore.Update(cfg)
+	defer func() {
+		configStore.Update(prev)
+		FlushGlobalCaches()
+	}()
+	state, rules, err := replayMoves(DefaultGameSettings(), benchPositions[1].moves)
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	ai := NewAIPlayer()
+	defer ai.Close()
+	ai.StartThinking(state, rules, nil, nil)
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	ai.StopThinking()
+	if elapsed := time.Since(start); elapsed > aiStopDeadline+100*time.Millisecond {
+		t.Fatalf("expected StopThinking to return within the deadline, took %v", elapsed)
+	}
+	if ai.IsThinking() || ai.HasMoveReady() {
+		t.Fatalf("expected a stopped player to be idle")
+	}
+	ai.StartThinking(state, rules, nil, nil)
+	if !ai.IsThinking() {
+		t.Fatalf("expected a new search to start right away")
+	}
+	ai.Close()
+	if ai.IsThinking() {
+		t.Fatalf("expected Close to stop the search")
+	}
+}
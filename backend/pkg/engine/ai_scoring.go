@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"fmt"
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -19,6 +20,8 @@ const (
 	lmrLateMoveStart              = 4
 	lmrMinDepth                   = 4
 	lmrReduction                  = 1
+	iidMinDepth                   = 4
+	iidReduction                  = 2
 	maxSearchBoardCells           = 19 * 19
 )
 
@@ -40,7 +43,7 @@ type AIScoreSettings struct {
 	BoardSize        int
 	Player           PlayerColor
 	OnGhostUpdate    func(GameState)
-	OnDepthComplete  func(depth int, move Move, score float64)
+	OnDepthComplete  func(depth int, move Move, score float64, scores []float64)
 	OnNodeProgress   func(delta int64)
 	OnSearchProgress func(delta SearchProgressDelta)
 	Cache            *AISearchCache
@@ -49,6 +52,17 @@ type AIScoreSettings struct {
 	Stats            *SearchStats
 	DirectDepthOnly  bool
 	SkipQueueBacklog bool
+	// MaxNodes stops the search after that many nodes, across all depths
+	// and workers, keeping the deepest completed depth. Zero means no limit.
+	MaxNodes int64
+
+	nodeCount *atomic.Int64
+	// aborted is set by the first check that finds the search must stop,
+	// so the other workers of the search see it with one atomic load.
+	aborted *atomic.Bool
+	// evalSalt keeps the eval cache entries of phase-adjusted weights
+	// apart from those of the configured ones (see applyGamePhase).
+	evalSalt uint64
 }
 
 type minimaxContext struct {
@@ -61,6 +75,9 @@ type minimaxContext struct {
 	deadline    time.Time
 	hasDeadline bool
 	logIndent   int
+	// rootPly is the depthFromRoot the root's moves are searched with;
+	// a node is depthFromRoot-rootPly moves from the root.
+	rootPly int
 }
 
 func maxScore(scores []float64) float64 {
@@ -103,6 +120,24 @@ type SearchStats struct {
 	HeuristicTime   time.Duration
 	BoardGenOps     int64
 	BoardGenTime    time.Duration
+	// AspFailHigh and AspFailLow count root moves scored at or past the
+	// upper or lower edge of the aspiration window and searched again with
+	// a full window. AspWindow is the last window used, 0 for none.
+	AspFailHigh int64
+	AspFailLow  int64
+	AspWindow   float64
+	// RootBestMoves is the best root move of each completed depth, and
+	// TimeExtended how much thinking time was added because it changed.
+	RootBestMoves []Move
+	TimeExtended  time.Duration
+	// IIDSearches counts internal iterative deepening searches and IIDMoves
+	// those that found a move to try first; FirstMoveCutoffs counts cutoffs
+	// made by a node's first move.
+	IIDSearches      int64
+	IIDMoves         int64
+	FirstMoveCutoffs int64
+	// ForkProofs counts moves proven to be forks.
+	ForkProofs int64
 
 	progressReportedNodes    int64
 	progressReportedBoardGen int64
@@ -437,16 +472,35 @@ func (rtc *RootTransposeCache) snapshotEntries() []RootTransposeEntry {
 	return entries
 }
 
-func (rtc *RootTransposeCache) loadEntries(entries []RootTransposeEntry) {
+// restoreEntry puts a persisted entry in its bucket like
+// TranspositionTable.restoreEntry.
+func (rtc *RootTransposeCache) restoreEntry(entry RootTransposeEntry) bool {
 	if rtc == nil {
-		return
+		return false
 	}
 	rtc.mu.Lock()
 	defer rtc.mu.Unlock()
-	if len(entries) > len(rtc.entries) {
-		entries = entries[:len(rtc.entries)]
+	start := rtc.bucketIndex(entry.Key)
+	slot, same := -1, false
+	for i := 0; i < rtc.buckets; i++ {
+		idx := start + i
+		current := rtc.entries[idx]
+		if current.Valid && current.Key == entry.Key {
+			if current.Depth > entry.Depth {
+				return false
+			}
+			slot, same = idx, true
+			break
+		}
+		if slot == -1 || (rtc.entries[slot].Valid && (!current.Valid || current.Depth < rtc.entries[slot].Depth)) {
+			slot = idx
+		}
+	}
+	if !same && rtc.entries[slot].Valid && rtc.entries[slot].Depth >= entry.Depth {
+		return false
 	}
-	copy(rtc.entries[:len(entries)], entries)
+	rtc.entries[slot] = entry
+	return true
 }
 
 type searchFootprint struct {
@@ -601,7 +655,7 @@ func FlushGlobalCaches() {
 	}
 }
 
-func ensureTT(cache *AISearchCache, config Config) *TranspositionTable {
+func EnsureTT(cache *AISearchCache, config Config) *TranspositionTable {
 	if cache == nil {
 		return nil
 	}
@@ -1317,6 +1371,12 @@ func orderCandidateMoves(state GameState, ctx minimaxContext, currentPlayer Play
 			}
 			score = heuristicForMove(state, ctx.rules, evalSettings, move)
 		}
+		if priority > prioWin && forkPlies(ctx.settings.Config, depthFromRoot) {
+			forkState := state
+			if _, ok := proveFork(&forkState, ctx.rules, move, currentPlayer, ctx.settings.BoardSize, ctx.settings.Config); ok {
+				priority = prioWin
+			}
+		}
 		if ctx.settings.Config.AiEnableKillerMoves && isKillerMove(ctx, depthFromRoot, move) {
 			boost := float64(ctx.settings.Config.AiKillerBoost)
 			if maximizing {
@@ -1501,7 +1561,7 @@ func evalBoardCached(state GameState, rules Rules, settings AIScoreSettings, cac
 			settings.Stats.EvalCacheProbes++
 		}
 		if stateHash != 0 {
-			if value, ok := evalCache.Get(evalKey(stateHash, settings.BoardSize, state.ToMove)); ok {
+			if value, ok := evalCache.Get(evalKey(stateHash^settings.evalSalt, settings.BoardSize, state.ToMove)); ok {
 				if settings.Stats != nil {
 					settings.Stats.EvalCacheHits++
 				}
@@ -1528,7 +1588,7 @@ func evalBoardCached(state GameState, rules Rules, settings AIScoreSettings, cac
 	}
 	if evalCache != nil && stateHash != 0 {
 		if math.Abs(value) >= settings.Config.AiEvalCacheMinAbs {
-			evalCache.Put(evalKey(stateHash, settings.BoardSize, state.ToMove), value)
+			evalCache.Put(evalKey(stateHash^settings.evalSalt, settings.BoardSize, state.ToMove), value)
 		}
 	}
 	return value
@@ -1538,43 +1598,72 @@ func captureUrgencyHeuristic(state GameState, rules Rules, config Config) float6
 	heuristics := resolvedHeuristicConfig(config)
 	blackCaptureMoves := findCaptureMoves(state, rules, PlayerBlack)
 	whiteCaptureMoves := findCaptureMoves(state, rules, PlayerWhite)
+	blackScale := captureScale(heuristics, state.CapturedBlack, rules.CaptureWinStones())
+	whiteScale := captureScale(heuristics, state.CapturedWhite, rules.CaptureWinStones())
 
 	score := 0.0
-	score += float64(len(blackCaptureMoves)-len(whiteCaptureMoves)) * heuristics.CaptureNow
+	score += (float64(len(blackCaptureMoves))*blackScale - float64(len(whiteCaptureMoves))*whiteScale) * heuristics.CaptureNow
 	if len(blackCaptureMoves) >= 2 {
-		score += heuristics.CaptureDoubleThreat
+		score += heuristics.CaptureDoubleThreat * blackScale
 	}
 	if len(whiteCaptureMoves) >= 2 {
-		score -= heuristics.CaptureDoubleThreat
+		score -= heuristics.CaptureDoubleThreat * whiteScale
 	}
 
 	blackRemaining := rules.CaptureWinStones() - state.CapturedBlack
 	whiteRemaining := rules.CaptureWinStones() - state.CapturedWhite
 	if blackRemaining <= 2 && len(blackCaptureMoves) > 0 {
-		score += winScore * heuristics.CaptureWinSoonScale
+		score += winScore * heuristics.CaptureScaleFinal
 	} else if blackRemaining <= 4 && len(blackCaptureMoves) > 0 {
 		score += heuristics.CaptureNearWin
 	}
 	if whiteRemaining <= 2 && len(whiteCaptureMoves) > 0 {
-		score -= winScore * heuristics.CaptureWinSoonScale
+		score -= winScore * heuristics.CaptureScaleFinal
 	} else if whiteRemaining <= 4 && len(whiteCaptureMoves) > 0 {
 		score -= heuristics.CaptureNearWin
 	}
 
 	if len(blackCaptureMoves) == 0 && hasCaptureInTwoPlies(state, rules, PlayerBlack, heuristics.CaptureInTwoLimit) {
-		score += heuristics.CaptureInTwo
+		score += heuristics.CaptureInTwo * blackScale
 	}
 	if len(whiteCaptureMoves) == 0 && hasCaptureInTwoPlies(state, rules, PlayerWhite, heuristics.CaptureInTwoLimit) {
-		score -= heuristics.CaptureInTwo
+		score -= heuristics.CaptureInTwo * whiteScale
 	}
 
+	// A hanging pair weighs with the progress of the side that can take it.
 	blackHangingPairs := countCapturablePairs(state.Board, PlayerBlack)
 	whiteHangingPairs := countCapturablePairs(state.Board, PlayerWhite)
-	score += float64(whiteHangingPairs-blackHangingPairs) * heuristics.HangingPair
+	score += (float64(whiteHangingPairs)*blackScale - float64(blackHangingPairs)*whiteScale) * heuristics.HangingPair
+
+	// Threats the other side can break by capturing one of their stones
+	// are not as strong as their shape says.
+	score -= defensiveCaptureCredit(state, rules, PlayerBlack, whiteCaptureMoves, heuristics)
+	score += defensiveCaptureCredit(state, rules, PlayerWhite, blackCaptureMoves, heuristics)
 
 	return score
 }
 
+// captureScale is the factor of a side's capture weights once it has
+// captured stones of the winStones it needs: CaptureScaleEarly with none,
+// CaptureScaleMid at half, CaptureScaleLate from two pairs short of the win,
+// and linear in between. A capture that wins outright is worth
+// CaptureScaleFinal of a win instead.
+func captureScale(heuristics HeuristicConfig, captured, winStones int) float64 {
+	late := float64(winStones - 4)
+	mid := float64(winStones) / 2
+	progress := float64(captured)
+	switch {
+	case progress >= late:
+		return heuristics.CaptureScaleLate
+	case progress <= 0:
+		return heuristics.CaptureScaleEarly
+	case progress <= mid:
+		return heuristics.CaptureScaleEarly + (heuristics.CaptureScaleMid-heuristics.CaptureScaleEarly)*progress/mid
+	default:
+		return heuristics.CaptureScaleMid + (heuristics.CaptureScaleLate-heuristics.CaptureScaleMid)*(progress-mid)/(late-mid)
+	}
+}
+
 func hasCaptureInTwoPlies(state GameState, rules Rules, player PlayerColor, prepLimit int) bool {
 	if prepLimit <= 0 {
 		return false
@@ -1633,9 +1722,10 @@ func evaluateStateHeuristic(state GameState, rules Rules, settings AIScoreSettin
 }
 
 func tacticalExtensionScore(state GameState, ctx minimaxContext, currentPlayer PlayerColor, depthFromRoot int) float64 {
+	ply := depthFromRoot - ctx.rootPly
 	candidates := tacticalCandidates(state, ctx, currentPlayer)
 	if len(candidates) == 0 {
-		return evaluateStateHeuristic(state, ctx.rules, ctx.settings)
+		return scoreAtPly(evaluateStateHeuristic(state, ctx.rules, ctx.settings), ply)
 	}
 	maximizing := currentPlayer == PlayerBlack
 	best := math.Inf(-1)
@@ -1652,7 +1742,7 @@ func tacticalExtensionScore(state GameState, ctx minimaxContext, currentPlayer P
 		if !applyMoveWithUndo(&next, ctx.rules, move, currentPlayer, &undo) {
 			continue
 		}
-		score := evaluateStateHeuristic(next, ctx.rules, ctx.settings)
+		score := scoreAtPly(evaluateStateHeuristic(next, ctx.rules, ctx.settings), ply+1)
 		undoMoveWithUndo(&next, undo)
 		if maximizing {
 			if score > best {
@@ -1665,18 +1755,34 @@ func tacticalExtensionScore(state GameState, ctx minimaxContext, currentPlayer P
 		}
 	}
 	if math.IsInf(best, 1) || math.IsInf(best, -1) {
-		return evaluateStateHeuristic(state, ctx.rules, ctx.settings)
+		return scoreAtPly(evaluateStateHeuristic(state, ctx.rules, ctx.settings), ply)
 	}
 	return best
 }
 
 func timedOut(ctx minimaxContext) bool {
+	if ctx.settings.aborted != nil && ctx.settings.aborted.Load() {
+		return true
+	}
+	if !searchMustStop(ctx) {
+		return false
+	}
+	if ctx.settings.aborted != nil {
+		ctx.settings.aborted.Store(true)
+	}
+	return true
+}
+
+func searchMustStop(ctx minimaxContext) bool {
 	if ctx.settings.ShouldStop != nil && ctx.settings.ShouldStop() {
 		return true
 	}
 	if ctx.hasDeadline && !ctx.deadline.IsZero() && time.Now().After(ctx.deadline) {
 		return true
 	}
+	if ctx.settings.nodeCount != nil && ctx.settings.nodeCount.Load() >= ctx.settings.MaxNodes {
+		return true
+	}
 	if ctx.settings.TimeoutMs <= 0 {
 		return false
 	}
@@ -1881,14 +1987,67 @@ func undoMoveWithUndo(state *GameState, undo searchMoveUndo) {
 	state.CanonHash = undo.prevCanonHash
 }
 
-func shouldApplyLMR(depth int, moveIndex int, quietNode bool) bool {
+func shouldApplyLMR(config Config, depth int, moveIndex int, quietNode bool) bool {
 	if !quietNode {
 		return false
 	}
-	if depth < lmrMinDepth {
+	minDepth := config.AiLmrMinDepth
+	if minDepth <= 0 {
+		minDepth = lmrMinDepth
+	}
+	if depth < minDepth {
 		return false
 	}
-	return moveIndex >= lmrLateMoveStart
+	lateMoveStart := config.AiLmrLateMoveStart
+	if lateMoveStart <= 0 {
+		lateMoveStart = lmrLateMoveStart
+	}
+	return moveIndex >= lateMoveStart
+}
+
+func lmrReductionFor(config Config) int {
+	if config.AiLmrReduction > 0 {
+		return config.AiLmrReduction
+	}
+	return lmrReduction
+}
+
+// shouldApplyIID reports whether a node without a TT move gets an internal
+// iterative deepening search for one. The search has no null-window probes,
+// so every node with an open window is on a principal variation.
+func shouldApplyIID(config Config, depth int, alpha, beta float64) bool {
+	if !config.AiEnableIID || beta-alpha <= 1 {
+		return false
+	}
+	minDepth := config.AiIIDMinDepth
+	if minDepth <= 0 {
+		minDepth = iidMinDepth
+	}
+	return depth >= minDepth && depth-iidReductionFor(config) >= 1
+}
+
+func iidReductionFor(config Config) int {
+	if config.AiIIDReduction > 0 {
+		return config.AiIIDReduction
+	}
+	return iidReduction
+}
+
+// iidMove searches the node a few plies shallower and returns the best move
+// that search stored in the TT, to be tried first at full depth.
+func iidMove(state *GameState, ctx minimaxContext, depth int, currentPlayer PlayerColor, depthFromRoot int, alpha, beta float64, tt *TranspositionTable, boardHash, heuristicHash uint64) (Move, bool) {
+	if ctx.settings.Stats != nil {
+		ctx.settings.Stats.IIDSearches++
+	}
+	minimax(state, ctx, depth-iidReductionFor(ctx.settings.Config), currentPlayer, depthFromRoot, alpha, beta)
+	entry, ok := tt.Probe(boardHash, heuristicHash)
+	if !ok || !entry.BestMove.IsValid(ctx.settings.BoardSize) {
+		return Move{}, false
+	}
+	if ctx.settings.Stats != nil {
+		ctx.settings.Stats.IIDMoves++
+	}
+	return entry.BestMove, true
 }
 
 func isImmediateWin(state GameState, rules Rules, move Move, player PlayerColor) bool {
@@ -2268,8 +2427,9 @@ func formatMoves(moves []Move) string {
 
 func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer PlayerColor, depthFromRoot int, alpha, beta float64) float64 {
 	logAITask(ctx, ctx.logIndent, "minimax enter depth=%d depthFromRoot=%d", depth, depthFromRoot)
+	ply := depthFromRoot - ctx.rootPly
 	if timedOut(ctx) || state.Status != StatusRunning {
-		return evaluateStateHeuristic(*state, ctx.rules, ctx.settings)
+		return scoreAtPly(evaluateStateHeuristic(*state, ctx.rules, ctx.settings), ply)
 	}
 	if depth <= 0 {
 		if ctx.settings.Config.AiEnableTacticalExt && ctx.settings.Config.AiTacticalExtDepth > 0 {
@@ -2277,9 +2437,12 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 				return tacticalExtensionScore(*state, ctx, currentPlayer, depthFromRoot)
 			}
 		}
-		return evaluateStateHeuristic(*state, ctx.rules, ctx.settings)
+		return scoreAtPly(evaluateStateHeuristic(*state, ctx.rules, ctx.settings), ply)
 	}
 
+	if ctx.settings.nodeCount != nil {
+		ctx.settings.nodeCount.Add(1)
+	}
 	if ctx.settings.Stats != nil {
 		ctx.settings.Stats.Nodes++
 		if ctx.settings.Stats.Nodes == 1 || (ctx.settings.Stats.Nodes&searchProgressChunkMask) == 0 {
@@ -2287,7 +2450,7 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 		}
 	}
 	cache := selectCache(ctx)
-	tt := ensureTT(cache, ctx.settings.Config)
+	tt := EnsureTT(cache, ctx.settings.Config)
 	boardSize := ctx.settings.BoardSize
 	boardHash := ttKeyFor(*state, boardSize)
 	heuristicHash := heuristicHashFromConfig(ctx.settings.Config)
@@ -2325,7 +2488,7 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 			}
 			if entry.Depth >= depth {
 				logAITask(ctx, ctx.logIndent+1, "TT exact entry depth=%d flag=%d value=%.2f", entry.Depth, entry.Flag, entry.ScoreFloat())
-				if _, ret, value := applyTTEntry(entry, depth, &alpha, &beta, ctx.settings.Stats); ret {
+				if _, ret, value := applyTTEntry(entry, depth, ply, &alpha, &beta, ctx.settings.Stats); ret {
 					logAITask(ctx, ctx.logIndent+1, "TT exact returning value=%.2f", value)
 					return value
 				}
@@ -2343,6 +2506,11 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 		}
 	}
 	logAITask(ctx, ctx.logIndent, "No TT hit; continuing search")
+	if pvMove == nil && tt != nil && shouldApplyIID(ctx.settings.Config, depth, alpha, beta) && !timedOut(ctx) {
+		if move, ok := iidMove(state, ctx, depth, currentPlayer, depthFromRoot, alpha, beta, tt, boardHash, heuristicHash); ok {
+			pvMove = &move
+		}
+	}
 
 	maximizing := currentPlayer == PlayerBlack
 	best := math.Inf(-1)
@@ -2436,13 +2604,10 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 			break
 		}
 		if ctx.settings.Config.AiQuickWinExit && isImmediateWinCached(cache, *state, ctx.rules, move, currentPlayer, ctx.settings.BoardSize) {
-			win := -winScore
-			if currentPlayer == PlayerBlack {
-				win = winScore
-			}
+			win := winScoreAt(currentPlayer, ply+1)
 			if tt != nil {
 				meta := buildTTMeta(*state, ctx.settings.BoardSize, ctx.footprint)
-				replaced, overwrote := tt.Store(boardHash, heuristicHash, depth, win, TTExact, move, meta)
+				replaced, overwrote := tt.Store(boardHash, heuristicHash, depth, mateScoreToTT(win, ply), TTExact, move, meta)
 				if ctx.settings.Stats != nil {
 					ctx.settings.Stats.TTStores++
 					if replaced || overwrote {
@@ -2453,10 +2618,30 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 			}
 			return win
 		}
+		if idx == 0 && forkPlies(ctx.settings.Config, depthFromRoot) {
+			if plies, ok := proveFork(state, ctx.rules, move, currentPlayer, ctx.settings.BoardSize, ctx.settings.Config); ok {
+				if ctx.settings.Stats != nil {
+					ctx.settings.Stats.ForkProofs++
+				}
+				win := winScoreAt(currentPlayer, ply+plies)
+				if tt != nil {
+					meta := buildTTMeta(*state, ctx.settings.BoardSize, ctx.footprint)
+					replaced, overwrote := tt.Store(boardHash, heuristicHash, depth, mateScoreToTT(win, ply), TTExact, move, meta)
+					if ctx.settings.Stats != nil {
+						ctx.settings.Stats.TTStores++
+						if replaced || overwrote {
+							ctx.settings.Stats.TTOverwrites++
+							ctx.settings.Stats.TTReplacements++
+						}
+					}
+				}
+				return win
+			}
+		}
 		searchDepth := depth
 		reducedSearch := false
-		if shouldApplyLMR(depth, idx, quietNode) {
-			searchDepth = depth - lmrReduction
+		if shouldApplyLMR(ctx.settings.Config, depth, idx, quietNode) {
+			searchDepth = depth - lmrReductionFor(ctx.settings.Config)
 			if searchDepth < 1 {
 				searchDepth = 1
 			}
@@ -2501,6 +2686,9 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 				ctx.settings.Stats.Cutoffs++
 				ctx.settings.Stats.ABCutoffs++
 			}
+			if idx == 0 && ctx.settings.Stats != nil {
+				ctx.settings.Stats.FirstMoveCutoffs++
+			}
 			logPrune(ctx, depth, move, best, alpha, beta)
 			if ctx.settings.Config.AiEnableKillerMoves {
 				recordKiller(ctx, depthFromRoot, move)
@@ -2532,7 +2720,7 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 	}
 	if tt != nil {
 		meta := buildTTMeta(*state, ctx.settings.BoardSize, ctx.footprint)
-		replaced, overwrote := tt.Store(boardHash, heuristicHash, depth, best, flag, bestMove, meta)
+		replaced, overwrote := tt.Store(boardHash, heuristicHash, depth, mateScoreToTT(best, ply), flag, bestMove, meta)
 		if ctx.settings.Stats != nil {
 			ctx.settings.Stats.TTStores++
 			if replaced || overwrote {
@@ -2544,20 +2732,21 @@ func minimax(state *GameState, ctx minimaxContext, depth int, currentPlayer Play
 	return best
 }
 
-func applyTTEntry(entry TTEntry, depth int, alpha *float64, beta *float64, stats *SearchStats) (used bool, ret bool, value float64) {
+// applyTTEntry uses entry for a node ply moves from the root, rebasing a
+// win or loss score to count from the root.
+func applyTTEntry(entry TTEntry, depth int, ply int, alpha *float64, beta *float64, stats *SearchStats) (used bool, ret bool, value float64) {
 	if entry.Depth < depth {
 		return false, false, 0.0
 	}
+	value = mateScoreFromTT(entry.ScoreFloat(), ply)
 	switch entry.Flag {
 	case TTExact:
-		return true, true, entry.ScoreFloat()
+		return true, true, value
 	case TTLower:
-		value := entry.ScoreFloat()
 		if value > *alpha {
 			*alpha = value
 		}
 	case TTUpper:
-		value := entry.ScoreFloat()
 		if value < *beta {
 			*beta = value
 		}
@@ -2567,14 +2756,15 @@ func applyTTEntry(entry TTEntry, depth int, alpha *float64, beta *float64, stats
 			stats.Cutoffs++
 			stats.TTCutoffs++
 		}
-		return true, true, entry.ScoreFloat()
+		return true, true, value
 	}
-	return true, false, entry.ScoreFloat()
+	return true, false, value
 }
 
 func evaluateMoveWithCache(state *GameState, ctx minimaxContext, currentPlayer PlayerColor, move Move, depthLeft int, depthFromRoot int, boardHash uint64, outCached *bool, alpha, beta float64) float64 {
+	ply := depthFromRoot - ctx.rootPly
 	if timedOut(ctx) {
-		return evaluateStateHeuristic(*state, ctx.rules, ctx.settings)
+		return scoreAtPly(evaluateStateHeuristic(*state, ctx.rules, ctx.settings), ply)
 	}
 	_ = boardHash
 
@@ -2608,7 +2798,7 @@ func evaluateMoveWithCache(state *GameState, ctx minimaxContext, currentPlayer P
 				ctx.settings.OnGhostUpdate(state.Clone())
 			}
 			if depthLeft <= 1 || timedOut(ctx) {
-				score = evaluateStateHeuristic(*state, ctx.rules, ctx.settings)
+				score = scoreAtPly(evaluateStateHeuristic(*state, ctx.rules, ctx.settings), ply+1)
 			} else {
 				nextCtx := ctx
 				nextCtx.logIndent = ctx.logIndent + 1
@@ -2627,6 +2817,7 @@ func scoreBoardAtDepth(state GameState, settings AIScoreSettings, ctx minimaxCon
 	if timedOut(ctx) {
 		return nil, false
 	}
+	ctx.rootPly = depth
 	usedCache := false
 	scores := make([]float64, settings.BoardSize*settings.BoardSize)
 	for i := range scores {
@@ -2635,7 +2826,7 @@ func scoreBoardAtDepth(state GameState, settings AIScoreSettings, ctx minimaxCon
 	boardHash := ttKeyFor(state, settings.BoardSize)
 	heuristicHash := heuristicHashFromConfig(settings.Config)
 	cache := selectCache(ctx)
-	tt := ensureTT(cache, settings.Config)
+	tt := EnsureTT(cache, settings.Config)
 	var pvMove *Move
 	if tt != nil {
 		if entry, ok := tt.Probe(boardHash, heuristicHash); ok {
@@ -2722,11 +2913,7 @@ func scoreBoardAtDepth(state GameState, settings AIScoreSettings, ctx minimaxCon
 			return nil, false
 		}
 		if settings.Config.AiQuickWinExit && isImmediateWinCached(cache, state, ctx.rules, move, settings.Player, settings.BoardSize) {
-			win := -winScore
-			if settings.Player == PlayerBlack {
-				win = winScore
-			}
-			scores[move.Y*settings.BoardSize+move.X] = win
+			scores[move.Y*settings.BoardSize+move.X] = winScoreAt(settings.Player, 1)
 			if outUsedCache != nil {
 				*outUsedCache = usedCache
 			}
@@ -2736,6 +2923,13 @@ func scoreBoardAtDepth(state GameState, settings AIScoreSettings, ctx minimaxCon
 		cached := false
 		score := evaluateMoveWithCache(&state, ctx, settings.Player, move, depth, depth, boardHash, &cached, rootAlpha, rootBeta)
 		if settings.Config.AiEnableAspiration && (score <= aspirationAlpha || score >= aspirationBeta) {
+			if settings.Stats != nil {
+				if score >= aspirationBeta {
+					settings.Stats.AspFailHigh++
+				} else {
+					settings.Stats.AspFailLow++
+				}
+			}
 			if timedOut(ctx) {
 				if outUsedCache != nil {
 					*outUsedCache = usedCache
@@ -2780,6 +2974,12 @@ func mergeSearchStats(dst, src *SearchStats) {
 	dst.Cutoffs += src.Cutoffs
 	dst.TTCutoffs += src.TTCutoffs
 	dst.ABCutoffs += src.ABCutoffs
+	dst.AspFailHigh += src.AspFailHigh
+	dst.AspFailLow += src.AspFailLow
+	dst.IIDSearches += src.IIDSearches
+	dst.IIDMoves += src.IIDMoves
+	dst.FirstMoveCutoffs += src.FirstMoveCutoffs
+	dst.ForkProofs += src.ForkProofs
 	dst.CandidateCount += src.CandidateCount
 	dst.RootCandidates += src.RootCandidates
 	dst.DeepCandidates += src.DeepCandidates
@@ -3056,6 +3256,13 @@ func ScoreBoardDirectDepthParallel(state GameState, rules Rules, settings AIScor
 	if state.Hash == 0 {
 		state.recomputeHashes()
 	}
+	applyGamePhase(state, rules, &settings)
+	if settings.MaxNodes > 0 && settings.nodeCount == nil {
+		settings.nodeCount = &atomic.Int64{}
+	}
+	if settings.aborted == nil {
+		settings.aborted = &atomic.Bool{}
+	}
 
 	scores := make([]float64, settings.BoardSize*settings.BoardSize)
 	for i := range scores {
@@ -3084,7 +3291,7 @@ func ScoreBoardDirectDepthParallel(state GameState, rules Rules, settings AIScor
 	baseCtx.footprint = newSearchFootprint(state, settings.BoardSize)
 
 	cache := selectCache(baseCtx)
-	tt := ensureTT(cache, settings.Config)
+	tt := EnsureTT(cache, settings.Config)
 	if tt != nil {
 		tt.NextGeneration()
 	}
@@ -3204,10 +3411,7 @@ func ScoreBoardDirectDepthParallel(state GameState, rules Rules, settings AIScor
 	}
 	evaluateRootMove := func(localState *GameState, localCtx minimaxContext, localSettings AIScoreSettings, localStats *SearchStats, move Move) float64 {
 		if settings.Config.AiQuickWinExit && isImmediateWinCached(cache, *localState, rules, move, settings.Player, settings.BoardSize) {
-			win := -winScore
-			if settings.Player == PlayerBlack {
-				win = winScore
-			}
+			win := winScoreAt(settings.Player, 1)
 			updateRootBound(win)
 			if localSettings.OnNodeProgress != nil {
 				localSettings.OnNodeProgress(1)
@@ -3215,6 +3419,7 @@ func ScoreBoardDirectDepthParallel(state GameState, rules Rules, settings AIScor
 			return win
 		}
 		alphaBound, betaBound := readRootBounds()
+		localCtx.rootPly = settings.Depth
 		score := evaluateMoveWithCache(localState, localCtx, settings.Player, move, settings.Depth, settings.Depth, boardHash, nil, alphaBound, betaBound)
 		updateRootBound(score)
 		flushSearchProgress(localStats, localSettings)
@@ -3275,12 +3480,19 @@ func ScoreBoardDirectDepthParallel(state GameState, rules Rules, settings AIScor
 				}()
 			}
 
+			// Once the search is stopped the remaining moves are not handed
+			// out, so the workers wind down after their current move.
+			sent := 0
 			for _, move := range remaining {
+				if settings.aborted.Load() {
+					break
+				}
 				jobs <- move
+				sent++
 			}
 			close(jobs)
 
-			for i := 0; i < len(remaining); i++ {
+			for i := 0; i < sent; i++ {
 				result := <-results
 				scores[result.move.Y*settings.BoardSize+result.move.X] = result.score
 			}
@@ -3357,6 +3569,13 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 	if state.Hash == 0 {
 		state.recomputeHashes()
 	}
+	applyGamePhase(state, rules, &settings)
+	if settings.MaxNodes > 0 && settings.nodeCount == nil {
+		settings.nodeCount = &atomic.Int64{}
+	}
+	if settings.aborted == nil {
+		settings.aborted = &atomic.Bool{}
+	}
 	queueState := GameState{}
 	queueStateReady := false
 	if settings.Config.AiQueueEnabled && !settings.SkipQueueBacklog && !settings.DirectDepthOnly {
@@ -3373,6 +3592,9 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 		minDepth = settings.Config.AiMinDepth
 	}
 	ctx := newMinimaxContext(rules, settings, time.Now())
+	if settings.Stats != nil {
+		defer globalStats.recordNodes(settings.Stats, settings.Stats.Nodes)
+	}
 	if settings.Stats != nil && settings.Stats.Start.IsZero() {
 		settings.Stats.Start = ctx.start
 	}
@@ -3401,7 +3623,7 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 	startTime := ctx.start
 	lastDepthCompleted := 0
 	cache := selectCache(ctx)
-	tt := ensureTT(cache, settings.Config)
+	tt := EnsureTT(cache, settings.Config)
 	if tt != nil {
 		tt.NextGeneration()
 	}
@@ -3424,6 +3646,9 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 	var scores []float64
 	var lastScores []float64
 	var lastBestScore float64
+	var bestScores []float64
+	var rootBests []Move
+	extended := false
 	var fallbackScores []float64
 	rootMaximizing := settings.Player == PlayerBlack
 	fallbackBestScore := math.Inf(1)
@@ -3466,10 +3691,7 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 					for i := range winScores {
 						winScores[i] = illegalScore
 					}
-					win := -winScore
-					if settings.Player == PlayerBlack {
-						win = winScore
-					}
+					win := winScoreAt(settings.Player, 1)
 					winScores[move.Y*settings.BoardSize+move.X] = win
 					if tt != nil {
 						meta := buildTTMeta(state, settings.BoardSize, ctx.footprint)
@@ -3490,14 +3712,14 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 		alpha := math.Inf(-1)
 		beta := math.Inf(1)
 		if settings.Config.AiEnableAspiration && haveBest {
-			window := settings.Config.AiAspWindow
+			window := aspirationWindow(settings.Config, bestScores)
 			if window > 0 {
-				if settings.Config.AiAspWindowMax > 0 && window > settings.Config.AiAspWindowMax {
-					window = settings.Config.AiAspWindowMax
-				}
 				alpha = lastBestScore - window
 				beta = lastBestScore + window
 			}
+			if settings.Stats != nil {
+				settings.Stats.AspWindow = window
+			}
 		}
 		usedCache := false
 		var completed bool
@@ -3592,14 +3814,30 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 			}
 		}
 		if bestX >= 0 && bestY >= 0 {
+			best := Move{X: bestX, Y: bestY}
+			if len(rootBests) > 0 && rootBests[len(rootBests)-1] != best && ctx.hasDeadline && !extended {
+				// The best move changed: give the next depth time to settle it.
+				if extension := unstableExtension(settings.Config); extension > 0 {
+					ctx.deadline = ctx.deadline.Add(extension)
+					extended = true
+					if settings.Stats != nil {
+						settings.Stats.TimeExtended = extension
+					}
+				}
+			}
+			rootBests = append(rootBests, best)
+			if settings.Stats != nil {
+				settings.Stats.RootBestMoves = append(settings.Stats.RootBestMoves, best)
+			}
 			storeRootTransposeExact(state, settings, cache, depth, bestScore, Move{X: bestX, Y: bestY}, meta)
 			if settings.OnDepthComplete != nil {
-				settings.OnDepthComplete(depth, Move{X: bestX, Y: bestY}, bestScore)
+				settings.OnDepthComplete(depth, Move{X: bestX, Y: bestY}, bestScore, scores)
 			}
 		}
 		lastDepthCompleted = depth
 		lastScores = scores
 		lastBestScore = bestScore
+		bestScores = append(bestScores, bestScore)
 		haveBest = true
 	}
 	totalDuration := time.Since(startTime)
@@ -3607,7 +3845,7 @@ func ScoreBoard(state GameState, rules Rules, settings AIScoreSettings) []float6
 	if !settings.DirectDepthOnly && lastDepthCompleted < settings.Depth {
 		if timedOut(ctx) || (ctx.settings.ShouldStop != nil && ctx.settings.ShouldStop()) {
 			if queueStateReady {
-				enqueueSearchBacklogTask(queueState, rules)
+				enqueueSearchBacklogTask(queueState, rules, false)
 			}
 		}
 	}
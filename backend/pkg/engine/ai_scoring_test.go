@@ -1,9 +1,11 @@
-package main
+package engine
 
 import (
+	"math"
 	"reflect"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestScoreBoardStoresRootTTEntryAtCompletedDepth(t *testing.T) {
@@ -52,7 +54,7 @@ func TestScoreBoardStoresRootTTEntryAtCompletedDepth(t *testing.T) {
 		t.Fatalf("expected a legal best move")
 	}
 
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -290,6 +292,40 @@ func TestCaptureUrgencyHeuristicPenalizesOpponentCaptureWinThreat(t *testing.T)
 	}
 }
 
+func TestCaptureScaleFollowsTheSchedule(t *testing.T) {
+	heuristics := HeuristicConfig{CaptureScaleEarly: 1, CaptureScaleMid: 2, CaptureScaleLate: 4}
+	cases := []struct {
+		captured int
+		want     float64
+	}{
+		{0, 1}, {2, 1.4}, {5, 2}, {6, 4}, {8, 4},
+	}
+	for _, tc := range cases {
+		if got := captureScale(heuristics, tc.captured, 10); math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("captureScale(%d of 10) = %.3f, want %.3f", tc.captured, got, tc.want)
+		}
+	}
+}
+
+func TestCaptureUrgencyHeuristicWeighsHangingPairsByCaptureProgress(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.Board.Set(2, 4, CellWhite)
+	state.Board.Set(3, 4, CellBlack)
+	state.Board.Set(4, 4, CellBlack)
+
+	cfg := DefaultConfig()
+	early := captureUrgencyHeuristic(state, rules, cfg)
+	state.CapturedWhite = 4
+	late := captureUrgencyHeuristic(state, rules, cfg)
+	if late >= early || early >= 0 {
+		t.Fatalf("expected the hanging pair to cost more once white is close to a capture win, got %.2f then %.2f", early, late)
+	}
+}
+
 func TestHeuristicForMoveStronglyPenalizesCreatingCapturablePair(t *testing.T) {
 	settings := DefaultGameSettings()
 	settings.BoardSize = 9
@@ -436,18 +472,23 @@ func TestCandidateLimitKeepsLegacyPathWhenHardCapsDisabled(t *testing.T) {
 }
 
 func TestShouldApplyLMR(t *testing.T) {
-	if shouldApplyLMR(4, 5, false) {
+	cfg := DefaultConfig()
+	if shouldApplyLMR(cfg, 4, 5, false) {
 		t.Fatalf("expected no LMR on non-quiet nodes")
 	}
-	if shouldApplyLMR(2, 6, true) {
+	if shouldApplyLMR(cfg, 2, 6, true) {
 		t.Fatalf("expected no LMR below minimum depth")
 	}
-	if shouldApplyLMR(4, 3, true) {
+	if shouldApplyLMR(cfg, 4, 3, true) {
 		t.Fatalf("expected no LMR before late-move threshold")
 	}
-	if !shouldApplyLMR(4, 4, true) {
+	if !shouldApplyLMR(cfg, 4, 4, true) {
 		t.Fatalf("expected LMR on quiet late moves")
 	}
+	cfg.AiLmrLateMoveStart = 8
+	if shouldApplyLMR(cfg, 4, 6, true) {
+		t.Fatalf("expected configured late-move threshold to apply")
+	}
 }
 
 func TestApplyMoveWithUndoRestoresState(t *testing.T) {
@@ -519,7 +560,7 @@ func TestScoreBoardUsesRootTTExactShortcut(t *testing.T) {
 	state.recomputeHashes()
 
 	cache := newAISearchCache()
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -635,3 +676,77 @@ func TestScoreBoardUsesRootTransposeShortcutAcrossTranslation(t *testing.T) {
 		t.Fatalf("expected translated best move (%d,%d), got (%d,%d)", bestBase.X+dx, bestBase.Y+dy, bestTranslated.X, bestTranslated.Y)
 	}
 }
+
+func TestScoreBoardStopsAtMaxNodes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiDepth = 6
+	cfg.AiMinDepth = 1
+	cfg.AiMaxDepth = 6
+	cfg.AiTimeBudgetMs = 0
+	cfg.AiTimeoutMs = 0
+	cfg.AiQuickWinExit = false
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.Board.Set(4, 4, CellBlack)
+	state.Board.Set(5, 4, CellWhite)
+	state.Board.Set(4, 5, CellBlack)
+	state.Board.Set(3, 3, CellWhite)
+	state.recomputeHashes()
+
+	search := func() ([]float64, *SearchStats) {
+		cache := newAISearchCache()
+		stats := &SearchStats{}
+		scores := ScoreBoard(state.Clone(), rules, AIScoreSettings{
+			Depth:            cfg.AiDepth,
+			BoardSize:        settings.BoardSize,
+			Player:           state.ToMove,
+			Cache:            &cache,
+			Config:           cfg,
+			Stats:            stats,
+			SkipQueueBacklog: true,
+			MaxNodes:         200,
+		})
+		return scores, stats
+	}
+	first, stats := search()
+	if stats.Nodes > 200 {
+		t.Fatalf("expected at most 200 nodes, got %d", stats.Nodes)
+	}
+	if stats.CompletedDepths >= cfg.AiDepth {
+		t.Fatalf("expected the node limit to cut the search short, completed depth %d", stats.CompletedDepths)
+	}
+	second, again := search()
+	if again.Nodes != stats.Nodes || !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected node-limited searches to be reproducible")
+	}
+}
+
+func TestScoreBoardDirectDepthParallelStopsAllWorkersQuickly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiTimeoutMs = 0
+	cfg.AiTimeBudgetMs = 0
+	state, rules, err := replayMoves(DefaultGameSettings(), benchPositions[1].moves)
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	cache := newAISearchCache()
+	start := time.Now()
+	ScoreBoardDirectDepthParallel(state, rules, AIScoreSettings{
+		Depth:           12,
+		BoardSize:       state.Board.Size(),
+		Player:          state.ToMove,
+		Cache:           &cache,
+		Config:          cfg,
+		Stats:           &SearchStats{},
+		DirectDepthOnly: true,
+		ShouldStop:      func() bool { return time.Since(start) > 100*time.Millisecond },
+	}, 4)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the workers to stop soon after the stop request, took %v", elapsed)
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// maxRequestDepth and maxRequestNodes bound the searches a request may
+	// ask for.
+	maxRequestDepth       = 20
+	maxRequestNodes int64 = 200_000_000
+	// nodeLimitCeilingMs still stops a node-limited search that runs
+	// this long, so no request holds a search worker indefinitely.
+	nodeLimitCeilingMs = 120_000
+)
+
+// validateSearchLimits checks the depth and node limit of a request; zero
+// keeps the live config's.
+func validateSearchLimits(depthField string, depth int, maxNodes int64) error {
+	if depth < 0 || depth > maxRequestDepth {
+		return fieldErrorf(depthField, "%s must be between 0 and %d", depthField, maxRequestDepth)
+	}
+	if maxNodes < 0 || maxNodes > maxRequestNodes {
+		return fieldErrorf("max_nodes", "max_nodes must be between 0 and %d", maxRequestNodes)
+	}
+	return nil
+}
+
+type AnalyzeRequest struct {
+	// Position, when set, is where Moves are replayed from instead of the
+	// empty board.
+	Position  *StartPosition `json:"position,omitempty"`
+	Moves     []Move         `json:"moves"`
+	Depth     int            `json:"depth"`
+	TimeoutMs int            `json:"timeout_ms"`
+	// MaxNodes bounds the search by node count. Without TimeoutMs the time
+	// limits are then lifted, up to nodeLimitCeilingMs, so the result does
+	// not depend on the machine.
+	MaxNodes int64 `json:"max_nodes"`
+}
+
+type Analysis struct {
+	BestMove   Move    `json:"best_move"`
+	Score      float64 `json:"score"`
+	Depth      int     `json:"depth"`
+	Nodes      int64   `json:"nodes"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+	NextPlayer int     `json:"next_player"`
+	Status     string  `json:"status"`
+	Board      [][]int `json:"board"`
+	// MateIn is how many plies away the forced win or loss the score
+	// announces is, 0 when it announces none.
+	MateIn int `json:"mate_in,omitempty"`
+	// Stability is how often the best move changed from depth to depth,
+	// absent when the answer came from the cache without a search.
+	Stability *RootStability `json:"stability,omitempty"`
+	// Resources is what the process used during the search, absent when
+	// there was nothing to search.
+	Resources *SearchResources `json:"resources,omitempty"`
+}
+
+func replayMoves(settings GameSettings, moves []Move) (GameState, Rules, error) {
+	rules := NewRules(settings)
+	state, err := replayFrom(DefaultGameState(settings), rules, moves)
+	return state, rules, err
+}
+
+// replayFrom plays moves on top of state, alternating sides from
+// state.ToMove and rejecting illegal moves or moves after the game ended.
+func replayFrom(state GameState, rules Rules, moves []Move) (GameState, error) {
+	state.Status = StatusRunning
+	for i, move := range moves {
+		if state.Status != StatusRunning {
+			return state, fieldErrorf(fmt.Sprintf("moves[%d]", i), "move %d played after game end", i+1)
+		}
+		if !move.IsValid(state.Board.Size()) {
+			return state, fieldErrorf(fmt.Sprintf("moves[%d]", i), "move %d out of bounds", i+1)
+		}
+		if ok, reason := rules.IsLegal(state, move, state.ToMove); !ok {
+			return state, fieldErrorf(fmt.Sprintf("moves[%d]", i), "move %d illegal: %s", i+1, reason)
+		}
+		applyMove(&state, rules, move, state.ToMove)
+	}
+	return state, nil
+}
+
+// Analyze replays moves from the request's position (the empty board by
+// default) and searches the result, returning the engine's preferred move
+// for the side to move.
+func Analyze(settings GameSettings, req AnalyzeRequest) (Analysis, error) {
+	start, err := renderStartState(settings, req.Position)
+	if err != nil {
+		return Analysis{}, err
+	}
+	rules := NewRules(settings)
+	state, err := replayFrom(start, rules, req.Moves)
+	if err != nil {
+		return Analysis{}, err
+	}
+	response := Analysis{
+		NextPlayer: PlayerToInt(state.ToMove),
+		Status:     StatusToString(state.Status),
+		Board:      BoardToSlice(state.Board),
+	}
+	if state.Status != StatusRunning {
+		return response, nil
+	}
+	if err := validateSearchLimits("depth", req.Depth, req.MaxNodes); err != nil {
+		return response, err
+	}
+	sample := sampleResources()
+	best, scores, stats, ok := searchPosition(state, rules, req.Depth, req.TimeoutMs, req.MaxNodes)
+	response.Resources = sample.since()
+	if !ok {
+		return response, errors.New("no legal move available")
+	}
+	response.BestMove = best
+	if score := scoreForMove(scores, best, state.Board.Size()); !math.IsInf(score, 0) && !math.IsNaN(score) {
+		response.Score = score
+		response.MateIn, _ = mateDistance(score)
+	}
+	response.Depth = stats.CompletedDepths
+	if stability := stats.RootStability(); stability.Depths > 0 {
+		response.Stability = &stability
+	}
+	response.Nodes = stats.Nodes
+	response.ElapsedMs = float64(time.Since(stats.Start).Microseconds()) / 1000.0
+	return response, nil
+}
+
+// searchPosition runs the live search configuration on state, with depth,
+// timeout and node limit overrides when greater than zero, and returns the
+// chosen move along with the root score of every cell (illegalScore for cells
+// not searched). The search waits for the shared pool like a suggestion.
+func searchPosition(state GameState, rules Rules, depth, timeoutMs int, maxNodes int64) (Move, []float64, *SearchStats, bool) {
+	config := liveAIConfig(GetConfig())
+	// Analysis reports the engine's real choice, whatever strength the
+	// live games are played at.
+	config.AiTargetElo = 0
+	config.AiHumanMoveBlend = 0
+	config.AiSelfPlayTemp = 0
+	if depth > 0 {
+		config.AiDepth = depth
+	}
+	if maxNodes > 0 {
+		config = nodeLimitedConfig(config)
+	}
+	if timeoutMs > 0 {
+		config.AiTimeoutMs = timeoutMs
+	}
+	stats := &SearchStats{Start: time.Now()}
+	aiSettings := AIScoreSettings{
+		Depth:     config.AiDepth,
+		TimeoutMs: config.AiTimeoutMs,
+		BoardSize: state.Board.Size(),
+		Player:    state.ToMove,
+		Cache:     SharedSearchCache(),
+		Config:    config,
+		Stats:     stats,
+		MaxNodes:  maxNodes,
+	}
+	var scores []float64
+	SearchPool.Run(searchJobSuggestion, "analysis", func() {
+		scores = ScoreBoard(state, rules, aiSettings)
+	})
+	ai := &AIPlayer{}
+	best, ok := ai.selectBestMove(state, rules, aiSettings, stats, scores)
+	if !ok {
+		return Move{}, scores, stats, false
+	}
+	return Move{X: best.X, Y: best.Y}, scores, stats, true
+}
+
+// nodeLimitedConfig lifts the wall-clock limits of config, leaving a node
+// limit as the bound on the search and nodeLimitCeilingMs as a backstop.
+func nodeLimitedConfig(config Config) Config {
+	config.AiTimeoutMs = nodeLimitCeilingMs
+	config.AiTimeBudgetMs = 0
+	return config
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReplayMovesAlternatesPlayers(t *testing.T) {
+	settings := DefaultGameSettings()
+	state, _, err := replayMoves(settings, []Move{{X: 9, Y: 9}, {X: 10, Y: 9}, {X: 9, Y: 10}})
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if state.Board.At(9, 9) != CellBlack || state.Board.At(10, 9) != CellWhite || state.Board.At(9, 10) != CellBlack {
+		t.Fatalf("unexpected stones after replay")
+	}
+	if state.ToMove != PlayerWhite {
+		t.Fatalf("expected white to move, got %v", state.ToMove)
+	}
+}
+
+func TestReplayMovesRejectsOccupiedCell(t *testing.T) {
+	settings := DefaultGameSettings()
+	if _, _, err := replayMoves(settings, []Move{{X: 9, Y: 9}, {X: 9, Y: 9}}); err == nil {
+		t.Fatalf("expected error when replaying onto an occupied cell")
+	}
+}
+
+func TestAnalysePositionReportsFinishedGame(t *testing.T) {
+	settings := DefaultGameSettings()
+	moves := []Move{}
+	for i := 0; i < 5; i++ {
+		moves = append(moves, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	moves = moves[:9]
+	response, err := Analyze(settings, AnalyzeRequest{Moves: moves, Depth: 1})
+	if err != nil {
+		t.Fatalf("unexpected analyse error: %v", err)
+	}
+	if response.Status != "black_won" {
+		t.Fatalf("expected black_won status, got %q", response.Status)
+	}
+	if response.Depth != 0 {
+		t.Fatalf("expected no search on a finished game, got depth %d", response.Depth)
+	}
+	if response.Resources != nil {
+		t.Fatalf("expected no resources without a search")
+	}
+}
+
+func TestAnalyseReportsSearchResources(t *testing.T) {
+	settings := DefaultGameSettings()
+	response, err := Analyze(settings, AnalyzeRequest{Moves: []Move{{X: 9, Y: 9}}, Depth: 2})
+	if err != nil {
+		t.Fatalf("unexpected analyse error: %v", err)
+	}
+	resources := response.Resources
+	if resources == nil || resources.PeakHeapBytes == 0 || resources.Mallocs == 0 || resources.GoroutinesStart == 0 {
+		t.Fatalf("expected sampled resources, got %+v", resources)
+	}
+}
+
+func TestSearchRequestsRejectUnboundedLimits(t *testing.T) {
+	settings := DefaultGameSettings()
+	moves := []Move{{X: 9, Y: 9}}
+	field := func(err error) string {
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			return ""
+		}
+		return fieldErr.Field
+	}
+	if _, err := Analyze(settings, AnalyzeRequest{Moves: moves, Depth: maxRequestDepth + 1}); field(err) != "depth" {
+		t.Fatalf("expected a too deep analysis to be refused, got %v", err)
+	}
+	if _, err := Analyze(settings, AnalyzeRequest{Moves: moves, MaxNodes: maxRequestNodes + 1}); field(err) != "max_nodes" {
+		t.Fatalf("expected too many nodes to be refused, got %v", err)
+	}
+	if _, err := Simulate(context.Background(), settings, SimulateRequest{WhiteDepth: 40}); field(err) != "white_depth" {
+		t.Fatalf("expected a too deep simulation to be refused, got %v", err)
+	}
+	if config := nodeLimitedConfig(DefaultConfig()); config.AiTimeoutMs != nodeLimitCeilingMs || config.AiTimeBudgetMs != 0 {
+		t.Fatalf("expected node-limited searches to keep the wall-clock backstop, got timeout %d", config.AiTimeoutMs)
+	}
+}
+
+func TestSolveReportsFinishedGameWinner(t *testing.T) {
+	settings := DefaultGameSettings()
+	moves := []Move{}
+	for i := 0; i < 5; i++ {
+		moves = append(moves, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	result, err := Solve(settings, moves[:9], 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected solve error: %v", err)
+	}
+	if !result.Solved || result.Winner != 1 {
+		t.Fatalf("expected solved black win, got %+v", result)
+	}
+}
+
+func TestSolveLeavesCaptureHeavyScoresUnsolved(t *testing.T) {
+	// A side one capture from winning scores winScore * CaptureScaleFinal,
+	// which is no forced win.
+	captures := winScore * resolvedHeuristicConfig(DefaultConfig()).CaptureScaleFinal
+	result := solveResult(Analysis{Status: "running", Score: captures, Depth: 6})
+	if result.Solved || result.Winner != 0 || result.MateIn != 0 {
+		t.Fatalf("expected a capture-heavy score to stay unsolved, got %+v", result)
+	}
+	result = solveResult(Analysis{Status: "running", Score: winScoreAt(PlayerWhite, 5), Depth: 6})
+	if !result.Solved || result.Winner != 2 || result.MateIn != 5 {
+		t.Fatalf("expected a forced white win in 5, got %+v", result)
+	}
+}
+
+func TestGameApplyMoveRejectsIllegalMove(t *testing.T) {
+	game := NewGame(DefaultGameSettings())
+	game.Start()
+	if err := game.ApplyMove(Move{X: 9, Y: 9}); err != nil {
+		t.Fatalf("unexpected error on first move: %v", err)
+	}
+	if err := game.ApplyMove(Move{X: 9, Y: 9}); err == nil {
+		t.Fatalf("expected error when playing on an occupied cell")
+	}
+}
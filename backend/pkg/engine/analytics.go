@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"math/bits"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type AnaliticsQueueEntryDTO struct {
+	ID                  string  `json:"id"`
+	Board               [][]int `json:"board"`
+	CurrentDepth        int     `json:"current_depth"`
+	TargetDepth         int     `json:"target_depth"`
+	Hits                int     `json:"hits"`
+	Frequency           int     `json:"frequency"`
+	Variants            int     `json:"variants"`
+	Analyzing           bool    `json:"analyzing"`
+	AnalysisStartedAtMs int64   `json:"analysis_started_at_ms"`
+}
+
+type AnaliticsQueueResponse struct {
+	Queue        []AnaliticsQueueEntryDTO `json:"queue"`
+	TotalInQueue int                      `json:"total_in_queue"`
+}
+
+type AnaliticsPayload struct {
+	Event        string                    `json:"event"`
+	Entry        *AnaliticsQueueEventEntry `json:"entry,omitempty"`
+	Dropped      []string                  `json:"dropped,omitempty"`
+	TotalInQueue int                       `json:"total_in_queue"`
+	UpdatedAt    int64                     `json:"updated_at_ms"`
+}
+
+type AnaliticsQueueEventEntry struct {
+	ID                  string `json:"id"`
+	CurrentDepth        int    `json:"current_depth"`
+	TargetDepth         int    `json:"target_depth"`
+	Hits                int    `json:"hits"`
+	Frequency           int    `json:"frequency"`
+	Variants            int    `json:"variants"`
+	Analyzing           bool   `json:"analyzing"`
+	AnalysisStartedAtMs int64  `json:"analysis_started_at_ms"`
+}
+
+type backlogAnalyticsEntry struct {
+	Hash                uint64
+	Board               Board
+	Stones              int
+	Created             time.Time
+	Hits                int
+	Frequency           int
+	Transforms          uint8
+	CurrentDepth        int
+	TargetDepth         int
+	Analyzing           bool
+	AnalysisStartedAtMs int64
+}
+
+func hashToBoardID(hash uint64) string {
+	return "0x" + strconv.FormatUint(hash, 16)
+}
+
+func boardToIntGrid(board Board) [][]int {
+	size := board.Size()
+	result := make([][]int, size)
+	for y := 0; y < size; y++ {
+		row := make([]int, size)
+		for x := 0; x < size; x++ {
+			row[x] = CellToInt(board.At(x, y))
+		}
+		result[y] = row
+	}
+	return result
+}
+
+func analiticsEntryToDTO(entry backlogAnalyticsEntry) AnaliticsQueueEntryDTO {
+	return AnaliticsQueueEntryDTO{
+		ID:                  hashToBoardID(entry.Hash),
+		Board:               boardToIntGrid(entry.Board),
+		CurrentDepth:        entry.CurrentDepth,
+		TargetDepth:         entry.TargetDepth,
+		Hits:                entry.Hits,
+		Frequency:           entry.Frequency,
+		Variants:            bits.OnesCount8(entry.Transforms),
+		Analyzing:           entry.Analyzing,
+		AnalysisStartedAtMs: entry.AnalysisStartedAtMs,
+	}
+}
+
+func analiticsEntryToEventEntry(entry backlogAnalyticsEntry) AnaliticsQueueEventEntry {
+	return AnaliticsQueueEventEntry{
+		ID:                  hashToBoardID(entry.Hash),
+		CurrentDepth:        entry.CurrentDepth,
+		TargetDepth:         entry.TargetDepth,
+		Hits:                entry.Hits,
+		Frequency:           entry.Frequency,
+		Variants:            bits.OnesCount8(entry.Transforms),
+		Analyzing:           entry.Analyzing,
+		AnalysisStartedAtMs: entry.AnalysisStartedAtMs,
+	}
+}
+
+func sortAnaliticsQueue(entries []backlogAnalyticsEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return compareAnaliticsPriority(entries[i], entries[j]) < 0
+	})
+}
+
+func compareAnaliticsPriority(a, b backlogAnalyticsEntry) int {
+	if a.Frequency != b.Frequency {
+		if a.Frequency > b.Frequency {
+			return -1
+		}
+		return 1
+	}
+	if a.Hits != b.Hits {
+		if a.Hits > b.Hits {
+			return -1
+		}
+		return 1
+	}
+	if a.Stones != b.Stones {
+		if a.Stones > b.Stones {
+			return -1
+		}
+		return 1
+	}
+	remainingA := analiticsRemainingDepth(a)
+	remainingB := analiticsRemainingDepth(b)
+	if remainingA != remainingB {
+		if remainingA > remainingB {
+			return -1
+		}
+		return 1
+	}
+	if !a.Created.Equal(b.Created) {
+		if a.Created.Before(b.Created) {
+			return -1
+		}
+		return 1
+	}
+	if a.Hash < b.Hash {
+		return -1
+	}
+	if a.Hash > b.Hash {
+		return 1
+	}
+	return 0
+}
+
+func analiticsRemainingDepth(entry backlogAnalyticsEntry) int {
+	remaining := entry.TargetDepth - entry.CurrentDepth
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func AnaliticsTopBoardsLimit() int {
+	limit := GetConfig().AiAnaliticsTopBoards
+	if limit <= 0 {
+		return 10
+	}
+	return limit
+}
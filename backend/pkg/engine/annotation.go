@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxAnnotationComment  = 2000
+	maxAnnotationShapes   = 64
+	maxAnnotationsPerGame = 500
+)
+
+// Annotation attaches a comment, arrows and marks to one ply of a game: ply
+// 0 is the start position and ply N the position after move N.
+type Annotation struct {
+	Ply         int               `json:"ply"`
+	Author      string            `json:"author,omitempty"`
+	Comment     string            `json:"comment,omitempty"`
+	Arrows      []AnnotationArrow `json:"arrows,omitempty"`
+	Marks       []AnnotationMark  `json:"marks,omitempty"`
+	CreatedAtMs int64             `json:"created_at_ms"`
+}
+
+type AnnotationArrow struct {
+	From Move `json:"from"`
+	To   Move `json:"to"`
+}
+
+// AnnotationMark highlights one cell. Shape is one of circle, square,
+// triangle, cross or label; labels carry a short Label text.
+type AnnotationMark struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Shape string `json:"shape"`
+	Label string `json:"label,omitempty"`
+}
+
+var annotationShapes = map[string]bool{"circle": true, "square": true, "triangle": true, "cross": true, "label": true}
+
+// Validate checks an annotation against a game of plies moves on a board of
+// the given size.
+func (a Annotation) Validate(boardSize, plies int) error {
+	if a.Ply < 0 || a.Ply > plies {
+		return fieldErrorf("ply", "ply must be between 0 and %d", plies)
+	}
+	if strings.TrimSpace(a.Comment) == "" && len(a.Arrows) == 0 && len(a.Marks) == 0 {
+		return fmt.Errorf("annotation needs a comment, arrows or marks")
+	}
+	if utf8.RuneCountInString(a.Comment) > maxAnnotationComment {
+		return fieldErrorf("comment", "comment longer than %d characters", maxAnnotationComment)
+	}
+	if utf8.RuneCountInString(a.Author) > 64 {
+		return fieldErrorf("author", "author longer than 64 characters")
+	}
+	if len(a.Arrows)+len(a.Marks) > maxAnnotationShapes {
+		return fmt.Errorf("at most %d arrows and marks per annotation", maxAnnotationShapes)
+	}
+	for _, arrow := range a.Arrows {
+		if !arrow.From.IsValid(boardSize) || !arrow.To.IsValid(boardSize) {
+			return fieldErrorf("arrows", "arrow %d,%d -> %d,%d out of bounds", arrow.From.X, arrow.From.Y, arrow.To.X, arrow.To.Y)
+		}
+		if arrow.From.X == arrow.To.X && arrow.From.Y == arrow.To.Y {
+			return fieldErrorf("arrows", "arrow must join two different cells")
+		}
+	}
+	for _, mark := range a.Marks {
+		if !(Move{X: mark.X, Y: mark.Y}).IsValid(boardSize) {
+			return fieldErrorf("marks", "mark %d,%d out of bounds", mark.X, mark.Y)
+		}
+		if !annotationShapes[mark.Shape] {
+			return fieldErrorf("marks", "unknown mark shape %q", mark.Shape)
+		}
+		if (mark.Shape == "label") != (mark.Label != "") {
+			return fieldErrorf("marks", "label marks, and only they, need a label")
+		}
+		if utf8.RuneCountInString(mark.Label) > 4 {
+			return fieldErrorf("marks", "labels are at most 4 characters")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package engine
+
+import "testing"
+
+func TestGameControllerAnnotationsSortedByPly(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	id := controller.GameID()
+	for _, move := range []Move{{X: 9, Y: 9}, {X: 10, Y: 9}} {
+		if applied, reason := controller.ApplyHumanMove(move); !applied {
+			t.Fatalf("expected human move to apply: %s", reason)
+		}
+	}
+	if _, _, err := controller.Annotate(id, Annotation{Ply: 2, Comment: "solid reply"}); err != nil {
+		t.Fatalf("annotate failed: %v", err)
+	}
+	annotations, ok, err := controller.Annotate(id, Annotation{Ply: 1, Marks: []AnnotationMark{{X: 10, Y: 9, Shape: "label", Label: "A"}}})
+	if !ok || err != nil {
+		t.Fatalf("annotate failed: found=%v err=%v", ok, err)
+	}
+	if len(annotations) != 2 || annotations[0].Ply != 1 || annotations[1].Comment != "solid reply" {
+		t.Fatalf("expected annotations ordered by ply, got %+v", annotations)
+	}
+	if _, _, err := controller.Annotate(id, Annotation{Ply: 3, Comment: "future"}); err == nil {
+		t.Fatalf("expected a ply past the history to be rejected")
+	}
+	if _, ok, _ := controller.Annotate(id+7, Annotation{Comment: "nowhere"}); ok {
+		t.Fatalf("expected an unknown game id")
+	}
+
+	controller.StartGame(settings)
+	record, ok := controller.GameRecord(id)
+	if !ok || len(record.Annotations) != 2 {
+		t.Fatalf("expected annotations to follow the game into the archive, got %+v", record.Annotations)
+	}
+}
+
+func TestAnnotationValidateShapes(t *testing.T) {
+	cases := []Annotation{
+		{},
+		{Marks: []AnnotationMark{{X: 1, Y: 1, Shape: "star"}}},
+		{Marks: []AnnotationMark{{X: 1, Y: 1, Shape: "circle", Label: "x"}}},
+		{Arrows: []AnnotationArrow{{From: Move{X: 1, Y: 1}, To: Move{X: 1, Y: 1}}}},
+		{Arrows: []AnnotationArrow{{From: Move{X: 1, Y: 1}, To: Move{X: 19, Y: 1}}}},
+	}
+	for i, annotation := range cases {
+		if err := annotation.Validate(19, 0); err == nil {
+			t.Fatalf("case %d: expected %+v to be rejected", i, annotation)
+		}
+	}
+	valid := Annotation{Arrows: []AnnotationArrow{{From: Move{X: 1, Y: 1}, To: Move{X: 4, Y: 4}}}}
+	if err := valid.Validate(19, 0); err != nil {
+		t.Fatalf("expected a valid arrow, got %v", err)
+	}
+}
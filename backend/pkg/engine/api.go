@@ -0,0 +1,63 @@
+package engine
+
+import "errors"
+
+type SolveResult struct {
+	Solved   bool    `json:"solved"`
+	Winner   int     `json:"winner"`
+	BestMove Move    `json:"best_move"`
+	Depth    int     `json:"depth"`
+	Score    float64 `json:"score"`
+	// MateIn is how many plies the proven win takes, 0 when unknown.
+	MateIn int   `json:"mate_in,omitempty"`
+	Nodes  int64 `json:"nodes"`
+}
+
+// ApplyMove plays move for the side to move, resolving captures and wins.
+// It returns an error when the game is not running or the move is illegal.
+func (g *Game) ApplyMove(move Move) error {
+	if ok, reason := g.TryApplyMove(move); !ok {
+		return errors.New(reason)
+	}
+	return nil
+}
+
+// Solve searches the position reached by moves up to maxDepth plies and
+// reports whether the search proved a forced win for either side.
+func Solve(settings GameSettings, moves []Move, maxDepth int, timeoutMs int) (SolveResult, error) {
+	analysis, err := Analyze(settings, AnalyzeRequest{Moves: moves, Depth: maxDepth, TimeoutMs: timeoutMs})
+	if err != nil {
+		return SolveResult{}, err
+	}
+	return solveResult(analysis), nil
+}
+
+// solveResult reads a search's answer as a proof: a finished game, or a
+// score the search marked as a forced win or loss. Large heuristic scores,
+// such as a side close to winning by captures, prove nothing.
+func solveResult(analysis Analysis) SolveResult {
+	result := SolveResult{
+		BestMove: analysis.BestMove,
+		Depth:    analysis.Depth,
+		Score:    analysis.Score,
+		Nodes:    analysis.Nodes,
+	}
+	switch analysis.Status {
+	case "black_won":
+		result.Solved, result.Winner = true, 1
+	case "white_won":
+		result.Solved, result.Winner = true, 2
+	case "draw":
+		result.Solved = true
+	default:
+		if plies, ok := mateDistance(analysis.Score); ok {
+			result.MateIn = plies
+			result.Solved = true
+			result.Winner = 1
+			if analysis.Score < 0 {
+				result.Winner = 2
+			}
+		}
+	}
+	return result
+}
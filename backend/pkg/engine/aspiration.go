@@ -0,0 +1,43 @@
+package engine
+
+import "math"
+
+// aspirationVolatilityScale is how many times the typical change of the
+// best score between depths the aspiration window spans.
+const aspirationVolatilityScale = 2.0
+
+// aspirationWindow sizes the window searched around the last depth's best
+// score, given the best score of every completed depth. After a single
+// depth it is AiAspWindow; after that it follows the root mean square of
+// the changes between depths, so a quiet position gets a narrow window and
+// a tactical one a wide one, kept within AiAspWindowMin..AiAspWindowMax.
+// It is 0, a full window, once the last score is a win or a loss.
+func aspirationWindow(config Config, bestScores []float64) float64 {
+	if len(bestScores) == 0 || config.AiAspWindow <= 0 {
+		return 0
+	}
+	if isMateScore(bestScores[len(bestScores)-1]) {
+		return 0
+	}
+	window := config.AiAspWindow
+	sum := 0.0
+	changes := 0
+	for i := 1; i < len(bestScores); i++ {
+		previous, current := bestScores[i-1], bestScores[i]
+		if isMateScore(previous) || isMateScore(current) {
+			continue
+		}
+		sum += (current - previous) * (current - previous)
+		changes++
+	}
+	if changes > 0 {
+		window = aspirationVolatilityScale * math.Sqrt(sum/float64(changes))
+	}
+	if config.AiAspWindowMin > 0 && window < config.AiAspWindowMin {
+		window = config.AiAspWindowMin
+	}
+	if config.AiAspWindowMax > 0 && window > config.AiAspWindowMax {
+		window = config.AiAspWindowMax
+	}
+	return window
+}
@@ -0,0 +1,80 @@
+package engine
+
+import "testing"
+
+func TestAspirationWindowFollowsScoreVolatility(t *testing.T) {
+	config := DefaultConfig()
+	config.AiAspWindow = 1200
+	config.AiAspWindowMin = 100
+	config.AiAspWindowMax = 50000
+
+	if window := aspirationWindow(config, nil); window != 0 {
+		t.Fatalf("expected no window before any depth, got %.0f", window)
+	}
+	if window := aspirationWindow(config, []float64{300}); window != 1200 {
+		t.Fatalf("expected the initial window after one depth, got %.0f", window)
+	}
+	quiet := aspirationWindow(config, []float64{300, 310, 305})
+	if quiet != 100 {
+		t.Fatalf("expected a quiet position to get the minimum window, got %.0f", quiet)
+	}
+	tactical := aspirationWindow(config, []float64{300, 8300, 300})
+	if tactical != 16000 {
+		t.Fatalf("expected twice the score swing, got %.0f", tactical)
+	}
+	if window := aspirationWindow(config, []float64{0, 90000}); window != 50000 {
+		t.Fatalf("expected the window to be capped, got %.0f", window)
+	}
+	if window := aspirationWindow(config, []float64{300, winScoreAt(PlayerBlack, 3)}); window != 0 {
+		t.Fatalf("expected a full window once a win is found, got %.0f", window)
+	}
+	if window := aspirationWindow(config, []float64{winScoreAt(PlayerBlack, 5), 200, 260}); window != 120 {
+		t.Fatalf("expected mate scores to be left out of the volatility, got %.0f", window)
+	}
+}
+
+func TestScoreBoardReportsAspirationWindow(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.AiDepth = 3
+	cfg.AiMinDepth = 1
+	cfg.AiMaxDepth = 3
+	cfg.AiEnableEvalCache = false
+	cfg.AiEnableAspiration = true
+	cfg.AiAspWindow = 1200
+	cfg.AiAspWindowMin = 100
+	cfg.AiTimeBudgetMs = 0
+	configStore.Update(cfg)
+	defer func() {
+		configStore.Update(prev)
+		FlushGlobalCaches()
+	}()
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.Board.Set(4, 4, CellBlack)
+	state.Board.Set(5, 4, CellWhite)
+	state.Board.Set(4, 5, CellBlack)
+	state.recomputeHashes()
+
+	cache := newAISearchCache()
+	stats := &SearchStats{}
+	ScoreBoard(state, rules, AIScoreSettings{
+		Depth:     3,
+		BoardSize: settings.BoardSize,
+		Player:    state.ToMove,
+		Cache:     &cache,
+		Config:    cfg,
+		Stats:     stats,
+	})
+	if stats.CompletedDepths != 3 {
+		t.Fatalf("expected depth 3 to complete, got %d", stats.CompletedDepths)
+	}
+	if stats.AspWindow < cfg.AiAspWindowMin {
+		t.Fatalf("expected the last depth to use an aspiration window, got %.0f", stats.AspWindow)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// backlogDutyPeriod is the period of the backlog's duty cycle: its
+	// searches run for AiQueueDutyCycle of it, then pause for the rest.
+	backlogDutyPeriod = 200 * time.Millisecond
+	// backlogDutyPoll is how often a paused search checks whether it was
+	// asked to stop.
+	backlogDutyPoll = 20 * time.Millisecond
+	// backlogMaxRest caps the pause AiQueueSleepRatio puts after a board.
+	backlogMaxRest = time.Minute
+)
+
+// backlogDutyCycle pauses the threads of a backlog search once they ran for
+// their share of the current period, all of them until the period ends.
+type backlogDutyCycle struct {
+	run    int64
+	period int64
+	start  atomic.Int64
+}
+
+// newBacklogDutyCycle returns nil when duty leaves the backlog at full
+// speed (0, or 1 and above).
+func newBacklogDutyCycle(duty float64, now time.Time) *backlogDutyCycle {
+	if duty <= 0 || duty >= 1 {
+		return nil
+	}
+	d := &backlogDutyCycle{run: int64(float64(backlogDutyPeriod) * duty), period: int64(backlogDutyPeriod)}
+	d.start.Store(now.UnixNano())
+	return d
+}
+
+// wait pauses the calling search thread when the running part of the period
+// is over, until the next period starts or stop reports true.
+func (d *backlogDutyCycle) wait(stop func() bool) {
+	start := d.start.Load()
+	now := time.Now().UnixNano()
+	if now-start < d.run {
+		return
+	}
+	for now < start+d.period && !stop() {
+		rest := time.Duration(start + d.period - now)
+		if rest > backlogDutyPoll {
+			rest = backlogDutyPoll
+		}
+		time.Sleep(rest)
+		now = time.Now().UnixNano()
+	}
+	d.start.CompareAndSwap(start, now)
+}
+
+// backlogRest is how long a worker pauses after searching a board for busy:
+// AiQueueSleepRatio times as long, at most backlogMaxRest.
+func backlogRest(config Config, busy time.Duration) time.Duration {
+	if config.AiQueueSleepRatio <= 0 || busy <= 0 {
+		return 0
+	}
+	rest := time.Duration(float64(busy) * config.AiQueueSleepRatio)
+	if rest > backlogMaxRest {
+		rest = backlogMaxRest
+	}
+	return rest
+}
@@ -0,0 +1,308 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bookReportEvery is how often RunBookReports scans the transposition table
+// again.
+const bookReportEvery = 10 * time.Minute
+
+// bookReportMaxNodes bounds the positions a report lists, so a table that
+// solved every opening still gives a readable one.
+const bookReportMaxNodes = 2000
+
+// bookCandidateRadius is how far from the stones a move may be played to be
+// looked up; an empty board looks up every cell.
+const bookCandidateRadius = 2
+
+// BookNode is a position of the solved-opening tree, reached by playing Move
+// as Player from its parent. A solved position has an exact transposition
+// table entry at least the report's MinDepth deep, whose Depth, Score (from
+// Black's side) and MatePlies it gives; only the root may be unsolved.
+// BestMove is the table's best move and Best marks the child it leads to.
+type BookNode struct {
+	Move      *Move       `json:"move,omitempty"`
+	Player    int         `json:"player,omitempty"`
+	Solved    bool        `json:"solved"`
+	Depth     int         `json:"depth,omitempty"`
+	Score     int32       `json:"score"`
+	MatePlies int         `json:"mate_plies,omitempty"`
+	BestMove  *Move       `json:"best_move,omitempty"`
+	Best      bool        `json:"best,omitempty"`
+	Children  []*BookNode `json:"children,omitempty"`
+}
+
+// BookReport is the tree of solved positions within Plies plies of the
+// empty board, one per position up to rotations and reflections. Children
+// are ordered best first for the side to move.
+type BookReport struct {
+	GeneratedAtMs int64     `json:"generated_at_ms"`
+	BoardSize     int       `json:"board_size"`
+	Plies         int       `json:"plies"`
+	MinDepth      int       `json:"min_depth"`
+	HeuristicHash string    `json:"heuristic_hash"`
+	Positions     int       `json:"positions"`
+	Truncated     bool      `json:"truncated"`
+	Root          *BookNode `json:"root"`
+}
+
+// BuildBookReport walks the openings of settings from the empty board,
+// following every move that reaches a solved position of tt.
+func BuildBookReport(settings GameSettings, tt *TranspositionTable, plies, minDepth int) BookReport {
+	config := GetConfig()
+	heuristicHash := heuristicHashFromConfig(config)
+	report := BookReport{
+		GeneratedAtMs: time.Now().UnixMilli(),
+		BoardSize:     settings.BoardSize,
+		Plies:         plies,
+		MinDepth:      minDepth,
+		HeuristicHash: FormatHeuristicHash(heuristicHash),
+		Root:          &BookNode{},
+	}
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	if tt == nil {
+		return report
+	}
+	rules := NewRules(settings)
+	size := settings.BoardSize
+	solved := func(node *BookNode, state GameState) bool {
+		entry, ok := tt.Peek(ttKeyFor(state, size), heuristicHash)
+		if !ok || entry.Flag != TTExact || entry.Depth < minDepth {
+			return false
+		}
+		node.Solved, node.Depth, node.Score = true, entry.Depth, entry.Score
+		if plies, ok := mateDistance(entry.ScoreFloat()); ok {
+			node.MatePlies = plies
+		}
+		if entry.BestMove.IsValid(size) {
+			best := entry.BestMove
+			best.Depth = 0
+			node.BestMove = &best
+		}
+		return true
+	}
+	solved(report.Root, state)
+
+	var expand func(node *BookNode, state GameState, ply int)
+	expand = func(node *BookNode, state GameState, ply int) {
+		if ply >= plies || state.Status != StatusRunning {
+			return
+		}
+		// The table's best move goes first, so it stands for the moves
+		// symmetric to it.
+		candidates := bookCandidates(state.Board)
+		if node.BestMove != nil {
+			candidates = append([]Move{*node.BestMove}, candidates...)
+		}
+		seen := map[uint64]bool{}
+		for i, move := range candidates {
+			if report.Positions >= bookReportMaxNodes {
+				report.Truncated = true
+				break
+			}
+			child := state.Clone()
+			player := child.ToMove
+			if !applyMove(&child, rules, move, player) {
+				continue
+			}
+			key := ttKeyFor(child, size)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			played := move
+			childNode := &BookNode{Move: &played, Player: PlayerToInt(player)}
+			if !solved(childNode, child) {
+				continue
+			}
+			report.Positions++
+			childNode.Best = i == 0 && node.BestMove != nil
+			node.Children = append(node.Children, childNode)
+		}
+		// Scores are from Black's side, so Black's best child scores highest.
+		sign := int32(1)
+		if state.ToMove == PlayerWhite {
+			sign = -1
+		}
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			a, b := node.Children[i], node.Children[j]
+			if a.Best != b.Best {
+				return a.Best
+			}
+			return sign*a.Score > sign*b.Score
+		})
+		for _, childNode := range node.Children {
+			child := state.Clone()
+			applyMove(&child, rules, *childNode.Move, child.ToMove)
+			expand(childNode, child, ply+1)
+		}
+	}
+	expand(report.Root, state, 0)
+	return report
+}
+
+// bookCandidates lists the empty cells within bookCandidateRadius of a
+// stone, or every cell of an empty board, row by row.
+func bookCandidates(board Board) []Move {
+	size := board.Size()
+	near := make([]bool, size*size)
+	stones := false
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if board.At(x, y) == CellEmpty {
+				continue
+			}
+			stones = true
+			for dy := -bookCandidateRadius; dy <= bookCandidateRadius; dy++ {
+				for dx := -bookCandidateRadius; dx <= bookCandidateRadius; dx++ {
+					if board.InBounds(x+dx, y+dy) {
+						near[(y+dy)*size+x+dx] = true
+					}
+				}
+			}
+		}
+	}
+	var moves []Move
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if board.At(x, y) == CellEmpty && (!stones || near[y*size+x]) {
+				moves = append(moves, Move{X: x, Y: y})
+			}
+		}
+	}
+	return moves
+}
+
+func (n *BookNode) summary() string {
+	var parts []string
+	if n.Solved {
+		parts = append(parts, fmt.Sprintf("score %d", n.Score))
+		if n.MatePlies > 0 {
+			parts = append(parts, fmt.Sprintf("forced result in %d plies", n.MatePlies))
+		}
+		parts = append(parts, fmt.Sprintf("depth %d", n.Depth))
+	} else {
+		parts = append(parts, "not solved")
+	}
+	if n.Best {
+		parts = append(parts, "best")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func bookPlayerName(player int) string {
+	if player == 1 {
+		return "Black"
+	}
+	return "White"
+}
+
+// WriteText writes the report as an indented tree, one position per line.
+func (r BookReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Solved openings: %dx%d board, first %d plies, exact entries of depth %d or more, heuristics %s\n",
+		r.BoardSize, r.BoardSize, r.Plies, r.MinDepth, r.HeuristicHash)
+	fmt.Fprintf(&b, "Generated %s, %d solved positions", time.UnixMilli(r.GeneratedAtMs).UTC().Format(time.RFC3339), r.Positions)
+	if r.Truncated {
+		fmt.Fprintf(&b, " (stopped at %d)", bookReportMaxNodes)
+	}
+	b.WriteString("\nScores are from Black's side.\n\n")
+	if r.Root != nil {
+		fmt.Fprintf(&b, "Empty board: %s\n", r.Root.summary())
+		var write func(node *BookNode, ply int)
+		write = func(node *BookNode, ply int) {
+			for _, child := range node.Children {
+				fmt.Fprintf(&b, "%s%d. %s %d,%d: %s\n", strings.Repeat("  ", ply), ply+1, bookPlayerName(child.Player), child.Move.X, child.Move.Y, child.summary())
+				write(child, ply+1)
+			}
+		}
+		write(r.Root, 0)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSGF writes the report as an SGF record with a variation per solved
+// move, each commented with its score.
+func (r BookReport) WriteSGF(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "(;GM[4]FF[4]SZ[%d]GN[Solved openings]C[First %d plies, exact entries of depth %d or more, heuristics %s. Scores are from Black's side.]",
+		r.BoardSize, r.Plies, r.MinDepth, r.HeuristicHash)
+	var write func(node *BookNode)
+	write = func(node *BookNode) {
+		for _, child := range node.Children {
+			if len(node.Children) > 1 {
+				b.WriteByte('(')
+			}
+			color := "B"
+			if child.Player == 2 {
+				color = "W"
+			}
+			fmt.Fprintf(&b, ";%s[%c%c]C[%s]", color, 'a'+child.Move.X, 'a'+child.Move.Y, child.summary())
+			write(child)
+			if len(node.Children) > 1 {
+				b.WriteByte(')')
+			}
+		}
+	}
+	if r.Root != nil {
+		write(r.Root)
+	}
+	b.WriteString(")\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var bookReports struct {
+	mu     sync.Mutex
+	report *BookReport
+}
+
+// RefreshBookReport builds the report of the live settings from the shared
+// transposition table, with the plies and depth of the config, and keeps it
+// for LatestBookReport.
+func RefreshBookReport(settings GameSettings) BookReport {
+	config := GetConfig()
+	report := BuildBookReport(settings, EnsureTT(SharedSearchCache(), config), config.AiBookPlies, config.AiBookMinDepth)
+	bookReports.mu.Lock()
+	bookReports.report = &report
+	bookReports.mu.Unlock()
+	return report
+}
+
+// LatestBookReport returns the last report built, and false before the
+// first.
+func LatestBookReport() (BookReport, bool) {
+	bookReports.mu.Lock()
+	defer bookReports.mu.Unlock()
+	if bookReports.report == nil {
+		return BookReport{}, false
+	}
+	return *bookReports.report, true
+}
+
+// RunBookReports refreshes the report every bookReportEvery until ctx is
+// done, for the settings of the live game. It does nothing while
+// ai_book_plies is 0.
+func RunBookReports(ctx context.Context, settings func() GameSettings) {
+	ticker := time.NewTicker(bookReportEvery)
+	defer ticker.Stop()
+	for {
+		if GetConfig().AiBookPlies > 0 {
+			RefreshBookReport(settings())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildBookReportFollowsSolvedOpenings(t *testing.T) {
+	settings := DefaultGameSettings()
+	size := settings.BoardSize
+	hash := heuristicHashFromConfig(GetConfig())
+	tt := NewTranspositionTable(1<<12, 4)
+	store := func(moves []Move, depth int, score float64, flag TTFlag, best Move) {
+		state, _, err := replayMoves(settings, moves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.Store(ttKeyFor(state, size), hash, depth, score, flag, best, TTMeta{})
+	}
+	center := Move{X: size / 2, Y: size / 2}
+	store([]Move{center}, 10, 300, TTExact, Move{X: center.X + 1, Y: center.Y + 1})
+	store([]Move{center, {X: center.X + 1, Y: center.Y + 1}}, 9, 250, TTExact, Move{X: center.X - 1, Y: center.Y})
+	store([]Move{center, {X: center.X + 1, Y: center.Y}}, 9, 400, TTLower, Move{})
+	store([]Move{center, {X: center.X, Y: center.Y + 2}}, 3, 500, TTExact, Move{})
+
+	report := BuildBookReport(settings, tt, 3, 8)
+	root := report.Root
+	if report.Positions != 2 || root.Solved || len(root.Children) != 1 {
+		t.Fatalf("expected one solved first move, got %d positions and %+v", report.Positions, root)
+	}
+	first := root.Children[0]
+	if *first.Move != center || first.Player != 1 || first.Depth != 10 || first.Score != 300 || len(first.Children) != 1 {
+		t.Fatalf("unexpected first move %+v", first)
+	}
+	// The diagonal replies are rotations of one another and count once.
+	if reply := first.Children[0]; !reply.Best || reply.Player != 2 || reply.Depth != 9 {
+		t.Fatalf("expected the table's best reply, got %+v", reply)
+	}
+
+	var sgf bytes.Buffer
+	if err := report.WriteSGF(&sgf); err != nil {
+		t.Fatal(err)
+	}
+	game, err := ParseSGF(sgf.String())
+	if err != nil || len(game.Moves) != 2 || game.Moves[0] != center || game.BoardSize != size {
+		t.Fatalf("expected the SGF to replay the solved line, got %+v (%v) from %s", game, err, sgf.String())
+	}
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil || !strings.Contains(text.String(), "  2. White") {
+		t.Fatalf("unexpected text report:\n%s", text.String())
+	}
+}
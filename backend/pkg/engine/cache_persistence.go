@@ -0,0 +1,29 @@
+package engine
+
+func PersistCaches() {
+	persistTTPersistence(GetConfig(), SharedSearchCache())
+	persistPositionFrequencies(GetConfig(), positionFrequencies)
+	persistGameDatabase(GetConfig(), storedGames)
+	persistUserProfiles(GetConfig(), UserProfiles)
+	persistCorrespondenceGames(GetConfig(), CorrespondenceGames)
+	persistCalibrations(GetConfig(), Calibrations)
+	persistColorAudits(GetConfig(), ColorAudits)
+	persistAnalysisSessions(GetConfig(), AnalysisSessions)
+	persistHumanMoves(GetConfig(), humanMoves)
+	persistHeuristicRegistry(GetConfig(), HeuristicRegistry)
+	persistGlobalStats(GetConfig(), globalStats)
+}
+
+func LoadPersistedCaches() {
+	loadTTPersistence(GetConfig(), SharedSearchCache())
+	loadPositionFrequencies(GetConfig(), positionFrequencies)
+	loadGameDatabase(GetConfig(), storedGames)
+	loadUserProfiles(GetConfig(), UserProfiles)
+	loadCorrespondenceGames(GetConfig(), CorrespondenceGames)
+	loadCalibrations(GetConfig(), Calibrations)
+	loadColorAudits(GetConfig(), ColorAudits)
+	loadAnalysisSessions(GetConfig(), AnalysisSessions)
+	loadHumanMoves(GetConfig(), humanMoves)
+	loadHeuristicRegistry(GetConfig(), HeuristicRegistry)
+	loadGlobalStats(GetConfig(), globalStats)
+}
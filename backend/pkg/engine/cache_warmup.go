@@ -0,0 +1,171 @@
+package engine
+
+import "sort"
+
+// warmupBreadth is how many continuations of each position of the opening
+// tree are queued.
+const warmupBreadth = 3
+
+// WarmupWindowOpen reports whether warm-up searches may run: a game against
+// the engine is in its first AiWarmupPlies plies and the human is to move.
+func (gc *GameController) WarmupWindowOpen() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.humanThinkingLocked() && gc.game.History().Size() <= GetConfig().AiWarmupPlies
+}
+
+// warmOpeningCacheLocked queues the opening tree of the game that was just
+// started for the backlog, so the engine's first moves hit the transposition
+// table.
+func (gc *GameController) warmOpeningCacheLocked() {
+	config := GetConfig()
+	if !config.AiQueueEnabled || config.AiWarmupPlies <= 0 {
+		return
+	}
+	blackAI, whiteAI := !gc.game.sideIsHuman(PlayerBlack), !gc.game.sideIsHuman(PlayerWhite)
+	if !blackAI && !whiteAI {
+		return
+	}
+	state, rules := gc.game.State(), gc.game.rules
+	go func() {
+		for _, position := range openingTree(state, rules, config.AiWarmupPlies, warmupBreadth) {
+			if (position.ToMove == PlayerBlack && blackAI) || (position.ToMove == PlayerWhite && whiteAI) {
+				enqueueBacklogTask(position, rules, false, backlogWarmup)
+			}
+		}
+	}()
+}
+
+// openingTree lists the positions reached from state within plies moves,
+// following at most breadth continuations of each position, state included.
+// Transpositions are listed once.
+func openingTree(state GameState, rules Rules, plies, breadth int) []GameState {
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	seen := map[uint64]bool{PositionKey(state): true}
+	positions := []GameState{state}
+	frontier := []GameState{state}
+	for ply := 0; ply < plies; ply++ {
+		var next []GameState
+		for _, position := range frontier {
+			for _, child := range openingContinuations(position, rules, breadth) {
+				key := PositionKey(child)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				positions = append(positions, child)
+				next = append(next, child)
+			}
+		}
+		frontier = next
+	}
+	return positions
+}
+
+// openingContinuations returns up to breadth distinct positions following
+// state: the moves played from it in the stored games first, then the
+// moves humans played, then the empty cells next to the stones closest to
+// the centre.
+func openingContinuations(state GameState, rules Rules, breadth int) []GameState {
+	if state.Status != StatusRunning {
+		return nil
+	}
+	size := state.Board.Size()
+	seen := map[uint64]bool{}
+	var children []GameState
+	add := func(move Move) {
+		if len(children) >= breadth || !move.IsValid(size) || state.Board.At(move.X, move.Y) != CellEmpty {
+			return
+		}
+		if ok, _ := rules.IsLegal(state, move, state.ToMove); !ok {
+			return
+		}
+		child := state.Clone()
+		if !applyMove(&child, rules, move, child.ToMove) || child.Status != StatusRunning {
+			return
+		}
+		key := PositionKey(child)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		children = append(children, child)
+	}
+	for _, move := range storedContinuations(state) {
+		add(move)
+	}
+	for _, stat := range humanMoves.ForState(state) {
+		add(stat.Move)
+	}
+	for _, move := range cellsNearStones(state.Board) {
+		add(move)
+	}
+	return children
+}
+
+// storedContinuations lists the moves played from state in the stored
+// games, most played first, on the orientation of state.
+func storedContinuations(state GameState) []Move {
+	size := state.Board.Size()
+	transform := symmetryTransforms[canonicalSymIndex(state.HashSym)]
+	counts := map[Move]int{}
+	for _, hit := range storedGames.index(size).positions[ttKeyFor(state, size)] {
+		cx, cy := transformCoord(hit.next.X, hit.next.Y, size, symmetryTransforms[hit.transform])
+		x, y := inverseTransformCoord(cx, cy, size, transform)
+		counts[Move{X: x, Y: y}]++
+	}
+	moves := make([]Move, 0, len(counts))
+	for move := range counts {
+		moves = append(moves, move)
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if counts[moves[i]] != counts[moves[j]] {
+			return counts[moves[i]] > counts[moves[j]]
+		}
+		if moves[i].Y != moves[j].Y {
+			return moves[i].Y < moves[j].Y
+		}
+		return moves[i].X < moves[j].X
+	})
+	return moves
+}
+
+// cellsNearStones lists the empty cells touching a stone, closest to the
+// centre first; the centre alone on an empty board.
+func cellsNearStones(board Board) []Move {
+	size := board.Size()
+	center := size / 2
+	var cells []Move
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if board.At(x, y) != CellEmpty || !hasNeighborStone(board, x, y) {
+				continue
+			}
+			cells = append(cells, Move{X: x, Y: y})
+		}
+	}
+	if len(cells) == 0 && board.At(center, center) == CellEmpty {
+		return []Move{{X: center, Y: center}}
+	}
+	distance := func(move Move) int {
+		dx, dy := move.X-center, move.Y-center
+		return dx*dx + dy*dy
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return distance(cells[i]) < distance(cells[j]) })
+	return cells
+}
+
+func hasNeighborStone(board Board, x, y int) bool {
+	size := board.Size()
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if (dx != 0 || dy != 0) && nx >= 0 && ny >= 0 && nx < size && ny < size && board.At(nx, ny) != CellEmpty {
+				return true
+			}
+		}
+	}
+	return false
+}
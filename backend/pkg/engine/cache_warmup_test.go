@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpeningTreeSkipsSymmetricReplies(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 15
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	positions := openingTree(state, NewRules(settings), 2, warmupBreadth)
+	// The empty board, the centre, then the orthogonal and diagonal replies:
+	// every other reply next to the centre is a rotation of those two.
+	if len(positions) != 4 {
+		t.Fatalf("expected 4 positions, got %d", len(positions))
+	}
+	if positions[1].Board.At(7, 7) != CellBlack {
+		t.Fatalf("expected the first move in the centre")
+	}
+	for _, position := range positions[2:] {
+		if position.ToMove != PlayerBlack || countBoardStones(position.Board) != 2 {
+			t.Fatalf("expected black to move after white's reply")
+		}
+	}
+}
+
+func TestWarmupTasksRunOnlyWhenNoOtherBoardIsSearched(t *testing.T) {
+	b := newSearchBacklog()
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	regular := DefaultGameState(settings)
+	regular.Board.Set(4, 4, CellBlack)
+	regular.recomputeHashes()
+	opening := DefaultGameState(settings)
+	opening.Board.Set(0, 0, CellBlack)
+	opening.recomputeHashes()
+	b.enqueue(backlogTask{state: regular, created: time.Unix(1, 0), targetDepth: 8}, false)
+	b.enqueue(backlogTask{state: opening, created: time.Unix(2, 0), targetDepth: 8, kind: backlogWarmup}, false)
+
+	task, hash, ok := b.pickHumanTurnTask(true)
+	if !ok || task.kind != backlogWarmup {
+		t.Fatalf("expected the warm-up task to be picked")
+	}
+	b.finishTaskProcessing(hash, false)
+	if task, _, ok := b.pickTaskForProcessing(); !ok || task.kind != backlogRegular {
+		t.Fatalf("expected the regular task first outside a game")
+	}
+	if _, _, ok := b.pickHumanTurnTask(true); ok {
+		t.Fatalf("expected no warm-up while a regular board is searched")
+	}
+}
+
+func TestWarmupWindowFollowsTheHumanTurn(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	if !controller.WarmupWindowOpen() {
+		t.Fatalf("expected warm-up while the human thinks about the first move")
+	}
+	controller.ApplyHumanMove(Move{X: 4, Y: 4})
+	if controller.WarmupWindowOpen() {
+		t.Fatalf("expected no warm-up while the engine is to move")
+	}
+}
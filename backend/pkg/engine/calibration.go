@@ -0,0 +1,377 @@
+package engine
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	calibrationQueueSize       = 4
+	calibrationKeep            = 32
+	calibrationDefaultMinDepth = 1
+	calibrationDefaultMaxDepth = 4
+	calibrationMaxDepth        = 10
+)
+
+// CalibrationRequest plays every depth from MinDepth to MaxDepth against the
+// next one over a duel opening suite (see DuelRequest). Move budgets default
+// to the maximum so the depth, not the clock, limits each side.
+type CalibrationRequest struct {
+	MinDepth     int      `json:"min_depth"`
+	MaxDepth     int      `json:"max_depth"`
+	Openings     [][]Move `json:"openings,omitempty"`
+	OpeningCount int      `json:"opening_count"`
+	OpeningPlies int      `json:"opening_plies"`
+	Seed         int64    `json:"seed"`
+	MoveBudgetMs int      `json:"move_budget_ms"`
+	MaxMoves     int      `json:"max_moves"`
+	Workers      int      `json:"workers"`
+}
+
+// CalibrationPair is the match of Depth+1 against Depth, reported from the
+// deeper side.
+type CalibrationPair struct {
+	Depth      int     `json:"depth"`
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Draws      int     `json:"draws"`
+	Losses     int     `json:"losses"`
+	Unfinished int     `json:"unfinished"`
+	Errors     int     `json:"errors"`
+	ScoreRate  float64 `json:"score_rate"`
+	Elo        float64 `json:"elo"`
+	EloLow     float64 `json:"elo_low"`
+	EloHigh    float64 `json:"elo_high"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+}
+
+// CalibrationPoint rates a depth against MinDepth by chaining the pairwise
+// differences; the interval is the sum of the pairwise intervals. AvgMoveMs
+// is the mean search time of that depth's moves over its games.
+type CalibrationPoint struct {
+	Depth     int     `json:"depth"`
+	Elo       float64 `json:"elo"`
+	EloLow    float64 `json:"elo_low"`
+	EloHigh   float64 `json:"elo_high"`
+	AvgMoveMs float64 `json:"avg_move_ms"`
+	Moves     int     `json:"moves"`
+}
+
+type Calibration struct {
+	ID           uint64             `json:"id"`
+	Status       string             `json:"status"`
+	Error        string             `json:"error,omitempty"`
+	Request      CalibrationRequest `json:"request"`
+	BoardSize    int                `json:"board_size"`
+	Openings     [][]Move           `json:"openings"`
+	Pairs        []CalibrationPair  `json:"pairs"`
+	Curve        []CalibrationPoint `json:"curve"`
+	CreatedAtMs  int64              `json:"created_at_ms"`
+	FinishedAtMs int64              `json:"finished_at_ms,omitempty"`
+}
+
+type calibrationJob struct {
+	calibration *Calibration
+	settings    GameSettings
+}
+
+// CalibrationQueue runs depth calibrations one at a time in the background
+// and keeps the last results, which survive restarts.
+type CalibrationQueue struct {
+	mu           sync.Mutex
+	nextID       uint64
+	calibrations []*Calibration
+	pending      chan calibrationJob
+	persistMu    sync.Mutex
+}
+
+var Calibrations = NewCalibrationQueue()
+
+func NewCalibrationQueue() *CalibrationQueue {
+	return &CalibrationQueue{pending: make(chan calibrationJob, calibrationQueueSize)}
+}
+
+// Submit validates req, fills in its defaults and queues the calibration.
+func (q *CalibrationQueue) Submit(settings GameSettings, req CalibrationRequest) (Calibration, error) {
+	if req.MinDepth <= 0 {
+		req.MinDepth = calibrationDefaultMinDepth
+	}
+	if req.MaxDepth <= 0 {
+		req.MaxDepth = calibrationDefaultMaxDepth
+	}
+	if req.MaxDepth > calibrationMaxDepth {
+		return Calibration{}, fieldErrorf("max_depth", "max_depth must be at most %d", calibrationMaxDepth)
+	}
+	if req.MaxDepth <= req.MinDepth {
+		return Calibration{}, fieldErrorf("max_depth", "max_depth must be greater than min_depth")
+	}
+	if req.MoveBudgetMs <= 0 {
+		req.MoveBudgetMs = simulateMaxMoveBudgetMs
+	}
+	openings := duelRequestOpenings(settings, DuelRequest{Openings: req.Openings, OpeningCount: req.OpeningCount, OpeningPlies: req.OpeningPlies, Seed: req.Seed})
+	req.Openings = nil
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	calibration := &Calibration{
+		ID:          q.nextID + 1,
+		Status:      "queued",
+		Request:     req,
+		BoardSize:   settings.BoardSize,
+		Openings:    openings,
+		Pairs:       []CalibrationPair{},
+		Curve:       []CalibrationPoint{},
+		CreatedAtMs: time.Now().UnixMilli(),
+	}
+	select {
+	case q.pending <- calibrationJob{calibration: calibration, settings: settings}:
+	default:
+		return Calibration{}, fmt.Errorf("calibration queue is full")
+	}
+	q.nextID++
+	q.calibrations = append(q.calibrations, calibration)
+	q.trimLocked()
+	return q.copyLocked(calibration), nil
+}
+
+func (q *CalibrationQueue) Get(id uint64) (Calibration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, calibration := range q.calibrations {
+		if calibration.ID == id {
+			return q.copyLocked(calibration), true
+		}
+	}
+	return Calibration{}, false
+}
+
+// List returns the kept calibrations, newest first, without their openings.
+func (q *CalibrationQueue) List() []Calibration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := make([]Calibration, 0, len(q.calibrations))
+	for i := len(q.calibrations) - 1; i >= 0; i-- {
+		calibration := q.copyLocked(q.calibrations[i])
+		calibration.Openings = nil
+		list = append(list, calibration)
+	}
+	return list
+}
+
+func (q *CalibrationQueue) copyLocked(calibration *Calibration) Calibration {
+	copied := *calibration
+	copied.Pairs = append([]CalibrationPair{}, calibration.Pairs...)
+	copied.Curve = append([]CalibrationPoint{}, calibration.Curve...)
+	return copied
+}
+
+// trimLocked drops the oldest calibrations that are no longer pending.
+func (q *CalibrationQueue) trimLocked() {
+	for len(q.calibrations) > calibrationKeep {
+		dropped := false
+		for i, calibration := range q.calibrations {
+			if calibration.Status != "queued" && calibration.Status != "running" {
+				q.calibrations = append(q.calibrations[:i], q.calibrations[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			return
+		}
+	}
+}
+
+// Run calibrates queued requests until ctx is done.
+func (q *CalibrationQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.pending:
+			q.run(ctx, job)
+			persistCalibrations(GetConfig(), q)
+		}
+	}
+}
+
+func (q *CalibrationQueue) run(ctx context.Context, job calibrationJob) {
+	q.update(job.calibration, func(calibration *Calibration) { calibration.Status = "running" })
+	req := job.calibration.Request
+	moveMs := map[int]float64{}
+	moves := map[int]int{}
+	curve := []CalibrationPoint{{Depth: req.MinDepth}}
+	for depth := req.MinDepth; depth < req.MaxDepth; depth++ {
+		pair, records := q.playPair(ctx, job, depth, moveMs, moves)
+		if ctx.Err() != nil {
+			q.update(job.calibration, func(calibration *Calibration) { calibration.Status, calibration.Error = "failed", "cancelled" })
+			return
+		}
+		pair.Elo, pair.EloLow, pair.EloHigh = duelEloInterval(records)
+		last := curve[len(curve)-1]
+		curve = append(curve, CalibrationPoint{
+			Depth:   depth + 1,
+			Elo:     last.Elo + pair.Elo,
+			EloLow:  last.EloLow + pair.EloLow,
+			EloHigh: last.EloHigh + pair.EloHigh,
+		})
+		for i := range curve {
+			curve[i].Moves = moves[curve[i].Depth]
+			if curve[i].Moves > 0 {
+				curve[i].AvgMoveMs = moveMs[curve[i].Depth] / float64(curve[i].Moves)
+			}
+		}
+		q.update(job.calibration, func(calibration *Calibration) {
+			calibration.Pairs = append(calibration.Pairs, pair)
+			calibration.Curve = append([]CalibrationPoint(nil), curve...)
+		})
+	}
+	q.update(job.calibration, func(calibration *Calibration) {
+		calibration.Status = "done"
+		calibration.FinishedAtMs = time.Now().UnixMilli()
+	})
+}
+
+// playPair plays depth+1 (as black_heuristics' side) against depth and
+// adds each side's search times to moveMs and moves.
+func (q *CalibrationQueue) playPair(ctx context.Context, job calibrationJob, depth int, moveMs map[int]float64, moves map[int]int) (CalibrationPair, []DuelGame) {
+	req := job.calibration.Request
+	batch := SimulateBatchRequest{
+		Openings:        job.calibration.Openings,
+		AlternateColors: true,
+		MoveBudgetMs:    req.MoveBudgetMs,
+		BlackDepth:      depth + 1,
+		WhiteDepth:      depth,
+		MaxMoves:        req.MaxMoves,
+		Workers:         req.Workers,
+		DisableCache:    true,
+	}
+	var records []DuelGame
+	summary := SimulateBatch(ctx, job.settings, batch, func(game SimulateBatchGame) {
+		records = append(records, DuelGame{Index: game.Index, Score: game.Score, Error: game.Error})
+		deeperColor := 1
+		if game.Swapped {
+			deeperColor = 2
+		}
+		for _, move := range game.Result.Moves {
+			if !move.IsAi {
+				continue
+			}
+			side := depth
+			if move.Player == deeperColor {
+				side = depth + 1
+			}
+			moveMs[side] += move.ElapsedMs
+			moves[side]++
+		}
+	})
+	pair := CalibrationPair{
+		Depth:      depth,
+		Games:      summary.Games,
+		Wins:       summary.Wins,
+		Draws:      summary.Draws,
+		Losses:     summary.Losses,
+		Unfinished: summary.Unfinished,
+		Errors:     summary.Errors,
+		ScoreRate:  summary.ScoreRate,
+		ElapsedMs:  summary.ElapsedMs,
+	}
+	return pair, records
+}
+
+func (q *CalibrationQueue) update(calibration *Calibration, change func(*Calibration)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(calibration)
+}
+
+type calibrationSnapshot struct {
+	NextID       uint64
+	Calibrations []Calibration
+}
+
+// snapshot keeps the finished calibrations; queued and running ones would
+// not resume after a restart.
+func (q *CalibrationQueue) snapshot() calibrationSnapshot {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	snapshot := calibrationSnapshot{NextID: q.nextID}
+	for _, calibration := range q.calibrations {
+		if calibration.Status == "done" || calibration.Status == "failed" {
+			snapshot.Calibrations = append(snapshot.Calibrations, q.copyLocked(calibration))
+		}
+	}
+	return snapshot
+}
+
+func (q *CalibrationQueue) load(snapshot calibrationSnapshot) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.calibrations = q.calibrations[:0]
+	for i := range snapshot.Calibrations {
+		calibration := snapshot.Calibrations[i]
+		q.calibrations = append(q.calibrations, &calibration)
+	}
+	if snapshot.NextID > q.nextID {
+		q.nextID = snapshot.NextID
+	}
+}
+
+func loadCalibrations(cfg Config, queue *CalibrationQueue) {
+	if queue == nil || cfg.CalibrationPath == "" {
+		log.Printf("[ai:cache] restored calibrations: 0 calibrations (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.CalibrationPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open calibrations %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored calibrations: 0 calibrations (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot calibrationSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode calibrations %s: %v", path, err)
+		return
+	}
+	queue.load(snapshot)
+	log.Printf("[ai:cache] restored calibrations from %s (%d calibrations)", path, len(snapshot.Calibrations))
+}
+
+func persistCalibrations(cfg Config, queue *CalibrationQueue) {
+	if queue == nil || cfg.CalibrationPath == "" {
+		log.Printf("[ai:cache] stored calibrations: 0 calibrations (disabled or no path)")
+		return
+	}
+	queue.persistMu.Lock()
+	defer queue.persistMu.Unlock()
+	path := resolveTTPersistencePath(cfg.CalibrationPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create calibration directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := queue.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create calibrations %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode calibrations %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored calibrations to %s (%d calibrations)", path, len(snapshot.Calibrations))
+}
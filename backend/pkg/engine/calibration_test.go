@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCalibrationChainsPairwiseElo(t *testing.T) {
+	opening := []Move{}
+	for i := 0; i < 4; i++ {
+		opening = append(opening, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	queue := NewCalibrationQueue()
+	submitted, err := queue.Submit(DefaultGameSettings(), CalibrationRequest{
+		MinDepth:     1,
+		MaxDepth:     3,
+		Openings:     [][]Move{opening},
+		MoveBudgetMs: 200,
+		MaxMoves:     4,
+	})
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	queue.run(context.Background(), <-queue.pending)
+
+	calibration, ok := queue.Get(submitted.ID)
+	if !ok || calibration.Status != "done" {
+		t.Fatalf("expected a finished calibration, got %+v", calibration)
+	}
+	if len(calibration.Pairs) != 2 || calibration.Pairs[0].Depth != 1 || calibration.Pairs[1].Depth != 2 {
+		t.Fatalf("expected pairs 2v1 and 3v2, got %+v", calibration.Pairs)
+	}
+	if len(calibration.Curve) != 3 || calibration.Curve[0].Elo != 0 || calibration.Curve[2].Depth != 3 {
+		t.Fatalf("unexpected curve %+v", calibration.Curve)
+	}
+	// Black completes a five at once in every game, so colour decides and
+	// each pair comes out even.
+	if calibration.Curve[2].Elo != 0 || calibration.Curve[1].Moves == 0 {
+		t.Fatalf("expected an even curve with timed moves, got %+v", calibration.Curve)
+	}
+
+	restored := NewCalibrationQueue()
+	restored.load(queue.snapshot())
+	if got, ok := restored.Get(submitted.ID); !ok || len(got.Curve) != 3 {
+		t.Fatalf("expected the calibration to survive a snapshot, got %+v", got)
+	}
+	if _, err := restored.Submit(DefaultGameSettings(), CalibrationRequest{MinDepth: 3, MaxDepth: 3}); err == nil {
+		t.Fatalf("expected an empty depth range to be rejected")
+	}
+}
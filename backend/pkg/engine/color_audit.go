@@ -0,0 +1,449 @@
+package engine
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	colorAuditQueueSize     = 4
+	colorAuditKeep          = 32
+	colorAuditDefaultDepth  = 3
+	colorAuditMaxDepth      = 10
+	colorAuditMaxMismatches = 20
+	// colorAuditEvalTolerance absorbs float rounding between the
+	// evaluation of a position and of its colour swap.
+	colorAuditEvalTolerance = 1e-6
+)
+
+// ColorAuditRequest plays the engine against itself at Depth over a duel
+// opening suite (see DuelRequest), each opening twice: as given and with
+// the squares of black and white exchanged. Openings are cut to an even
+// number of plies so that black is to move after both.
+type ColorAuditRequest struct {
+	Depth        int      `json:"depth"`
+	Openings     [][]Move `json:"openings,omitempty"`
+	OpeningCount int      `json:"opening_count"`
+	OpeningPlies int      `json:"opening_plies"`
+	Seed         int64    `json:"seed"`
+	MoveBudgetMs int      `json:"move_budget_ms"`
+	MaxMoves     int      `json:"max_moves"`
+	Workers      int      `json:"workers"`
+}
+
+// ColorAuditPair is one mirrored pair of games. Scores are black's (1 win,
+// 0.5 draw or unfinished, 0 loss). An engine without a colour bias wins
+// with whichever stones the opening favours, so black scores 1 over the
+// pair; Bias names the colour that scored more.
+type ColorAuditPair struct {
+	OpeningIndex       int     `json:"opening_index"`
+	BlackScore         float64 `json:"black_score"`
+	MirroredBlackScore float64 `json:"mirrored_black_score"`
+	Bias               string  `json:"bias,omitempty"`
+	Error              string  `json:"error,omitempty"`
+}
+
+// ColorAuditEval compares the static evaluation of every running position
+// of the games with the evaluation of its colour swap (stones, captures and
+// side to move exchanged), which should be its exact negation. MeanBias is
+// the average sum of the two: above zero the evaluation favours black.
+type ColorAuditEval struct {
+	Positions     int            `json:"positions"`
+	Asymmetric    int            `json:"asymmetric"`
+	MeanBias      float64        `json:"mean_bias"`
+	MaxDifference float64        `json:"max_difference"`
+	Mismatches    []EvalMismatch `json:"mismatches"`
+}
+
+// ColorAudit is a colour-balance experiment. BlackElo is the rating edge of
+// the side moving first, with a 95% interval; FirstPlayerBias is set when
+// the interval does not include zero.
+type ColorAudit struct {
+	ID              uint64            `json:"id"`
+	Kind            string            `json:"kind"`
+	Status          string            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	Request         ColorAuditRequest `json:"request"`
+	BoardSize       int               `json:"board_size"`
+	Openings        [][]Move          `json:"openings"`
+	Games           int               `json:"games"`
+	BlackWins       int               `json:"black_wins"`
+	WhiteWins       int               `json:"white_wins"`
+	Draws           int               `json:"draws"`
+	Unfinished      int               `json:"unfinished"`
+	Errors          int               `json:"errors"`
+	BlackScoreRate  float64           `json:"black_score_rate"`
+	BlackElo        float64           `json:"black_elo"`
+	BlackEloLow     float64           `json:"black_elo_low"`
+	BlackEloHigh    float64           `json:"black_elo_high"`
+	FirstPlayerBias bool              `json:"first_player_bias"`
+	BlackPairs      int               `json:"black_pairs"`
+	WhitePairs      int               `json:"white_pairs"`
+	EvenPairs       int               `json:"even_pairs"`
+	Pairs           []ColorAuditPair  `json:"pairs"`
+	Eval            ColorAuditEval    `json:"eval"`
+	CreatedAtMs     int64             `json:"created_at_ms"`
+	FinishedAtMs    int64             `json:"finished_at_ms,omitempty"`
+}
+
+type colorAuditJob struct {
+	audit    *ColorAudit
+	settings GameSettings
+}
+
+// ColorAuditQueue runs colour-balance audits one at a time in the
+// background and keeps the last results, which survive restarts.
+type ColorAuditQueue struct {
+	mu        sync.Mutex
+	nextID    uint64
+	audits    []*ColorAudit
+	pending   chan colorAuditJob
+	persistMu sync.Mutex
+}
+
+var ColorAudits = NewColorAuditQueue()
+
+func NewColorAuditQueue() *ColorAuditQueue {
+	return &ColorAuditQueue{pending: make(chan colorAuditJob, colorAuditQueueSize)}
+}
+
+// colorSwappedOpening exchanges the squares of black and white in opening,
+// which must have an even number of plies.
+func colorSwappedOpening(opening []Move) []Move {
+	swapped := make([]Move, len(opening))
+	for i := range opening {
+		swapped[i] = opening[i^1]
+	}
+	return swapped
+}
+
+// Submit validates req, fills in its defaults and queues the audit.
+func (q *ColorAuditQueue) Submit(settings GameSettings, req ColorAuditRequest) (ColorAudit, error) {
+	if req.Depth <= 0 {
+		req.Depth = colorAuditDefaultDepth
+	}
+	if req.Depth > colorAuditMaxDepth {
+		return ColorAudit{}, fieldErrorf("depth", "depth must be at most %d", colorAuditMaxDepth)
+	}
+	if req.MoveBudgetMs <= 0 {
+		req.MoveBudgetMs = simulateMaxMoveBudgetMs
+	}
+	openings := duelRequestOpenings(settings, DuelRequest{Openings: req.Openings, OpeningCount: req.OpeningCount, OpeningPlies: req.OpeningPlies, Seed: req.Seed})
+	for i, opening := range openings {
+		openings[i] = append([]Move(nil), opening[:len(opening)&^1]...)
+	}
+	req.Openings = nil
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	audit := &ColorAudit{
+		ID:          q.nextID + 1,
+		Kind:        "color_balance",
+		Status:      "queued",
+		Request:     req,
+		BoardSize:   settings.BoardSize,
+		Openings:    openings,
+		Pairs:       []ColorAuditPair{},
+		Eval:        ColorAuditEval{Mismatches: []EvalMismatch{}},
+		CreatedAtMs: time.Now().UnixMilli(),
+	}
+	select {
+	case q.pending <- colorAuditJob{audit: audit, settings: settings}:
+	default:
+		return ColorAudit{}, fmt.Errorf("experiment queue is full")
+	}
+	q.nextID++
+	q.audits = append(q.audits, audit)
+	q.trimLocked()
+	return q.copyLocked(audit), nil
+}
+
+func (q *ColorAuditQueue) Get(id uint64) (ColorAudit, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, audit := range q.audits {
+		if audit.ID == id {
+			return q.copyLocked(audit), true
+		}
+	}
+	return ColorAudit{}, false
+}
+
+// List returns the kept audits, newest first, without their openings,
+// pairs and mismatches.
+func (q *ColorAuditQueue) List() []ColorAudit {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := make([]ColorAudit, 0, len(q.audits))
+	for i := len(q.audits) - 1; i >= 0; i-- {
+		audit := q.copyLocked(q.audits[i])
+		audit.Openings, audit.Pairs, audit.Eval.Mismatches = nil, nil, nil
+		list = append(list, audit)
+	}
+	return list
+}
+
+func (q *ColorAuditQueue) copyLocked(audit *ColorAudit) ColorAudit {
+	copied := *audit
+	copied.Pairs = append([]ColorAuditPair{}, audit.Pairs...)
+	copied.Eval.Mismatches = append([]EvalMismatch{}, audit.Eval.Mismatches...)
+	return copied
+}
+
+// trimLocked drops the oldest audits that are no longer pending.
+func (q *ColorAuditQueue) trimLocked() {
+	for len(q.audits) > colorAuditKeep {
+		dropped := false
+		for i, audit := range q.audits {
+			if audit.Status != "queued" && audit.Status != "running" {
+				q.audits = append(q.audits[:i], q.audits[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			return
+		}
+	}
+}
+
+// Run audits queued requests until ctx is done.
+func (q *ColorAuditQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.pending:
+			q.run(ctx, job)
+			persistColorAudits(GetConfig(), q)
+		}
+	}
+}
+
+func (q *ColorAuditQueue) run(ctx context.Context, job colorAuditJob) {
+	q.update(job.audit, func(audit *ColorAudit) { audit.Status = "running" })
+	req := job.audit.Request
+	openings := make([][]Move, 0, 2*len(job.audit.Openings))
+	pairs := make([]ColorAuditPair, len(job.audit.Openings))
+	for i, opening := range job.audit.Openings {
+		openings = append(openings, opening, colorSwappedOpening(opening))
+		pairs[i].OpeningIndex = i
+	}
+	batch := SimulateBatchRequest{
+		Openings:     openings,
+		MoveBudgetMs: req.MoveBudgetMs,
+		Depth:        req.Depth,
+		MaxMoves:     req.MaxMoves,
+		Workers:      req.Workers,
+		DisableCache: true,
+	}
+	config := GetConfig()
+	rules := NewRules(job.settings)
+	var records []DuelGame
+	eval := ColorAuditEval{Mismatches: []EvalMismatch{}}
+	summary := SimulateBatch(ctx, job.settings, batch, func(game SimulateBatchGame) {
+		records = append(records, DuelGame{Index: game.Index, Score: game.Score, Error: game.Error})
+		pair := &pairs[game.OpeningIndex/2]
+		if game.Error != "" {
+			pair.Error = game.Error
+			return
+		}
+		if game.OpeningIndex%2 == 0 {
+			pair.BlackScore = game.Score
+		} else {
+			pair.MirroredBlackScore = game.Score
+		}
+		colorAuditEvaluate(&eval, game, job.settings, rules, config)
+	})
+	if ctx.Err() != nil {
+		q.update(job.audit, func(audit *ColorAudit) { audit.Status, audit.Error = "failed", "cancelled" })
+		return
+	}
+	elo, low, high := duelEloInterval(records)
+	if eval.Positions > 0 {
+		eval.MeanBias /= float64(eval.Positions)
+	}
+	q.update(job.audit, func(audit *ColorAudit) {
+		audit.Games = summary.Games
+		audit.BlackWins, audit.WhiteWins = summary.BlackWins, summary.WhiteWins
+		audit.Draws, audit.Unfinished, audit.Errors = summary.Draws, summary.Unfinished, summary.Errors
+		audit.BlackScoreRate = summary.ScoreRate
+		audit.BlackElo, audit.BlackEloLow, audit.BlackEloHigh = elo, low, high
+		audit.FirstPlayerBias = low > 0 || high < 0
+		for _, pair := range pairs {
+			switch total := pair.BlackScore + pair.MirroredBlackScore; {
+			case pair.Error != "":
+			case total > 1:
+				pair.Bias = "black"
+				audit.BlackPairs++
+			case total < 1:
+				pair.Bias = "white"
+				audit.WhitePairs++
+			default:
+				audit.EvenPairs++
+			}
+			audit.Pairs = append(audit.Pairs, pair)
+		}
+		audit.Eval = eval
+		audit.Status = "done"
+		audit.FinishedAtMs = time.Now().UnixMilli()
+	})
+}
+
+// colorAuditEvaluate replays game and checks the evaluation of each running
+// position against its colour swap.
+func colorAuditEvaluate(eval *ColorAuditEval, game SimulateBatchGame, settings GameSettings, rules Rules, config Config) {
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	moves := []Move{}
+	for _, played := range game.Result.Moves {
+		move := Move{X: played.X, Y: played.Y}
+		var undo searchMoveUndo
+		if !applyMoveWithUndo(&state, rules, move, state.ToMove, &undo) {
+			return
+		}
+		moves = append(moves, move)
+		if state.Status != StatusRunning {
+			return
+		}
+		score := evalVerifyFull(state, rules, config)
+		swapped := evalVerifyFull(colorSwappedState(state), rules, config)
+		diff := score + swapped
+		eval.Positions++
+		eval.MeanBias += diff
+		if math.Abs(diff) <= colorAuditEvalTolerance {
+			continue
+		}
+		eval.Asymmetric++
+		eval.MaxDifference = math.Max(eval.MaxDifference, math.Abs(diff))
+		if len(eval.Mismatches) < colorAuditMaxMismatches {
+			eval.Mismatches = append(eval.Mismatches, EvalMismatch{
+				Sample: game.Index + 1,
+				Kind:   "color",
+				Moves:  append([]Move(nil), moves...),
+				Want:   -score,
+				Got:    swapped,
+			})
+		}
+	}
+}
+
+// colorSwappedState is state with the colours of its stones, its capture
+// counts and its side to move exchanged.
+func colorSwappedState(state GameState) GameState {
+	swapped := state.Clone()
+	size := state.Board.Size()
+	swapped.Board = NewBoard(size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			switch state.Board.At(x, y) {
+			case CellBlack:
+				swapped.Board.Set(x, y, CellWhite)
+			case CellWhite:
+				swapped.Board.Set(x, y, CellBlack)
+			}
+		}
+	}
+	swapped.CapturedBlack, swapped.CapturedWhite = state.CapturedWhite, state.CapturedBlack
+	swapped.ToMove = otherPlayer(state.ToMove)
+	swapped.recomputeHashes()
+	return swapped
+}
+
+func (q *ColorAuditQueue) update(audit *ColorAudit, change func(*ColorAudit)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(audit)
+}
+
+type colorAuditSnapshot struct {
+	NextID uint64
+	Audits []ColorAudit
+}
+
+// snapshot keeps the finished audits; queued and running ones would not
+// resume after a restart.
+func (q *ColorAuditQueue) snapshot() colorAuditSnapshot {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	snapshot := colorAuditSnapshot{NextID: q.nextID}
+	for _, audit := range q.audits {
+		if audit.Status == "done" || audit.Status == "failed" {
+			snapshot.Audits = append(snapshot.Audits, q.copyLocked(audit))
+		}
+	}
+	return snapshot
+}
+
+func (q *ColorAuditQueue) load(snapshot colorAuditSnapshot) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.audits = q.audits[:0]
+	for i := range snapshot.Audits {
+		audit := snapshot.Audits[i]
+		q.audits = append(q.audits, &audit)
+	}
+	if snapshot.NextID > q.nextID {
+		q.nextID = snapshot.NextID
+	}
+}
+
+func loadColorAudits(cfg Config, queue *ColorAuditQueue) {
+	if queue == nil || cfg.ColorAuditPath == "" {
+		log.Printf("[ai:cache] restored color audits: 0 audits (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.ColorAuditPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open color audits %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored color audits: 0 audits (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot colorAuditSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode color audits %s: %v", path, err)
+		return
+	}
+	queue.load(snapshot)
+	log.Printf("[ai:cache] restored color audits from %s (%d audits)", path, len(snapshot.Audits))
+}
+
+func persistColorAudits(cfg Config, queue *ColorAuditQueue) {
+	if queue == nil || cfg.ColorAuditPath == "" {
+		log.Printf("[ai:cache] stored color audits: 0 audits (disabled or no path)")
+		return
+	}
+	queue.persistMu.Lock()
+	defer queue.persistMu.Unlock()
+	path := resolveTTPersistencePath(cfg.ColorAuditPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create color audit directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := queue.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create color audits %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode color audits %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored color audits to %s (%d audits)", path, len(snapshot.Audits))
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestColorSwappedStateNegatesEvaluation(t *testing.T) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	config := DefaultConfig()
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	for _, move := range []Move{{X: 9, Y: 9}, {X: 10, Y: 10}, {X: 9, Y: 10}, {X: 12, Y: 12}, {X: 9, Y: 11}} {
+		var undo searchMoveUndo
+		if !applyMoveWithUndo(&state, rules, move, state.ToMove, &undo) {
+			t.Fatalf("move %v rejected", move)
+		}
+	}
+	swapped := colorSwappedState(state)
+	if swapped.Board.At(9, 9) != CellWhite || swapped.Board.At(10, 10) != CellBlack || swapped.ToMove != PlayerBlack {
+		t.Fatalf("expected the colours and the side to move to be exchanged")
+	}
+	score := evalVerifyFull(state, rules, config)
+	if score == 0 {
+		t.Fatalf("expected black's three to count")
+	}
+	if got := evalVerifyFull(swapped, rules, config); got != -score {
+		t.Fatalf("expected the swap to score %v, got %v", -score, got)
+	}
+	if got := colorSwappedOpening([]Move{{X: 1}, {X: 2}, {X: 3}, {X: 4}}); got[0].X != 2 || got[1].X != 1 || got[3].X != 3 {
+		t.Fatalf("unexpected mirrored opening %v", got)
+	}
+}
+
+func TestColorAuditPlaysMirroredPairs(t *testing.T) {
+	opening := []Move{}
+	for i := 0; i < 4; i++ {
+		opening = append(opening, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	queue := NewColorAuditQueue()
+	submitted, err := queue.Submit(DefaultGameSettings(), ColorAuditRequest{
+		Depth:        1,
+		Openings:     [][]Move{append(opening, Move{X: 0, Y: 0})},
+		MoveBudgetMs: 200,
+		MaxMoves:     4,
+	})
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if len(submitted.Openings[0]) != len(opening) {
+		t.Fatalf("expected the opening to be cut to an even length, got %v", submitted.Openings[0])
+	}
+	queue.run(context.Background(), <-queue.pending)
+
+	audit, ok := queue.Get(submitted.ID)
+	if !ok || audit.Status != "done" || audit.Games != 2 {
+		t.Fatalf("expected a finished audit of two games, got %+v", audit)
+	}
+	// Black completes a five at once from either side of the pair, so the
+	// audit must find the first player favoured.
+	if audit.BlackWins != 2 || audit.BlackPairs != 1 || len(audit.Pairs) != 1 || audit.Pairs[0].Bias != "black" {
+		t.Fatalf("expected black to win both games, got %+v", audit)
+	}
+	if audit.Eval.Positions == 0 {
+		t.Fatalf("expected the evaluation to be checked, got %+v", audit.Eval)
+	}
+
+	restored := NewColorAuditQueue()
+	restored.load(queue.snapshot())
+	if got, ok := restored.Get(submitted.ID); !ok || len(got.Pairs) != 1 {
+		t.Fatalf("expected the audit to survive a snapshot, got %+v", got)
+	}
+	if _, err := restored.Submit(DefaultGameSettings(), ColorAuditRequest{Depth: colorAuditMaxDepth + 1}); err == nil {
+		t.Fatalf("expected a too deep audit to be rejected")
+	}
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	CommentaryWin       = "win"
+	CommentaryBlunder   = "blunder"
+	CommentaryCapture   = "capture"
+	CommentaryThreat    = "threat"
+	CommentaryBlock     = "block"
+	CommentaryQuietMove = "move"
+
+	commentaryMaxLines = 3
+)
+
+// CommentaryLine is one sentence about a move; Kind is the signal it comes
+// from.
+type CommentaryLine struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// Commentary describes the move that reached ply of game GameID. WinProb is
+// black's chance of winning by the mover's search, when it had one, and
+// Swing how far that moved from what the previous search expected, towards
+// the mover.
+type Commentary struct {
+	GameID        uint64           `json:"game_id"`
+	Ply           int              `json:"ply"`
+	Player        int              `json:"player"`
+	Move          Move             `json:"move"`
+	Lines         []CommentaryLine `json:"lines"`
+	WinProb       *float64         `json:"win_prob,omitempty"`
+	Swing         float64          `json:"swing,omitempty"`
+	CapturedBlack int              `json:"captured_black"`
+	CapturedWhite int              `json:"captured_white"`
+}
+
+// CommentaryTracker remembers the threats of the last position it
+// described, so the next move can be told apart from what was already there.
+type CommentaryTracker struct {
+	mu      sync.Mutex
+	threats ThreatTracker
+	gameID  uint64
+	ply     int
+}
+
+// Update describes the last move of history, which leads to state, unless
+// it was already described.
+func (t *CommentaryTracker) Update(gameID uint64, history []HistoryEntry, state GameState, settings GameSettings) (Commentary, bool) {
+	ply := len(history)
+	if ply == 0 {
+		return Commentary{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.gameID == gameID && t.ply >= ply {
+		return Commentary{}, false
+	}
+	t.gameID, t.ply = gameID, ply
+	report := t.threats.Update(gameID, ply, state, settings)
+
+	entry := history[ply-1]
+	mover := playerLabel(entry.Player)
+	commentary := Commentary{
+		GameID:        gameID,
+		Ply:           ply,
+		Player:        PlayerToInt(entry.Player),
+		Move:          Move{X: entry.Move.X, Y: entry.Move.Y},
+		Lines:         []CommentaryLine{},
+		CapturedBlack: state.CapturedBlack,
+		CapturedWhite: state.CapturedWhite,
+	}
+	add := func(kind, format string, args ...any) {
+		if len(commentary.Lines) < commentaryMaxLines {
+			commentary.Lines = append(commentary.Lines, CommentaryLine{Kind: kind, Text: fmt.Sprintf(format, args...)})
+		}
+	}
+	winStones := settings.CaptureWinStones
+	captured := state.CapturedBlack
+	if entry.Player == PlayerWhite {
+		captured = state.CapturedWhite
+	}
+
+	if state.Status == StatusBlackWon || state.Status == StatusWhiteWon {
+		if winStones > 0 && captured >= winStones {
+			add(CommentaryWin, "%s captures a pair and wins on captures, %d stones to %d.", mover, captured, opponentCaptured(state, entry.Player))
+		} else {
+			add(CommentaryWin, "%s completes five in a row at (%d,%d) and wins.", mover, entry.Move.X, entry.Move.Y)
+		}
+	}
+
+	if entry.Scored {
+		winProb := WinProbability(entry.Score)
+		black := winProb
+		if entry.Player == PlayerWhite {
+			black = 1 - winProb
+		}
+		commentary.WinProb = &black
+		// The opponent's search expected 1-p for the mover; finding much
+		// more means the opponent's move gave something away.
+		if ply >= 2 && history[ply-2].Scored && history[ply-2].Player != entry.Player {
+			previous := history[ply-2]
+			expected := 1 - WinProbability(previous.Score)
+			commentary.Swing = winProb - expected
+			if commentary.Swing >= reviewCriticalSwing && state.Status == StatusRunning {
+				add(CommentaryBlunder, "%s's move at (%d,%d) was a blunder: %s's chances jump from %d%% to %d%%.",
+					playerLabel(previous.Player), previous.Move.X, previous.Move.Y, mover, percent(expected), percent(winProb))
+			}
+		}
+	}
+
+	if entry.CapturedCount > 0 && (winStones <= 0 || captured < winStones) {
+		text := fmt.Sprintf("%s captures %s", mover, formatCapturedPairs(entry.CapturedPositions))
+		switch {
+		case winStones > 0 && captured+2 >= winStones:
+			add(CommentaryCapture, "%s: one more pair wins it (%d of %d).", text, captured, winStones)
+		case winStones > 0:
+			add(CommentaryCapture, "%s, %d to %d in the capture race.", text, captured, opponentCaptured(state, entry.Player))
+		default:
+			add(CommentaryCapture, "%s.", text)
+		}
+	}
+
+	if state.Status == StatusRunning {
+		commentThreats(report.Created, mover, playerLabel(otherPlayer(entry.Player)), winStones > 0 && captured+2 >= winStones, add)
+		if len(report.Blocked) > 0 {
+			blocked := report.Blocked[0]
+			add(CommentaryBlock, "%s stops %s's %s.", mover, playerLabel(otherPlayer(entry.Player)), threatLabel(blocked.Kind))
+		}
+	}
+
+	if len(commentary.Lines) == 0 {
+		if commentary.WinProb != nil {
+			add(CommentaryQuietMove, "%s plays (%d,%d) and rates their chances at %d%%.", mover, entry.Move.X, entry.Move.Y, percent(WinProbability(entry.Score)))
+		} else {
+			add(CommentaryQuietMove, "%s plays (%d,%d).", mover, entry.Move.X, entry.Move.Y)
+		}
+	}
+	return commentary, true
+}
+
+// commentThreats describes the threats a move created, the strongest first:
+// two winning threats at once are a fork the opponent cannot meet.
+func commentThreats(created []Threat, mover, opponent string, captureWins bool, add func(kind, format string, args ...any)) {
+	winning := 0
+	for _, threat := range created {
+		if threat.Kind != ThreatCapture {
+			winning++
+		}
+	}
+	if winning >= 2 {
+		add(CommentaryThreat, "%s forks with %s and %s: %s cannot stop both.", mover, threatLabel(created[0].Kind), threatLabel(created[1].Kind), opponent)
+		return
+	}
+	for _, threat := range created {
+		switch threat.Kind {
+		case ThreatOpenFour:
+			add(CommentaryThreat, "%s makes an open four: five in a row cannot be stopped.", mover)
+		case ThreatFour:
+			cell := threat.Cells[0]
+			add(CommentaryThreat, "%s threatens five at (%d,%d); %s must answer.", mover, cell.X, cell.Y, opponent)
+		case ThreatOpenThree:
+			add(CommentaryThreat, "%s builds an open three; left alone it becomes an open four.", mover)
+		case ThreatCapture:
+			cell := threat.Cells[0]
+			if captureWins {
+				add(CommentaryThreat, "%s threatens a capture at (%d,%d) that would win the game.", mover, cell.X, cell.Y)
+			} else {
+				add(CommentaryThreat, "%s threatens to capture at (%d,%d).", mover, cell.X, cell.Y)
+			}
+		}
+		return
+	}
+}
+
+func threatLabel(kind string) string {
+	switch kind {
+	case ThreatOpenFour:
+		return "open four"
+	case ThreatFour:
+		return "four"
+	case ThreatOpenThree:
+		return "open three"
+	default:
+		return "capture threat"
+	}
+}
+
+func playerLabel(player PlayerColor) string {
+	return CellFromPlayer(player).String()
+}
+
+func opponentCaptured(state GameState, player PlayerColor) int {
+	if player == PlayerBlack {
+		return state.CapturedWhite
+	}
+	return state.CapturedBlack
+}
+
+func formatCapturedPairs(stones []Move) string {
+	if len(stones) == 2 {
+		return fmt.Sprintf("the pair at (%d,%d) and (%d,%d)", stones[0].X, stones[0].Y, stones[1].X, stones[1].Y)
+	}
+	return fmt.Sprintf("%d pairs", len(stones)/2)
+}
+
+func percent(p float64) int {
+	return int(math.Round(p * 100))
+}
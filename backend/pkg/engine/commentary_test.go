@@ -0,0 +1,49 @@
+package engine
+
+import "testing"
+
+func TestCommentaryDescribesThreatsBlocksAndBlunders(t *testing.T) {
+	settings := DefaultGameSettings()
+	state := DefaultGameState(settings)
+	tracker := &CommentaryTracker{}
+	history := []HistoryEntry{}
+	step := func(move Move, score float64, scored bool) (Commentary, bool) {
+		history = append(history, HistoryEntry{Move: move, Player: state.ToMove, IsAi: true, Score: score, Scored: scored})
+		applyMove(&state, NewRules(settings), move, state.ToMove)
+		return tracker.Update(1, history, state, settings)
+	}
+	hasKind := func(c Commentary, kind string) bool {
+		for _, line := range c.Lines {
+			if line.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+
+	quiet, ok := step(Move{X: 5, Y: 5}, 0, true)
+	if !ok || len(quiet.Lines) != 1 || quiet.Lines[0].Kind != CommentaryQuietMove || quiet.WinProb == nil {
+		t.Fatalf("expected a quiet move with a win probability, got %+v", quiet)
+	}
+	if _, again := tracker.Update(1, history, state, settings); again {
+		t.Fatalf("a move should only be described once")
+	}
+	step(Move{X: 0, Y: 0}, 0, true)
+	step(Move{X: 6, Y: 5}, 0, true)
+	step(Move{X: 0, Y: 18}, 0, true)
+	three, _ := step(Move{X: 7, Y: 5}, 0, false)
+	if !hasKind(three, CommentaryThreat) || three.WinProb != nil {
+		t.Fatalf("expected black's open three to be described, got %+v", three)
+	}
+	blocked, _ := step(Move{X: 8, Y: 5}, 0, true)
+	if !hasKind(blocked, CommentaryBlock) || hasKind(blocked, CommentaryBlunder) {
+		t.Fatalf("expected only a block without a previous score, got %+v", blocked)
+	}
+	// White's search rates the position far better for itself than black's
+	// last search did for white: black's move was a blunder.
+	step(Move{X: 10, Y: 10}, 0, true)
+	blunder, _ := step(Move{X: 12, Y: 12}, reviewScoreScale*2, true)
+	if !hasKind(blunder, CommentaryBlunder) || blunder.Swing < reviewCriticalSwing {
+		t.Fatalf("expected white to punish a blunder, got %+v", blunder)
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ComponentStatus is the liveness of one long-running part of the server.
+// A component is stuck when it has not checked in for StaleAfterMs while it
+// is busy or has work queued.
+type ComponentStatus struct {
+	Name          string           `json:"name"`
+	Kind          string           `json:"kind"`
+	State         string           `json:"state"`
+	Busy          bool             `json:"busy"`
+	StartedAtMs   int64            `json:"started_at_ms"`
+	LastBeatMs    int64            `json:"last_beat_ms"`
+	IdleMs        int64            `json:"idle_ms"`
+	StaleAfterMs  int64            `json:"stale_after_ms"`
+	Queue         int              `json:"queue"`
+	QueueCapacity int              `json:"queue_capacity"`
+	Counters      map[string]int64 `json:"counters,omitempty"`
+	Stuck         bool             `json:"stuck"`
+}
+
+// Component is a registered part of the server: a hub, a worker, a player.
+// Its methods may be called on a nil Component, which does nothing.
+type Component struct {
+	name       string
+	kind       string
+	staleAfter time.Duration
+	queue      func() (int, int)
+	startedAt  time.Time
+	lastBeat   atomic.Int64
+	busy       atomic.Bool
+	state      atomic.Value
+	counters   sync.Map
+}
+
+// Beat records that the component is alive.
+func (c *Component) Beat() {
+	if c == nil {
+		return
+	}
+	c.lastBeat.Store(time.Now().UnixNano())
+}
+
+// SetState names what the component is doing, with busy telling whether it
+// is expected to check in while doing it.
+func (c *Component) SetState(state string, busy bool) {
+	if c == nil {
+		return
+	}
+	c.state.Store(state)
+	c.busy.Store(busy)
+	c.Beat()
+}
+
+// Add adds delta to the named counter, such as the frames a hub dropped.
+func (c *Component) Add(counter string, delta int64) {
+	if c == nil || delta == 0 {
+		return
+	}
+	value, _ := c.counters.LoadOrStore(counter, new(atomic.Int64))
+	value.(*atomic.Int64).Add(delta)
+}
+
+func (c *Component) status(now time.Time) ComponentStatus {
+	lastBeat := time.Unix(0, c.lastBeat.Load())
+	status := ComponentStatus{
+		Name:         c.name,
+		Kind:         c.kind,
+		Busy:         c.busy.Load(),
+		StartedAtMs:  c.startedAt.UnixMilli(),
+		LastBeatMs:   lastBeat.UnixMilli(),
+		IdleMs:       now.Sub(lastBeat).Milliseconds(),
+		StaleAfterMs: c.staleAfter.Milliseconds(),
+	}
+	if state, ok := c.state.Load().(string); ok {
+		status.State = state
+	}
+	if c.queue != nil {
+		status.Queue, status.QueueCapacity = c.queue()
+	}
+	c.counters.Range(func(key, value any) bool {
+		if status.Counters == nil {
+			status.Counters = make(map[string]int64)
+		}
+		status.Counters[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	status.Stuck = (status.Busy || status.Queue > 0) && now.Sub(lastBeat) > c.staleAfter
+	return status
+}
+
+// ComponentRegistry keeps the registered components by name.
+type ComponentRegistry struct {
+	mu         sync.Mutex
+	components map[string]*Component
+}
+
+var Components = NewComponentRegistry()
+
+func NewComponentRegistry() *ComponentRegistry {
+	return &ComponentRegistry{components: make(map[string]*Component)}
+}
+
+// Register adds a component, replacing any of the same name. queue, when
+// not nil, reports the component's queued items and their capacity.
+func (r *ComponentRegistry) Register(name, kind string, staleAfter time.Duration, queue func() (int, int)) *Component {
+	c := &Component{name: name, kind: kind, staleAfter: staleAfter, queue: queue, startedAt: time.Now()}
+	c.SetState("idle", false)
+	r.mu.Lock()
+	r.components[name] = c
+	r.mu.Unlock()
+	return c
+}
+
+// Unregister removes c, unless another component took its name since.
+func (r *ComponentRegistry) Unregister(c *Component) {
+	if c == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.components[c.name] == c {
+		delete(r.components, c.name)
+	}
+	r.mu.Unlock()
+}
+
+// List returns the components by kind and name.
+func (r *ComponentRegistry) List() []ComponentStatus {
+	r.mu.Lock()
+	components := make([]*Component, 0, len(r.components))
+	for _, c := range r.components {
+		components = append(components, c)
+	}
+	r.mu.Unlock()
+	// The queue callbacks take their owners' locks, so they run without
+	// the registry's.
+	now := time.Now()
+	list := make([]ComponentStatus, 0, len(components))
+	for _, c := range components {
+		list = append(list, c.status(now))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
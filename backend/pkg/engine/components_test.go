@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComponentsReportStuckWork(t *testing.T) {
+	registry := NewComponentRegistry()
+	queued := 0
+	hub := registry.Register("hub", "hub", time.Minute, func() (int, int) { return queued, 8 })
+	worker := registry.Register("worker", "backlog_worker", time.Minute, nil)
+	worker.SetState("searching", true)
+
+	list := registry.List()
+	if len(list) != 2 || list[0].Name != "worker" || list[1].Name != "hub" || list[1].QueueCapacity != 8 {
+		t.Fatalf("expected both components by kind, got %+v", list)
+	}
+	if list[0].Stuck || list[1].Stuck {
+		t.Fatalf("expected fresh components not to be stuck, got %+v", list)
+	}
+
+	past := time.Now().Add(-2 * time.Minute).UnixNano()
+	hub.lastBeat.Store(past)
+	worker.lastBeat.Store(past)
+	if list := registry.List(); !list[0].Stuck || list[1].Stuck {
+		t.Fatalf("expected only the busy worker to be stuck, got %+v", list)
+	}
+	queued = 3
+	if list := registry.List(); !list[1].Stuck || list[1].Queue != 3 {
+		t.Fatalf("expected the hub to be stuck with messages queued, got %+v", list[1])
+	}
+
+	hub.Add("dropped_frames", 2)
+	hub.Add("dropped_frames", 1)
+	hub.Add("evicted_clients", 0)
+	if list := registry.List(); list[1].Counters["dropped_frames"] != 3 || len(list[1].Counters) != 1 || list[0].Counters != nil {
+		t.Fatalf("expected the hub to count 3 dropped frames, got %+v", list)
+	}
+
+	replaced := registry.Register("worker", "backlog_worker", time.Minute, nil)
+	registry.Unregister(worker)
+	if list := registry.List(); len(list) != 2 {
+		t.Fatalf("expected unregistering a replaced component to keep the new one, got %+v", list)
+	}
+	registry.Unregister(replaced)
+	if list := registry.List(); len(list) != 1 {
+		t.Fatalf("expected the worker to be gone, got %+v", list)
+	}
+}
@@ -1,13 +1,17 @@
-package main
+package engine
 
 import "sync"
 
 type Config struct {
 	GhostMode             bool            `json:"ghost_mode"`
+	TeachingMode          bool            `json:"teaching_mode"`
+	Commentary            bool            `json:"commentary"`
+	AutoPlayCaptureWin    bool            `json:"auto_play_capture_win"`
 	LogDepthScores        bool            `json:"log_depth_scores"`
 	AiDepth               int             `json:"ai_depth"`
 	AiTimeoutMs           int             `json:"ai_timeout_ms"`
 	AiTimeBudgetMs        int             `json:"ai_time_budget_ms"`
+	AiUnstableExtendPct   int             `json:"ai_unstable_extend_pct"`
 	AiBacklogEstimateMs   int             `json:"ai_backlog_estimate_ms"`
 	AiMaxDepth            int             `json:"ai_max_depth"`
 	AiMinDepth            int             `json:"ai_min_depth"`
@@ -31,10 +35,21 @@ type Config struct {
 	AiQuickWinExit        bool            `json:"ai_quick_win_exit"`
 	AiEnableAspiration    bool            `json:"ai_enable_aspiration"`
 	AiAspWindow           float64         `json:"ai_asp_window"`
+	AiAspWindowMin        float64         `json:"ai_asp_window_min"`
 	AiAspWindowMax        float64         `json:"ai_asp_window_max"`
 	AiTtMaxEntries        int64           `json:"ai_tt_max_entries"`
 	AiPonderingEnabled    bool            `json:"ai_pondering_enabled"`
 	AiGhostThrottleMs     int             `json:"ai_ghost_throttle_ms"`
+	AiScoreThrottleMs     int             `json:"ai_score_throttle_ms"`
+	AiMinMoveMs           int             `json:"ai_min_move_ms"`
+	AiMaxMoveMs           int             `json:"ai_max_move_ms"`
+	IdleWarningMs         int             `json:"idle_warning_ms"`
+	MatchGraceMs          int             `json:"match_grace_ms"`
+	MatchPauseClock       bool            `json:"match_pause_clock"`
+	AiSuggestDepth        int             `json:"ai_suggest_depth"`
+	AiSuggestBudgetMs     int             `json:"ai_suggest_budget_ms"`
+	AiSuggestPerMinute    int             `json:"ai_suggest_per_minute"`
+	AiSuggestYield        bool            `json:"ai_suggest_yield"`
 	AiTtSize              int             `json:"ai_tt_size"`
 	AiTtBuckets           int             `json:"ai_tt_buckets"`
 	AiTtUseSetAssoc       bool            `json:"ai_tt_use_set_assoc"`
@@ -42,6 +57,17 @@ type Config struct {
 	AiTtMaxMemoryBytes    int64           `json:"ai_tt_max_memory_bytes"`
 	AiEnableTtPersistence bool            `json:"ai_enable_tt_persistence"`
 	AiTtPersistencePath   string          `json:"ai_tt_persistence_path"`
+	AiFrequencyPath       string          `json:"ai_position_frequency_path"`
+	AiGameDatabasePath    string          `json:"ai_game_database_path"`
+	AiEventLogPath        string          `json:"ai_event_log_path"`
+	UsersPath             string          `json:"users_path"`
+	CorrespondencePath    string          `json:"correspondence_path"`
+	CalibrationPath       string          `json:"calibration_path"`
+	SessionsPath          string          `json:"sessions_path"`
+	HumanMovesPath        string          `json:"human_moves_path"`
+	HeuristicRegistryPath string          `json:"heuristic_registry_path"`
+	GlobalStatsPath       string          `json:"global_stats_path"`
+	ColorAuditPath        string          `json:"color_audit_path"`
 	AiEnableRootTranspose bool            `json:"ai_enable_root_transpose_tt"`
 	AiRootTransposeSize   int             `json:"ai_root_transpose_tt_size"`
 	AiLogSearchStats      bool            `json:"ai_log_search_stats"`
@@ -50,6 +76,19 @@ type Config struct {
 	AiEnableHistoryMoves  bool            `json:"ai_enable_history_moves"`
 	AiKillerBoost         int             `json:"ai_killer_boost"`
 	AiHistoryBoost        int             `json:"ai_history_boost"`
+	AiLmrLateMoveStart    int             `json:"ai_lmr_late_move_start"`
+	AiLmrMinDepth         int             `json:"ai_lmr_min_depth"`
+	AiLmrReduction        int             `json:"ai_lmr_reduction"`
+	AiEnableIID           bool            `json:"ai_enable_iid"`
+	AiIIDMinDepth         int             `json:"ai_iid_min_depth"`
+	AiIIDReduction        int             `json:"ai_iid_reduction"`
+	AiForkPlies           int             `json:"ai_fork_plies"`
+	AiPhaseOpeningStones  int             `json:"ai_phase_opening_stones"`
+	AiPhaseEndgameStones  int             `json:"ai_phase_endgame_stones"`
+	AiPhaseCaptures       int             `json:"ai_phase_endgame_captures"`
+	AiPhaseThreatDensity  float64         `json:"ai_phase_endgame_threat_density"`
+	AiOpeningCaptureScale float64         `json:"ai_phase_opening_capture_scale"`
+	AiEndgameCaptureScale float64         `json:"ai_phase_endgame_capture_scale"`
 	AiUseScanWinIn1       bool            `json:"ai_use_scan_win_in_1"`
 	AiEnableTacticalMode  bool            `json:"ai_enable_tactical_mode"`
 	AiEnableTacticalExt   bool            `json:"ai_enable_tactical_extension"`
@@ -62,10 +101,25 @@ type Config struct {
 	AiLostModeMaxMoves    int             `json:"ai_lost_mode_max_moves"`
 	AiLostModeReplyLimit  int             `json:"ai_lost_mode_reply_limit"`
 	AiLostModeMinDepth    int             `json:"ai_lost_mode_min_depth"`
+	AiTargetElo           int             `json:"ai_target_elo"`
+	AiOpponentModel       bool            `json:"ai_opponent_model"`
+	AiHumanMoveBlend      float64         `json:"ai_human_move_blend"`
+	AiSelfPlayTemp        float64         `json:"ai_self_play_temperature"`
+	AiSelfPlayHalfLife    int             `json:"ai_self_play_temperature_half_life"`
+	AiSearchWorkers       int             `json:"ai_search_workers"`
 	AiQueueWorkers        int             `json:"ai_queue_workers"`
 	AiQueueAnalyzeThreads int             `json:"ai_queue_analyze_threads"`
 	AiQueueEnabled        bool            `json:"ai_enable_queue"`
+	AiWarmupPlies         int             `json:"ai_warmup_plies"`
+	AiPrefetchReplies     int             `json:"ai_prefetch_replies"`
 	AiAnaliticsTopBoards  int             `json:"ai_analitics_top_boards"`
+	AiBookPlies           int             `json:"ai_book_plies"`
+	AiBookMinDepth        int             `json:"ai_book_min_depth"`
+	AiQueueCompactMs      int             `json:"ai_queue_compact_interval_ms"`
+	AiQueueSchedule       string          `json:"ai_queue_schedule"`
+	AiQueueDutyCycle      float64         `json:"ai_queue_duty_cycle"`
+	AiQueueSleepRatio     float64         `json:"ai_queue_sleep_ratio"`
+	AiQueueMaxProcs       int             `json:"ai_queue_max_procs"`
 	Heuristics            HeuristicConfig `json:"heuristics"`
 }
 
@@ -85,7 +139,11 @@ type HeuristicConfig struct {
 	CaptureNearWin      float64 `json:"capture_near_win"`
 	CaptureInTwo        float64 `json:"capture_in_two"`
 	HangingPair         float64 `json:"hanging_pair"`
-	CaptureWinSoonScale float64 `json:"capture_win_soon_scale"`
+	CaptureScaleEarly   float64 `json:"capture_scale_early"`
+	CaptureScaleMid     float64 `json:"capture_scale_mid"`
+	CaptureScaleLate    float64 `json:"capture_scale_late"`
+	CaptureScaleFinal   float64 `json:"capture_scale_final"`
+	DefensiveCapture    float64 `json:"defensive_capture"`
 	CaptureInTwoLimit   int     `json:"capture_in_two_limit"`
 }
 
@@ -105,8 +163,13 @@ type ConfigStore struct {
 func DefaultConfig() Config {
 	return Config{
 		GhostMode:      false,
+		TeachingMode:   false,
+		Commentary:     true,
 		LogDepthScores: false,
 
+		// The side that can win by capture after a move does so at once.
+		AutoPlayCaptureWin: true,
+
 		// Time budget mode
 		AiTimeBudgetMs:       500,
 		AiBacklogEstimateMs:  120000,
@@ -115,6 +178,9 @@ func DefaultConfig() Config {
 		AiMinDepth:           3,
 		AiMaxDepth:           10,
 		AiReturnLastComplete: true,
+		// A best move that changes between depths may think this much longer
+		// than the budget, once.
+		AiUnstableExtendPct: 50,
 
 		// Branching control
 		AiEnableDynamicTopK: true,
@@ -156,9 +222,11 @@ func DefaultConfig() Config {
 		AiQuickWinExit:  true,
 
 		// Aspiration ON (small window -> fewer nodes, usually faster)
-		// If it causes too many re-searches, increase window (not disable immediately).
+		// The window starts at AiAspWindow, then follows how much the best
+		// score moves between depths, within AiAspWindowMin..AiAspWindowMax.
 		AiEnableAspiration: true,
 		AiAspWindow:        1200.0,
+		AiAspWindowMin:     100.0,
 		AiAspWindowMax:     2000000000.0,
 
 		// Caches
@@ -173,11 +241,25 @@ func DefaultConfig() Config {
 		AiLostModeReplyLimit: 12,
 		AiLostModeMinDepth:   2,
 
+		// Self-play exploration
+		AiSelfPlayTemp:     0,
+		AiSelfPlayHalfLife: 20,
+
 		// Queue
+		AiSearchWorkers:       0,
 		AiQueueWorkers:        1,
 		AiQueueAnalyzeThreads: 0,
 		AiQueueEnabled:        true,
+		AiWarmupPlies:         3,
+		AiPrefetchReplies:     4,
 		AiAnaliticsTopBoards:  7,
+		AiBookPlies:           4,
+		AiBookMinDepth:        8,
+		AiQueueCompactMs:      30000,
+		AiQueueSchedule:       "",
+		AiQueueDutyCycle:      1,
+		AiQueueSleepRatio:     0,
+		AiQueueMaxProcs:       0,
 
 		// TT: slightly larger than 1<<18 helps a lot once you deepen regularly
 		AiTtUseSetAssoc:       true,
@@ -188,6 +270,17 @@ func DefaultConfig() Config {
 		AiTtMaxMemoryBytes:    5 * 1024 * 1024 * 1024, // 5 GB
 		AiEnableTtPersistence: true,
 		AiTtPersistencePath:   "tt_cache.gob",
+		AiFrequencyPath:       "position_frequency.gob",
+		AiGameDatabasePath:    "game_database.gob",
+		AiEventLogPath:        "event_log.jsonl",
+		UsersPath:             "users.gob",
+		CorrespondencePath:    "correspondence.gob",
+		CalibrationPath:       "calibration.gob",
+		SessionsPath:          "sessions.gob",
+		HumanMovesPath:        "human_moves.gob",
+		HeuristicRegistryPath: "heuristic_registry.gob",
+		GlobalStatsPath:       "global_stats.gob",
+		ColorAuditPath:        "color_audit.gob",
 		AiEnableRootTranspose: true,
 		AiRootTransposeSize:   1 << 16, // 65536
 
@@ -199,10 +292,47 @@ func DefaultConfig() Config {
 		AiKillerBoost:  6000,
 		AiHistoryBoost: 16,
 
+		// Late move reductions for quiet nodes
+		AiLmrLateMoveStart: lmrLateMoveStart,
+		AiLmrMinDepth:      lmrMinDepth,
+		AiLmrReduction:     lmrReduction,
+
+		// Internal iterative deepening where the TT has no move to try first
+		AiEnableIID:    true,
+		AiIIDMinDepth:  iidMinDepth,
+		AiIIDReduction: iidReduction,
+
+		// Prove forks in the first plies of the search
+		AiForkPlies: 2,
+
+		// Game phases; the capture weights are the same in every phase
+		AiPhaseOpeningStones:  8,
+		AiPhaseEndgameStones:  60,
+		AiPhaseCaptures:       6,
+		AiPhaseThreatDensity:  0.15,
+		AiOpeningCaptureScale: 1,
+		AiEndgameCaptureScale: 1,
+
 		// Background pondering off for latency
 		AiPonderingEnabled: false,
 
+		// Engine moves against a human show after half a second to a second
+		AiMinMoveMs: 500,
+		AiMaxMoveMs: 1000,
+
+		// Warn a human who has not moved for two minutes
+		IdleWarningMs: 120000,
+
+		// Hold a disconnected match player's seat for a minute, clock stopped
+		MatchGraceMs:    60000,
+		MatchPauseClock: true,
+
 		AiGhostThrottleMs:  50,
+		AiScoreThrottleMs:  250,
+		AiSuggestDepth:     10,
+		AiSuggestBudgetMs:  0,
+		AiSuggestPerMinute: 60,
+		AiSuggestYield:     true,
 		AiLogSearchStats:   false,
 		AiMinmaxCacheLimit: 1000,
 
@@ -226,7 +356,11 @@ func DefaultConfig() Config {
 			CaptureNearWin:      12000.0,
 			CaptureInTwo:        700.0,
 			HangingPair:         2400.0,
-			CaptureWinSoonScale: 0.95,
+			CaptureScaleEarly:   1,
+			CaptureScaleMid:     1.25,
+			CaptureScaleLate:    2,
+			CaptureScaleFinal:   0.95,
+			DefensiveCapture:    0.5,
 			CaptureInTwoLimit:   8,
 		},
 	}
@@ -249,3 +383,19 @@ func (c *ConfigStore) Update(newConfig Config) {
 	c.config = newConfig
 	c.mu.Unlock()
 }
+
+// modify applies change to the config under the store's lock, so it does
+// not race with other updates, and returns the config before and after.
+func (c *ConfigStore) modify(change func(*Config)) (Config, Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.config
+	change(&c.config)
+	return previous, c.config
+}
+
+// UpdateConfig sets the config through the API: its values override the
+// config file's.
+func UpdateConfig(newConfig Config) {
+	configFile.update(newConfig)
+}
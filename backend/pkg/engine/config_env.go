@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigEnvPrefix starts the environment variables that set config
+// values: GOMOKU_AI_DEPTH for ai_depth, GOMOKU_HEURISTICS_OPEN_4 for
+// heuristics.open_4.
+const ConfigEnvPrefix = "GOMOKU_"
+
+// configEnvBinding ties a config key to its environment variable.
+type configEnvBinding struct {
+	key  string
+	kind reflect.Kind
+}
+
+var configEnvBindings = bindConfigEnv(reflect.TypeOf(Config{}), "")
+
+// bindConfigEnv names the variables of every scalar field of t from its
+// JSON tag, nested structs under their own tag.
+func bindConfigEnv(t reflect.Type, prefix string) map[string]configEnvBinding {
+	bindings := make(map[string]configEnvBinding)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			for env, binding := range bindConfigEnv(field.Type, prefix+name+".") {
+				bindings[env] = binding
+			}
+			continue
+		}
+		bindings[configEnvName(prefix+name)] = configEnvBinding{key: prefix + name, kind: field.Type.Kind()}
+	}
+	return bindings
+}
+
+func configEnvName(key string) string {
+	return ConfigEnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// parseConfigEnv turns the GOMOKU_ variables of environ ("NAME=value")
+// into config values. Unknown names and values that do not parse as their
+// field's type are returned as errors and skipped.
+func parseConfigEnv(environ []string) (map[string]json.RawMessage, []error) {
+	values := make(map[string]json.RawMessage)
+	var errs []error
+	for _, entry := range environ {
+		name, raw, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, ConfigEnvPrefix) {
+			continue
+		}
+		binding, ok := configEnvBindings[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: no such config value", name))
+			continue
+		}
+		value, err := configEnvValue(binding.kind, strings.TrimSpace(raw))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		values[binding.key] = value
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return values, errs
+}
+
+func configEnvValue(kind reflect.Kind, raw string) (json.RawMessage, error) {
+	var value any
+	var err error
+	switch kind {
+	case reflect.Bool:
+		value, err = strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err = strconv.ParseUint(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		value, err = strconv.ParseFloat(raw, 64)
+	case reflect.String:
+		value = raw
+	default:
+		return nil, fmt.Errorf("%s values cannot be set from the environment", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", kind, raw)
+	}
+	return json.Marshal(value)
+}
+
+// loadEnv makes the GOMOKU_ variables of environ the environment layer.
+func (l *configLayers) loadEnv(environ []string) []error {
+	values, errs := parseConfigEnv(environ)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next, err := buildConfig(values, l.file, l.persisted, l.api)
+	if err == nil {
+		_, err = ParseSchedule(next.AiQueueSchedule)
+	}
+	if err != nil {
+		return append(errs, fmt.Errorf("environment not applied: %v", err))
+	}
+	l.env = values
+	l.store.Update(next)
+	return errs
+}
+
+// LoadConfigEnv applies the GOMOKU_ variables of environ over the defaults,
+// under the config file and the API. It returns the variables it skipped.
+func LoadConfigEnv(environ []string) []error {
+	return configFile.loadEnv(environ)
+}
@@ -0,0 +1,466 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where an effective config value came from, from the lowest precedence.
+const (
+	ConfigSourceDefault   = "default"
+	ConfigSourceEnv       = "env"
+	ConfigSourceFile      = "file"
+	ConfigSourcePersisted = "persisted"
+	ConfigSourceAPI       = "api"
+)
+
+// ConfigWatchInterval is how often WatchConfigFile checks the file for a
+// change.
+const ConfigWatchInterval = time.Second
+
+// ConfigValueSource is one effective config value, keyed by its JSON name
+// (nested heuristics as "heuristics.open_4"), with the environment variable
+// that sets it. EnvValue and FileValue are the environment's and the file's
+// values when a later layer overrides them.
+type ConfigValueSource struct {
+	Key       string          `json:"key"`
+	Env       string          `json:"env"`
+	Value     json.RawMessage `json:"value"`
+	Source    string          `json:"source"`
+	EnvValue  json.RawMessage `json:"env_value,omitempty"`
+	FileValue json.RawMessage `json:"file_value,omitempty"`
+}
+
+// ConfigSourceReport is where the effective config comes from: the file it
+// was loaded from, if any, and the source of every value.
+type ConfigSourceReport struct {
+	File           string              `json:"file,omitempty"`
+	FileLoadedAtMs int64               `json:"file_loaded_at_ms,omitempty"`
+	FileError      string              `json:"file_error,omitempty"`
+	EnvKeys        int                 `json:"env_keys"`
+	FileKeys       int                 `json:"file_keys"`
+	PersistedKeys  int                 `json:"persisted_keys"`
+	APIKeys        int                 `json:"api_keys"`
+	Values         []ConfigValueSource `json:"values"`
+}
+
+// configLayers builds the effective config from the defaults, the GOMOKU_
+// environment variables, the config file, the state restored from the
+// persisted caches and what the API set, each overriding the ones before.
+// The file and the persisted state are one tier: a restored value holds
+// until the file changes that key. The persisted and API layers hold the
+// values that differ from the layers below, so a file change never undoes
+// an API change.
+type configLayers struct {
+	mu        sync.Mutex
+	store     *ConfigStore
+	path      string
+	modTime   time.Time
+	loadedAt  time.Time
+	err       error
+	env       map[string]json.RawMessage
+	file      map[string]json.RawMessage
+	persisted map[string]json.RawMessage
+	api       map[string]json.RawMessage
+}
+
+var configFile = &configLayers{store: configStore}
+
+// flattenConfig returns the JSON values of config by key, nested objects
+// under dotted keys.
+func flattenConfig(config Config) map[string]json.RawMessage {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil
+	}
+	flat := make(map[string]json.RawMessage)
+	flattenConfigTree("", tree, flat)
+	return flat
+}
+
+func flattenConfigTree(prefix string, tree map[string]any, flat map[string]json.RawMessage) {
+	for key, value := range tree {
+		if nested, ok := value.(map[string]any); ok {
+			flattenConfigTree(prefix+key+".", nested, flat)
+			continue
+		}
+		raw, _ := json.Marshal(value)
+		flat[prefix+key] = raw
+	}
+}
+
+// buildConfig decodes flat values onto the defaults; unknown keys and
+// values of the wrong type are errors.
+func buildConfig(layers ...map[string]json.RawMessage) (Config, error) {
+	tree := map[string]any{}
+	for _, layer := range layers {
+		for key, raw := range layer {
+			node := tree
+			parts := strings.Split(key, ".")
+			for _, part := range parts[:len(parts)-1] {
+				child, ok := node[part].(map[string]any)
+				if !ok {
+					child = map[string]any{}
+					node[part] = child
+				}
+				node = child
+			}
+			node[parts[len(parts)-1]] = raw
+		}
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return Config{}, err
+	}
+	config := DefaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// parseConfigFile reads a JSON document, or YAML for .yaml and .yml files,
+// into flat values.
+func parseConfigFile(path string, data []byte) (map[string]json.RawMessage, error) {
+	var tree map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parsed, err := parseYAMLMapping(data)
+		if err != nil {
+			return nil, err
+		}
+		tree = parsed
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, err
+		}
+	}
+	flat := make(map[string]json.RawMessage)
+	flattenConfigTree("", tree, flat)
+	config, err := buildConfig(flat)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseSchedule(config.AiQueueSchedule); err != nil {
+		return nil, err
+	}
+	return flat, nil
+}
+
+// configDiff returns the values of next that differ from the config the
+// layers build.
+func configDiff(next Config, layers ...map[string]json.RawMessage) map[string]json.RawMessage {
+	base, err := buildConfig(layers...)
+	if err != nil {
+		base = DefaultConfig()
+	}
+	baseValues := flattenConfig(base)
+	diff := make(map[string]json.RawMessage)
+	for key, value := range flattenConfig(next) {
+		if !bytes.Equal(baseValues[key], value) {
+			diff[key] = value
+		}
+	}
+	return diff
+}
+
+// noteAPI makes next the effective config, keeping in the API layer what
+// differs from the layers below. Callers hold l.mu.
+func (l *configLayers) noteAPI(next Config) {
+	l.api = configDiff(next, l.env, l.file, l.persisted)
+}
+
+func (l *configLayers) update(next Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store.Update(next)
+	l.noteAPI(next)
+}
+
+func (l *configLayers) modify(change func(*Config)) (Config, Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous, next := l.store.modify(change)
+	l.noteAPI(next)
+	return previous, next
+}
+
+// restore applies change, made to restore persisted state, in the persisted
+// layer: under the API layer, over the file and the environment. It returns
+// the effective configs before and after.
+func (l *configLayers) restore(change func(*Config)) (Config, Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous := l.store.Get()
+	restored, err := buildConfig(l.env, l.file, l.persisted)
+	if err != nil {
+		return previous, previous
+	}
+	change(&restored)
+	persisted := configDiff(restored, l.env, l.file)
+	next, err := buildConfig(l.env, l.file, persisted, l.api)
+	if err != nil {
+		return previous, previous
+	}
+	l.persisted = persisted
+	l.store.Update(next)
+	return previous, next
+}
+
+// load reads the file when it changed since the last load, or always with
+// force, and applies it over the environment. Restored values of keys the
+// new file changes are dropped; the API layer stays over it. It reports whether the
+// effective config changed, with the configs before and after. A file that
+// fails to parse is reported and leaves the config alone.
+func (l *configLayers) load(force bool) (bool, Config, Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous := l.store.Get()
+	info, err := os.Stat(l.path)
+	if err != nil {
+		l.err = err
+		return false, previous, previous, err
+	}
+	if !force && info.ModTime().Equal(l.modTime) {
+		return false, previous, previous, nil
+	}
+	l.modTime = info.ModTime()
+	data, err := os.ReadFile(l.path)
+	var file map[string]json.RawMessage
+	if err == nil {
+		file, err = parseConfigFile(l.path, data)
+	}
+	l.err = err
+	if err != nil {
+		return false, previous, previous, err
+	}
+	persisted := make(map[string]json.RawMessage)
+	for key, value := range l.persisted {
+		if bytes.Equal(file[key], l.file[key]) {
+			persisted[key] = value
+		}
+	}
+	next, err := buildConfig(l.env, file, persisted, l.api)
+	if err != nil {
+		l.err = err
+		return false, previous, previous, err
+	}
+	l.file = file
+	l.persisted = persisted
+	l.loadedAt = time.Now()
+	l.store.Update(next)
+	return !bytes.Equal(mustJSON(previous), mustJSON(next)), previous, next, nil
+}
+
+func mustJSON(config Config) []byte {
+	data, _ := json.Marshal(config)
+	return data
+}
+
+func (l *configLayers) report() ConfigSourceReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report := ConfigSourceReport{File: l.path, EnvKeys: len(l.env), FileKeys: len(l.file), PersistedKeys: len(l.persisted), APIKeys: len(l.api)}
+	if !l.loadedAt.IsZero() {
+		report.FileLoadedAtMs = l.loadedAt.UnixMilli()
+	}
+	if l.err != nil {
+		report.FileError = l.err.Error()
+	}
+	for key, value := range flattenConfig(l.store.Get()) {
+		source := ConfigValueSource{Key: key, Env: configEnvName(key), Value: value, Source: ConfigSourceDefault}
+		if _, ok := l.env[key]; ok {
+			source.Source = ConfigSourceEnv
+		}
+		if _, ok := l.file[key]; ok {
+			source.Source = ConfigSourceFile
+			source.EnvValue = l.env[key]
+		}
+		if _, ok := l.persisted[key]; ok {
+			source.Source = ConfigSourcePersisted
+			source.EnvValue, source.FileValue = l.env[key], l.file[key]
+		}
+		if _, ok := l.api[key]; ok {
+			source.Source = ConfigSourceAPI
+			source.EnvValue, source.FileValue = l.env[key], l.file[key]
+		}
+		report.Values = append(report.Values, source)
+	}
+	sort.Slice(report.Values, func(i, j int) bool { return report.Values[i].Key < report.Values[j].Key })
+	return report
+}
+
+// LoadConfigFile makes path the config file and applies it over the
+// environment variables. Values the API sets later override the file's.
+func LoadConfigFile(path string) error {
+	configFile.mu.Lock()
+	configFile.path = path
+	configFile.mu.Unlock()
+	_, _, _, err := configFile.load(true)
+	return err
+}
+
+// WatchConfigFile applies the config file again whenever it changes, until
+// ctx is done, calling onChange when the effective config changed. It does
+// nothing without a config file.
+func WatchConfigFile(ctx context.Context, onChange func(previous, next Config)) {
+	configFile.mu.Lock()
+	path := configFile.path
+	configFile.mu.Unlock()
+	if path == "" {
+		return
+	}
+	ticker := time.NewTicker(ConfigWatchInterval)
+	defer ticker.Stop()
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, previous, next, err := configFile.load(false)
+		if err != nil {
+			if err.Error() != lastErr {
+				log.Printf("[backend] config file %s not applied: %v", path, err)
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+		if changed {
+			log.Printf("[backend] config file %s reloaded", path)
+			if onChange != nil {
+				onChange(previous, next)
+			}
+		}
+	}
+}
+
+// ConfigSources reports where each effective config value came from.
+func ConfigSources() ConfigSourceReport {
+	return configFile.report()
+}
+
+// parseYAMLMapping reads the YAML a config file needs: nested mappings of
+// scalars by indentation, with comments. Sequences, anchors and multi-line
+// scalars are not supported.
+func parseYAMLMapping(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	type level struct {
+		indent  int
+		mapping map[string]any
+	}
+	stack := []level{{indent: -1, mapping: root}}
+	pendingKey := ""
+	for number, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(line), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			return nil, fmt.Errorf("line %d: sequences are not supported", number+1)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		if pendingKey != "" {
+			parent := stack[len(stack)-1]
+			child := map[string]any{}
+			if indent > parent.indent {
+				parent.mapping[pendingKey] = child
+				stack = append(stack, level{indent: indent, mapping: child})
+			} else {
+				parent.mapping[pendingKey] = nil
+			}
+			pendingKey = ""
+		}
+		for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		if indent != stack[len(stack)-1].indent && len(stack) > 1 {
+			return nil, fmt.Errorf("line %d: unexpected indentation", number+1)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", number+1)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			pendingKey = key
+			continue
+		}
+		scalar, err := parseYAMLScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", number+1, err)
+		}
+		stack[len(stack)-1].mapping[key] = scalar
+	}
+	if pendingKey != "" {
+		stack[len(stack)-1].mapping[pendingKey] = nil
+	}
+	return root, nil
+}
+
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseYAMLScalar(value string) (any, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		var text string
+		if err := json.Unmarshal([]byte(value), &text); err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", value)
+		}
+		return text, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	switch strings.ToLower(value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	var number json.Number
+	if err := json.Unmarshal([]byte(value), &number); err == nil {
+		return number, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigFileLayersUnderTheAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Make every write visible to the modification time check.
+		stamp := time.Now().Add(time.Duration(len(content)) * time.Second)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# tuned for the demo box\nai_depth: 6\nai_queue_schedule: \"22:00-06:00\"\nheuristics:\n  open_4: 123 # stronger\n")
+	layers := &configLayers{store: &ConfigStore{config: DefaultConfig()}, path: path}
+	if _, _, _, err := layers.load(true); err != nil {
+		t.Fatalf("expected the file to load, got %v", err)
+	}
+	config := layers.store.Get()
+	if config.AiDepth != 6 || config.AiQueueSchedule != "22:00-06:00" || config.Heuristics.Open4 != 123 || config.AiTimeoutMs != DefaultConfig().AiTimeoutMs {
+		t.Fatalf("expected the file over the defaults, got depth %d, schedule %q, open_4 %v", config.AiDepth, config.AiQueueSchedule, config.Heuristics.Open4)
+	}
+
+	config.AiDepth = 8
+	layers.update(config)
+	write("ai_depth: 5\nheuristics:\n  open_4: 150\n")
+	changed, _, next, err := layers.load(false)
+	if err != nil || !changed {
+		t.Fatalf("expected the changed file to reload, got %v", err)
+	}
+	if next.AiDepth != 8 || next.Heuristics.Open4 != 150 || next.AiQueueSchedule != "" {
+		t.Fatalf("expected the API's depth to win over the file, got depth %d, open_4 %v, schedule %q", next.AiDepth, next.Heuristics.Open4, next.AiQueueSchedule)
+	}
+	sources := map[string]ConfigValueSource{}
+	for _, value := range layers.report().Values {
+		sources[value.Key] = value
+	}
+	if sources["ai_depth"].Source != ConfigSourceAPI || string(sources["ai_depth"].FileValue) != "5" || sources["heuristics.open_4"].Source != ConfigSourceFile || sources["ai_timeout_ms"].Source != ConfigSourceDefault {
+		t.Fatalf("unexpected sources %+v %+v %+v", sources["ai_depth"], sources["heuristics.open_4"], sources["ai_timeout_ms"])
+	}
+
+	write("ai_depth: deep\n")
+	if _, _, _, err := layers.load(false); err == nil || layers.report().FileError == "" {
+		t.Fatalf("expected a bad value to be reported")
+	}
+	write("ai_dpeth: 5\n")
+	if _, _, _, err := layers.load(false); err == nil {
+		t.Fatalf("expected an unknown key to be refused")
+	}
+	if config := layers.store.Get(); config.AiDepth != 8 || config.Heuristics.Open4 != 150 {
+		t.Fatalf("expected a refused file to leave the config alone, got depth %d", config.AiDepth)
+	}
+}
+
+func TestConfigFileOverridesTheEnv(t *testing.T) {
+	if _, ok := configEnvBindings["GOMOKU_HEURISTICS_OPEN_4"]; !ok {
+		t.Fatalf("expected nested heuristics to be bound")
+	}
+	layers := &configLayers{store: &ConfigStore{config: DefaultConfig()}, file: map[string]json.RawMessage{"ai_depth": json.RawMessage("6")}}
+	errs := layers.loadEnv([]string{"PATH=/bin", "GOMOKU_AI_DEPTH=7", "GOMOKU_HEURISTICS_OPEN_4=99.5", "GOMOKU_AI_DPETH=3", "GOMOKU_AI_TIMEOUT_MS=soon"})
+	if len(errs) != 2 {
+		t.Fatalf("expected the unknown and the bad variable to be skipped, got %v", errs)
+	}
+	config := layers.store.Get()
+	if config.AiDepth != 6 || config.Heuristics.Open4 != 99.5 || config.AiTimeoutMs != DefaultConfig().AiTimeoutMs {
+		t.Fatalf("expected the file over the environment, got depth %d, open_4 %v", config.AiDepth, config.Heuristics.Open4)
+	}
+
+	config.AiDepth = 9
+	layers.update(config)
+	sources := map[string]ConfigValueSource{}
+	for _, value := range layers.report().Values {
+		sources[value.Key] = value
+	}
+	if depth := sources["ai_depth"]; depth.Source != ConfigSourceAPI || string(depth.EnvValue) != "7" || string(depth.FileValue) != "6" || depth.Env != "GOMOKU_AI_DEPTH" {
+		t.Fatalf("unexpected ai_depth source %+v", depth)
+	}
+	if sources["heuristics.open_4"].Source != ConfigSourceEnv {
+		t.Fatalf("unexpected open_4 source %+v", sources["heuristics.open_4"])
+	}
+}
+
+func TestConfigLayerPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string, stamp time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layers := &configLayers{store: &ConfigStore{config: DefaultConfig()}, path: path}
+	winner := func(want int, source string) {
+		t.Helper()
+		if depth := layers.store.Get().AiDepth; depth != want {
+			t.Fatalf("expected ai_depth %d from %s, got %d", want, source, depth)
+		}
+		for _, value := range layers.report().Values {
+			if value.Key == "ai_depth" && value.Source != source {
+				t.Fatalf("expected ai_depth from %s, got %+v", source, value)
+			}
+		}
+	}
+	winner(DefaultConfig().AiDepth, ConfigSourceDefault)
+	if errs := layers.loadEnv([]string{"GOMOKU_AI_DEPTH=3"}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	winner(3, ConfigSourceEnv)
+	write(`{"ai_depth": 4}`, time.Now())
+	if _, _, _, err := layers.load(true); err != nil {
+		t.Fatal(err)
+	}
+	winner(4, ConfigSourceFile)
+	layers.restore(func(config *Config) { config.AiDepth = 5 })
+	winner(5, ConfigSourcePersisted)
+	_, config := layers.modify(func(config *Config) { config.AiDepth = 6 })
+	winner(6, ConfigSourceAPI)
+
+	// The API keeps winning over a file change, which replaces the restored
+	// value of the same tier.
+	write(`{"ai_depth": 7}`, time.Now().Add(time.Hour))
+	if _, _, _, err := layers.load(false); err != nil {
+		t.Fatal(err)
+	}
+	winner(6, ConfigSourceAPI)
+	config.AiDepth = 7
+	layers.update(config)
+	winner(7, ConfigSourceFile)
+	if errs := layers.loadEnv([]string{"GOMOKU_AI_DEPTH=2"}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	winner(7, ConfigSourceFile)
+}
@@ -0,0 +1,76 @@
+package engine
+
+func BoardToSlice(board Board) [][]int {
+	size := board.Size()
+	rows := make([][]int, size)
+	for y := 0; y < size; y++ {
+		rows[y] = make([]int, size)
+		for x := 0; x < size; x++ {
+			cell := board.At(x, y)
+			rows[y][x] = CellToInt(cell)
+		}
+	}
+	return rows
+}
+
+func CellToInt(cell Cell) int {
+	switch cell {
+	case CellBlack:
+		return 1
+	case CellWhite:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func IntToCell(value int) Cell {
+	switch value {
+	case 1:
+		return CellBlack
+	case 2:
+		return CellWhite
+	default:
+		return CellEmpty
+	}
+}
+
+func PlayerToInt(player PlayerColor) int {
+	if player == PlayerBlack {
+		return 1
+	}
+	return 2
+}
+
+func IntToPlayer(value int) PlayerColor {
+	if value == 2 {
+		return PlayerWhite
+	}
+	return PlayerBlack
+}
+
+func WinnerFromStatus(status GameStatus) int {
+	switch status {
+	case StatusBlackWon:
+		return 1
+	case StatusWhiteWon:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func StatusToString(status GameStatus) string {
+	switch status {
+	case StatusNotStarted:
+		return "not_started"
+	case StatusBlackWon:
+		return "black_won"
+	case StatusWhiteWon:
+		return "white_won"
+	case StatusDraw:
+		return "draw"
+	default:
+		return "running"
+	}
+}
@@ -0,0 +1,404 @@
+package engine
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxCorrespondenceGames = 500
+
+// CorrespondencePlayer is one side of a correspondence game. The token is
+// the player's secret for moving; the webhook and e-mail address are told
+// when it is the player's turn. None of them are shown by the API.
+type CorrespondencePlayer struct {
+	Name    string `json:"name"`
+	Webhook string `json:"-"`
+	Email   string `json:"-"`
+	Token   string `json:"-"`
+}
+
+// CorrespondenceGame is a human vs human game without a tick loop or turn
+// clock: it only changes when a player moves or resigns, and is saved to
+// disk every time it does.
+type CorrespondenceGame struct {
+	ID          string               `json:"id"`
+	Black       CorrespondencePlayer `json:"black"`
+	White       CorrespondencePlayer `json:"white"`
+	Rules       UserPreferences      `json:"rules"`
+	Moves       []Move               `json:"moves"`
+	CreatedAtMs int64                `json:"created_at_ms"`
+	UpdatedAtMs int64                `json:"updated_at_ms"`
+	Status      string               `json:"status"`
+	Winner      int                  `json:"winner"`
+	NextPlayer  int                  `json:"next_player"`
+	Resigned    int                  `json:"resigned,omitempty"`
+}
+
+// CorrespondenceAnalysis is what the background analysis knows about the
+// current position, from the side to move.
+type CorrespondenceAnalysis struct {
+	Depth    int     `json:"depth"`
+	Score    float64 `json:"score"`
+	BestMove *Move   `json:"best_move,omitempty"`
+}
+
+type CorrespondenceView struct {
+	CorrespondenceGame
+	Board    [][]int                 `json:"board"`
+	Analysis *CorrespondenceAnalysis `json:"analysis,omitempty"`
+}
+
+type CorrespondenceStore struct {
+	mu        sync.Mutex
+	persistMu sync.Mutex
+	games     map[string]*CorrespondenceGame
+}
+
+type correspondenceSnapshot struct {
+	Games []CorrespondenceGame
+}
+
+var CorrespondenceGames = NewCorrespondenceStore()
+
+func NewCorrespondenceStore() *CorrespondenceStore {
+	return &CorrespondenceStore{games: make(map[string]*CorrespondenceGame)}
+}
+
+func (p CorrespondencePlayer) validate(side string) error {
+	if name := strings.TrimSpace(p.Name); name == "" || len(name) > 32 {
+		return fieldErrorf(side+".name", "%s name must be 1 to 32 characters", side)
+	}
+	if p.Webhook != "" && !validNotifyWebhook(p.Webhook) {
+		return fieldErrorf(side+".webhook", "%s webhook must be an http(s) URL", side)
+	}
+	if p.Email != "" && !validNotifyEmail(p.Email) {
+		return fieldErrorf(side+".email", "%s email is not a valid address", side)
+	}
+	return nil
+}
+
+func (g *CorrespondenceGame) player(color int) *CorrespondencePlayer {
+	if color == 2 {
+		return &g.White
+	}
+	return &g.Black
+}
+
+func (g *CorrespondenceGame) colorForToken(token string) int {
+	switch {
+	case token == "":
+		return 0
+	case token == g.Black.Token:
+		return 1
+	case token == g.White.Token:
+		return 2
+	}
+	return 0
+}
+
+// state replays the game's moves under its rules.
+func (g *CorrespondenceGame) state() (GameState, Rules, error) {
+	return replayMoves(matchSettings(g.Rules), g.Moves)
+}
+
+func (g *CorrespondenceGame) update(state GameState) {
+	g.UpdatedAtMs = time.Now().UnixMilli()
+	g.Status = StatusToString(state.Status)
+	g.Winner = WinnerFromStatus(state.Status)
+	g.NextPlayer = PlayerToInt(state.ToMove)
+}
+
+// Create starts a game and returns it with the black and white tokens.
+func (s *CorrespondenceStore) Create(black, white CorrespondencePlayer, rules UserPreferences) (CorrespondenceGame, error) {
+	if err := black.validate("black"); err != nil {
+		return CorrespondenceGame{}, err
+	}
+	if err := white.validate("white"); err != nil {
+		return CorrespondenceGame{}, err
+	}
+	if err := rules.Validate(); err != nil {
+		return CorrespondenceGame{}, err
+	}
+	rules.Color = 0
+	black.Name, white.Name = strings.TrimSpace(black.Name), strings.TrimSpace(white.Name)
+	black.Token, white.Token = newMatchID(), newMatchID()
+	now := time.Now().UnixMilli()
+	game := &CorrespondenceGame{ID: newMatchID(), Black: black, White: white, Rules: rules, Moves: []Move{}, CreatedAtMs: now}
+	state, _, err := game.state()
+	if err != nil {
+		return CorrespondenceGame{}, err
+	}
+	game.update(state)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.games) >= maxCorrespondenceGames && !s.evictFinishedLocked() {
+		return CorrespondenceGame{}, fmt.Errorf("at most %d correspondence games", maxCorrespondenceGames)
+	}
+	s.games[game.ID] = game
+	notifyCorrespondenceTurn(*game)
+	return copyCorrespondenceGame(game), nil
+}
+
+// evictFinishedLocked drops the finished game that was updated last the
+// longest time ago.
+func (s *CorrespondenceStore) evictFinishedLocked() bool {
+	var oldest *CorrespondenceGame
+	for _, game := range s.games {
+		if game.Status != "running" && (oldest == nil || game.UpdatedAtMs < oldest.UpdatedAtMs) {
+			oldest = game
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(s.games, oldest.ID)
+	return true
+}
+
+func (s *CorrespondenceStore) Get(id string) (CorrespondenceGame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, ok := s.games[id]
+	if !ok {
+		return CorrespondenceGame{}, false
+	}
+	return copyCorrespondenceGame(game), true
+}
+
+// List returns the games, most recently updated first.
+func (s *CorrespondenceStore) List() []CorrespondenceGame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	games := make([]CorrespondenceGame, 0, len(s.games))
+	for _, game := range s.games {
+		games = append(games, copyCorrespondenceGame(game))
+	}
+	sort.Slice(games, func(i, j int) bool {
+		if games[i].UpdatedAtMs != games[j].UpdatedAtMs {
+			return games[i].UpdatedAtMs > games[j].UpdatedAtMs
+		}
+		return games[i].ID < games[j].ID
+	})
+	return games
+}
+
+// Play applies move for the player holding token. The new position goes to
+// the front of the analysis backlog and the opponent is notified.
+func (s *CorrespondenceStore) Play(id, token string, move Move) (CorrespondenceGame, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, ok := s.games[id]
+	if !ok {
+		return CorrespondenceGame{}, false, nil
+	}
+	color := game.colorForToken(token)
+	if color == 0 {
+		return CorrespondenceGame{}, true, ErrInvalidToken
+	}
+	state, rules, err := game.state()
+	if err != nil {
+		return CorrespondenceGame{}, true, err
+	}
+	if game.Status != "running" {
+		return CorrespondenceGame{}, true, ErrGameOver
+	}
+	if PlayerToInt(state.ToMove) != color {
+		return CorrespondenceGame{}, true, ErrNotYourTurn
+	}
+	if !move.IsValid(state.Board.Size()) {
+		return CorrespondenceGame{}, true, fmt.Errorf("%w: out of bounds", ErrIllegalMove)
+	}
+	if legal, reason := rules.IsLegal(state, move, state.ToMove); !legal {
+		return CorrespondenceGame{}, true, fmt.Errorf("%w: %s", ErrIllegalMove, reason)
+	}
+	applyMove(&state, rules, move, state.ToMove)
+	game.Moves = append(game.Moves, move)
+	game.update(state)
+	if state.Status == StatusRunning {
+		enqueueSearchBacklogTask(state, rules, true)
+		notifyCorrespondenceTurn(*game)
+	}
+	return copyCorrespondenceGame(game), true, nil
+}
+
+// Resign ends the game in the opponent's favour.
+func (s *CorrespondenceStore) Resign(id, token string) (CorrespondenceGame, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	game, ok := s.games[id]
+	if !ok {
+		return CorrespondenceGame{}, false, nil
+	}
+	color := game.colorForToken(token)
+	if color == 0 {
+		return CorrespondenceGame{}, true, ErrInvalidToken
+	}
+	if game.Status != "running" {
+		return CorrespondenceGame{}, true, ErrGameOver
+	}
+	game.Resigned = color
+	game.Winner = 3 - color
+	game.Status = StatusToString(StatusBlackWon)
+	if game.Winner == 2 {
+		game.Status = StatusToString(StatusWhiteWon)
+	}
+	game.UpdatedAtMs = time.Now().UnixMilli()
+	return copyCorrespondenceGame(game), true, nil
+}
+
+func notifyCorrespondenceTurn(game CorrespondenceGame) {
+	player := game.player(game.NextPlayer)
+	if player.Webhook == "" && player.Email == "" {
+		return
+	}
+	color := "black"
+	if game.NextPlayer == 2 {
+		color = "white"
+	}
+	notifyRecipient(player.Webhook, player.Email, NotifyCorrespondenceTurn, map[string]string{
+		"game":   game.ID,
+		"player": player.Name,
+		"color":  color,
+		"moves":  strconv.Itoa(len(game.Moves)),
+	})
+}
+
+// ViewCorrespondence adds the board and, when tt knows the position, the
+// analysis of the side to move.
+func ViewCorrespondence(game CorrespondenceGame, tt *TranspositionTable) (CorrespondenceView, error) {
+	state, rules, err := game.state()
+	if err != nil {
+		return CorrespondenceView{}, err
+	}
+	view := CorrespondenceView{CorrespondenceGame: game, Board: BoardToSlice(state.Board)}
+	if tt == nil || game.Status != "running" {
+		return view, nil
+	}
+	size := state.Board.Size()
+	entry, ok := tt.Peek(ttKeyFor(state, size), heuristicHashFromConfig(GetConfig()))
+	if !ok {
+		return view, nil
+	}
+	analysis := &CorrespondenceAnalysis{Depth: entry.Depth, Score: entry.ScoreFloat()}
+	if entry.BestMove.IsValid(size) {
+		best := Move{X: entry.BestMove.X, Y: entry.BestMove.Y}
+		if legal, _ := rules.IsLegal(state, best, state.ToMove); legal {
+			analysis.BestMove = &best
+		}
+	}
+	view.Analysis = analysis
+	return view, nil
+}
+
+func copyCorrespondenceGame(game *CorrespondenceGame) CorrespondenceGame {
+	copied := *game
+	copied.Moves = append([]Move{}, game.Moves...)
+	return copied
+}
+
+func (s *CorrespondenceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.games)
+}
+
+// Running counts the games still being played.
+func (s *CorrespondenceStore) Running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running := 0
+	for _, game := range s.games {
+		if game.Status == "running" {
+			running++
+		}
+	}
+	return running
+}
+
+func (s *CorrespondenceStore) snapshot() correspondenceSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := correspondenceSnapshot{Games: make([]CorrespondenceGame, 0, len(s.games))}
+	for _, game := range s.games {
+		snapshot.Games = append(snapshot.Games, copyCorrespondenceGame(game))
+	}
+	return snapshot
+}
+
+func (s *CorrespondenceStore) load(snapshot correspondenceSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games = make(map[string]*CorrespondenceGame, len(snapshot.Games))
+	for i := range snapshot.Games {
+		game := snapshot.Games[i]
+		s.games[game.ID] = &game
+	}
+}
+
+// PersistCorrespondenceGames saves the games right away. They change rarely
+// and a move must not be lost if the server stops before shutdown.
+func PersistCorrespondenceGames() {
+	persistCorrespondenceGames(GetConfig(), CorrespondenceGames)
+}
+
+func loadCorrespondenceGames(cfg Config, store *CorrespondenceStore) {
+	if store == nil || cfg.CorrespondencePath == "" {
+		log.Printf("[ai:cache] restored correspondence games: 0 games (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.CorrespondencePath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open correspondence games %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored correspondence games: 0 games (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot correspondenceSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode correspondence games %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored correspondence games from %s (%d games)", path, store.Len())
+}
+
+func persistCorrespondenceGames(cfg Config, store *CorrespondenceStore) {
+	if store == nil || cfg.CorrespondencePath == "" {
+		log.Printf("[ai:cache] stored correspondence games: 0 games (disabled or no path)")
+		return
+	}
+	store.persistMu.Lock()
+	defer store.persistMu.Unlock()
+	path := resolveTTPersistencePath(cfg.CorrespondencePath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create correspondence directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create correspondence games %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode correspondence games %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored correspondence games to %s (%d games)", path, len(snapshot.Games))
+}
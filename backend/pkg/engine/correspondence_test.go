@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCorrespondenceGameChecksTokensAndTurns(t *testing.T) {
+	store := NewCorrespondenceStore()
+	game, err := store.Create(CorrespondencePlayer{Name: "ada"}, CorrespondencePlayer{Name: "bob"}, UserPreferences{BoardSize: 9})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if game.Black.Token == "" || game.Black.Token == game.White.Token {
+		t.Fatalf("expected distinct tokens, got %q and %q", game.Black.Token, game.White.Token)
+	}
+	if _, ok, err := store.Play(game.ID, game.White.Token, Move{X: 4, Y: 4}); !ok || err == nil {
+		t.Fatalf("expected white to wait for black, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := store.Play(game.ID, "wrong", Move{X: 4, Y: 4}); !ok || err == nil {
+		t.Fatalf("expected an unknown token to be rejected")
+	}
+	played, ok, err := store.Play(game.ID, game.Black.Token, Move{X: 4, Y: 4})
+	if !ok || err != nil || played.NextPlayer != 2 || len(played.Moves) != 1 {
+		t.Fatalf("expected black move to be applied, got %+v ok=%v err=%v", played, ok, err)
+	}
+	if _, _, err := store.Play(game.ID, game.White.Token, Move{X: 4, Y: 4}); err == nil {
+		t.Fatalf("expected an occupied cell to be rejected")
+	}
+	resigned, _, err := store.Resign(game.ID, game.White.Token)
+	if err != nil || resigned.Status != "black_won" || resigned.Resigned != 2 {
+		t.Fatalf("expected white resignation to end the game, got %+v (%v)", resigned, err)
+	}
+	if _, _, err := store.Play(game.ID, game.White.Token, Move{X: 0, Y: 0}); err == nil {
+		t.Fatalf("expected moves after the end to be rejected")
+	}
+}
+
+func TestCorrespondenceGameNotifiesNextPlayer(t *testing.T) {
+	bodies := make(chan map[string]string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	store := NewCorrespondenceStore()
+	game, err := store.Create(CorrespondencePlayer{Name: "ada"}, CorrespondencePlayer{Name: "bob", Webhook: server.URL}, UserPreferences{BoardSize: 9})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if _, _, err := store.Play(game.ID, game.Black.Token, Move{X: 4, Y: 4}); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	select {
+	case body := <-bodies:
+		want := "bob, it is your move (white) in correspondence game " + game.ID + " after 1 moves."
+		if body["text"] != want {
+			t.Fatalf("unexpected notification %q", body["text"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected white to be notified")
+	}
+}
+
+func TestCorrespondenceGamesPersistenceRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CorrespondencePath = filepath.Join(t.TempDir(), "correspondence.gob")
+	store := NewCorrespondenceStore()
+	game, err := store.Create(CorrespondencePlayer{Name: "ada"}, CorrespondencePlayer{Name: "bob"}, UserPreferences{BoardSize: 9})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if _, _, err := store.Play(game.ID, game.Black.Token, Move{X: 4, Y: 4}); err != nil {
+		t.Fatalf("move failed: %v", err)
+	}
+	persistCorrespondenceGames(cfg, store)
+
+	restored := NewCorrespondenceStore()
+	loadCorrespondenceGames(cfg, restored)
+	if _, _, err := restored.Play(game.ID, game.White.Token, Move{X: 5, Y: 5}); err != nil {
+		t.Fatalf("expected restored game to accept white's move: %v", err)
+	}
+	view, err := ViewCorrespondence(mustCorrespondenceGame(t, restored, game.ID), nil)
+	if err != nil || view.Board[4][4] != 1 || view.Board[5][5] != 2 {
+		t.Fatalf("unexpected restored board: %v (%v)", view.Board, err)
+	}
+}
+
+func mustCorrespondenceGame(t *testing.T, store *CorrespondenceStore, id string) CorrespondenceGame {
+	t.Helper()
+	game, ok := store.Get(id)
+	if !ok {
+		t.Fatalf("game %s not found", id)
+	}
+	return game
+}
@@ -0,0 +1,73 @@
+package engine
+
+// evalOpenFour is what EvaluateBoard scores an open four at, whatever else
+// is on the board.
+const evalOpenFour = 900000.0
+
+// defensiveCaptureCredit is what the static evaluation overrates attacker's
+// threats by when the defender can break them by capturing one of their
+// stones: DefensiveCapture of the weight of each four and open three holding
+// a stone the defender's capture moves take. Fours only count with the
+// defender to move, as the attacker would otherwise complete them first; an
+// open four, which EvaluateBoard scores flat, is credited against that flat
+// score. defenderCaptures are the defender's capture moves.
+func defensiveCaptureCredit(state GameState, rules Rules, attacker PlayerColor, defenderCaptures []Move, heuristics HeuristicConfig) float64 {
+	if len(defenderCaptures) == 0 || heuristics.DefensiveCapture <= 0 {
+		return 0
+	}
+	board := state.Board
+	size := board.Size()
+	defender := otherPlayer(attacker)
+	defenderCell := CellFromPlayer(defender)
+	capturable := make([]bool, size*size)
+	probe := board.Clone()
+	for _, move := range defenderCaptures {
+		probe.Set(move.X, move.Y, defenderCell)
+		for _, stone := range rules.FindCaptures(probe, move, defenderCell) {
+			capturable[stone.Y*size+stone.X] = true
+		}
+		probe.Remove(move.X, move.Y)
+	}
+	breaks := func(stones []int) bool {
+		for _, idx := range stones {
+			if capturable[idx] {
+				return true
+			}
+		}
+		return false
+	}
+
+	defenderToMove := state.ToMove == defender
+	openFour := false
+	credit := 0.0
+	var buf []byte
+	for _, line := range getLinesForSize(size) {
+		buf = buildTokensInto(board, line, attacker, buf)
+		tokens := buf[1 : len(buf)-1]
+		if defenderToMove {
+			for start := 0; start+5 <= len(tokens); start++ {
+				stones, _, ok := windowShape(tokens[start:start+5], line[start:start+5], 4)
+				if !ok || !breaks(stones) {
+					continue
+				}
+				credit += heuristics.Closed4
+				if start+6 <= len(tokens) && tokens[start] == '.' && tokens[start+5] == '.' {
+					openFour = true
+				}
+			}
+		}
+		for start := 0; start+6 <= len(tokens); start++ {
+			if tokens[start] != '.' || tokens[start+5] != '.' {
+				continue
+			}
+			stones, _, ok := windowShape(tokens[start+1:start+5], line[start+1:start+5], 3)
+			if ok && breaks(stones) {
+				credit += heuristics.Open3
+			}
+		}
+	}
+	if openFour {
+		credit += evalOpenFour
+	}
+	return credit * heuristics.DefensiveCapture
+}
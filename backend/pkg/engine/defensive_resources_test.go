@@ -0,0 +1,35 @@
+package engine
+
+import "testing"
+
+func TestDefensiveCaptureCreditsFoursBrokenByCapture(t *testing.T) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	// White four along row 5, closed by black at (4,5).
+	state.Board.Set(4, 5, CellBlack)
+	for x := 5; x <= 8; x++ {
+		state.Board.Set(x, 5, CellWhite)
+	}
+	heuristics := resolvedHeuristicConfig(DefaultConfig())
+
+	if credit := defensiveCaptureCredit(state, rules, PlayerWhite, findCaptureMoves(state, rules, PlayerBlack), heuristics); credit != 0 {
+		t.Fatalf("expected no credit without a capture, got %.2f", credit)
+	}
+
+	// (6,5) and (6,6) form a white pair black captures by playing (6,7).
+	state.Board.Set(6, 4, CellBlack)
+	state.Board.Set(6, 6, CellWhite)
+	captures := findCaptureMoves(state, rules, PlayerBlack)
+	credit := defensiveCaptureCredit(state, rules, PlayerWhite, captures, heuristics)
+	if want := heuristics.Closed4 * heuristics.DefensiveCapture; credit < want {
+		t.Fatalf("expected at least %.2f for a four broken by capture, got %.2f", want, credit)
+	}
+
+	state.ToMove = PlayerWhite
+	if got := defensiveCaptureCredit(state, rules, PlayerWhite, captures, heuristics); got >= credit {
+		t.Fatalf("a four the attacker completes next move should not be credited, got %.2f", got)
+	}
+}
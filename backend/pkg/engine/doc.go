@@ -0,0 +1,16 @@
+// Package engine holds the gomoku rules, board representation and AI search
+// used by the backend server. It can be embedded directly by other Go programs:
+//
+//	game := engine.NewGame(engine.DefaultGameSettings())
+//	game.Start()
+//	if err := game.ApplyMove(engine.NewMove(9, 9)); err != nil {
+//		// illegal move or game over
+//	}
+//	analysis, err := engine.Analyze(engine.DefaultGameSettings(), engine.AnalyzeRequest{
+//		Moves: []engine.Move{{X: 9, Y: 9}},
+//		Depth: 6,
+//	})
+//
+// Search behaviour is driven by the process-wide Config (see GetConfig and
+// UpdateConfig) and shares the global transposition table.
+package engine
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	duelDefaultOpenings = 4
+	duelDefaultPlies    = 4
+	duelMaxPlies        = 12
+	duelZ95             = 1.96
+	duelEloCap          = 800
+)
+
+// DuelRequest compares heuristics A and B over an opening suite, each
+// opening played once per colour. Openings are generated from Seed around
+// the centre when none are given.
+type DuelRequest struct {
+	A            *HeuristicConfig `json:"a,omitempty"`
+	B            *HeuristicConfig `json:"b,omitempty"`
+	Openings     [][]Move         `json:"openings"`
+	OpeningCount int              `json:"opening_count"`
+	OpeningPlies int              `json:"opening_plies"`
+	Seed         int64            `json:"seed"`
+	Games        int              `json:"games"`
+	MoveBudgetMs int              `json:"move_budget_ms"`
+	Depth        int              `json:"depth"`
+	ADepth       int              `json:"a_depth"`
+	BDepth       int              `json:"b_depth"`
+	MaxMoves     int              `json:"max_moves"`
+	Workers      int              `json:"workers"`
+	DisableCache bool             `json:"disable_cache"`
+}
+
+type DuelGame struct {
+	Index        int     `json:"index"`
+	OpeningIndex int     `json:"opening_index"`
+	AColor       int     `json:"a_color"`
+	Status       string  `json:"status"`
+	Winner       int     `json:"winner"`
+	Score        float64 `json:"score"`
+	Plies        int     `json:"plies"`
+	ElapsedMs    float64 `json:"elapsed_ms"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// DuelResult reports everything from A's side. Elo is the rating difference
+// A-B implied by the score rate, with a 95% interval from the per-game score
+// variance; both are capped at +-800.
+type DuelResult struct {
+	Games      int        `json:"games"`
+	Wins       int        `json:"wins"`
+	Draws      int        `json:"draws"`
+	Losses     int        `json:"losses"`
+	Unfinished int        `json:"unfinished"`
+	Errors     int        `json:"errors"`
+	Score      float64    `json:"score"`
+	ScoreRate  float64    `json:"score_rate"`
+	Elo        float64    `json:"elo"`
+	EloLow     float64    `json:"elo_low"`
+	EloHigh    float64    `json:"elo_high"`
+	Openings   [][]Move   `json:"openings"`
+	Records    []DuelGame `json:"records"`
+	ElapsedMs  float64    `json:"elapsed_ms"`
+}
+
+// DuelOpenings builds count reproducible openings of plies stones each,
+// picked from the cells around the board centre.
+func DuelOpenings(boardSize, count, plies int, seed int64) [][]Move {
+	rng := rand.New(rand.NewSource(seed))
+	center := boardSize / 2
+	offsets := []Move{
+		{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 0, Y: -1}, {X: 1, Y: 1},
+		{X: -1, Y: -1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: 2, Y: 0}, {X: 0, Y: 2},
+	}
+	if plies > len(offsets) {
+		plies = len(offsets)
+	}
+	suite := make([][]Move, 0, count)
+	for i := 0; i < count; i++ {
+		used := map[Move]bool{}
+		opening := make([]Move, 0, plies)
+		for len(opening) < plies {
+			off := offsets[rng.Intn(len(offsets))]
+			move := Move{X: center + off.X, Y: center + off.Y}
+			if !move.IsValid(boardSize) || used[move] {
+				continue
+			}
+			used[move] = true
+			opening = append(opening, move)
+		}
+		suite = append(suite, opening)
+	}
+	return suite
+}
+
+// EloFromScore converts a score rate into a rating difference.
+func EloFromScore(rate float64) float64 {
+	if rate <= 0 {
+		return -duelEloCap
+	}
+	if rate >= 1 {
+		return duelEloCap
+	}
+	elo := -400 * math.Log10(1/rate-1)
+	return math.Max(-duelEloCap, math.Min(duelEloCap, elo))
+}
+
+func duelRequestOpenings(settings GameSettings, req DuelRequest) [][]Move {
+	if len(req.Openings) > 0 {
+		return req.Openings
+	}
+	count := req.OpeningCount
+	if count <= 0 {
+		count = duelDefaultOpenings
+	}
+	plies := req.OpeningPlies
+	if plies <= 0 {
+		plies = duelDefaultPlies
+	}
+	if plies > duelMaxPlies {
+		plies = duelMaxPlies
+	}
+	seed := req.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	return DuelOpenings(settings.BoardSize, count, plies, seed)
+}
+
+// Duel plays A against B with alternating colours and summarises the result
+// from A's side. Unfinished games count as draws.
+func Duel(ctx context.Context, settings GameSettings, req DuelRequest) DuelResult {
+	openings := duelRequestOpenings(settings, req)
+	batch := SimulateBatchRequest{
+		Games:           req.Games,
+		Openings:        openings,
+		BlackHeuristics: req.A,
+		WhiteHeuristics: req.B,
+		AlternateColors: true,
+		MoveBudgetMs:    req.MoveBudgetMs,
+		Depth:           req.Depth,
+		BlackDepth:      req.ADepth,
+		WhiteDepth:      req.BDepth,
+		MaxMoves:        req.MaxMoves,
+		Workers:         req.Workers,
+		DisableCache:    req.DisableCache,
+	}
+	result := DuelResult{Openings: openings}
+	summary := SimulateBatch(ctx, settings, batch, func(game SimulateBatchGame) {
+		record := DuelGame{
+			Index:        game.Index,
+			OpeningIndex: game.OpeningIndex,
+			AColor:       1,
+			Status:       game.Result.Status,
+			Winner:       game.Result.Winner,
+			Score:        game.Score,
+			Plies:        len(game.Result.Moves),
+			ElapsedMs:    game.Result.ElapsedMs,
+			Error:        game.Error,
+		}
+		if game.Swapped {
+			record.AColor = 2
+		}
+		result.Records = append(result.Records, record)
+	})
+	sort.Slice(result.Records, func(i, j int) bool { return result.Records[i].Index < result.Records[j].Index })
+	result.Games = summary.Games
+	result.Wins = summary.Wins
+	result.Draws = summary.Draws
+	result.Losses = summary.Losses
+	result.Unfinished = summary.Unfinished
+	result.Errors = summary.Errors
+	result.Score = summary.Score
+	result.ScoreRate = summary.ScoreRate
+	result.ElapsedMs = summary.ElapsedMs
+	result.Elo, result.EloLow, result.EloHigh = duelEloInterval(result.Records)
+	return result
+}
+
+func duelEloInterval(records []DuelGame) (float64, float64, float64) {
+	n := 0
+	total := 0.0
+	for _, record := range records {
+		if record.Error == "" {
+			n++
+			total += record.Score
+		}
+	}
+	if n == 0 {
+		return 0, -duelEloCap, duelEloCap
+	}
+	mean := total / float64(n)
+	variance := 0.0
+	for _, record := range records {
+		if record.Error == "" {
+			d := record.Score - mean
+			variance += d * d
+		}
+	}
+	variance /= float64(n)
+	margin := duelZ95 * math.Sqrt(variance/float64(n))
+	return EloFromScore(mean), EloFromScore(mean - margin), EloFromScore(mean + margin)
+}
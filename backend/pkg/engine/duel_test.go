@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDuelOpeningsAreReproducible(t *testing.T) {
+	first := DuelOpenings(19, 3, 4, 7)
+	second := DuelOpenings(19, 3, 4, 7)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected identical suites for the same seed")
+	}
+	for _, opening := range first {
+		seen := map[Move]bool{}
+		for _, move := range opening {
+			if seen[move] || !move.IsValid(19) {
+				t.Fatalf("expected distinct in-bounds opening moves, got %+v", opening)
+			}
+			seen[move] = true
+		}
+	}
+}
+
+func TestEloFromScore(t *testing.T) {
+	if got := EloFromScore(0.5); got != 0 {
+		t.Fatalf("expected 0 elo at an even score, got %v", got)
+	}
+	if got := EloFromScore(0.75); got < 190 || got > 192 {
+		t.Fatalf("expected about +191 elo at 75%%, got %v", got)
+	}
+	if EloFromScore(1) != duelEloCap || EloFromScore(0) != -duelEloCap {
+		t.Fatalf("expected perfect scores to be capped")
+	}
+}
+
+func TestDuelReportsFromASide(t *testing.T) {
+	opening := []Move{}
+	for i := 0; i < 4; i++ {
+		opening = append(opening, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	result := Duel(context.Background(), DefaultGameSettings(), DuelRequest{
+		Openings:     [][]Move{opening},
+		Depth:        2,
+		MoveBudgetMs: 200,
+		MaxMoves:     4,
+	})
+	if result.Games != 2 || len(result.Records) != 2 {
+		t.Fatalf("expected two games, got %+v", result)
+	}
+	if result.Records[0].AColor != 1 || result.Records[1].AColor != 2 {
+		t.Fatalf("expected A to play black then white, got %+v", result.Records)
+	}
+	if result.ScoreRate != 0.5 || result.Elo != 0 {
+		t.Fatalf("expected an even duel, got rate %v elo %v", result.ScoreRate, result.Elo)
+	}
+	if result.EloLow >= 0 || result.EloHigh <= 0 {
+		t.Fatalf("expected the interval to straddle zero, got [%v, %v]", result.EloLow, result.EloHigh)
+	}
+}
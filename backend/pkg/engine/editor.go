@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+)
+
+// EditorView is the position being set up in the board editor. Problem
+// explains why the position cannot be started or analysed yet; it is empty
+// once the position passes StartPosition.State.
+type EditorView struct {
+	StartPosition
+	Valid   bool   `json:"valid"`
+	Problem string `json:"problem,omitempty"`
+}
+
+// BoardEditor holds a position set up stone by stone outside of any game,
+// for studies and puzzles. Edits only check bounds; the rules are applied
+// when the position is started or analysed.
+type BoardEditor struct {
+	mu       sync.Mutex
+	position StartPosition
+}
+
+var Editor = NewBoardEditor()
+
+func NewBoardEditor() *BoardEditor {
+	return &BoardEditor{}
+}
+
+// View returns the position for settings, starting from an empty board with
+// black to move when nothing was set up or the board size changed.
+func (e *BoardEditor) View(settings GameSettings) EditorView {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ensureLocked(settings.BoardSize)
+	return e.viewLocked(settings)
+}
+
+// Clear empties the board, resets the capture counts and gives black the
+// move.
+func (e *BoardEditor) Clear(settings GameSettings) EditorView {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.position = StartPosition{}
+	e.ensureLocked(settings.BoardSize)
+	return e.viewLocked(settings)
+}
+
+// Load replaces the position with state, e.g. the live game's.
+func (e *BoardEditor) Load(settings GameSettings, state GameState) EditorView {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.position = StartPosition{
+		Board:         BoardToSlice(state.Board),
+		NextPlayer:    PlayerToInt(state.ToMove),
+		CapturedBlack: state.CapturedBlack,
+		CapturedWhite: state.CapturedWhite,
+	}
+	e.ensureLocked(settings.BoardSize)
+	return e.viewLocked(settings)
+}
+
+// SetStone places a stone of player (1 black, 2 white) at move, or removes
+// the stone there when player is 0.
+func (e *BoardEditor) SetStone(settings GameSettings, move Move, player int) (EditorView, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ensureLocked(settings.BoardSize)
+	if !move.IsValid(settings.BoardSize) {
+		return e.viewLocked(settings), fieldErrorf("x", "stone %d,%d is out of bounds", move.X, move.Y)
+	}
+	if player < 0 || player > 2 {
+		return e.viewLocked(settings), fieldErrorf("player", "player must be 0 (remove), 1 or 2")
+	}
+	e.position.Board[move.Y][move.X] = player
+	return e.viewLocked(settings), nil
+}
+
+// SetCaptures sets the number of stones each side has taken so far.
+func (e *BoardEditor) SetCaptures(settings GameSettings, black, white int) (EditorView, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ensureLocked(settings.BoardSize)
+	if black < 0 || white < 0 {
+		return e.viewLocked(settings), errors.New("captured counts must not be negative")
+	}
+	e.position.CapturedBlack = black
+	e.position.CapturedWhite = white
+	return e.viewLocked(settings), nil
+}
+
+// SetNextPlayer gives the move to player (1 black, 2 white).
+func (e *BoardEditor) SetNextPlayer(settings GameSettings, player int) (EditorView, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ensureLocked(settings.BoardSize)
+	if player != 1 && player != 2 {
+		return e.viewLocked(settings), fieldErrorf("next_player", "next_player must be 1 or 2")
+	}
+	e.position.NextPlayer = player
+	return e.viewLocked(settings), nil
+}
+
+// Position returns a copy of the edited position.
+func (e *BoardEditor) Position(settings GameSettings) StartPosition {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ensureLocked(settings.BoardSize)
+	return e.copyLocked()
+}
+
+func (e *BoardEditor) ensureLocked(size int) {
+	if len(e.position.Board) != size {
+		e.position.Board = emptyPositionBoard(size)
+		e.position.NextPlayer = 1
+		e.position.CapturedBlack = 0
+		e.position.CapturedWhite = 0
+	}
+	if e.position.NextPlayer == 0 {
+		e.position.NextPlayer = 1
+	}
+}
+
+func (e *BoardEditor) copyLocked() StartPosition {
+	position := e.position
+	position.Board = make([][]int, len(e.position.Board))
+	for y, row := range e.position.Board {
+		position.Board[y] = append([]int(nil), row...)
+	}
+	return position
+}
+
+func (e *BoardEditor) viewLocked(settings GameSettings) EditorView {
+	view := EditorView{StartPosition: e.copyLocked(), Valid: true}
+	if _, err := view.StartPosition.State(settings); err != nil {
+		view.Valid = false
+		view.Problem = err.Error()
+	}
+	return view
+}
+
+func emptyPositionBoard(size int) [][]int {
+	board := make([][]int, size)
+	for y := range board {
+		board[y] = make([]int, size)
+	}
+	return board
+}
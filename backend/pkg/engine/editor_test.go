@@ -0,0 +1,84 @@
+package engine
+
+import "testing"
+
+func TestBoardEditorDefersLegalityChecks(t *testing.T) {
+	settings := DefaultGameSettings()
+	editor := NewBoardEditor()
+	view := editor.View(settings)
+	if len(view.Board) != settings.BoardSize || view.NextPlayer != 1 || !view.Valid {
+		t.Fatalf("expected an empty valid board with black to move, got %+v", view)
+	}
+	for x := 3; x < 8; x++ {
+		if _, err := editor.SetStone(settings, Move{X: x, Y: 4}, 1); err != nil {
+			t.Fatalf("unexpected edit error: %v", err)
+		}
+	}
+	view = editor.View(settings)
+	if view.Valid || view.Problem == "" {
+		t.Fatalf("expected a five to be reported as a problem")
+	}
+	if _, err := editor.SetStone(settings, Move{X: 5, Y: 4}, 0); err != nil {
+		t.Fatalf("unexpected removal error: %v", err)
+	}
+	if _, err := editor.SetCaptures(settings, 3, 0); err != nil {
+		t.Fatalf("expected odd capture counts to be accepted while editing: %v", err)
+	}
+	if view = editor.View(settings); view.Valid {
+		t.Fatalf("expected an odd capture count to be reported")
+	}
+	if _, err := editor.SetCaptures(settings, 2, 0); err != nil {
+		t.Fatalf("unexpected capture error: %v", err)
+	}
+	if _, err := editor.SetStone(settings, Move{X: settings.BoardSize, Y: 0}, 1); err == nil {
+		t.Fatalf("expected out of bounds stones to be rejected")
+	}
+	if _, err := editor.SetNextPlayer(settings, 2); err != nil {
+		t.Fatalf("unexpected next player error: %v", err)
+	}
+	view = editor.View(settings)
+	if !view.Valid || view.NextPlayer != 2 || view.CapturedBlack != 2 || view.Board[4][5] != 0 {
+		t.Fatalf("unexpected edited position: %+v", view)
+	}
+
+	state, err := editor.Position(settings).State(settings)
+	if err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+	if state.ToMove != PlayerWhite || state.Board.At(3, 4) != CellBlack {
+		t.Fatalf("expected the edited position to carry over")
+	}
+}
+
+func TestBoardEditorResetsOnBoardSizeChange(t *testing.T) {
+	settings := DefaultGameSettings()
+	editor := NewBoardEditor()
+	if _, err := editor.SetStone(settings, Move{X: 1, Y: 1}, 2); err != nil {
+		t.Fatalf("unexpected edit error: %v", err)
+	}
+	settings.BoardSize = 9
+	if view := editor.View(settings); len(view.Board) != 9 || view.Board[1][1] != 0 {
+		t.Fatalf("expected a fresh 9x9 board")
+	}
+}
+
+func TestAnalyzeFromPosition(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	board := emptyStartBoard(9)
+	for x := 1; x < 5; x++ {
+		board[4][x] = 1
+	}
+	board[0][0] = 2
+	board[0][1] = 2
+	response, err := Analyze(settings, AnalyzeRequest{
+		Position: &StartPosition{Board: board, NextPlayer: 1},
+		Depth:    2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected analysis error: %v", err)
+	}
+	if !response.BestMove.Equals(Move{X: 0, Y: 4}) && !response.BestMove.Equals(Move{X: 5, Y: 4}) {
+		t.Fatalf("expected black to complete the five, got %+v", response.BestMove)
+	}
+}
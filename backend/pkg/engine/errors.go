@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors shared by the game sessions, so the API can tell them apart.
+var (
+	ErrIllegalMove  = errors.New("illegal move")
+	ErrNotYourTurn  = errors.New("not your turn")
+	ErrGameOver     = errors.New("game is over")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// FieldError is a validation error about one request field. The message
+// reads on its own; the API also reports the field separately.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+func fieldErrorf(field, format string, args ...any) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
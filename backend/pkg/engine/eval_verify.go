@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const (
+	evalVerifyDefaultSamples = 200
+	evalVerifyMaxSamples     = 5000
+	evalVerifyMaxMoves       = 60
+	evalVerifyMaxMismatches  = 20
+)
+
+// EvalVerifyOptions says how many random positions VerifyEvaluation checks,
+// drawn from Seed so a failing run can be repeated.
+type EvalVerifyOptions struct {
+	Samples   int   `json:"samples"`
+	Seed      int64 `json:"seed"`
+	BoardSize int   `json:"board_size"`
+}
+
+// EvalMismatch is one position the evaluation disagreed with itself on.
+// Kind is "symmetry" (Transform is the rotation or mirror of the board,
+// 1 to 7), "hash" (the search's incremental hash against one computed from
+// the board) or "incremental" (the value the search sees after making the
+// moves one by one against a full scan of the board).
+type EvalMismatch struct {
+	Sample    int     `json:"sample"`
+	Kind      string  `json:"kind"`
+	Transform int     `json:"transform,omitempty"`
+	Moves     []Move  `json:"moves"`
+	Want      float64 `json:"want"`
+	Got       float64 `json:"got"`
+	Detail    string  `json:"detail,omitempty"`
+}
+
+// EvalVerifyReport is the result of VerifyEvaluation. Mismatches holds the
+// first ones found; MismatchCount counts them all.
+type EvalVerifyReport struct {
+	Samples       int            `json:"samples"`
+	Seed          int64          `json:"seed"`
+	BoardSize     int            `json:"board_size"`
+	Positions     int            `json:"positions"`
+	OK            bool           `json:"ok"`
+	MismatchCount int            `json:"mismatch_count"`
+	Mismatches    []EvalMismatch `json:"mismatches"`
+}
+
+func (r *EvalVerifyReport) mismatch(m EvalMismatch) {
+	r.MismatchCount++
+	if len(r.Mismatches) < evalVerifyMaxMismatches {
+		m.Moves = append([]Move(nil), m.Moves...)
+		r.Mismatches = append(r.Mismatches, m)
+	}
+}
+
+// VerifyEvaluation plays random games and checks the static evaluation on
+// every position they reach. Each sample's final position must score the
+// same under all 8 rotations and mirrors of the board. Every position on the
+// way must score the same when the search reaches it by making moves one by
+// one (incremental hashes, captures and the eval cache) as when the board is
+// scanned from scratch. It guards changes to how positions are evaluated.
+func VerifyEvaluation(opts EvalVerifyOptions, config Config) EvalVerifyReport {
+	if opts.Samples <= 0 {
+		opts.Samples = evalVerifyDefaultSamples
+	}
+	if opts.Samples > evalVerifyMaxSamples {
+		opts.Samples = evalVerifyMaxSamples
+	}
+	settings := DefaultGameSettings()
+	if opts.BoardSize >= 5 {
+		settings.BoardSize = opts.BoardSize
+	}
+	report := EvalVerifyReport{
+		Samples:    opts.Samples,
+		Seed:       opts.Seed,
+		BoardSize:  settings.BoardSize,
+		Mismatches: []EvalMismatch{},
+	}
+	rules := NewRules(settings)
+	config.AiEnableEvalCache = true
+	cache := newAISearchCache()
+	scoreSettings := AIScoreSettings{
+		BoardSize: settings.BoardSize,
+		Config:    config,
+		Cache:     &cache,
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for sample := 1; sample <= opts.Samples; sample++ {
+		state := DefaultGameState(settings)
+		state.Status = StatusRunning
+		moves := []Move{}
+		length := rng.Intn(evalVerifyMaxMoves + 1)
+		for len(moves) < length && state.Status == StatusRunning {
+			move, ok := evalVerifyMove(state, rules, rng)
+			if !ok {
+				break
+			}
+			var undo searchMoveUndo
+			if !applyMoveWithUndo(&state, rules, move, state.ToMove, &undo) {
+				break
+			}
+			moves = append(moves, move)
+			report.Positions++
+			evalVerifyIncremental(&report, sample, moves, state, rules, scoreSettings)
+		}
+		evalVerifySymmetry(&report, sample, moves, state, rules, config)
+	}
+	report.OK = report.MismatchCount == 0
+	return report
+}
+
+// evalVerifyMove picks a random legal move next to the stones on the board,
+// the kind of position the search evaluates, or the centre on an empty one.
+func evalVerifyMove(state GameState, rules Rules, rng *rand.Rand) (Move, bool) {
+	size := state.Board.Size()
+	candidates := []Move{}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if state.Board.At(x, y) != CellEmpty || !hasNeighborStone(state.Board, x, y) {
+				continue
+			}
+			move := Move{X: x, Y: y}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); ok {
+				candidates = append(candidates, move)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		center := Move{X: size / 2, Y: size / 2}
+		if state.Board.At(center.X, center.Y) != CellEmpty {
+			return Move{}, false
+		}
+		return center, true
+	}
+	return candidates[rng.Intn(len(candidates))], true
+}
+
+// evalVerifyFull scores state from a scan of its board alone.
+func evalVerifyFull(state GameState, rules Rules, config Config) float64 {
+	return EvaluateBoard(state.Board, PlayerBlack, config) + captureUrgencyHeuristic(state, rules, config)
+}
+
+// evalVerifyRebuild is state rebuilt from its board, captures and side to
+// move, with nothing carried over from the moves that led to it.
+func evalVerifyRebuild(state GameState) GameState {
+	rebuilt := state.Clone()
+	rebuilt.recomputeHashes()
+	return rebuilt
+}
+
+func evalVerifyIncremental(report *EvalVerifyReport, sample int, moves []Move, state GameState, rules Rules, settings AIScoreSettings) {
+	rebuilt := evalVerifyRebuild(state)
+	if rebuilt.Hash != state.Hash || rebuilt.CanonHash != state.CanonHash {
+		report.mismatch(EvalMismatch{
+			Sample: sample,
+			Kind:   "hash",
+			Moves:  moves,
+			Detail: fmt.Sprintf("incremental %016x/%016x, from the board %016x/%016x", state.Hash, state.CanonHash, rebuilt.Hash, rebuilt.CanonHash),
+		})
+	}
+	if state.Status != StatusRunning {
+		return
+	}
+	want := evalVerifyFull(rebuilt, rules, settings.Config)
+	got := evalBoardCached(state, rules, settings, settings.Cache)
+	if got != want {
+		report.mismatch(EvalMismatch{Sample: sample, Kind: "incremental", Moves: moves, Want: want, Got: got})
+	}
+}
+
+func evalVerifySymmetry(report *EvalVerifyReport, sample int, moves []Move, state GameState, rules Rules, config Config) {
+	if state.Status != StatusRunning {
+		return
+	}
+	want := evalVerifyFull(state, rules, config)
+	size := state.Board.Size()
+	for i := 1; i < len(symmetryTransforms); i++ {
+		transformed := state.Clone()
+		transformed.Board = NewBoard(size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if cell := state.Board.At(x, y); cell != CellEmpty {
+					tx, ty := transformCoord(x, y, size, symmetryTransforms[i])
+					transformed.Board.Set(tx, ty, cell)
+				}
+			}
+		}
+		if state.HasLastMove {
+			transformed.LastMove.X, transformed.LastMove.Y = transformCoord(state.LastMove.X, state.LastMove.Y, size, symmetryTransforms[i])
+		}
+		transformed.recomputeHashes()
+		if got := evalVerifyFull(transformed, rules, config); got != want {
+			report.mismatch(EvalMismatch{Sample: sample, Kind: "symmetry", Transform: i, Moves: moves, Want: want, Got: got})
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import "testing"
+
+func TestVerifyEvaluationFindsNoMismatches(t *testing.T) {
+	report := VerifyEvaluation(EvalVerifyOptions{Samples: 40, Seed: 7}, GetConfig())
+	if report.Positions == 0 {
+		t.Fatalf("report = %+v, want positions checked", report)
+	}
+	if !report.OK {
+		t.Fatalf("evaluation mismatches: %+v", report.Mismatches)
+	}
+}
+
+func TestVerifyEvaluationReportsAStaleEvalCacheEntry(t *testing.T) {
+	config := GetConfig()
+	config.AiEnableEvalCache = true
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	moves := []Move{{X: 9, Y: 9}, {X: 10, Y: 10}, {X: 9, Y: 10}}
+	for _, move := range moves {
+		if !applyMove(&state, rules, move, state.ToMove) {
+			t.Fatalf("move %v rejected", move)
+		}
+	}
+	cache := newAISearchCache()
+	scoreSettings := AIScoreSettings{BoardSize: settings.BoardSize, Config: config, Cache: &cache}
+	ensureEvalCache(&cache, config).Put(evalKey(state.Hash, settings.BoardSize, state.ToMove), 12345)
+
+	report := EvalVerifyReport{}
+	evalVerifyIncremental(&report, 1, moves, state, rules, scoreSettings)
+	if report.MismatchCount != 1 || report.Mismatches[0].Kind != "incremental" || report.Mismatches[0].Got != 12345 {
+		t.Fatalf("report = %+v, want the stale cached value reported", report)
+	}
+}
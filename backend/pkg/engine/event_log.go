@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventGameStarted     EventKind = "game_started"
+	EventGameReset       EventKind = "game_reset"
+	EventSettingsChanged EventKind = "settings_changed"
+	EventMoveApplied     EventKind = "move_applied"
+	EventWinDeclared     EventKind = "win_declared"
+	EventCachesFlushed   EventKind = "caches_flushed"
+	// EventHeuristicsPromoted carries the weight set made live in
+	// Promotion.
+	EventHeuristicsPromoted EventKind = "heuristics_promoted"
+)
+
+// Event actors: who caused an event. Moves name the side that played them,
+// and a human side linked to a user profile is "user:<id>"; a capture win
+// the rules played on a side's behalf is "rules".
+const (
+	ActorHuman = "human"
+	ActorAI    = "ai"
+	ActorAPI   = "api"
+	ActorRules = "rules"
+)
+
+// EventSettings are the game settings an event started or changed a game
+// with, with the player types spelled out.
+type EventSettings struct {
+	GameSettings
+	Black string `json:"black"`
+	White string `json:"white"`
+}
+
+func eventSettings(settings GameSettings) *EventSettings {
+	return &EventSettings{GameSettings: settings, Black: playerTypeName(settings.BlackType), White: playerTypeName(settings.WhiteType)}
+}
+
+// Settings returns the game settings back with their player types.
+func (s EventSettings) Settings() GameSettings {
+	settings := s.GameSettings
+	settings.BlackType = playerTypeFromName(s.Black)
+	settings.WhiteType = playerTypeFromName(s.White)
+	return settings
+}
+
+func playerTypeName(kind PlayerType) string {
+	if kind == PlayerAI {
+		return ActorAI
+	}
+	return ActorHuman
+}
+
+func playerTypeFromName(name string) PlayerType {
+	if name == ActorAI {
+		return PlayerAI
+	}
+	return PlayerHuman
+}
+
+// Event is one entry of the event log. Seq grows by one per event and is
+// what clients page with; Hash is the position after the event.
+type Event struct {
+	Seq      uint64         `json:"seq"`
+	Time     time.Time      `json:"time"`
+	Kind     EventKind      `json:"kind"`
+	Actor    string         `json:"actor"`
+	GameID   uint64         `json:"game_id,omitempty"`
+	Ply      int            `json:"ply,omitempty"`
+	Move     *Move          `json:"move,omitempty"`
+	Player   int            `json:"player,omitempty"`
+	Captured int            `json:"captured,omitempty"`
+	Settings *EventSettings `json:"settings,omitempty"`
+	Position *StartPosition `json:"position,omitempty"`
+	Status   string         `json:"status,omitempty"`
+	Winner   int            `json:"winner,omitempty"`
+	Hash     string         `json:"hash,omitempty"`
+	// Promotion is the heuristics_promoted event's.
+	Promotion *HeuristicPromotion `json:"promotion,omitempty"`
+}
+
+// eventLogRetained is how many events the log keeps in memory; older ones
+// are only in the file.
+const eventLogRetained = 10000
+
+// EventLog is an append-only log of the lifecycle of the main game. Events
+// are kept in memory and, once opened on a path, appended to it as JSON
+// lines.
+type EventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   uint64
+	file   *os.File
+	now    func() time.Time
+	// restored is the last sequence number read back from the file.
+	restored uint64
+}
+
+func NewEventLog() *EventLog {
+	return &EventLog{next: 1, now: time.Now}
+}
+
+// Events is the process-wide event log.
+var Events = NewEventLog()
+
+// Record stamps event with the next sequence number and the time, and
+// appends it.
+func (l *EventLog) Record(event Event) Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	event.Seq = l.next
+	l.next++
+	event.Time = l.now().UTC()
+	l.events = append(l.events, event)
+	if len(l.events) > eventLogRetained {
+		l.events = append([]Event(nil), l.events[len(l.events)-eventLogRetained:]...)
+	}
+	l.appendLocked(event)
+	return event
+}
+
+// appendLocked writes event to the file, if one is open.
+func (l *EventLog) appendLocked(event Event) {
+	if l.file == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[events] failed to encode event %d: %v", event.Seq, err)
+		return
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		log.Printf("[events] failed to append event %d: %v", event.Seq, err)
+	}
+}
+
+// EventQuery selects events: those after Since matching every filter set,
+// oldest first, at most Limit of them (no limit when zero or less).
+type EventQuery struct {
+	Since  uint64
+	Limit  int
+	Kind   EventKind
+	Actor  string
+	GameID uint64
+}
+
+func (q EventQuery) matches(event Event) bool {
+	return (q.Kind == "" || event.Kind == q.Kind) &&
+		(q.Actor == "" || event.Actor == q.Actor) &&
+		(q.GameID == 0 || event.GameID == q.GameID)
+}
+
+// Query returns the events q selects and whether more follow the last one.
+func (l *EventLog) Query(q EventQuery) ([]Event, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := len(l.events)
+	for start > 0 && l.events[start-1].Seq > q.Since {
+		start--
+	}
+	events := []Event{}
+	for _, event := range l.events[start:] {
+		if !q.matches(event) {
+			continue
+		}
+		if q.Limit > 0 && len(events) == q.Limit {
+			return events, true
+		}
+		events = append(events, event)
+	}
+	return events, false
+}
+
+// LastSeq returns the sequence number of the latest event, 0 when empty.
+func (l *EventLog) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next - 1
+}
+
+// RestoredSeq returns the last sequence number restored by Open; the
+// events after it were recorded by this process.
+func (l *EventLog) RestoredSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.restored
+}
+
+// Open restores the events already in the file at path, then appends every
+// new event to it. An empty path keeps the log in memory.
+func (l *EventLog) Open(path string) error {
+	if path == "" {
+		return nil
+	}
+	path = resolveTTPersistencePath(path)
+	if dir := filepath.Dir(path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create event log directory %s: %w", dir, err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event log %s: %w", path, err)
+	}
+	var restored []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// A line cut short by a crash.
+			continue
+		}
+		restored = append(restored, event)
+		if len(restored) > eventLogRetained {
+			restored = restored[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return fmt.Errorf("read event log %s: %w", path, err)
+	}
+	if err := endWithNewline(file); err != nil {
+		file.Close()
+		return fmt.Errorf("repair event log %s: %w", path, err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next := uint64(1)
+	if len(restored) > 0 {
+		l.restored = restored[len(restored)-1].Seq
+		next = l.restored + 1
+	}
+	// Events recorded before Open follow the restored ones, in the file too.
+	pending := l.events
+	for i := range pending {
+		pending[i].Seq = next
+		next++
+	}
+	l.events = append(restored, pending...)
+	l.next = next
+	l.file = file
+	for _, event := range pending {
+		l.appendLocked(event)
+	}
+	log.Printf("[events] restored %d events from %s", len(restored), path)
+	return nil
+}
+
+// Close stops appending to the file.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// endWithNewline terminates a last line cut short by a crash, so the next
+// event starts on a line of its own.
+func endWithNewline(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+func eventHash(state GameState) string {
+	return fmt.Sprintf("%016x", state.Hash)
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventLogQueryPagesAndFilters(t *testing.T) {
+	events := NewEventLog()
+	events.Record(Event{Kind: EventGameStarted, Actor: ActorAPI, GameID: 1})
+	events.Record(Event{Kind: EventMoveApplied, Actor: ActorHuman, GameID: 1})
+	events.Record(Event{Kind: EventMoveApplied, Actor: ActorAI, GameID: 1})
+	events.Record(Event{Kind: EventGameStarted, Actor: ActorAPI, GameID: 2})
+
+	page, more := events.Query(EventQuery{Since: 1, Limit: 2})
+	if len(page) != 2 || page[0].Seq != 2 || page[1].Seq != 3 || !more {
+		t.Fatalf("page after 1 = %+v (more %v), want seq 2 and 3 with more", page, more)
+	}
+	page, more = events.Query(EventQuery{Since: 3, Limit: 2})
+	if len(page) != 1 || page[0].Seq != 4 || more {
+		t.Fatalf("page after 3 = %+v (more %v), want seq 4 alone", page, more)
+	}
+	page, _ = events.Query(EventQuery{Kind: EventMoveApplied, Actor: ActorAI})
+	if len(page) != 1 || page[0].Seq != 3 {
+		t.Fatalf("ai moves = %+v, want seq 3", page)
+	}
+	page, _ = events.Query(EventQuery{GameID: 2})
+	if len(page) != 1 || page[0].Kind != EventGameStarted {
+		t.Fatalf("game 2 events = %+v, want its start", page)
+	}
+	if events.LastSeq() != 4 {
+		t.Fatalf("LastSeq = %d, want 4", events.LastSeq())
+	}
+}
+
+func TestEventLogRestoresFileAndAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	first := NewEventLog()
+	if err := first.Open(path); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	first.Record(Event{Kind: EventGameStarted, Actor: ActorAPI, GameID: 1})
+	first.Record(Event{Kind: EventMoveApplied, Actor: ActorHuman, GameID: 1, Move: &Move{X: 9, Y: 9}})
+	if err := first.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// A crash in the middle of a line.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	_, _ = file.WriteString(`{"seq":3,"kind":"mo`)
+	file.Close()
+
+	second := NewEventLog()
+	second.Record(Event{Kind: EventCachesFlushed, Actor: ActorAPI})
+	if err := second.Open(path); err != nil {
+		t.Fatalf("open again: %v", err)
+	}
+	second.Record(Event{Kind: EventWinDeclared, Actor: ActorHuman, GameID: 1})
+	second.Close()
+
+	third := NewEventLog()
+	if err := third.Open(path); err != nil {
+		t.Fatalf("open a third time: %v", err)
+	}
+	defer third.Close()
+	events, _ := third.Query(EventQuery{})
+	kinds := []EventKind{EventGameStarted, EventMoveApplied, EventCachesFlushed, EventWinDeclared}
+	if len(events) != len(kinds) {
+		t.Fatalf("restored %d events, want %d: %+v", len(events), len(kinds), events)
+	}
+	for i, event := range events {
+		if event.Seq != uint64(i+1) || event.Kind != kinds[i] {
+			t.Fatalf("event %d = seq %d %s, want seq %d %s", i, event.Seq, event.Kind, i+1, kinds[i])
+		}
+	}
+	if events[1].Move == nil || *events[1].Move != (Move{X: 9, Y: 9}) {
+		t.Fatalf("restored move = %v, want 9,9", events[1].Move)
+	}
+}
+
+func TestGameControllerLogsLifecycle(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	since := Events.LastSeq()
+	controller.StartGame(settings)
+	for _, move := range []Move{{X: 9, Y: 9}, {X: 10, Y: 10}} {
+		if ok, reason := controller.ApplyHumanMove(move); !ok {
+			t.Fatalf("move %v rejected: %s", move, reason)
+		}
+	}
+	update := settings
+	update.CaptureWinStones = 8
+	controller.UpdateSettings(update, false)
+
+	events, _ := Events.Query(EventQuery{Since: since, GameID: controller.GameID()})
+	kinds := []EventKind{EventGameStarted, EventMoveApplied, EventMoveApplied, EventSettingsChanged}
+	if len(events) != len(kinds) {
+		t.Fatalf("logged %+v, want kinds %v", events, kinds)
+	}
+	for i, event := range events {
+		if event.Kind != kinds[i] {
+			t.Fatalf("event %d is %s, want %s", i, event.Kind, kinds[i])
+		}
+	}
+	if events[0].Settings == nil || events[0].Settings.Black != ActorHuman {
+		t.Fatalf("start event settings = %+v", events[0].Settings)
+	}
+	second := events[2]
+	if second.Ply != 2 || second.Player != 2 || second.Actor != ActorHuman || *second.Move != (Move{X: 10, Y: 10}) {
+		t.Fatalf("second move event = %+v", second)
+	}
+	if second.Hash != eventHash(controller.State()) {
+		t.Fatalf("second move hash %s, want the live position's", second.Hash)
+	}
+	if events[3].Settings.CaptureWinStones != 8 {
+		t.Fatalf("settings event = %+v", events[3].Settings)
+	}
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// explorerMaxPly bounds how deep into each game the opening explorer looks.
+const explorerMaxPly = 40
+
+type ExplorerRequest struct {
+	Moves []Move `json:"moves"`
+}
+
+// ExplorerMove is one continuation of the explored position. Results and
+// WinRate (wins plus half the draws) are from the mover's side; Eval is the
+// transposition table score of the position after the move, also from the
+// mover's side, when the engine has searched it.
+type ExplorerMove struct {
+	Move       Move     `json:"move"`
+	Games      int      `json:"games"`
+	Frequency  float64  `json:"frequency"`
+	Wins       int      `json:"wins"`
+	Draws      int      `json:"draws"`
+	Losses     int      `json:"losses"`
+	WinRate    float64  `json:"win_rate"`
+	Eval       *float64 `json:"eval,omitempty"`
+	EvalDepth  int      `json:"eval_depth,omitempty"`
+	EngineBest bool     `json:"engine_best,omitempty"`
+}
+
+type Explorer struct {
+	Ply           int            `json:"ply"`
+	NextPlayer    int            `json:"next_player"`
+	Games         int            `json:"games"`
+	StoredGames   int            `json:"stored_games"`
+	Continuations []ExplorerMove `json:"continuations"`
+}
+
+// explorerHit is a game reaching an indexed position: the move played from
+// it, the symmetry mapping the game's board onto the canonical one and the
+// result for the side to move (1 win, 0 draw, -1 loss).
+type explorerHit struct {
+	next      Move
+	transform int
+	result    int
+}
+
+type explorerIndex struct {
+	version   uint64
+	games     int
+	positions map[uint64][]explorerHit
+}
+
+func newExplorerIndex(records []GameRecord, boardSize int) *explorerIndex {
+	index := &explorerIndex{positions: make(map[uint64][]explorerHit)}
+	for _, record := range records {
+		if record.Settings.BoardSize != boardSize {
+			continue
+		}
+		index.games++
+		forEachExplorerPosition(record, func(key uint64, hit explorerHit) {
+			index.positions[key] = append(index.positions[key], hit)
+		})
+	}
+	return index
+}
+
+// forEachExplorerPosition replays the opening of a finished game and reports
+// every position that has a continuation.
+func forEachExplorerPosition(record GameRecord, visit func(uint64, explorerHit)) {
+	state, err := renderStartState(record.Settings, record.Start)
+	if err != nil {
+		return
+	}
+	rules := NewRules(record.Settings)
+	state.Status = StatusRunning
+	winner := WinnerFromStatus(record.Status)
+	size := record.Settings.BoardSize
+	for ply, entry := range record.Entries {
+		if ply >= explorerMaxPly {
+			return
+		}
+		hit := explorerHit{next: entry.Move, transform: canonicalSymIndex(state.HashSym)}
+		switch winner {
+		case 0:
+		case PlayerToInt(state.ToMove):
+			hit.result = 1
+		default:
+			hit.result = -1
+		}
+		visit(ttKeyFor(state, size), hit)
+		if !applyMove(&state, rules, entry.Move, entry.Player) {
+			return
+		}
+	}
+}
+
+// inverseTransformCoord undoes transformCoord.
+func inverseTransformCoord(x, y, size int, transform symmetryTransform) (int, int) {
+	if transform.flip {
+		x = size - 1 - x
+	}
+	return transformCoord(x, y, size, symmetryTransform{rot: (4 - transform.rot) % 4})
+}
+
+// Explore returns the continuations played from the position after moves in
+// the stored games (plus extra, typically the live game), matching
+// transpositions and rotated or mirrored openings.
+func Explore(settings GameSettings, req ExplorerRequest, extra []GameRecord, tt *TranspositionTable) (Explorer, error) {
+	if len(req.Moves) >= explorerMaxPly {
+		return Explorer{}, fmt.Errorf("the explorer covers the first %d plies", explorerMaxPly)
+	}
+	state, rules, err := replayMoves(settings, req.Moves)
+	if err != nil {
+		return Explorer{}, err
+	}
+	size := settings.BoardSize
+	key := ttKeyFor(state, size)
+	transform := symmetryTransforms[canonicalSymIndex(state.HashSym)]
+	index := storedGames.index(size)
+	hits := index.positions[key]
+	games := index.games
+	for _, record := range extra {
+		if record.Settings.BoardSize != size || !record.Finished() {
+			continue
+		}
+		games++
+		forEachExplorerPosition(record, func(positionKey uint64, hit explorerHit) {
+			if positionKey == key {
+				hits = append(hits, hit)
+			}
+		})
+	}
+	explorer := Explorer{
+		Ply:           len(req.Moves),
+		NextPlayer:    PlayerToInt(state.ToMove),
+		Games:         len(hits),
+		StoredGames:   games,
+		Continuations: []ExplorerMove{},
+	}
+	if state.Status != StatusRunning {
+		return explorer, nil
+	}
+	byMove := map[Move]*ExplorerMove{}
+	continuation := func(move Move) *ExplorerMove {
+		stat, ok := byMove[move]
+		if !ok {
+			stat = &ExplorerMove{Move: move}
+			byMove[move] = stat
+		}
+		return stat
+	}
+	for _, hit := range hits {
+		cx, cy := transformCoord(hit.next.X, hit.next.Y, size, symmetryTransforms[hit.transform])
+		x, y := inverseTransformCoord(cx, cy, size, transform)
+		stat := continuation(Move{X: x, Y: y})
+		stat.Games++
+		switch hit.result {
+		case 1:
+			stat.Wins++
+		case -1:
+			stat.Losses++
+		default:
+			stat.Draws++
+		}
+	}
+	heuristicHash := heuristicHashFromConfig(GetConfig())
+	if tt != nil {
+		if entry, ok := tt.Peek(key, heuristicHash); ok && entry.BestMove.IsValid(size) {
+			best := Move{X: entry.BestMove.X, Y: entry.BestMove.Y}
+			if legal, _ := rules.IsLegal(state, best, state.ToMove); legal {
+				continuation(best).EngineBest = true
+			}
+		}
+	}
+	for move, stat := range byMove {
+		if len(hits) > 0 {
+			stat.Frequency = float64(stat.Games) / float64(len(hits))
+		}
+		if stat.Games > 0 {
+			stat.WinRate = (float64(stat.Wins) + float64(stat.Draws)/2) / float64(stat.Games)
+		}
+		if tt == nil {
+			continue
+		}
+		next := state.Clone()
+		if !applyMove(&next, rules, move, next.ToMove) || next.Status != StatusRunning {
+			continue
+		}
+		if entry, ok := tt.Peek(ttKeyFor(next, size), heuristicHash); ok {
+			// Table scores are from Black's side.
+			eval := entry.ScoreFloat()
+			if state.ToMove == PlayerWhite {
+				eval = -eval
+			}
+			stat.Eval = &eval
+			stat.EvalDepth = entry.Depth
+		}
+	}
+	for _, stat := range byMove {
+		explorer.Continuations = append(explorer.Continuations, *stat)
+	}
+	sort.Slice(explorer.Continuations, func(i, j int) bool {
+		a, b := explorer.Continuations[i], explorer.Continuations[j]
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		if a.EngineBest != b.EngineBest {
+			return a.EngineBest
+		}
+		if a.Move.Y != b.Move.Y {
+			return a.Move.Y < b.Move.Y
+		}
+		return a.Move.X < b.Move.X
+	})
+	return explorer, nil
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func explorerTestRecord(status GameStatus, moves ...Move) GameRecord {
+	record := GameRecord{Settings: DefaultGameSettings(), Status: status}
+	player := PlayerBlack
+	for _, move := range moves {
+		record.Entries = append(record.Entries, HistoryEntry{Move: move, Player: player})
+		player = otherPlayer(player)
+	}
+	return record
+}
+
+func TestExploreMergesRotatedOpenings(t *testing.T) {
+	prev := storedGames
+	storedGames = newGameDatabase(16)
+	t.Cleanup(func() { storedGames = prev })
+
+	storedGames.Add(explorerTestRecord(StatusBlackWon, Move{X: 9, Y: 9}, Move{X: 10, Y: 9}, Move{X: 10, Y: 11}, Move{X: 11, Y: 11}))
+	// The same opening rotated by 180 degrees.
+	storedGames.Add(explorerTestRecord(StatusWhiteWon, Move{X: 9, Y: 9}, Move{X: 8, Y: 9}, Move{X: 8, Y: 7}, Move{X: 7, Y: 7}))
+	storedGames.Add(explorerTestRecord(StatusRunning, Move{X: 9, Y: 9}))
+
+	explorer, err := Explore(DefaultGameSettings(), ExplorerRequest{Moves: []Move{{X: 9, Y: 9}, {X: 10, Y: 9}, {X: 10, Y: 11}}}, nil, nil)
+	if err != nil {
+		t.Fatalf("explore failed: %v", err)
+	}
+	if explorer.StoredGames != 2 || explorer.Games != 2 || explorer.NextPlayer != 2 {
+		t.Fatalf("unexpected explorer %+v", explorer)
+	}
+	if len(explorer.Continuations) != 1 {
+		t.Fatalf("expected the rotated games to share one continuation, got %+v", explorer.Continuations)
+	}
+	move := explorer.Continuations[0]
+	if move.Move != (Move{X: 11, Y: 11}) || move.Wins != 1 || move.Losses != 1 || move.WinRate != 0.5 || move.Frequency != 1 {
+		t.Fatalf("unexpected continuation %+v", move)
+	}
+
+	extra := explorerTestRecord(StatusDraw, Move{X: 9, Y: 9}, Move{X: 9, Y: 10})
+	explorer, err = Explore(DefaultGameSettings(), ExplorerRequest{Moves: []Move{{X: 9, Y: 9}}}, []GameRecord{extra}, nil)
+	if err != nil {
+		t.Fatalf("explore failed: %v", err)
+	}
+	if explorer.Games != 3 || len(explorer.Continuations) != 3 {
+		t.Fatalf("expected three games from the first move, got %+v", explorer)
+	}
+}
+
+func TestExploreEvalIsFromTheMoversSide(t *testing.T) {
+	prev := storedGames
+	storedGames = newGameDatabase(16)
+	t.Cleanup(func() { storedGames = prev })
+
+	settings := DefaultGameSettings()
+	record := explorerTestRecord(StatusDraw, Move{X: 9, Y: 9}, Move{X: 10, Y: 9})
+	tt := NewTranspositionTable(1024, 2)
+	heuristicHash := heuristicHashFromConfig(GetConfig())
+	for _, moves := range [][]Move{{{X: 9, Y: 9}}, {{X: 9, Y: 9}, {X: 10, Y: 9}}} {
+		child, _, err := replayMoves(settings, moves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Table scores are from Black's side: good for Black both times.
+		tt.Store(ttKeyFor(child, settings.BoardSize), heuristicHash, 5, 300, TTExact, Move{X: -1, Y: -1}, TTMeta{})
+	}
+
+	for _, tc := range []struct {
+		moves []Move
+		want  float64
+	}{
+		{nil, 300},
+		{[]Move{{X: 9, Y: 9}}, -300},
+	} {
+		explorer, err := Explore(settings, ExplorerRequest{Moves: tc.moves}, []GameRecord{record}, tt)
+		if err != nil {
+			t.Fatalf("explore failed: %v", err)
+		}
+		if len(explorer.Continuations) != 1 {
+			t.Fatalf("expected one continuation, got %+v", explorer.Continuations)
+		}
+		if move := explorer.Continuations[0]; move.Eval == nil || *move.Eval != tc.want || move.EvalDepth != 5 {
+			t.Fatalf("after %v expected eval %v, got %+v", tc.moves, tc.want, move)
+		}
+	}
+}
+
+func TestGameDatabasePersistenceRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiGameDatabasePath = filepath.Join(t.TempDir(), "game_database.gob")
+	db := newGameDatabase(2)
+	for i := 0; i < 3; i++ {
+		db.Add(explorerTestRecord(StatusBlackWon, Move{X: i, Y: 0}))
+	}
+	persistGameDatabase(cfg, db)
+
+	restored := newGameDatabase(8)
+	loadGameDatabase(cfg, restored)
+	if restored.Len() != 2 {
+		t.Fatalf("expected the two newest games to round-trip, got %d", restored.Len())
+	}
+	if games := restored.snapshot().Games; games[0].Entries[0].Move.X != 1 {
+		t.Fatalf("expected the oldest game to be dropped, got %+v", games[0])
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// fairPlayOpeningPlies are left out: opening moves are known by heart.
+	fairPlayOpeningPlies = 4
+	// fairPlayDecided is the win probability past which a position is left
+	// out, as almost every move keeps the result there.
+	fairPlayDecided = 0.95
+	// fairPlayBaseline is how often a strong honest player picks the
+	// engine's top choice in the positions that are counted.
+	fairPlayBaseline = 0.5
+	// fairPlayMinMoves is the fewest counted moves a side is flagged on.
+	fairPlayMinMoves = 12
+	// fairPlayFlagConfidence is the confidence a side is flagged at.
+	fairPlayFlagConfidence = 0.999
+)
+
+// FairPlaySide is how often one human side played the engine's top choice
+// (a "best" grade in the review) in the positions that left a real choice:
+// past the opening, not an only move and not already decided. Confidence is
+// the chance an honest player agrees less often than that, from the
+// binomial tail at fairPlayBaseline.
+type FairPlaySide struct {
+	Player     int     `json:"player"`
+	User       string  `json:"user,omitempty"`
+	Moves      int     `json:"moves"`
+	TopMoves   int     `json:"top_moves"`
+	MatchRate  float64 `json:"match_rate"`
+	Accuracy   float64 `json:"accuracy"`
+	Confidence float64 `json:"confidence"`
+	Flagged    bool    `json:"flagged"`
+}
+
+// FairPlayReport is the engine agreement check of a reviewed game's human
+// sides, kept with the game record for moderation.
+type FairPlayReport struct {
+	ReviewID    uint64         `json:"review_id"`
+	MatchID     string         `json:"match_id,omitempty"`
+	Baseline    float64        `json:"baseline"`
+	Sides       []FairPlaySide `json:"sides"`
+	Flagged     bool           `json:"flagged"`
+	CheckedAtMs int64          `json:"checked_at_ms"`
+}
+
+// FairPlay checks the sides of a finished review with the given colours.
+func (r Review) FairPlay(colors ...int) (FairPlayReport, bool) {
+	if r.Status != "done" || len(colors) == 0 {
+		return FairPlayReport{}, false
+	}
+	report := FairPlayReport{ReviewID: r.ID, Baseline: fairPlayBaseline, CheckedAtMs: time.Now().UnixMilli()}
+	for _, color := range colors {
+		side := fairPlaySide(r.Moves, color)
+		report.Flagged = report.Flagged || side.Flagged
+		report.Sides = append(report.Sides, side)
+	}
+	return report, true
+}
+
+func fairPlaySide(moves []ReviewMove, color int) FairPlaySide {
+	side := FairPlaySide{Player: color}
+	for _, move := range moves {
+		if move.Player != color || move.Ply <= fairPlayOpeningPlies || move.OnlyMove {
+			continue
+		}
+		if move.WinProb >= fairPlayDecided || move.WinProb <= 1-fairPlayDecided {
+			continue
+		}
+		side.Moves++
+		side.Accuracy += MoveAccuracy(move.Loss)
+		if move.Class == "best" {
+			side.TopMoves++
+		}
+	}
+	if side.Moves == 0 {
+		return side
+	}
+	side.MatchRate = float64(side.TopMoves) / float64(side.Moves)
+	side.Accuracy /= float64(side.Moves)
+	if side.TopMoves > 0 {
+		side.Confidence = 1 - binomialTail(side.Moves, side.TopMoves, fairPlayBaseline)
+	}
+	side.Flagged = side.Moves >= fairPlayMinMoves && side.Confidence >= fairPlayFlagConfidence
+	return side
+}
+
+// binomialTail is the chance of at least k successes in n trials of
+// probability p.
+func binomialTail(n, k int, p float64) float64 {
+	tail := 0.0
+	for i := k; i <= n; i++ {
+		lnN, _ := math.Lgamma(float64(n + 1))
+		lnI, _ := math.Lgamma(float64(i + 1))
+		lnRest, _ := math.Lgamma(float64(n - i + 1))
+		tail += math.Exp(lnN - lnI - lnRest + float64(i)*math.Log(p) + float64(n-i)*math.Log(1-p))
+	}
+	return math.Min(1, tail)
+}
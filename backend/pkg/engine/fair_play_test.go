@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+// fairPlayReview has black play moves graded with class from ply 5 on, in
+// balanced positions, and white a mix of best and worse moves.
+func fairPlayReview(blackClass string, plies int) Review {
+	review := Review{ID: 7, Status: "done"}
+	for ply := 1; ply <= plies; ply++ {
+		move := ReviewMove{Ply: ply, Player: 1, WinProb: 0.5, Class: blackClass}
+		if ply%2 == 0 {
+			move.Player, move.Class, move.Loss = 2, "best", 0
+			if ply%4 == 0 {
+				move.Class, move.Loss = "mistake", 0.15
+			}
+		}
+		review.Moves = append(review.Moves, move)
+	}
+	return review
+}
+
+func TestFairPlayFlagsConstantEngineAgreement(t *testing.T) {
+	report, ok := fairPlayReview("best", 60).FairPlay(1, 2)
+	if !ok || len(report.Sides) != 2 {
+		t.Fatalf("expected a report for both sides, got %+v %v", report, ok)
+	}
+	black, white := report.Sides[0], report.Sides[1]
+	if black.Moves != 28 || black.TopMoves != 28 || black.MatchRate != 1 {
+		t.Fatalf("expected the opening left out and every other black move counted, got %+v", black)
+	}
+	if !black.Flagged || !report.Flagged || black.Confidence < fairPlayFlagConfidence {
+		t.Fatalf("expected 28 top moves in 28 to be flagged, got %+v", black)
+	}
+	if white.Flagged || white.MatchRate != 0.5 {
+		t.Fatalf("expected white's even agreement not to be flagged, got %+v", white)
+	}
+}
+
+func TestFairPlaySkipsForcedAndDecidedMoves(t *testing.T) {
+	review := fairPlayReview("best", 60)
+	for i := range review.Moves {
+		switch {
+		case i%3 == 0:
+			review.Moves[i].OnlyMove = true
+		case i%3 == 1:
+			review.Moves[i].WinProb = 0.99
+		}
+	}
+	report, _ := review.FairPlay(1)
+	if side := report.Sides[0]; side.Moves != 9 || side.Flagged {
+		t.Fatalf("expected forced and decided moves left out and too few to flag, got %+v", side)
+	}
+	if _, ok := (Review{Status: "running"}).FairPlay(1); ok {
+		t.Fatalf("expected no report before the review is done")
+	}
+}
+
+func TestBinomialTail(t *testing.T) {
+	if got := binomialTail(10, 0, 0.5); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("expected the whole distribution, got %f", got)
+	}
+	if got := binomialTail(10, 10, 0.5); math.Abs(got-1.0/1024) > 1e-9 {
+		t.Fatalf("expected 1/1024 for ten in ten, got %f", got)
+	}
+}
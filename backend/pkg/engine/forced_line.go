@@ -0,0 +1,107 @@
+package engine
+
+// ForcedLineMove is one move of a forcing sequence, numbered from 1 in the
+// order it is played.
+type ForcedLineMove struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Player int `json:"player"`
+	Index  int `json:"index"`
+}
+
+// ForcedLine is the sequence a search expects a proven win to be played
+// through, from the move the search chose. Moves already played are
+// dropped from the front, so Moves starts with the next move and keeps the
+// numbering. Complete is false when the sequence stops short of the win.
+type ForcedLine struct {
+	Winner   int              `json:"winner"`
+	MateIn   int              `json:"mate_in"`
+	Moves    []ForcedLineMove `json:"moves"`
+	Complete bool             `json:"complete"`
+}
+
+// advance drops move from the front of the line, or reports that the game
+// left the line.
+func (l *ForcedLine) advance(move Move) bool {
+	if len(l.Moves) == 0 || l.Moves[0].X != move.X || l.Moves[0].Y != move.Y {
+		return false
+	}
+	l.Moves = l.Moves[1:]
+	return len(l.Moves) > 0
+}
+
+// forcedLineFor returns the sequence behind score, the root search score
+// of first in state, when the score is a proven win or loss. The moves come
+// from the transposition table entries config's search stored and, where
+// they run out, from immediate wins, forced blocks and proven forks.
+func forcedLineFor(state GameState, rules Rules, config Config, first Move, score float64) *ForcedLine {
+	mateIn, ok := mateDistance(score)
+	if !ok {
+		return nil
+	}
+	state = state.Clone()
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	size := state.Board.Size()
+	settings := AIScoreSettings{BoardSize: size, Player: state.ToMove, Cache: SharedSearchCache(), Config: liveAIConfig(config)}
+	applyGamePhase(state, rules, &settings)
+	tt := EnsureTT(settings.Cache, settings.Config)
+	heuristicHash := heuristicHashFromConfig(settings.Config)
+
+	winner := PlayerBlack
+	if score < 0 {
+		winner = PlayerWhite
+	}
+	line := &ForcedLine{Winner: PlayerToInt(winner), MateIn: mateIn}
+	move := first
+	for len(line.Moves) < mateIn && move.IsValid(size) {
+		player := state.ToMove
+		if !applyMoveWithUndo(&state, rules, move, player, nil) {
+			break
+		}
+		line.Moves = append(line.Moves, ForcedLineMove{X: move.X, Y: move.Y, Player: PlayerToInt(player), Index: len(line.Moves) + 1})
+		if state.Status != StatusRunning {
+			line.Complete = state.Status == StatusBlackWon && winner == PlayerBlack || state.Status == StatusWhiteWon && winner == PlayerWhite
+			break
+		}
+		move = forcedLineNext(state, rules, settings.Config, tt, heuristicHash, winner)
+	}
+	return line
+}
+
+// forcedLineNext is the move the line continues with from state, an
+// invalid move when there is none to tell.
+func forcedLineNext(state GameState, rules Rules, config Config, tt *TranspositionTable, heuristicHash uint64, winner PlayerColor) Move {
+	size := state.Board.Size()
+	if tt != nil {
+		if entry, ok := tt.Probe(ttKeyFor(state, size), heuristicHash); ok && entry.BestMove.IsValid(size) {
+			if legal, _ := rules.IsLegal(state, entry.BestMove, state.ToMove); legal {
+				return entry.BestMove
+			}
+		}
+	}
+	wins := findImmediateWinMovesCached(nil, state, rules, winner, size, config)
+	if state.ToMove == winner {
+		if len(wins) > 0 {
+			return wins[0]
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				move := Move{X: x, Y: y}
+				if _, ok := proveFork(&state, rules, move, winner, size, config); ok {
+					return move
+				}
+			}
+		}
+		return Move{X: -1, Y: -1}
+	}
+	// The loser blocks a five, the only one there is or the first of those
+	// it cannot all stop.
+	for _, move := range wins {
+		if legal, _ := rules.IsLegal(state, move, state.ToMove); legal {
+			return move
+		}
+	}
+	return Move{X: -1, Y: -1}
+}
@@ -0,0 +1,35 @@
+package engine
+
+import "testing"
+
+func TestForcedLineFollowsAForkToTheWin(t *testing.T) {
+	state, rules := forkTestState(false)
+	config := DefaultConfig()
+	config.AiUseTtCache = false
+
+	if line := forcedLineFor(state, rules, config, Move{X: 8, Y: 5}, 1200); line != nil {
+		t.Fatalf("expected no line without a proven win, got %+v", line)
+	}
+	line := forcedLineFor(state, rules, config, Move{X: 8, Y: 5}, winScoreAt(PlayerBlack, 5))
+	if line == nil || !line.Complete || line.Winner != 1 || line.MateIn != 5 || len(line.Moves) != 5 {
+		t.Fatalf("expected a complete 5-move line for black, got %+v", line)
+	}
+	for i, move := range line.Moves {
+		if move.Index != i+1 || move.Player != 1+i%2 {
+			t.Fatalf("expected move %d numbered and alternating, got %+v", i, move)
+		}
+	}
+	if block := line.Moves[1]; block.X != 9 || block.Y != 5 {
+		t.Fatalf("expected white to block the four at (9,5), got %+v", block)
+	}
+	if state.Board.At(8, 5) != CellEmpty {
+		t.Fatalf("expected the position to be left as it was")
+	}
+
+	if !line.advance(Move{X: 8, Y: 5}) || line.Moves[0].Index != 2 {
+		t.Fatalf("expected the line to drop its played move, got %+v", line.Moves)
+	}
+	if line.advance(Move{X: 0, Y: 0}) {
+		t.Fatalf("expected a move off the line to end it")
+	}
+}
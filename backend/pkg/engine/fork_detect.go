@@ -0,0 +1,110 @@
+package engine
+
+// forkProofFours is how many fours in a row a fork proof plays: the fork
+// itself and, for a four made together with an open three, the open four
+// that follows the block.
+const forkProofFours = 2
+
+// forkPlies reports whether a node depthFromRoot plies from the root is
+// shallow enough for moves to be checked for forks.
+func forkPlies(config Config, depthFromRoot int) bool {
+	return depthFromRoot < config.AiForkPlies
+}
+
+// makesFour reports whether player's stone at move completes four of a
+// five-cell window whose fifth cell is empty, split fours included.
+func makesFour(board Board, move Move, player PlayerColor) bool {
+	cell := CellFromPlayer(player)
+	directions := [4][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+	for _, dir := range directions {
+		for start := -4; start <= 0; start++ {
+			stones, empty := 0, 0
+			for i := start; i < start+5; i++ {
+				x := move.X + i*dir[0]
+				y := move.Y + i*dir[1]
+				if !board.InBounds(x, y) {
+					break
+				}
+				switch {
+				case i == 0 || board.At(x, y) == cell:
+					stones++
+				case board.At(x, y) == CellEmpty:
+					empty++
+				}
+			}
+			if stones == 4 && empty == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// proveFork reports whether move makes a four player wins with whatever the
+// opponent answers, and in how many plies from move the five comes at the
+// latest: 3 for two fours at once, 5 for a four and an open three. Moves
+// that win outright are not forks.
+func proveFork(state *GameState, rules Rules, move Move, player PlayerColor, boardSize int, config Config) (int, bool) {
+	return proveForkWithin(state, rules, move, player, boardSize, config, forkProofFours)
+}
+
+func proveForkWithin(state *GameState, rules Rules, move Move, player PlayerColor, boardSize int, config Config, fours int) (int, bool) {
+	if fours <= 0 || !state.Board.IsEmpty(move.X, move.Y) || !makesFour(state.Board, move, player) {
+		return 0, false
+	}
+	var undo searchMoveUndo
+	if !applyMoveWithUndo(state, rules, move, player, &undo) {
+		return 0, false
+	}
+	defer undoMoveWithUndo(state, undo)
+	if state.Status != StatusRunning {
+		return 0, false
+	}
+	opponent := otherPlayer(player)
+	if hasImmediateWinCached(nil, *state, rules, opponent, boardSize, config) {
+		return 0, false
+	}
+	wins := findImmediateWinMovesCached(nil, *state, rules, player, boardSize, config)
+	if len(wins) == 0 {
+		return 0, false
+	}
+	// Any other answer leaves a five to play: only blocking a winning cell
+	// or capturing a stone out of the four can hold.
+	plies := 3
+	answers := append(wins, findCaptureMoves(*state, rules, opponent)...)
+	for _, answer := range answers {
+		var answerUndo searchMoveUndo
+		if !applyMoveWithUndo(state, rules, answer, opponent, &answerUndo) {
+			continue
+		}
+		held := state.Status == StatusRunning
+		if held && !hasImmediateWinCached(nil, *state, rules, player, boardSize, config) {
+			next, ok := forkFollowUp(state, rules, player, boardSize, config, fours-1)
+			held = ok
+			if ok && next+2 > plies {
+				plies = next + 2
+			}
+		}
+		undoMoveWithUndo(state, answerUndo)
+		if !held {
+			return 0, false
+		}
+	}
+	return plies, true
+}
+
+// forkFollowUp looks for a four of player's that proveForkWithin proves,
+// such as the open four an open three becomes once its four was blocked.
+func forkFollowUp(state *GameState, rules Rules, player PlayerColor, boardSize int, config Config, fours int) (int, bool) {
+	if fours <= 0 {
+		return 0, false
+	}
+	for y := 0; y < boardSize; y++ {
+		for x := 0; x < boardSize; x++ {
+			if plies, ok := proveForkWithin(state, rules, Move{X: x, Y: y}, player, boardSize, config, fours); ok {
+				return plies, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,77 @@
+package engine
+
+import "testing"
+
+// forkTestState has black three in a row on row 5, closed by white at
+// (4,5), and (8,6),(8,7) on column 8: black at (8,5) makes a four and an
+// open three, and with column stones added a second four.
+func forkTestState(closeColumn bool) (GameState, Rules) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.Board.Set(4, 5, CellWhite)
+	for x := 5; x <= 7; x++ {
+		state.Board.Set(x, 5, CellBlack)
+	}
+	state.Board.Set(8, 6, CellBlack)
+	state.Board.Set(8, 7, CellBlack)
+	if closeColumn {
+		state.Board.Set(8, 8, CellBlack)
+		state.Board.Set(8, 9, CellWhite)
+	}
+	return state, rules
+}
+
+func TestProveForkFindsDoubleFours(t *testing.T) {
+	state, rules := forkTestState(true)
+	config := DefaultConfig()
+	fork := Move{X: 8, Y: 5}
+
+	plies, ok := proveFork(&state, rules, fork, PlayerBlack, 19, config)
+	if !ok || plies != 3 {
+		t.Fatalf("expected two fours to win in 3 plies, got %d %v", plies, ok)
+	}
+	if state.Board.At(8, 5) != CellEmpty || state.ToMove != PlayerBlack {
+		t.Fatalf("expected the proof to leave the position as it was")
+	}
+	if _, ok := proveFork(&state, rules, Move{X: 9, Y: 5}, PlayerBlack, 19, config); ok {
+		t.Fatalf("a single four should not be a fork")
+	}
+
+	// A white four black does not block outlives the fork.
+	for y := 0; y < 4; y++ {
+		state.Board.Set(0, y, CellWhite)
+	}
+	if _, ok := proveFork(&state, rules, fork, PlayerBlack, 19, config); ok {
+		t.Fatalf("a fork should not hold against an immediate win")
+	}
+}
+
+func TestProveForkFindsFourAndOpenThree(t *testing.T) {
+	state, rules := forkTestState(false)
+	plies, ok := proveFork(&state, rules, Move{X: 8, Y: 5}, PlayerBlack, 19, DefaultConfig())
+	if !ok || plies != 5 {
+		t.Fatalf("expected a four and an open three to win in 5 plies, got %d %v", plies, ok)
+	}
+}
+
+func TestMinimaxScoresProvenForksAsWins(t *testing.T) {
+	state, rules := forkTestState(false)
+	config := DefaultConfig()
+	config.AiTimeBudgetMs = 0
+	cache := newAISearchCache()
+	ctx := minimaxContext{rules: rules, settings: AIScoreSettings{Config: config, BoardSize: 19, Player: PlayerBlack, Cache: &cache}}
+
+	if got, want := minimax(&state, ctx, 1, PlayerBlack, 0, -winScore*2, winScore*2), winScoreAt(PlayerBlack, 5); got != want {
+		t.Fatalf("expected the fork to score %.0f at depth 1, got %.0f", want, got)
+	}
+
+	fresh := newAISearchCache()
+	ctx.settings.Cache = &fresh
+	ctx.settings.Config.AiForkPlies = 0
+	if got := minimax(&state, ctx, 1, PlayerBlack, 0, -winScore*2, winScore*2); isMateScore(got) {
+		t.Fatalf("expected no win without fork proofs at depth 1, got %.0f", got)
+	}
+}
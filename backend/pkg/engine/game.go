@@ -1,7 +1,8 @@
-package main
+package engine
 
 import (
 	"fmt"
+	"math/rand"
 	"time"
 )
 
@@ -14,10 +15,35 @@ type Game struct {
 	whitePlayer        IPlayer
 	moveSuggestionAI   *AIPlayer
 	moveSuggestionHash uint64
-	turnStart          time.Time
-	coordWidth         int
-	captureWidth       int
-	timeWidth          int
+	// moveSuggestionCharged is the last position counted against
+	// AiSuggestPerMinute, moveSuggestionCapped one the cap turned away.
+	moveSuggestionCharged uint64
+	moveSuggestionCapped  uint64
+	turnStart             time.Time
+	coordWidth            int
+	captureWidth          int
+	timeWidth             int
+	startPosition         *StartPosition
+	// forcedLine is what is left of the last forcing sequence an engine
+	// side announced, nil once the game leaves it.
+	forcedLine *ForcedLine
+	// paced is an engine move held back until its visible thinking time
+	// is up.
+	paced *pacedMove
+	// simulated marks an engine-vs-engine game played by Simulate, whose
+	// positions are left out of the position frequencies.
+	simulated bool
+}
+
+// pacedMove is an engine move waiting for playAt, with what the search
+// found about it and how long it took.
+type pacedMove struct {
+	move      Move
+	score     *float64
+	line      *ForcedLine
+	search    *MoveSearchStats
+	computeMs float64
+	playAt    time.Time
 }
 
 func NewGame(settings GameSettings) Game {
@@ -28,10 +54,16 @@ func NewGame(settings GameSettings) Game {
 
 func (g *Game) Reset(settings GameSettings) {
 	g.stopMoveSuggestion(nil)
+	if settings.Seed == 0 {
+		settings.Seed = newGameSeed()
+	}
 	g.settings = settings
 	g.rules = NewRules(settings)
 	g.state.Reset(settings)
 	g.history.Clear()
+	g.startPosition = nil
+	g.forcedLine = nil
+	g.paced = nil
 	g.createPlayers()
 	g.computeLogWidths()
 	g.turnStart = time.Now()
@@ -47,6 +79,29 @@ func (g *Game) Start() {
 	}
 }
 
+// StartFromPosition resets the game with settings and starts it from the
+// given position instead of the empty board. The game is left untouched when
+// the position is invalid.
+func (g *Game) StartFromPosition(settings GameSettings, position StartPosition) error {
+	state, err := position.State(settings)
+	if err != nil {
+		return err
+	}
+	g.Reset(settings)
+	g.state = state
+	g.startPosition = &position
+	g.Start()
+	return nil
+}
+
+// StartPosition returns the position the game was seeded from, if any.
+func (g *Game) StartPosition() (StartPosition, bool) {
+	if g.startPosition == nil {
+		return StartPosition{}, false
+	}
+	return *g.startPosition, true
+}
+
 func (g *Game) State() GameState {
 	return g.state.Clone()
 }
@@ -55,6 +110,17 @@ func (g *Game) History() MoveHistory {
 	return g.history
 }
 
+// ForcedLine returns the rest of the forcing sequence an engine side is
+// playing, from the next move on.
+func (g *Game) ForcedLine() (ForcedLine, bool) {
+	if g.forcedLine == nil || g.state.Status != StatusRunning {
+		return ForcedLine{}, false
+	}
+	line := *g.forcedLine
+	line.Moves = append([]ForcedLineMove(nil), line.Moves...)
+	return line, true
+}
+
 func (g *Game) TurnStartedAtMs() int64 {
 	if g.turnStart.IsZero() {
 		return 0
@@ -62,7 +128,23 @@ func (g *Game) TurnStartedAtMs() int64 {
 	return g.turnStart.UnixMilli()
 }
 
+// pauseTurnClock takes d off the time the current turn has lasted, for a
+// side that was away through it.
+func (g *Game) pauseTurnClock(d time.Duration) {
+	if d > 0 && !g.turnStart.IsZero() {
+		g.turnStart = g.turnStart.Add(d)
+	}
+}
+
 func (g *Game) TryApplyMove(move Move) (bool, string) {
+	return g.applyMove(move, nil, -1, nil)
+}
+
+// applyMove plays move, recording score, the mover's search score, and
+// search, what the search cost, in its history entry when there are any,
+// and computeMs, how long the engine took to find it, when not negative;
+// otherwise the move took as long as it was visibly thought about.
+func (g *Game) applyMove(move Move, score *float64, computeMs float64, search *MoveSearchStats) (bool, string) {
 	if g.state.Status != StatusRunning {
 		return false, "game not running"
 	}
@@ -84,6 +166,9 @@ func (g *Game) TryApplyMove(move Move) (bool, string) {
 		g.state.LastMessage = "Illegal move: " + reason
 		return false, g.state.LastMessage
 	}
+	if !isAiMove && g.sideIsHuman(g.state.ToMove) {
+		humanMoves.Record(g.state, move)
+	}
 	g.stopMoveSuggestion(nil)
 	g.state.LastMessage = ""
 	elapsedMs := float64(time.Since(g.turnStart).Milliseconds())
@@ -93,10 +178,18 @@ func (g *Game) TryApplyMove(move Move) (bool, string) {
 	g.state.HasLastMove = true
 	g.state.MustCapture = false
 	g.state.ForcedCaptureMoves = nil
+	g.state.PendingAlignment = nil
 	g.state.WinningLine = nil
 	g.state.WinningCapturePair = nil
+	g.state.clearCaptureWin()
 
-	entry := HistoryEntry{Move: move, Player: g.state.ToMove, ElapsedMs: elapsedMs, IsAi: isAiMove, Depth: move.Depth}
+	if computeMs < 0 || computeMs > elapsedMs {
+		computeMs = elapsedMs
+	}
+	entry := HistoryEntry{Move: move, Player: g.state.ToMove, ElapsedMs: elapsedMs, ComputeMs: computeMs, IsAi: isAiMove, Depth: move.Depth, Search: search}
+	if score != nil {
+		entry.Score, entry.Scored = *score, true
+	}
 	entry.CapturedPositions = g.rules.FindCaptures(g.state.Board, move, cell)
 	entry.CapturedCount = len(entry.CapturedPositions)
 	for _, captured := range entry.CapturedPositions {
@@ -118,8 +211,12 @@ func (g *Game) TryApplyMove(move Move) (bool, string) {
 	}
 	g.logMovePlayed(move, elapsedMs, isAiMove, totalCaptured, capturedCount)
 	g.history.Push(entry)
+	if g.forcedLine != nil && !g.forcedLine.advance(move) {
+		g.forcedLine = nil
+	}
 	requireCapture := false
 	forcedCaptures := []Move{}
+	var pendingAlignment []Move
 
 	captureCount := g.state.CapturedBlack
 	if g.state.ToMove == PlayerWhite {
@@ -159,53 +256,59 @@ func (g *Game) TryApplyMove(move Move) (bool, string) {
 		}
 		forcedCaptures = g.rules.FindAlignmentBreakCaptures(g.state, opponent)
 		requireCapture = len(forcedCaptures) > 0
+		if requireCapture {
+			pendingAlignment, _ = g.rules.FindAlignmentLine(g.state.Board, move)
+		}
 	}
 	opponentCaptureCount := g.state.CapturedBlack
 	if opponent == PlayerWhite {
 		opponentCaptureCount = g.state.CapturedWhite
 	}
-	if forcedMove, forcedCaptures, ok := g.rules.FindImmediateCaptureWinMove(g.state, opponent, opponentCaptureCount); ok {
-		// Commit current move first so forced opponent capture is applied on top of it.
+	captureWinMove, captureWinCaptures, captureWin := g.rules.FindImmediateCaptureWinMove(g.state, opponent, opponentCaptureCount)
+	if captureWin && GetConfig().AutoPlayCaptureWin {
+		// Commit current move first so forced opponent capture is applied on
+		// top of it, with the opponent to move.
+		g.state.ToMove = opponent
 		UpdateHashAfterMove(&g.state, move, prevToMove, entry.CapturedPositions, prevToMove, prevCapturedBlack, prevCapturedWhite)
 
 		forcedPrevCapturedBlack := g.state.CapturedBlack
 		forcedPrevCapturedWhite := g.state.CapturedWhite
-		g.state.ToMove = opponent
-		g.state.Board.Set(forcedMove.X, forcedMove.Y, CellFromPlayer(opponent))
-		for _, captured := range forcedCaptures {
+		g.state.Board.Set(captureWinMove.X, captureWinMove.Y, CellFromPlayer(opponent))
+		for _, captured := range captureWinCaptures {
 			g.state.Board.Remove(captured.X, captured.Y)
 		}
 		if opponent == PlayerBlack {
-			g.state.CapturedBlack += len(forcedCaptures)
+			g.state.CapturedBlack += len(captureWinCaptures)
 		} else {
-			g.state.CapturedWhite += len(forcedCaptures)
+			g.state.CapturedWhite += len(captureWinCaptures)
 		}
 		forcedEntry := HistoryEntry{
-			Move:              forcedMove,
+			Move:              captureWinMove,
 			Player:            opponent,
 			ElapsedMs:         0,
 			IsAi:              !g.playerForColor(opponent).IsHuman(),
-			CapturedCount:     len(forcedCaptures),
-			CapturedPositions: append([]Move(nil), forcedCaptures...),
+			CapturedCount:     len(captureWinCaptures),
+			CapturedPositions: append([]Move(nil), captureWinCaptures...),
+			AutoPlayed:        true,
 		}
 		g.history.Push(forcedEntry)
-		g.logMovePlayed(forcedMove, 0, forcedEntry.IsAi, func() int {
+		g.logMovePlayed(captureWinMove, 0, forcedEntry.IsAi, func() int {
 			if opponent == PlayerBlack {
 				return g.state.CapturedBlack
 			}
 			return g.state.CapturedWhite
-		}(), len(forcedCaptures))
+		}(), len(captureWinCaptures))
 		g.logWin(opponent, "capture-threat")
 		if opponent == PlayerBlack {
 			g.state.Status = StatusBlackWon
 		} else {
 			g.state.Status = StatusWhiteWon
 		}
-		g.state.LastMove = forcedMove
+		g.state.LastMove = captureWinMove
 		g.state.HasLastMove = true
 		g.state.WinningLine = nil
-		g.state.WinningCapturePair = append([]Move(nil), forcedCaptures...)
-		UpdateHashAfterMove(&g.state, forcedMove, opponent, forcedCaptures, opponent, forcedPrevCapturedBlack, forcedPrevCapturedWhite)
+		g.state.WinningCapturePair = append([]Move(nil), captureWinCaptures...)
+		UpdateHashAfterMove(&g.state, captureWinMove, opponent, captureWinCaptures, opponent, forcedPrevCapturedBlack, forcedPrevCapturedWhite)
 		notifyAiCaches()
 		return true, ""
 	}
@@ -223,13 +326,25 @@ func (g *Game) TryApplyMove(move Move) (bool, string) {
 	if requireCapture {
 		g.state.MustCapture = true
 		g.state.ForcedCaptureMoves = forcedCaptures
+		g.state.PendingAlignment = pendingAlignment
+	}
+	if captureWin {
+		g.state.HasCaptureWin = true
+		g.state.CaptureWinMove = captureWinMove
+		g.state.CaptureWinCaptures = captureWinCaptures
 	}
 	g.turnStart = time.Now()
+	if !g.simulated {
+		recordPlayedPosition(g.state)
+	}
 	notifyAiCaches()
 	return true, ""
 }
 
-func (g *Game) Tick(ghostEnabled bool, ghostSink func(ghostPayload)) bool {
+// Tick advances the game: it plays a pending human move, or starts the
+// engine's search and plays its move once found and paced. scoreSink, when
+// set, gets the search's evaluation as its depths complete.
+func (g *Game) Tick(ghostEnabled bool, ghostSink func(GhostPayload), scoreSink func(ScoreUpdate)) bool {
 	if g.state.Status != StatusRunning {
 		g.stopMoveSuggestion(ghostSink)
 		return false
@@ -256,27 +371,46 @@ func (g *Game) Tick(ghostEnabled bool, ghostSink func(ghostPayload)) bool {
 	g.stopMoveSuggestion(ghostSink)
 	ai, ok := player.(*AIPlayer)
 	if ok {
-		if ai.HasMoveReady() {
-			move := ai.TakeMove()
-			applied, _ := g.TryApplyMove(move)
-			return applied
+		if g.paced == nil {
+			if ai.HasMoveReady() {
+				line, search := ai.TakeForcedLine(), ai.TakeSearchStats()
+				move, score, scored := ai.TakeScoredMove()
+				g.paced = &pacedMove{move: move, line: line, search: search}
+				if scored {
+					g.paced.score = &score
+				}
+			} else if move, ok := ai.TakePonderedMove(g.state.Clone(), g.rules); ok {
+				g.paced = &pacedMove{move: move}
+			}
+			if g.paced != nil {
+				g.pace(g.paced, GetConfig())
+			}
 		}
-		if move, ok := ai.TakePonderedMove(g.state.Clone(), g.rules); ok {
-			applied, _ := g.TryApplyMove(move)
+		if g.paced != nil {
+			if time.Now().Before(g.paced.playAt) {
+				return false
+			}
+			paced := g.paced
+			g.paced = nil
+			if paced.line != nil {
+				g.forcedLine = paced.line
+			}
+			applied, _ := g.applyMove(paced.move, paced.score, paced.computeMs, paced.search)
 			return applied
 		}
 		if !ai.IsThinking() {
 			var sink func(GameState)
 			if ghostEnabled && ghostSink != nil {
 				sink = func(gs GameState) {
-					ghostSink(ghostPayload{
-						Mode:      "preview_board",
-						Positions: ghostPositionsFromBoard(gs.Board),
+					ghostSink(GhostPayload{
+						Mode:      GhostModePreviewBoard,
+						Positions: GhostPositionsFromBoard(gs.Board),
 						Active:    true,
 					})
 				}
 			}
-			ai.StartThinking(g.state.Clone(), g.rules, sink, nil)
+			depthSink := scoreUpdateSink(g.state, g.history.Size()+1, GetConfig(), scoreSink)
+			ai.StartThinking(g.state.Clone(), g.rules, sink, depthSink)
 		}
 		return false
 	}
@@ -285,6 +419,26 @@ func (g *Game) Tick(ghostEnabled bool, ghostSink func(ghostPayload)) bool {
 	return applied
 }
 
+// pace sets when a move the engine just found is played: against a human,
+// not before the turn has lasted a visible thinking time drawn between
+// AiMinMoveMs and AiMaxMoveMs, so moves found at once from the caches do
+// not land instantly. The time actually spent is kept as computeMs.
+func (g *Game) pace(paced *pacedMove, config Config) {
+	now := time.Now()
+	paced.computeMs = float64(now.Sub(g.turnStart).Milliseconds())
+	paced.playAt = now
+	if g.sideIsHuman(PlayerBlack) == g.sideIsHuman(PlayerWhite) || config.AiMinMoveMs <= 0 && config.AiMaxMoveMs <= 0 {
+		return
+	}
+	visibleMs := config.AiMinMoveMs
+	if config.AiMaxMoveMs > visibleMs {
+		visibleMs += rand.Intn(config.AiMaxMoveMs - visibleMs + 1)
+	}
+	if playAt := g.turnStart.Add(time.Duration(visibleMs) * time.Millisecond); playAt.After(now) {
+		paced.playAt = playAt
+	}
+}
+
 func (g *Game) SubmitHumanMove(move Move) bool {
 	player := g.currentPlayer()
 	if player == nil || !player.IsHuman() {
@@ -307,6 +461,15 @@ func (g *Game) currentPlayer() IPlayer {
 	return g.playerForColor(g.state.ToMove)
 }
 
+// sideIsHuman reports whether the settings give color to a human. Headless
+// games feed every move in by hand, so their players cannot tell.
+func (g *Game) sideIsHuman(color PlayerColor) bool {
+	if color == PlayerBlack {
+		return g.settings.BlackType == PlayerHuman
+	}
+	return g.settings.WhiteType == PlayerHuman
+}
+
 func (g *Game) playerForColor(color PlayerColor) IPlayer {
 	if color == PlayerBlack {
 		return g.blackPlayer
@@ -315,11 +478,20 @@ func (g *Game) playerForColor(color PlayerColor) IPlayer {
 }
 
 func (g *Game) createPlayers() {
+	// The players of the previous game may still be searching; they are
+	// stopped without waiting so a new game starts at once.
+	for _, player := range []IPlayer{g.blackPlayer, g.whitePlayer} {
+		if ai, ok := player.(*AIPlayer); ok {
+			ai.Close()
+		}
+	}
 	if g.settings.BlackType == PlayerHuman {
 		g.blackPlayer = NewHumanPlayer()
 	} else {
 		ai := NewAIPlayer()
 		ai.SetHeuristicsOverride(g.settings.BlackHeuristics)
+		ai.SetSeed(g.settings.Seed)
+		ai.component = Components.Register("ai_black", "ai_player", aiStaleAfter, nil)
 		g.blackPlayer = ai
 	}
 	if g.settings.WhiteType == PlayerHuman {
@@ -327,13 +499,40 @@ func (g *Game) createPlayers() {
 	} else {
 		ai := NewAIPlayer()
 		ai.SetHeuristicsOverride(g.settings.WhiteHeuristics)
+		// Keep the sides apart so an AI-vs-AI game does not mirror its
+		// random choices.
+		ai.SetSeed(g.settings.Seed ^ whiteSeedMask)
+		ai.component = Components.Register("ai_white", "ai_player", aiStaleAfter, nil)
 		g.whitePlayer = ai
 	}
+	selfPlay := g.settings.BlackType == PlayerAI && g.settings.WhiteType == PlayerAI
+	for _, player := range []IPlayer{g.blackPlayer, g.whitePlayer} {
+		if ai, ok := player.(*AIPlayer); ok {
+			ai.SetSelfPlay(selfPlay)
+		}
+	}
 	if g.moveSuggestionAI == nil {
-		g.moveSuggestionAI = NewAIPlayer()
+		g.moveSuggestionAI = newSuggestionAIPlayer()
+	}
+}
+
+// setOpponentModel hands model to the engine sides of the game.
+func (g *Game) setOpponentModel(model *OpponentModel) {
+	for _, player := range []IPlayer{g.blackPlayer, g.whitePlayer} {
+		if ai, ok := player.(*AIPlayer); ok {
+			ai.SetOpponentModel(model)
+		}
 	}
 }
 
+const whiteSeedMask = 0x5bd1e995
+
+// newGameSeed returns a non-zero seed for a game started without one,
+// small enough to survive a round trip through a JavaScript number.
+func newGameSeed() int64 {
+	return rand.Int63n(1<<53-1) + 1
+}
+
 func (g *Game) syncAIPlayersToCurrentState() {
 	if aiBlack, ok := g.blackPlayer.(*AIPlayer); ok {
 		aiBlack.OnMoveApplied(g.state, g.rules)
@@ -398,7 +597,7 @@ func (g *Game) AiThinking() bool {
 	player := g.currentPlayer()
 	ai, ok := player.(*AIPlayer)
 	if ok {
-		return ai.IsThinking()
+		return ai.IsThinking() || g.paced != nil
 	}
 	return false
 }
@@ -426,9 +625,18 @@ func (g *Game) ResetForConfigChange() {
 	}
 }
 
-func (g *Game) startMoveSuggestion(ghostSink func(ghostPayload)) {
+func (g *Game) startMoveSuggestion(ghostSink func(GhostPayload)) {
 	if g.moveSuggestionAI == nil {
-		g.moveSuggestionAI = NewAIPlayer()
+		g.moveSuggestionAI = newSuggestionAIPlayer()
+	}
+	config := GetConfig()
+	if suggestionsSuspended(config) {
+		// Picked up again from the transposition table once the engine's
+		// own search is done.
+		if g.moveSuggestionAI.IsThinking() {
+			g.moveSuggestionAI.StopThinking()
+		}
+		return
 	}
 	state := g.state.Clone()
 	if state.Hash == 0 {
@@ -438,35 +646,44 @@ func (g *Game) startMoveSuggestion(ghostSink func(ghostPayload)) {
 	if g.moveSuggestionHash == hash && (g.moveSuggestionAI.IsThinking() || g.moveSuggestionAI.HasMoveReady()) {
 		return
 	}
+	if g.moveSuggestionCapped == hash && !suggestionStarts.available(time.Now(), config.AiSuggestPerMinute) {
+		return
+	}
 	g.moveSuggestionAI.StopThinking()
 	g.moveSuggestionHash = hash
 	historyLen := g.history.Size()
-	toMove := playerToInt(state.ToMove)
-	suggestionConfig := GetConfig()
-	suggestionConfig.AiDepth = 10
-	suggestionConfig.AiMaxDepth = 10
+	toMove := PlayerToInt(state.ToMove)
+	depth := suggestDepth(config)
+	suggestionConfig := config
+	suggestionConfig.AiDepth = depth
+	suggestionConfig.AiMaxDepth = depth
 	suggestionConfig.AiMinDepth = 1
-	suggestionConfig.AiTimeoutMs = 0
+	suggestionConfig.AiTimeoutMs = config.AiSuggestBudgetMs
 	suggestionConfig.AiTimeBudgetMs = 0
+	suggestionConfig.AiTargetElo = 0
+	suggestionConfig.AiHumanMoveBlend = 0
+	suggestionConfig.AiSelfPlayTemp = 0
 	heuristicHash := heuristicHashFromConfig(suggestionConfig)
-	if tt := ensureTT(SharedSearchCache(), suggestionConfig); tt != nil {
+	if tt := EnsureTT(SharedSearchCache(), suggestionConfig); tt != nil {
 		if entry, ok := tt.Probe(hash, heuristicHash); ok && entry.Flag == TTExact && entry.BestMove.IsValid(state.Board.Size()) {
 			if legal, _ := g.rules.IsLegal(state, entry.BestMove, state.ToMove); legal {
 				knownDepth := entry.Depth
-				if knownDepth > 10 {
-					knownDepth = 10
+				if knownDepth > depth {
+					knownDepth = depth
 				}
 				if knownDepth > 0 {
-					ghostSink(ghostPayload{
+					ghostSink(GhostPayload{
 						Mode:       "best_move",
-						Best:       &ghostCell{X: entry.BestMove.X, Y: entry.BestMove.Y, Player: toMove},
+						Best:       &GhostCell{X: entry.BestMove.X, Y: entry.BestMove.Y, Player: toMove},
 						Depth:      knownDepth,
 						Score:      entry.ScoreFloat(),
 						NextPlayer: toMove,
 						HistoryLen: historyLen,
 						Active:     true,
+						Threat:     ghostThreat(state, g.rules, entry.BestMove),
+						ForcedLine: forcedLineFor(state, g.rules, suggestionConfig, entry.BestMove, entry.ScoreFloat()),
 					})
-					if knownDepth >= 10 {
+					if knownDepth >= depth {
 						return
 					}
 					if knownDepth+1 > suggestionConfig.AiMinDepth {
@@ -476,26 +693,40 @@ func (g *Game) startMoveSuggestion(ghostSink func(ghostPayload)) {
 			}
 		}
 	}
-	g.moveSuggestionAI.StartThinkingWithConfig(state, g.rules, nil, func(move Move, depth int, score float64) {
-		ghostSink(ghostPayload{
-			Mode:       "best_move",
-			Best:       &ghostCell{X: move.X, Y: move.Y, Player: toMove},
-			Depth:      depth,
-			Score:      score,
-			NextPlayer: toMove,
-			HistoryLen: historyLen,
-			Active:     true,
+	// A position is charged once against the per-minute cap; searching it
+	// again after a suspension is free.
+	if g.moveSuggestionCharged != hash {
+		if !suggestionStarts.take(time.Now(), config.AiSuggestPerMinute) {
+			g.moveSuggestionCapped = hash
+			return
+		}
+		g.moveSuggestionCharged = hash
+	}
+	g.moveSuggestionCapped = 0
+	g.moveSuggestionAI.StartThinkingWithConfig(state, g.rules, nil, func(move Move, depth int, score float64, scores []float64, stability RootStability) {
+		ghostSink(GhostPayload{
+			Mode:         "best_move",
+			Best:         &GhostCell{X: move.X, Y: move.Y, Player: toMove},
+			Depth:        depth,
+			Score:        score,
+			NextPlayer:   toMove,
+			HistoryLen:   historyLen,
+			Active:       true,
+			Alternatives: ghostAlternatives(scores, state, move, 3),
+			Threat:       ghostThreat(state, g.rules, move),
+			Stability:    &stability,
+			ForcedLine:   forcedLineFor(state, g.rules, suggestionConfig, move, score),
 		})
 	}, suggestionConfig)
 }
 
-func (g *Game) stopMoveSuggestion(ghostSink func(ghostPayload)) {
+func (g *Game) stopMoveSuggestion(ghostSink func(GhostPayload)) {
 	g.moveSuggestionHash = 0
 	if g.moveSuggestionAI != nil {
 		g.moveSuggestionAI.StopThinking()
 	}
 	if ghostSink != nil {
-		ghostSink(ghostPayload{
+		ghostSink(GhostPayload{
 			Mode:   "best_move",
 			Active: false,
 		})
@@ -0,0 +1,164 @@
+package engine
+
+import "testing"
+
+func TestGameStopsBeforeTenthCaptureWhenThreatExists(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.ForbidDoubleThreeBlack = false
+	g := NewGame(settings)
+	g.Start()
+
+	// White already has 8 captures. If White plays at (3,4), it captures
+	// the Black pair (4,4)-(5,4) thanks to the White stone at (6,4).
+	g.state.CapturedWhite = 8
+	g.state.Board.Set(4, 4, CellBlack)
+	g.state.Board.Set(5, 4, CellBlack)
+	g.state.Board.Set(6, 4, CellWhite)
+	g.state.recomputeHashes()
+
+	applied, reason := g.TryApplyMove(Move{X: 0, Y: 0})
+	if !applied {
+		t.Fatalf("expected move to be applied, got reason: %s", reason)
+	}
+	if g.state.Status != StatusWhiteWon {
+		t.Fatalf("expected White to win via forced capture move, got status=%v", g.state.Status)
+	}
+	if len(g.state.WinningCapturePair) != 2 {
+		t.Fatalf("expected 2 threatened stones, got %d", len(g.state.WinningCapturePair))
+	}
+	if !containsMove(g.state.WinningCapturePair, Move{X: 4, Y: 4}) || !containsMove(g.state.WinningCapturePair, Move{X: 5, Y: 4}) {
+		t.Fatalf("expected threatened pair to be (4,4) and (5,4), got %+v", g.state.WinningCapturePair)
+	}
+	if g.state.Board.At(4, 4) != CellEmpty || g.state.Board.At(5, 4) != CellEmpty {
+		t.Fatalf("expected threatened stones to be removed by forced capture move")
+	}
+	if g.state.Board.At(3, 4) != CellWhite {
+		t.Fatalf("expected forced winning move at (3,4) to be played")
+	}
+	entries := g.history.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected history to contain original + forced move, got %d", len(entries))
+	}
+	last := entries[len(entries)-1]
+	if last.Player != PlayerWhite || !last.Move.Equals(Move{X: 3, Y: 4}) {
+		t.Fatalf("expected last history move to be forced White capture at (3,4), got player=%v move=%+v", last.Player, last.Move)
+	}
+	if len(last.CapturedPositions) < 2 {
+		t.Fatalf("expected forced history move to carry captured stones, got %+v", last.CapturedPositions)
+	}
+	if !last.AutoPlayed || entries[0].AutoPlayed {
+		t.Fatalf("expected only the forced move to be marked auto-played, got %+v", entries)
+	}
+	if hash, _ := computeSymmetricHashes(g.state); hash != g.state.Hash {
+		t.Fatalf("expected the hash to match the final position, got %016x want %016x", g.state.Hash, hash)
+	}
+}
+
+func TestGameFlagsCaptureWinWhenAutoPlayIsOff(t *testing.T) {
+	previous := GetConfig()
+	defer UpdateConfig(previous)
+	config := previous
+	config.AutoPlayCaptureWin = false
+	UpdateConfig(config)
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.ForbidDoubleThreeBlack = false
+	g := NewGame(settings)
+	g.Start()
+
+	g.state.CapturedWhite = 8
+	g.state.Board.Set(4, 4, CellBlack)
+	g.state.Board.Set(5, 4, CellBlack)
+	g.state.Board.Set(6, 4, CellWhite)
+	g.state.recomputeHashes()
+
+	if applied, reason := g.TryApplyMove(Move{X: 0, Y: 0}); !applied {
+		t.Fatalf("expected move to be applied, got reason: %s", reason)
+	}
+	state := g.State()
+	if state.Status != StatusRunning || state.ToMove != PlayerWhite || g.history.Size() != 1 {
+		t.Fatalf("expected White to move in a running game, got status=%v toMove=%v moves=%d", state.Status, state.ToMove, g.history.Size())
+	}
+	if !state.HasCaptureWin || !state.CaptureWinMove.Equals(Move{X: 3, Y: 4}) || len(state.CaptureWinCaptures) != 2 {
+		t.Fatalf("expected the capture win at (3,4) to be flagged, got %+v %+v", state.CaptureWinMove, state.CaptureWinCaptures)
+	}
+	conditions := WinConditionsFromState(state, settings)
+	if conditions.CaptureWinPlayer != 2 || conditions.CaptureWinMove == nil || conditions.Summary != "White wins by capture at 3,4" {
+		t.Fatalf("expected win conditions to report the capture win, got %+v", conditions)
+	}
+
+	if applied, _ := g.TryApplyMove(Move{X: 3, Y: 4}); !applied {
+		t.Fatalf("expected the capture win to be playable")
+	}
+	state = g.State()
+	if state.Status != StatusWhiteWon || state.HasCaptureWin {
+		t.Fatalf("expected White to win and the flag to clear, got status=%v flagged=%v", state.Status, state.HasCaptureWin)
+	}
+	if last := g.history.All()[1]; last.AutoPlayed {
+		t.Fatalf("expected the capture played by White not to be marked auto-played")
+	}
+}
+
+func TestGameDoesNotStopBeforeTenthCaptureWithoutEnoughCapturedPairs(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.ForbidDoubleThreeBlack = false
+	g := NewGame(settings)
+	g.Start()
+
+	g.state.CapturedWhite = 6
+	g.state.Board.Set(4, 4, CellBlack)
+	g.state.Board.Set(5, 4, CellBlack)
+	g.state.Board.Set(6, 4, CellWhite)
+	g.state.recomputeHashes()
+
+	applied, reason := g.TryApplyMove(Move{X: 0, Y: 0})
+	if !applied {
+		t.Fatalf("expected move to be applied, got reason: %s", reason)
+	}
+	if g.state.Status != StatusRunning {
+		t.Fatalf("expected game to keep running, got status=%v", g.state.Status)
+	}
+	if len(g.state.WinningCapturePair) != 0 {
+		t.Fatalf("expected no threatened capture pair, got %+v", g.state.WinningCapturePair)
+	}
+}
+
+func TestGameExposesPendingAlignmentWhenBreakCaptureExists(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.ForbidDoubleThreeBlack = false
+	g := NewGame(settings)
+	g.Start()
+
+	// Black completes (1,4)-(5,4), but White can capture (2,4)-(2,5) by
+	// playing (2,6) against its stone at (2,3).
+	for x := 1; x <= 4; x++ {
+		g.state.Board.Set(x, 4, CellBlack)
+	}
+	g.state.Board.Set(2, 5, CellBlack)
+	g.state.Board.Set(2, 3, CellWhite)
+	g.state.recomputeHashes()
+
+	if applied, reason := g.TryApplyMove(Move{X: 5, Y: 4}); !applied {
+		t.Fatalf("expected move to be applied, got reason: %s", reason)
+	}
+	state := g.State()
+	if state.Status != StatusRunning || !state.MustCapture {
+		t.Fatalf("expected a running game with a forced capture, got status=%v mustCapture=%v", state.Status, state.MustCapture)
+	}
+	if !containsMove(state.ForcedCaptureMoves, Move{X: 2, Y: 6}) {
+		t.Fatalf("expected (2,6) among forced captures, got %+v", state.ForcedCaptureMoves)
+	}
+	if len(state.PendingAlignment) != 5 || !containsMove(state.PendingAlignment, Move{X: 5, Y: 4}) {
+		t.Fatalf("expected the pending five to be exposed, got %+v", state.PendingAlignment)
+	}
+	if applied, _ := g.TryApplyMove(Move{X: 2, Y: 6}); !applied {
+		t.Fatalf("expected the forced capture to be legal")
+	}
+	if state := g.State(); state.MustCapture || len(state.PendingAlignment) != 0 {
+		t.Fatalf("expected the forced state to clear after the capture, got %+v", state.PendingAlignment)
+	}
+}
@@ -0,0 +1,394 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// gameArchiveSize is how many previous games the controller keeps for
+// replay export.
+const gameArchiveSize = 32
+
+type GameController struct {
+	mu               sync.Mutex
+	game             Game
+	gameID           uint64
+	archive          []GameRecord
+	annotations      map[uint64][]Annotation
+	accuracy         map[uint64]GameAccuracy
+	user             string
+	userCredited     bool
+	aiDepth          int
+	ghostEnabled     func() bool
+	ghostPublisher   func(GhostPayload)
+	premove          *pendingPremove
+	premovePublisher func(PremoveEvent)
+	scorePublisher   func(ScoreUpdate)
+	// loggedPlies and loggedOver are how much of the game is in the event
+	// log.
+	loggedPlies int
+	loggedOver  bool
+}
+
+func NewGameController(settings GameSettings) *GameController {
+	return &GameController{game: NewGame(settings), gameID: 1, annotations: make(map[uint64][]Annotation), accuracy: make(map[uint64]GameAccuracy), aiDepth: GetConfig().AiDepth}
+}
+
+func (gc *GameController) SetGhostPublisher(enabled func() bool, publisher func(GhostPayload)) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.ghostEnabled = enabled
+	gc.ghostPublisher = publisher
+}
+
+func (gc *GameController) OnCellClicked(x, y int) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	_ = gc.game.SubmitHumanMove(Move{X: x, Y: y})
+	gc.creditUserLocked()
+}
+
+func (gc *GameController) ApplyHumanMove(move Move) (bool, string) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if !gc.game.CurrentPlayerIsHuman() {
+		return false, "not human turn"
+	}
+	applied, reason := gc.game.TryApplyMove(move)
+	gc.creditUserLocked()
+	gc.logProgressLocked()
+	return applied, reason
+}
+
+func (gc *GameController) Tick() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	// A premove is played on a tick of its own, so the engine's move
+	// before it is reported first.
+	if gc.playPremoveLocked() {
+		return true
+	}
+	ghostEnabled := false
+	if gc.ghostEnabled != nil {
+		ghostEnabled = gc.ghostEnabled()
+	}
+	changed := gc.game.Tick(ghostEnabled, gc.ghostPublisher, gc.scoreSinkLocked())
+	gc.creditUserLocked()
+	gc.logProgressLocked()
+	if changed {
+		gc.prefetchRepliesLocked()
+	}
+	return changed
+}
+
+// SetUser links the current game to a user profile, whose statistics get
+// the result once the game ends. Only games against the engine count.
+func (gc *GameController) SetUser(id string) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if (GameRecord{Settings: gc.game.settings}).HumanColor() == 0 {
+		return fmt.Errorf("only games against the AI can be linked to a user")
+	}
+	gc.user = id
+	gc.userCredited = false
+	if model, ok := UserProfiles.OpponentModel(id); ok {
+		gc.game.setOpponentModel(&model)
+	}
+	return nil
+}
+
+func (gc *GameController) User() string {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.user
+}
+
+func (gc *GameController) creditUserLocked() {
+	status := gc.game.state.Status
+	if gc.user == "" || gc.userCredited || status == StatusNotStarted || status == StatusRunning {
+		return
+	}
+	gc.userCredited = true
+	UserProfiles.RecordGame(gc.recordLocked())
+}
+
+func (gc *GameController) State() GameState {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.State()
+}
+
+func (gc *GameController) Settings() GameSettings {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.settings
+}
+
+func (gc *GameController) History() MoveHistory {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.History()
+}
+
+// Snapshot returns state, history and game id read under a single lock, so
+// they always describe the same position.
+func (gc *GameController) Snapshot() (GameState, MoveHistory, uint64) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.State(), gc.game.History(), gc.gameID
+}
+
+// GameID changes every time the game is reset or restarted, so clients
+// following the history can tell a new game from a longer one.
+func (gc *GameController) GameID() uint64 {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.gameID
+}
+
+// ForcedLine returns the rest of the forcing sequence an engine side of
+// the game is playing, if any.
+func (gc *GameController) ForcedLine() (ForcedLine, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.ForcedLine()
+}
+
+func (gc *GameController) CurrentTurnStartedAtMs() int64 {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.TurnStartedAtMs()
+}
+
+// PauseTurnClock leaves d out of the current turn's elapsed time.
+func (gc *GameController) PauseTurnClock(d time.Duration) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.game.pauseTurnClock(d)
+}
+
+func (gc *GameController) LatestHistoryEntry() (HistoryEntry, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	history := gc.game.History()
+	if history.Size() == 0 {
+		return HistoryEntry{}, false
+	}
+	entries := history.All()
+	return entries[len(entries)-1], true
+}
+
+func (gc *GameController) AiThinking() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.AiThinking()
+}
+
+func (gc *GameController) HasGhostBoard() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.HasGhostBoard()
+}
+
+func (gc *GameController) GhostBoard() (Board, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.GhostBoard()
+}
+
+func (gc *GameController) Reset(settings GameSettings) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.archiveLocked()
+	gc.game.Reset(settings)
+	gc.gameID++
+	gc.logGameLocked(EventGameReset)
+}
+
+func (gc *GameController) StartGame(settings GameSettings) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.archiveLocked()
+	gc.game.Reset(settings)
+	gc.game.Start()
+	gc.gameID++
+	gc.logGameLocked(EventGameStarted)
+	gc.warmOpeningCacheLocked()
+}
+
+func (gc *GameController) StartGameFromPosition(settings GameSettings, position StartPosition) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if _, err := position.State(settings); err != nil {
+		return err
+	}
+	gc.archiveLocked()
+	if err := gc.game.StartFromPosition(settings, position); err != nil {
+		return err
+	}
+	gc.gameID++
+	gc.logGameLocked(EventGameStarted)
+	gc.warmOpeningCacheLocked()
+	return nil
+}
+
+func (gc *GameController) StartPosition() (StartPosition, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.game.StartPosition()
+}
+
+func (gc *GameController) UpdateSettings(update GameSettings, reset bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if reset {
+		gc.archiveLocked()
+		gc.game.Reset(update)
+		gc.gameID++
+		gc.logGameLocked(EventGameReset)
+		return
+	}
+	gc.game.settings = update
+	gc.logSettingsLocked()
+	gc.game.createPlayers()
+	if gc.game.state.Status == StatusRunning {
+		gc.game.syncAIPlayersToCurrentState()
+	}
+}
+
+func (gc *GameController) ResetForConfigChange() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.game.ResetForConfigChange()
+}
+
+// GameRecord returns the current game or one of the last archived games.
+func (gc *GameController) GameRecord(id uint64) (GameRecord, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.findRecordLocked(id)
+}
+
+// Annotate stores an annotation on a ply of the current or an archived game
+// and returns all of that game's annotations, ordered by ply.
+func (gc *GameController) Annotate(id uint64, annotation Annotation) ([]Annotation, bool, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	record, ok := gc.findRecordLocked(id)
+	if !ok {
+		return nil, false, nil
+	}
+	if err := annotation.Validate(record.Settings.BoardSize, len(record.Entries)); err != nil {
+		return nil, true, err
+	}
+	annotations := gc.annotations[id]
+	if len(annotations) >= maxAnnotationsPerGame {
+		return nil, true, fmt.Errorf("game already has %d annotations", maxAnnotationsPerGame)
+	}
+	annotation.CreatedAtMs = time.Now().UnixMilli()
+	insertAt := len(annotations)
+	for insertAt > 0 && annotations[insertAt-1].Ply > annotation.Ply {
+		insertAt--
+	}
+	annotations = append(annotations, Annotation{})
+	copy(annotations[insertAt+1:], annotations[insertAt:])
+	annotations[insertAt] = annotation
+	gc.annotations[id] = annotations
+	return append([]Annotation(nil), annotations...), true, nil
+}
+
+// RecordAccuracy stores the accuracy of a review with the current or an
+// archived game, replacing the one of an earlier review.
+func (gc *GameController) RecordAccuracy(id uint64, accuracy GameAccuracy) bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if _, ok := gc.findRecordLocked(id); !ok {
+		return false
+	}
+	gc.accuracy[id] = accuracy
+	return true
+}
+
+func (gc *GameController) accuracyLocked(id uint64) *GameAccuracy {
+	accuracy, ok := gc.accuracy[id]
+	if !ok {
+		return nil
+	}
+	return &accuracy
+}
+
+func (gc *GameController) findRecordLocked(id uint64) (GameRecord, bool) {
+	if id == gc.gameID {
+		return gc.recordLocked(), true
+	}
+	for _, record := range gc.archive {
+		if record.ID == id {
+			record.Annotations = append([]Annotation(nil), gc.annotations[id]...)
+			record.Accuracy = gc.accuracyLocked(id)
+			return record, true
+		}
+	}
+	return GameRecord{}, false
+}
+
+// GameRecords lists the archived games, oldest first, followed by the
+// current one.
+func (gc *GameController) GameRecords() []GameRecord {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	records := make([]GameRecord, 0, len(gc.archive)+1)
+	for _, record := range gc.archive {
+		record.Annotations = append([]Annotation(nil), gc.annotations[record.ID]...)
+		record.Accuracy = gc.accuracyLocked(record.ID)
+		records = append(records, record)
+	}
+	return append(records, gc.recordLocked())
+}
+
+func (gc *GameController) recordLocked() GameRecord {
+	state := gc.game.State()
+	record := GameRecord{
+		ID:          gc.gameID,
+		Settings:    gc.game.settings,
+		Entries:     gc.game.History().All(),
+		Status:      state.Status,
+		WinningLine: append([]Move(nil), state.WinningLine...),
+		Annotations: append([]Annotation(nil), gc.annotations[gc.gameID]...),
+		User:        gc.user,
+		AIDepth:     gc.aiDepth,
+		Accuracy:    gc.accuracyLocked(gc.gameID),
+	}
+	if start, ok := gc.game.StartPosition(); ok {
+		record.Start = &start
+	}
+	return record
+}
+
+// archiveLocked keeps the game about to be replaced, unless nothing was
+// played in it. Finished games also go to the persisted game database. The
+// next game starts without a user, at the configured depth.
+func (gc *GameController) archiveLocked() {
+	gc.creditUserLocked()
+	gc.logProgressLocked()
+	defer func() {
+		gc.user, gc.userCredited, gc.aiDepth = "", false, GetConfig().AiDepth
+	}()
+	if gc.game.History().Size() == 0 {
+		delete(gc.annotations, gc.gameID)
+		delete(gc.accuracy, gc.gameID)
+		return
+	}
+	record := gc.recordLocked()
+	storedGames.Add(record)
+	globalStats.recordGame(record)
+	gc.archive = append(gc.archive, record)
+	if len(gc.archive) > gameArchiveSize {
+		dropped := len(gc.archive) - gameArchiveSize
+		for _, record := range gc.archive[:dropped] {
+			delete(gc.annotations, record.ID)
+			delete(gc.accuracy, record.ID)
+		}
+		gc.archive = append([]GameRecord(nil), gc.archive[dropped:]...)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"testing"
@@ -72,3 +72,25 @@ func TestUpdateSettingsSwitchToAIVsAIKeepsBoardAndContinuesGame(t *testing.T) {
 		t.Fatalf("expected history to grow after AI move")
 	}
 }
+
+func TestGameIDChangesOnRestartOnly(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	started := controller.GameID()
+	if applied, reason := controller.ApplyHumanMove(Move{X: 9, Y: 9}); !applied {
+		t.Fatalf("expected human move to apply: %s", reason)
+	}
+	controller.UpdateSettings(settings, false)
+	if got := controller.GameID(); got != started {
+		t.Fatalf("expected moves and live settings changes to keep game id %d, got %d", started, got)
+	}
+	controller.StartGame(settings)
+	_, history, restarted := controller.Snapshot()
+	if restarted == started || history.Size() != 0 {
+		t.Fatalf("expected a new game id and empty history after restart, got id %d with %d moves", restarted, history.Size())
+	}
+}
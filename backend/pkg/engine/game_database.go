@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"encoding/gob"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const gameDatabaseDefaultLimit = 5000
+
+// gameDatabase keeps finished games across restarts for the opening
+// explorer. The oldest games are dropped past the limit.
+type gameDatabase struct {
+	mu      sync.Mutex
+	games   []GameRecord
+	limit   int
+	version uint64
+	indexes map[int]*explorerIndex
+}
+
+type gameDatabaseSnapshot struct {
+	Games []GameRecord
+}
+
+var storedGames = newGameDatabase(gameDatabaseDefaultLimit)
+
+func newGameDatabase(limit int) *gameDatabase {
+	if limit <= 0 {
+		limit = gameDatabaseDefaultLimit
+	}
+	return &gameDatabase{limit: limit, indexes: make(map[int]*explorerIndex)}
+}
+
+// Add stores a finished game; unfinished ones are ignored.
+func (d *gameDatabase) Add(record GameRecord) {
+	if !record.Finished() || len(record.Entries) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.games = append(d.games, record)
+	if len(d.games) > d.limit {
+		d.games = append([]GameRecord(nil), d.games[len(d.games)-d.limit:]...)
+	}
+	d.version++
+}
+
+// setFairPlay stores report with the game of its match.
+func (d *gameDatabase) setFairPlay(report FairPlayReport) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.games) - 1; i >= 0; i-- {
+		if d.games[i].MatchID == report.MatchID {
+			d.games[i].FairPlay = &report
+			return true
+		}
+	}
+	return false
+}
+
+// FairPlayReports returns the fair play reports of stored games, newest
+// first, or only the flagged ones.
+func FairPlayReports(flaggedOnly bool) []FairPlayReport {
+	storedGames.mu.Lock()
+	defer storedGames.mu.Unlock()
+	reports := []FairPlayReport{}
+	for i := len(storedGames.games) - 1; i >= 0; i-- {
+		report := storedGames.games[i].FairPlay
+		if report != nil && (report.Flagged || !flaggedOnly) {
+			reports = append(reports, *report)
+		}
+	}
+	return reports
+}
+
+func (d *gameDatabase) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.games)
+}
+
+// StoredGameCount is the number of games in the game database.
+func StoredGameCount() int {
+	return storedGames.Len()
+}
+
+// index returns the explorer index for boardSize, rebuilding it when games
+// were added since it was built.
+func (d *gameDatabase) index(boardSize int) *explorerIndex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if index, ok := d.indexes[boardSize]; ok && index.version == d.version {
+		return index
+	}
+	index := newExplorerIndex(d.games, boardSize)
+	index.version = d.version
+	d.indexes[boardSize] = index
+	return index
+}
+
+func (d *gameDatabase) snapshot() gameDatabaseSnapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return gameDatabaseSnapshot{Games: append([]GameRecord(nil), d.games...)}
+}
+
+func (d *gameDatabase) load(snapshot gameDatabaseSnapshot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.games = snapshot.Games
+	if len(d.games) > d.limit {
+		d.games = d.games[len(d.games)-d.limit:]
+	}
+	d.version++
+}
+
+func loadGameDatabase(cfg Config, db *gameDatabase) {
+	if db == nil || cfg.AiGameDatabasePath == "" {
+		log.Printf("[ai:cache] restored game database: 0 games (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.AiGameDatabasePath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open game database %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored game database: 0 games (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot gameDatabaseSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode game database %s: %v", path, err)
+		return
+	}
+	db.load(snapshot)
+	log.Printf("[ai:cache] restored game database from %s (%d games)", path, db.Len())
+}
+
+func persistGameDatabase(cfg Config, db *gameDatabase) {
+	if db == nil || cfg.AiGameDatabasePath == "" {
+		log.Printf("[ai:cache] stored game database: 0 games (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.AiGameDatabasePath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create game database directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := db.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create game database %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode game database %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored game database to %s (%d games)", path, len(snapshot.Games))
+}
@@ -0,0 +1,89 @@
+package engine
+
+// logGameLocked records that the controller's game was started or reset.
+func (gc *GameController) logGameLocked(kind EventKind) {
+	event := Event{
+		Kind:     kind,
+		Actor:    ActorAPI,
+		GameID:   gc.gameID,
+		Settings: eventSettings(gc.game.settings),
+		Status:   StatusToString(gc.game.state.Status),
+		Hash:     eventHash(gc.game.state),
+	}
+	if position, ok := gc.game.StartPosition(); ok {
+		event.Position = &position
+	}
+	Events.Record(event)
+	gc.loggedPlies = gc.game.history.Size()
+	gc.loggedOver = false
+}
+
+// logSettingsLocked records settings changed without resetting the game.
+func (gc *GameController) logSettingsLocked() {
+	Events.Record(Event{
+		Kind:     EventSettingsChanged,
+		Actor:    ActorAPI,
+		GameID:   gc.gameID,
+		Settings: eventSettings(gc.game.settings),
+	})
+}
+
+// logProgressLocked records the moves played since the last call and the
+// end of the game once it is over. Only the latest move carries the hash
+// of the position.
+func (gc *GameController) logProgressLocked() {
+	entries := gc.game.history.entries
+	if gc.loggedPlies > len(entries) {
+		gc.loggedPlies = len(entries)
+	}
+	actor := ActorAPI
+	for ply := gc.loggedPlies; ply < len(entries); ply++ {
+		entry := entries[ply]
+		move := entry.Move
+		actor = gc.moveActorLocked(entry)
+		event := Event{
+			Kind:     EventMoveApplied,
+			Actor:    actor,
+			GameID:   gc.gameID,
+			Ply:      ply + 1,
+			Move:     &move,
+			Player:   PlayerToInt(entry.Player),
+			Captured: len(entry.CapturedPositions),
+		}
+		if ply == len(entries)-1 {
+			event.Hash = eventHash(gc.game.state)
+		}
+		Events.Record(event)
+	}
+	gc.loggedPlies = len(entries)
+	status := gc.game.state.Status
+	if gc.loggedOver || status == StatusRunning || status == StatusNotStarted {
+		return
+	}
+	gc.loggedOver = true
+	if len(entries) > 0 {
+		actor = gc.moveActorLocked(entries[len(entries)-1])
+	}
+	Events.Record(Event{
+		Kind:   EventWinDeclared,
+		Actor:  actor,
+		GameID: gc.gameID,
+		Ply:    len(entries),
+		Status: StatusToString(status),
+		Winner: WinnerFromStatus(status),
+		Hash:   eventHash(gc.game.state),
+	})
+}
+
+func (gc *GameController) moveActorLocked(entry HistoryEntry) string {
+	if entry.AutoPlayed {
+		return ActorRules
+	}
+	if entry.IsAi {
+		return ActorAI
+	}
+	if gc.user != "" {
+		return "user:" + gc.user
+	}
+	return ActorHuman
+}
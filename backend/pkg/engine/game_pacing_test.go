@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPaceHoldsQuickEngineMovesAgainstHumans(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	game := NewGame(settings)
+	game.Start()
+	config := DefaultConfig()
+	config.AiMinMoveMs = 300
+	config.AiMaxMoveMs = 300
+
+	game.turnStart = time.Now().Add(-100 * time.Millisecond)
+	paced := &pacedMove{move: Move{X: 4, Y: 4}}
+	game.pace(paced, config)
+	if wait := time.Until(paced.playAt); wait < 150*time.Millisecond || wait > 200*time.Millisecond {
+		t.Fatalf("expected the move held until 300ms into the turn, waits %v", wait)
+	}
+	if paced.computeMs < 100 || paced.computeMs > 150 {
+		t.Fatalf("expected about 100ms of compute, got %.0f", paced.computeMs)
+	}
+
+	game.turnStart = time.Now().Add(-time.Second)
+	slow := &pacedMove{move: Move{X: 4, Y: 4}}
+	game.pace(slow, config)
+	if time.Until(slow.playAt) > 0 {
+		t.Fatalf("expected a slow move to be played at once")
+	}
+	if applied, reason := game.applyMove(slow.move, nil, 40, nil); !applied {
+		t.Fatalf("expected the move to be played: %s", reason)
+	}
+	entry := game.History().All()[0]
+	if entry.ComputeMs != 40 || entry.ElapsedMs < 1000 {
+		t.Fatalf("expected compute and visible time apart, got %.0f and %.0f", entry.ComputeMs, entry.ElapsedMs)
+	}
+
+	settings.BlackType = PlayerAI
+	game.Reset(settings)
+	game.Start()
+	game.turnStart = time.Now()
+	engines := &pacedMove{move: Move{X: 4, Y: 4}}
+	game.pace(engines, config)
+	if time.Until(engines.playAt) > 0 {
+		t.Fatalf("expected no pacing between engines")
+	}
+}
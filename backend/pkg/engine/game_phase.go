@@ -0,0 +1,102 @@
+package engine
+
+const (
+	PhaseOpening    = "opening"
+	PhaseMiddlegame = "middlegame"
+	PhaseEndgame    = "endgame"
+)
+
+// GamePhaseReport is the phase of a position and what it was read from:
+// the stones on the board, the most stones either side has captured, and
+// the fours and open threes of both sides per stone.
+type GamePhaseReport struct {
+	Phase         string  `json:"phase"`
+	Stones        int     `json:"stones"`
+	Captured      int     `json:"captured"`
+	Threats       int     `json:"threats"`
+	ThreatDensity float64 `json:"threat_density"`
+	Reason        string  `json:"reason"`
+}
+
+// ClassifyGamePhase places state in the opening while it has fewer than
+// AiPhaseOpeningStones stones and no threat, and in the endgame once it has
+// AiPhaseEndgameStones stones, a side has captured AiPhaseCaptures
+// stones or, past the opening's stone count, the threats reach
+// AiPhaseThreatDensity per stone. A zero threshold is never reached.
+func ClassifyGamePhase(state GameState, rules Rules, config Config) GamePhaseReport {
+	report := GamePhaseReport{
+		Stones:   countStones(state.Board),
+		Captured: state.CapturedBlack,
+	}
+	if state.CapturedWhite > report.Captured {
+		report.Captured = state.CapturedWhite
+	}
+	for _, player := range []PlayerColor{PlayerBlack, PlayerWhite} {
+		for _, threat := range FindThreats(state.Board, rules, player) {
+			if threat.Kind != ThreatCapture {
+				report.Threats++
+			}
+		}
+	}
+	if report.Stones > 0 {
+		report.ThreatDensity = float64(report.Threats) / float64(report.Stones)
+	}
+	switch {
+	case config.AiPhaseEndgameStones > 0 && report.Stones >= config.AiPhaseEndgameStones:
+		report.Phase, report.Reason = PhaseEndgame, "stones"
+	case config.AiPhaseCaptures > 0 && report.Captured >= config.AiPhaseCaptures:
+		report.Phase, report.Reason = PhaseEndgame, "captures"
+	case config.AiPhaseThreatDensity > 0 && report.Threats > 0 && report.Stones >= config.AiPhaseOpeningStones && report.ThreatDensity >= config.AiPhaseThreatDensity:
+		report.Phase, report.Reason = PhaseEndgame, "threats"
+	case report.Stones < config.AiPhaseOpeningStones && report.Threats == 0:
+		report.Phase, report.Reason = PhaseOpening, "stones"
+	default:
+		report.Phase, report.Reason = PhaseMiddlegame, "stones"
+	}
+	return report
+}
+
+func countStones(board Board) int {
+	size := board.Size()
+	return size*size - board.CountEmpty()
+}
+
+// phaseCaptureScale is the factor the capture weights take in phase; zero
+// leaves them as they are.
+func phaseCaptureScale(phase string, config Config) float64 {
+	scale := 0.0
+	switch phase {
+	case PhaseOpening:
+		scale = config.AiOpeningCaptureScale
+	case PhaseEndgame:
+		scale = config.AiEndgameCaptureScale
+	}
+	if scale <= 0 {
+		return 1
+	}
+	return scale
+}
+
+// applyGamePhase sets the search up for the phase of its root position. The
+// capture weights of that phase are written into the config, so the search
+// hashes them into its transposition table entries like any other weights,
+// and the eval cache is keyed apart for them.
+func applyGamePhase(state GameState, rules Rules, settings *AIScoreSettings) {
+	config := settings.Config
+	if settings.evalSalt != 0 || phaseCaptureScale(PhaseOpening, config) == 1 && phaseCaptureScale(PhaseEndgame, config) == 1 {
+		return
+	}
+	phase := ClassifyGamePhase(state, rules, config).Phase
+	scale := phaseCaptureScale(phase, config)
+	if scale == 1 {
+		return
+	}
+	heuristics := resolvedHeuristicConfig(config)
+	heuristics.CaptureNow *= scale
+	heuristics.CaptureDoubleThreat *= scale
+	heuristics.CaptureNearWin *= scale
+	heuristics.CaptureInTwo *= scale
+	heuristics.HangingPair *= scale
+	settings.Config.Heuristics = heuristics
+	settings.evalSalt = mixKey(heuristicHashFromConfig(settings.Config))
+}
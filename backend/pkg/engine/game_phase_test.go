@@ -0,0 +1,56 @@
+package engine
+
+import "testing"
+
+func TestClassifyGamePhase(t *testing.T) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	config := DefaultConfig()
+	state := DefaultGameState(settings)
+	state.Board.Set(9, 9, CellBlack)
+	state.Board.Set(10, 10, CellWhite)
+	if report := ClassifyGamePhase(state, rules, config); report.Phase != PhaseOpening || report.Stones != 2 {
+		t.Fatalf("expected two quiet stones to be the opening, got %+v", report)
+	}
+
+	state.Board.Set(10, 9, CellBlack)
+	state.Board.Set(11, 9, CellBlack)
+	if report := ClassifyGamePhase(state, rules, config); report.Phase != PhaseMiddlegame || report.Threats != 1 {
+		t.Fatalf("expected a threat to end the opening, got %+v", report)
+	}
+	config.AiPhaseOpeningStones = 4
+	if report := ClassifyGamePhase(state, rules, config); report.Phase != PhaseEndgame || report.Reason != "threats" {
+		t.Fatalf("expected an open three among five stones to be dense enough for the endgame, got %+v", report)
+	}
+	config.AiPhaseThreatDensity = 0
+
+	state.CapturedWhite = config.AiPhaseCaptures
+	if report := ClassifyGamePhase(state, rules, config); report.Phase != PhaseEndgame || report.Reason != "captures" {
+		t.Fatalf("expected captures to bring the endgame, got %+v", report)
+	}
+}
+
+func TestApplyGamePhaseScalesCaptureWeights(t *testing.T) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Board.Set(9, 9, CellBlack)
+	state.CapturedBlack = 8
+
+	search := AIScoreSettings{Config: DefaultConfig()}
+	applyGamePhase(state, rules, &search)
+	if search.evalSalt != 0 || search.Config.Heuristics != DefaultConfig().Heuristics {
+		t.Fatalf("neutral phase scales should leave the weights alone")
+	}
+
+	search.Config.AiEndgameCaptureScale = 2
+	applyGamePhase(state, rules, &search)
+	base := DefaultConfig().Heuristics
+	if search.Config.Heuristics.CaptureNow != 2*base.CaptureNow || search.Config.Heuristics.Open4 != base.Open4 || search.evalSalt == 0 {
+		t.Fatalf("expected only the capture weights doubled in the endgame, got %+v", search.Config.Heuristics)
+	}
+	applyGamePhase(state, rules, &search)
+	if search.Config.Heuristics.CaptureNow != 2*base.CaptureNow {
+		t.Fatalf("the phase must only be applied once per search")
+	}
+}
@@ -0,0 +1,234 @@
+package engine
+
+// gameReportOpeningPlies is how many moves of a game the report gives as
+// its opening.
+const gameReportOpeningPlies = 6
+
+// MoveSearchStats is what the engine's search of one move cost.
+type MoveSearchStats struct {
+	Nodes           int64 `json:"nodes"`
+	TTProbes        int64 `json:"tt_probes"`
+	TTHits          int64 `json:"tt_hits"`
+	EvalCacheProbes int64 `json:"eval_cache_probes"`
+	EvalCacheHits   int64 `json:"eval_cache_hits"`
+}
+
+func moveSearchStats(stats *SearchStats) *MoveSearchStats {
+	if stats == nil {
+		return nil
+	}
+	return &MoveSearchStats{
+		Nodes:           stats.Nodes,
+		TTProbes:        stats.TTProbes,
+		TTHits:          stats.TTHits,
+		EvalCacheProbes: stats.EvalCacheProbes,
+		EvalCacheHits:   stats.EvalCacheHits,
+	}
+}
+
+// GameReportMove is one ply of a game report. WinProb is black's chance of
+// winning after the move, when the engine or a review valued it; Class is
+// the review's grade.
+type GameReportMove struct {
+	Ply       int              `json:"ply"`
+	Player    int              `json:"player"`
+	Move      Move             `json:"move"`
+	IsAi      bool             `json:"is_ai"`
+	Depth     int              `json:"depth,omitempty"`
+	ElapsedMs float64          `json:"elapsed_ms"`
+	ComputeMs float64          `json:"compute_ms"`
+	Captures  int              `json:"captures,omitempty"`
+	Search    *MoveSearchStats `json:"search,omitempty"`
+	WinProb   *float64         `json:"win_prob,omitempty"`
+	Class     string           `json:"class,omitempty"`
+	Blunder   bool             `json:"blunder,omitempty"`
+}
+
+// GameReportSide sums up one side of a game. Depth, nodes and compute time
+// only count the moves its searches were timed on.
+type GameReportSide struct {
+	Player         int      `json:"player"`
+	Type           string   `json:"type"`
+	Moves          int      `json:"moves"`
+	TimeMs         float64  `json:"time_ms"`
+	ComputeMs      float64  `json:"compute_ms"`
+	AverageDepth   float64  `json:"average_depth"`
+	Nodes          int64    `json:"nodes"`
+	NodesPerSecond float64  `json:"nodes_per_second"`
+	Captures       int      `json:"captures"`
+	Blunders       int      `json:"blunders"`
+	Accuracy       *float64 `json:"accuracy,omitempty"`
+}
+
+// GameReportCache is how well the caches served the game's searches.
+type GameReportCache struct {
+	TTProbes         int64   `json:"tt_probes"`
+	TTHits           int64   `json:"tt_hits"`
+	TTHitRate        float64 `json:"tt_hit_rate"`
+	EvalCacheProbes  int64   `json:"eval_cache_probes"`
+	EvalCacheHits    int64   `json:"eval_cache_hits"`
+	EvalCacheHitRate float64 `json:"eval_cache_hit_rate"`
+}
+
+// GameReportPoint is black's chance of winning after a ply.
+type GameReportPoint struct {
+	Ply     int     `json:"ply"`
+	WinProb float64 `json:"win_prob"`
+}
+
+// GameReport is the engine's statistics of one game in a single document.
+// Evaluations and blunders come from a finished review when there is one,
+// otherwise from the scores of the engine's own moves.
+type GameReport struct {
+	GameID     uint64            `json:"game_id"`
+	Status     string            `json:"status"`
+	Winner     int               `json:"winner"`
+	BoardSize  int               `json:"board_size"`
+	Plies      int               `json:"plies"`
+	Opening    []Move            `json:"opening"`
+	Black      GameReportSide    `json:"black"`
+	White      GameReportSide    `json:"white"`
+	Cache      GameReportCache   `json:"cache"`
+	Trajectory []GameReportPoint `json:"trajectory"`
+	Blunders   []int             `json:"blunders"`
+	Moves      []GameReportMove  `json:"moves"`
+	ReviewID   uint64            `json:"review_id,omitempty"`
+}
+
+// BuildGameReport assembles the report of record, with review, a finished
+// review of it, when not nil.
+func BuildGameReport(record GameRecord, review *Review) GameReport {
+	report := GameReport{
+		GameID:     record.ID,
+		Status:     StatusToString(record.Status),
+		Winner:     WinnerFromStatus(record.Status),
+		BoardSize:  record.Settings.BoardSize,
+		Plies:      len(record.Entries),
+		Opening:    []Move{},
+		Black:      GameReportSide{Player: 1, Type: playerTypeName(record.Settings.BlackType)},
+		White:      GameReportSide{Player: 2, Type: playerTypeName(record.Settings.WhiteType)},
+		Trajectory: []GameReportPoint{},
+		Blunders:   []int{},
+		Moves:      make([]GameReportMove, 0, len(record.Entries)),
+	}
+	if review != nil && review.Status == "done" && len(review.Moves) == len(record.Entries) {
+		report.ReviewID = review.ID
+		black, white := review.Black.Accuracy, review.White.Accuracy
+		report.Black.Accuracy, report.White.Accuracy = &black, &white
+	} else {
+		review = nil
+	}
+	for i, entry := range record.Entries {
+		move := GameReportMove{
+			Ply:       i + 1,
+			Player:    PlayerToInt(entry.Player),
+			Move:      Move{X: entry.Move.X, Y: entry.Move.Y},
+			IsAi:      entry.IsAi,
+			Depth:     entry.Depth,
+			ElapsedMs: entry.ElapsedMs,
+			ComputeMs: entry.ComputeMs,
+			Captures:  entry.CapturedCount,
+			Search:    entry.Search,
+		}
+		if i < gameReportOpeningPlies {
+			report.Opening = append(report.Opening, move.Move)
+		}
+		switch {
+		case review != nil:
+			graded := review.Moves[i]
+			winProb := graded.PlayedWinProb
+			if graded.Player == 2 {
+				winProb = 1 - winProb
+			}
+			move.WinProb, move.Class, move.Blunder = &winProb, graded.Class, graded.Class == "blunder"
+		case entry.Scored:
+			winProb := WinProbability(entry.Score)
+			if entry.Player == PlayerWhite {
+				winProb = 1 - winProb
+			}
+			move.WinProb = &winProb
+		}
+		report.Moves = append(report.Moves, move)
+	}
+	if review == nil {
+		markEngineBlunders(report.Moves)
+	}
+
+	var searchedMs [2]float64
+	var depths, searched [2]int
+	for _, move := range report.Moves {
+		side := &report.Black
+		if move.Player == 2 {
+			side = &report.White
+		}
+		side.Moves++
+		side.TimeMs += move.ElapsedMs
+		side.Captures += move.Captures
+		if move.WinProb != nil {
+			report.Trajectory = append(report.Trajectory, GameReportPoint{Ply: move.Ply, WinProb: *move.WinProb})
+		}
+		if move.Blunder {
+			side.Blunders++
+			report.Blunders = append(report.Blunders, move.Ply)
+		}
+		if move.Search == nil {
+			continue
+		}
+		side.ComputeMs += move.ComputeMs
+		side.Nodes += move.Search.Nodes
+		searchedMs[move.Player-1] += move.ComputeMs
+		depths[move.Player-1] += move.Depth
+		searched[move.Player-1]++
+		report.Cache.TTProbes += move.Search.TTProbes
+		report.Cache.TTHits += move.Search.TTHits
+		report.Cache.EvalCacheProbes += move.Search.EvalCacheProbes
+		report.Cache.EvalCacheHits += move.Search.EvalCacheHits
+	}
+	for i, side := range []*GameReportSide{&report.Black, &report.White} {
+		if searched[i] > 0 {
+			side.AverageDepth = float64(depths[i]) / float64(searched[i])
+		}
+		if searchedMs[i] > 0 {
+			side.NodesPerSecond = float64(side.Nodes) * 1000 / searchedMs[i]
+		}
+	}
+	if report.Cache.TTProbes > 0 {
+		report.Cache.TTHitRate = float64(report.Cache.TTHits) / float64(report.Cache.TTProbes)
+	}
+	if report.Cache.EvalCacheProbes > 0 {
+		report.Cache.EvalCacheHitRate = float64(report.Cache.EvalCacheHits) / float64(report.Cache.EvalCacheProbes)
+	}
+	return report
+}
+
+// markEngineBlunders flags, between two valued plies, the last move of the
+// side black's chance of winning swung against by reviewCriticalSwing or
+// more. Without a review only the engine's own moves are valued, so the
+// move blamed is usually the human move the engine answered.
+func markEngineBlunders(moves []GameReportMove) {
+	previous := -1
+	for i, move := range moves {
+		if move.WinProb == nil {
+			continue
+		}
+		if previous < 0 {
+			previous = i
+			continue
+		}
+		swing := *move.WinProb - *moves[previous].WinProb
+		loser := 0
+		switch {
+		case swing <= -reviewCriticalSwing:
+			loser = 1
+		case swing >= reviewCriticalSwing:
+			loser = 2
+		}
+		for j := i; loser != 0 && j > previous; j-- {
+			if moves[j].Player == loser {
+				moves[j].Blunder = true
+				break
+			}
+		}
+		previous = i
+	}
+}
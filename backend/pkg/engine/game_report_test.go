@@ -0,0 +1,63 @@
+package engine
+
+import "testing"
+
+// reportTestRecord is a human (black) vs engine (white) game where the
+// engine's evaluation turns in its favour after black's third move.
+func reportTestRecord() GameRecord {
+	settings := DefaultGameSettings()
+	record := GameRecord{ID: 3, Settings: settings, Status: StatusWhiteWon}
+	scores := []float64{0, 0, -30000}
+	for i := 0; i < 6; i++ {
+		entry := HistoryEntry{Move: Move{X: i, Y: 0}, Player: PlayerBlack, ElapsedMs: 2000}
+		if i%2 == 1 {
+			entry.Player, entry.IsAi, entry.Depth = PlayerWhite, true, 6
+			entry.ElapsedMs, entry.ComputeMs = 800, 500
+			entry.Score, entry.Scored = -scores[i/2], true
+			entry.Search = &MoveSearchStats{Nodes: 1000, TTProbes: 100, TTHits: 25, EvalCacheProbes: 50, EvalCacheHits: 40}
+		}
+		record.Entries = append(record.Entries, entry)
+	}
+	return record
+}
+
+func TestBuildGameReportSumsSearchStats(t *testing.T) {
+	report := BuildGameReport(reportTestRecord(), nil)
+	if report.Plies != 6 || len(report.Opening) != 6 || report.Winner != 2 {
+		t.Fatalf("unexpected report header: %+v", report)
+	}
+	white := report.White
+	if white.Type != ActorAI || white.Moves != 3 || white.Nodes != 3000 || white.AverageDepth != 6 || white.NodesPerSecond != 2000 {
+		t.Fatalf("unexpected engine side: %+v", white)
+	}
+	if report.Black.Type != ActorHuman || report.Black.TimeMs != 6000 || report.Black.Nodes != 0 {
+		t.Fatalf("unexpected human side: %+v", report.Black)
+	}
+	if report.Cache.TTHitRate != 0.25 || report.Cache.EvalCacheHitRate != 0.8 {
+		t.Fatalf("unexpected cache rates: %+v", report.Cache)
+	}
+	if len(report.Trajectory) != 3 || report.Trajectory[2].WinProb > 0.5 {
+		t.Fatalf("expected black's chances to fall on the engine's last move, got %+v", report.Trajectory)
+	}
+	if len(report.Blunders) != 1 || report.Blunders[0] != 5 || report.Black.Blunders != 1 {
+		t.Fatalf("expected black's last move to be blamed, got %v", report.Blunders)
+	}
+}
+
+func TestBuildGameReportUsesReview(t *testing.T) {
+	review := &Review{ID: 9, Status: "done", Black: ReviewSideSummary{Accuracy: 70}, White: ReviewSideSummary{Accuracy: 95}}
+	for ply := 1; ply <= 6; ply++ {
+		graded := ReviewMove{Ply: ply, Player: 1 + (ply+1)%2, Class: "best", PlayedWinProb: 0.5}
+		if ply == 1 {
+			graded.Class = "blunder"
+		}
+		review.Moves = append(review.Moves, graded)
+	}
+	report := BuildGameReport(reportTestRecord(), review)
+	if report.ReviewID != 9 || report.Black.Accuracy == nil || *report.Black.Accuracy != 70 {
+		t.Fatalf("expected the review's accuracy, got %+v", report)
+	}
+	if len(report.Trajectory) != 6 || len(report.Blunders) != 1 || report.Blunders[0] != 1 {
+		t.Fatalf("expected the review's grades, got %+v %v", report.Trajectory, report.Blunders)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package engine
 
 type PlayerType int
 
@@ -18,6 +18,9 @@ type GameSettings struct {
 	ForbidDoubleThreeWhite bool       `json:"forbid_double_three_white"`
 	BlackHeuristics        *HeuristicConfig
 	WhiteHeuristics        *HeuristicConfig
+	// Seed drives the engine's random choices in the game; zero draws a
+	// fresh one when the game is reset.
+	Seed int64 `json:"seed"`
 }
 
 func DefaultGameSettings() GameSettings {
@@ -1,4 +1,4 @@
-package main
+package engine
 
 type PlayerColor int
 
@@ -30,9 +30,16 @@ type GameState struct {
 	CanonHash          uint64
 	MustCapture        bool
 	ForcedCaptureMoves []Move
+	PendingAlignment   []Move
 	LastMessage        string
 	WinningLine        []Move
 	WinningCapturePair []Move
+	// CaptureWinMove wins the game by capture for the side to move, taking
+	// CaptureWinCaptures. It is only set when AutoPlayCaptureWin is off;
+	// otherwise the move is played at once.
+	HasCaptureWin      bool
+	CaptureWinMove     Move
+	CaptureWinCaptures []Move
 }
 
 func DefaultGameState(settings GameSettings) GameState {
@@ -58,9 +65,11 @@ func (s *GameState) Reset(settings GameSettings) {
 	s.CanonHash = 0
 	s.MustCapture = false
 	s.ForcedCaptureMoves = nil
+	s.PendingAlignment = nil
 	s.LastMessage = ""
 	s.WinningLine = nil
 	s.WinningCapturePair = nil
+	s.clearCaptureWin()
 	s.recomputeHashes()
 }
 
@@ -68,8 +77,10 @@ func (s GameState) Clone() GameState {
 	clone := s
 	clone.Board = s.Board.Clone()
 	clone.ForcedCaptureMoves = append([]Move(nil), s.ForcedCaptureMoves...)
+	clone.PendingAlignment = append([]Move(nil), s.PendingAlignment...)
 	clone.WinningLine = append([]Move(nil), s.WinningLine...)
 	clone.WinningCapturePair = append([]Move(nil), s.WinningCapturePair...)
+	clone.CaptureWinCaptures = append([]Move(nil), s.CaptureWinCaptures...)
 	return clone
 }
 
@@ -80,6 +91,12 @@ func otherPlayer(player PlayerColor) PlayerColor {
 	return PlayerBlack
 }
 
+func (s *GameState) clearCaptureWin() {
+	s.HasCaptureWin = false
+	s.CaptureWinMove = Move{}
+	s.CaptureWinCaptures = nil
+}
+
 func (s *GameState) recomputeHashes() {
 	hash, sym := computeSymmetricHashes(*s)
 	s.Hash = hash
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+// moveSource picks the move of the side to move in state.
+type moveSource func(ctx context.Context, state GameState, rules Rules) (Move, error)
+
+// Step advances the game to its next event without the ticker: a pending
+// human move is played, or the engine searches the position in the calling
+// goroutine and plays its move. It reports whether a move was played; false
+// with a nil error means the game is over or waits for a human. Cancelling
+// ctx aborts the search and returns ctx.Err().
+func (g *Game) Step(ctx context.Context) (bool, error) {
+	if g.state.Status != StatusRunning {
+		return false, nil
+	}
+	switch player := g.currentPlayer().(type) {
+	case nil:
+		return false, nil
+	case *HumanPlayer:
+		if !player.HasPendingMove() {
+			return false, nil
+		}
+		return g.stepWith(ctx, func(context.Context, GameState, Rules) (Move, error) {
+			return player.TakePendingMove(), nil
+		})
+	case *AIPlayer:
+		if player.IsThinking() {
+			player.StopThinking()
+		}
+		return g.stepWith(ctx, func(ctx context.Context, state GameState, rules Rules) (Move, error) {
+			move, ok := player.chooseMoveContext(ctx, state, rules)
+			if err := ctx.Err(); err != nil {
+				return Move{}, err
+			}
+			if !ok {
+				return Move{}, fmt.Errorf("no legal move available")
+			}
+			return move, nil
+		})
+	default:
+		return g.stepWith(ctx, func(_ context.Context, state GameState, rules Rules) (Move, error) {
+			return player.ChooseMove(state, rules), nil
+		})
+	}
+}
+
+// stepWith plays the move source picks for the side to move.
+func (g *Game) stepWith(ctx context.Context, source moveSource) (bool, error) {
+	if g.state.Status != StatusRunning {
+		return false, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	ply := g.history.Size() + 1
+	move, err := source(ctx, g.State(), g.rules)
+	if err != nil {
+		return false, fmt.Errorf("ply %d: %w", ply, err)
+	}
+	if ok, reason := g.TryApplyMove(move); !ok {
+		return false, fmt.Errorf("ply %d: %w: %s", ply, ErrIllegalMove, reason)
+	}
+	return true, nil
+}
+
+// Step advances the game to its next event in the calling goroutine, as
+// Game.Step, instead of waiting for Tick to notice it: a premove, a pending
+// human move or the engine's move. It holds the controller for the whole
+// search, so it is meant for tests and headless runs rather than next to
+// the live ticker.
+func (gc *GameController) Step(ctx context.Context) (bool, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.playPremoveLocked() {
+		return true, nil
+	}
+	changed, err := gc.game.Step(ctx)
+	gc.creditUserLocked()
+	gc.logProgressLocked()
+	if changed {
+		gc.prefetchRepliesLocked()
+	}
+	return changed, err
+}
+
+// RunUntilIdle steps the game until it ends, waits for a human or played
+// maxMoves moves (no limit when zero or less), and returns how many moves
+// were played.
+func (gc *GameController) RunUntilIdle(ctx context.Context, maxMoves int) (int, error) {
+	played := 0
+	for maxMoves <= 0 || played < maxMoves {
+		changed, err := gc.Step(ctx)
+		if err != nil {
+			return played, err
+		}
+		if !changed {
+			break
+		}
+		played++
+	}
+	return played, nil
+}
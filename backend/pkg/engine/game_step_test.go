@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestControllerStepPlaysBothSidesWithoutTicker(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	ctx := context.Background()
+
+	if changed, err := controller.Step(ctx); changed || err != nil {
+		t.Fatalf("expected the game to wait for the human, got changed=%v err=%v", changed, err)
+	}
+	controller.OnCellClicked(4, 4)
+	if changed, err := controller.Step(ctx); !changed || err != nil {
+		t.Fatalf("expected the human move to be played, got changed=%v err=%v", changed, err)
+	}
+	if changed, err := controller.Step(ctx); !changed || err != nil {
+		t.Fatalf("expected the engine to answer within the step, got changed=%v err=%v", changed, err)
+	}
+	state := controller.State()
+	if state.ToMove != PlayerBlack || countBoardStones(state.Board) != 2 {
+		t.Fatalf("expected black to move after the engine's reply")
+	}
+	if played, err := controller.RunUntilIdle(ctx, 0); played != 0 || err != nil {
+		t.Fatalf("expected nothing to run until the human moves, got %d %v", played, err)
+	}
+}
+
+func TestGameStepStopsOnCancelledContext(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerAI
+	settings.WhiteType = PlayerAI
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if changed, err := controller.Step(ctx); changed || err == nil {
+		t.Fatalf("expected a cancelled step to fail, got changed=%v err=%v", changed, err)
+	}
+	if countBoardStones(controller.State().Board) != 0 {
+		t.Fatalf("expected no move after cancellation")
+	}
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+// GhostModePreviewBoard payloads carry the board the search is currently
+// looking at; they are the bulk of ghost traffic and are sent as deltas.
+const GhostModePreviewBoard = "preview_board"
+
+// Every ghostKeyframeInterval-th preview frame is a keyframe with the full
+// board, so a client that missed a delta resyncs quickly.
+const ghostKeyframeInterval = 32
+
+type GhostCell struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Player int `json:"player"`
+}
+
+type GhostPayload struct {
+	Mode       string      `json:"mode,omitempty"`
+	Positions  []GhostCell `json:"positions,omitempty"`
+	Best       *GhostCell  `json:"best,omitempty"`
+	Depth      int         `json:"depth,omitempty"`
+	Score      float64     `json:"score,omitempty"`
+	NextPlayer int         `json:"next_player,omitempty"`
+	HistoryLen int         `json:"history_len,omitempty"`
+	Active     bool        `json:"active"`
+	Final      bool        `json:"final,omitempty"`
+	Frame      uint64      `json:"frame,omitempty"`
+	Keyframe   bool        `json:"keyframe,omitempty"`
+	Added      []GhostCell `json:"added,omitempty"`
+	Removed    []Move      `json:"removed,omitempty"`
+	// Best move suggestions also carry the next best moves and the threat
+	// the best move makes or stops, for the overlay to draw.
+	Alternatives []GhostAlternative `json:"alternatives,omitempty"`
+	Threat       *Threat            `json:"threat,omitempty"`
+	// Stability says whether the search has settled on its best move.
+	Stability *RootStability `json:"stability,omitempty"`
+	// ForcedLine is the winning sequence behind a proven win or loss.
+	ForcedLine *ForcedLine `json:"forced_line,omitempty"`
+}
+
+type GhostAlternative struct {
+	X     int     `json:"x"`
+	Y     int     `json:"y"`
+	Score float64 `json:"score"`
+}
+
+// ghostAlternatives returns up to limit moves other than best, best first
+// for the side to move.
+func ghostAlternatives(scores []float64, state GameState, best Move, limit int) []GhostAlternative {
+	size := state.Board.Size()
+	if len(scores) < size*size {
+		return nil
+	}
+	alternatives := []GhostAlternative{}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			score := scores[y*size+x]
+			if score == illegalScore || math.IsInf(score, 0) || math.IsNaN(score) || (x == best.X && y == best.Y) {
+				continue
+			}
+			alternatives = append(alternatives, GhostAlternative{X: x, Y: y, Score: score})
+		}
+	}
+	maximizing := state.ToMove == PlayerBlack
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		if maximizing {
+			return alternatives[i].Score > alternatives[j].Score
+		}
+		return alternatives[i].Score < alternatives[j].Score
+	})
+	if len(alternatives) > limit {
+		alternatives = alternatives[:limit]
+	}
+	return alternatives
+}
+
+// ghostThreat returns the strongest threat best creates for the side to
+// move, or failing that the strongest opponent threat it blocks.
+func ghostThreat(state GameState, rules Rules, best Move) *Threat {
+	player := state.ToMove
+	board := state.Board.Clone()
+	board.Set(best.X, best.Y, CellFromPlayer(player))
+	for _, threat := range FindThreats(board, rules, player) {
+		if containsMove(threat.Stones, best) {
+			return &threat
+		}
+	}
+	for _, threat := range FindThreats(state.Board, rules, otherPlayer(player)) {
+		if containsMove(threat.Cells, best) {
+			return &threat
+		}
+	}
+	return nil
+}
+
+func containsMove(moves []Move, target Move) bool {
+	for _, move := range moves {
+		if move.Equals(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func GhostPositionsFromBoard(board Board) []GhostCell {
+	positions := []GhostCell{}
+	size := board.Size()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			cell := board.At(x, y)
+			if cell == CellEmpty {
+				continue
+			}
+			positions = append(positions, GhostCell{X: x, Y: y, Player: CellToInt(cell)})
+		}
+	}
+	return positions
+}
+
+// GhostDeltaEncoder numbers preview_board frames and strips them down to the
+// cells added or removed since the previous frame. A delta applies only on
+// top of frame Frame-1; keyframes carry the full Positions instead.
+type GhostDeltaEncoder struct {
+	frame uint64
+	cells map[[2]int]int
+	order []GhostCell
+}
+
+func NewGhostDeltaEncoder() *GhostDeltaEncoder {
+	return &GhostDeltaEncoder{cells: make(map[[2]int]int)}
+}
+
+// Next records a full preview payload and returns both encodings of it: the
+// delta for clients holding the previous frame and the keyframe for the rest.
+// On keyframe intervals both are the keyframe.
+func (e *GhostDeltaEncoder) Next(payload GhostPayload) (GhostPayload, GhostPayload) {
+	e.frame++
+	keyframe := payload
+	keyframe.Frame = e.frame
+	keyframe.Keyframe = true
+	keyframe.Added = nil
+	keyframe.Removed = nil
+
+	delta := keyframe
+	delta.Keyframe = false
+	delta.Positions = nil
+	cells := make(map[[2]int]int, len(payload.Positions))
+	for _, cell := range payload.Positions {
+		key := [2]int{cell.X, cell.Y}
+		cells[key] = cell.Player
+		if player, ok := e.cells[key]; !ok || player != cell.Player {
+			delta.Added = append(delta.Added, cell)
+		}
+	}
+	for _, cell := range e.order {
+		if _, ok := cells[[2]int{cell.X, cell.Y}]; !ok {
+			delta.Removed = append(delta.Removed, Move{X: cell.X, Y: cell.Y})
+		}
+	}
+	e.cells = cells
+	e.order = payload.Positions
+
+	if e.frame%ghostKeyframeInterval == 1 {
+		return keyframe, keyframe
+	}
+	return delta, keyframe
+}
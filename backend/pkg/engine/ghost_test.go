@@ -0,0 +1,110 @@
+package engine
+
+import "testing"
+
+func TestGhostDeltaEncoderRoundTrip(t *testing.T) {
+	encoder := NewGhostDeltaEncoder()
+	boards := [][]GhostCell{
+		{{X: 9, Y: 9, Player: 1}},
+		{{X: 9, Y: 9, Player: 1}, {X: 10, Y: 9, Player: 2}},
+		{{X: 9, Y: 9, Player: 2}, {X: 11, Y: 9, Player: 1}},
+		{},
+	}
+	client := map[[2]int]int{}
+	for i, positions := range boards {
+		delta, keyframe := encoder.Next(GhostPayload{Mode: GhostModePreviewBoard, Positions: positions, Active: true})
+		if delta.Frame != uint64(i+1) || keyframe.Frame != delta.Frame || !keyframe.Keyframe {
+			t.Fatalf("frame %d: unexpected numbering delta=%+v keyframe=%+v", i, delta, keyframe)
+		}
+		if len(keyframe.Positions) != len(positions) {
+			t.Fatalf("frame %d: keyframe should carry the full board, got %+v", i, keyframe.Positions)
+		}
+		if i == 0 {
+			if !delta.Keyframe {
+				t.Fatalf("first frame should be a keyframe")
+			}
+			for _, cell := range delta.Positions {
+				client[[2]int{cell.X, cell.Y}] = cell.Player
+			}
+			continue
+		}
+		if delta.Keyframe || delta.Positions != nil {
+			t.Fatalf("frame %d: expected a delta, got %+v", i, delta)
+		}
+		for _, move := range delta.Removed {
+			delete(client, [2]int{move.X, move.Y})
+		}
+		for _, cell := range delta.Added {
+			client[[2]int{cell.X, cell.Y}] = cell.Player
+		}
+		if len(client) != len(positions) {
+			t.Fatalf("frame %d: rebuilt %v, want %v", i, client, positions)
+		}
+		for _, cell := range positions {
+			if client[[2]int{cell.X, cell.Y}] != cell.Player {
+				t.Fatalf("frame %d: rebuilt %v, want %v", i, client, positions)
+			}
+		}
+	}
+}
+
+func TestGhostDeltaEncoderPeriodicKeyframe(t *testing.T) {
+	encoder := NewGhostDeltaEncoder()
+	for i := 1; i <= ghostKeyframeInterval+1; i++ {
+		delta, _ := encoder.Next(GhostPayload{Mode: GhostModePreviewBoard, Positions: []GhostCell{{X: i % 19, Y: 0, Player: 1}}})
+		if want := i%ghostKeyframeInterval == 1; delta.Keyframe != want {
+			t.Fatalf("frame %d: keyframe=%v, want %v", i, delta.Keyframe, want)
+		}
+	}
+}
+
+func TestGhostAlternativesOrdersForSideToMove(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	state := DefaultGameState(settings)
+	state.ToMove = PlayerWhite
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	scores[0] = -500
+	scores[1] = 300
+	scores[2] = -100
+	scores[3] = 0
+	scores[4] = 900
+
+	alternatives := ghostAlternatives(scores, state, Move{X: 0, Y: 0}, 3)
+	want := []int{2, 3, 1}
+	if len(alternatives) != len(want) {
+		t.Fatalf("expected %d alternatives, got %+v", len(want), alternatives)
+	}
+	for i, x := range want {
+		if alternatives[i].X != x || alternatives[i].Y != 0 {
+			t.Fatalf("alternative %d: expected x=%d, got %+v", i, x, alternatives)
+		}
+	}
+}
+
+func TestGhostThreatPrefersCreatedThenBlocked(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.ToMove = PlayerBlack
+	for x := 2; x <= 4; x++ {
+		state.Board.Set(x, 2, CellBlack)
+	}
+	threat := ghostThreat(state, rules, Move{X: 5, Y: 2})
+	if threat == nil || threat.Player != 1 || threat.Kind != ThreatOpenFour {
+		t.Fatalf("expected the move to make an open four, got %+v", threat)
+	}
+
+	state.ToMove = PlayerWhite
+	threat = ghostThreat(state, rules, Move{X: 5, Y: 2})
+	if threat == nil || threat.Player != 1 || !containsMove(threat.Cells, Move{X: 5, Y: 2}) {
+		t.Fatalf("expected the move to block black's three, got %+v", threat)
+	}
+	if ghostThreat(state, rules, Move{X: 8, Y: 8}) != nil {
+		t.Fatalf("expected no threat for a quiet move")
+	}
+}
@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"context"
+	"encoding/gob"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// globalStatsSampleEvery is how often the transposition table's
+	// occupancy is sampled, and globalStatsSamples how many samples are
+	// kept: a week's worth.
+	globalStatsSampleEvery = 10 * time.Minute
+	globalStatsSamples     = 7 * 24 * 6
+)
+
+// GlobalAIResults are the results of the engine playing one colour at one
+// search depth.
+type GlobalAIResults struct {
+	Color   int     `json:"color"`
+	Depth   int     `json:"depth"`
+	Games   int64   `json:"games"`
+	Wins    int64   `json:"wins"`
+	Losses  int64   `json:"losses"`
+	Draws   int64   `json:"draws"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// TTOccupancySample is the transposition table's fill at one time.
+type TTOccupancySample struct {
+	AtMs     int64   `json:"at_ms"`
+	Entries  int     `json:"entries"`
+	Capacity int     `json:"capacity"`
+	Fill     float64 `json:"fill"`
+}
+
+// GlobalStats are the lifetime numbers of the server, kept across restarts.
+// Games count the finished games stored in the game database, and
+// PositionsAnalyzed the nodes of every search.
+type GlobalStats struct {
+	SinceMs           int64               `json:"since_ms"`
+	Games             int64               `json:"games"`
+	GamesByMode       map[string]int64    `json:"games_by_mode"`
+	AverageMoves      float64             `json:"average_moves"`
+	AI                []GlobalAIResults   `json:"ai"`
+	PositionsAnalyzed int64               `json:"positions_analyzed"`
+	TTOccupancy       []TTOccupancySample `json:"tt_occupancy"`
+}
+
+type globalAIKey struct {
+	Color int
+	Depth int
+}
+
+type globalStatsStore struct {
+	mu          sync.Mutex
+	sinceMs     int64
+	gamesByMode map[string]int64
+	moves       int64
+	ai          map[globalAIKey]GlobalAIResults
+	nodes       int64
+	samples     []TTOccupancySample
+}
+
+type globalStatsSnapshot struct {
+	SinceMs     int64
+	GamesByMode map[string]int64
+	Moves       int64
+	AI          map[globalAIKey]GlobalAIResults
+	Nodes       int64
+	Samples     []TTOccupancySample
+}
+
+var globalStats = newGlobalStatsStore()
+
+func newGlobalStatsStore() *globalStatsStore {
+	return &globalStatsStore{
+		sinceMs:     time.Now().UnixMilli(),
+		gamesByMode: make(map[string]int64),
+		ai:          make(map[globalAIKey]GlobalAIResults),
+	}
+}
+
+// gameMode names a game's pairing as the settings API does.
+func gameMode(settings GameSettings) string {
+	switch {
+	case settings.BlackType == PlayerAI && settings.WhiteType == PlayerAI:
+		return "ai_vs_ai"
+	case settings.BlackType == PlayerHuman && settings.WhiteType == PlayerHuman:
+		return "human_vs_human"
+	}
+	return "ai_vs_human"
+}
+
+// recordGame counts a finished game, as stored in the game database.
+func (s *globalStatsStore) recordGame(record GameRecord) {
+	if !record.Finished() || len(record.Entries) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gamesByMode[gameMode(record.Settings)]++
+	s.moves += int64(len(record.Entries))
+	winner := WinnerFromStatus(record.Status)
+	for color, kind := range map[int]PlayerType{1: record.Settings.BlackType, 2: record.Settings.WhiteType} {
+		if kind != PlayerAI {
+			continue
+		}
+		key := globalAIKey{Color: color, Depth: record.AIDepth}
+		results := s.ai[key]
+		results.Color, results.Depth = color, record.AIDepth
+		results.Games++
+		switch winner {
+		case 0:
+			results.Draws++
+		case color:
+			results.Wins++
+		default:
+			results.Losses++
+		}
+		results.WinRate = float64(results.Wins) / float64(results.Games)
+		s.ai[key] = results
+	}
+}
+
+// recordNodes counts the nodes stats gained since it held startNodes.
+func (s *globalStatsStore) recordNodes(stats *SearchStats, startNodes int64) {
+	if stats == nil || stats.Nodes <= startNodes {
+		return
+	}
+	s.mu.Lock()
+	s.nodes += stats.Nodes - startNodes
+	s.mu.Unlock()
+}
+
+func ttOccupancy(now time.Time) TTOccupancySample {
+	sample := TTOccupancySample{AtMs: now.UnixMilli()}
+	sample.Entries, sample.Capacity = ttUsage()
+	if sample.Capacity > 0 {
+		sample.Fill = float64(sample.Entries) / float64(sample.Capacity)
+	}
+	return sample
+}
+
+func (s *globalStatsStore) sample(sample TTOccupancySample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, sample)
+	if len(s.samples) > globalStatsSamples {
+		s.samples = append([]TTOccupancySample(nil), s.samples[len(s.samples)-globalStatsSamples:]...)
+	}
+}
+
+func (s *globalStatsStore) stats() GlobalStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := GlobalStats{
+		SinceMs:           s.sinceMs,
+		GamesByMode:       make(map[string]int64, len(s.gamesByMode)),
+		AI:                make([]GlobalAIResults, 0, len(s.ai)),
+		PositionsAnalyzed: s.nodes,
+		TTOccupancy:       append([]TTOccupancySample{}, s.samples...),
+	}
+	for mode, count := range s.gamesByMode {
+		stats.GamesByMode[mode] = count
+		stats.Games += count
+	}
+	if stats.Games > 0 {
+		stats.AverageMoves = float64(s.moves) / float64(stats.Games)
+	}
+	for _, results := range s.ai {
+		stats.AI = append(stats.AI, results)
+	}
+	sort.Slice(stats.AI, func(i, j int) bool {
+		if stats.AI[i].Color != stats.AI[j].Color {
+			return stats.AI[i].Color < stats.AI[j].Color
+		}
+		return stats.AI[i].Depth < stats.AI[j].Depth
+	})
+	return stats
+}
+
+// GlobalStatistics returns the lifetime numbers, with the transposition
+// table's current occupancy as the last sample.
+func GlobalStatistics() GlobalStats {
+	stats := globalStats.stats()
+	stats.TTOccupancy = append(stats.TTOccupancy, ttOccupancy(time.Now()))
+	return stats
+}
+
+// RunGlobalStatsSampler samples the transposition table's occupancy every
+// globalStatsSampleEvery until ctx is done.
+func RunGlobalStatsSampler(ctx context.Context) {
+	ticker := time.NewTicker(globalStatsSampleEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			globalStats.sample(ttOccupancy(now))
+		}
+	}
+}
+
+func (s *globalStatsStore) snapshot() globalStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := globalStatsSnapshot{
+		SinceMs:     s.sinceMs,
+		GamesByMode: make(map[string]int64, len(s.gamesByMode)),
+		Moves:       s.moves,
+		AI:          make(map[globalAIKey]GlobalAIResults, len(s.ai)),
+		Nodes:       s.nodes,
+		Samples:     append([]TTOccupancySample(nil), s.samples...),
+	}
+	for mode, count := range s.gamesByMode {
+		snapshot.GamesByMode[mode] = count
+	}
+	for key, results := range s.ai {
+		snapshot.AI[key] = results
+	}
+	return snapshot
+}
+
+// load adds the counts of snapshot, saved by an earlier run, to the ones
+// made since this run started.
+func (s *globalStatsStore) load(snapshot globalStatsSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshot.SinceMs > 0 && snapshot.SinceMs < s.sinceMs {
+		s.sinceMs = snapshot.SinceMs
+	}
+	for mode, count := range snapshot.GamesByMode {
+		s.gamesByMode[mode] += count
+	}
+	s.moves += snapshot.Moves
+	for key, saved := range snapshot.AI {
+		results := s.ai[key]
+		results.Color, results.Depth = key.Color, key.Depth
+		results.Games += saved.Games
+		results.Wins += saved.Wins
+		results.Losses += saved.Losses
+		results.Draws += saved.Draws
+		if results.Games > 0 {
+			results.WinRate = float64(results.Wins) / float64(results.Games)
+		}
+		s.ai[key] = results
+	}
+	s.nodes += snapshot.Nodes
+	s.samples = append(append([]TTOccupancySample(nil), snapshot.Samples...), s.samples...)
+	if len(s.samples) > globalStatsSamples {
+		s.samples = s.samples[len(s.samples)-globalStatsSamples:]
+	}
+}
+
+func loadGlobalStats(cfg Config, store *globalStatsStore) {
+	if store == nil || cfg.GlobalStatsPath == "" {
+		log.Printf("[ai:cache] restored global stats: none (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.GlobalStatsPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open global stats %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored global stats: none (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot globalStatsSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode global stats %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored global stats from %s", path)
+}
+
+func persistGlobalStats(cfg Config, store *globalStatsStore) {
+	if store == nil || cfg.GlobalStatsPath == "" {
+		log.Printf("[ai:cache] stored global stats: none (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.GlobalStatsPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create global stats directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create global stats %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode global stats %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored global stats to %s", path)
+}
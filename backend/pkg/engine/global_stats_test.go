@@ -0,0 +1,58 @@
+package engine
+
+import "testing"
+
+func globalStatsTestRecord(status GameStatus, moves int) GameRecord {
+	settings := DefaultGameSettings()
+	settings.BlackType, settings.WhiteType = PlayerHuman, PlayerAI
+	record := GameRecord{Settings: settings, Status: status, AIDepth: 6}
+	for i := 0; i < moves; i++ {
+		record.Entries = append(record.Entries, HistoryEntry{Move: Move{X: i, Y: 0}})
+	}
+	return record
+}
+
+func TestGlobalStatsCountsGames(t *testing.T) {
+	store := newGlobalStatsStore()
+	store.recordGame(globalStatsTestRecord(StatusWhiteWon, 10))
+	store.recordGame(globalStatsTestRecord(StatusBlackWon, 20))
+	store.recordGame(globalStatsTestRecord(StatusRunning, 4))
+	store.recordNodes(&SearchStats{Nodes: 1500}, 500)
+
+	stats := store.stats()
+	if stats.Games != 2 || stats.GamesByMode["ai_vs_human"] != 2 || stats.AverageMoves != 15 {
+		t.Fatalf("unexpected game counts: %+v", stats)
+	}
+	if len(stats.AI) != 1 {
+		t.Fatalf("expected the engine's white results only, got %+v", stats.AI)
+	}
+	white := stats.AI[0]
+	if white.Color != 2 || white.Depth != 6 || white.Games != 2 || white.Wins != 1 || white.Losses != 1 || white.WinRate != 0.5 {
+		t.Fatalf("unexpected engine results: %+v", white)
+	}
+	if stats.PositionsAnalyzed != 1000 {
+		t.Fatalf("expected 1000 positions, got %d", stats.PositionsAnalyzed)
+	}
+}
+
+func TestGlobalStatsLoadAddsSavedCounts(t *testing.T) {
+	saved := newGlobalStatsStore()
+	saved.recordGame(globalStatsTestRecord(StatusWhiteWon, 10))
+	saved.sample(TTOccupancySample{AtMs: 1, Entries: 10, Capacity: 100, Fill: 0.1})
+	snapshot := saved.snapshot()
+	snapshot.SinceMs = 1
+
+	store := newGlobalStatsStore()
+	store.recordGame(globalStatsTestRecord(StatusWhiteWon, 30))
+	store.load(snapshot)
+	stats := store.stats()
+	if stats.SinceMs != 1 || stats.Games != 2 || stats.AverageMoves != 20 {
+		t.Fatalf("expected the saved game to be added, got %+v", stats)
+	}
+	if len(stats.AI) != 1 || stats.AI[0].Wins != 2 || stats.AI[0].WinRate != 1 {
+		t.Fatalf("unexpected engine results: %+v", stats.AI)
+	}
+	if len(stats.TTOccupancy) != 1 || stats.TTOccupancy[0].AtMs != 1 {
+		t.Fatalf("expected the saved sample, got %+v", stats.TTOccupancy)
+	}
+}
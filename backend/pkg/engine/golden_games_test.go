@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// goldenDepth is the search depth the golden games are checked at.
+const goldenDepth = 6
+
+type goldenGame struct {
+	Name      string        `json:"name"`
+	BoardSize int           `json:"board_size"`
+	Moves     []Move        `json:"moves"`
+	Checks    []goldenCheck `json:"checks"`
+}
+
+// goldenCheck expects the engine, to move after the first Ply moves, to play
+// one of Expected.
+type goldenCheck struct {
+	Ply      int    `json:"ply"`
+	Tag      string `json:"tag"`
+	Expected []Move `json:"expected"`
+}
+
+// TestGoldenGames replays the games of testdata/golden_games.json and checks
+// that the engine still finds the critical move at each tagged ply. Every
+// position is searched to a fixed depth without a clock and with fresh
+// caches, so the result depends neither on earlier tests nor on the machine.
+func TestGoldenGames(t *testing.T) {
+	data, err := os.ReadFile("testdata/golden_games.json")
+	if err != nil {
+		t.Fatalf("read golden games: %v", err)
+	}
+	var games []goldenGame
+	if err := json.Unmarshal(data, &games); err != nil {
+		t.Fatalf("decode golden games: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.AiDepth = goldenDepth
+	cfg.AiMinDepth = goldenDepth
+	cfg.AiMaxDepth = goldenDepth
+	cfg.AiTimeoutMs = 0
+	cfg.AiTimeBudgetMs = 0
+
+	for _, game := range games {
+		t.Run(game.Name, func(t *testing.T) {
+			settings := DefaultGameSettings()
+			if game.BoardSize > 0 {
+				settings.BoardSize = game.BoardSize
+			}
+			for _, check := range game.Checks {
+				if check.Ply > len(game.Moves) {
+					t.Fatalf("%s at ply %d: the game has %d moves", check.Tag, check.Ply, len(game.Moves))
+				}
+				state, rules, err := replayMoves(settings, game.Moves[:check.Ply])
+				if err != nil {
+					t.Fatalf("%s at ply %d: %v", check.Tag, check.Ply, err)
+				}
+				cache := newAISearchCache()
+				scores := ScoreBoard(state, rules, AIScoreSettings{
+					Depth:           goldenDepth,
+					BoardSize:       settings.BoardSize,
+					Player:          state.ToMove,
+					Cache:           &cache,
+					Config:          cfg,
+					Stats:           &SearchStats{},
+					DirectDepthOnly: true,
+				})
+				best, ok := bestMoveFromScores(scores, state, rules, settings.BoardSize)
+				if !ok || !containsMove(check.Expected, best) {
+					t.Fatalf("%s at ply %d: expected one of %v, got %v", check.Tag, check.Ply, check.Expected, best)
+				}
+			}
+		})
+	}
+}
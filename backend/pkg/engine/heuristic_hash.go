@@ -1,6 +1,9 @@
-package main
+package engine
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 const fnv64Offset = 1469598103934665603
 const fnv64Prime = 1099511628211
@@ -56,8 +59,20 @@ func resolvedHeuristicConfig(config Config) HeuristicConfig {
 	if heuristics.HangingPair == 0 {
 		heuristics.HangingPair = defaults.HangingPair
 	}
-	if heuristics.CaptureWinSoonScale == 0 {
-		heuristics.CaptureWinSoonScale = defaults.CaptureWinSoonScale
+	if heuristics.CaptureScaleEarly == 0 {
+		heuristics.CaptureScaleEarly = defaults.CaptureScaleEarly
+	}
+	if heuristics.CaptureScaleMid == 0 {
+		heuristics.CaptureScaleMid = defaults.CaptureScaleMid
+	}
+	if heuristics.CaptureScaleLate == 0 {
+		heuristics.CaptureScaleLate = defaults.CaptureScaleLate
+	}
+	if heuristics.CaptureScaleFinal == 0 {
+		heuristics.CaptureScaleFinal = defaults.CaptureScaleFinal
+	}
+	if heuristics.DefensiveCapture == 0 {
+		heuristics.DefensiveCapture = defaults.DefensiveCapture
 	}
 	if heuristics.CaptureInTwoLimit <= 0 {
 		heuristics.CaptureInTwoLimit = defaults.CaptureInTwoLimit
@@ -92,7 +107,11 @@ func heuristicHash(config HeuristicConfig) uint64 {
 	mix(config.CaptureNearWin)
 	mix(config.CaptureInTwo)
 	mix(config.HangingPair)
-	mix(config.CaptureWinSoonScale)
+	mix(config.CaptureScaleEarly)
+	mix(config.CaptureScaleMid)
+	mix(config.CaptureScaleLate)
+	mix(config.CaptureScaleFinal)
+	mix(config.DefensiveCapture)
 	mix(float64(config.CaptureInTwoLimit))
 	return hash
 }
@@ -100,3 +119,20 @@ func heuristicHash(config HeuristicConfig) uint64 {
 func heuristicHashFromConfig(config Config) uint64 {
 	return heuristicHash(resolvedHeuristicConfig(config))
 }
+
+// HeuristicHash identifies the weight set config evaluates with, once its
+// unset weights take their defaults. Transposition table entries are keyed
+// by it, so it tells which weights produced an entry.
+func HeuristicHash(config Config) string {
+	return FormatHeuristicHash(heuristicHashFromConfig(config))
+}
+
+// FormatHeuristicHash is how heuristic hashes appear in the API.
+func FormatHeuristicHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ResolvedHeuristics is the weight set config evaluates with.
+func ResolvedHeuristics(config Config) HeuristicConfig {
+	return resolvedHeuristicConfig(config)
+}
@@ -0,0 +1,96 @@
+package engine
+
+import "math"
+
+// HeuristicPromotion records a weight set made the live one. Unset weights
+// of the promoted set take their defaults. The transposition table keeps
+// entries per heuristic hash, so those of the previous set are dropped to
+// free their slots unless kept; the eval and root transposition caches are
+// not scoped by weights and are cleared.
+type HeuristicPromotion struct {
+	Heuristics       HeuristicConfig `json:"heuristics"`
+	HeuristicHash    string          `json:"heuristic_hash"`
+	PreviousHash     string          `json:"previous_hash"`
+	Source           string          `json:"source,omitempty"`
+	Changed          bool            `json:"changed"`
+	TTEntriesPruned  int             `json:"tt_entries_pruned"`
+	EvalCacheCleared bool            `json:"eval_cache_cleared"`
+}
+
+type heuristicWeight struct {
+	field string
+	value float64
+}
+
+// heuristicWeights lists the weights of h by their JSON names, the
+// capture_in_two_limit count last.
+func heuristicWeights(h HeuristicConfig) []heuristicWeight {
+	return []heuristicWeight{
+		{"open_4", h.Open4}, {"closed_4", h.Closed4}, {"broken_4", h.Broken4},
+		{"open_3", h.Open3}, {"broken_3", h.Broken3}, {"closed_3", h.Closed3},
+		{"open_2", h.Open2}, {"broken_2", h.Broken2},
+		{"fork_open_3", h.ForkOpen3}, {"fork_four_plus", h.ForkFourPlus},
+		{"capture_now", h.CaptureNow}, {"capture_double_threat", h.CaptureDoubleThreat},
+		{"capture_near_win", h.CaptureNearWin}, {"capture_in_two", h.CaptureInTwo},
+		{"hanging_pair", h.HangingPair},
+		{"capture_scale_early", h.CaptureScaleEarly}, {"capture_scale_mid", h.CaptureScaleMid},
+		{"capture_scale_late", h.CaptureScaleLate}, {"capture_scale_final", h.CaptureScaleFinal},
+		{"defensive_capture", h.DefensiveCapture},
+		{"capture_in_two_limit", float64(h.CaptureInTwoLimit)},
+	}
+}
+
+// ValidateHeuristics rejects weights the evaluation cannot use.
+func ValidateHeuristics(h HeuristicConfig) error {
+	for _, weight := range heuristicWeights(h) {
+		if math.IsNaN(weight.value) || math.IsInf(weight.value, 0) || weight.value < 0 {
+			return fieldErrorf("heuristics."+weight.field, "%s must be a finite, non-negative weight", weight.field)
+		}
+	}
+	return nil
+}
+
+// PromoteHeuristics makes heuristics the live weight set in one config
+// update, so no search sees half of it, registers it and handles the
+// caches as HeuristicPromotion describes. Live players pick the new weights
+// up on their next search.
+func PromoteHeuristics(heuristics HeuristicConfig, source string, keepPrevious bool) (HeuristicPromotion, error) {
+	if err := ValidateHeuristics(heuristics); err != nil {
+		return HeuristicPromotion{}, err
+	}
+	unlock := lockDefaultCache()
+	defer unlock()
+	previous, next := configFile.modify(func(config *Config) {
+		config.Heuristics = heuristics
+		config.Heuristics = resolvedHeuristicConfig(*config)
+	})
+	previousHash := heuristicHashFromConfig(previous)
+	nextHash := heuristicHashFromConfig(next)
+	promotion := HeuristicPromotion{
+		Heuristics:    next.Heuristics,
+		HeuristicHash: FormatHeuristicHash(nextHash),
+		PreviousHash:  FormatHeuristicHash(previousHash),
+		Source:        source,
+		Changed:       nextHash != previousHash,
+	}
+	HeuristicRegistry.noteActive(next.Heuristics, source)
+	if !promotion.Changed {
+		return promotion, nil
+	}
+	defaultCache.mu.Lock()
+	tt := defaultCache.TT
+	evalCache := defaultCache.EvalCache
+	rootTranspose := defaultCache.RootTranspose
+	defaultCache.mu.Unlock()
+	if tt != nil && !keepPrevious {
+		promotion.TTEntriesPruned = tt.DeleteByHeuristicHash(previousHash)
+	}
+	if evalCache != nil {
+		evalCache.Clear()
+		promotion.EvalCacheCleared = true
+	}
+	if rootTranspose != nil {
+		rootTranspose.Clear()
+	}
+	return promotion, nil
+}
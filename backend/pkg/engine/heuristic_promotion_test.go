@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPromoteHeuristicsSwapsWeightsAndScopesCaches(t *testing.T) {
+	withTestHeuristicRegistry(t)
+	prev := GetConfig()
+	FlushGlobalCaches()
+	oldHash := heuristicHashFromConfig(prev)
+	tt := EnsureTT(SharedSearchCache(), prev)
+	if tt == nil {
+		t.Fatalf("expected the shared transposition table")
+	}
+	tt.Store(0x1234, oldHash, 4, 100, TTExact, Move{X: 1, Y: 1}, TTMeta{})
+	evalCache := ensureEvalCache(SharedSearchCache(), prev)
+	evalCache.Put(0x5678, 42)
+
+	promoted := prev.Heuristics
+	promoted.Open3 *= 2
+	promoted.Broken2 = 0
+	promotion, err := PromoteHeuristics(promoted, "trainer champion-g3", false)
+	if err != nil {
+		t.Fatalf("promotion failed: %v", err)
+	}
+	live := GetConfig()
+	if live.Heuristics.Open3 != promoted.Open3 || live.Heuristics.Broken2 != prev.Heuristics.Broken2 {
+		t.Fatalf("live heuristics = %+v, want the promoted set with unset weights defaulted", live.Heuristics)
+	}
+	if !promotion.Changed || promotion.HeuristicHash != HeuristicHash(live) || promotion.PreviousHash != FormatHeuristicHash(oldHash) {
+		t.Fatalf("promotion = %+v, want the hashes of both sets", promotion)
+	}
+	if promotion.TTEntriesPruned != 1 || !promotion.EvalCacheCleared {
+		t.Fatalf("promotion = %+v, want the old entry pruned and the eval cache cleared", promotion)
+	}
+	if _, ok := evalCache.Get(0x5678); ok {
+		t.Fatalf("expected the eval cache to be cleared")
+	}
+
+	again, err := PromoteHeuristics(promoted, "trainer champion-g3", false)
+	if err != nil || again.Changed {
+		t.Fatalf("promoting the live set again = %+v, %v, want no change", again, err)
+	}
+}
+
+func TestPromoteHeuristicsRejectsUnusableWeights(t *testing.T) {
+	prev := GetConfig()
+	heuristics := prev.Heuristics
+	heuristics.Open4 = math.NaN()
+	_, err := PromoteHeuristics(heuristics, "", false)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "heuristics.open_4" {
+		t.Fatalf("err = %v, want a field error on open_4", err)
+	}
+	if HeuristicHash(GetConfig()) != HeuristicHash(prev) {
+		t.Fatalf("expected the live heuristics to be left alone")
+	}
+}
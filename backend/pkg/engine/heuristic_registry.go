@@ -0,0 +1,390 @@
+package engine
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heuristicRegistryHistoryLimit is how many activations the registry keeps.
+const heuristicRegistryHistoryLimit = 1000
+
+// Heuristic sources: the built-in defaults, a trainer generation
+// ("trainer-gen12") and a set made live by hand.
+const (
+	HeuristicSourceDefault = "default"
+	HeuristicSourceManual  = "manual"
+)
+
+// TrainerHeuristicSource names the weights a trainer generation produced.
+func TrainerHeuristicSource(generation int) string {
+	return fmt.Sprintf("trainer-gen%d", generation)
+}
+
+// HeuristicRecord is a weight set the registry knows, by heuristic hash.
+// Source is where it first came from. GauntletRate and GauntletElo are the
+// trainer's last gauntlet result for it, if any. Active is set on the live
+// set.
+type HeuristicRecord struct {
+	Hash            string          `json:"hash"`
+	Heuristics      HeuristicConfig `json:"heuristics"`
+	Source          string          `json:"source"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastActivatedAt time.Time       `json:"last_activated_at,omitempty"`
+	Activations     int             `json:"activations"`
+	GauntletRate    *float64        `json:"gauntlet_rate,omitempty"`
+	GauntletElo     *float64        `json:"gauntlet_elo,omitempty"`
+	Active          bool            `json:"active"`
+}
+
+// HeuristicActivation is one change of the live weight set: Hash replaced
+// Previous, for Source ("manual", "trainer-gen12", "rollback", ...).
+type HeuristicActivation struct {
+	Hash     string    `json:"hash"`
+	Previous string    `json:"previous"`
+	Source   string    `json:"source"`
+	At       time.Time `json:"at"`
+}
+
+// HeuristicWeightDiff is one weight of two sets side by side.
+type HeuristicWeightDiff struct {
+	Field string  `json:"field"`
+	A     float64 `json:"a"`
+	B     float64 `json:"b"`
+	Delta float64 `json:"delta"`
+	// Ratio is B over A, 0 when A is.
+	Ratio float64 `json:"ratio"`
+}
+
+// HeuristicComparison compares two registered sets weight by weight.
+type HeuristicComparison struct {
+	A       HeuristicRecord       `json:"a"`
+	B       HeuristicRecord       `json:"b"`
+	Weights []HeuristicWeightDiff `json:"weights"`
+	Changed int                   `json:"changed"`
+}
+
+// HeuristicRegistryListing is the whole registry: the live set's hash, the
+// sets newest first and the activations latest first.
+type HeuristicRegistryListing struct {
+	Active  string                `json:"active"`
+	Records []HeuristicRecord     `json:"records"`
+	History []HeuristicActivation `json:"history"`
+}
+
+// HeuristicRegistryStore records every weight set the engine has played
+// with and every change of the live one, so a set can be found by the hash
+// its cache entries carry and made live again.
+type HeuristicRegistryStore struct {
+	mu      sync.Mutex
+	records map[string]*HeuristicRecord
+	history []HeuristicActivation
+	active  string
+	now     func() time.Time
+}
+
+type heuristicRegistrySnapshot struct {
+	Records []HeuristicRecord
+	History []HeuristicActivation
+	Active  string
+}
+
+func NewHeuristicRegistry() *HeuristicRegistryStore {
+	registry := &HeuristicRegistryStore{records: map[string]*HeuristicRecord{}, now: time.Now}
+	registry.Register(DefaultConfig().Heuristics, HeuristicSourceDefault)
+	registry.active = HeuristicHash(DefaultConfig())
+	return registry
+}
+
+// HeuristicRegistry is the process-wide registry.
+var HeuristicRegistry = NewHeuristicRegistry()
+
+func heuristicRecordHash(heuristics HeuristicConfig) (HeuristicConfig, string) {
+	config := DefaultConfig()
+	config.Heuristics = heuristics
+	resolved := resolvedHeuristicConfig(config)
+	config.Heuristics = resolved
+	return resolved, HeuristicHash(config)
+}
+
+// Register adds a weight set, unset weights defaulted, and returns its
+// record. A set already registered keeps its source and date.
+func (r *HeuristicRegistryStore) Register(heuristics HeuristicConfig, source string) HeuristicRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recordLocked(r.registerLocked(heuristics, source))
+}
+
+func (r *HeuristicRegistryStore) registerLocked(heuristics HeuristicConfig, source string) *HeuristicRecord {
+	resolved, hash := heuristicRecordHash(heuristics)
+	if record, ok := r.records[hash]; ok {
+		return record
+	}
+	if source == "" {
+		source = HeuristicSourceManual
+	}
+	record := &HeuristicRecord{Hash: hash, Heuristics: resolved, Source: source, CreatedAt: r.now().UTC()}
+	r.records[hash] = record
+	return record
+}
+
+// SetGauntlet stores a gauntlet result for a registered set.
+func (r *HeuristicRegistryStore) SetGauntlet(hash string, rate, elo *float64) (HeuristicRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[strings.ToLower(hash)]
+	if !ok {
+		return HeuristicRecord{}, false
+	}
+	if rate != nil {
+		value := *rate
+		record.GauntletRate = &value
+	}
+	if elo != nil {
+		value := *elo
+		record.GauntletElo = &value
+	}
+	return r.recordLocked(record), true
+}
+
+func (r *HeuristicRegistryStore) recordLocked(record *HeuristicRecord) HeuristicRecord {
+	out := *record
+	out.Active = record.Hash == r.active
+	return out
+}
+
+// noteActive records heuristics as the live set for source when they are
+// not already, and reports whether they were not.
+func (r *HeuristicRegistryStore) noteActive(heuristics HeuristicConfig, source string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.registerLocked(heuristics, source)
+	if record.Hash == r.active {
+		return false
+	}
+	if source == "" {
+		source = HeuristicSourceManual
+	}
+	now := r.now().UTC()
+	r.history = append(r.history, HeuristicActivation{Hash: record.Hash, Previous: r.active, Source: source, At: now})
+	if len(r.history) > heuristicRegistryHistoryLimit {
+		r.history = r.history[len(r.history)-heuristicRegistryHistoryLimit:]
+	}
+	r.active = record.Hash
+	record.Activations++
+	record.LastActivatedAt = now
+	return true
+}
+
+// NoteLiveHeuristics registers the live weights, changed by a config
+// update rather than a promotion, as activated for source, and reports
+// whether they changed.
+func NoteLiveHeuristics(source string) bool {
+	return HeuristicRegistry.noteActive(GetConfig().Heuristics, source)
+}
+
+// Get returns the set registered under hash.
+func (r *HeuristicRegistryStore) Get(hash string) (HeuristicRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[strings.ToLower(hash)]
+	if !ok {
+		return HeuristicRecord{}, false
+	}
+	return r.recordLocked(record), true
+}
+
+// List returns the registry, sets newest first and activations latest
+// first.
+func (r *HeuristicRegistryStore) List() HeuristicRegistryListing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	listing := HeuristicRegistryListing{
+		Active:  r.active,
+		Records: make([]HeuristicRecord, 0, len(r.records)),
+		History: make([]HeuristicActivation, 0, len(r.history)),
+	}
+	for _, record := range r.records {
+		listing.Records = append(listing.Records, r.recordLocked(record))
+	}
+	sort.Slice(listing.Records, func(i, j int) bool {
+		if !listing.Records[i].CreatedAt.Equal(listing.Records[j].CreatedAt) {
+			return listing.Records[i].CreatedAt.After(listing.Records[j].CreatedAt)
+		}
+		return listing.Records[i].Hash < listing.Records[j].Hash
+	})
+	for i := len(r.history) - 1; i >= 0; i-- {
+		listing.History = append(listing.History, r.history[i])
+	}
+	return listing
+}
+
+// Compare lines up the weights of the sets registered under a and b.
+func (r *HeuristicRegistryStore) Compare(a, b string) (HeuristicComparison, error) {
+	recordA, ok := r.Get(a)
+	if !ok {
+		return HeuristicComparison{}, fieldErrorf("a", "no heuristics registered under %s", a)
+	}
+	recordB, ok := r.Get(b)
+	if !ok {
+		return HeuristicComparison{}, fieldErrorf("b", "no heuristics registered under %s", b)
+	}
+	comparison := HeuristicComparison{A: recordA, B: recordB}
+	weightsB := heuristicWeights(recordB.Heuristics)
+	for i, weight := range heuristicWeights(recordA.Heuristics) {
+		diff := HeuristicWeightDiff{Field: weight.field, A: weight.value, B: weightsB[i].value, Delta: weightsB[i].value - weight.value}
+		if weight.value != 0 {
+			diff.Ratio = weightsB[i].value / weight.value
+		}
+		if diff.Delta != 0 {
+			comparison.Changed++
+		}
+		comparison.Weights = append(comparison.Weights, diff)
+	}
+	return comparison, nil
+}
+
+// rollbackTarget is the set that was live before the current one.
+func (r *HeuristicRegistryStore) rollbackTarget() (HeuristicRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.history) == 0 {
+		return HeuristicRecord{}, false
+	}
+	last := r.history[len(r.history)-1]
+	record, ok := r.records[last.Previous]
+	if !ok || last.Hash != r.active {
+		return HeuristicRecord{}, false
+	}
+	return r.recordLocked(record), true
+}
+
+// ActivateHeuristics makes the set registered under hash the live one, as
+// PromoteHeuristics does, recording source as the reason.
+func ActivateHeuristics(hash, source string) (HeuristicPromotion, error) {
+	record, ok := HeuristicRegistry.Get(hash)
+	if !ok {
+		return HeuristicPromotion{}, fieldErrorf("hash", "no heuristics registered under %s", hash)
+	}
+	return PromoteHeuristics(record.Heuristics, source, false)
+}
+
+// RollbackHeuristics makes the set that was live before the current one
+// live again. Rolling back twice returns to where it started.
+func RollbackHeuristics() (HeuristicPromotion, error) {
+	record, ok := HeuristicRegistry.rollbackTarget()
+	if !ok {
+		return HeuristicPromotion{}, fmt.Errorf("no earlier heuristics to roll back to")
+	}
+	return PromoteHeuristics(record.Heuristics, "rollback", false)
+}
+
+func (r *HeuristicRegistryStore) snapshot() heuristicRegistrySnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := heuristicRegistrySnapshot{Active: r.active, History: append([]HeuristicActivation(nil), r.history...)}
+	for _, record := range r.records {
+		snapshot.Records = append(snapshot.Records, *record)
+	}
+	return snapshot
+}
+
+func (r *HeuristicRegistryStore) load(snapshot heuristicRegistrySnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range snapshot.Records {
+		record := snapshot.Records[i]
+		r.records[record.Hash] = &record
+	}
+	r.history = snapshot.History
+	if _, ok := r.records[snapshot.Active]; ok {
+		r.active = snapshot.Active
+	}
+}
+
+func (r *HeuristicRegistryStore) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+// loadHeuristicRegistry restores the registry and makes the set that was
+// live when it was stored live again.
+func loadHeuristicRegistry(cfg Config, registry *HeuristicRegistryStore) {
+	if registry == nil || cfg.HeuristicRegistryPath == "" {
+		log.Printf("[ai:cache] restored heuristic registry: 0 sets (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.HeuristicRegistryPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open heuristic registry %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored heuristic registry: 0 sets (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot heuristicRegistrySnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode heuristic registry %s: %v", path, err)
+		return
+	}
+	registry.load(snapshot)
+	log.Printf("[ai:cache] restored heuristic registry from %s (%d sets)", path, registry.Len())
+	if record, ok := registry.Get(snapshot.Active); ok && record.Hash != HeuristicHash(cfg) {
+		configFile.restore(func(config *Config) {
+			config.Heuristics = record.Heuristics
+		})
+		log.Printf("[ai:cache] restored live heuristics %s (%s)", record.Hash, record.Source)
+	}
+}
+
+func persistHeuristicRegistry(cfg Config, registry *HeuristicRegistryStore) {
+	if registry == nil || cfg.HeuristicRegistryPath == "" {
+		log.Printf("[ai:cache] stored heuristic registry: 0 sets (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.HeuristicRegistryPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create heuristic registry directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := registry.snapshot()
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create heuristic registry %s: %v", path, err)
+		return
+	}
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		file.Close()
+		log.Printf("[ai:cache] failed to encode heuristic registry %s: %v", path, err)
+		return
+	}
+	if err := file.Close(); err != nil {
+		log.Printf("[ai:cache] failed to write heuristic registry %s: %v", path, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("[ai:cache] failed to replace heuristic registry %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored heuristic registry to %s (%d sets)", path, len(snapshot.Records))
+}
+
+// SaveHeuristicRegistry stores the registry now rather than at shutdown,
+// after a change worth keeping through a crash.
+func SaveHeuristicRegistry() {
+	persistHeuristicRegistry(GetConfig(), HeuristicRegistry)
+}
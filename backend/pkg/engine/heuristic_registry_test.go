@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func withTestHeuristicRegistry(t *testing.T) {
+	t.Helper()
+	prevConfig := GetConfig()
+	prevRegistry := HeuristicRegistry
+	HeuristicRegistry = NewHeuristicRegistry()
+	t.Cleanup(func() {
+		HeuristicRegistry = prevRegistry
+		configStore.Update(prevConfig)
+		FlushGlobalCaches()
+	})
+}
+
+func TestHeuristicRegistryTracksPromotionsAndRollsBack(t *testing.T) {
+	withTestHeuristicRegistry(t)
+	defaults := DefaultConfig().Heuristics
+	first := defaults
+	first.Open3 *= 1.5
+	second := defaults
+	second.Open3 *= 2
+	second.Closed4 *= 0.5
+
+	if _, err := PromoteHeuristics(first, TrainerHeuristicSource(1), false); err != nil {
+		t.Fatalf("promotion failed: %v", err)
+	}
+	promotion, err := PromoteHeuristics(second, TrainerHeuristicSource(2), false)
+	if err != nil {
+		t.Fatalf("promotion failed: %v", err)
+	}
+	listing := HeuristicRegistry.List()
+	if len(listing.Records) != 3 || len(listing.History) != 2 || listing.Active != promotion.HeuristicHash {
+		t.Fatalf("listing = %+v, want the defaults and both promotions with the second live", listing)
+	}
+	if listing.History[0].Source != "trainer-gen2" || listing.History[0].Previous != promotion.PreviousHash {
+		t.Fatalf("latest activation = %+v, want trainer-gen2 replacing the first", listing.History[0])
+	}
+
+	comparison, err := HeuristicRegistry.Compare(promotion.PreviousHash, promotion.HeuristicHash)
+	if err != nil || comparison.Changed != 2 {
+		t.Fatalf("comparison = %+v, %v, want open_3 and closed_4 changed", comparison, err)
+	}
+	if _, err := HeuristicRegistry.Compare(promotion.PreviousHash, "0000000000000000"); err == nil {
+		t.Fatalf("expected an unknown hash to be rejected")
+	}
+
+	rollback, err := RollbackHeuristics()
+	if err != nil || rollback.HeuristicHash != promotion.PreviousHash {
+		t.Fatalf("rollback = %+v, %v, want the first promotion back", rollback, err)
+	}
+	if GetConfig().Heuristics.Open3 != first.Open3 {
+		t.Fatalf("live open_3 = %v, want the first promotion's %v", GetConfig().Heuristics.Open3, first.Open3)
+	}
+	if record, ok := HeuristicRegistry.Get(rollback.HeuristicHash); !ok || !record.Active || record.Activations != 2 || record.Source != "trainer-gen1" {
+		t.Fatalf("record = %+v, want the first promotion live, activated twice", record)
+	}
+
+	activated, err := ActivateHeuristics(HeuristicHash(DefaultConfig()), "activate")
+	if err != nil || !activated.Changed || GetConfig().Heuristics != defaults {
+		t.Fatalf("activation = %+v, %v, want the defaults live", activated, err)
+	}
+}
+
+func TestHeuristicRegistryPersistsAndRestoresTheLiveSet(t *testing.T) {
+	withTestHeuristicRegistry(t)
+	cfg := GetConfig()
+	cfg.HeuristicRegistryPath = filepath.Join(t.TempDir(), "registry.gob")
+	tuned := DefaultConfig().Heuristics
+	tuned.Broken3 *= 3
+	promotion, err := PromoteHeuristics(tuned, TrainerHeuristicSource(4), false)
+	if err != nil {
+		t.Fatalf("promotion failed: %v", err)
+	}
+	rate, elo := 0.75, 190.8
+	HeuristicRegistry.SetGauntlet(promotion.HeuristicHash, &rate, &elo)
+	persistHeuristicRegistry(cfg, HeuristicRegistry)
+
+	configStore.Update(DefaultConfig())
+	restored := NewHeuristicRegistry()
+	loadHeuristicRegistry(cfg, restored)
+	record, ok := restored.Get(promotion.HeuristicHash)
+	if !ok || !record.Active || record.GauntletRate == nil || *record.GauntletRate != rate || record.GauntletElo == nil {
+		t.Fatalf("record = %+v, want the promoted set restored live with its gauntlet", record)
+	}
+	if HeuristicHash(GetConfig()) != promotion.HeuristicHash {
+		t.Fatalf("expected the restored registry to make its live set live again")
+	}
+}
@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"encoding/gob"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	humanMovesDefaultLimit = 1 << 18
+	// humanMovesMinSamples is how many human moves a position needs before
+	// the engine imitates them.
+	humanMovesMinSamples = 5
+	// humanMovesMargin is how far below the best root score, from the
+	// engine's side, an imitated move may be.
+	humanMovesMargin = 3000.0
+)
+
+// HumanMoveStat is a move humans played from a position, with its share of
+// all human moves recorded there.
+type HumanMoveStat struct {
+	Move      Move    `json:"move"`
+	Count     int     `json:"count"`
+	Frequency float64 `json:"frequency"`
+}
+
+// humanMoveStore counts the moves humans played in each position. Positions
+// are keyed like the transposition table and moves are kept on the
+// canonical orientation, so rotated and mirrored games count together.
+type humanMoveStore struct {
+	mu        sync.Mutex
+	positions map[uint64]map[uint16]uint32
+	limit     int
+}
+
+type humanMoveSnapshot struct {
+	Positions map[uint64]map[uint16]uint32
+}
+
+var humanMoves = newHumanMoveStore(humanMovesDefaultLimit)
+
+func newHumanMoveStore(limit int) *humanMoveStore {
+	if limit <= 0 {
+		limit = humanMovesDefaultLimit
+	}
+	return &humanMoveStore{positions: make(map[uint64]map[uint16]uint32), limit: limit}
+}
+
+// Record counts move, played by a human from state.
+func (s *humanMoveStore) Record(state GameState, move Move) {
+	size := state.Board.Size()
+	if !move.IsValid(size) {
+		return
+	}
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	key := ttKeyFor(state, size)
+	cx, cy := transformCoord(move.X, move.Y, size, symmetryTransforms[canonicalSymIndex(state.HashSym)])
+	cell := uint16(cy*size + cx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.positions[key]
+	if !ok {
+		if len(s.positions) >= s.limit {
+			return
+		}
+		counts = make(map[uint16]uint32)
+		s.positions[key] = counts
+	}
+	if counts[cell] < ^uint32(0) {
+		counts[cell]++
+	}
+}
+
+// Moves returns the human moves recorded for the position key on a board of
+// size, on the canonical orientation, most played first.
+func (s *humanMoveStore) Moves(key uint64, size int) []HumanMoveStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := s.positions[key]
+	total := 0
+	for _, count := range counts {
+		total += int(count)
+	}
+	stats := make([]HumanMoveStat, 0, len(counts))
+	for cell, count := range counts {
+		move := Move{X: int(cell) % size, Y: int(cell) / size}
+		if !move.IsValid(size) {
+			continue
+		}
+		stats = append(stats, HumanMoveStat{Move: move, Count: int(count), Frequency: float64(count) / float64(total)})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		if stats[i].Move.Y != stats[j].Move.Y {
+			return stats[i].Move.Y < stats[j].Move.Y
+		}
+		return stats[i].Move.X < stats[j].Move.X
+	})
+	return stats
+}
+
+// ForState returns the human moves recorded for state, on its own
+// orientation.
+func (s *humanMoveStore) ForState(state GameState) []HumanMoveStat {
+	size := state.Board.Size()
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	transform := symmetryTransforms[canonicalSymIndex(state.HashSym)]
+	stats := s.Moves(ttKeyFor(state, size), size)
+	for i := range stats {
+		stats[i].Move.X, stats[i].Move.Y = inverseTransformCoord(stats[i].Move.X, stats[i].Move.Y, size, transform)
+	}
+	return stats
+}
+
+func (s *humanMoveStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.positions)
+}
+
+// HumanMoves returns the moves humans played from the position key on a
+// board of size, on the canonical orientation of the position.
+func HumanMoves(key uint64, size int) []HumanMoveStat {
+	return humanMoves.Moves(key, size)
+}
+
+// PositionKey is the canonical key of state, shared by its rotations and
+// mirror images, as used by the transposition table and HumanMoves.
+func PositionKey(state GameState) uint64 {
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	return ttKeyFor(state, state.Board.Size())
+}
+
+// maybeSelectHumanMove plays like a human with probability
+// Config.AiHumanMoveBlend: it draws one of the moves humans played from the
+// position, weighted by how often they did, among those scoring within
+// humanMovesMargin of the best.
+func maybeSelectHumanMove(scores []float64, state GameState, rules Rules, settings AIScoreSettings, currentBest Move, rng *rand.Rand) (Move, bool) {
+	blend := settings.Config.AiHumanMoveBlend
+	size := settings.BoardSize
+	if blend <= 0 || !currentBest.IsValid(size) || len(scores) < size*size {
+		return Move{}, false
+	}
+	if blend < 1 && rng.Float64() >= blend {
+		return Move{}, false
+	}
+	stats := humanMoves.ForState(state)
+	total := 0
+	for _, stat := range stats {
+		total += stat.Count
+	}
+	if total < humanMovesMinSamples {
+		return Move{}, false
+	}
+	sign := scoreSign(state.ToMove)
+	bestScore := sign * scores[currentBest.Y*size+currentBest.X]
+	if math.IsInf(bestScore, 0) || math.IsNaN(bestScore) {
+		return Move{}, false
+	}
+	candidates := stats[:0]
+	weight := 0
+	for _, stat := range stats {
+		raw := scores[stat.Move.Y*size+stat.Move.X]
+		if raw == illegalScore || math.IsInf(raw, 0) || math.IsNaN(raw) || bestScore-sign*raw > humanMovesMargin {
+			continue
+		}
+		if ok, _ := rules.IsLegal(state, stat.Move, state.ToMove); !ok {
+			continue
+		}
+		candidates = append(candidates, stat)
+		weight += stat.Count
+	}
+	if weight == 0 {
+		return Move{}, false
+	}
+	pick := rng.Intn(weight)
+	for _, stat := range candidates {
+		if pick < stat.Count {
+			if stat.Move == currentBest {
+				return Move{}, false
+			}
+			return stat.Move, true
+		}
+		pick -= stat.Count
+	}
+	return Move{}, false
+}
+
+func (s *humanMoveStore) snapshot() humanMoveSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	positions := make(map[uint64]map[uint16]uint32, len(s.positions))
+	for key, counts := range s.positions {
+		copied := make(map[uint16]uint32, len(counts))
+		for cell, count := range counts {
+			copied[cell] = count
+		}
+		positions[key] = copied
+	}
+	return humanMoveSnapshot{Positions: positions}
+}
+
+func (s *humanMoveStore) load(snapshot humanMoveSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions = make(map[uint64]map[uint16]uint32, len(snapshot.Positions))
+	for key, counts := range snapshot.Positions {
+		if len(s.positions) >= s.limit {
+			break
+		}
+		s.positions[key] = counts
+	}
+}
+
+func loadHumanMoves(cfg Config, store *humanMoveStore) {
+	if store == nil || cfg.HumanMovesPath == "" {
+		log.Printf("[ai:cache] restored human moves: 0 positions (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.HumanMovesPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open human moves %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored human moves: 0 positions (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot humanMoveSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode human moves %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored human moves from %s (%d positions)", path, store.Len())
+}
+
+func persistHumanMoves(cfg Config, store *humanMoveStore) {
+	if store == nil || cfg.HumanMovesPath == "" {
+		log.Printf("[ai:cache] stored human moves: 0 positions (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.HumanMovesPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create human moves directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create human moves %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode human moves %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored human moves to %s (%d positions)", path, len(snapshot.Positions))
+}
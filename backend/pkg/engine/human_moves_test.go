@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHumanMovesCountRotatedPositionsTogether(t *testing.T) {
+	store := newHumanMoveStore(16)
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	state := DefaultGameState(settings)
+	state.Board.Set(2, 1, CellBlack)
+	state.ToMove = PlayerWhite
+	state.recomputeHashes()
+	store.Record(state, Move{X: 3, Y: 1})
+
+	// The same position rotated by a quarter turn: (x, y) -> (8-y, x).
+	rotated := DefaultGameState(settings)
+	rotated.Board.Set(7, 2, CellBlack)
+	rotated.ToMove = PlayerWhite
+	rotated.recomputeHashes()
+	store.Record(rotated, Move{X: 7, Y: 3})
+	store.Record(rotated, Move{X: 4, Y: 4})
+
+	if PositionKey(state) != PositionKey(rotated) {
+		t.Fatalf("expected rotated positions to share a key")
+	}
+	stats := store.ForState(state)
+	if len(stats) != 2 || stats[0].Count != 2 || !stats[0].Move.Equals(Move{X: 3, Y: 1}) {
+		t.Fatalf("expected the rotated reply to count with the original, got %+v", stats)
+	}
+	if stats[1].Frequency != 1.0/3 || !stats[1].Move.Equals(Move{X: 4, Y: 4}) {
+		t.Fatalf("unexpected second move %+v", stats[1])
+	}
+	if len(store.Moves(PositionKey(state), 9)) != 2 {
+		t.Fatalf("expected the canonical lookup to find both moves")
+	}
+}
+
+func TestOnlyHumanSidesAreRecorded(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 7
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	game := newHeadlessGame(settings)
+	first := game.State()
+	game.TryApplyMove(Move{X: 0, Y: 6})
+	second := game.State()
+	game.TryApplyMove(Move{X: 6, Y: 6})
+	if stats := humanMoves.ForState(first); len(stats) != 1 || !stats[0].Move.Equals(Move{X: 0, Y: 6}) {
+		t.Fatalf("expected the human move to be recorded, got %+v", stats)
+	}
+	if stats := humanMoves.ForState(second); len(stats) != 0 {
+		t.Fatalf("expected the engine side to be ignored, got %+v", stats)
+	}
+}
+
+func TestHumanMoveBlendPlaysRecordedMoves(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.Board.Set(1, 7, CellBlack)
+	state.ToMove = PlayerWhite
+	state.recomputeHashes()
+	for i := 0; i < humanMovesMinSamples; i++ {
+		humanMoves.Record(state, Move{X: 2, Y: 6})
+	}
+
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	best := Move{X: 1, Y: 6}
+	scores[best.Y*9+best.X] = -200
+	scores[6*9+2] = 100
+	cfg := DefaultConfig()
+	aiSettings := AIScoreSettings{BoardSize: 9, Player: PlayerWhite, Config: cfg}
+	rng := rand.New(rand.NewSource(1))
+	if _, changed := maybeSelectHumanMove(scores, state, rules, aiSettings, best, rng); changed {
+		t.Fatalf("expected no imitation without a blend")
+	}
+	cfg.AiHumanMoveBlend = 1
+	aiSettings.Config = cfg
+	move, changed := maybeSelectHumanMove(scores, state, rules, aiSettings, best, rng)
+	if !changed || !move.Equals(Move{X: 2, Y: 6}) {
+		t.Fatalf("expected the human move, got %+v changed=%v", move, changed)
+	}
+	scores[6*9+2] = 5000
+	if _, changed := maybeSelectHumanMove(scores, state, rules, aiSettings, best, rng); changed {
+		t.Fatalf("expected a much worse human move to be ignored")
+	}
+}
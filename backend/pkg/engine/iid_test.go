@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShouldApplyIID(t *testing.T) {
+	config := DefaultConfig()
+	config.AiEnableIID = true
+	config.AiIIDMinDepth = 4
+	config.AiIIDReduction = 2
+	if !shouldApplyIID(config, 4, math.Inf(-1), math.Inf(1)) {
+		t.Fatalf("expected IID at the minimum depth with an open window")
+	}
+	if shouldApplyIID(config, 3, math.Inf(-1), math.Inf(1)) {
+		t.Fatalf("expected no IID below the minimum depth")
+	}
+	if shouldApplyIID(config, 6, 100, 100.5) {
+		t.Fatalf("expected no IID in a null window")
+	}
+	config.AiIIDReduction = 5
+	if shouldApplyIID(config, 5, math.Inf(-1), math.Inf(1)) {
+		t.Fatalf("expected no IID when the reduction leaves no depth")
+	}
+	config.AiEnableIID = false
+	if shouldApplyIID(config, 8, math.Inf(-1), math.Inf(1)) {
+		t.Fatalf("expected no IID when disabled")
+	}
+}
+
+func TestIIDFindsAMoveForAColdNode(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.AiDepth = 5
+	cfg.AiMinDepth = 5
+	cfg.AiMaxDepth = 5
+	cfg.AiEnableIID = true
+	cfg.AiIIDMinDepth = 3
+	cfg.AiIIDReduction = 2
+	cfg.AiEnableEvalCache = false
+	cfg.AiEnableAspiration = false
+	cfg.AiTimeBudgetMs = 0
+	configStore.Update(cfg)
+	defer func() {
+		configStore.Update(prev)
+		FlushGlobalCaches()
+	}()
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.Board.Set(4, 4, CellBlack)
+	state.Board.Set(5, 5, CellWhite)
+	state.recomputeHashes()
+
+	cache := newAISearchCache()
+	stats := &SearchStats{}
+	ScoreBoard(state, rules, AIScoreSettings{
+		Depth:           5,
+		BoardSize:       settings.BoardSize,
+		Player:          state.ToMove,
+		Cache:           &cache,
+		Config:          cfg,
+		Stats:           stats,
+		DirectDepthOnly: true,
+	})
+	if stats.IIDSearches == 0 || stats.IIDMoves == 0 {
+		t.Fatalf("expected IID to run and find moves on a cold TT, got %d searches and %d moves", stats.IIDSearches, stats.IIDMoves)
+	}
+}
@@ -0,0 +1,450 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxMatchQueue      = 256
+	maxMatches         = 128
+	matchTicketTimeout = 10 * time.Minute
+)
+
+// MatchTicket is a user's place in the matchmaking queue. Its id is only
+// returned to the user who joined and doubles as their credential for
+// playing the match.
+type MatchTicket struct {
+	ID          string          `json:"ticket"`
+	User        string          `json:"user"`
+	Preferences UserPreferences `json:"preferences"`
+	JoinedAtMs  int64           `json:"joined_at_ms"`
+	MatchID     string          `json:"match_id,omitempty"`
+	Color       int             `json:"color,omitempty"`
+	// connections counts the ticket's open match sockets. A matched ticket
+	// whose last socket closes is away from awayAtMs and keeps its seat
+	// until graceUntilMs.
+	connections  int
+	awayAtMs     int64
+	graceUntilMs int64
+}
+
+// MatchInfo describes a human vs human game created by the matchmaker.
+type MatchInfo struct {
+	ID                string  `json:"id"`
+	Black             string  `json:"black"`
+	White             string  `json:"white"`
+	CreatedAtMs       int64   `json:"created_at_ms"`
+	BoardSize         int     `json:"board_size"`
+	WinLength         int     `json:"win_length"`
+	CaptureWinStones  int     `json:"capture_win_stones"`
+	ForbidDoubleThree bool    `json:"forbid_double_three"`
+	Status            string  `json:"status"`
+	Winner            int     `json:"winner"`
+	NextPlayer        int     `json:"next_player"`
+	Board             [][]int `json:"board"`
+	Moves             []Move  `json:"moves"`
+	BlackAwayUntilMs  int64   `json:"black_away_until_ms,omitempty"`
+	WhiteAwayUntilMs  int64   `json:"white_away_until_ms,omitempty"`
+	Forfeited         int     `json:"forfeited,omitempty"`
+}
+
+// MatchEvent is published for each ticket of a match when it is created
+// ("match_found") and after every move or forfeit ("match_update"). A
+// player's opponent also gets "opponent_disconnected" when the player's
+// last socket closes and "opponent_reconnected" when they reclaim the seat.
+type MatchEvent struct {
+	Type   string    `json:"type"`
+	Ticket string    `json:"-"`
+	Color  int       `json:"color"`
+	Match  MatchInfo `json:"match"`
+}
+
+type match struct {
+	id          string
+	black       *MatchTicket
+	white       *MatchTicket
+	createdAtMs int64
+	controller  *GameController
+	// forfeited is the colour that lost by staying away past its grace
+	// period.
+	forfeited int
+}
+
+// Matchmaker pairs queued users with the same rules into fresh games.
+type Matchmaker struct {
+	mu        sync.Mutex
+	queue     []*MatchTicket
+	tickets   map[string]*MatchTicket
+	matches   map[string]*match
+	order     []string
+	publisher func(MatchEvent)
+	reviewer  func(GameRecord)
+}
+
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{tickets: make(map[string]*MatchTicket), matches: make(map[string]*match)}
+}
+
+func (m *Matchmaker) SetPublisher(publisher func(MatchEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publisher = publisher
+}
+
+// SetReviewer registers fn to be called with the record of every finished
+// match, to review it for RecordFairPlay.
+func (m *Matchmaker) SetReviewer(fn func(GameRecord)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reviewer = fn
+}
+
+func newMatchID() string {
+	var raw [12]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(raw[:])
+}
+
+// matchSettings resolves preferences into the settings of a human vs human
+// game; the double-three preference applies to both colours.
+func matchSettings(preferences UserPreferences) GameSettings {
+	settings := preferences.Apply(DefaultGameSettings(), 1)
+	settings.ForbidDoubleThreeWhite = settings.ForbidDoubleThreeBlack
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	return settings
+}
+
+func matchCompatible(a, b *MatchTicket) bool {
+	if a.User == b.User {
+		return false
+	}
+	if a.Preferences.Color != 0 && a.Preferences.Color == b.Preferences.Color {
+		return false
+	}
+	sa, sb := matchSettings(a.Preferences), matchSettings(b.Preferences)
+	return sa.BoardSize == sb.BoardSize && sa.WinLength == sb.WinLength &&
+		sa.CaptureWinStones == sb.CaptureWinStones && sa.ForbidDoubleThreeBlack == sb.ForbidDoubleThreeBlack
+}
+
+// Join queues user with the given preferences. When a compatible user is
+// already waiting the two are paired at once and the returned ticket carries
+// the match id and colour.
+func (m *Matchmaker) Join(user string, preferences UserPreferences) (MatchTicket, error) {
+	if err := preferences.Validate(); err != nil {
+		return MatchTicket{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expireLocked(time.Now())
+	for _, waiting := range m.queue {
+		if waiting.User == user {
+			return MatchTicket{}, fmt.Errorf("user %s is already queued", user)
+		}
+	}
+	ticket := &MatchTicket{ID: newMatchID(), User: user, Preferences: preferences, JoinedAtMs: time.Now().UnixMilli()}
+	for i, waiting := range m.queue {
+		if !matchCompatible(waiting, ticket) {
+			continue
+		}
+		m.queue = append(m.queue[:i], m.queue[i+1:]...)
+		m.tickets[ticket.ID] = ticket
+		m.startLocked(waiting, ticket)
+		return *ticket, nil
+	}
+	if len(m.queue) >= maxMatchQueue {
+		return MatchTicket{}, fmt.Errorf("matchmaking queue is full")
+	}
+	m.queue = append(m.queue, ticket)
+	m.tickets[ticket.ID] = ticket
+	return *ticket, nil
+}
+
+// Leave removes a waiting ticket. Matched tickets stay valid for their game.
+func (m *Matchmaker) Leave(ticketID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, waiting := range m.queue {
+		if waiting.ID == ticketID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			delete(m.tickets, ticketID)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matchmaker) Ticket(ticketID string) (MatchTicket, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ticket, ok := m.tickets[ticketID]
+	if !ok {
+		return MatchTicket{}, false
+	}
+	return *ticket, true
+}
+
+func (m *Matchmaker) QueueLen() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+// ActiveMatches counts the matches still being played.
+func (m *Matchmaker) ActiveMatches() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	active := 0
+	for _, game := range m.matches {
+		if game.running() {
+			active++
+		}
+	}
+	return active
+}
+
+func (m *Matchmaker) Match(id string) (MatchInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	game, ok := m.matches[id]
+	if !ok {
+		return MatchInfo{}, false
+	}
+	return game.info(), true
+}
+
+// Play applies move for the holder of ticketID. found is false when the
+// ticket or its match is unknown.
+func (m *Matchmaker) Play(ticketID string, move Move) (MatchInfo, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ticket, ok := m.tickets[ticketID]
+	if !ok || ticket.MatchID == "" {
+		return MatchInfo{}, false, nil
+	}
+	game, ok := m.matches[ticket.MatchID]
+	if !ok {
+		return MatchInfo{}, false, nil
+	}
+	state := game.controller.State()
+	if state.Status != StatusRunning || game.forfeited != 0 {
+		return MatchInfo{}, true, ErrGameOver
+	}
+	if PlayerToInt(state.ToMove) != ticket.Color {
+		return MatchInfo{}, true, ErrNotYourTurn
+	}
+	if applied, reason := game.controller.ApplyHumanMove(move); !applied {
+		return MatchInfo{}, true, fmt.Errorf("%w: %s", ErrIllegalMove, strings.TrimPrefix(reason, "Illegal move: "))
+	}
+	info := game.info()
+	if info.Status != "running" {
+		if record, ok := game.controller.GameRecord(game.controller.GameID()); ok {
+			record.MatchID = game.id
+			storedGames.Add(record)
+			globalStats.recordGame(record)
+			if m.reviewer != nil {
+				m.reviewer(record)
+			}
+		}
+	}
+	m.publishLocked(game, "match_update", info)
+	return info, true, nil
+}
+
+// Attach counts a socket opened with ticketID's token. A player who comes
+// back within the grace period reclaims their seat; with MatchPauseClock
+// their turn's clock does not count the time they were away.
+func (m *Matchmaker) Attach(ticketID string, now time.Time) (MatchTicket, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ticket, ok := m.tickets[ticketID]
+	if !ok {
+		return MatchTicket{}, false
+	}
+	ticket.connections++
+	game := m.matches[ticket.MatchID]
+	if ticket.awayAtMs == 0 || game == nil {
+		return *ticket, true
+	}
+	awayAtMs := ticket.awayAtMs
+	ticket.awayAtMs, ticket.graceUntilMs = 0, 0
+	if !game.running() {
+		return *ticket, true
+	}
+	state := game.controller.State()
+	if GetConfig().MatchPauseClock && PlayerToInt(state.ToMove) == ticket.Color {
+		if startedAtMs := game.controller.CurrentTurnStartedAtMs(); startedAtMs > awayAtMs {
+			awayAtMs = startedAtMs
+		}
+		game.controller.PauseTurnClock(time.Duration(now.UnixMilli()-awayAtMs) * time.Millisecond)
+	}
+	m.publishOpponentLocked(game, ticket, "opponent_reconnected")
+	return *ticket, true
+}
+
+// Detach drops a socket Attach counted. When it was the ticket's last one
+// during a running match the seat is held for MatchGraceMs.
+func (m *Matchmaker) Detach(ticketID string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ticket, ok := m.tickets[ticketID]
+	if !ok || ticket.connections == 0 {
+		return
+	}
+	ticket.connections--
+	game := m.matches[ticket.MatchID]
+	if ticket.connections > 0 || game == nil || !game.running() {
+		return
+	}
+	ticket.awayAtMs = now.UnixMilli()
+	ticket.graceUntilMs = ticket.awayAtMs + int64(GetConfig().MatchGraceMs)
+	m.publishOpponentLocked(game, ticket, "opponent_disconnected")
+}
+
+// ExpireSeats forfeits the matches of players still away once their grace
+// period is over.
+func (m *Matchmaker) ExpireSeats(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nowMs := now.UnixMilli()
+	for _, id := range m.order {
+		game := m.matches[id]
+		if !game.running() {
+			continue
+		}
+		for _, ticket := range []*MatchTicket{game.black, game.white} {
+			if ticket.awayAtMs != 0 && nowMs >= ticket.graceUntilMs {
+				ticket.awayAtMs, ticket.graceUntilMs = 0, 0
+				game.forfeited = ticket.Color
+				m.publishLocked(game, "match_update", game.info())
+				break
+			}
+		}
+	}
+}
+
+// RecordFairPlay checks both players of the match a finished review is of
+// and stores the report with the match's game record.
+func (m *Matchmaker) RecordFairPlay(review Review) (FairPlayReport, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	game, ok := m.matches[review.MatchID]
+	if !ok {
+		return FairPlayReport{}, false
+	}
+	report, ok := review.FairPlay(1, 2)
+	if !ok {
+		return FairPlayReport{}, false
+	}
+	report.MatchID = game.id
+	report.Sides[0].User, report.Sides[1].User = game.black.User, game.white.User
+	storedGames.setFairPlay(report)
+	return report, true
+}
+
+func (m *Matchmaker) startLocked(first, second *MatchTicket) {
+	black, white := first, second
+	if first.Preferences.Color == 2 || second.Preferences.Color == 1 {
+		black, white = second, first
+	}
+	black.Color, white.Color = 1, 2
+	settings := matchSettings(first.Preferences)
+	game := &match{
+		id:          newMatchID(),
+		black:       black,
+		white:       white,
+		createdAtMs: time.Now().UnixMilli(),
+		controller:  NewGameController(settings),
+	}
+	game.controller.StartGame(settings)
+	black.MatchID, white.MatchID = game.id, game.id
+	m.matches[game.id] = game
+	m.order = append(m.order, game.id)
+	if len(m.order) > maxMatches {
+		dropped := m.matches[m.order[0]]
+		delete(m.tickets, dropped.black.ID)
+		delete(m.tickets, dropped.white.ID)
+		delete(m.matches, dropped.id)
+		m.order = m.order[1:]
+	}
+	m.publishLocked(game, "match_found", game.info())
+}
+
+func (m *Matchmaker) publishLocked(game *match, kind string, info MatchInfo) {
+	if m.publisher == nil {
+		return
+	}
+	for _, ticket := range []*MatchTicket{game.black, game.white} {
+		m.publisher(MatchEvent{Type: kind, Ticket: ticket.ID, Color: ticket.Color, Match: info})
+	}
+}
+
+func (m *Matchmaker) publishOpponentLocked(game *match, ticket *MatchTicket, kind string) {
+	if m.publisher == nil {
+		return
+	}
+	opponent := game.black
+	if opponent == ticket {
+		opponent = game.white
+	}
+	m.publisher(MatchEvent{Type: kind, Ticket: opponent.ID, Color: opponent.Color, Match: game.info()})
+}
+
+func (m *Matchmaker) expireLocked(now time.Time) {
+	cutoff := now.Add(-matchTicketTimeout).UnixMilli()
+	kept := m.queue[:0]
+	for _, ticket := range m.queue {
+		if ticket.JoinedAtMs < cutoff {
+			delete(m.tickets, ticket.ID)
+			continue
+		}
+		kept = append(kept, ticket)
+	}
+	m.queue = kept
+}
+
+func (g *match) running() bool {
+	return g.forfeited == 0 && g.controller.State().Status == StatusRunning
+}
+
+func (g *match) info() MatchInfo {
+	state, history, _ := g.controller.Snapshot()
+	moves := make([]Move, 0, history.Size())
+	for _, entry := range history.All() {
+		moves = append(moves, entry.Move)
+	}
+	settings := g.controller.Settings()
+	info := MatchInfo{
+		ID:                g.id,
+		Black:             g.black.User,
+		White:             g.white.User,
+		CreatedAtMs:       g.createdAtMs,
+		BoardSize:         settings.BoardSize,
+		WinLength:         settings.WinLength,
+		CaptureWinStones:  settings.CaptureWinStones,
+		ForbidDoubleThree: settings.ForbidDoubleThreeBlack,
+		Status:            StatusToString(state.Status),
+		Winner:            WinnerFromStatus(state.Status),
+		NextPlayer:        PlayerToInt(state.ToMove),
+		Board:             BoardToSlice(state.Board),
+		Moves:             moves,
+		BlackAwayUntilMs:  g.black.graceUntilMs,
+		WhiteAwayUntilMs:  g.white.graceUntilMs,
+	}
+	if g.forfeited != 0 {
+		info.Forfeited = g.forfeited
+		info.Winner = 3 - g.forfeited
+		info.Status = StatusToString(StatusBlackWon)
+		if info.Winner == 2 {
+			info.Status = StatusToString(StatusWhiteWon)
+		}
+	}
+	return info
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchmakerPairsCompatibleUsers(t *testing.T) {
+	matchmaker := NewMatchmaker()
+	var events []MatchEvent
+	matchmaker.SetPublisher(func(event MatchEvent) { events = append(events, event) })
+
+	first, err := matchmaker.Join("ada", UserPreferences{BoardSize: 15, Color: 2})
+	if err != nil || first.MatchID != "" {
+		t.Fatalf("expected first user to wait, got %+v (%v)", first, err)
+	}
+	if _, err := matchmaker.Join("ada", UserPreferences{BoardSize: 15}); err == nil {
+		t.Fatalf("expected a user to be queued only once")
+	}
+	other, err := matchmaker.Join("bob", UserPreferences{BoardSize: 13})
+	if err != nil || other.MatchID != "" {
+		t.Fatalf("expected different board sizes not to pair, got %+v (%v)", other, err)
+	}
+	second, err := matchmaker.Join("cyd", UserPreferences{BoardSize: 15})
+	if err != nil || second.MatchID == "" {
+		t.Fatalf("expected compatible users to pair, got %+v (%v)", second, err)
+	}
+	if second.Color != 1 {
+		t.Fatalf("expected the first user's colour preference to be honoured, got %d", second.Color)
+	}
+	if matchmaker.QueueLen() != 1 {
+		t.Fatalf("expected only the unmatched user to wait, got %d", matchmaker.QueueLen())
+	}
+	if len(events) != 2 || events[0].Type != "match_found" || events[0].Match.BoardSize != 15 {
+		t.Fatalf("expected a match_found event per player, got %+v", events)
+	}
+	if ticket, _ := matchmaker.Ticket(first.ID); ticket.MatchID != second.MatchID || ticket.Color != 2 {
+		t.Fatalf("expected waiting ticket to join the match as white, got %+v", ticket)
+	}
+}
+
+func TestMatchmakerPlayChecksTurns(t *testing.T) {
+	matchmaker := NewMatchmaker()
+	white, _ := matchmaker.Join("ada", UserPreferences{BoardSize: 9, Color: 2})
+	black, _ := matchmaker.Join("bob", UserPreferences{BoardSize: 9})
+
+	if _, ok, err := matchmaker.Play(white.ID, Move{X: 4, Y: 4}); !ok || err == nil {
+		t.Fatalf("expected white to wait for black, got ok=%v err=%v", ok, err)
+	}
+	info, ok, err := matchmaker.Play(black.ID, Move{X: 4, Y: 4})
+	if !ok || err != nil {
+		t.Fatalf("expected black move to be applied, got ok=%v err=%v", ok, err)
+	}
+	if info.Board[4][4] != 1 || info.NextPlayer != 2 || len(info.Moves) != 1 {
+		t.Fatalf("unexpected match after first move: %+v", info)
+	}
+	if _, ok, _ := matchmaker.Play("missing", Move{X: 0, Y: 0}); ok {
+		t.Fatalf("expected unknown ticket to be reported")
+	}
+	if matchmaker.Leave(black.ID) {
+		t.Fatalf("expected matched tickets not to leave the queue")
+	}
+}
+
+func TestMatchmakerHoldsSeatOfDisconnectedPlayer(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.MatchGraceMs = 1000
+	cfg.MatchPauseClock = true
+	configStore.Update(cfg)
+	defer configStore.Update(prev)
+	matchmaker := NewMatchmaker()
+	var events []MatchEvent
+	matchmaker.SetPublisher(func(event MatchEvent) { events = append(events, event) })
+	white, _ := matchmaker.Join("ada", UserPreferences{BoardSize: 9, Color: 2})
+	black, _ := matchmaker.Join("bob", UserPreferences{BoardSize: 9})
+	now := time.Now()
+	matchmaker.Attach(white.ID, now)
+	matchmaker.Attach(black.ID, now)
+	events = nil
+
+	matchmaker.Detach(black.ID, now)
+	if len(events) != 1 || events[0].Type != "opponent_disconnected" || events[0].Ticket != white.ID {
+		t.Fatalf("expected the opponent to hear of the disconnect, got %+v", events)
+	}
+	if events[0].Match.BlackAwayUntilMs != now.UnixMilli()+1000 {
+		t.Fatalf("expected black's seat to be held for the grace period, got %+v", events[0].Match)
+	}
+	startedAtMs := matchmaker.matches[black.MatchID].controller.CurrentTurnStartedAtMs()
+	matchmaker.ExpireSeats(now.Add(500 * time.Millisecond))
+	if _, ok := matchmaker.Attach(black.ID, now.Add(500*time.Millisecond)); !ok {
+		t.Fatalf("expected the ticket to reclaim its seat")
+	}
+	if got := matchmaker.matches[black.MatchID].controller.CurrentTurnStartedAtMs(); got < startedAtMs+500 {
+		t.Fatalf("expected the clock to stop while black was away, turn start moved from %d to %d", startedAtMs, got)
+	}
+	if last := events[len(events)-1]; last.Type != "opponent_reconnected" || last.Match.BlackAwayUntilMs != 0 {
+		t.Fatalf("expected the opponent to hear of the reclaim, got %+v", last)
+	}
+	if _, ok, err := matchmaker.Play(black.ID, Move{X: 4, Y: 4}); !ok || err != nil {
+		t.Fatalf("expected the reclaimed seat to play, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMatchmakerForfeitsAfterGracePeriod(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.MatchGraceMs = 1000
+	configStore.Update(cfg)
+	defer configStore.Update(prev)
+	matchmaker := NewMatchmaker()
+	white, _ := matchmaker.Join("ada", UserPreferences{BoardSize: 9, Color: 2})
+	black, _ := matchmaker.Join("bob", UserPreferences{BoardSize: 9})
+	now := time.Now()
+	matchmaker.Attach(white.ID, now)
+	matchmaker.Detach(white.ID, now)
+
+	matchmaker.ExpireSeats(now.Add(999 * time.Millisecond))
+	if matchmaker.ActiveMatches() != 1 {
+		t.Fatalf("expected the match to wait out the grace period")
+	}
+	matchmaker.ExpireSeats(now.Add(time.Second))
+	info, _ := matchmaker.Match(black.MatchID)
+	if info.Forfeited != 2 || info.Winner != 1 || info.Status != "black_won" {
+		t.Fatalf("expected white to forfeit, got %+v", info)
+	}
+	if _, ok, err := matchmaker.Play(black.ID, Move{X: 4, Y: 4}); !ok || err != ErrGameOver {
+		t.Fatalf("expected a forfeited match to be over, got ok=%v err=%v", ok, err)
+	}
+	if matchmaker.ActiveMatches() != 0 {
+		t.Fatalf("expected forfeited matches not to count as active")
+	}
+}
@@ -0,0 +1,70 @@
+package engine
+
+import "math"
+
+// maxMatePly is how far from the root a win can be and still be scored as
+// one: scores within maxMatePly of winScore are wins found by the search,
+// winScore less the plies they take.
+const maxMatePly = 1000
+
+// winScoreAt scores a win for winner ply moves from the root. The sooner
+// the win the larger the score, so the search prefers the fastest win and,
+// when losing, the slowest loss.
+func winScoreAt(winner PlayerColor, ply int) float64 {
+	if winner == PlayerBlack {
+		return winScore - float64(ply)
+	}
+	return -winScore + float64(ply)
+}
+
+// isMateScore reports whether score is a win or a loss found by the search.
+func isMateScore(score float64) bool {
+	return math.Abs(score) > winScore-maxMatePly
+}
+
+// mateDistance returns the plies to the win or loss score announces, and
+// false when score is not one.
+func mateDistance(score float64) (int, bool) {
+	if !isMateScore(score) {
+		return 0, false
+	}
+	return int(math.Round(winScore - math.Abs(score))), true
+}
+
+// scoreAtPly turns a finished game's score from evaluateStateHeuristic,
+// plus or minus winScore, into the score of that result ply moves from the
+// root.
+func scoreAtPly(score float64, ply int) float64 {
+	switch {
+	case score >= winScore:
+		return winScore - float64(ply)
+	case score <= -winScore:
+		return -winScore + float64(ply)
+	}
+	return score
+}
+
+// mateScoreToTT rebases a win or loss score found ply moves from the root
+// to count from the position it is stored for, so the entry holds when the
+// position is reached from another root or at another ply.
+func mateScoreToTT(score float64, ply int) float64 {
+	if !isMateScore(score) {
+		return score
+	}
+	if score > 0 {
+		return score + float64(ply)
+	}
+	return score - float64(ply)
+}
+
+// mateScoreFromTT undoes mateScoreToTT for a position ply moves from the
+// root.
+func mateScoreFromTT(score float64, ply int) float64 {
+	if !isMateScore(score) {
+		return score
+	}
+	if score > 0 {
+		return score - float64(ply)
+	}
+	return score + float64(ply)
+}
@@ -0,0 +1,92 @@
+package engine
+
+import "testing"
+
+func TestMateScoresPreferFasterWinsAndSlowerLosses(t *testing.T) {
+	if winScoreAt(PlayerBlack, 1) <= winScoreAt(PlayerBlack, 3) {
+		t.Fatalf("expected a win in 1 to outscore a win in 3")
+	}
+	if winScoreAt(PlayerWhite, 5) <= winScoreAt(PlayerWhite, 2) {
+		t.Fatalf("expected Black to prefer losing in 5 over losing in 2")
+	}
+	if distance, ok := mateDistance(winScoreAt(PlayerWhite, 7)); !ok || distance != 7 {
+		t.Fatalf("expected mate distance 7, got %d (ok=%v)", distance, ok)
+	}
+	if _, ok := mateDistance(winScore * 0.95); ok {
+		t.Fatalf("expected the capture-soon heuristic not to read as a mate score")
+	}
+	if scoreAtPly(winScore, 4) != winScoreAt(PlayerBlack, 4) || scoreAtPly(-winScore, 4) != winScoreAt(PlayerWhite, 4) {
+		t.Fatalf("expected terminal scores to take the ply off")
+	}
+	if scoreAtPly(1234, 4) != 1234 {
+		t.Fatalf("expected heuristic scores to be left alone")
+	}
+}
+
+func TestMateScoresRebaseThroughTheTT(t *testing.T) {
+	// A win found 6 plies from the root at a node 2 plies deep is a win in
+	// 4 from that node, and a win in 5 when the node is reached at ply 1.
+	stored := mateScoreToTT(winScoreAt(PlayerBlack, 6), 2)
+	if stored != winScoreAt(PlayerBlack, 4) {
+		t.Fatalf("stored %.0f, want a win in 4", stored)
+	}
+	if got := mateScoreFromTT(stored, 1); got != winScoreAt(PlayerBlack, 5) {
+		t.Fatalf("probed %.0f, want a win in 5", got)
+	}
+	loss := mateScoreToTT(winScoreAt(PlayerWhite, 6), 2)
+	if got := mateScoreFromTT(loss, 2); got != winScoreAt(PlayerWhite, 6) {
+		t.Fatalf("round trip gave %.0f, want the loss in 6 back", got)
+	}
+
+	entry := TTEntry{Depth: 4, Flag: TTExact, Score: scoreToTT(stored), Valid: true}
+	alpha, beta := -winScore*2, winScore*2
+	if _, ret, value := applyTTEntry(entry, 3, 3, &alpha, &beta, nil); !ret || value != winScoreAt(PlayerBlack, 7) {
+		t.Fatalf("applyTTEntry returned %.0f (ret=%v), want a win in 7", value, ret)
+	}
+}
+
+func TestSearchScoresAnImmediateWinAsMateInOne(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.AiDepth = 3
+	cfg.AiMinDepth = 3
+	cfg.AiMaxDepth = 3
+	cfg.AiQuickWinExit = false
+	cfg.AiEnableEvalCache = false
+	cfg.AiEnableAspiration = false
+	cfg.AiTimeBudgetMs = 0
+	configStore.Update(cfg)
+	defer func() {
+		configStore.Update(prev)
+		FlushGlobalCaches()
+	}()
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	for x := 2; x <= 5; x++ {
+		state.Board.Set(x, 4, CellBlack)
+	}
+	state.Board.Set(4, 6, CellWhite)
+	state.Board.Set(5, 6, CellWhite)
+	state.recomputeHashes()
+
+	cache := newAISearchCache()
+	scores := ScoreBoard(state, rules, AIScoreSettings{
+		Depth:           3,
+		BoardSize:       settings.BoardSize,
+		Player:          state.ToMove,
+		Cache:           &cache,
+		Config:          cfg,
+		DirectDepthOnly: true,
+	})
+	for _, win := range []Move{{X: 1, Y: 4}, {X: 6, Y: 4}} {
+		score := scores[win.Y*settings.BoardSize+win.X]
+		if distance, ok := mateDistance(score); !ok || distance != 1 || score < 0 {
+			t.Fatalf("win at %v scored %.0f, want a win in 1", win, score)
+		}
+	}
+}
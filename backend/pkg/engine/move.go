@@ -1,4 +1,4 @@
-package main
+package engine
 
 type Move struct {
 	X     int `json:"x"`
@@ -0,0 +1,43 @@
+package engine
+
+type HistoryEntry struct {
+	Move              Move
+	Player            PlayerColor
+	CapturedPositions []Move
+	// ElapsedMs is how long the turn visibly lasted, which move pacing may
+	// stretch past ComputeMs, the time the engine took to find the move.
+	ElapsedMs     float64
+	ComputeMs     float64
+	IsAi          bool
+	CapturedCount int
+	Depth         int
+	// AutoPlayed marks a capture win played by the rules on the player's
+	// behalf (see AutoPlayCaptureWin), not chosen by them.
+	AutoPlayed bool
+	// Score is the search score of an AI move from the mover's side, set
+	// when Scored.
+	Score  float64
+	Scored bool
+	// Search is what the search of an AI move cost, when it was timed.
+	Search *MoveSearchStats
+}
+
+type MoveHistory struct {
+	entries []HistoryEntry
+}
+
+func (h *MoveHistory) Clear() {
+	h.entries = nil
+}
+
+func (h *MoveHistory) Push(entry HistoryEntry) {
+	h.entries = append(h.entries, entry)
+}
+
+func (h MoveHistory) Size() int {
+	return len(h.entries)
+}
+
+func (h MoveHistory) All() []HistoryEntry {
+	return append([]HistoryEntry(nil), h.entries...)
+}
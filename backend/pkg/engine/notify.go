@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NotifyQueueDrained       = "queue_drained"
+	NotifyTTFull             = "tt_full"
+	NotifyCorrespondenceTurn = "correspondence_turn"
+)
+
+var defaultNotifyTemplates = map[string]string{
+	NotifyQueueDrained:       "Analysis backlog drained: {processed} boards analyzed, TT {tt_count}/{tt_capacity} entries.",
+	NotifyTTFull:             "Transposition table is full: {tt_count}/{tt_capacity} entries.",
+	NotifyCorrespondenceTurn: "{player}, it is your move ({color}) in correspondence game {game} after {moves} moves.",
+}
+
+type WebhookNotifier struct {
+	url       string
+	templates map[string]string
+	client    *http.Client
+}
+
+// SMTPMailer sends notifications to players' e-mail addresses.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+var (
+	notifierMu sync.RWMutex
+	notifier   *WebhookNotifier
+	mailer     *SMTPMailer
+)
+
+func NewWebhookNotifier(url string, templates map[string]string) *WebhookNotifier {
+	merged := make(map[string]string, len(defaultNotifyTemplates))
+	for event, template := range defaultNotifyTemplates {
+		merged[event] = template
+	}
+	for event, template := range templates {
+		if strings.TrimSpace(template) != "" {
+			merged[event] = template
+		}
+	}
+	return &WebhookNotifier{
+		url:       strings.TrimSpace(url),
+		templates: merged,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func SetWebhookNotifier(n *WebhookNotifier) {
+	notifierMu.Lock()
+	notifier = n
+	notifierMu.Unlock()
+}
+
+// NewSMTPMailer returns nil when addr or from is empty, which disables
+// e-mail notifications. Credentials are optional.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	addr, from = strings.TrimSpace(addr), strings.TrimSpace(from)
+	if addr == "" || from == "" {
+		return nil
+	}
+	m := &SMTPMailer{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func SetMailer(m *SMTPMailer) {
+	notifierMu.Lock()
+	mailer = m
+	notifierMu.Unlock()
+}
+
+func (m *SMTPMailer) Send(to, subject, message string) {
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", m.from, to, subject, message)
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(body)); err != nil {
+		log.Printf("[notify] mail to %s failed: %v", to, err)
+	}
+}
+
+// validNotifyWebhook and validNotifyEmail check recipient addresses given
+// through the API.
+func validNotifyWebhook(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func validNotifyEmail(address string) bool {
+	at := strings.Index(address, "@")
+	return at > 0 && at < len(address)-1 && !strings.ContainsAny(address, " \r\n<>,")
+}
+
+func notifyEvent(event string, fields map[string]string) {
+	notifierMu.RLock()
+	n := notifier
+	notifierMu.RUnlock()
+	if n == nil || n.url == "" {
+		return
+	}
+	go n.Send(event, fields)
+}
+
+// notifyRecipient sends event to one recipient's own webhook and e-mail
+// address, rendered with the configured templates.
+func notifyRecipient(webhook, email, event string, fields map[string]string) {
+	notifierMu.RLock()
+	n, m := notifier, mailer
+	notifierMu.RUnlock()
+	if n == nil {
+		n = NewWebhookNotifier("", nil)
+	}
+	if webhook != "" {
+		recipient := &WebhookNotifier{url: webhook, templates: n.templates, client: n.client}
+		go recipient.Send(event, fields)
+	}
+	if email != "" && m != nil {
+		go m.Send(email, "Gomoku: your move", n.Render(event, fields))
+	}
+}
+
+func (n *WebhookNotifier) Render(event string, fields map[string]string) string {
+	template, ok := n.templates[event]
+	if !ok {
+		template = event
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys)*2+2)
+	pairs = append(pairs, "{event}", event)
+	for _, key := range keys {
+		pairs = append(pairs, "{"+key+"}", fields[key])
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func (n *WebhookNotifier) Send(event string, fields map[string]string) {
+	message := n.Render(event, fields)
+	body, err := json.Marshal(webhookBody(n.url, message))
+	if err != nil {
+		return
+	}
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[notify] %s webhook failed: %v", event, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[notify] %s webhook returned %d", event, resp.StatusCode)
+	}
+}
+
+func webhookBody(url, message string) map[string]string {
+	if strings.Contains(url, "discord") {
+		return map[string]string{"content": message}
+	}
+	return map[string]string{"text": message}
+}
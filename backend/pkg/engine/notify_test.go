@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotifierRendersTemplateFields(t *testing.T) {
+	n := NewWebhookNotifier("http://example.invalid", map[string]string{
+		NotifyTTFull: "{event}: {tt_count} of {tt_capacity}",
+	})
+	got := n.Render(NotifyTTFull, map[string]string{"tt_count": "10", "tt_capacity": "10"})
+	if got != "tt_full: 10 of 10" {
+		t.Fatalf("unexpected rendered message %q", got)
+	}
+	drained := n.Render(NotifyQueueDrained, map[string]string{"processed": "3", "tt_count": "1", "tt_capacity": "8"})
+	if drained != "Analysis backlog drained: 3 boards analyzed, TT 1/8 entries." {
+		t.Fatalf("expected default template, got %q", drained)
+	}
+}
+
+func TestWebhookNotifierPostsSlackStyleBody(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(server.URL, nil)
+	n.Send(NotifyTTFull, map[string]string{"tt_count": "4", "tt_capacity": "4"})
+	if body["text"] != "Transposition table is full: 4/4 entries." {
+		t.Fatalf("unexpected webhook body %+v", body)
+	}
+}
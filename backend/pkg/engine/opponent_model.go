@@ -0,0 +1,249 @@
+package engine
+
+import "math"
+
+const (
+	// opponentModelMinMoves is how many of a user's moves the model needs
+	// before the engine acts on it.
+	opponentModelMinMoves = 40
+	// opponentModelMargin is how far below the best root score, from the
+	// engine's side, a move may be to be picked for exploiting the model.
+	// It stays under an open three so the engine never gives one away.
+	opponentModelMargin = 3000.0
+	// opponentModelPressure is the fraction of a user's average think time
+	// in a game under which a move counts as played under time pressure.
+	opponentModelPressure = 1.0 / 3
+)
+
+// Line directions of OpponentModel.Directions.
+var opponentDirections = [4][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+
+// OpponentModel is what the engine learned about a human player over their
+// games. Directions counts the moves extending one of the player's stones,
+// by line: horizontal, vertical, diagonal and anti-diagonal.
+type OpponentModel struct {
+	Moves           int    `json:"moves"`
+	Directions      [4]int `json:"directions"`
+	CaptureThreats  int    `json:"capture_threats"`
+	CaptureDefended int    `json:"capture_defended"`
+	PressuredMoves  int    `json:"pressured_moves"`
+	PressuredErrors int    `json:"pressured_errors"`
+	CalmMoves       int    `json:"calm_moves"`
+	CalmErrors      int    `json:"calm_errors"`
+}
+
+// FavoredDirection returns the line the player extends most, when it takes
+// at least 40% of their extending moves.
+func (m OpponentModel) FavoredDirection() (int, bool) {
+	total, best := 0, 0
+	for d, count := range m.Directions {
+		total += count
+		if count > m.Directions[best] {
+			best = d
+		}
+	}
+	if total == 0 || float64(m.Directions[best]) < 0.4*float64(total) {
+		return 0, false
+	}
+	return best, true
+}
+
+// MissesCaptureThreats reports whether the player leaves most capture
+// threats against their stones standing.
+func (m OpponentModel) MissesCaptureThreats() bool {
+	return m.CaptureThreats >= 5 && m.CaptureDefended*2 < m.CaptureThreats
+}
+
+// CracksUnderPressure reports whether the player's mistakes are clearly more
+// frequent on quick moves than on considered ones.
+func (m OpponentModel) CracksUnderPressure() bool {
+	if m.PressuredMoves < 10 || m.CalmMoves < 10 {
+		return false
+	}
+	pressured := float64(m.PressuredErrors) / float64(m.PressuredMoves)
+	calm := float64(m.CalmErrors) / float64(m.CalmMoves)
+	return pressured > calm+0.05
+}
+
+// recordGame folds the moves color played in record into the model.
+func (m *OpponentModel) recordGame(record GameRecord, color int) {
+	rules := NewRules(record.Settings)
+	state, err := renderStartState(record.Settings, record.Start)
+	if err != nil {
+		return
+	}
+	state.Status = StatusRunning
+	for _, entry := range record.Entries {
+		player := entry.Player
+		if PlayerToInt(player) == color {
+			m.Moves++
+			m.recordDirections(state.Board, entry.Move, CellFromPlayer(player))
+			threatened := hasCaptureThreat(state.Board, rules, otherPlayer(player))
+			if threatened {
+				m.CaptureThreats++
+			}
+			if !applyMove(&state, rules, entry.Move, player) {
+				return
+			}
+			if threatened && !hasCaptureThreat(state.Board, rules, otherPlayer(player)) {
+				m.CaptureDefended++
+			}
+		} else if !applyMove(&state, rules, entry.Move, player) {
+			return
+		}
+		if state.Status != StatusRunning {
+			return
+		}
+	}
+}
+
+func (m *OpponentModel) recordDirections(board Board, move Move, cell Cell) {
+	size := board.Size()
+	for d, dir := range opponentDirections {
+		for _, sign := range []int{1, -1} {
+			x, y := move.X+sign*dir[0], move.Y+sign*dir[1]
+			if x >= 0 && y >= 0 && x < size && y < size && board.At(x, y) == cell {
+				m.Directions[d]++
+				break
+			}
+		}
+	}
+}
+
+// recordReview splits the graded moves of color between quick and
+// considered ones and counts the mistakes and blunders of each.
+func (m *OpponentModel) recordReview(review Review, color int) {
+	total, count := 0.0, 0
+	for _, move := range review.Moves {
+		if move.Player == color && move.ElapsedMs > 0 {
+			total += move.ElapsedMs
+			count++
+		}
+	}
+	if count == 0 {
+		return
+	}
+	threshold := total / float64(count) * opponentModelPressure
+	for _, move := range review.Moves {
+		if move.Player != color || move.ElapsedMs <= 0 {
+			continue
+		}
+		mistake := move.Class == "mistake" || move.Class == "blunder"
+		if move.ElapsedMs < threshold {
+			m.PressuredMoves++
+			if mistake {
+				m.PressuredErrors++
+			}
+		} else {
+			m.CalmMoves++
+			if mistake {
+				m.CalmErrors++
+			}
+		}
+	}
+}
+
+func hasCaptureThreat(board Board, rules Rules, player PlayerColor) bool {
+	for _, threat := range FindThreats(board, rules, player) {
+		if threat.Kind == ThreatCapture {
+			return true
+		}
+	}
+	return false
+}
+
+// maybeSelectModeledMove replaces currentBest by a move about as good that
+// plays into the opponent's known weaknesses: capture threats against a
+// player who misses them, forcing threats against one who errs when hurried,
+// and blocks across the line they like to extend.
+func maybeSelectModeledMove(scores []float64, state GameState, rules Rules, settings AIScoreSettings, currentBest Move, model *OpponentModel) (Move, bool) {
+	if model == nil || !settings.Config.AiOpponentModel || model.Moves < opponentModelMinMoves {
+		return Move{}, false
+	}
+	size := settings.BoardSize
+	if !currentBest.IsValid(size) || len(scores) < size*size {
+		return Move{}, false
+	}
+	sign := scoreSign(state.ToMove)
+	bestScore := sign * scores[currentBest.Y*size+currentBest.X]
+	if math.IsInf(bestScore, 0) || math.IsNaN(bestScore) {
+		return Move{}, false
+	}
+	direction, hasDirection := model.FavoredDirection()
+	missesCaptures := model.MissesCaptureThreats()
+	cracks := model.CracksUnderPressure()
+	if !hasDirection && !missesCaptures && !cracks {
+		return Move{}, false
+	}
+
+	opponent := otherPlayer(state.ToMove)
+	mine := CellFromPlayer(state.ToMove)
+	captureBefore, forcingBefore := countOwnThreats(state.Board, rules, state.ToMove)
+	chosen, chosenBonus, chosenScore := Move{}, 0, math.Inf(-1)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			raw := scores[y*size+x]
+			if raw == illegalScore || math.IsInf(raw, 0) || math.IsNaN(raw) {
+				continue
+			}
+			score := sign * raw
+			if bestScore-score > opponentModelMargin {
+				continue
+			}
+			move := Move{X: x, Y: y}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); !ok {
+				continue
+			}
+			bonus := 0
+			if hasDirection && blocksDirection(state.Board, move, CellFromPlayer(opponent), direction) {
+				bonus++
+			}
+			if missesCaptures || cracks {
+				board := state.Board.Clone()
+				board.Set(x, y, mine)
+				captures, forcing := countOwnThreats(board, rules, state.ToMove)
+				if missesCaptures && captures > captureBefore {
+					bonus++
+				}
+				if cracks && forcing > forcingBefore {
+					bonus++
+				}
+			}
+			if bonus > chosenBonus || (bonus == chosenBonus && bonus > 0 && score > chosenScore) {
+				chosen, chosenBonus, chosenScore = move, bonus, score
+			}
+		}
+	}
+	if chosenBonus == 0 || chosen == currentBest {
+		return Move{}, false
+	}
+	return chosen, true
+}
+
+// countOwnThreats counts player's capture threats and forcing threats
+// (fours and open threes).
+func countOwnThreats(board Board, rules Rules, player PlayerColor) (int, int) {
+	captures, forcing := 0, 0
+	for _, threat := range FindThreats(board, rules, player) {
+		if threat.Kind == ThreatCapture {
+			captures++
+		} else {
+			forcing++
+		}
+	}
+	return captures, forcing
+}
+
+// blocksDirection reports whether move sits next to one of the opponent's
+// stones along direction d.
+func blocksDirection(board Board, move Move, opponent Cell, d int) bool {
+	size := board.Size()
+	dir := opponentDirections[d]
+	for _, sign := range []int{1, -1} {
+		x, y := move.X+sign*dir[0], move.Y+sign*dir[1]
+		if x >= 0 && y >= 0 && x < size && y < size && board.At(x, y) == opponent {
+			return true
+		}
+	}
+	return false
+}
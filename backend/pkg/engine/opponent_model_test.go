@@ -0,0 +1,74 @@
+package engine
+
+import "testing"
+
+func TestOpponentModelRecordsGameTendencies(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	record := GameRecord{
+		Settings: settings,
+		Status:   StatusWhiteWon,
+		Entries: []HistoryEntry{
+			{Move: Move{X: 4, Y: 4}, Player: PlayerBlack},
+			{Move: Move{X: 0, Y: 0}, Player: PlayerWhite},
+			{Move: Move{X: 5, Y: 4}, Player: PlayerBlack},
+			{Move: Move{X: 6, Y: 4}, Player: PlayerWhite},
+			{Move: Move{X: 8, Y: 8}, Player: PlayerBlack},
+		},
+	}
+	var model OpponentModel
+	model.recordGame(record, 1)
+	if model.Moves != 3 {
+		t.Fatalf("expected 3 black moves, got %d", model.Moves)
+	}
+	if model.Directions[0] != 1 || model.Directions[1]+model.Directions[2]+model.Directions[3] != 0 {
+		t.Fatalf("expected one horizontal extension, got %v", model.Directions)
+	}
+	// White's 6,4 threatens to capture 4,4 and 5,4 from 3,4; black ignored it.
+	if model.CaptureThreats != 1 || model.CaptureDefended != 0 {
+		t.Fatalf("expected one ignored capture threat, got %d/%d", model.CaptureDefended, model.CaptureThreats)
+	}
+}
+
+func TestModeledMoveBlocksFavoredDirection(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.Board.Set(3, 4, CellBlack)
+	state.Board.Set(4, 4, CellBlack)
+	state.ToMove = PlayerWhite
+
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	best := Move{X: 4, Y: 6}
+	scores[best.Y*9+best.X] = -100
+	scores[4*9+5] = 0
+	scores[0] = 5000
+
+	cfg := DefaultConfig()
+	cfg.AiOpponentModel = true
+	aiSettings := AIScoreSettings{BoardSize: 9, Player: PlayerWhite, Config: cfg}
+	model := &OpponentModel{Moves: opponentModelMinMoves, Directions: [4]int{30, 2, 2, 2}}
+	move, changed := maybeSelectModeledMove(scores, state, rules, aiSettings, best, model)
+	if !changed || !move.Equals(Move{X: 5, Y: 4}) {
+		t.Fatalf("expected the block across the favoured line, got %+v changed=%v", move, changed)
+	}
+
+	cfg.AiOpponentModel = false
+	aiSettings.Config = cfg
+	if _, changed := maybeSelectModeledMove(scores, state, rules, aiSettings, best, model); changed {
+		t.Fatalf("expected the model to stay off unless enabled")
+	}
+	cfg.AiOpponentModel = true
+	aiSettings.Config = cfg
+	model.Moves = opponentModelMinMoves - 1
+	if _, changed := maybeSelectModeledMove(scores, state, rules, aiSettings, best, model); changed {
+		t.Fatalf("expected a thin model to be ignored")
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+// StartPosition is an arbitrary position a game can begin from instead of the
+// empty board. Board rows are indexed [y][x] with 0 empty, 1 black, 2 white,
+// as in BoardToSlice; captured counts are stones taken by each side so far.
+type StartPosition struct {
+	Board         [][]int `json:"board"`
+	NextPlayer    int     `json:"next_player"`
+	CapturedBlack int     `json:"captured_black"`
+	CapturedWhite int     `json:"captured_white"`
+}
+
+// State validates the position against settings and returns it as a
+// not-yet-started GameState. Positions that are already decided (an
+// alignment on the board or a capture count at the win threshold) are
+// rejected.
+func (p StartPosition) State(settings GameSettings) (GameState, error) {
+	size := settings.BoardSize
+	if len(p.Board) != size {
+		return GameState{}, fieldErrorf("board", "board has %d rows, expected %d", len(p.Board), size)
+	}
+	if p.NextPlayer != 1 && p.NextPlayer != 2 {
+		return GameState{}, fieldErrorf("next_player", "next_player must be 1 or 2")
+	}
+	if err := validateCaptured("captured_black", p.CapturedBlack, settings); err != nil {
+		return GameState{}, err
+	}
+	if err := validateCaptured("captured_white", p.CapturedWhite, settings); err != nil {
+		return GameState{}, err
+	}
+	state := DefaultGameState(settings)
+	for y, row := range p.Board {
+		if len(row) != size {
+			return GameState{}, fieldErrorf("board", "board row %d has %d cells, expected %d", y, len(row), size)
+		}
+		for x, value := range row {
+			if value < 0 || value > 2 {
+				return GameState{}, fieldErrorf("board", "board cell %d,%d has invalid value %d", x, y, value)
+			}
+			state.Board.Set(x, y, IntToCell(value))
+		}
+	}
+	rules := NewRules(settings)
+	if rules.hasAnyAlignment(state.Board, CellBlack) || rules.hasAnyAlignment(state.Board, CellWhite) {
+		return GameState{}, fieldErrorf("board", "board already contains a winning alignment")
+	}
+	if rules.IsDraw(state.Board) {
+		return GameState{}, fieldErrorf("board", "board has no empty cell")
+	}
+	state.ToMove = IntToPlayer(p.NextPlayer)
+	state.CapturedBlack = p.CapturedBlack
+	state.CapturedWhite = p.CapturedWhite
+	state.recomputeHashes()
+	return state, nil
+}
+
+func validateCaptured(field string, captured int, settings GameSettings) error {
+	if captured < 0 || captured%2 != 0 {
+		return fieldErrorf(field, "%s must be even and non-negative", field)
+	}
+	if captured >= settings.CaptureWinStones {
+		return fieldErrorf(field, "%s must stay below %d", field, settings.CaptureWinStones)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"encoding/gob"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const positionFrequencyDefaultLimit = 1 << 20
+
+type positionFrequencyStore struct {
+	mu     sync.Mutex
+	counts map[uint64]uint32
+	limit  int
+}
+
+type positionFrequencySnapshot struct {
+	Counts map[uint64]uint32
+}
+
+var positionFrequencies = newPositionFrequencyStore(positionFrequencyDefaultLimit)
+
+func newPositionFrequencyStore(limit int) *positionFrequencyStore {
+	if limit <= 0 {
+		limit = positionFrequencyDefaultLimit
+	}
+	return &positionFrequencyStore{
+		counts: make(map[uint64]uint32),
+		limit:  limit,
+	}
+}
+
+func (s *positionFrequencyStore) Record(hash uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, ok := s.counts[hash]
+	if !ok && len(s.counts) >= s.limit {
+		return
+	}
+	if count < ^uint32(0) {
+		s.counts[hash] = count + 1
+	}
+}
+
+func (s *positionFrequencyStore) Get(hash uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(s.counts[hash])
+}
+
+func (s *positionFrequencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counts)
+}
+
+func (s *positionFrequencyStore) snapshot() positionFrequencySnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[uint64]uint32, len(s.counts))
+	for hash, count := range s.counts {
+		counts[hash] = count
+	}
+	return positionFrequencySnapshot{Counts: counts}
+}
+
+func (s *positionFrequencyStore) load(snapshot positionFrequencySnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = make(map[uint64]uint32, len(snapshot.Counts))
+	for hash, count := range snapshot.Counts {
+		if len(s.counts) >= s.limit {
+			break
+		}
+		s.counts[hash] = count
+	}
+}
+
+func recordPlayedPosition(state GameState) {
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	positionFrequencies.Record(ttKeyFor(state, state.Board.Size()))
+}
+
+func loadPositionFrequencies(cfg Config, store *positionFrequencyStore) {
+	if store == nil || cfg.AiFrequencyPath == "" {
+		log.Printf("[ai:cache] restored position frequencies: 0 entries (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.AiFrequencyPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open position frequencies %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored position frequencies: 0 entries (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot positionFrequencySnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode position frequencies %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored position frequencies from %s (%d entries)", path, store.Len())
+}
+
+func persistPositionFrequencies(cfg Config, store *positionFrequencyStore) {
+	if store == nil || cfg.AiFrequencyPath == "" {
+		log.Printf("[ai:cache] stored position frequencies: 0 entries (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.AiFrequencyPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create position frequency directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create position frequencies %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode position frequencies %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored position frequencies to %s (%d entries)", path, len(snapshot.Counts))
+}
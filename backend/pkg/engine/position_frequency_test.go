@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPositionFrequencyStoreRespectsLimit(t *testing.T) {
+	store := newPositionFrequencyStore(2)
+	store.Record(1)
+	store.Record(2)
+	store.Record(3)
+	store.Record(1)
+	if got := store.Get(1); got != 2 {
+		t.Fatalf("expected existing key to keep counting, got %d", got)
+	}
+	if got := store.Get(3); got != 0 {
+		t.Fatalf("expected new key to be ignored once full, got %d", got)
+	}
+}
+
+func TestPositionFrequencyPersistenceRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiFrequencyPath = filepath.Join(t.TempDir(), "position_frequency.gob")
+	store := newPositionFrequencyStore(16)
+	store.Record(0xabc)
+	store.Record(0xabc)
+	store.Record(0xdef)
+	persistPositionFrequencies(cfg, store)
+
+	restored := newPositionFrequencyStore(16)
+	loadPositionFrequencies(cfg, restored)
+	if restored.Get(0xabc) != 2 || restored.Get(0xdef) != 1 {
+		t.Fatalf("expected frequencies to round-trip, got %d and %d", restored.Get(0xabc), restored.Get(0xdef))
+	}
+}
+
+func TestSimulateLeavesPositionFrequenciesAlone(t *testing.T) {
+	prev := positionFrequencies
+	positionFrequencies = newPositionFrequencyStore(1024)
+	t.Cleanup(func() { positionFrequencies = prev })
+
+	opening := []Move{{X: 9, Y: 9}, {X: 10, Y: 10}}
+	result, err := Simulate(context.Background(), DefaultGameSettings(), SimulateRequest{Opening: opening, Depth: 1, MaxMoves: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Moves) <= len(opening) {
+		t.Fatalf("expected engine moves after the opening, got %d moves", len(result.Moves))
+	}
+	if count := positionFrequencies.Len(); count != 0 {
+		t.Fatalf("expected a simulated game to record no positions, got %d", count)
+	}
+
+	game := newHeadlessGame(DefaultGameSettings())
+	game.TryApplyMove(Move{X: 9, Y: 9})
+	if positionFrequencies.Len() != 1 {
+		t.Fatalf("expected a played game to record its positions")
+	}
+}
+
+func TestPickTaskForProcessingPrefersFrequentPositions(t *testing.T) {
+	prev := positionFrequencies
+	positionFrequencies = newPositionFrequencyStore(16)
+	t.Cleanup(func() { positionFrequencies = prev })
+
+	b := newSearchBacklog()
+	settings := DefaultGameSettings()
+
+	rare := DefaultGameState(settings)
+	rare.Board.Set(4, 4, CellBlack)
+	rare.Board.Set(5, 5, CellWhite)
+	rare.recomputeHashes()
+
+	frequent := DefaultGameState(settings)
+	frequent.Board.Set(3, 3, CellBlack)
+	frequent.recomputeHashes()
+	frequentKey := ttKeyFor(frequent, frequent.Board.Size())
+	positionFrequencies.Record(frequentKey)
+
+	b.enqueue(backlogTask{state: rare, created: time.Unix(1, 0), knownDepth: 6, targetDepth: 12}, false)
+	b.enqueue(backlogTask{state: rare, created: time.Unix(1, 0), knownDepth: 6, targetDepth: 12}, false)
+	b.enqueue(backlogTask{state: frequent, created: time.Unix(2, 0), knownDepth: 6, targetDepth: 12}, false)
+
+	_, pickedHash, ok := b.pickTaskForProcessing()
+	if !ok {
+		t.Fatalf("expected a task to be picked")
+	}
+	if pickedHash != frequentKey {
+		t.Fatalf("expected frequently played board 0x%x first, got 0x%x", frequentKey, pickedHash)
+	}
+}
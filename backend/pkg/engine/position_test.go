@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func emptyStartBoard(size int) [][]int {
+	board := make([][]int, size)
+	for y := range board {
+		board[y] = make([]int, size)
+	}
+	return board
+}
+
+func TestStartFromPositionSeedsState(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	board := emptyStartBoard(settings.BoardSize)
+	board[9][9] = 1
+	board[9][10] = 2
+	board[10][9] = 1
+	position := StartPosition{Board: board, NextPlayer: 2, CapturedBlack: 2}
+
+	game := NewGame(settings)
+	if err := game.StartFromPosition(settings, position); err != nil {
+		t.Fatalf("unexpected seeding error: %v", err)
+	}
+	state := game.State()
+	if state.Status != StatusRunning || state.ToMove != PlayerWhite || state.CapturedBlack != 2 {
+		t.Fatalf("unexpected seeded state: status=%v toMove=%v capturedBlack=%d", state.Status, state.ToMove, state.CapturedBlack)
+	}
+	if state.Board.At(9, 10) != CellBlack || state.Board.At(10, 9) != CellWhite {
+		t.Fatalf("expected board rows to be indexed [y][x]")
+	}
+	replayed, _, err := replayMoves(settings, []Move{{X: 9, Y: 9}, {X: 10, Y: 9}, {X: 9, Y: 10}})
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if state.Hash == replayed.Hash {
+		t.Fatalf("expected capture counts to be part of the seeded hash")
+	}
+	if ok, _ := game.TryApplyMove(Move{X: 0, Y: 0}); !ok {
+		t.Fatalf("expected white to be able to move in the seeded game")
+	}
+	if _, ok := game.StartPosition(); !ok {
+		t.Fatalf("expected the start position to be kept")
+	}
+	game.Reset(settings)
+	if _, ok := game.StartPosition(); ok {
+		t.Fatalf("expected reset to drop the start position")
+	}
+}
+
+func TestStartPositionRejectsInvalidBoards(t *testing.T) {
+	settings := DefaultGameSettings()
+	won := emptyStartBoard(settings.BoardSize)
+	for x := 3; x < 3+settings.WinLength; x++ {
+		won[4][x] = 1
+	}
+	invalid := emptyStartBoard(settings.BoardSize)
+	invalid[1][1] = 7
+	cases := map[string]struct {
+		position StartPosition
+		field    string
+	}{
+		"short board":       {StartPosition{Board: emptyStartBoard(settings.BoardSize - 1), NextPlayer: 1}, "board"},
+		"bad player":        {StartPosition{Board: emptyStartBoard(settings.BoardSize), NextPlayer: 3}, "next_player"},
+		"negative captures": {StartPosition{Board: emptyStartBoard(settings.BoardSize), NextPlayer: 1, CapturedBlack: -2}, "captured_black"},
+		"odd captures":      {StartPosition{Board: emptyStartBoard(settings.BoardSize), NextPlayer: 1, CapturedWhite: 3}, "captured_white"},
+		"capture win":       {StartPosition{Board: emptyStartBoard(settings.BoardSize), NextPlayer: 1, CapturedWhite: settings.CaptureWinStones}, "captured_white"},
+		"already won":       {StartPosition{Board: won, NextPlayer: 2}, "board"},
+		"invalid value":     {StartPosition{Board: invalid, NextPlayer: 1}, "board"},
+	}
+	for name, tc := range cases {
+		_, err := tc.position.State(settings)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("%s: expected a field error, got %v", name, err)
+		}
+		if fieldErr.Field != tc.field {
+			t.Fatalf("%s: expected field %q, got %q", name, tc.field, fieldErr.Field)
+		}
+	}
+}
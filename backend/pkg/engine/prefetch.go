@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+// prefetchPredictDepth is the depth of the search ranking the human's
+// replies before they are searched to full depth.
+const prefetchPredictDepth = 2
+
+// HumanThinking reports whether a game against the engine is running with
+// the human to move.
+func (gc *GameController) HumanThinking() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.humanThinkingLocked()
+}
+
+func (gc *GameController) humanThinkingLocked() bool {
+	state := gc.game.state
+	if state.Status != StatusRunning {
+		return false
+	}
+	return gc.game.CurrentPlayerIsHuman() && !gc.game.playerForColor(otherPlayer(state.ToMove)).IsHuman()
+}
+
+// humanThinkingAt reports whether the human is still to move from the
+// position key.
+func (gc *GameController) humanThinkingAt(key uint64) bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.humanThinkingLocked() && PositionKey(gc.game.state) == key
+}
+
+// prefetchRepliesLocked queues the positions after the human's most likely
+// replies for the backlog, so the engine's answer hits the transposition
+// table whichever of them is played.
+func (gc *GameController) prefetchRepliesLocked() {
+	config := GetConfig()
+	if !config.AiQueueEnabled || config.AiPrefetchReplies <= 0 || !gc.humanThinkingLocked() {
+		return
+	}
+	state, rules := gc.game.State(), gc.game.rules
+	go func() {
+		replies := likelyReplies(state, rules, config.AiPrefetchReplies)
+		if !gc.humanThinkingAt(PositionKey(state)) {
+			return
+		}
+		SearchBacklogManager.demotePrefetch()
+		for _, reply := range replies {
+			enqueueBacklogTask(reply, rules, false, backlogPrefetch)
+		}
+	}()
+}
+
+// likelyReplies ranks the moves of the side to move with a shallow search
+// and returns the positions after the count best ones, best first.
+// Transpositions are listed once and finished games are skipped.
+func likelyReplies(state GameState, rules Rules, count int) []GameState {
+	if state.Status != StatusRunning || count <= 0 {
+		return nil
+	}
+	if state.Hash == 0 {
+		state.recomputeHashes()
+	}
+	_, scores, _, ok := searchPosition(state, rules, prefetchPredictDepth, 0, 0)
+	size := state.Board.Size()
+	if !ok || len(scores) < size*size {
+		return nil
+	}
+	sign := scoreSign(state.ToMove)
+	var moves []Move
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			score := scores[y*size+x]
+			if score == illegalScore || math.IsInf(score, 0) || math.IsNaN(score) || state.Board.At(x, y) != CellEmpty {
+				continue
+			}
+			moves = append(moves, Move{X: x, Y: y})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return sign*scores[moves[i].Y*size+moves[i].X] > sign*scores[moves[j].Y*size+moves[j].X]
+	})
+	seen := map[uint64]bool{}
+	var replies []GameState
+	for _, move := range moves {
+		if len(replies) >= count {
+			break
+		}
+		child := state.Clone()
+		if !applyMove(&child, rules, move, child.ToMove) || child.Status != StatusRunning {
+			continue
+		}
+		key := PositionKey(child)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		replies = append(replies, child)
+	}
+	return replies
+}
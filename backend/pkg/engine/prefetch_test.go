@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLikelyRepliesBlockTheOpenFour(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	for x := 2; x <= 4; x++ {
+		state.Board.Set(x, 4, CellBlack)
+	}
+	state.Board.Set(4, 0, CellWhite)
+	state.Board.Set(5, 0, CellWhite)
+	state.ToMove = PlayerWhite
+	state.recomputeHashes()
+	replies := likelyReplies(state, NewRules(settings), 3)
+	if len(replies) == 0 || len(replies) > 3 {
+		t.Fatalf("expected up to 3 replies, got %d", len(replies))
+	}
+	first := replies[0]
+	if first.Board.At(1, 4) != CellWhite && first.Board.At(5, 4) != CellWhite {
+		t.Fatalf("expected the best reply to block the open three")
+	}
+	seen := map[uint64]bool{}
+	for _, reply := range replies {
+		if reply.ToMove != PlayerBlack || seen[PositionKey(reply)] {
+			t.Fatalf("expected distinct positions with black to move")
+		}
+		seen[PositionKey(reply)] = true
+	}
+}
+
+func TestPrefetchTasksComeBeforeTheOpeningTree(t *testing.T) {
+	b := newSearchBacklog()
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	opening := DefaultGameState(settings)
+	opening.Board.Set(0, 0, CellBlack)
+	opening.recomputeHashes()
+	reply := DefaultGameState(settings)
+	reply.Board.Set(8, 8, CellBlack)
+	reply.Board.Set(4, 4, CellWhite)
+	reply.recomputeHashes()
+	b.enqueue(backlogTask{state: opening, created: time.Unix(1, 0), targetDepth: 8, kind: backlogWarmup}, false)
+	b.enqueue(backlogTask{state: reply, created: time.Unix(2, 0), targetDepth: 8, kind: backlogPrefetch}, false)
+
+	task, hash, ok := b.pickHumanTurnTask(true)
+	if !ok || task.kind != backlogPrefetch {
+		t.Fatalf("expected the prefetched reply first")
+	}
+	b.finishTaskProcessing(hash, false)
+	if task, _, ok := b.pickHumanTurnTask(false); !ok || task.kind != backlogPrefetch {
+		t.Fatalf("expected prefetched replies past the opening")
+	}
+	b.demotePrefetch()
+	if task, _, ok := b.pickHumanTurnTask(false); ok {
+		t.Fatalf("expected demoted replies to wait, got kind %d", task.kind)
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import "fmt"
+
+// Premove states reported to clients.
+const (
+	PremoveQueued    = "queued"
+	PremoveApplied   = "applied"
+	PremoveRejected  = "rejected"
+	PremoveCancelled = "cancelled"
+)
+
+// PremoveEvent reports what happened to a premove: queued while the engine
+// thinks, applied or rejected when the turn came back, or cancelled.
+type PremoveEvent struct {
+	GameID uint64 `json:"game_id"`
+	Move   Move   `json:"move"`
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type pendingPremove struct {
+	gameID uint64
+	move   Move
+}
+
+// SetPremovePublisher registers the sink for premoves applied or rejected
+// by the tick loop.
+func (gc *GameController) SetPremovePublisher(publisher func(PremoveEvent)) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.premovePublisher = publisher
+}
+
+// QueuePremove records the human's next move while the engine is to move,
+// replacing an earlier premove. It is only checked for bounds and an empty
+// cell now; the rules apply when the turn flips. When the human is already
+// to move, the move is played right away.
+func (gc *GameController) QueuePremove(move Move) (PremoveEvent, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	event := PremoveEvent{GameID: gc.gameID, Move: move}
+	state := gc.game.state
+	if state.Status != StatusRunning {
+		return event, ErrGameOver
+	}
+	if !move.IsValid(state.Board.Size()) {
+		return event, fmt.Errorf("%w: out of bounds", ErrIllegalMove)
+	}
+	if gc.game.CurrentPlayerIsHuman() {
+		gc.premove = nil
+		return gc.applyPremoveLocked(event), nil
+	}
+	if !gc.game.playerForColor(otherPlayer(state.ToMove)).IsHuman() {
+		return event, ErrNotYourTurn
+	}
+	if state.Board.At(move.X, move.Y) != CellEmpty {
+		return event, fmt.Errorf("%w: occupied", ErrIllegalMove)
+	}
+	gc.premove = &pendingPremove{gameID: gc.gameID, move: move}
+	event.State = PremoveQueued
+	return event, nil
+}
+
+// CancelPremove drops the queued premove, if any.
+func (gc *GameController) CancelPremove() (PremoveEvent, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.premove == nil || gc.premove.gameID != gc.gameID {
+		gc.premove = nil
+		return PremoveEvent{}, false
+	}
+	event := PremoveEvent{GameID: gc.gameID, Move: gc.premove.move, State: PremoveCancelled}
+	gc.premove = nil
+	return event, true
+}
+
+// Premove returns the queued premove of the current game.
+func (gc *GameController) Premove() (Move, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.premove == nil || gc.premove.gameID != gc.gameID {
+		return Move{}, false
+	}
+	return gc.premove.move, true
+}
+
+// playPremoveLocked plays the queued premove once the human is to move and
+// reports whether the game changed. A premove left from an earlier game is
+// dropped silently.
+func (gc *GameController) playPremoveLocked() bool {
+	if gc.premove == nil {
+		return false
+	}
+	if gc.premove.gameID != gc.gameID || gc.game.state.Status != StatusRunning {
+		gc.premove = nil
+		return false
+	}
+	if !gc.game.CurrentPlayerIsHuman() {
+		return false
+	}
+	event := PremoveEvent{GameID: gc.gameID, Move: gc.premove.move}
+	gc.premove = nil
+	event = gc.applyPremoveLocked(event)
+	if gc.premovePublisher != nil {
+		gc.premovePublisher(event)
+	}
+	return event.State == PremoveApplied
+}
+
+func (gc *GameController) applyPremoveLocked(event PremoveEvent) PremoveEvent {
+	applied, reason := gc.game.TryApplyMove(event.Move)
+	gc.creditUserLocked()
+	gc.logProgressLocked()
+	if !applied {
+		event.State, event.Reason = PremoveRejected, reason
+		return event
+	}
+	event.State = PremoveApplied
+	return event
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func newPremoveController(t *testing.T) *GameController {
+	t.Helper()
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	return controller
+}
+
+func TestPremoveIsPlayedWhenTheTurnFlips(t *testing.T) {
+	controller := newPremoveController(t)
+	var events []PremoveEvent
+	controller.SetPremovePublisher(func(event PremoveEvent) { events = append(events, event) })
+
+	event, err := controller.QueuePremove(Move{X: 4, Y: 4})
+	if err != nil || event.State != PremoveApplied {
+		t.Fatalf("expected a premove on the human's turn to be played at once, got %+v err=%v", event, err)
+	}
+	event, err = controller.QueuePremove(Move{X: 5, Y: 5})
+	if err != nil || event.State != PremoveQueued {
+		t.Fatalf("expected the premove to be queued, got %+v err=%v", event, err)
+	}
+	if _, err := controller.QueuePremove(Move{X: 4, Y: 4}); !errors.Is(err, ErrIllegalMove) {
+		t.Fatalf("expected an occupied cell to be refused, got %v", err)
+	}
+
+	controller.mu.Lock()
+	controller.game.TryApplyMove(Move{X: 0, Y: 0})
+	controller.mu.Unlock()
+	if !controller.Tick() {
+		t.Fatalf("expected the tick to play the premove")
+	}
+	if len(events) != 1 || events[0].State != PremoveApplied {
+		t.Fatalf("expected an applied acknowledgement, got %+v", events)
+	}
+	if controller.State().Board.At(5, 5) != CellBlack {
+		t.Fatalf("expected the premove on the board")
+	}
+}
+
+func TestPremoveRejectedWhenNoLongerLegal(t *testing.T) {
+	controller := newPremoveController(t)
+	var events []PremoveEvent
+	controller.SetPremovePublisher(func(event PremoveEvent) { events = append(events, event) })
+	if _, err := controller.QueuePremove(Move{X: 4, Y: 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := controller.QueuePremove(Move{X: 5, Y: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	controller.mu.Lock()
+	controller.game.TryApplyMove(Move{X: 5, Y: 5})
+	controller.mu.Unlock()
+	if controller.Tick() && controller.State().Board.At(5, 5) == CellBlack {
+		t.Fatalf("expected the premove on an occupied cell not to be played")
+	}
+	if len(events) != 1 || events[0].State != PremoveRejected || events[0].Reason == "" {
+		t.Fatalf("expected a rejected acknowledgement, got %+v", events)
+	}
+	if _, ok := controller.Premove(); ok {
+		t.Fatalf("expected the rejected premove to be dropped")
+	}
+}
+
+func TestCancelPremove(t *testing.T) {
+	controller := newPremoveController(t)
+	if _, err := controller.QueuePremove(Move{X: 4, Y: 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := controller.QueuePremove(Move{X: 5, Y: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event, ok := controller.CancelPremove(); !ok || event.State != PremoveCancelled {
+		t.Fatalf("expected the premove to be cancelled, got %+v", event)
+	}
+	if _, ok := controller.CancelPremove(); ok {
+		t.Fatalf("expected nothing left to cancel")
+	}
+}
@@ -0,0 +1,155 @@
+package engine
+
+// MovePreview describes what playing a move would do, without playing it.
+type MovePreview struct {
+	Move          Move    `json:"move"`
+	Player        int     `json:"player"`
+	Legal         bool    `json:"legal"`
+	Reason        string  `json:"reason,omitempty"`
+	Captures      []Move  `json:"captures"`
+	CapturedBlack int     `json:"captured_black"`
+	CapturedWhite int     `json:"captured_white"`
+	Board         [][]int `json:"board,omitempty"`
+	Status        string  `json:"status"`
+	Winner        int     `json:"winner"`
+	// WinReason is "capture", "alignment" or "capture-threat" when the move
+	// ends the game, the latter when the opponent is then forced to win by
+	// capture.
+	WinReason          string `json:"win_reason,omitempty"`
+	WinningLine        []Move `json:"winning_line,omitempty"`
+	WinningCapturePair []Move `json:"winning_capture_pair,omitempty"`
+	// A five the opponent can still break by capture does not win yet; the
+	// opponent must then play one of ForcedCaptureMoves.
+	AlignmentBreakable bool   `json:"alignment_breakable"`
+	ForcedCaptureMoves []Move `json:"forced_capture_moves,omitempty"`
+	PendingAlignment   []Move `json:"pending_alignment,omitempty"`
+	// OpponentCaptureWin is the capture the opponent answers with when it
+	// wins the game. With AutoPlayCaptureWin off it is not played: the game
+	// goes on and OpponentCaptureWinCaptures are the stones it would take.
+	OpponentCaptureWin         *Move  `json:"opponent_capture_win,omitempty"`
+	OpponentCaptureWinCaptures []Move `json:"opponent_capture_win_captures,omitempty"`
+	NextPlayer                 int    `json:"next_player"`
+}
+
+// PreviewMove plays move for the side to move on a copy of the game, with
+// the same consequences as a real move.
+func (gc *GameController) PreviewMove(move Move) (MovePreview, error) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if gc.game.state.Status != StatusRunning {
+		return MovePreview{}, ErrGameOver
+	}
+	return previewMove(gc.game.state.Clone(), gc.game.rules, move), nil
+}
+
+// previewMove mirrors Game.TryApplyMove on state, which it owns.
+func previewMove(state GameState, rules Rules, move Move) MovePreview {
+	player := state.ToMove
+	preview := MovePreview{
+		Move:          move,
+		Player:        PlayerToInt(player),
+		Captures:      []Move{},
+		CapturedBlack: state.CapturedBlack,
+		CapturedWhite: state.CapturedWhite,
+		Status:        StatusToString(state.Status),
+		NextPlayer:    PlayerToInt(player),
+	}
+	if ok, reason := rules.IsLegal(state, move, player); !ok {
+		preview.Reason = reason
+		return preview
+	}
+	preview.Legal = true
+
+	cell := CellFromPlayer(player)
+	state.Board.Set(move.X, move.Y, cell)
+	state.LastMove = move
+	state.HasLastMove = true
+	state.MustCapture = false
+	state.ForcedCaptureMoves = nil
+	captures := rules.FindCaptures(state.Board, move, cell)
+	for _, captured := range captures {
+		state.Board.Remove(captured.X, captured.Y)
+	}
+	if player == PlayerBlack {
+		state.CapturedBlack += len(captures)
+	} else {
+		state.CapturedWhite += len(captures)
+	}
+	preview.Captures = append(preview.Captures, captures...)
+
+	opponent := otherPlayer(player)
+	finish := func(winner PlayerColor, reason string) MovePreview {
+		state.Status = StatusWhiteWon
+		if winner == PlayerBlack {
+			state.Status = StatusBlackWon
+		}
+		preview.WinReason = reason
+		preview.CapturedBlack = state.CapturedBlack
+		preview.CapturedWhite = state.CapturedWhite
+		preview.Board = BoardToSlice(state.Board)
+		preview.Status = StatusToString(state.Status)
+		preview.Winner = WinnerFromStatus(state.Status)
+		preview.NextPlayer = PlayerToInt(opponent)
+		return preview
+	}
+
+	captureCount := state.CapturedBlack
+	if player == PlayerWhite {
+		captureCount = state.CapturedWhite
+	}
+	if captureCount >= rules.CaptureWinStones() {
+		return finish(player, "capture")
+	}
+	if rules.IsWin(state.Board, move) {
+		if !rules.OpponentCanBreakAlignmentByCapture(state, opponent) {
+			if line, ok := rules.FindAlignmentLine(state.Board, move); ok {
+				preview.WinningLine = line
+			}
+			return finish(player, "alignment")
+		}
+		preview.AlignmentBreakable = true
+		preview.ForcedCaptureMoves = rules.FindAlignmentBreakCaptures(state, opponent)
+		if len(preview.ForcedCaptureMoves) > 0 {
+			preview.PendingAlignment, _ = rules.FindAlignmentLine(state.Board, move)
+		}
+	}
+	opponentCaptureCount := state.CapturedBlack
+	if opponent == PlayerWhite {
+		opponentCaptureCount = state.CapturedWhite
+	}
+	forcedMove, forcedCaptures, captureWin := rules.FindImmediateCaptureWinMove(state, opponent, opponentCaptureCount)
+	if captureWin && !GetConfig().AutoPlayCaptureWin {
+		preview.OpponentCaptureWin = &forcedMove
+		preview.OpponentCaptureWinCaptures = forcedCaptures
+	} else if captureWin {
+		state.Board.Set(forcedMove.X, forcedMove.Y, CellFromPlayer(opponent))
+		for _, captured := range forcedCaptures {
+			state.Board.Remove(captured.X, captured.Y)
+		}
+		if opponent == PlayerBlack {
+			state.CapturedBlack += len(forcedCaptures)
+		} else {
+			state.CapturedWhite += len(forcedCaptures)
+		}
+		preview.AlignmentBreakable = false
+		preview.ForcedCaptureMoves = nil
+		preview.PendingAlignment = nil
+		preview.OpponentCaptureWin = &forcedMove
+		preview.WinningCapturePair = forcedCaptures
+		return finish(opponent, "capture-threat")
+	}
+
+	if rules.IsDraw(state.Board) {
+		state.Status = StatusDraw
+		preview.ForcedCaptureMoves = nil
+		preview.PendingAlignment = nil
+		preview.AlignmentBreakable = false
+	}
+	preview.CapturedBlack = state.CapturedBlack
+	preview.CapturedWhite = state.CapturedWhite
+	preview.Board = BoardToSlice(state.Board)
+	preview.Status = StatusToString(state.Status)
+	preview.Winner = WinnerFromStatus(state.Status)
+	preview.NextPlayer = PlayerToInt(opponent)
+	return preview
+}
@@ -0,0 +1,64 @@
+package engine
+
+import "testing"
+
+func TestPreviewMoveMatchesAppliedMove(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	for _, move := range []Move{{X: 9, Y: 9}, {X: 10, Y: 9}, {X: 0, Y: 0}, {X: 11, Y: 9}} {
+		if applied, reason := controller.ApplyHumanMove(move); !applied {
+			t.Fatalf("setup move %v rejected: %s", move, reason)
+		}
+	}
+
+	before := controller.State()
+	preview, err := controller.PreviewMove(Move{X: 12, Y: 9})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if !preview.Legal || len(preview.Captures) != 2 || preview.CapturedBlack != 2 || preview.Status != "running" {
+		t.Fatalf("expected a legal capture of two stones, got %+v", preview)
+	}
+	if preview.Board[9][10] != 0 || preview.Board[9][12] != 1 || preview.NextPlayer != 2 {
+		t.Fatalf("unexpected resulting board or side to move: %+v", preview)
+	}
+	after := controller.State()
+	if after.Hash != before.Hash || after.CapturedBlack != 0 || controller.History().Size() != 4 {
+		t.Fatalf("preview must not change the game")
+	}
+
+	if applied, reason := controller.ApplyHumanMove(Move{X: 12, Y: 9}); !applied {
+		t.Fatalf("move rejected: %s", reason)
+	}
+	state := controller.State()
+	if got := BoardToSlice(state.Board); got[9][10] != preview.Board[9][10] || got[9][11] != preview.Board[9][11] || state.CapturedBlack != preview.CapturedBlack {
+		t.Fatalf("preview disagrees with the applied move")
+	}
+
+	illegal, err := controller.PreviewMove(Move{X: 9, Y: 9})
+	if err != nil || illegal.Legal || illegal.Reason != "occupied" || illegal.Board != nil {
+		t.Fatalf("expected an occupied-cell rejection, got %+v (%v)", illegal, err)
+	}
+}
+
+func TestPreviewMoveReportsAlignmentWin(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	for i := 0; i < 4; i++ {
+		controller.ApplyHumanMove(Move{X: 5 + i, Y: 5})
+		controller.ApplyHumanMove(Move{X: 5 + i, Y: 12})
+	}
+	preview, err := controller.PreviewMove(Move{X: 9, Y: 5})
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if preview.Status != "black_won" || preview.Winner != 1 || preview.WinReason != "alignment" || len(preview.WinningLine) < 5 {
+		t.Fatalf("expected an alignment win, got %+v", preview)
+	}
+}
@@ -0,0 +1,338 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+)
+
+const (
+	DefaultRenderCellSize = 32
+	minRenderCellSize     = 8
+	maxRenderCellSize     = 96
+)
+
+// RenderRequest describes a position to draw that is not the live game:
+// Moves are replayed on top of Position (or the empty board) and Ply, when
+// set, stops the replay after that many moves.
+type RenderRequest struct {
+	Position *StartPosition `json:"position,omitempty"`
+	Moves    []Move         `json:"moves"`
+	Ply      *int           `json:"ply,omitempty"`
+}
+
+// BoardImage is a position ready to be drawn. Board and Numbers are indexed
+// [y][x]; Numbers holds the ply that placed each stone, 0 for stones that
+// came with the start position.
+type BoardImage struct {
+	Board       [][]int
+	Numbers     [][]int
+	LastMove    *Move
+	WinningLine []Move
+}
+
+// BoardImageFromHistory draws the first ply entries of a recorded game. The
+// winning line is only shown when ply covers the whole history.
+func BoardImageFromHistory(settings GameSettings, start *StartPosition, entries []HistoryEntry, ply int, winningLine []Move) (BoardImage, error) {
+	if ply < 0 || ply > len(entries) {
+		return BoardImage{}, fmt.Errorf("ply must be between 0 and %d", len(entries))
+	}
+	state, err := renderStartState(settings, start)
+	if err != nil {
+		return BoardImage{}, err
+	}
+	numbers := newRenderNumbers(state.Board.Size())
+	for i, entry := range entries[:ply] {
+		state.Board.Set(entry.Move.X, entry.Move.Y, playerCell(entry.Player))
+		for _, captured := range entry.CapturedPositions {
+			state.Board.Remove(captured.X, captured.Y)
+		}
+		numbers[entry.Move.Y][entry.Move.X] = i + 1
+	}
+	img := newBoardImage(state.Board, numbers, entries[:ply])
+	if ply == len(entries) {
+		img.WinningLine = append([]Move(nil), winningLine...)
+	}
+	return img, nil
+}
+
+// BoardImageFromMoves replays req.Moves with the game rules and draws the
+// result, highlighting the alignment when the last move won by one.
+func BoardImageFromMoves(settings GameSettings, req RenderRequest) (BoardImage, error) {
+	ply := len(req.Moves)
+	if req.Ply != nil {
+		if *req.Ply < 0 || *req.Ply > len(req.Moves) {
+			return BoardImage{}, fmt.Errorf("ply must be between 0 and %d", len(req.Moves))
+		}
+		ply = *req.Ply
+	}
+	state, err := renderStartState(settings, req.Position)
+	if err != nil {
+		return BoardImage{}, err
+	}
+	state.Status = StatusRunning
+	rules := NewRules(settings)
+	size := state.Board.Size()
+	numbers := newRenderNumbers(size)
+	entries := make([]HistoryEntry, 0, ply)
+	for i, move := range req.Moves[:ply] {
+		if state.Status != StatusRunning {
+			return BoardImage{}, fmt.Errorf("move %d played after game end", i+1)
+		}
+		if !move.IsValid(size) {
+			return BoardImage{}, fmt.Errorf("move %d out of bounds", i+1)
+		}
+		if ok, reason := rules.IsLegal(state, move, state.ToMove); !ok {
+			return BoardImage{}, fmt.Errorf("move %d illegal: %s", i+1, reason)
+		}
+		entries = append(entries, HistoryEntry{Move: move, Player: state.ToMove})
+		applyMove(&state, rules, move, state.ToMove)
+		numbers[move.Y][move.X] = i + 1
+	}
+	img := newBoardImage(state.Board, numbers, entries)
+	if state.Status != StatusRunning && state.HasLastMove && rules.IsWin(state.Board, state.LastMove) {
+		if line, ok := rules.FindAlignmentLine(state.Board, state.LastMove); ok {
+			img.WinningLine = line
+		}
+	}
+	return img, nil
+}
+
+func renderStartState(settings GameSettings, start *StartPosition) (GameState, error) {
+	if start == nil {
+		return DefaultGameState(settings), nil
+	}
+	return start.State(settings)
+}
+
+func newRenderNumbers(size int) [][]int {
+	numbers := make([][]int, size)
+	for y := range numbers {
+		numbers[y] = make([]int, size)
+	}
+	return numbers
+}
+
+func newBoardImage(board Board, numbers [][]int, entries []HistoryEntry) BoardImage {
+	img := BoardImage{Board: BoardToSlice(board), Numbers: numbers}
+	for y, row := range img.Board {
+		for x, value := range row {
+			if value == 0 {
+				numbers[y][x] = 0
+			}
+		}
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1].Move
+		img.LastMove = &last
+	}
+	return img
+}
+
+// renderLayout places cells on the canvas: square cells separated by a small
+// gap, with a half-cell margin around the board.
+type renderLayout struct {
+	cell   int
+	gap    int
+	margin int
+	size   int
+}
+
+func newRenderLayout(boardSize, cellSize int) renderLayout {
+	if cellSize <= 0 {
+		cellSize = DefaultRenderCellSize
+	}
+	cellSize = min(max(cellSize, minRenderCellSize), maxRenderCellSize)
+	gap := max(1, cellSize/12)
+	margin := cellSize / 2
+	return renderLayout{
+		cell:   cellSize,
+		gap:    gap,
+		margin: margin,
+		size:   2*margin + boardSize*cellSize + max(0, boardSize-1)*gap,
+	}
+}
+
+func (l renderLayout) origin(x, y int) (int, int) {
+	return l.margin + x*(l.cell+l.gap), l.margin + y*(l.cell+l.gap)
+}
+
+// Colours follow the web UI: Blue plays black, Red plays white. Raster
+// images are paletted with exactly these colours.
+var (
+	renderBackground = color.RGBA{0x05, 0x06, 0x08, 0xff}
+	renderEmptyCell  = color.RGBA{0x15, 0x16, 0x18, 0xff}
+	renderBlack      = color.RGBA{0x4f, 0x9a, 0xff, 0xff}
+	renderWhite      = color.RGBA{0xff, 0x4c, 0x4c, 0xff}
+	renderWinning    = color.RGBA{0xff, 0xd8, 0x4d, 0xff}
+	renderLastMove   = color.RGBA{0xf4, 0xf6, 0xfb, 0xff}
+	renderPalette    = color.Palette{renderBackground, renderEmptyCell, renderBlack, renderWhite, renderWinning, renderLastMove}
+)
+
+const (
+	renderIndexBackground uint8 = iota
+	renderIndexEmptyCell
+	renderIndexBlack
+	renderIndexWhite
+	renderIndexWinning
+	renderIndexLastMove
+)
+
+func renderCellColor(value int) color.RGBA {
+	return renderPalette[renderCellIndex(value)].(color.RGBA)
+}
+
+func renderCellIndex(value int) uint8 {
+	switch value {
+	case 1:
+		return renderIndexBlack
+	case 2:
+		return renderIndexWhite
+	default:
+		return renderIndexEmptyCell
+	}
+}
+
+func renderHex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (b BoardImage) winningSet() map[Move]bool {
+	set := make(map[Move]bool, len(b.WinningLine))
+	for _, move := range b.WinningLine {
+		set[Move{X: move.X, Y: move.Y}] = true
+	}
+	return set
+}
+
+// SVG draws the board with optional move numbers on each stone.
+func (b BoardImage) SVG(cellSize int, numbers bool) []byte {
+	layout := newRenderLayout(len(b.Board), cellSize)
+	winning := b.winningSet()
+	radius := max(1, layout.cell/6)
+	stroke := max(2, layout.cell/12)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, layout.size, layout.size, layout.size, layout.size)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, renderHex(renderBackground))
+	for y, row := range b.Board {
+		for x, value := range row {
+			left, top := layout.origin(x, y)
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s"`, left, top, layout.cell, layout.cell, radius, renderHex(renderCellColor(value)))
+			switch {
+			case winning[Move{X: x, Y: y}]:
+				fmt.Fprintf(&buf, ` stroke="%s" stroke-width="%d"`, renderHex(renderWinning), stroke)
+			case b.LastMove != nil && b.LastMove.X == x && b.LastMove.Y == y:
+				fmt.Fprintf(&buf, ` stroke="%s" stroke-width="%d"`, renderHex(renderLastMove), stroke)
+			}
+			buf.WriteString(`/>`)
+			if numbers && value != 0 && b.Numbers[y][x] > 0 {
+				fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="monospace" font-size="%d" fill="%s" text-anchor="middle" dominant-baseline="central">%d</text>`,
+					left+layout.cell/2, top+layout.cell/2, layout.cell*45/100, renderHex(renderLastMove), b.Numbers[y][x])
+			}
+		}
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// PNG draws the same picture as SVG. Move numbers use a built-in 3x5 digit
+// font and are left out on cells too small to hold them.
+func (b BoardImage) PNG(cellSize int, numbers bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, b.raster(newRenderLayout(len(b.Board), cellSize), numbers)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (b BoardImage) raster(layout renderLayout, numbers bool) *image.Paletted {
+	winning := b.winningSet()
+	canvas := image.NewPaletted(image.Rect(0, 0, layout.size, layout.size), renderPalette)
+	radius := max(1, layout.cell/6)
+	stroke := max(2, layout.cell/12)
+	for y, row := range b.Board {
+		for x, value := range row {
+			left, top := layout.origin(x, y)
+			fillRoundedRect(canvas, left, top, layout.cell, radius, renderCellIndex(value))
+			switch {
+			case winning[Move{X: x, Y: y}]:
+				strokeRect(canvas, left, top, layout.cell, stroke, renderIndexWinning)
+			case b.LastMove != nil && b.LastMove.X == x && b.LastMove.Y == y:
+				strokeRect(canvas, left, top, layout.cell, stroke, renderIndexLastMove)
+			}
+			if numbers && value != 0 && b.Numbers[y][x] > 0 {
+				drawNumber(canvas, left, top, layout.cell, b.Numbers[y][x], renderIndexLastMove)
+			}
+		}
+	}
+	return canvas
+}
+
+func fillRect(canvas *image.Paletted, left, top, width, height int, index uint8) {
+	for y := top; y < top+height; y++ {
+		for x := left; x < left+width; x++ {
+			canvas.SetColorIndex(x, y, index)
+		}
+	}
+}
+
+func fillRoundedRect(canvas *image.Paletted, left, top, size, radius int, index uint8) {
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			cx := min(max(dx, radius), size-1-radius)
+			cy := min(max(dy, radius), size-1-radius)
+			if (dx-cx)*(dx-cx)+(dy-cy)*(dy-cy) > radius*radius {
+				continue
+			}
+			canvas.SetColorIndex(left+dx, top+dy, index)
+		}
+	}
+}
+
+func strokeRect(canvas *image.Paletted, left, top, size, width int, index uint8) {
+	fillRect(canvas, left, top, size, width, index)
+	fillRect(canvas, left, top+size-width, size, width, index)
+	fillRect(canvas, left, top, width, size, index)
+	fillRect(canvas, left+size-width, top, width, size, index)
+}
+
+// renderDigits is a 3x5 bitmap font, one row per entry, most significant bit
+// on the left.
+var renderDigits = [10][5]uint8{
+	{7, 5, 5, 5, 7},
+	{2, 6, 2, 2, 7},
+	{7, 1, 7, 4, 7},
+	{7, 1, 7, 1, 7},
+	{5, 5, 7, 1, 1},
+	{7, 4, 7, 1, 7},
+	{7, 4, 7, 5, 7},
+	{7, 1, 1, 1, 1},
+	{7, 5, 7, 5, 7},
+	{7, 5, 7, 1, 7},
+}
+
+func drawNumber(canvas *image.Paletted, left, top, cell, number int, index uint8) {
+	text := strconv.Itoa(number)
+	scale := max(1, cell/14)
+	width := (len(text)*4 - 1) * scale
+	height := 5 * scale
+	if width > cell-2 {
+		return
+	}
+	x0 := left + (cell-width)/2
+	y0 := top + (cell-height)/2
+	for i, ch := range text {
+		glyph := renderDigits[ch-'0']
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) == 0 {
+					continue
+				}
+				fillRect(canvas, x0+(i*4+col)*scale, y0+row*scale, scale, scale, index)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestBoardImageFromHistoryDropsCapturedNumbers(t *testing.T) {
+	settings := DefaultGameSettings()
+	entries := []HistoryEntry{
+		{Move: Move{X: 4, Y: 4}, Player: PlayerBlack},
+		{Move: Move{X: 5, Y: 4}, Player: PlayerWhite},
+		{Move: Move{X: 9, Y: 9}, Player: PlayerBlack},
+		{Move: Move{X: 6, Y: 4}, Player: PlayerWhite},
+		{Move: Move{X: 7, Y: 4}, Player: PlayerBlack, CapturedPositions: []Move{{X: 5, Y: 4}, {X: 6, Y: 4}}},
+	}
+	img, err := BoardImageFromHistory(settings, nil, entries, len(entries), nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if img.Board[4][5] != 0 || img.Numbers[4][5] != 0 || img.Numbers[4][7] != 5 {
+		t.Fatalf("unexpected board/numbers after capture: %v %v", img.Board[4], img.Numbers[4])
+	}
+	if img.LastMove == nil || *img.LastMove != (Move{X: 7, Y: 4}) {
+		t.Fatalf("unexpected last move %+v", img.LastMove)
+	}
+	early, err := BoardImageFromHistory(settings, nil, entries, 2, []Move{{X: 0, Y: 0}})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if early.Board[4][5] != 2 || early.Board[9][9] != 0 || early.WinningLine != nil {
+		t.Fatalf("ply 2 should show two stones and no winning line: %+v", early)
+	}
+	if _, err := BoardImageFromHistory(settings, nil, entries, 6, nil); err == nil {
+		t.Fatalf("expected ply past the history to be rejected")
+	}
+}
+
+func TestBoardImageFromMovesHighlightsWin(t *testing.T) {
+	settings := DefaultGameSettings()
+	moves := []Move{}
+	for i := 0; i < 5; i++ {
+		moves = append(moves, Move{X: 2 + i, Y: 2})
+		if i < 4 {
+			moves = append(moves, Move{X: 2 + i, Y: 10})
+		}
+	}
+	img, err := BoardImageFromMoves(settings, RenderRequest{Moves: moves})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if len(img.WinningLine) != 5 {
+		t.Fatalf("expected a 5-stone winning line, got %v", img.WinningLine)
+	}
+	svg := string(img.SVG(20, true))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, ">9</text>") || !strings.Contains(svg, "#ffd84d") {
+		t.Fatalf("svg is missing numbers or the winning highlight")
+	}
+	data, err := img.PNG(20, true)
+	if err != nil {
+		t.Fatalf("png failed: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png does not decode: %v", err)
+	}
+	layout := newRenderLayout(settings.BoardSize, 20)
+	if decoded.Bounds().Dx() != layout.size || decoded.Bounds().Dy() != layout.size {
+		t.Fatalf("unexpected png size %v, want %d", decoded.Bounds(), layout.size)
+	}
+}
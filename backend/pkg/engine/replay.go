@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"time"
+)
+
+const (
+	DefaultReplayFrameDelay = 600 * time.Millisecond
+	replayFinalFrameHold    = 3 * time.Second
+)
+
+// GameRecord is a game kept for replay export: the settings it was played
+// with, its seed position (nil for the empty board), its full history and
+// the annotations attached to it. User is the profile playing the human side
+// against the engine, searching at AIDepth.
+type GameRecord struct {
+	ID          uint64
+	Settings    GameSettings
+	Start       *StartPosition
+	Entries     []HistoryEntry
+	Status      GameStatus
+	WinningLine []Move
+	Annotations []Annotation
+	User        string
+	AIDepth     int
+	// Accuracy is set once a review of the game finished.
+	Accuracy *GameAccuracy
+	// MatchID is set for matchmaking games, FairPlay once their review
+	// finished.
+	MatchID  string
+	FairPlay *FairPlayReport
+}
+
+func (r GameRecord) Finished() bool {
+	return r.Status != StatusNotStarted && r.Status != StatusRunning
+}
+
+// HumanColor is the colour of the only human side, or 0 unless exactly one
+// side is human.
+func (r GameRecord) HumanColor() int {
+	switch {
+	case r.Settings.BlackType == PlayerHuman && r.Settings.WhiteType != PlayerHuman:
+		return 1
+	case r.Settings.WhiteType == PlayerHuman && r.Settings.BlackType != PlayerHuman:
+		return 2
+	}
+	return 0
+}
+
+// ReplayGIF renders every ply of a recorded game, from the start position to
+// the final one, into a looping animated GIF. After the first frame only the
+// region that changed since the previous ply is stored, so a frame usually
+// costs a couple of cells.
+func ReplayGIF(record GameRecord, cellSize int, frameDelay time.Duration, numbers bool) ([]byte, error) {
+	if frameDelay <= 0 {
+		frameDelay = DefaultReplayFrameDelay
+	}
+	layout := newRenderLayout(record.Settings.BoardSize, cellSize)
+	anim := &gif.GIF{
+		Config: image.Config{ColorModel: renderPalette, Width: layout.size, Height: layout.size},
+	}
+	var previous *image.Paletted
+	for ply := 0; ply <= len(record.Entries); ply++ {
+		img, err := BoardImageFromHistory(record.Settings, record.Start, record.Entries, ply, record.WinningLine)
+		if err != nil {
+			return nil, fmt.Errorf("ply %d: %w", ply, err)
+		}
+		canvas := img.raster(layout, numbers)
+		frame := canvas
+		if previous != nil {
+			frame = canvas.SubImage(changedBounds(previous, canvas)).(*image.Paletted)
+		}
+		delay := frameDelay
+		if ply == len(record.Entries) {
+			delay = replayFinalFrameHold
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+		previous = canvas
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// changedBounds is the smallest rectangle holding every pixel that differs
+// between two same-sized canvases (a single pixel when nothing changed).
+func changedBounds(before, after *image.Paletted) image.Rectangle {
+	bounds := after.Bounds()
+	minX, minY, maxX, maxY := bounds.Max.X, bounds.Max.Y, bounds.Min.X-1, bounds.Min.Y-1
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := y * after.Stride
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if before.Pix[row+x] == after.Pix[row+x] {
+				continue
+			}
+			minX, maxX = min(minX, x), max(maxX, x)
+			minY, maxY = min(minY, y), max(maxY, y)
+		}
+	}
+	if maxX < minX {
+		return image.Rect(0, 0, 1, 1)
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
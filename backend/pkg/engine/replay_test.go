@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+)
+
+func TestReplayGIFHasOneFramePerPly(t *testing.T) {
+	settings := DefaultGameSettings()
+	record := GameRecord{
+		ID:       1,
+		Settings: settings,
+		Entries: []HistoryEntry{
+			{Move: Move{X: 9, Y: 9}, Player: PlayerBlack},
+			{Move: Move{X: 10, Y: 9}, Player: PlayerWhite},
+			{Move: Move{X: 9, Y: 10}, Player: PlayerBlack},
+		},
+		Status: StatusDraw,
+	}
+	data, err := ReplayGIF(record, 16, 0, true)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gif does not decode: %v", err)
+	}
+	if len(anim.Image) != len(record.Entries)+1 {
+		t.Fatalf("expected %d frames, got %d", len(record.Entries)+1, len(anim.Image))
+	}
+	layout := newRenderLayout(settings.BoardSize, 16)
+	if anim.Config.Width != layout.size || anim.Image[0].Bounds().Dx() != layout.size {
+		t.Fatalf("first frame should cover the board, got %v", anim.Image[0].Bounds())
+	}
+	if later := anim.Image[2].Bounds(); later.Dx() >= layout.size/2 {
+		t.Fatalf("later frames should only hold the changed cells, got %v", later)
+	}
+	if anim.Delay[len(anim.Delay)-1] <= anim.Delay[0] {
+		t.Fatalf("final frame should be held longer: %v", anim.Delay)
+	}
+}
+
+func TestGameControllerArchivesReplacedGames(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	first := controller.GameID()
+	if applied, reason := controller.ApplyHumanMove(Move{X: 9, Y: 9}); !applied {
+		t.Fatalf("expected human move to apply: %s", reason)
+	}
+	controller.StartGame(settings)
+	controller.StartGame(settings)
+
+	record, ok := controller.GameRecord(first)
+	if !ok || len(record.Entries) != 1 || record.Entries[0].Move != (Move{X: 9, Y: 9}) {
+		t.Fatalf("expected the first game in the archive, got %+v (found=%v)", record, ok)
+	}
+	if records := controller.GameRecords(); len(records) != 2 {
+		t.Fatalf("empty games should not be archived, got %d records", len(records))
+	}
+	if current, ok := controller.GameRecord(controller.GameID()); !ok || current.Finished() {
+		t.Fatalf("expected the running current game, got %+v", current)
+	}
+}
@@ -0,0 +1,560 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	reviewQueueSize        = 16
+	reviewKeep             = 64
+	reviewDefaultTimeoutMs = 500
+	// reviewScoreScale turns search scores into win probabilities: a lead
+	// worth about one open three moves the needle by a quarter.
+	reviewScoreScale = 20000.0
+	// reviewCriticalSwing is the win probability swing, or the gap between
+	// the best and the second best move, that makes a ply a critical moment.
+	reviewCriticalSwing = 0.2
+	// reviewOnlyMoveFloor is the win probability the only move must keep:
+	// below it the result is already gone whatever is played.
+	reviewOnlyMoveFloor = 0.3
+)
+
+// Loss thresholds, in win probability for the side that moved.
+var reviewClasses = []struct {
+	maxLoss float64
+	class   string
+}{
+	{0.02, "best"},
+	{0.05, "good"},
+	{0.10, "inaccuracy"},
+	{0.20, "mistake"},
+	{math.Inf(1), "blunder"},
+}
+
+// ReviewRequest imports a game for review, either as an SGF record or as a
+// move list on top of an optional start position. Depth and TimeoutMs bound
+// the search of every position (default: live depth, 500 ms).
+type ReviewRequest struct {
+	SGF       string         `json:"sgf,omitempty"`
+	Moves     []Move         `json:"moves,omitempty"`
+	Position  *StartPosition `json:"position,omitempty"`
+	BoardSize int            `json:"board_size,omitempty"`
+	Depth     int            `json:"depth"`
+	TimeoutMs int            `json:"timeout_ms"`
+	Source    string         `json:"source,omitempty"`
+	User      string         `json:"user,omitempty"`
+	UserColor int            `json:"user_color,omitempty"`
+}
+
+// ReviewMove grades one move. Scores and win probabilities are from the
+// mover's side: Score/WinProb with the engine's best move, PlayedScore and
+// PlayedWinProb with the move actually played.
+type ReviewMove struct {
+	Ply           int     `json:"ply"`
+	Player        int     `json:"player"`
+	Move          Move    `json:"move"`
+	BestMove      Move    `json:"best_move"`
+	Score         float64 `json:"score"`
+	PlayedScore   float64 `json:"played_score"`
+	WinProb       float64 `json:"win_prob"`
+	PlayedWinProb float64 `json:"played_win_prob"`
+	Loss          float64 `json:"loss"`
+	Class         string  `json:"class"`
+	Depth         int     `json:"depth"`
+	// OnlyMove is set when every other move loses at least
+	// reviewCriticalSwing of win probability against the best one.
+	OnlyMove bool `json:"only_move,omitempty"`
+	// ElapsedMs is the think time of the move, for recorded games.
+	ElapsedMs float64 `json:"elapsed_ms,omitempty"`
+}
+
+// CriticalMoment marks a ply worth jumping to: the evaluation swung by
+// more than reviewCriticalSwing ("swing") or only one move kept the result
+// ("only_move"). Swing and WinProb are from black's side, WinProb after the
+// move.
+type CriticalMoment struct {
+	Ply      int      `json:"ply"`
+	Player   int      `json:"player"`
+	Move     Move     `json:"move"`
+	BestMove Move     `json:"best_move"`
+	Reasons  []string `json:"reasons"`
+	Swing    float64  `json:"swing"`
+	WinProb  float64  `json:"win_prob"`
+}
+
+// ReviewSideSummary counts one side's grades. Accuracy is the average of the
+// per-move accuracies, from 100 for a move that gives nothing up down to 0.
+type ReviewSideSummary struct {
+	Moves        int     `json:"moves"`
+	Best         int     `json:"best"`
+	Good         int     `json:"good"`
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+	AverageLoss  float64 `json:"average_loss"`
+	Accuracy     float64 `json:"accuracy"`
+}
+
+// Review is a queued, running or finished game review. Moves fills in as
+// the review progresses. A finished review of a user's game counts towards
+// the accuracy of the side they played.
+type Review struct {
+	ID           uint64            `json:"id"`
+	GameID       uint64            `json:"game_id,omitempty"`
+	MatchID      string            `json:"match_id,omitempty"`
+	Source       string            `json:"source"`
+	User         string            `json:"user,omitempty"`
+	UserColor    int               `json:"user_color,omitempty"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	BoardSize    int               `json:"board_size"`
+	TotalMoves   int               `json:"total_moves"`
+	Moves        []ReviewMove      `json:"moves"`
+	Critical     []CriticalMoment  `json:"critical"`
+	Black        ReviewSideSummary `json:"black"`
+	White        ReviewSideSummary `json:"white"`
+	CreatedAtMs  int64             `json:"created_at_ms"`
+	FinishedAtMs int64             `json:"finished_at_ms,omitempty"`
+}
+
+type reviewJob struct {
+	review    *Review
+	settings  GameSettings
+	start     GameState
+	moves     []Move
+	elapsed   []float64
+	depth     int
+	timeoutMs int
+}
+
+// ReviewQueue runs game reviews one at a time in the background and keeps
+// the last results for retrieval.
+type ReviewQueue struct {
+	mu       sync.Mutex
+	nextID   uint64
+	reviews  []*Review
+	pending  chan reviewJob
+	finished func(Review)
+}
+
+func NewReviewQueue() *ReviewQueue {
+	return &ReviewQueue{pending: make(chan reviewJob, reviewQueueSize)}
+}
+
+// SetFinishedHandler registers fn to be called with every review that
+// finishes successfully.
+func (q *ReviewQueue) SetFinishedHandler(fn func(Review)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.finished = fn
+}
+
+// SubmitGame queues a review of a recorded game.
+func (q *ReviewQueue) SubmitGame(record GameRecord, depth, timeoutMs int) (Review, error) {
+	moves := make([]Move, 0, len(record.Entries))
+	elapsed := make([]float64, 0, len(record.Entries))
+	for _, entry := range record.Entries {
+		moves = append(moves, entry.Move)
+		elapsed = append(elapsed, entry.ElapsedMs)
+	}
+	start, err := renderStartState(record.Settings, record.Start)
+	if err != nil {
+		return Review{}, err
+	}
+	review := Review{Source: "game", GameID: record.ID, MatchID: record.MatchID}
+	if color := record.HumanColor(); record.User != "" && color != 0 {
+		review.User, review.UserColor = record.User, color
+	}
+	return q.submit(record.Settings, start, moves, elapsed, depth, timeoutMs, review)
+}
+
+// SubmitImport validates an external game by replaying it through the rules
+// and queues its review. settings supplies the rules; the board size comes
+// from the SGF or the request when given.
+func (q *ReviewQueue) SubmitImport(settings GameSettings, req ReviewRequest) (Review, error) {
+	moves := req.Moves
+	position := req.Position
+	var colors []int
+	if req.SGF != "" {
+		if len(req.Moves) > 0 || req.Position != nil {
+			return Review{}, fmt.Errorf("send either sgf or moves/position, not both")
+		}
+		game, err := ParseSGF(req.SGF)
+		if err != nil {
+			return Review{}, fieldErrorf("sgf", "invalid sgf: %v", err)
+		}
+		settings.BoardSize = game.BoardSize
+		moves, position, colors = game.Moves, game.Position, game.Colors
+	} else if req.BoardSize > 0 {
+		if req.BoardSize < 5 || req.BoardSize > 19 {
+			return Review{}, fieldErrorf("board_size", "board_size must be between 5 and 19")
+		}
+		settings.BoardSize = req.BoardSize
+	}
+	if len(moves) == 0 {
+		return Review{}, fieldErrorf("moves", "game has no moves")
+	}
+	start, err := renderStartState(settings, position)
+	if err != nil {
+		return Review{}, err
+	}
+	expected := PlayerToInt(start.ToMove)
+	for i, color := range colors {
+		if color != expected {
+			return Review{}, fieldErrorf(fmt.Sprintf("moves[%d]", i), "move %d is played by the wrong colour", i+1)
+		}
+		expected = 3 - expected
+	}
+	if _, err := replayFrom(start.Clone(), NewRules(settings), moves); err != nil {
+		return Review{}, err
+	}
+	review := Review{Source: req.Source, User: req.User, UserColor: req.UserColor}
+	if review.Source == "" {
+		review.Source = "import"
+	}
+	if err := validateSearchLimits("depth", req.Depth, 0); err != nil {
+		return Review{}, err
+	}
+	if req.User != "" && req.UserColor != 1 && req.UserColor != 2 {
+		return Review{}, fieldErrorf("user_color", "user_color must be 1 or 2 when user is set")
+	}
+	return q.submit(settings, start, moves, nil, req.Depth, req.TimeoutMs, review)
+}
+
+// submit queues template, filled in with the game's size and the new id.
+// elapsed holds the think time of each move when known.
+func (q *ReviewQueue) submit(settings GameSettings, start GameState, moves []Move, elapsed []float64, depth, timeoutMs int, template Review) (Review, error) {
+	if timeoutMs <= 0 {
+		timeoutMs = reviewDefaultTimeoutMs
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	review := &template
+	review.ID = q.nextID + 1
+	review.Status = "queued"
+	review.BoardSize = settings.BoardSize
+	review.TotalMoves = len(moves)
+	review.Moves = []ReviewMove{}
+	review.Critical = []CriticalMoment{}
+	review.CreatedAtMs = time.Now().UnixMilli()
+	job := reviewJob{review: review, settings: settings, start: start.Clone(), moves: append([]Move(nil), moves...), elapsed: elapsed, depth: depth, timeoutMs: timeoutMs}
+	select {
+	case q.pending <- job:
+	default:
+		return Review{}, fmt.Errorf("review queue is full")
+	}
+	q.nextID++
+	q.reviews = append(q.reviews, review)
+	if len(q.reviews) > reviewKeep {
+		q.reviews = append([]*Review(nil), q.reviews[len(q.reviews)-reviewKeep:]...)
+	}
+	return q.copyLocked(review), nil
+}
+
+func (q *ReviewQueue) Get(id uint64) (Review, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, review := range q.reviews {
+		if review.ID == id {
+			return q.copyLocked(review), true
+		}
+	}
+	return Review{}, false
+}
+
+// LatestForGame returns the newest finished review of the main game id.
+func (q *ReviewQueue) LatestForGame(id uint64) (Review, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := len(q.reviews) - 1; i >= 0; i-- {
+		review := q.reviews[i]
+		if review.GameID == id && review.MatchID == "" && review.Status == "done" {
+			return q.copyLocked(review), true
+		}
+	}
+	return Review{}, false
+}
+
+// List returns the kept reviews, newest first, without their moves and
+// critical moments.
+func (q *ReviewQueue) List() []Review {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	reviews := make([]Review, 0, len(q.reviews))
+	for i := len(q.reviews) - 1; i >= 0; i-- {
+		review := *q.reviews[i]
+		review.Moves = nil
+		review.Critical = nil
+		reviews = append(reviews, review)
+	}
+	return reviews
+}
+
+func (q *ReviewQueue) copyLocked(review *Review) Review {
+	copied := *review
+	copied.Moves = append([]ReviewMove{}, review.Moves...)
+	copied.Critical = make([]CriticalMoment, len(review.Critical))
+	for i, moment := range review.Critical {
+		moment.Reasons = append([]string(nil), moment.Reasons...)
+		copied.Critical[i] = moment
+	}
+	return copied
+}
+
+// Run reviews queued games until ctx is done.
+func (q *ReviewQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.pending:
+			q.run(ctx, job)
+		}
+	}
+}
+
+func (q *ReviewQueue) run(ctx context.Context, job reviewJob) {
+	q.update(job.review, func(review *Review) { review.Status = "running" })
+	rules := NewRules(job.settings)
+	state := job.start
+	state.Status = StatusRunning
+	previous := -1.0
+	for ply, move := range job.moves {
+		if ctx.Err() != nil {
+			q.update(job.review, func(review *Review) { review.Status, review.Error = "failed", "cancelled" })
+			return
+		}
+		graded := gradeMove(state, rules, move, job.depth, job.timeoutMs)
+		graded.Ply = ply + 1
+		if ply < len(job.elapsed) {
+			graded.ElapsedMs = job.elapsed[ply]
+		}
+		moment, critical := criticalMoment(graded, &previous)
+		q.update(job.review, func(review *Review) {
+			review.Moves = append(review.Moves, graded)
+			if critical {
+				review.Critical = append(review.Critical, moment)
+			}
+			side := &review.Black
+			if graded.Player == 2 {
+				side = &review.White
+			}
+			side.add(graded)
+		})
+		if !applyMove(&state, rules, move, state.ToMove) {
+			q.update(job.review, func(review *Review) { review.Status, review.Error = "failed", fmt.Sprintf("move %d illegal", ply+1) })
+			return
+		}
+	}
+	var finished Review
+	var handler func(Review)
+	q.update(job.review, func(review *Review) {
+		review.Status = "done"
+		review.FinishedAtMs = time.Now().UnixMilli()
+		finished = q.copyLocked(review)
+		handler = q.finished
+	})
+	UserProfiles.RecordReview(finished)
+	if handler != nil {
+		handler(finished)
+	}
+}
+
+func (q *ReviewQueue) update(review *Review, change func(*Review)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(review)
+}
+
+func (s *ReviewSideSummary) add(move ReviewMove) {
+	s.AverageLoss = (s.AverageLoss*float64(s.Moves) + move.Loss) / float64(s.Moves+1)
+	s.Accuracy = (s.Accuracy*float64(s.Moves) + MoveAccuracy(move.Loss)) / float64(s.Moves+1)
+	s.Moves++
+	switch move.Class {
+	case "best":
+		s.Best++
+	case "good":
+		s.Good++
+	case "inaccuracy":
+		s.Inaccuracies++
+	case "mistake":
+		s.Mistakes++
+	case "blunder":
+		s.Blunders++
+	}
+}
+
+// criticalMoment tags graded when it is a critical moment. previous holds
+// black's win probability after the previous ply (negative before the first
+// one) and is advanced to the one after graded.
+func criticalMoment(graded ReviewMove, previous *float64) (CriticalMoment, bool) {
+	before, after := graded.WinProb, graded.PlayedWinProb
+	if graded.Player == 2 {
+		before, after = 1-before, 1-after
+	}
+	if *previous < 0 {
+		*previous = before
+	}
+	moment := CriticalMoment{
+		Ply:      graded.Ply,
+		Player:   graded.Player,
+		Move:     graded.Move,
+		BestMove: graded.BestMove,
+		Reasons:  []string{},
+		Swing:    after - *previous,
+		WinProb:  after,
+	}
+	*previous = after
+	if math.Abs(moment.Swing) >= reviewCriticalSwing {
+		moment.Reasons = append(moment.Reasons, "swing")
+	}
+	if graded.OnlyMove {
+		moment.Reasons = append(moment.Reasons, "only_move")
+	}
+	return moment, len(moment.Reasons) > 0
+}
+
+// gradeMove searches the position before move and compares the played move
+// with the engine's choice. A played move the root search did not score is
+// valued by searching the reply position instead.
+func gradeMove(state GameState, rules Rules, move Move, depth, timeoutMs int) ReviewMove {
+	size := state.Board.Size()
+	sign := scoreSign(state.ToMove)
+	graded := ReviewMove{Player: PlayerToInt(state.ToMove), Move: move, BestMove: move}
+	best, scores, stats, ok := searchPosition(state.Clone(), rules, depth, timeoutMs, 0)
+	graded.Depth = stats.CompletedDepths
+	bestScore := 0.0
+	if ok {
+		graded.BestMove = best
+		bestScore = sign * reviewScore(scoreForMove(scores, best, size))
+		graded.OnlyMove = onlyMove(scores, state, rules, best, bestScore)
+	}
+	playedScore := scoreForMove(scores, move, size)
+	if math.IsInf(playedScore, 0) || math.IsNaN(playedScore) || playedScore == illegalScore {
+		playedScore = scoreAfterMove(state, rules, move, depth, timeoutMs)
+	} else {
+		playedScore = sign * reviewScore(playedScore)
+	}
+	if !ok || playedScore > bestScore {
+		bestScore = playedScore
+	}
+	graded.Score = bestScore
+	graded.PlayedScore = playedScore
+	graded.WinProb = WinProbability(bestScore)
+	graded.PlayedWinProb = WinProbability(playedScore)
+	graded.Loss = max(0, graded.WinProb-graded.PlayedWinProb)
+	if move == graded.BestMove {
+		graded.Loss = 0
+	}
+	for _, class := range reviewClasses {
+		if graded.Loss < class.maxLoss {
+			graded.Class = class.class
+			break
+		}
+	}
+	return graded
+}
+
+// scoreAfterMove values move for its player as minus the best reply score.
+func scoreAfterMove(state GameState, rules Rules, move Move, depth, timeoutMs int) float64 {
+	mover := state.ToMove
+	next := state.Clone()
+	applyMove(&next, rules, move, mover)
+	switch {
+	case next.Status == StatusDraw:
+		return 0
+	case next.Status != StatusRunning:
+		if WinnerFromStatus(next.Status) == PlayerToInt(mover) {
+			return winScore
+		}
+		return -winScore
+	}
+	reply, scores, _, ok := searchPosition(next.Clone(), rules, depth, timeoutMs, 0)
+	if !ok {
+		return 0
+	}
+	return scoreSign(mover) * reviewScore(scoreForMove(scores, reply, next.Board.Size()))
+}
+
+// onlyMove reports whether best, worth bestScore to the side to move, keeps
+// the result while every other scored move loses at least
+// reviewCriticalSwing of win probability.
+func onlyMove(scores []float64, state GameState, rules Rules, best Move, bestScore float64) bool {
+	bestProb := WinProbability(bestScore)
+	if bestProb < reviewOnlyMoveFloor {
+		return false
+	}
+	size := state.Board.Size()
+	sign := scoreSign(state.ToMove)
+	alternatives := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			move := Move{X: x, Y: y}
+			score := scoreForMove(scores, move, size)
+			if move == best || score == illegalScore || math.IsInf(score, 0) || math.IsNaN(score) {
+				continue
+			}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); !ok {
+				continue
+			}
+			if bestProb-WinProbability(sign*score) < reviewCriticalSwing {
+				return false
+			}
+			alternatives++
+		}
+	}
+	return alternatives > 0
+}
+
+// scoreSign turns a score from black's side into one from player's side.
+func scoreSign(player PlayerColor) float64 {
+	if player == PlayerWhite {
+		return -1
+	}
+	return 1
+}
+
+func reviewScore(score float64) float64 {
+	if math.IsInf(score, 0) || math.IsNaN(score) || score == illegalScore {
+		return 0
+	}
+	return score
+}
+
+// MoveAccuracy turns the win probability a move gave up into an accuracy in
+// percent, with the curve chess sites fit on engine games: a 5% loss still
+// scores about 80, a 20% loss about 40.
+func MoveAccuracy(loss float64) float64 {
+	accuracy := 103.1668*math.Exp(-0.04354*loss*100) - 3.1669
+	return math.Max(0, math.Min(100, accuracy))
+}
+
+// GameAccuracy is the accuracy of each side over a reviewed game.
+type GameAccuracy struct {
+	ReviewID uint64  `json:"review_id"`
+	Black    float64 `json:"black"`
+	White    float64 `json:"white"`
+}
+
+// Accuracy returns the accuracy of both sides of a finished review.
+func (r Review) Accuracy() (GameAccuracy, bool) {
+	if r.Status != "done" {
+		return GameAccuracy{}, false
+	}
+	return GameAccuracy{ReviewID: r.ID, Black: r.Black.Accuracy, White: r.White.Accuracy}, true
+}
+
+// WinProbability maps a search score to the side's chance of winning.
+func WinProbability(score float64) float64 {
+	if score >= winScore/2 {
+		return 1
+	}
+	if score <= -winScore/2 {
+		return 0
+	}
+	return 1 / (1 + math.Exp(-score/reviewScoreScale))
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReviewImportValidatesMoves(t *testing.T) {
+	settings := DefaultGameSettings()
+	queue := NewReviewQueue()
+	cases := []ReviewRequest{
+		{},
+		{Moves: []Move{{X: 9, Y: 9}, {X: 9, Y: 9}}},
+		{SGF: "(;SZ[19];B[jj];B[kk])"},
+		{SGF: "(;SZ[19];B[jj])", Moves: []Move{{X: 1, Y: 1}}},
+	}
+	for i, req := range cases {
+		if _, err := queue.SubmitImport(settings, req); err == nil {
+			t.Fatalf("case %d: expected the import to be rejected", i)
+		}
+	}
+	if len(queue.List()) != 0 {
+		t.Fatalf("rejected imports should not be queued")
+	}
+}
+
+func TestReviewQueueGradesEveryMove(t *testing.T) {
+	settings := DefaultGameSettings()
+	queue := NewReviewQueue()
+	review, err := queue.SubmitImport(settings, ReviewRequest{SGF: "(;SZ[19];B[jj];W[kk];B[ji])", Depth: 1, TimeoutMs: 50})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if review.Status != "queued" || review.TotalMoves != 3 || review.Source != "import" {
+		t.Fatalf("unexpected queued review %+v", review)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		review, _ = queue.Get(review.ID)
+		if review.Status == "done" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if review.Status != "done" || len(review.Moves) != 3 {
+		t.Fatalf("expected a finished review of 3 moves, got %+v", review)
+	}
+	if review.Black.Moves != 2 || review.White.Moves != 1 {
+		t.Fatalf("unexpected side summaries black=%+v white=%+v", review.Black, review.White)
+	}
+	for _, move := range review.Moves {
+		if move.Class == "" || move.Loss < 0 || move.WinProb < 0 || move.WinProb > 1 {
+			t.Fatalf("unexpected graded move %+v", move)
+		}
+	}
+}
+
+func TestWinProbabilityIsMonotonic(t *testing.T) {
+	previous := -1.0
+	for _, score := range []float64{-winScore, -50000, -1000, 0, 1000, 50000, winScore} {
+		p := WinProbability(score)
+		if p < previous || p < 0 || p > 1 {
+			t.Fatalf("win probability not monotonic at %v: %v after %v", score, p, previous)
+		}
+		previous = p
+	}
+	if WinProbability(0) != 0.5 {
+		t.Fatalf("even score should be a coin flip")
+	}
+}
+
+func TestOnlyMoveNeedsEveryAlternativeToLose(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerWhite
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	best := Move{X: 4, Y: 4}
+	// Black-side scores: white keeps the balance only with best.
+	scores[4*9+4] = 0
+	scores[0] = 60000
+	scores[8] = 80000
+	if !onlyMove(scores, state, rules, best, 0) {
+		t.Fatalf("expected the only saving move to be detected")
+	}
+	scores[8] = 1000
+	if onlyMove(scores, state, rules, best, 0) {
+		t.Fatalf("expected a second playable move to rule out an only move")
+	}
+}
+
+func TestCriticalMomentTracksSwingsFromBlackSide(t *testing.T) {
+	previous := -1.0
+	quiet := ReviewMove{Ply: 1, Player: 1, WinProb: 0.55, PlayedWinProb: 0.5}
+	if _, critical := criticalMoment(quiet, &previous); critical || previous != 0.5 {
+		t.Fatalf("expected a small loss not to be critical, previous=%v", previous)
+	}
+	blunder := ReviewMove{Ply: 2, Player: 2, WinProb: 0.5, PlayedWinProb: 0.1}
+	moment, critical := criticalMoment(blunder, &previous)
+	if !critical || moment.Reasons[0] != "swing" || moment.WinProb != 0.9 || moment.Swing < 0.39 {
+		t.Fatalf("expected white's blunder to swing black's chances up, got %+v", moment)
+	}
+	forced := ReviewMove{Ply: 3, Player: 1, WinProb: 0.9, PlayedWinProb: 0.9, OnlyMove: true}
+	if moment, critical = criticalMoment(forced, &previous); !critical || len(moment.Reasons) != 1 || moment.Reasons[0] != "only_move" {
+		t.Fatalf("expected an only move to be critical, got %+v", moment)
+	}
+}
+
+func TestMoveAccuracyCurve(t *testing.T) {
+	if got := MoveAccuracy(0); got < 99.99 || got > 100 {
+		t.Fatalf("expected a lossless move to be 100%% accurate, got %v", got)
+	}
+	if got := MoveAccuracy(0.2); got < 39 || got > 41 {
+		t.Fatalf("expected a 20%% loss to score about 40, got %v", got)
+	}
+	if got := MoveAccuracy(1); got != 0 {
+		t.Fatalf("expected a lost game thrown away to score 0, got %v", got)
+	}
+}
+
+func TestGameControllerRecordsReviewAccuracy(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	id := controller.GameID()
+	if controller.RecordAccuracy(id+1, GameAccuracy{}) {
+		t.Fatalf("expected an unknown game to be rejected")
+	}
+	if !controller.RecordAccuracy(id, GameAccuracy{ReviewID: 3, Black: 91.5, White: 64}) {
+		t.Fatalf("expected the current game to take the accuracy")
+	}
+	record, _ := controller.GameRecord(id)
+	if record.Accuracy == nil || record.Accuracy.Black != 91.5 || record.Accuracy.ReviewID != 3 {
+		t.Fatalf("expected the accuracy on the game record, got %+v", record.Accuracy)
+	}
+}
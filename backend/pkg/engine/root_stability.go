@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// RootStability is how settled the search's best move was over iterative
+// deepening. Changes counts the depths whose best move differed from the
+// previous depth's, StableDepths how many depths in a row ended on the
+// current one. The search is Unstable when the last depth changed its
+// mind; Candidates are then the last two best moves, latest first.
+type RootStability struct {
+	Depths       int    `json:"depths"`
+	Changes      int    `json:"changes"`
+	StableDepths int    `json:"stable_depths"`
+	Unstable     bool   `json:"unstable"`
+	Candidates   []Move `json:"candidates,omitempty"`
+	Summary      string `json:"summary"`
+}
+
+// RootStabilityFromMoves describes the best moves of successive depths.
+func RootStabilityFromMoves(bests []Move) RootStability {
+	stability := RootStability{Depths: len(bests)}
+	if len(bests) == 0 {
+		return stability
+	}
+	last := bests[len(bests)-1]
+	for i := 1; i < len(bests); i++ {
+		if bests[i] != bests[i-1] {
+			stability.Changes++
+		}
+	}
+	for i := len(bests) - 1; i >= 0 && bests[i] == last; i-- {
+		stability.StableDepths++
+	}
+	if len(bests) >= 2 && stability.StableDepths == 1 {
+		previous := bests[len(bests)-2]
+		stability.Unstable = true
+		stability.Candidates = []Move{last, previous}
+		stability.Summary = fmt.Sprintf("unstable: still deciding between (%d,%d) and (%d,%d)", last.X, last.Y, previous.X, previous.Y)
+		return stability
+	}
+	stability.Summary = fmt.Sprintf("stable: (%d,%d) best for the last %d depths", last.X, last.Y, stability.StableDepths)
+	if stability.StableDepths == 1 {
+		stability.Summary = fmt.Sprintf("stable: (%d,%d) after one depth", last.X, last.Y)
+	}
+	return stability
+}
+
+// RootStability describes the best moves of the depths the search
+// completed.
+func (s *SearchStats) RootStability() RootStability {
+	return RootStabilityFromMoves(s.RootBestMoves)
+}
+
+// unstableExtension is how much longer than its budget a search may think
+// once its best move changes between depths.
+func unstableExtension(config Config) time.Duration {
+	if config.AiTimeBudgetMs <= 0 || config.AiUnstableExtendPct <= 0 {
+		return 0
+	}
+	return time.Duration(config.AiTimeBudgetMs*config.AiUnstableExtendPct/100) * time.Millisecond
+}
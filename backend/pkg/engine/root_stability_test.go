@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRootStabilityFromMoves(t *testing.T) {
+	a, b := Move{X: 9, Y: 9}, Move{X: 10, Y: 8}
+
+	unstable := RootStabilityFromMoves([]Move{a, a, b, a})
+	if !unstable.Unstable || unstable.Changes != 2 || unstable.StableDepths != 1 {
+		t.Fatalf("unexpected stability %+v", unstable)
+	}
+	if unstable.Summary != "unstable: still deciding between (9,9) and (10,8)" {
+		t.Fatalf("unexpected summary %q", unstable.Summary)
+	}
+
+	stable := RootStabilityFromMoves([]Move{b, a, a, a})
+	if stable.Unstable || stable.Changes != 1 || stable.StableDepths != 3 || len(stable.Candidates) != 0 {
+		t.Fatalf("unexpected stability %+v", stable)
+	}
+	if stable.Summary != "stable: (9,9) best for the last 3 depths" {
+		t.Fatalf("unexpected summary %q", stable.Summary)
+	}
+
+	if single := RootStabilityFromMoves([]Move{a}); single.Unstable || single.Depths != 1 {
+		t.Fatalf("expected a single depth to count as settled, got %+v", single)
+	}
+}
+
+func TestUnstableExtensionScalesWithBudget(t *testing.T) {
+	config := DefaultConfig()
+	config.AiTimeBudgetMs = 800
+	config.AiUnstableExtendPct = 50
+	if got := unstableExtension(config); got != 400*time.Millisecond {
+		t.Fatalf("expected 400ms, got %v", got)
+	}
+	config.AiUnstableExtendPct = 0
+	if got := unstableExtension(config); got != 0 {
+		t.Fatalf("expected no extension when disabled, got %v", got)
+	}
+	config.AiUnstableExtendPct = 50
+	config.AiTimeBudgetMs = 0
+	if got := unstableExtension(config); got != 0 {
+		t.Fatalf("expected no extension without a budget, got %v", got)
+	}
+}
+
+func TestScoreBoardRecordsBestMovePerDepth(t *testing.T) {
+	prev := GetConfig()
+	cfg := prev
+	cfg.AiDepth = 3
+	cfg.AiMinDepth = 1
+	cfg.AiMaxDepth = 3
+	cfg.AiEnableEvalCache = false
+	cfg.AiTimeBudgetMs = 0
+	configStore.Update(cfg)
+	defer func() {
+		configStore.Update(prev)
+		FlushGlobalCaches()
+	}()
+
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.Board.Set(4, 4, CellBlack)
+	state.Board.Set(5, 4, CellWhite)
+	state.recomputeHashes()
+
+	cache := newAISearchCache()
+	stats := &SearchStats{}
+	scores := ScoreBoard(state, rules, AIScoreSettings{
+		Depth:     3,
+		BoardSize: settings.BoardSize,
+		Player:    state.ToMove,
+		Cache:     &cache,
+		Config:    cfg,
+		Stats:     stats,
+	})
+	if len(stats.RootBestMoves) != 3 {
+		t.Fatalf("expected a best move per depth, got %v", stats.RootBestMoves)
+	}
+	last := stats.RootBestMoves[2]
+	best := last.Y*settings.BoardSize + last.X
+	for i, score := range scores {
+		if score != illegalScore && score > scores[best] {
+			t.Fatalf("cell %d scores %.0f above the recorded best %v (%.0f)", i, score, last, scores[best])
+		}
+	}
+	if stability := stats.RootStability(); stability.Depths != 3 {
+		t.Fatalf("unexpected stability %+v", stability)
+	}
+}
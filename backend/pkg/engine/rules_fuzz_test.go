@@ -0,0 +1,162 @@
+package engine
+
+import "testing"
+
+// fuzzBoardSize keeps the fuzzed boards small so captures and alignments
+// come up within a few dozen moves.
+const fuzzBoardSize = 9
+
+// fuzzMoves turns each byte of data into the choice of a legal move for the
+// side to move and plays the game on, checking the rule and hashing
+// invariants after every move. It stops when the game ends.
+func fuzzMoves(t *testing.T, data []byte, check func(before, after GameState, rules Rules, move Move)) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = fuzzBoardSize
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.recomputeHashes()
+	for _, choice := range data {
+		if state.Status != StatusRunning {
+			return
+		}
+		legal := legalMovesFor(state, rules)
+		if len(legal) == 0 {
+			return
+		}
+		move := legal[int(choice)%len(legal)]
+		before := state.Clone()
+		if !applyMove(&state, rules, move, state.ToMove) {
+			t.Fatalf("legal move %v rejected", move)
+		}
+		check(before, state, rules, move)
+	}
+}
+
+func legalMovesFor(state GameState, rules Rules) []Move {
+	size := state.Board.Size()
+	var moves []Move
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			move := Move{X: x, Y: y}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); ok {
+				moves = append(moves, move)
+			}
+		}
+	}
+	return moves
+}
+
+// alignedThrough scans every line of the board for a run of at least length
+// stones of one colour that covers move.
+func alignedThrough(board Board, move Move, length int) bool {
+	size := board.Size()
+	directions := [4][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			cell := board.At(x, y)
+			if cell == CellEmpty {
+				continue
+			}
+			for _, d := range directions {
+				px, py := x-d[0], y-d[1]
+				if px >= 0 && py >= 0 && px < size && py < size && board.At(px, py) == cell {
+					continue
+				}
+				run, covers := 0, false
+				for cx, cy := x, y; cx >= 0 && cy >= 0 && cx < size && cy < size && board.At(cx, cy) == cell; cx, cy = cx+d[0], cy+d[1] {
+					run++
+					if cx == move.X && cy == move.Y {
+						covers = true
+					}
+				}
+				if covers && run >= length {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func sameState(a, b GameState) bool {
+	if a.Hash != b.Hash || a.HashSym != b.HashSym || a.CanonHash != b.CanonHash || a.ToMove != b.ToMove || a.Status != b.Status {
+		return false
+	}
+	if a.CapturedBlack != b.CapturedBlack || a.CapturedWhite != b.CapturedWhite {
+		return false
+	}
+	size := a.Board.Size()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if a.Board.At(x, y) != b.Board.At(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func addFuzzSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{40, 41, 31, 50, 32, 60, 33, 70, 34})
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20})
+	f.Add([]byte("black to move, white to capture, and then some"))
+}
+
+func FuzzApplyUndo(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzMoves(t, data, func(before, after GameState, rules Rules, move Move) {
+			hash, sym := computeSymmetricHashes(after)
+			if after.Hash != hash || after.HashSym != sym {
+				t.Fatalf("incremental hash differs from recomputed after %v", move)
+			}
+			state := before.Clone()
+			var undo searchMoveUndo
+			if !applyMoveWithUndo(&state, rules, move, state.ToMove, &undo) {
+				t.Fatalf("applyMoveWithUndo rejected %v", move)
+			}
+			if !sameState(state, after) {
+				t.Fatalf("applyMoveWithUndo and applyMove disagree on %v", move)
+			}
+			undoMoveWithUndo(&state, undo)
+			if !sameState(state, before) {
+				t.Fatalf("undo of %v did not restore the position", move)
+			}
+		})
+	})
+}
+
+func FuzzRulesInvariants(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzMoves(t, data, func(before, after GameState, rules Rules, move Move) {
+			if after.CapturedBlack < 0 || after.CapturedWhite < 0 || after.CapturedBlack%2 != 0 || after.CapturedWhite%2 != 0 {
+				t.Fatalf("invalid capture counts %d/%d after %v", after.CapturedBlack, after.CapturedWhite, move)
+			}
+			if after.CapturedBlack < before.CapturedBlack || after.CapturedWhite < before.CapturedWhite {
+				t.Fatalf("capture counts went down after %v", move)
+			}
+			if got, want := rules.IsWin(after.Board, move), alignedThrough(after.Board, move, rules.WinLength()); got != want {
+				t.Fatalf("IsWin after %v = %v, scanner says %v", move, got, want)
+			}
+			if after.Status != StatusRunning {
+				return
+			}
+			position := StartPosition{
+				Board:         BoardToSlice(after.Board),
+				NextPlayer:    PlayerToInt(after.ToMove),
+				CapturedBlack: after.CapturedBlack,
+				CapturedWhite: after.CapturedWhite,
+			}
+			restored, err := position.State(rules.settings)
+			if err != nil {
+				t.Fatalf("position after %v does not round-trip: %v", move, err)
+			}
+			if restored.Hash != after.Hash || restored.CanonHash != after.CanonHash {
+				t.Fatalf("round-tripped position after %v hashes differently", move)
+			}
+		})
+	})
+}
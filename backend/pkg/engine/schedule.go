@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a set of weekly time windows written as
+// "[days ]HH:MM-HH:MM" entries separated by ";", e.g.
+// "mon-fri 22:00-06:00; sat,sun 00:00-24:00". A window whose end is before
+// its start runs past midnight into the next day. The empty schedule is
+// always open.
+type Schedule struct {
+	Windows []ScheduleWindow
+}
+
+type ScheduleWindow struct {
+	Days  [7]bool
+	Start int
+	End   int
+}
+
+var scheduleDayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func ParseSchedule(spec string) (Schedule, error) {
+	var schedule Schedule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		window, err := parseScheduleWindow(part)
+		if err != nil {
+			return Schedule{}, err
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return schedule, nil
+}
+
+func parseScheduleWindow(text string) (ScheduleWindow, error) {
+	var window ScheduleWindow
+	fields := strings.Fields(text)
+	var hours string
+	switch len(fields) {
+	case 1:
+		for i := range window.Days {
+			window.Days[i] = true
+		}
+		hours = fields[0]
+	case 2:
+		days, err := parseScheduleDays(fields[0])
+		if err != nil {
+			return ScheduleWindow{}, err
+		}
+		window.Days = days
+		hours = fields[1]
+	default:
+		return ScheduleWindow{}, fmt.Errorf("invalid schedule window %q", text)
+	}
+	bounds := strings.Split(hours, "-")
+	if len(bounds) != 2 {
+		return ScheduleWindow{}, fmt.Errorf("invalid schedule hours %q", hours)
+	}
+	start, err := parseScheduleClock(bounds[0])
+	if err != nil {
+		return ScheduleWindow{}, err
+	}
+	end, err := parseScheduleClock(bounds[1])
+	if err != nil {
+		return ScheduleWindow{}, err
+	}
+	if start == end || start == 24*60 {
+		return ScheduleWindow{}, fmt.Errorf("empty schedule window %q", hours)
+	}
+	window.Start = start
+	window.End = end
+	return window, nil
+}
+
+func parseScheduleDays(text string) ([7]bool, error) {
+	var days [7]bool
+	for _, item := range strings.Split(strings.ToLower(text), ",") {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return days, fmt.Errorf("invalid schedule days %q", text)
+		}
+		first, ok := scheduleDayNames[bounds[0]]
+		if !ok {
+			return days, fmt.Errorf("unknown schedule day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, ok = scheduleDayNames[bounds[1]]
+			if !ok {
+				return days, fmt.Errorf("unknown schedule day %q", bounds[1])
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseScheduleClock(text string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(text), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid schedule time %q", text)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q", text)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 || hour < 0 || hour > 24 || hour == 24 && minute != 0 {
+		return 0, fmt.Errorf("invalid schedule time %q", text)
+	}
+	return hour*60 + minute, nil
+}
+
+func (s Schedule) Always() bool {
+	return len(s.Windows) == 0
+}
+
+func (s Schedule) Active(t time.Time) bool {
+	if s.Always() {
+		return true
+	}
+	day := t.Weekday()
+	previous := (day + 6) % 7
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range s.Windows {
+		if window.Start < window.End {
+			if window.Days[day] && minute >= window.Start && minute < window.End {
+				return true
+			}
+			continue
+		}
+		if window.Days[day] && minute >= window.Start {
+			return true
+		}
+		if window.Days[previous] && minute < window.End {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns t when the schedule is open, otherwise the start of the
+// next window within the coming week.
+func (s Schedule) NextStart(t time.Time) time.Time {
+	if s.Active(t) {
+		return t
+	}
+	var next time.Time
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for offset := 0; offset <= 7; offset++ {
+		date := midnight.AddDate(0, 0, offset)
+		for _, window := range s.Windows {
+			if !window.Days[date.Weekday()] {
+				continue
+			}
+			start := time.Date(date.Year(), date.Month(), date.Day(), window.Start/60, window.Start%60, 0, 0, t.Location())
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return next
+}
+
+func backlogScheduleOpen(config Config, now time.Time) bool {
+	schedule, err := ParseSchedule(config.AiQueueSchedule)
+	if err != nil {
+		return true
+	}
+	return schedule.Active(now)
+}
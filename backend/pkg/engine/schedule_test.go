@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleOvernightWindowWrapsMidnight(t *testing.T) {
+	schedule, err := ParseSchedule("mon-fri 22:00-06:00")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	// 2026-10-16 is a Friday.
+	cases := []struct {
+		at     time.Time
+		active bool
+	}{
+		{time.Date(2026, 10, 16, 21, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 17, 5, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if got := schedule.Active(tc.at); got != tc.active {
+			t.Fatalf("Active(%s) = %v, want %v", tc.at.Format(time.RFC3339), got, tc.active)
+		}
+	}
+	next := schedule.NextStart(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("NextStart = %s, want %s", next, want)
+	}
+}
+
+func TestScheduleEmptyIsAlwaysOpenAndRejectsGarbage(t *testing.T) {
+	schedule, err := ParseSchedule("  ")
+	if err != nil || !schedule.Active(time.Now()) {
+		t.Fatalf("expected empty schedule to be open, err=%v", err)
+	}
+	for _, spec := range []string{"22:00", "noday 01:00-02:00", "10:00-10:00", "25:00-01:00"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
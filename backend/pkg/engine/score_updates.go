@@ -0,0 +1,71 @@
+package engine
+
+import "time"
+
+// ScoreUpdate is the evaluation of the live game's engine side after a
+// completed depth of its search. Score and WinProb are from black's side;
+// MateIn counts plies to a proven result, won by the side Score favours.
+type ScoreUpdate struct {
+	GameID    uint64  `json:"game_id"`
+	Ply       int     `json:"ply"`
+	Player    int     `json:"player"`
+	Depth     int     `json:"depth"`
+	BestMove  Move    `json:"best_move"`
+	Score     float64 `json:"score"`
+	WinProb   float64 `json:"win_prob"`
+	MateIn    int     `json:"mate_in,omitempty"`
+	ElapsedMs int64   `json:"elapsed_ms"`
+}
+
+// SetScorePublisher registers the sink for the score updates of the
+// engine's searches in the live game.
+func (gc *GameController) SetScorePublisher(publisher func(ScoreUpdate)) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.scorePublisher = publisher
+}
+
+// scoreSinkLocked is the publisher with the current game's id filled in.
+func (gc *GameController) scoreSinkLocked() func(ScoreUpdate) {
+	if gc.scorePublisher == nil {
+		return nil
+	}
+	publisher, gameID := gc.scorePublisher, gc.gameID
+	return func(update ScoreUpdate) {
+		update.GameID = gameID
+		publisher(update)
+	}
+}
+
+// scoreUpdateSink turns the completed depths of a search of state into
+// score updates for sink, at most one per AiScoreThrottleMs. The search
+// calls it from its own goroutine.
+func scoreUpdateSink(state GameState, ply int, config Config, sink func(ScoreUpdate)) func(Move, int, float64, []float64, RootStability) {
+	if sink == nil {
+		return nil
+	}
+	started := time.Now()
+	throttle := time.Duration(config.AiScoreThrottleMs) * time.Millisecond
+	var lastPublish time.Time
+	player := PlayerToInt(state.ToMove)
+	return func(move Move, depth int, score float64, _ []float64, _ RootStability) {
+		now := time.Now()
+		if throttle > 0 && !lastPublish.IsZero() && now.Sub(lastPublish) < throttle {
+			return
+		}
+		lastPublish = now
+		update := ScoreUpdate{
+			Ply:       ply,
+			Player:    player,
+			Depth:     depth,
+			BestMove:  Move{X: move.X, Y: move.Y},
+			Score:     score,
+			WinProb:   WinProbability(score),
+			ElapsedMs: now.Sub(started).Milliseconds(),
+		}
+		if mateIn, ok := mateDistance(score); ok {
+			update.MateIn = mateIn
+		}
+		sink(update)
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreUpdateSinkThrottlesDepths(t *testing.T) {
+	state := DefaultGameState(DefaultGameSettings())
+	state.ToMove = PlayerWhite
+	config := DefaultConfig()
+	config.AiScoreThrottleMs = 50
+	var updates []ScoreUpdate
+	sink := scoreUpdateSink(state, 4, config, func(update ScoreUpdate) { updates = append(updates, update) })
+
+	sink(Move{X: 9, Y: 9, Depth: 3}, 1, -20000, nil, RootStability{})
+	sink(Move{X: 9, Y: 9}, 2, -25000, nil, RootStability{})
+	if len(updates) != 1 {
+		t.Fatalf("expected the second depth to be throttled, got %+v", updates)
+	}
+	first := updates[0]
+	if first.Ply != 4 || first.Player != 2 || first.Depth != 1 || first.BestMove != (Move{X: 9, Y: 9}) {
+		t.Fatalf("unexpected update: %+v", first)
+	}
+	if first.WinProb >= 0.5 || first.MateIn != 0 {
+		t.Fatalf("expected white to be ahead without a mate, got %+v", first)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	sink(Move{X: 8, Y: 9}, 3, winScoreAt(PlayerWhite, 3), nil, RootStability{})
+	if len(updates) != 2 || updates[1].MateIn != 3 || updates[1].WinProb != 0 {
+		t.Fatalf("expected a mate update once the throttle passed, got %+v", updates)
+	}
+	if scoreUpdateSink(state, 4, config, nil) != nil {
+		t.Fatalf("expected no depth sink without a score sink")
+	}
+}
@@ -1,8 +1,9 @@
-package main
+package engine
 
 import (
 	"fmt"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,8 +15,23 @@ type backlogTask struct {
 	created     time.Time
 	knownDepth  int
 	targetDepth int
+	transform   int
+	kind        backlogTaskKind
 }
 
+// backlogTaskKind tells which boards may be searched while a game against
+// the engine is running, during the human's turn.
+type backlogTaskKind int
+
+const (
+	backlogRegular backlogTaskKind = iota
+	// backlogWarmup is a position of the opening tree of a game that just
+	// started, searched during the first plies.
+	backlogWarmup
+	// backlogPrefetch is a position after one of the human's likely replies.
+	backlogPrefetch
+)
+
 type searchBacklog struct {
 	mu               sync.Mutex
 	queue            []backlogTask
@@ -23,12 +39,14 @@ type searchBacklog struct {
 	priorityCounts   map[uint64]int
 	analytics        map[uint64]backlogAnalyticsEntry
 	processing       map[uint64]bool
-	analiticsHub     *AnaliticsHub
+	publish          func(AnaliticsPayload)
 	currentHash      uint64
 	currentSet       bool
 	stop             atomic.Bool
 	limitWarned      bool
 	queueEmptyLogged bool
+	processedCount   int
+	ttFullNotified   bool
 }
 
 type backlogNeedsInfo struct {
@@ -41,7 +59,7 @@ type backlogNeedsInfo struct {
 	RootTransposeEntry RootTransposeEntry
 }
 
-var searchBacklogManager = newSearchBacklog()
+var SearchBacklogManager = newSearchBacklog()
 
 func newSearchBacklog() *searchBacklog {
 	return &searchBacklog{
@@ -52,7 +70,13 @@ func newSearchBacklog() *searchBacklog {
 	}
 }
 
-func enqueueSearchBacklogTask(state GameState, rules Rules) {
+// enqueueSearchBacklogTask queues state for background analysis unless it is
+// already known deeply enough; front puts it ahead of the existing queue.
+func enqueueSearchBacklogTask(state GameState, rules Rules, front bool) {
+	enqueueBacklogTask(state, rules, front, backlogRegular)
+}
+
+func enqueueBacklogTask(state GameState, rules Rules, front bool, kind backlogTaskKind) {
 	config := GetConfig()
 	if !config.AiQueueEnabled {
 		return
@@ -73,8 +97,10 @@ func enqueueSearchBacklogTask(state GameState, rules Rules) {
 		created:     time.Now(),
 		knownDepth:  info.SolvedDepth,
 		targetDepth: info.TargetDepth,
+		transform:   canonicalSymIndex(state.HashSym),
+		kind:        kind,
 	}
-	searchBacklogManager.enqueue(task, false)
+	SearchBacklogManager.enqueue(task, front)
 }
 
 func logBacklogInfo(action string, state GameState, info backlogNeedsInfo, suffix string) {
@@ -133,7 +159,7 @@ func logBacklogInfo(action string, state GameState, info backlogNeedsInfo, suffi
 }
 
 func (b *searchBacklog) enqueue(task backlogTask, front bool) {
-	var eventPayload analiticsPayload
+	var eventPayload AnaliticsPayload
 	b.mu.Lock()
 	hash := ttKeyFor(task.state, task.state.Board.Size())
 	b.priorityCounts[hash]++
@@ -154,9 +180,19 @@ func (b *searchBacklog) enqueue(task backlogTask, front bool) {
 	if task.targetDepth > entry.TargetDepth {
 		entry.TargetDepth = task.targetDepth
 	}
+	entry.Transforms |= 1 << uint(task.transform)
 	entry.Hits = b.priorityCounts[hash]
+	entry.Frequency = positionFrequencies.Get(hash)
 	b.analytics[hash] = entry
 	if _, ok := b.present[hash]; ok {
+		if task.kind != backlogRegular {
+			for i := range b.queue {
+				if ttKeyFor(b.queue[i].state, b.queue[i].state.Board.Size()) == hash {
+					b.queue[i].kind = task.kind
+					break
+				}
+			}
+		}
 		eventPayload = b.analiticsPayloadLocked("board_hit", hash)
 		b.mu.Unlock()
 		b.publishAnaliticsEvent(eventPayload)
@@ -187,17 +223,41 @@ func (b *searchBacklog) enqueue(task backlogTask, front bool) {
 }
 
 func (b *searchBacklog) pickTaskForProcessing() (backlogTask, uint64, bool) {
+	return b.pickTask(nil)
+}
+
+// pickHumanTurnTask picks a board to search while the human thinks: the
+// positions after their likely replies first, then the opening tree when
+// opening is set. Nothing is picked while a regular board is still being
+// searched.
+func (b *searchBacklog) pickHumanTurnTask(opening bool) (backlogTask, uint64, bool) {
+	return b.pickTask(func(task backlogTask) bool {
+		return task.kind == backlogPrefetch || (opening && task.kind == backlogWarmup)
+	})
+}
+
+// pickTask picks the queued board with the best priority among those
+// allowed, or among all of them when allowed is nil.
+func (b *searchBacklog) pickTask(allowed func(backlogTask) bool) (backlogTask, uint64, bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	if len(b.queue) == 0 {
 		return backlogTask{}, 0, false
 	}
+	if allowed != nil {
+		for _, task := range b.queue {
+			if task.kind == backlogRegular && b.processing[ttKeyFor(task.state, task.state.Board.Size())] {
+				return backlogTask{}, 0, false
+			}
+		}
+	}
 	bestIdx := -1
 	var bestHash uint64
 	var bestEntry backlogAnalyticsEntry
+	var bestKind backlogTaskKind
 	for i, task := range b.queue {
 		hash := ttKeyFor(task.state, task.state.Board.Size())
-		if b.processing[hash] {
+		if b.processing[hash] || (allowed != nil && !allowed(task)) {
 			continue
 		}
 		entry, ok := b.analytics[hash]
@@ -209,12 +269,23 @@ func (b *searchBacklog) pickTaskForProcessing() (backlogTask, uint64, bool) {
 				Hits:         b.priorityCounts[hash],
 				CurrentDepth: task.knownDepth,
 				TargetDepth:  task.targetDepth,
+				Frequency:    positionFrequencies.Get(hash),
 			}
+		} else {
+			entry.Frequency = positionFrequencies.Get(hash)
+			b.analytics[hash] = entry
+		}
+		if allowed != nil && bestIdx != -1 && task.kind != bestKind {
+			if task.kind > bestKind {
+				bestIdx, bestHash, bestEntry, bestKind = i, hash, entry, task.kind
+			}
+			continue
 		}
 		if bestIdx == -1 || compareAnaliticsPriority(entry, bestEntry) < 0 {
 			bestIdx = i
 			bestHash = hash
 			bestEntry = entry
+			bestKind = task.kind
 		}
 	}
 	if bestIdx == -1 {
@@ -238,7 +309,7 @@ func countBoardStones(board Board) int {
 }
 
 func (b *searchBacklog) finishTaskProcessing(hash uint64, remove bool) {
-	var eventPayload analiticsPayload
+	var eventPayload AnaliticsPayload
 	b.mu.Lock()
 	delete(b.processing, hash)
 	entry := b.analytics[hash]
@@ -268,6 +339,122 @@ func (b *searchBacklog) finishTaskProcessing(hash uint64, remove bool) {
 	b.mu.Unlock()
 }
 
+// demotePrefetch turns the queued replies of an earlier turn into regular
+// boards, searched once the game is over.
+func (b *searchBacklog) demotePrefetch() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.queue {
+		if b.queue[i].kind == backlogPrefetch {
+			b.queue[i].kind = backlogRegular
+		}
+	}
+}
+
+func (b *searchBacklog) compact(config Config, cache *AISearchCache) []uint64 {
+	b.mu.Lock()
+	candidates := make([]backlogTask, 0, len(b.queue))
+	for _, task := range b.queue {
+		if b.processing[ttKeyFor(task.state, task.state.Board.Size())] {
+			continue
+		}
+		candidates = append(candidates, task)
+	}
+	b.mu.Unlock()
+
+	solved := make(map[uint64]bool)
+	for _, task := range candidates {
+		if b.shouldStop() {
+			break
+		}
+		info := backlogNeedsAnalysis(task.state, config, cache)
+		if info.Needs {
+			continue
+		}
+		solved[ttKeyFor(task.state, task.state.Board.Size())] = true
+	}
+	if len(solved) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	dropped := make([]uint64, 0, len(solved))
+	kept := b.queue[:0]
+	for _, task := range b.queue {
+		hash := ttKeyFor(task.state, task.state.Board.Size())
+		if !solved[hash] || b.processing[hash] {
+			kept = append(kept, task)
+			continue
+		}
+		dropped = append(dropped, hash)
+		delete(b.present, hash)
+		delete(b.priorityCounts, hash)
+		delete(b.analytics, hash)
+	}
+	b.queue = kept
+	if len(dropped) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	ids := make([]string, 0, len(dropped))
+	for _, hash := range dropped {
+		ids = append(ids, hashToBoardID(hash))
+	}
+	payload := b.analiticsPayloadLocked("board_compacted", 0)
+	payload.Dropped = ids
+	remaining := len(b.queue)
+	b.mu.Unlock()
+	fmt.Printf("[ai:queue] compaction dropped %d solved boards, %d remain in queue\n", len(dropped), remaining)
+	b.publishAnaliticsEvent(payload)
+	return dropped
+}
+
+// backlogCompactionIdlePoll is how often a disabled compaction checks
+// whether ai_queue_compact_interval_ms was set.
+const backlogCompactionIdlePoll = 5 * time.Second
+
+// compactionInterval is AiQueueCompactMs as a duration, 0 when disabled.
+func compactionInterval(config Config) time.Duration {
+	if config.AiQueueCompactMs <= 0 {
+		return 0
+	}
+	return time.Duration(config.AiQueueCompactMs) * time.Millisecond
+}
+
+// startCompaction runs the compaction pass every AiQueueCompactMs, read
+// again after each pass so a config change applies from the next one.
+func (b *searchBacklog) startCompaction() {
+	go func() {
+		for {
+			wait := compactionInterval(GetConfig())
+			if wait <= 0 {
+				wait = backlogCompactionIdlePoll
+			}
+			time.Sleep(wait)
+			config := GetConfig()
+			if compactionInterval(config) <= 0 || !config.AiQueueEnabled || b.Len() == 0 {
+				continue
+			}
+			b.compact(backlogConfig(config), SharedSearchCache())
+		}
+	}()
+}
+
+func (b *searchBacklog) startScheduleWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, ok := b.currentBoardHash(); !ok {
+				continue
+			}
+			if !backlogScheduleOpen(GetConfig(), time.Now()) {
+				b.requestStop("the schedule window closed")
+			}
+		}
+	}()
+}
+
 func (b *searchBacklog) logQueueEmptyIfNeeded() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -276,6 +463,45 @@ func (b *searchBacklog) logQueueEmptyIfNeeded() {
 	}
 	fmt.Println("[ai:queue] All boards from the queue as been analyzed")
 	b.queueEmptyLogged = true
+	if b.processedCount > 0 {
+		fields := map[string]string{"processed": strconv.Itoa(b.processedCount)}
+		b.processedCount = 0
+		go func() {
+			for key, value := range ttNotifyFields() {
+				fields[key] = value
+			}
+			notifyEvent(NotifyQueueDrained, fields)
+		}()
+	}
+}
+
+func (b *searchBacklog) noteTaskProcessed() {
+	count, capacity := ttUsage()
+	full := capacity > 0 && count >= capacity
+	b.mu.Lock()
+	b.processedCount++
+	notify := full && !b.ttFullNotified
+	b.ttFullNotified = full
+	b.mu.Unlock()
+	if notify {
+		notifyEvent(NotifyTTFull, ttNotifyFields())
+	}
+}
+
+func ttUsage() (int, int) {
+	tt := EnsureTT(SharedSearchCache(), GetConfig())
+	if tt == nil {
+		return 0, 0
+	}
+	return tt.Count(), tt.Capacity()
+}
+
+func ttNotifyFields() map[string]string {
+	count, capacity := ttUsage()
+	return map[string]string{
+		"tt_count":    strconv.Itoa(count),
+		"tt_capacity": strconv.Itoa(capacity),
+	}
 }
 
 func (b *searchBacklog) Len() int {
@@ -284,13 +510,41 @@ func (b *searchBacklog) Len() int {
 	return len(b.queue)
 }
 
-func (b *searchBacklog) SetAnaliticsHub(hub *AnaliticsHub) {
+// BacklogOverview summarises the analysis backlog for the admin API. Busy
+// counts the boards being searched right now.
+type BacklogOverview struct {
+	Enabled      bool `json:"enabled"`
+	Workers      int  `json:"workers"`
+	Busy         int  `json:"busy"`
+	Queued       int  `json:"queued"`
+	Processed    int  `json:"processed"`
+	ScheduleOpen bool `json:"schedule_open"`
+}
+
+func (b *searchBacklog) Overview() BacklogOverview {
+	config := GetConfig()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	overview := BacklogOverview{
+		Enabled:      config.AiQueueEnabled,
+		Busy:         len(b.processing),
+		Queued:       len(b.queue),
+		Processed:    b.processedCount,
+		ScheduleOpen: backlogScheduleOpen(config, time.Now()),
+	}
+	if overview.Enabled {
+		overview.Workers = backlogWorkerCount(config, runtime.NumCPU())
+	}
+	return overview
+}
+
+func (b *searchBacklog) SetAnaliticsPublisher(publish func(AnaliticsPayload)) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.analiticsHub = hub
+	b.publish = publish
 }
 
-func (b *searchBacklog) TopAnaliticsQueue(limit int) []analiticsQueueEntryDTO {
+func (b *searchBacklog) TopAnaliticsQueue(limit int) []AnaliticsQueueEntryDTO {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	return b.topAnaliticsQueueLocked(limit)
@@ -329,9 +583,9 @@ func (b *searchBacklog) markBoardDepth(hash uint64, depth int) {
 	b.publishAnaliticsEvent(payload)
 }
 
-func (b *searchBacklog) topAnaliticsQueueLocked(limit int) []analiticsQueueEntryDTO {
+func (b *searchBacklog) topAnaliticsQueueLocked(limit int) []AnaliticsQueueEntryDTO {
 	if limit <= 0 {
-		return []analiticsQueueEntryDTO{}
+		return []AnaliticsQueueEntryDTO{}
 	}
 	items := make([]backlogAnalyticsEntry, 0, len(b.analytics))
 	for hash := range b.present {
@@ -345,20 +599,20 @@ func (b *searchBacklog) topAnaliticsQueueLocked(limit int) []analiticsQueueEntry
 	if len(items) > limit {
 		items = items[:limit]
 	}
-	result := make([]analiticsQueueEntryDTO, 0, len(items))
+	result := make([]AnaliticsQueueEntryDTO, 0, len(items))
 	for _, item := range items {
 		result = append(result, analiticsEntryToDTO(item))
 	}
 	return result
 }
 
-func (b *searchBacklog) analiticsPayloadLocked(event string, hash uint64) analiticsPayload {
-	var eventEntry *analiticsQueueEventEntry
+func (b *searchBacklog) analiticsPayloadLocked(event string, hash uint64) AnaliticsPayload {
+	var eventEntry *AnaliticsQueueEventEntry
 	if analyticsEntry, ok := b.analytics[hash]; ok && analyticsEntry.Hash != 0 {
 		dto := analiticsEntryToEventEntry(analyticsEntry)
 		eventEntry = &dto
 	}
-	payload := analiticsPayload{
+	payload := AnaliticsPayload{
 		Event:        event,
 		Entry:        eventEntry,
 		TotalInQueue: len(b.present),
@@ -367,14 +621,14 @@ func (b *searchBacklog) analiticsPayloadLocked(event string, hash uint64) analit
 	return payload
 }
 
-func (b *searchBacklog) publishAnaliticsEvent(payload analiticsPayload) {
+func (b *searchBacklog) publishAnaliticsEvent(payload AnaliticsPayload) {
 	b.mu.Lock()
-	hub := b.analiticsHub
+	publish := b.publish
 	b.mu.Unlock()
-	if hub == nil {
+	if publish == nil {
 		return
 	}
-	hub.Publish(payload)
+	publish(payload)
 }
 
 func (b *searchBacklog) setCurrentBoard(hash uint64) {
@@ -401,9 +655,13 @@ func (b *searchBacklog) currentBoardHash() (uint64, bool) {
 }
 
 func (b *searchBacklog) RequestStop() {
+	b.requestStop("a new game started")
+}
+
+func (b *searchBacklog) requestStop(reason string) {
 	if b.stop.CompareAndSwap(false, true) {
 		if hash, ok := b.currentBoardHash(); ok {
-			fmt.Printf("[ai:queue] stopping board 0x%x because a new game started\n", hash)
+			fmt.Printf("[ai:queue] stopping board 0x%x because %s\n", hash, reason)
 		}
 	}
 }
@@ -416,13 +674,15 @@ func (b *searchBacklog) shouldStop() bool {
 	return b.stop.Load()
 }
 
-func startSearchBacklogWorker(controller *GameController) {
+func StartSearchBacklogWorker(controller *GameController) {
 	if !GetConfig().AiQueueEnabled {
 		return
 	}
 	workerCount := backlogWorkerCount(GetConfig(), runtime.NumCPU())
 	fmt.Printf("[ai:queue] starting workers=%d\n", workerCount)
-	searchBacklogManager.startWorkers(controller, workerCount)
+	SearchBacklogManager.startWorkers(controller, workerCount)
+	SearchBacklogManager.startCompaction()
+	SearchBacklogManager.startScheduleWatcher(time.Second)
 }
 
 func backlogWorkerCount(config Config, cpuCount int) int {
@@ -456,6 +716,13 @@ func backlogAnalyzeThreadCount(config Config, cpuCount int) int {
 	if threads > cpuCount {
 		threads = cpuCount
 	}
+	if config.AiQueueMaxProcs > 0 {
+		// Every worker gets an equal share of the backlog's CPUs.
+		share := config.AiQueueMaxProcs / backlogWorkerCount(config, cpuCount)
+		if threads > share {
+			threads = share
+		}
+	}
 	if threads < 1 {
 		threads = 1
 	}
@@ -464,6 +731,10 @@ func backlogAnalyzeThreadCount(config Config, cpuCount int) int {
 
 const backlogMinUsefulDepth = 6
 
+// backlogStaleAfter is how long a worker may search one depth of a board
+// before the admin components view reports it stuck.
+const backlogStaleAfter = 10 * time.Minute
+
 func backlogDepthRange(config Config) (int, int) {
 	target := config.AiDepth
 	if config.AiMaxDepth > 0 && config.AiMaxDepth < target {
@@ -493,7 +764,7 @@ func backlogNeedsAnalysis(state GameState, config Config, cache *AISearchCache)
 	var info backlogNeedsInfo
 	info.TargetDepth = targetDepth
 	info.Needs = true
-	tt := ensureTT(cache, config)
+	tt := EnsureTT(cache, config)
 	if tt == nil {
 		info.Needs = true
 		return info
@@ -596,38 +867,114 @@ func (b *searchBacklog) startWorkers(controller *GameController, count int) {
 	}
 }
 
-func (b *searchBacklog) worker(controller *GameController, _ int) {
+func (b *searchBacklog) worker(controller *GameController, index int) {
+	component := Components.Register(fmt.Sprintf("backlog_worker_%d", index), "backlog_worker", backlogStaleAfter, func() (int, int) { return b.Len(), 0 })
 	pausedLogged := false
+	windowClosedLogged := false
 	for {
+		component.Beat()
+		if !backlogScheduleOpen(GetConfig(), time.Now()) {
+			component.SetState("outside_schedule", false)
+			if b.Len() > 0 && !windowClosedLogged {
+				fmt.Printf("[ai:queue] outside schedule window, pausing backlog (%d queued)\n", b.Len())
+				windowClosedLogged = true
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		if windowClosedLogged {
+			fmt.Println("[ai:queue] schedule window opened, resuming backlog")
+			windowClosedLogged = false
+		}
+		humanTurn, opening := false, false
 		if controller != nil {
 			state := controller.State()
 			if state.Status == StatusRunning {
-				b.RequestStop()
-				if b.Len() > 0 && !pausedLogged {
-					fmt.Printf("[ai:queue] game running, pausing backlog (%d queued)\n", b.Len())
-					pausedLogged = true
+				humanTurn = controller.HumanThinking()
+				opening = controller.WarmupWindowOpen()
+				if !humanTurn {
+					component.SetState("paused", false)
+					b.RequestStop()
+					if b.Len() > 0 && !pausedLogged {
+						fmt.Printf("[ai:queue] game running, pausing backlog (%d queued)\n", b.Len())
+						pausedLogged = true
+					}
+					time.Sleep(150 * time.Millisecond)
+					continue
 				}
-				time.Sleep(150 * time.Millisecond)
-				continue
 			}
 		}
 		pausedLogged = false
-		task, hash, ok := b.pickTaskForProcessing()
+		var (
+			task backlogTask
+			hash uint64
+			ok   bool
+		)
+		if humanTurn {
+			task, hash, ok = b.pickHumanTurnTask(opening)
+		} else {
+			task, hash, ok = b.pickTaskForProcessing()
+		}
 		if !ok {
-			b.logQueueEmptyIfNeeded()
+			component.SetState("idle", false)
+			if !humanTurn {
+				b.logQueueEmptyIfNeeded()
+			}
 			time.Sleep(150 * time.Millisecond)
 			continue
 		}
+		component.SetState("searching", true)
 		b.setCurrentBoard(hash)
 		b.markBoardStarted(hash)
 		b.ResetStop()
-		completed := b.processTask(task)
+		var watching chan struct{}
+		if humanTurn {
+			open := controller.HumanThinking
+			if task.kind == backlogWarmup {
+				open = controller.WarmupWindowOpen
+			}
+			watching = make(chan struct{})
+			go b.watchHumanTurn(open, watching)
+		}
+		// The search itself runs on the shared pool, behind live moves,
+		// pondering and suggestions.
+		var completed bool
+		started := time.Now()
+		SearchPool.Run(searchJobBacklog, "backlog", func() {
+			completed = b.processTask(task, component)
+		})
+		if watching != nil {
+			close(watching)
+		}
 		b.finishTaskProcessing(hash, completed)
 		b.clearCurrentBoard()
+		b.noteTaskProcessed()
+		if rest := backlogRest(GetConfig(), time.Since(started)); rest > 0 {
+			component.SetState("resting", false)
+			time.Sleep(rest)
+		}
 	}
 }
 
-func (b *searchBacklog) processTask(task backlogTask) bool {
+// watchHumanTurn stops a search run during the human's turn as soon as open
+// turns false, so it never competes with the engine's own search.
+func (b *searchBacklog) watchHumanTurn(open func() bool, done <-chan struct{}) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if !open() {
+			b.requestStop("the engine is to move")
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *searchBacklog) processTask(task backlogTask, component *Component) bool {
 	config := GetConfig()
 	debugLogs := config.AiLogSearchStats
 	config.AiTimeBudgetMs = 0
@@ -678,6 +1025,12 @@ func (b *searchBacklog) processTask(task backlogTask) bool {
 		DirectDepthOnly:  true,
 		SkipQueueBacklog: true,
 	}
+	if duty := newBacklogDutyCycle(config.AiQueueDutyCycle, time.Now()); duty != nil {
+		settings.ShouldStop = func() bool {
+			duty.wait(b.shouldStop)
+			return b.shouldStop()
+		}
+	}
 	if debugLogs {
 		settings.OnNodeProgress = func(delta int64) {
 			if delta > 0 {
@@ -829,6 +1182,7 @@ func (b *searchBacklog) processTask(task backlogTask) bool {
 			break
 		}
 		completedDepth = depth
+		component.Beat()
 		if debugLogs {
 			depthElapsedMs := time.Since(depthStart).Milliseconds()
 			deltaNodes := stats.Nodes - beforeNodes
@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"testing"
@@ -59,6 +59,49 @@ func TestBacklogAnalyzeThreadCountRespectsConfiguredValue(t *testing.T) {
 	}
 }
 
+func TestBacklogAnalyzeThreadCountSharesMaxProcs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiQueueAnalyzeThreads = 6
+	cfg.AiQueueWorkers = 2
+	cfg.AiQueueMaxProcs = 5
+	if got := backlogAnalyzeThreadCount(cfg, 8); got != 2 {
+		t.Fatalf("expected each of 2 workers to get 2 of 5 procs, got %d", got)
+	}
+	cfg.AiQueueMaxProcs = 1
+	if got := backlogAnalyzeThreadCount(cfg, 8); got != 1 {
+		t.Fatalf("expected at least one thread per worker, got %d", got)
+	}
+}
+
+func TestBacklogThrottleRestAndDutyCycle(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := backlogRest(cfg, time.Second); got != 0 {
+		t.Fatalf("expected no rest by default, got %v", got)
+	}
+	cfg.AiQueueSleepRatio = 0.5
+	if got := backlogRest(cfg, time.Second); got != 500*time.Millisecond {
+		t.Fatalf("expected half the search time, got %v", got)
+	}
+	if got := backlogRest(cfg, time.Hour); got != backlogMaxRest {
+		t.Fatalf("expected the rest to be capped, got %v", got)
+	}
+
+	if newBacklogDutyCycle(1, time.Now()) != nil || newBacklogDutyCycle(0, time.Now()) != nil {
+		t.Fatalf("expected no duty cycle at full speed")
+	}
+	duty := newBacklogDutyCycle(0.5, time.Now().Add(-backlogDutyPeriod/2))
+	started := time.Now()
+	duty.wait(func() bool { return false })
+	if waited := time.Since(started); waited < backlogDutyPeriod/4 {
+		t.Fatalf("expected the search to pause for the rest of the period, waited %v", waited)
+	}
+	started = time.Now()
+	duty.wait(func() bool { return false })
+	if waited := time.Since(started); waited > backlogDutyPeriod/4 {
+		t.Fatalf("expected a new period to run at once, waited %v", waited)
+	}
+}
+
 func TestBacklogDepthRangeDefaultsToSixToTarget(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.AiMinDepth = 1
@@ -142,7 +185,7 @@ func TestBacklogNeedsAnalysisSkipsWhenExactEntryMeetsTarget(t *testing.T) {
 	state.Status = StatusRunning
 	state.recomputeHashes()
 	cache := newAISearchCache()
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -214,7 +257,7 @@ func TestSuggestionDepthTenStoresTTAndSkipsBacklogEnqueue(t *testing.T) {
 		t.Fatalf("expected completed depth 10, got %d", stats.CompletedDepths)
 	}
 
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -243,7 +286,7 @@ func TestBacklogNeedsAnalysisDoesNotSkipNonExactEntry(t *testing.T) {
 	state.Status = StatusRunning
 	state.recomputeHashes()
 	cache := newAISearchCache()
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -267,7 +310,7 @@ func TestBacklogNeedsAnalysisTracksExactSolvedDepthBelowTarget(t *testing.T) {
 	state.Status = StatusRunning
 	state.recomputeHashes()
 	cache := newAISearchCache()
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT to be initialized")
 	}
@@ -438,3 +481,75 @@ func TestPickTaskForProcessingTieBreaksByStonesThenRemainingDepthThenCreated(t *
 		t.Fatalf("expected picked task to match hash 0x%x", expectedHash)
 	}
 }
+
+func TestEnqueueMergesSymmetricVariantsIntoOneEntry(t *testing.T) {
+	b := newSearchBacklog()
+	settings := DefaultGameSettings()
+
+	original := DefaultGameState(settings)
+	original.Board.Set(3, 4, CellBlack)
+	original.Board.Set(5, 4, CellWhite)
+	original.recomputeHashes()
+
+	mirrored := DefaultGameState(settings)
+	size := settings.BoardSize
+	mirrored.Board.Set(size-1-3, 4, CellBlack)
+	mirrored.Board.Set(size-1-5, 4, CellWhite)
+	mirrored.recomputeHashes()
+
+	b.enqueue(backlogTask{state: original, created: time.Unix(1, 0), transform: canonicalSymIndex(original.HashSym)}, false)
+	b.enqueue(backlogTask{state: mirrored, created: time.Unix(2, 0), transform: canonicalSymIndex(mirrored.HashSym)}, false)
+
+	if got := b.TotalAnaliticsQueue(); got != 1 {
+		t.Fatalf("expected mirrored boards to share one queue entry, got %d", got)
+	}
+	queue := b.TopAnaliticsQueue(10)
+	if len(queue) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(queue))
+	}
+	if queue[0].Hits != 2 {
+		t.Fatalf("expected both variants to count as hits, got %d", queue[0].Hits)
+	}
+	if queue[0].Variants != 2 {
+		t.Fatalf("expected 2 recorded variants, got %d", queue[0].Variants)
+	}
+}
+
+func TestCompactDropsTasksSolvedToTargetDepth(t *testing.T) {
+	cfg := backlogConfig(DefaultConfig())
+	cfg.AiEnableRootTranspose = false
+	settings := DefaultGameSettings()
+	cache := newAISearchCache()
+	tt := EnsureTT(&cache, cfg)
+	if tt == nil {
+		t.Fatalf("expected TT to be initialized")
+	}
+	_, target := backlogDepthRange(cfg)
+
+	solved := DefaultGameState(settings)
+	solved.Status = StatusRunning
+	solved.Board.Set(9, 9, CellBlack)
+	solved.recomputeHashes()
+	pending := DefaultGameState(settings)
+	pending.Status = StatusRunning
+	pending.Board.Set(9, 9, CellBlack)
+	pending.Board.Set(10, 9, CellWhite)
+	pending.recomputeHashes()
+
+	b := newSearchBacklog()
+	b.enqueue(backlogTask{state: solved, created: time.Unix(1, 0), targetDepth: target}, false)
+	b.enqueue(backlogTask{state: pending, created: time.Unix(2, 0), targetDepth: target}, false)
+	solvedKey := ttKeyFor(solved, solved.Board.Size())
+	tt.Store(solvedKey, heuristicHashFromConfig(cfg), target, 10, TTExact, Move{X: 10, Y: 10}, TTMeta{})
+
+	dropped := b.compact(cfg, &cache)
+	if len(dropped) != 1 || dropped[0] != solvedKey {
+		t.Fatalf("expected solved board 0x%x to be dropped, got %v", solvedKey, dropped)
+	}
+	if b.Len() != 1 || b.TotalAnaliticsQueue() != 1 {
+		t.Fatalf("expected one pending board left, got queue=%d present=%d", b.Len(), b.TotalAnaliticsQueue())
+	}
+	if again := b.compact(cfg, &cache); len(again) != 0 {
+		t.Fatalf("expected nothing left to compact, got %v", again)
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+// SearchParams overrides the search knobs of the live config for one side
+// of a simulated game; zero fields keep the live value. The JSON keys are
+// the config keys, so tuned values can be sent to /api/settings as they are.
+type SearchParams struct {
+	MaxCandidatesRoot int     `json:"ai_max_candidates_root,omitempty"`
+	MaxCandidatesMid  int     `json:"ai_max_candidates_mid,omitempty"`
+	MaxCandidatesDeep int     `json:"ai_max_candidates_deep,omitempty"`
+	LmrLateMoveStart  int     `json:"ai_lmr_late_move_start,omitempty"`
+	LmrMinDepth       int     `json:"ai_lmr_min_depth,omitempty"`
+	LmrReduction      int     `json:"ai_lmr_reduction,omitempty"`
+	AspWindow         float64 `json:"ai_asp_window,omitempty"`
+	AspWindowMin      float64 `json:"ai_asp_window_min,omitempty"`
+	AspWindowMax      float64 `json:"ai_asp_window_max,omitempty"`
+	KillerBoost       int     `json:"ai_killer_boost,omitempty"`
+	HistoryBoost      int     `json:"ai_history_boost,omitempty"`
+}
+
+// Apply returns config with the non-zero fields of p set.
+func (p *SearchParams) Apply(config Config) Config {
+	if p == nil {
+		return config
+	}
+	setInt := func(dst *int, value int) {
+		if value > 0 {
+			*dst = value
+		}
+	}
+	setInt(&config.AiMaxCandidatesRoot, p.MaxCandidatesRoot)
+	setInt(&config.AiMaxCandidatesMid, p.MaxCandidatesMid)
+	setInt(&config.AiMaxCandidatesDeep, p.MaxCandidatesDeep)
+	setInt(&config.AiLmrLateMoveStart, p.LmrLateMoveStart)
+	setInt(&config.AiLmrMinDepth, p.LmrMinDepth)
+	setInt(&config.AiLmrReduction, p.LmrReduction)
+	setInt(&config.AiKillerBoost, p.KillerBoost)
+	setInt(&config.AiHistoryBoost, p.HistoryBoost)
+	if p.AspWindow > 0 {
+		config.AiAspWindow = p.AspWindow
+	}
+	if p.AspWindowMin > 0 {
+		config.AiAspWindowMin = p.AspWindowMin
+	}
+	if p.AspWindowMax > 0 {
+		config.AiAspWindowMax = p.AspWindowMax
+	}
+	return config
+}
@@ -0,0 +1,22 @@
+package engine
+
+import "testing"
+
+func TestSearchParamsApplyKeepsZeroFields(t *testing.T) {
+	base := DefaultConfig()
+	params := &SearchParams{MaxCandidatesRoot: 12, LmrReduction: 2, AspWindow: 900}
+	config := params.Apply(base)
+	if config.AiMaxCandidatesRoot != 12 || config.AiLmrReduction != 2 || config.AiAspWindow != 900 {
+		t.Fatalf("expected overrides to apply, got %+v", config)
+	}
+	if config.AiMaxCandidatesMid != base.AiMaxCandidatesMid || config.AiKillerBoost != base.AiKillerBoost {
+		t.Fatalf("expected zero fields to keep the live values")
+	}
+	var none *SearchParams
+	if none.Apply(base).AiMaxCandidatesRoot != base.AiMaxCandidatesRoot {
+		t.Fatalf("expected a nil override to keep the config")
+	}
+	if !shouldApplyLMR(Config{}, lmrMinDepth, lmrLateMoveStart, true) || shouldApplyLMR(config, lmrMinDepth, lmrLateMoveStart-1, true) {
+		t.Fatalf("expected LMR to fall back to the defaults")
+	}
+}
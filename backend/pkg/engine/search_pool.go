@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// searchJobPriority orders the searches competing for the shared workers;
+// lower values run first.
+type searchJobPriority int
+
+const (
+	searchJobLive searchJobPriority = iota
+	searchJobPonder
+	searchJobSuggestion
+	searchJobSimulation
+	searchJobBacklog
+	searchJobPriorities
+)
+
+var searchJobPriorityNames = [searchJobPriorities]string{"live", "ponder", "suggestion", "simulation", "backlog"}
+
+func (p searchJobPriority) String() string {
+	if p < 0 || p >= searchJobPriorities {
+		return "unknown"
+	}
+	return searchJobPriorityNames[p]
+}
+
+// reservesWorker reports whether jobs of priority p leave the last idle
+// worker to live moves.
+func (p searchJobPriority) reservesWorker() bool {
+	return p > searchJobLive
+}
+
+type searchJob struct {
+	priority searchJobPriority
+	owner    string
+	run      func()
+	queued   time.Time
+	started  bool
+}
+
+// SearchPoolClassStats describes the jobs of one priority.
+type SearchPoolClassStats struct {
+	Priority  string  `json:"priority"`
+	Queued    int     `json:"queued"`
+	Running   int     `json:"running"`
+	Completed int64   `json:"completed"`
+	Cancelled int64   `json:"cancelled"`
+	AvgWaitMs float64 `json:"avg_wait_ms"`
+	MaxWaitMs int64   `json:"max_wait_ms"`
+}
+
+// SearchPoolStats is a snapshot of the shared search workers.
+type SearchPoolStats struct {
+	Workers int                    `json:"workers"`
+	Busy    int                    `json:"busy"`
+	Owners  int                    `json:"owners"`
+	Classes []SearchPoolClassStats `json:"classes"`
+}
+
+// searchPool runs the engine's searches on a fixed set of workers shared by
+// every player, game, analysis, simulation and the backlog; only a game
+// stepped synchronously searches in the caller. A free worker takes the highest
+// priority job queued; within a priority it serves the owner served least
+// recently, so one busy game cannot starve the others. Other jobs than live
+// moves never take the last idle worker of a pool of two or more, which
+// stays free for the next live move.
+type searchPool struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	target    int
+	workers   int
+	busy      int
+	queues    [searchJobPriorities][]*searchJob
+	running   [searchJobPriorities]int
+	completed [searchJobPriorities]int64
+	cancelled [searchJobPriorities]int64
+	waitTotal [searchJobPriorities]time.Duration
+	waitMax   [searchJobPriorities]time.Duration
+	served    map[string]uint64
+	serial    uint64
+	// size is how many workers the pool should run, read on each Submit.
+	size func() int
+}
+
+// searchPoolOwnersLimit bounds how many owners the pool remembers for
+// fairness; past it the history starts over.
+const searchPoolOwnersLimit = 4096
+
+func newSearchPool(size func() int) *searchPool {
+	pool := &searchPool{served: make(map[string]uint64), size: size}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool
+}
+
+// SearchPool is the process-wide pool of search workers.
+var SearchPool = newSearchPool(func() int { return searchPoolWorkerCount(GetConfig(), runtime.NumCPU()) })
+
+// searchPoolWorkerCount is AiSearchWorkers, or the CPU count but at least
+// four when it is unset.
+func searchPoolWorkerCount(config Config, cpuCount int) int {
+	if config.AiSearchWorkers > 0 {
+		return config.AiSearchWorkers
+	}
+	if cpuCount < 4 {
+		return 4
+	}
+	return cpuCount
+}
+
+// Submit queues run as a job of the given priority for owner and returns
+// the job, which Cancel can take back while it waits.
+func (p *searchPool) Submit(priority searchJobPriority, owner string, run func()) *searchJob {
+	job := &searchJob{priority: priority, owner: owner, run: run, queued: time.Now()}
+	p.mu.Lock()
+	p.resizeLocked(p.size())
+	p.queues[priority] = append(p.queues[priority], job)
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	return job
+}
+
+// Run queues run and waits for it to finish.
+func (p *searchPool) Run(priority searchJobPriority, owner string, run func()) {
+	done := make(chan struct{})
+	p.Submit(priority, owner, func() {
+		defer close(done)
+		run()
+	})
+	<-done
+}
+
+// Cancel removes job from its queue and reports whether it had not started;
+// a job already running is left to finish.
+func (p *searchPool) Cancel(job *searchJob) bool {
+	if job == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if job.started {
+		return false
+	}
+	queue := p.queues[job.priority]
+	for i, queued := range queue {
+		if queued == job {
+			p.queues[job.priority] = append(queue[:i:i], queue[i+1:]...)
+			p.cancelled[job.priority]++
+			return true
+		}
+	}
+	return false
+}
+
+// Resize sets how many workers the pool runs. Extra workers leave once
+// their current job is done.
+func (p *searchPool) Resize(workers int) {
+	p.mu.Lock()
+	p.resizeLocked(workers)
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+func (p *searchPool) resizeLocked(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	p.target = workers
+	for p.workers < p.target {
+		p.workers++
+		go p.work()
+	}
+}
+
+func (p *searchPool) work() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if p.workers > p.target {
+			p.workers--
+			return
+		}
+		job := p.nextLocked()
+		if job == nil {
+			p.cond.Wait()
+			continue
+		}
+		waited := time.Since(job.queued)
+		p.busy++
+		p.running[job.priority]++
+		p.waitTotal[job.priority] += waited
+		if waited > p.waitMax[job.priority] {
+			p.waitMax[job.priority] = waited
+		}
+		p.mu.Unlock()
+		job.run()
+		p.mu.Lock()
+		p.busy--
+		p.running[job.priority]--
+		p.completed[job.priority]++
+		p.cond.Broadcast()
+	}
+}
+
+// nextLocked takes the job to run next off its queue, or returns nil when
+// nothing may run on this worker.
+func (p *searchPool) nextLocked() *searchJob {
+	idle := p.target - p.busy
+	for priority := searchJobPriority(0); priority < searchJobPriorities; priority++ {
+		queue := p.queues[priority]
+		if len(queue) == 0 {
+			continue
+		}
+		if priority.reservesWorker() && p.target > 1 && idle <= 1 {
+			return nil
+		}
+		pick := 0
+		for i := 1; i < len(queue); i++ {
+			if p.served[queue[i].owner] < p.served[queue[pick].owner] {
+				pick = i
+			}
+		}
+		job := queue[pick]
+		p.queues[priority] = append(queue[:pick:pick], queue[pick+1:]...)
+		job.started = true
+		if len(p.served) >= searchPoolOwnersLimit {
+			p.served = make(map[string]uint64)
+		}
+		p.serial++
+		p.served[job.owner] = p.serial
+		return job
+	}
+	return nil
+}
+
+// Running reports how many jobs of priority are running.
+func (p *searchPool) Running(priority searchJobPriority) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running[priority]
+}
+
+// Stats returns a snapshot of the pool.
+func (p *searchPool) Stats() SearchPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := SearchPoolStats{
+		Workers: p.target,
+		Busy:    p.busy,
+		Owners:  len(p.served),
+		Classes: make([]SearchPoolClassStats, 0, searchJobPriorities),
+	}
+	for priority := searchJobPriority(0); priority < searchJobPriorities; priority++ {
+		class := SearchPoolClassStats{
+			Priority:  priority.String(),
+			Queued:    len(p.queues[priority]),
+			Running:   p.running[priority],
+			Completed: p.completed[priority],
+			Cancelled: p.cancelled[priority],
+			MaxWaitMs: p.waitMax[priority].Milliseconds(),
+		}
+		if started := p.completed[priority] + int64(p.running[priority]); started > 0 {
+			class.AvgWaitMs = float64(p.waitTotal[priority].Microseconds()) / float64(started) / 1000
+		}
+		stats.Classes = append(stats.Classes, class)
+	}
+	return stats
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockPool fills every worker of pool with a job that waits for release.
+func blockPool(t *testing.T, pool *searchPool, workers int) chan struct{} {
+	t.Helper()
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(workers)
+	for i := 0; i < workers; i++ {
+		pool.Submit(searchJobLive, "blocker", func() {
+			started.Done()
+			<-release
+		})
+	}
+	started.Wait()
+	return release
+}
+
+func TestSearchPoolRunsHigherPrioritiesFirst(t *testing.T) {
+	pool := newSearchPool(func() int { return 1 })
+	release := blockPool(t, pool, 1)
+
+	var mu sync.Mutex
+	var order []string
+	var done sync.WaitGroup
+	for _, priority := range []searchJobPriority{searchJobBacklog, searchJobSuggestion, searchJobPonder, searchJobLive} {
+		priority := priority
+		done.Add(1)
+		pool.Submit(priority, "owner", func() {
+			defer done.Done()
+			mu.Lock()
+			order = append(order, priority.String())
+			mu.Unlock()
+		})
+	}
+	close(release)
+	done.Wait()
+
+	want := []string{"live", "ponder", "suggestion", "backlog"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("run order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSearchPoolTakesTurnsBetweenOwners(t *testing.T) {
+	pool := newSearchPool(func() int { return 1 })
+	release := blockPool(t, pool, 1)
+
+	var mu sync.Mutex
+	var order []string
+	var done sync.WaitGroup
+	submit := func(owner string) {
+		done.Add(1)
+		pool.Submit(searchJobLive, owner, func() {
+			defer done.Done()
+			mu.Lock()
+			order = append(order, owner)
+			mu.Unlock()
+		})
+	}
+	submit("a")
+	submit("a")
+	submit("a")
+	submit("b")
+	close(release)
+	done.Wait()
+
+	if order[0] != "a" || order[1] != "b" {
+		t.Fatalf("run order = %v, want b served second", order)
+	}
+}
+
+func TestSearchPoolKeepsLastWorkerForLiveMoves(t *testing.T) {
+	pool := newSearchPool(func() int { return 2 })
+	release := blockPool(t, pool, 1)
+	defer close(release)
+
+	backlogRan := make(chan struct{})
+	pool.Submit(searchJobBacklog, "backlog", func() { close(backlogRan) })
+	liveRan := make(chan struct{})
+	pool.Submit(searchJobLive, "ai", func() { close(liveRan) })
+
+	select {
+	case <-liveRan:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("live move did not get the idle worker")
+	}
+	select {
+	case <-backlogRan:
+		t.Fatalf("backlog job took the last idle worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+	stats := pool.Stats()
+	if stats.Workers != 2 || stats.Busy != 1 {
+		t.Fatalf("stats = %+v, want 2 workers, 1 busy", stats)
+	}
+	if class := stats.Classes[searchJobBacklog]; class.Queued != 1 {
+		t.Fatalf("backlog class = %+v, want 1 queued", class)
+	}
+}
+
+func TestSearchPoolCancelDropsQueuedJob(t *testing.T) {
+	pool := newSearchPool(func() int { return 1 })
+	release := blockPool(t, pool, 1)
+
+	ran := make(chan struct{})
+	job := pool.Submit(searchJobPonder, "ai", func() { close(ran) })
+	if !pool.Cancel(job) {
+		t.Fatalf("Cancel of a queued job = false")
+	}
+	close(release)
+	pool.Run(searchJobLive, "ai", func() {})
+	select {
+	case <-ran:
+		t.Fatalf("cancelled job ran")
+	default:
+	}
+	if pool.Cancel(job) {
+		t.Fatalf("second Cancel = true")
+	}
+	if class := pool.Stats().Classes[searchJobPonder]; class.Cancelled != 1 || class.Completed != 0 {
+		t.Fatalf("ponder class = %+v, want 1 cancelled", class)
+	}
+}
+
+func TestAnalysisAndSimulationRunOnTheSharedPool(t *testing.T) {
+	// A job counts as completed only after Run has returned, so count the
+	// ones started.
+	started := func(priority searchJobPriority) int64 {
+		class := SearchPool.Stats().Classes[priority]
+		return class.Completed + int64(class.Running)
+	}
+	suggestions, simulations := started(searchJobSuggestion), started(searchJobSimulation)
+	settings := DefaultGameSettings()
+	if _, err := Analyze(settings, AnalyzeRequest{Moves: []Move{{X: 9, Y: 9}}, Depth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Simulate(context.Background(), settings, SimulateRequest{Opening: []Move{{X: 9, Y: 9}}, Depth: 1, MaxMoves: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if started(searchJobSuggestion) <= suggestions || started(searchJobSimulation) <= simulations {
+		t.Fatalf("expected the analysis as a suggestion job and the simulation as a simulation job, got %+v", SearchPool.Stats().Classes)
+	}
+	if searchJobSimulation <= searchJobSuggestion || searchJobSimulation >= searchJobBacklog {
+		t.Fatalf("expected simulations between suggestions and the backlog")
+	}
+
+	// A running simulation leaves suggestions alone.
+	release, running := make(chan struct{}), make(chan struct{})
+	SearchPool.Submit(searchJobSimulation, "simulate", func() {
+		close(running)
+		<-release
+	})
+	<-running
+	config := DefaultConfig()
+	config.AiSuggestYield = true
+	suspended := suggestionsSuspended(config)
+	close(release)
+	if suspended && SearchPool.Running(searchJobLive) == 0 && SearchPool.Running(searchJobBacklog) == 0 {
+		t.Fatalf("expected a simulation not to suspend suggestions")
+	}
+}
@@ -0,0 +1,46 @@
+package engine
+
+import "runtime"
+
+// SearchResources is what the process used while a search ran, from runtime
+// statistics sampled when it started and when it ended. The figures are
+// process wide, so searches running at the same time show up in each other.
+type SearchResources struct {
+	AllocBytes      uint64  `json:"alloc_bytes"`
+	Mallocs         uint64  `json:"mallocs"`
+	PeakHeapBytes   uint64  `json:"peak_heap_bytes"`
+	GoroutinesStart int     `json:"goroutines_start"`
+	GoroutinesEnd   int     `json:"goroutines_end"`
+	GCCycles        uint32  `json:"gc_cycles"`
+	GCPauseMs       float64 `json:"gc_pause_ms"`
+}
+
+type resourceSample struct {
+	mem        runtime.MemStats
+	goroutines int
+}
+
+func sampleResources() resourceSample {
+	var sample resourceSample
+	runtime.ReadMemStats(&sample.mem)
+	sample.goroutines = runtime.NumGoroutine()
+	return sample
+}
+
+// since reports the resources used between start and now.
+func (start resourceSample) since() *SearchResources {
+	end := sampleResources()
+	peak := start.mem.HeapAlloc
+	if end.mem.HeapAlloc > peak {
+		peak = end.mem.HeapAlloc
+	}
+	return &SearchResources{
+		AllocBytes:      end.mem.TotalAlloc - start.mem.TotalAlloc,
+		Mallocs:         end.mem.Mallocs - start.mem.Mallocs,
+		PeakHeapBytes:   peak,
+		GoroutinesStart: start.goroutines,
+		GoroutinesEnd:   end.goroutines,
+		GCCycles:        end.mem.NumGC - start.mem.NumGC,
+		GCPauseMs:       float64(end.mem.PauseTotalNs-start.mem.PauseTotalNs) / 1e6,
+	}
+}
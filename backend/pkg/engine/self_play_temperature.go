@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// selfPlayTempTopK is how many of the best root moves the temperature
+	// draws from, and selfPlayTempMaxDrop how much worse than the best one,
+	// in the side to move's chance of winning, a move may be.
+	selfPlayTempTopK    = 3
+	selfPlayTempMaxDrop = 0.15
+	// selfPlayTempMin is the temperature below which the best move is
+	// always played.
+	selfPlayTempMin = 0.005
+)
+
+// selfPlayTemperature is Config.AiSelfPlayTemp at the ply-th move of a
+// game, halved every AiSelfPlayHalfLife plies.
+func selfPlayTemperature(config Config, ply int) float64 {
+	temperature := config.AiSelfPlayTemp
+	if temperature <= 0 {
+		return 0
+	}
+	if config.AiSelfPlayHalfLife > 0 {
+		temperature *= math.Pow(0.5, float64(ply)/float64(config.AiSelfPlayHalfLife))
+	}
+	return temperature
+}
+
+// maybeSelectTemperatureMove replaces currentBest in an AI-vs-AI game by
+// one of the next best root moves, drawn with a weight of
+// exp(-drop/temperature), where drop is how much of the side to move's
+// chance of winning the move gives up against the best one. It varies the
+// positions self-play games reach; a forced win is always played.
+func maybeSelectTemperatureMove(scores []float64, state GameState, rules Rules, settings AIScoreSettings, currentBest Move, rng *rand.Rand) (Move, bool) {
+	size := settings.BoardSize
+	temperature := selfPlayTemperature(settings.Config, countStones(state.Board)+state.CapturedBlack+state.CapturedWhite)
+	if temperature < selfPlayTempMin || !currentBest.IsValid(size) || len(scores) < size*size {
+		return Move{}, false
+	}
+	sign := scoreSign(state.ToMove)
+	bestScore := sign * scores[currentBest.Y*size+currentBest.X]
+	if math.IsInf(bestScore, 0) || math.IsNaN(bestScore) {
+		return Move{}, false
+	}
+	if _, ok := mateDistance(bestScore); ok && bestScore > 0 {
+		return Move{}, false
+	}
+	bestProb := WinProbability(bestScore)
+	type temperatureCandidate struct {
+		move Move
+		prob float64
+	}
+	candidates := []temperatureCandidate{}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			raw := scores[y*size+x]
+			if raw == illegalScore || math.IsInf(raw, 0) || math.IsNaN(raw) {
+				continue
+			}
+			prob := WinProbability(sign * raw)
+			if bestProb-prob > selfPlayTempMaxDrop {
+				continue
+			}
+			move := Move{X: x, Y: y}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); !ok {
+				continue
+			}
+			candidates = append(candidates, temperatureCandidate{move: move, prob: prob})
+		}
+	}
+	if len(candidates) < 2 {
+		return Move{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].prob > candidates[j].prob })
+	if len(candidates) > selfPlayTempTopK {
+		candidates = candidates[:selfPlayTempTopK]
+	}
+	top := candidates[0].prob
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, cand := range candidates {
+		weights[i] = math.Exp((cand.prob - top) / temperature)
+		total += weights[i]
+	}
+	pick := rng.Float64() * total
+	for i, cand := range candidates {
+		pick -= weights[i]
+		if pick <= 0 {
+			return cand.move, cand.move != currentBest
+		}
+	}
+	return Move{}, false
+}
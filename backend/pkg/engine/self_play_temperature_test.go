@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTemperatureMoveExploresCloseMoves(t *testing.T) {
+	gameSettings := DefaultGameSettings()
+	gameSettings.BoardSize = 9
+	rules := NewRules(gameSettings)
+	state := DefaultGameState(gameSettings)
+	state.Status = StatusRunning
+	state.Board.Set(4, 4, CellBlack)
+	state.Board.Set(4, 5, CellWhite)
+
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	// Black maximises: (3,3) is best, (5,5) and (6,6) close, (1,1) fourth
+	// and (2,2) far worse.
+	scores[3*9+3] = 2000
+	scores[5*9+5] = 1000
+	scores[6*9+6] = 0
+	scores[1*9+1] = -500
+	scores[2*9+2] = -20000
+	config := DefaultConfig()
+	config.AiSelfPlayTemp = 0.05
+	settings := AIScoreSettings{BoardSize: 9, Config: config}
+	best := Move{X: 3, Y: 3}
+	rng := rand.New(rand.NewSource(1))
+
+	seen := map[Move]bool{}
+	for i := 0; i < 200; i++ {
+		move, changed := maybeSelectTemperatureMove(scores, state, rules, settings, best, rng)
+		if !changed {
+			move = best
+		}
+		seen[move] = true
+	}
+	if !seen[best] || !seen[Move{X: 5, Y: 5}] || !seen[Move{X: 6, Y: 6}] || seen[Move{X: 1, Y: 1}] || seen[Move{X: 2, Y: 2}] {
+		t.Fatalf("expected the 3 best moves to be mixed, got %v", seen)
+	}
+
+	state.CapturedBlack = 400
+	if _, changed := maybeSelectTemperatureMove(scores, state, rules, settings, best, rng); changed {
+		t.Fatalf("expected the temperature to have decayed by ply 402")
+	}
+	state.CapturedBlack = 0
+	scores[3*9+3] = winScoreAt(PlayerBlack, 3)
+	for i := 0; i < 50; i++ {
+		if _, changed := maybeSelectTemperatureMove(scores, state, rules, settings, best, rng); changed {
+			t.Fatalf("expected a forced win to be played")
+		}
+	}
+}
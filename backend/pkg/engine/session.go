@@ -0,0 +1,569 @@
+package engine
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxAnalysisSessions     = 200
+	maxAnalysisSessionNodes = 5000
+	maxSessionCommentLength = 2000
+)
+
+var ErrUnknownSessionNode = errors.New("unknown node")
+
+// SessionEval is the engine's view of a node: from a search run on the node
+// (Source "search") or from the TT entry of its position (Source "tt").
+type SessionEval struct {
+	BestMove *Move   `json:"best_move,omitempty"`
+	Score    float64 `json:"score"`
+	Depth    int     `json:"depth"`
+	Nodes    int64   `json:"nodes,omitempty"`
+	Source   string  `json:"source"`
+}
+
+// SessionNode is a position in a session's variation tree, reached by
+// playing Move from Parent. The root (id 0) is the start position and has
+// no move. The first child continues the main line.
+type SessionNode struct {
+	ID       int          `json:"id"`
+	Parent   int          `json:"parent"`
+	Move     *Move        `json:"move,omitempty"`
+	Children []int        `json:"children"`
+	Comment  string       `json:"comment,omitempty"`
+	Eval     *SessionEval `json:"eval,omitempty"`
+}
+
+// AnalysisSession is a server-side study board: a game or position with its
+// variations, stepped through with Current.
+type AnalysisSession struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	GameID      uint64         `json:"game_id,omitempty"`
+	Settings    GameSettings   `json:"settings"`
+	Start       *StartPosition `json:"start_position,omitempty"`
+	Nodes       []SessionNode  `json:"nodes"`
+	Current     int            `json:"current"`
+	NextNodeID  int            `json:"-"`
+	CreatedAtMs int64          `json:"created_at_ms"`
+	UpdatedAtMs int64          `json:"updated_at_ms"`
+}
+
+// SessionNodeView is a node with its position.
+type SessionNodeView struct {
+	SessionID     string       `json:"session_id"`
+	Node          SessionNode  `json:"node"`
+	Moves         []Move       `json:"moves"`
+	Board         [][]int      `json:"board"`
+	NextPlayer    int          `json:"next_player"`
+	Status        string       `json:"status"`
+	Winner        int          `json:"winner"`
+	CapturedBlack int          `json:"captured_black"`
+	CapturedWhite int          `json:"captured_white"`
+	Eval          *SessionEval `json:"eval,omitempty"`
+}
+
+type SessionStore struct {
+	mu        sync.Mutex
+	persistMu sync.Mutex
+	sessions  map[string]*AnalysisSession
+}
+
+type sessionSnapshot struct {
+	Sessions []AnalysisSession
+}
+
+var AnalysisSessions = NewSessionStore()
+
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]*AnalysisSession)}
+}
+
+// Create starts a session on start (the empty board when nil) with moves
+// as its main line.
+func (s *SessionStore) Create(name string, settings GameSettings, start *StartPosition, moves []Move, gameID uint64) (AnalysisSession, error) {
+	name = strings.TrimSpace(name)
+	if len(name) > 64 {
+		return AnalysisSession{}, fieldErrorf("name", "name must be at most 64 characters")
+	}
+	if len(moves) >= maxAnalysisSessionNodes {
+		return AnalysisSession{}, fieldErrorf("moves", "at most %d moves", maxAnalysisSessionNodes-1)
+	}
+	state, err := renderStartState(settings, start)
+	if err != nil {
+		return AnalysisSession{}, err
+	}
+	if _, err := replayFrom(state, NewRules(settings), moves); err != nil {
+		return AnalysisSession{}, err
+	}
+	settings.BlackType, settings.WhiteType = PlayerHuman, PlayerHuman
+	now := time.Now().UnixMilli()
+	session := &AnalysisSession{
+		ID:          newMatchID(),
+		Name:        name,
+		GameID:      gameID,
+		Settings:    settings,
+		Start:       start,
+		Nodes:       []SessionNode{{ID: 0, Parent: -1, Children: []int{}}},
+		NextNodeID:  1,
+		CreatedAtMs: now,
+		UpdatedAtMs: now,
+	}
+	parent := 0
+	for _, move := range moves {
+		parent = session.addNode(parent, move)
+	}
+	session.Current = parent
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= maxAnalysisSessions {
+		s.evictOldestLocked()
+	}
+	s.sessions[session.ID] = session
+	return copyAnalysisSession(session), nil
+}
+
+// evictOldestLocked drops the session that was updated last the longest
+// time ago.
+func (s *SessionStore) evictOldestLocked() {
+	var oldest *AnalysisSession
+	for _, session := range s.sessions {
+		if oldest == nil || session.UpdatedAtMs < oldest.UpdatedAtMs {
+			oldest = session
+		}
+	}
+	if oldest != nil {
+		delete(s.sessions, oldest.ID)
+	}
+}
+
+func (s *SessionStore) Get(id string) (AnalysisSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return AnalysisSession{}, false
+	}
+	return copyAnalysisSession(session), true
+}
+
+// List returns the sessions, most recently updated first.
+func (s *SessionStore) List() []AnalysisSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]AnalysisSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, copyAnalysisSession(session))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].UpdatedAtMs != sessions[j].UpdatedAtMs {
+			return sessions[i].UpdatedAtMs > sessions[j].UpdatedAtMs
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+func (s *SessionStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[id]; !ok {
+		return false
+	}
+	delete(s.sessions, id)
+	return true
+}
+
+// Play plays move from node parent and makes the resulting node current.
+// Playing a move that already has a node steps into it instead of adding a
+// duplicate variation.
+func (s *SessionStore) Play(id string, parent int, move Move) (AnalysisSession, bool, error) {
+	return s.update(id, func(session *AnalysisSession) error {
+		node := session.node(parent)
+		if node == nil {
+			return ErrUnknownSessionNode
+		}
+		for _, child := range node.Children {
+			if existing := session.node(child); existing.Move.Equals(move) {
+				session.Current = child
+				return nil
+			}
+		}
+		if len(session.Nodes) >= maxAnalysisSessionNodes {
+			return fmt.Errorf("at most %d nodes per session", maxAnalysisSessionNodes)
+		}
+		state, rules, err := session.stateAt(parent)
+		if err != nil {
+			return err
+		}
+		if state.Status != StatusRunning {
+			return ErrGameOver
+		}
+		if !move.IsValid(state.Board.Size()) {
+			return fmt.Errorf("%w: out of bounds", ErrIllegalMove)
+		}
+		if legal, reason := rules.IsLegal(state, move, state.ToMove); !legal {
+			return fmt.Errorf("%w: %s", ErrIllegalMove, reason)
+		}
+		session.Current = session.addNode(parent, move)
+		return nil
+	})
+}
+
+// Select makes node current.
+func (s *SessionStore) Select(id string, node int) (AnalysisSession, bool, error) {
+	return s.update(id, func(session *AnalysisSession) error {
+		if session.node(node) == nil {
+			return ErrUnknownSessionNode
+		}
+		session.Current = node
+		return nil
+	})
+}
+
+// Comment sets the comment of node; an empty comment removes it.
+func (s *SessionStore) Comment(id string, node int, comment string) (AnalysisSession, bool, error) {
+	return s.update(id, func(session *AnalysisSession) error {
+		target := session.node(node)
+		if target == nil {
+			return ErrUnknownSessionNode
+		}
+		if len(comment) > maxSessionCommentLength {
+			return fieldErrorf("comment", "comment must be at most %d characters", maxSessionCommentLength)
+		}
+		target.Comment = strings.TrimSpace(comment)
+		return nil
+	})
+}
+
+// Promote makes node the first child of its parent, so it continues the
+// main line.
+func (s *SessionStore) Promote(id string, node int) (AnalysisSession, bool, error) {
+	return s.update(id, func(session *AnalysisSession) error {
+		target := session.node(node)
+		if target == nil || target.Parent < 0 {
+			return ErrUnknownSessionNode
+		}
+		parent := session.node(target.Parent)
+		children := []int{node}
+		for _, child := range parent.Children {
+			if child != node {
+				children = append(children, child)
+			}
+		}
+		parent.Children = children
+		return nil
+	})
+}
+
+// DeleteNode removes node and its subtree. The root cannot be removed; a
+// current node inside the subtree moves up to node's parent.
+func (s *SessionStore) DeleteNode(id string, node int) (AnalysisSession, bool, error) {
+	return s.update(id, func(session *AnalysisSession) error {
+		target := session.node(node)
+		if target == nil || target.Parent < 0 {
+			return ErrUnknownSessionNode
+		}
+		parentID := target.Parent
+		removed := map[int]bool{}
+		queue := []int{node}
+		for len(queue) > 0 {
+			current := session.node(queue[0])
+			queue = queue[1:]
+			removed[current.ID] = true
+			queue = append(queue, current.Children...)
+		}
+		kept := session.Nodes[:0]
+		for _, n := range session.Nodes {
+			if !removed[n.ID] {
+				kept = append(kept, n)
+			}
+		}
+		session.Nodes = kept
+		parent := session.node(parentID)
+		children := parent.Children[:0]
+		for _, child := range parent.Children {
+			if child != node {
+				children = append(children, child)
+			}
+		}
+		parent.Children = children
+		if removed[session.Current] {
+			session.Current = parentID
+		}
+		return nil
+	})
+}
+
+// Node returns node with its position. Without a stored evaluation the TT
+// entry of the position is used when tt knows it.
+func (s *SessionStore) Node(id string, node int, tt *TranspositionTable) (SessionNodeView, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return SessionNodeView{}, false, nil
+	}
+	target := session.node(node)
+	if target == nil {
+		return SessionNodeView{}, true, ErrUnknownSessionNode
+	}
+	state, rules, err := session.stateAt(node)
+	if err != nil {
+		return SessionNodeView{}, true, err
+	}
+	view := SessionNodeView{
+		SessionID:     session.ID,
+		Node:          copySessionNode(*target),
+		Moves:         session.path(node),
+		Board:         BoardToSlice(state.Board),
+		NextPlayer:    PlayerToInt(state.ToMove),
+		Status:        StatusToString(state.Status),
+		Winner:        WinnerFromStatus(state.Status),
+		CapturedBlack: state.CapturedBlack,
+		CapturedWhite: state.CapturedWhite,
+		Eval:          target.Eval,
+	}
+	if view.Eval == nil && tt != nil && state.Status == StatusRunning {
+		view.Eval = sessionEvalFromTT(state, rules, tt)
+	}
+	return view, true, nil
+}
+
+// Evaluate searches the position of node with the live config (depth,
+// timeout and node limit overrides when greater than zero) and stores the
+// result on the node. The search runs outside the store lock and shares the
+// global caches.
+func (s *SessionStore) Evaluate(id string, node int, depth, timeoutMs int, maxNodes int64) (SessionEval, bool, error) {
+	if err := validateSearchLimits("depth", depth, maxNodes); err != nil {
+		return SessionEval{}, true, err
+	}
+	s.mu.Lock()
+	session, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return SessionEval{}, false, nil
+	}
+	if session.node(node) == nil {
+		s.mu.Unlock()
+		return SessionEval{}, true, ErrUnknownSessionNode
+	}
+	state, rules, err := session.stateAt(node)
+	s.mu.Unlock()
+	if err != nil {
+		return SessionEval{}, true, err
+	}
+	if state.Status != StatusRunning {
+		return SessionEval{}, true, ErrGameOver
+	}
+	best, scores, stats, ok := searchPosition(state, rules, depth, timeoutMs, maxNodes)
+	if !ok {
+		return SessionEval{}, true, errors.New("no legal move available")
+	}
+	eval := SessionEval{BestMove: &best, Depth: stats.CompletedDepths, Nodes: stats.Nodes, Source: "search"}
+	if score := scoreForMove(scores, best, state.Board.Size()); !math.IsInf(score, 0) && !math.IsNaN(score) {
+		eval.Score = score
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The node may have been deleted while the search ran.
+	if session, ok := s.sessions[id]; ok {
+		if target := session.node(node); target != nil {
+			stored := eval
+			target.Eval = &stored
+			session.UpdatedAtMs = time.Now().UnixMilli()
+		}
+	}
+	return eval, true, nil
+}
+
+func (s *SessionStore) update(id string, apply func(*AnalysisSession) error) (AnalysisSession, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return AnalysisSession{}, false, nil
+	}
+	if err := apply(session); err != nil {
+		return AnalysisSession{}, true, err
+	}
+	session.UpdatedAtMs = time.Now().UnixMilli()
+	return copyAnalysisSession(session), true, nil
+}
+
+func sessionEvalFromTT(state GameState, rules Rules, tt *TranspositionTable) *SessionEval {
+	size := state.Board.Size()
+	entry, ok := tt.Peek(ttKeyFor(state, size), heuristicHashFromConfig(GetConfig()))
+	if !ok {
+		return nil
+	}
+	eval := &SessionEval{Depth: entry.Depth, Score: entry.ScoreFloat(), Source: "tt"}
+	if entry.BestMove.IsValid(size) {
+		best := Move{X: entry.BestMove.X, Y: entry.BestMove.Y}
+		if legal, _ := rules.IsLegal(state, best, state.ToMove); legal {
+			eval.BestMove = &best
+		}
+	}
+	return eval
+}
+
+func (a *AnalysisSession) node(id int) *SessionNode {
+	for i := range a.Nodes {
+		if a.Nodes[i].ID == id {
+			return &a.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (a *AnalysisSession) addNode(parent int, move Move) int {
+	id := a.NextNodeID
+	a.NextNodeID++
+	played := move
+	a.Nodes = append(a.Nodes, SessionNode{ID: id, Parent: parent, Move: &played, Children: []int{}})
+	p := a.node(parent)
+	p.Children = append(p.Children, id)
+	return id
+}
+
+// path returns the moves leading from the root to node.
+func (a *AnalysisSession) path(node int) []Move {
+	moves := []Move{}
+	for current := a.node(node); current != nil && current.Move != nil; current = a.node(current.Parent) {
+		moves = append(moves, *current.Move)
+	}
+	for i, j := 0, len(moves)-1; i < j; i, j = i+1, j-1 {
+		moves[i], moves[j] = moves[j], moves[i]
+	}
+	return moves
+}
+
+func (a *AnalysisSession) stateAt(node int) (GameState, Rules, error) {
+	rules := NewRules(a.Settings)
+	start, err := renderStartState(a.Settings, a.Start)
+	if err != nil {
+		return GameState{}, rules, err
+	}
+	state, err := replayFrom(start, rules, a.path(node))
+	return state, rules, err
+}
+
+func copySessionNode(node SessionNode) SessionNode {
+	node.Children = append([]int{}, node.Children...)
+	if node.Move != nil {
+		move := *node.Move
+		node.Move = &move
+	}
+	if node.Eval != nil {
+		eval := *node.Eval
+		node.Eval = &eval
+	}
+	return node
+}
+
+func copyAnalysisSession(session *AnalysisSession) AnalysisSession {
+	copied := *session
+	copied.Nodes = make([]SessionNode, len(session.Nodes))
+	for i, node := range session.Nodes {
+		copied.Nodes[i] = copySessionNode(node)
+	}
+	return copied
+}
+
+func (s *SessionStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func (s *SessionStore) snapshot() sessionSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := sessionSnapshot{Sessions: make([]AnalysisSession, 0, len(s.sessions))}
+	for _, session := range s.sessions {
+		snapshot.Sessions = append(snapshot.Sessions, copyAnalysisSession(session))
+	}
+	return snapshot
+}
+
+func (s *SessionStore) load(snapshot sessionSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]*AnalysisSession, len(snapshot.Sessions))
+	for i := range snapshot.Sessions {
+		session := snapshot.Sessions[i]
+		s.sessions[session.ID] = &session
+	}
+}
+
+// PersistAnalysisSessions saves the sessions right away, so a study is not
+// lost if the server stops before shutdown.
+func PersistAnalysisSessions() {
+	persistAnalysisSessions(GetConfig(), AnalysisSessions)
+}
+
+func loadAnalysisSessions(cfg Config, store *SessionStore) {
+	if store == nil || cfg.SessionsPath == "" {
+		log.Printf("[ai:cache] restored analysis sessions: 0 sessions (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.SessionsPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open analysis sessions %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored analysis sessions: 0 sessions (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot sessionSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode analysis sessions %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored analysis sessions from %s (%d sessions)", path, store.Len())
+}
+
+func persistAnalysisSessions(cfg Config, store *SessionStore) {
+	if store == nil || cfg.SessionsPath == "" {
+		log.Printf("[ai:cache] stored analysis sessions: 0 sessions (disabled or no path)")
+		return
+	}
+	store.persistMu.Lock()
+	defer store.persistMu.Unlock()
+	path := resolveTTPersistencePath(cfg.SessionsPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create analysis session directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create analysis sessions %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode analysis sessions %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored analysis sessions to %s (%d sessions)", path, len(snapshot.Sessions))
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAnalysisSessionBranchesVariations(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	store := NewSessionStore()
+	session, err := store.Create("study", settings, nil, []Move{{X: 4, Y: 4}, {X: 4, Y: 5}}, 0)
+	if err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+	if len(session.Nodes) != 3 || session.Current != 2 {
+		t.Fatalf("expected a root and a two-move main line, got %d nodes, current %d", len(session.Nodes), session.Current)
+	}
+
+	session, _, err = store.Play(session.ID, 1, Move{X: 5, Y: 5})
+	if err != nil {
+		t.Fatalf("unexpected play error: %v", err)
+	}
+	branch := session.Current
+	if branch != 3 || len(session.Nodes) != 4 {
+		t.Fatalf("expected a new variation node, got current %d", branch)
+	}
+	again, _, _ := store.Play(session.ID, 1, Move{X: 5, Y: 5})
+	if again.Current != branch || len(again.Nodes) != 4 {
+		t.Fatalf("expected replaying a known move to step into its node")
+	}
+	if _, _, err := store.Play(session.ID, 1, Move{X: 4, Y: 4}); !errors.Is(err, ErrIllegalMove) {
+		t.Fatalf("expected an occupied cell to be rejected, got %v", err)
+	}
+
+	view, _, err := store.Node(session.ID, branch, nil)
+	if err != nil {
+		t.Fatalf("unexpected node error: %v", err)
+	}
+	if len(view.Moves) != 2 || view.Board[5][5] != 2 || view.Board[5][4] != 0 || view.NextPlayer != 1 {
+		t.Fatalf("unexpected variation position: %+v", view)
+	}
+
+	session, _, err = store.Promote(session.ID, branch)
+	if err != nil || session.Nodes[1].Children[0] != branch {
+		t.Fatalf("expected the variation to become the main line, err=%v", err)
+	}
+	session, _, err = store.DeleteNode(session.ID, branch)
+	if err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if len(session.Nodes) != 3 || session.Current != 1 {
+		t.Fatalf("expected the variation removed and current moved to its parent, got %d nodes, current %d", len(session.Nodes), session.Current)
+	}
+	if _, _, err := store.DeleteNode(session.ID, 0); !errors.Is(err, ErrUnknownSessionNode) {
+		t.Fatalf("expected the root to stay, got %v", err)
+	}
+}
+
+func TestAnalysisSessionEvaluateStoresEval(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	store := NewSessionStore()
+	moves := []Move{{X: 1, Y: 4}, {X: 0, Y: 0}, {X: 2, Y: 4}, {X: 1, Y: 0}, {X: 3, Y: 4}, {X: 8, Y: 8}, {X: 4, Y: 4}, {X: 8, Y: 7}}
+	session, err := store.Create("", settings, nil, moves, 0)
+	if err != nil {
+		t.Fatalf("unexpected create error: %v", err)
+	}
+	eval, _, err := store.Evaluate(session.ID, session.Current, 2, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected evaluate error: %v", err)
+	}
+	if eval.BestMove == nil || (!eval.BestMove.Equals(Move{X: 0, Y: 4}) && !eval.BestMove.Equals(Move{X: 5, Y: 4})) {
+		t.Fatalf("expected black to complete the five, got %+v", eval.BestMove)
+	}
+	view, _, _ := store.Node(session.ID, session.Current, nil)
+	if view.Eval == nil || view.Eval.Source != "search" {
+		t.Fatalf("expected the evaluation to be stored on the node")
+	}
+}
@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SGFGame is the main line of an SGF record. Colors holds 1 (B) or 2 (W) for
+// each move; Position is set when the record has setup stones (AB/AW).
+type SGFGame struct {
+	BoardSize int
+	Position  *StartPosition
+	Moves     []Move
+	Colors    []int
+}
+
+// ParseSGF reads a gomoku SGF record: board size (SZ, default 19), setup
+// stones (AB/AW, PL for the side to move) and B/W moves. Only the main line
+// is kept: parsing stops at the first closed variation.
+func ParseSGF(data string) (SGFGame, error) {
+	game := SGFGame{BoardSize: 19}
+	var black, white []Move
+	nextPlayer := 0
+	started, closed := false, false
+	for i := 0; i < len(data); i++ {
+		switch ch := data[i]; {
+		case ch == '(':
+			started = true
+			continue
+		case ch == ')':
+			closed = true
+			i = len(data)
+			continue
+		case ch < 'A' || ch > 'Z':
+			continue
+		}
+		if !started {
+			return game, fmt.Errorf("sgf must start with '('")
+		}
+		start := i
+		for i < len(data) && data[i] >= 'A' && data[i] <= 'Z' {
+			i++
+		}
+		name := data[start:i]
+		values := []string{}
+		for {
+			for i < len(data) && strings.ContainsRune(" \t\r\n", rune(data[i])) {
+				i++
+			}
+			if i >= len(data) || data[i] != '[' {
+				break
+			}
+			value, next, err := sgfValue(data, i)
+			if err != nil {
+				return game, err
+			}
+			values = append(values, value)
+			i = next + 1
+		}
+		i--
+		if len(values) == 0 {
+			continue
+		}
+		switch name {
+		case "SZ":
+			size, err := strconv.Atoi(strings.TrimSpace(values[0]))
+			if err != nil || size < 5 || size > 19 {
+				return game, fmt.Errorf("unsupported board size %q", values[0])
+			}
+			game.BoardSize = size
+		case "B", "W":
+			move, err := parseSGFPoint(values[0], game.BoardSize)
+			if err != nil {
+				return game, fmt.Errorf("move %d: %w", len(game.Moves)+1, err)
+			}
+			color := 1
+			if name == "W" {
+				color = 2
+			}
+			game.Moves = append(game.Moves, move)
+			game.Colors = append(game.Colors, color)
+		case "AB", "AW":
+			if len(game.Moves) > 0 {
+				return game, fmt.Errorf("setup stones after the first move are not supported")
+			}
+			for _, value := range values {
+				move, err := parseSGFPoint(value, game.BoardSize)
+				if err != nil {
+					return game, fmt.Errorf("setup stone: %w", err)
+				}
+				if name == "AB" {
+					black = append(black, move)
+				} else {
+					white = append(white, move)
+				}
+			}
+		case "PL":
+			switch strings.ToUpper(strings.TrimSpace(values[0])) {
+			case "B":
+				nextPlayer = 1
+			case "W":
+				nextPlayer = 2
+			default:
+				return game, fmt.Errorf("invalid PL value %q", values[0])
+			}
+		}
+	}
+	if !closed {
+		return game, fmt.Errorf("sgf record is not closed")
+	}
+	if len(black)+len(white) == 0 {
+		return game, nil
+	}
+	board := make([][]int, game.BoardSize)
+	for y := range board {
+		board[y] = make([]int, game.BoardSize)
+	}
+	for _, move := range black {
+		board[move.Y][move.X] = 1
+	}
+	for _, move := range white {
+		board[move.Y][move.X] = 2
+	}
+	if nextPlayer == 0 {
+		nextPlayer = 1
+		if len(game.Colors) > 0 {
+			nextPlayer = game.Colors[0]
+		}
+	}
+	game.Position = &StartPosition{Board: board, NextPlayer: nextPlayer}
+	return game, nil
+}
+
+// sgfValue returns the unescaped value opening at data[open] and the index
+// of its closing bracket.
+func sgfValue(data string, open int) (string, int, error) {
+	var b strings.Builder
+	for i := open + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			if i+1 < len(data) {
+				i++
+				b.WriteByte(data[i])
+			}
+		case ']':
+			return b.String(), i, nil
+		default:
+			b.WriteByte(data[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated value at offset %d", open)
+}
+
+func parseSGFPoint(raw string, boardSize int) (Move, error) {
+	if raw == "" || raw == "tt" {
+		return Move{}, fmt.Errorf("passes are not allowed")
+	}
+	if len(raw) != 2 || raw[0] < 'a' || raw[0] > 'z' || raw[1] < 'a' || raw[1] > 'z' {
+		return Move{}, fmt.Errorf("invalid coordinate %q", raw)
+	}
+	move := Move{X: int(raw[0] - 'a'), Y: int(raw[1] - 'a')}
+	if !move.IsValid(boardSize) {
+		return Move{}, fmt.Errorf("coordinate %q outside a %dx%d board", raw, boardSize, boardSize)
+	}
+	return move, nil
+}
@@ -0,0 +1,32 @@
+package engine
+
+import "testing"
+
+func TestParseSGFMainLineAndSetup(t *testing.T) {
+	game, err := ParseSGF("(;GM[4]SZ[15]AB[hh][ii]AW[hi]PL[W]C[a \\] b]\n;W[jj];B[kk](;W[ll])(;W[aa]))")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if game.BoardSize != 15 || len(game.Moves) != 3 || game.Moves[2] != (Move{X: 11, Y: 11}) {
+		t.Fatalf("expected the 15x15 main line, got %+v", game)
+	}
+	if game.Colors[0] != 2 || game.Colors[1] != 1 {
+		t.Fatalf("unexpected colours %v", game.Colors)
+	}
+	if game.Position == nil || game.Position.NextPlayer != 2 || game.Position.Board[7][7] != 1 || game.Position.Board[8][7] != 2 {
+		t.Fatalf("unexpected setup position %+v", game.Position)
+	}
+}
+
+func TestParseSGFRejectsBadInput(t *testing.T) {
+	for _, data := range []string{
+		"(;SZ[19];B[tt])",
+		"(;SZ[9];B[kk])",
+		"(;SZ[19];B[aa]",
+		"(;SZ[40])",
+	} {
+		if _, err := ParseSGF(data); err == nil {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+const (
+	defaultSimilarLimit = 20
+	maxSimilarLimit     = 200
+)
+
+// SimilarRequest asks which known positions share a shape with Position.
+// With Radius 0 the whole position is the pattern; otherwise only the stones
+// within Radius (Chebyshev distance) of Center are, and archived positions
+// are compared around their last move.
+type SimilarRequest struct {
+	Position StartPosition `json:"position"`
+	Radius   int           `json:"radius"`
+	Center   *Move         `json:"center,omitempty"`
+	Limit    int           `json:"limit"`
+}
+
+// SimilarGameMatch is an archived position with the same pattern. NextMove
+// is the move played from it, mapped into the request's coordinates; Result
+// is the game's outcome for the side to move.
+type SimilarGameMatch struct {
+	GameID   uint64 `json:"game_id"`
+	Ply      int    `json:"ply"`
+	NextMove *Move  `json:"next_move,omitempty"`
+	Result   string `json:"result"`
+}
+
+// SimilarTTMatch is a transposition table entry for the pattern shifted by
+// OffsetX/OffsetY on the request's board. BestMove is shifted back into the
+// request's coordinates and omitted when it does not land on an empty cell.
+type SimilarTTMatch struct {
+	OffsetX  int
+	OffsetY  int
+	Entry    TTEntry
+	BestMove *Move
+}
+
+// SimilarMove aggregates the continuations found for the pattern.
+// BestScore is the best score of the table entries suggesting the move, from
+// the side to move's point of view.
+type SimilarMove struct {
+	Move      Move    `json:"move"`
+	Games     int     `json:"games"`
+	Wins      int     `json:"wins"`
+	Draws     int     `json:"draws"`
+	Losses    int     `json:"losses"`
+	TTEntries int     `json:"tt_entries"`
+	BestScore float64 `json:"best_score,omitempty"`
+}
+
+type SimilarResult struct {
+	Pattern string
+	Stones  int
+	Games   []SimilarGameMatch
+	TT      []SimilarTTMatch
+	Moves   []SimilarMove
+}
+
+type patternStone struct {
+	x, y int
+	cell Cell
+}
+
+// positionPattern is a translation and symmetry normalised shape: the stones
+// cropped to their bounding box under the transform giving the smallest
+// encoding. transform and minX/minY map board cells onto pattern cells.
+type positionPattern struct {
+	key        string
+	size       int
+	transform  int
+	minX, minY int
+}
+
+func newPositionPattern(stones []patternStone, size int, toMove PlayerColor) positionPattern {
+	best := positionPattern{size: size}
+	moved := make([]patternStone, len(stones))
+	for t, transform := range symmetryTransforms {
+		minX, minY := size, size
+		for i, stone := range stones {
+			x, y := transformCoord(stone.x, stone.y, size, transform)
+			moved[i] = patternStone{x: x, y: y, cell: stone.cell}
+			minX, minY = min(minX, x), min(minY, y)
+		}
+		sort.Slice(moved, func(i, j int) bool {
+			if moved[i].y != moved[j].y {
+				return moved[i].y < moved[j].y
+			}
+			return moved[i].x < moved[j].x
+		})
+		key := make([]byte, 0, 1+3*len(moved))
+		key = append(key, byte(PlayerToInt(toMove)))
+		for _, stone := range moved {
+			key = append(key, byte(stone.x-minX), byte(stone.y-minY), byte(stone.cell))
+		}
+		if t == 0 || string(key) < best.key {
+			best = positionPattern{key: string(key), size: size, transform: t, minX: minX, minY: minY}
+		}
+	}
+	return best
+}
+
+// toPattern maps a board cell to pattern coordinates.
+func (p positionPattern) toPattern(move Move) (int, int) {
+	x, y := transformCoord(move.X, move.Y, p.size, symmetryTransforms[p.transform])
+	return x - p.minX, y - p.minY
+}
+
+// fromPattern maps pattern coordinates back to a board cell.
+func (p positionPattern) fromPattern(px, py int) (Move, bool) {
+	for y := 0; y < p.size; y++ {
+		for x := 0; x < p.size; x++ {
+			if tx, ty := p.toPattern(Move{X: x, Y: y}); tx == px && ty == py {
+				return Move{X: x, Y: y}, true
+			}
+		}
+	}
+	return Move{}, false
+}
+
+func patternStones(board Board, center *Move, radius int) []patternStone {
+	var stones []patternStone
+	size := board.Size()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			cell := board.At(x, y)
+			if cell == CellEmpty {
+				continue
+			}
+			if radius > 0 && max(x-center.X, center.X-x, y-center.Y, center.Y-y) > radius {
+				continue
+			}
+			stones = append(stones, patternStone{x: x, y: y, cell: cell})
+		}
+	}
+	return stones
+}
+
+// FindSimilarPositions looks the request's pattern up in the archived games
+// and, when tt is not nil, in the transposition table at every translation
+// of the pattern that fits on the board.
+func FindSimilarPositions(settings GameSettings, req SimilarRequest, records []GameRecord, tt *TranspositionTable) (SimilarResult, error) {
+	size := len(req.Position.Board)
+	if size < 5 || size > 19 {
+		return SimilarResult{}, fmt.Errorf("board must have 5 to 19 rows")
+	}
+	settings.BoardSize = size
+	state, err := req.Position.State(settings)
+	if err != nil {
+		return SimilarResult{}, err
+	}
+	if req.Radius < 0 || req.Radius > size {
+		return SimilarResult{}, fmt.Errorf("radius must be between 0 and %d", size)
+	}
+	if req.Radius > 0 && (req.Center == nil || !req.Center.IsValid(size)) {
+		return SimilarResult{}, fmt.Errorf("a local pattern needs a center on the board")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSimilarLimit
+	}
+	limit = min(limit, maxSimilarLimit)
+	stones := patternStones(state.Board, req.Center, req.Radius)
+	if len(stones) == 0 {
+		return SimilarResult{}, fmt.Errorf("pattern has no stones")
+	}
+	query := newPositionPattern(stones, size, state.ToMove)
+	digest := fnv.New64a()
+	_, _ = digest.Write([]byte(query.key))
+	result := SimilarResult{
+		Pattern: fmt.Sprintf("%016x", digest.Sum64()),
+		Stones:  len(stones),
+		Games:   []SimilarGameMatch{},
+		TT:      []SimilarTTMatch{},
+	}
+	moves := map[Move]*SimilarMove{}
+	continuation := func(move Move) *SimilarMove {
+		stat, ok := moves[move]
+		if !ok {
+			stat = &SimilarMove{Move: move}
+			moves[move] = stat
+		}
+		return stat
+	}
+	for _, record := range records {
+		for _, match := range similarGameMatches(record, query, req.Radius) {
+			if match.NextMove != nil {
+				stat := continuation(*match.NextMove)
+				stat.Games++
+				switch match.Result {
+				case "win":
+					stat.Wins++
+				case "loss":
+					stat.Losses++
+				case "draw":
+					stat.Draws++
+				}
+			}
+			if len(result.Games) < limit {
+				result.Games = append(result.Games, match)
+			}
+		}
+	}
+	if tt != nil {
+		result.TT = similarTTMatches(state, stones, tt, limit)
+		for _, match := range result.TT {
+			if match.BestMove == nil {
+				continue
+			}
+			stat := continuation(*match.BestMove)
+			// Table scores are from Black's side.
+			score := match.Entry.ScoreFloat()
+			if state.ToMove == PlayerWhite {
+				score = -score
+			}
+			if stat.TTEntries == 0 || score > stat.BestScore {
+				stat.BestScore = score
+			}
+			stat.TTEntries++
+		}
+	}
+	result.Moves = make([]SimilarMove, 0, len(moves))
+	for _, stat := range moves {
+		result.Moves = append(result.Moves, *stat)
+	}
+	sort.Slice(result.Moves, func(i, j int) bool {
+		a, b := result.Moves[i], result.Moves[j]
+		if a.Games+a.TTEntries != b.Games+b.TTEntries {
+			return a.Games+a.TTEntries > b.Games+b.TTEntries
+		}
+		if a.Move.Y != b.Move.Y {
+			return a.Move.Y < b.Move.Y
+		}
+		return a.Move.X < b.Move.X
+	})
+	return result, nil
+}
+
+// similarGameMatches replays record and returns every position whose pattern
+// equals query.
+func similarGameMatches(record GameRecord, query positionPattern, radius int) []SimilarGameMatch {
+	state, err := renderStartState(record.Settings, record.Start)
+	if err != nil {
+		return nil
+	}
+	rules := NewRules(record.Settings)
+	state.Status = StatusRunning
+	winner := WinnerFromStatus(record.Status)
+	var matches []SimilarGameMatch
+	for ply := 0; ply <= len(record.Entries); ply++ {
+		if ply > 0 {
+			entry := record.Entries[ply-1]
+			if !applyMove(&state, rules, entry.Move, entry.Player) {
+				return matches
+			}
+		}
+		var center *Move
+		if radius > 0 {
+			if ply == 0 {
+				continue
+			}
+			center = &record.Entries[ply-1].Move
+		}
+		stones := patternStones(state.Board, center, radius)
+		if len(stones) == 0 {
+			continue
+		}
+		pattern := newPositionPattern(stones, state.Board.Size(), state.ToMove)
+		if pattern.key != query.key {
+			continue
+		}
+		match := SimilarGameMatch{GameID: record.ID, Ply: ply, Result: "unfinished"}
+		switch {
+		case record.Status == StatusDraw:
+			match.Result = "draw"
+		case winner == PlayerToInt(state.ToMove):
+			match.Result = "win"
+		case winner != 0:
+			match.Result = "loss"
+		}
+		if ply < len(record.Entries) {
+			px, py := pattern.toPattern(record.Entries[ply].Move)
+			if move, ok := query.fromPattern(px, py); ok {
+				match.NextMove = &move
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// similarTTMatches probes the table with the pattern alone on the board at
+// every offset keeping all of its stones in bounds. Symmetric positions share
+// a key, so rotations and reflections are covered by each probe.
+func similarTTMatches(state GameState, stones []patternStone, tt *TranspositionTable, limit int) []SimilarTTMatch {
+	size := state.Board.Size()
+	minX, minY, maxX, maxY := size, size, -1, -1
+	for _, stone := range stones {
+		minX, maxX = min(minX, stone.x), max(maxX, stone.x)
+		minY, maxY = min(minY, stone.y), max(maxY, stone.y)
+	}
+	heuristicHash := heuristicHashFromConfig(GetConfig())
+	matches := []SimilarTTMatch{}
+	for dy := -minY; dy < size-maxY; dy++ {
+		for dx := -minX; dx < size-maxX; dx++ {
+			probe := state.Clone()
+			probe.Board.Reset(size)
+			for _, stone := range stones {
+				probe.Board.Set(stone.x+dx, stone.y+dy, stone.cell)
+			}
+			probe.Status = StatusRunning
+			probe.recomputeHashes()
+			entry, ok := tt.Peek(ttKeyFor(probe, size), heuristicHash)
+			if !ok {
+				continue
+			}
+			match := SimilarTTMatch{OffsetX: dx, OffsetY: dy, Entry: entry}
+			best := Move{X: entry.BestMove.X - dx, Y: entry.BestMove.Y - dy}
+			if probe.Board.IsEmpty(entry.BestMove.X, entry.BestMove.Y) && best.IsValid(size) {
+				match.BestMove = &best
+			}
+			matches = append(matches, match)
+			if len(matches) == limit {
+				return matches
+			}
+		}
+	}
+	return matches
+}
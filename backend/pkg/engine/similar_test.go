@@ -0,0 +1,87 @@
+package engine
+
+import "testing"
+
+func TestFindSimilarPositionsMatchesMirroredShapes(t *testing.T) {
+	settings := DefaultGameSettings()
+	record := GameRecord{
+		ID:       7,
+		Settings: settings,
+		Entries: []HistoryEntry{
+			{Move: Move{X: 9, Y: 9}, Player: PlayerBlack},
+			{Move: Move{X: 10, Y: 9}, Player: PlayerWhite},
+			{Move: Move{X: 9, Y: 10}, Player: PlayerBlack},
+			{Move: Move{X: 11, Y: 11}, Player: PlayerWhite},
+		},
+		Status: StatusBlackWon,
+	}
+	// The position after three moves, mirrored and shifted onto a 15x15 board.
+	board := make([][]int, 15)
+	for y := range board {
+		board[y] = make([]int, 15)
+	}
+	board[4][4], board[4][3], board[5][4] = 1, 2, 1
+	req := SimilarRequest{Position: StartPosition{Board: board, NextPlayer: 2}}
+
+	tt := NewTranspositionTable(1024, 2)
+	probe := DefaultGameState(GameSettings{BoardSize: 15})
+	probe.Board.Set(6, 7, CellBlack)
+	probe.Board.Set(5, 7, CellWhite)
+	probe.Board.Set(6, 8, CellBlack)
+	probe.ToMove = PlayerWhite
+	probe.Status = StatusRunning
+	probe.recomputeHashes()
+	tt.Store(ttKeyFor(probe, 15), heuristicHashFromConfig(GetConfig()), 4, 1200, TTExact, Move{X: 7, Y: 9}, TTMeta{})
+
+	result, err := FindSimilarPositions(settings, req, []GameRecord{record}, tt)
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if len(result.Games) != 1 || result.Games[0].Ply != 3 || result.Games[0].Result != "loss" {
+		t.Fatalf("expected the ply 3 position as a loss for white, got %+v", result.Games)
+	}
+	if next := result.Games[0].NextMove; next == nil || *next != (Move{X: 2, Y: 6}) {
+		t.Fatalf("expected the continuation mapped to 2,6, got %v", next)
+	}
+	if len(result.TT) != 1 || result.TT[0].OffsetX != 2 || result.TT[0].OffsetY != 3 {
+		t.Fatalf("expected one table entry at offset 2,3, got %+v", result.TT)
+	}
+	if best := result.TT[0].BestMove; best == nil || *best != (Move{X: 5, Y: 6}) {
+		t.Fatalf("expected the table move shifted to 5,6, got %v", best)
+	}
+	if len(result.Moves) != 2 || result.Moves[0].Games+result.Moves[0].TTEntries != 1 {
+		t.Fatalf("unexpected continuations %+v", result.Moves)
+	}
+	for _, move := range result.Moves {
+		// White is to move, so Black's 1200 is -1200 for the mover.
+		if move.TTEntries == 1 && move.BestScore != -1200 {
+			t.Fatalf("expected the table score from white's side, got %+v", move)
+		}
+	}
+
+	local := req
+	local.Radius = 1
+	local.Center = &Move{X: 4, Y: 4}
+	result, err = FindSimilarPositions(settings, local, []GameRecord{record}, nil)
+	if err != nil {
+		t.Fatalf("local lookup failed: %v", err)
+	}
+	if len(result.Games) != 1 || result.Stones != 3 {
+		t.Fatalf("expected the local shape around the last move to match once, got %+v", result)
+	}
+}
+
+func TestFindSimilarPositionsRejectsEmptyPatterns(t *testing.T) {
+	board := make([][]int, 9)
+	for y := range board {
+		board[y] = make([]int, 9)
+	}
+	req := SimilarRequest{Position: StartPosition{Board: board, NextPlayer: 1}}
+	if _, err := FindSimilarPositions(DefaultGameSettings(), req, nil, nil); err == nil {
+		t.Fatalf("expected an empty board to be rejected")
+	}
+	req.Radius = 2
+	if _, err := FindSimilarPositions(DefaultGameSettings(), req, nil, nil); err == nil {
+		t.Fatalf("expected a local pattern without a center to be rejected")
+	}
+}
@@ -0,0 +1,496 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxSimuls                = 16
+	maxSimulBoards           = 20
+	simulDefaultMoveBudgetMs = 2000
+	// simulMinMoveBudgetMs is the least time a move gets however many
+	// boards are waiting.
+	simulMinMoveBudgetMs = 200
+)
+
+// SimulRequest starts a simul: the engine plays one board against each of
+// Players, with the same rules and colour everywhere.
+type SimulRequest struct {
+	Name    string          `json:"name"`
+	Players []string        `json:"players"`
+	Rules   UserPreferences `json:"rules"`
+	// AIColor is the engine's colour on every board, black by default.
+	AIColor int `json:"ai_color"`
+	// MoveBudgetMs is the thinking time of a move while a single board
+	// waits for the engine. It is shared out between the waiting boards.
+	MoveBudgetMs int `json:"move_budget_ms"`
+	Depth        int `json:"depth"`
+}
+
+// SimulBoard is one game of a simul. WaitingSinceMs is set while the engine
+// is to move on it.
+type SimulBoard struct {
+	Index          int     `json:"index"`
+	Player         string  `json:"player"`
+	Status         string  `json:"status"`
+	Winner         int     `json:"winner"`
+	NextPlayer     int     `json:"next_player"`
+	Board          [][]int `json:"board"`
+	Moves          []Move  `json:"moves"`
+	WaitingSinceMs int64   `json:"waiting_since_ms,omitempty"`
+	Thinking       bool    `json:"thinking"`
+	AIThinkMs      float64 `json:"ai_think_ms"`
+}
+
+type SimulInfo struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Status       string       `json:"status"`
+	AIColor      int          `json:"ai_color"`
+	MoveBudgetMs int          `json:"move_budget_ms"`
+	Depth        int          `json:"depth"`
+	CreatedAtMs  int64        `json:"created_at_ms"`
+	Waiting      int          `json:"waiting"`
+	Boards       []SimulBoard `json:"boards"`
+}
+
+// SimulEvent is published whenever a move is played on a simul board.
+type SimulEvent struct {
+	SimulID string     `json:"simul_id"`
+	Board   SimulBoard `json:"board"`
+}
+
+type simulBoard struct {
+	player       string
+	token        string
+	game         *Game
+	waitingSince time.Time
+	thinkMs      float64
+	// stuck is set when the engine found no move to play; the board is
+	// then left alone.
+	stuck bool
+}
+
+type simulTurn struct {
+	index    int
+	state    GameState
+	rules    Rules
+	budgetMs int
+}
+
+// Simul is a set of human vs engine games played by one engine. A single
+// scheduler searches one board at a time, always the one whose player has
+// been waiting for a reply the longest.
+type Simul struct {
+	mu           sync.Mutex
+	id           string
+	name         string
+	settings     GameSettings
+	aiColor      PlayerColor
+	moveBudgetMs int
+	depth        int
+	createdAt    time.Time
+	boards       []*simulBoard
+	thinking     int
+	stopped      bool
+	cancel       context.CancelFunc
+	wake         chan struct{}
+	publisher    func(SimulEvent)
+	ai           *AIPlayer
+}
+
+// SimulManager keeps the simuls being played and the last finished ones.
+type SimulManager struct {
+	mu        sync.Mutex
+	simuls    map[string]*Simul
+	order     []string
+	publisher func(SimulEvent)
+}
+
+func NewSimulManager() *SimulManager {
+	return &SimulManager{simuls: make(map[string]*Simul)}
+}
+
+func (m *SimulManager) SetPublisher(publisher func(SimulEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publisher = publisher
+}
+
+func newSimul(req SimulRequest) (*Simul, error) {
+	if len(req.Players) == 0 || len(req.Players) > maxSimulBoards {
+		return nil, fieldErrorf("players", "players must list 1 to %d names", maxSimulBoards)
+	}
+	if err := req.Rules.Validate(); err != nil {
+		return nil, err
+	}
+	if req.AIColor == 0 {
+		req.AIColor = 1
+	}
+	if req.AIColor != 1 && req.AIColor != 2 {
+		return nil, fieldErrorf("ai_color", "ai_color must be 1 or 2")
+	}
+	if req.Depth < 0 || req.Depth > 10 {
+		return nil, fieldErrorf("depth", "depth must be between 0 and 10")
+	}
+	req.Rules.Color = 0
+	settings := matchSettings(req.Rules)
+	if req.AIColor == 1 {
+		settings.BlackType = PlayerAI
+	} else {
+		settings.WhiteType = PlayerAI
+	}
+	s := &Simul{
+		id:           newMatchID(),
+		name:         strings.TrimSpace(req.Name),
+		settings:     settings,
+		aiColor:      IntToPlayer(req.AIColor),
+		moveBudgetMs: simulateMoveBudget(req.MoveBudgetMs, simulDefaultMoveBudgetMs),
+		depth:        req.Depth,
+		createdAt:    time.Now(),
+		thinking:     -1,
+		wake:         make(chan struct{}, 1),
+		ai:           &AIPlayer{},
+	}
+	for i, name := range req.Players {
+		name = strings.TrimSpace(name)
+		if name == "" || len(name) > 32 {
+			return nil, fieldErrorf(fmt.Sprintf("players[%d]", i), "player names must be 1 to 32 characters")
+		}
+		board := &simulBoard{player: name, token: newMatchID(), game: newHeadlessGame(settings)}
+		if board.game.state.ToMove == s.aiColor {
+			board.waitingSince = s.createdAt
+		}
+		s.boards = append(s.boards, board)
+	}
+	return s, nil
+}
+
+// Create starts a simul and returns it with one secret token per board, in
+// the order of the players. The engine plays until every game is over, the
+// simul is stopped or ctx is cancelled.
+func (m *SimulManager) Create(ctx context.Context, req SimulRequest) (SimulInfo, []string, error) {
+	s, err := newSimul(req)
+	if err != nil {
+		return SimulInfo{}, nil, err
+	}
+	m.mu.Lock()
+	if len(m.order) >= maxSimuls && !m.evictLocked() {
+		m.mu.Unlock()
+		return SimulInfo{}, nil, fmt.Errorf("at most %d simuls are played at once", maxSimuls)
+	}
+	s.publisher = m.publisher
+	m.simuls[s.id] = s
+	m.order = append(m.order, s.id)
+	m.mu.Unlock()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	go s.run(runCtx)
+	tokens := make([]string, len(s.boards))
+	for i, board := range s.boards {
+		tokens[i] = board.token
+	}
+	return s.Info(), tokens, nil
+}
+
+// evictLocked drops the oldest simul that is no longer played.
+func (m *SimulManager) evictLocked() bool {
+	for i, id := range m.order {
+		if m.simuls[id].Info().Status != "running" {
+			delete(m.simuls, id)
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *SimulManager) Get(id string) (*Simul, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.simuls[id]
+	return s, ok
+}
+
+// List returns the simuls, newest first.
+func (m *SimulManager) List() []SimulInfo {
+	m.mu.Lock()
+	simuls := make([]*Simul, 0, len(m.order))
+	for _, id := range m.order {
+		simuls = append(simuls, m.simuls[id])
+	}
+	m.mu.Unlock()
+	infos := make([]SimulInfo, 0, len(simuls))
+	for i := len(simuls) - 1; i >= 0; i-- {
+		infos = append(infos, simuls[i].Info())
+	}
+	return infos
+}
+
+func (s *Simul) Info() SimulInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := SimulInfo{
+		ID:           s.id,
+		Name:         s.name,
+		Status:       s.statusLocked(),
+		AIColor:      PlayerToInt(s.aiColor),
+		MoveBudgetMs: s.moveBudgetMs,
+		Depth:        s.depth,
+		CreatedAtMs:  s.createdAt.UnixMilli(),
+	}
+	for i := range s.boards {
+		board := s.boardLocked(i)
+		if board.WaitingSinceMs != 0 {
+			info.Waiting++
+		}
+		info.Boards = append(info.Boards, board)
+	}
+	return info
+}
+
+// Board returns one board of the simul.
+func (s *Simul) Board(index int) (SimulBoard, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index < 0 || index >= len(s.boards) {
+		return SimulBoard{}, false
+	}
+	return s.boardLocked(index), true
+}
+
+func (s *Simul) statusLocked() string {
+	if s.stopped {
+		return "stopped"
+	}
+	for _, board := range s.boards {
+		if board.game.state.Status == StatusRunning && !board.stuck {
+			return "running"
+		}
+	}
+	return "finished"
+}
+
+func (s *Simul) boardLocked(index int) SimulBoard {
+	board := s.boards[index]
+	state := board.game.State()
+	entries := board.game.History().All()
+	moves := make([]Move, 0, len(entries))
+	for _, entry := range entries {
+		moves = append(moves, entry.Move)
+	}
+	view := SimulBoard{
+		Index:      index,
+		Player:     board.player,
+		Status:     StatusToString(state.Status),
+		Winner:     WinnerFromStatus(state.Status),
+		NextPlayer: PlayerToInt(state.ToMove),
+		Board:      BoardToSlice(state.Board),
+		Moves:      moves,
+		Thinking:   s.thinking == index,
+		AIThinkMs:  board.thinkMs,
+	}
+	if !board.waitingSince.IsZero() {
+		view.WaitingSinceMs = board.waitingSince.UnixMilli()
+	}
+	return view
+}
+
+// Play applies the human's move on board index for the holder of token.
+// found is false for an unknown board.
+func (s *Simul) Play(index int, token string, move Move) (SimulBoard, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index < 0 || index >= len(s.boards) {
+		return SimulBoard{}, false, nil
+	}
+	board := s.boards[index]
+	if token == "" || token != board.token {
+		return SimulBoard{}, true, ErrInvalidToken
+	}
+	state := board.game.state
+	if s.stopped || state.Status != StatusRunning {
+		return SimulBoard{}, true, ErrGameOver
+	}
+	if state.ToMove == s.aiColor {
+		return SimulBoard{}, true, ErrNotYourTurn
+	}
+	if !move.IsValid(state.Board.Size()) {
+		return SimulBoard{}, true, fmt.Errorf("%w: out of bounds", ErrIllegalMove)
+	}
+	if applied, reason := board.game.TryApplyMove(Move{X: move.X, Y: move.Y}); !applied {
+		return SimulBoard{}, true, fmt.Errorf("%w: %s", ErrIllegalMove, strings.TrimPrefix(reason, "Illegal move: "))
+	}
+	if board.game.state.Status == StatusRunning {
+		board.waitingSince = time.Now()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return s.movedLocked(index), true, nil
+}
+
+// Stop ends the simul; unfinished games are left as they are.
+func (s *Simul) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// movedLocked publishes the board after a move and stores it in the game
+// database once it is over.
+func (s *Simul) movedLocked(index int) SimulBoard {
+	board := s.boards[index]
+	view := s.boardLocked(index)
+	if board.game.state.Status != StatusRunning {
+		record := GameRecord{
+			Settings:    s.settings,
+			Entries:     board.game.History().All(),
+			Status:      board.game.state.Status,
+			WinningLine: append([]Move(nil), board.game.state.WinningLine...),
+			AIDepth:     s.depth,
+		}
+		storedGames.Add(record)
+		globalStats.recordGame(record)
+	}
+	if s.publisher != nil {
+		s.publisher(SimulEvent{SimulID: s.id, Board: view})
+	}
+	return view
+}
+
+// next picks the board the engine should think about: among the boards
+// waiting for its reply, the one waiting the longest. The budget shrinks as
+// more boards wait, so one slow reply does not hold up every other player.
+// done reports that no board will need the engine again.
+func (s *Simul) next() (turn simulTurn, ok bool, done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return simulTurn{}, false, true
+	}
+	waiting := make([]int, 0, len(s.boards))
+	running := false
+	for i, board := range s.boards {
+		if board.game.state.Status != StatusRunning || board.stuck {
+			continue
+		}
+		running = true
+		if board.game.state.ToMove == s.aiColor {
+			waiting = append(waiting, i)
+		}
+	}
+	if len(waiting) == 0 {
+		return simulTurn{}, false, !running
+	}
+	sort.SliceStable(waiting, func(a, b int) bool {
+		return s.boards[waiting[a]].waitingSince.Before(s.boards[waiting[b]].waitingSince)
+	})
+	budget := s.moveBudgetMs / len(waiting)
+	if budget < simulMinMoveBudgetMs {
+		budget = simulMinMoveBudgetMs
+	}
+	if budget > s.moveBudgetMs {
+		budget = s.moveBudgetMs
+	}
+	index := waiting[0]
+	board := s.boards[index]
+	s.thinking = index
+	return simulTurn{index: index, state: board.game.State(), rules: board.game.rules, budgetMs: budget}, true, false
+}
+
+func (s *Simul) run(ctx context.Context) {
+	defer func() {
+		s.mu.Lock()
+		s.thinking = -1
+		s.mu.Unlock()
+	}()
+	for {
+		turn, ok, done := s.next()
+		if done {
+			return
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			}
+			continue
+		}
+		move, stats, found := s.think(ctx, turn)
+		if ctx.Err() != nil {
+			return
+		}
+		s.playEngineMove(turn.index, move, stats, found)
+	}
+}
+
+// think searches the board with the live engine configuration under the
+// turn's budget, as a live move of the shared pool.
+func (s *Simul) think(ctx context.Context, turn simulTurn) (Move, *SearchStats, bool) {
+	config := GetConfig()
+	config.AiTimeBudgetMs = turn.budgetMs
+	config.AiPonderingEnabled = false
+	if s.depth > 0 {
+		config.AiDepth = s.depth
+		config.AiMaxDepth = s.depth
+		if config.AiMinDepth > s.depth {
+			config.AiMinDepth = s.depth
+		}
+	}
+	config = liveAIConfig(config)
+	stats := &SearchStats{Start: time.Now()}
+	aiSettings := AIScoreSettings{
+		Depth:      config.AiDepth,
+		TimeoutMs:  config.AiTimeoutMs,
+		BoardSize:  turn.state.Board.Size(),
+		Player:     turn.state.ToMove,
+		Cache:      SharedSearchCache(),
+		Config:     config,
+		Stats:      stats,
+		ShouldStop: func() bool { return ctx.Err() != nil },
+	}
+	var scores []float64
+	SearchPool.Run(searchJobLive, "simul "+s.id, func() {
+		scores = ScoreBoard(turn.state, turn.rules, aiSettings)
+	})
+	if ctx.Err() != nil {
+		return Move{}, stats, false
+	}
+	move, ok := s.ai.selectBestMove(turn.state, turn.rules, aiSettings, stats, scores)
+	return move, stats, ok
+}
+
+func (s *Simul) playEngineMove(index int, move Move, stats *SearchStats, found bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.thinking = -1
+	if s.stopped {
+		return
+	}
+	board := s.boards[index]
+	board.thinkMs += float64(time.Since(stats.Start).Microseconds()) / 1000.0
+	if found {
+		found, _ = board.game.TryApplyMove(Move{X: move.X, Y: move.Y, Depth: stats.CompletedDepths})
+	}
+	if !found {
+		log.Printf("[simul] %s board %d: no engine move, leaving the board", s.id, index)
+		board.stuck = true
+		return
+	}
+	board.waitingSince = time.Time{}
+	s.movedLocked(index)
+}
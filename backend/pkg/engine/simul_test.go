@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSimulServesLongestWaitingBoardFirst(t *testing.T) {
+	simul, err := newSimul(SimulRequest{
+		Players:      []string{"ada", "bob", "cyd"},
+		Rules:        UserPreferences{BoardSize: 9},
+		AIColor:      2,
+		MoveBudgetMs: 1200,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := simul.next(); ok {
+		t.Fatalf("expected no board to wait while the humans have not moved")
+	}
+	if _, _, err := simul.Play(0, simul.boards[1].token, Move{X: 4, Y: 4}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected another board's token to be refused, got %v", err)
+	}
+	if _, _, err := simul.Play(2, simul.boards[2].token, Move{X: 4, Y: 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, _, err := simul.Play(0, simul.boards[0].token, Move{X: 3, Y: 3}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := simul.Play(0, simul.boards[0].token, Move{X: 5, Y: 5}); !errors.Is(err, ErrNotYourTurn) {
+		t.Fatalf("expected the engine to be to move, got %v", err)
+	}
+
+	turn, ok, _ := simul.next()
+	if !ok || turn.index != 2 {
+		t.Fatalf("expected the board that waited longest, got %d ok=%v", turn.index, ok)
+	}
+	if turn.budgetMs != 600 {
+		t.Fatalf("expected the budget shared by two waiting boards, got %d", turn.budgetMs)
+	}
+	if info := simul.Info(); info.Waiting != 2 || !info.Boards[2].Thinking {
+		t.Fatalf("unexpected simul info: waiting=%d", info.Waiting)
+	}
+}
+
+func TestSimulEngineAnswersEveryBoard(t *testing.T) {
+	manager := NewSimulManager()
+	events := make(chan SimulEvent, 16)
+	manager.SetPublisher(func(event SimulEvent) { events <- event })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	info, tokens, err := manager.Create(ctx, SimulRequest{
+		Players:      []string{"ada", "bob"},
+		Rules:        UserPreferences{BoardSize: 9},
+		MoveBudgetMs: 400,
+		Depth:        1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 || info.AIColor != 1 {
+		t.Fatalf("expected two tokens and the engine as black, got %d/%d", len(tokens), info.AIColor)
+	}
+	seen := map[int]bool{}
+	for len(seen) < 2 {
+		select {
+		case event := <-events:
+			if len(event.Board.Moves) != 1 || event.Board.NextPlayer != 2 {
+				t.Fatalf("expected the engine's opening move, got %+v", event.Board)
+			}
+			seen[event.Board.Index] = true
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for the engine's moves")
+		}
+	}
+	simul, _ := manager.Get(info.ID)
+	simul.Stop()
+	if simul.Info().Status != "stopped" {
+		t.Fatalf("expected the simul to be stopped")
+	}
+	if _, _, err := simul.Play(0, tokens[0], Move{X: 0, Y: 0}); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected moves after stopping to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	simulateMinMoveBudgetMs = 50
+	simulateMaxMoveBudgetMs = 10000
+)
+
+type SimulateRequest struct {
+	Opening         []Move           `json:"opening"`
+	BlackHeuristics *HeuristicConfig `json:"black_heuristics,omitempty"`
+	WhiteHeuristics *HeuristicConfig `json:"white_heuristics,omitempty"`
+	BlackSearch     *SearchParams    `json:"black_search,omitempty"`
+	WhiteSearch     *SearchParams    `json:"white_search,omitempty"`
+	MoveBudgetMs    int              `json:"move_budget_ms"`
+	Depth           int              `json:"depth"`
+	BlackDepth      int              `json:"black_depth"`
+	WhiteDepth      int              `json:"white_depth"`
+	MaxMoves        int              `json:"max_moves"`
+	// MaxNodes bounds every engine move by node count instead of
+	// MoveBudgetMs, so results do not depend on the machine.
+	MaxNodes int64 `json:"max_nodes"`
+	// DisableCache keeps the sides from reading each other's transposition
+	// table entries, so a shallow side cannot borrow a deeper search.
+	DisableCache bool `json:"disable_cache"`
+}
+
+type SimulatedMove struct {
+	X                 int     `json:"x"`
+	Y                 int     `json:"y"`
+	Player            int     `json:"player"`
+	ElapsedMs         float64 `json:"elapsed_ms"`
+	IsAi              bool    `json:"is_ai"`
+	Depth             int     `json:"depth"`
+	CapturedCount     int     `json:"captured_count"`
+	CapturedPositions []Move  `json:"captured_positions"`
+}
+
+type SimulateResult struct {
+	Status             string          `json:"status"`
+	Winner             int             `json:"winner"`
+	Moves              []SimulatedMove `json:"moves"`
+	WinningLine        []Move          `json:"winning_line"`
+	WinningCapturePair []Move          `json:"winning_capture_pair"`
+	CapturedBlack      int             `json:"captured_black"`
+	CapturedWhite      int             `json:"captured_white"`
+	ElapsedMs          float64         `json:"elapsed_ms"`
+}
+
+// newHeadlessGame builds a Game without AI player workers; moves are fed in
+// by the caller through TryApplyMove.
+func newHeadlessGame(settings GameSettings) *Game {
+	g := &Game{
+		settings:    settings,
+		rules:       NewRules(settings),
+		blackPlayer: NewHumanPlayer(),
+		whitePlayer: NewHumanPlayer(),
+	}
+	g.state.Reset(settings)
+	g.computeLogWidths()
+	g.turnStart = time.Now()
+	g.Start()
+	return g
+}
+
+// validate checks the depths and node limit of the request.
+func (req SimulateRequest) validate() error {
+	if err := validateSearchLimits("depth", req.Depth, req.MaxNodes); err != nil {
+		return err
+	}
+	if err := validateSearchLimits("black_depth", req.BlackDepth, 0); err != nil {
+		return err
+	}
+	return validateSearchLimits("white_depth", req.WhiteDepth, 0)
+}
+
+func simulateMoveBudget(requested int, fallback int) int {
+	budget := requested
+	if budget <= 0 {
+		budget = fallback
+	}
+	if budget < simulateMinMoveBudgetMs {
+		budget = simulateMinMoveBudgetMs
+	}
+	if budget > simulateMaxMoveBudgetMs {
+		budget = simulateMaxMoveBudgetMs
+	}
+	return budget
+}
+
+// Simulate plays a whole engine-vs-engine game synchronously from the given
+// opening, each side searching with its own heuristics under a bounded
+// per-move time budget. Games that hit MaxMoves are returned as "running".
+// Cancelling ctx aborts the current search and returns ctx.Err(). The
+// searches run on the shared pool behind everything but the backlog.
+func Simulate(ctx context.Context, settings GameSettings, req SimulateRequest) (SimulateResult, error) {
+	start := time.Now()
+	if err := req.validate(); err != nil {
+		return SimulateResult{}, err
+	}
+	settings.BlackType, settings.WhiteType = PlayerAI, PlayerAI
+	game := newHeadlessGame(settings)
+	game.simulated = true
+	for i, move := range req.Opening {
+		if !move.IsValid(settings.BoardSize) {
+			return SimulateResult{}, fmt.Errorf("opening move %d out of bounds", i+1)
+		}
+		if ok, reason := game.TryApplyMove(Move{X: move.X, Y: move.Y}); !ok {
+			return SimulateResult{}, fmt.Errorf("opening move %d illegal: %s", i+1, reason)
+		}
+	}
+	openingEntries := game.history.Size()
+
+	base := GetConfig()
+	base.AiTimeBudgetMs = simulateMoveBudget(req.MoveBudgetMs, base.AiTimeBudgetMs)
+	base.AiPonderingEnabled = false
+	if req.MaxNodes > 0 {
+		base = nodeLimitedConfig(base)
+	}
+	if req.DisableCache {
+		base.AiUseTtCache = false
+	}
+	if req.Depth > 0 {
+		base.AiDepth = req.Depth
+		base.AiMaxDepth = req.Depth
+	}
+	maxMoves := req.MaxMoves
+	if maxMoves <= 0 {
+		maxMoves = settings.BoardSize * settings.BoardSize
+	}
+	elapsed := map[int]float64{}
+	ai := &AIPlayer{}
+	engineMove := func(ctx context.Context, state GameState, rules Rules) (Move, error) {
+		config := base
+		heuristics, search, depth := req.BlackHeuristics, req.BlackSearch, req.BlackDepth
+		if state.ToMove == PlayerWhite {
+			heuristics, search, depth = req.WhiteHeuristics, req.WhiteSearch, req.WhiteDepth
+		}
+		if heuristics != nil {
+			config.Heuristics = *heuristics
+		}
+		config = search.Apply(config)
+		if depth > 0 {
+			config.AiDepth = depth
+			config.AiMaxDepth = depth
+			if config.AiMinDepth > depth {
+				config.AiMinDepth = depth
+			}
+		}
+		config = liveAIConfig(config)
+		stats := &SearchStats{Start: time.Now()}
+		aiSettings := AIScoreSettings{
+			Depth:      config.AiDepth,
+			TimeoutMs:  config.AiTimeoutMs,
+			BoardSize:  state.Board.Size(),
+			Player:     state.ToMove,
+			Cache:      SharedSearchCache(),
+			Config:     config,
+			Stats:      stats,
+			ShouldStop: func() bool { return ctx.Err() != nil },
+			MaxNodes:   req.MaxNodes,
+		}
+		var scores []float64
+		SearchPool.Run(searchJobSimulation, "simulate", func() {
+			scores = ScoreBoard(state, rules, aiSettings)
+		})
+		if err := ctx.Err(); err != nil {
+			return Move{}, err
+		}
+		best, ok := ai.selectBestMove(state, rules, aiSettings, stats, scores)
+		if !ok {
+			return Move{}, fmt.Errorf("no legal move available")
+		}
+		elapsed[game.history.Size()] = float64(time.Since(stats.Start).Microseconds()) / 1000.0
+		return Move{X: best.X, Y: best.Y, Depth: stats.CompletedDepths}, nil
+	}
+	for played := 0; played < maxMoves; played++ {
+		stepped, err := game.stepWith(ctx, engineMove)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return SimulateResult{}, ctxErr
+			}
+			return SimulateResult{}, err
+		}
+		if !stepped {
+			break
+		}
+	}
+
+	final := game.State()
+	result := SimulateResult{
+		Status:             StatusToString(final.Status),
+		Winner:             WinnerFromStatus(final.Status),
+		WinningLine:        final.WinningLine,
+		WinningCapturePair: final.WinningCapturePair,
+		CapturedBlack:      final.CapturedBlack,
+		CapturedWhite:      final.CapturedWhite,
+	}
+	for i, entry := range game.History().All() {
+		result.Moves = append(result.Moves, SimulatedMove{
+			X:                 entry.Move.X,
+			Y:                 entry.Move.Y,
+			Player:            PlayerToInt(entry.Player),
+			ElapsedMs:         elapsed[i],
+			IsAi:              i >= openingEntries,
+			Depth:             entry.Depth,
+			CapturedCount:     entry.CapturedCount,
+			CapturedPositions: entry.CapturedPositions,
+		})
+	}
+	result.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000.0
+	return result, nil
+}
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const simulateBatchMaxGames = 1000
+
+type SimulateBatchRequest struct {
+	Games           int              `json:"games"`
+	Openings        [][]Move         `json:"openings"`
+	BlackHeuristics *HeuristicConfig `json:"black_heuristics,omitempty"`
+	WhiteHeuristics *HeuristicConfig `json:"white_heuristics,omitempty"`
+	BlackSearch     *SearchParams    `json:"black_search,omitempty"`
+	WhiteSearch     *SearchParams    `json:"white_search,omitempty"`
+	AlternateColors bool             `json:"alternate_colors"`
+	MoveBudgetMs    int              `json:"move_budget_ms"`
+	Depth           int              `json:"depth"`
+	BlackDepth      int              `json:"black_depth"`
+	WhiteDepth      int              `json:"white_depth"`
+	MaxMoves        int              `json:"max_moves"`
+	MaxNodes        int64            `json:"max_nodes"`
+	Workers         int              `json:"workers"`
+	DisableCache    bool             `json:"disable_cache"`
+}
+
+// SimulateBatchGame is one finished game of a batch. Swapped games give
+// black_heuristics to white; Score is always from the black_heuristics
+// side (1 win, 0.5 draw or unfinished, 0 loss).
+type SimulateBatchGame struct {
+	Index        int            `json:"index"`
+	OpeningIndex int            `json:"opening_index"`
+	Swapped      bool           `json:"swapped"`
+	Score        float64        `json:"score"`
+	Result       SimulateResult `json:"result"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// SimulateBatchSummary aggregates a batch; Wins/Draws/Losses are from the
+// black_heuristics side, BlackWins/WhiteWins by board colour.
+type SimulateBatchSummary struct {
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Draws      int     `json:"draws"`
+	Losses     int     `json:"losses"`
+	Unfinished int     `json:"unfinished"`
+	Errors     int     `json:"errors"`
+	BlackWins  int     `json:"black_wins"`
+	WhiteWins  int     `json:"white_wins"`
+	Score      float64 `json:"score"`
+	ScoreRate  float64 `json:"score_rate"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+}
+
+func simulateBatchSize(req SimulateBatchRequest) int {
+	games := req.Games
+	if games <= 0 {
+		games = len(req.Openings)
+		if req.AlternateColors {
+			games *= 2
+		}
+	}
+	if games <= 0 {
+		games = 1
+	}
+	if games > simulateBatchMaxGames {
+		games = simulateBatchMaxGames
+	}
+	return games
+}
+
+func simulateBatchWorkers(requested, games, cpuCount int) int {
+	workers := requested
+	if workers <= 0 {
+		workers = 1
+	}
+	if cpuCount < 1 {
+		cpuCount = 1
+	}
+	if workers > cpuCount {
+		workers = cpuCount
+	}
+	if workers > games {
+		workers = games
+	}
+	return workers
+}
+
+// simulateBatchGameRequest maps game index to its opening and colours:
+// with AlternateColors each opening is played twice in a row, swapped the
+// second time.
+func simulateBatchGameRequest(req SimulateBatchRequest, index int) (SimulateRequest, int, bool) {
+	slot := index
+	swapped := false
+	if req.AlternateColors {
+		slot = index / 2
+		swapped = index%2 == 1
+	}
+	openingIndex := 0
+	var opening []Move
+	if len(req.Openings) > 0 {
+		openingIndex = slot % len(req.Openings)
+		opening = req.Openings[openingIndex]
+	}
+	game := SimulateRequest{
+		Opening:         opening,
+		BlackHeuristics: req.BlackHeuristics,
+		WhiteHeuristics: req.WhiteHeuristics,
+		BlackSearch:     req.BlackSearch,
+		WhiteSearch:     req.WhiteSearch,
+		MoveBudgetMs:    req.MoveBudgetMs,
+		Depth:           req.Depth,
+		BlackDepth:      req.BlackDepth,
+		WhiteDepth:      req.WhiteDepth,
+		MaxMoves:        req.MaxMoves,
+		MaxNodes:        req.MaxNodes,
+		DisableCache:    req.DisableCache,
+	}
+	if swapped {
+		game.BlackHeuristics, game.WhiteHeuristics = req.WhiteHeuristics, req.BlackHeuristics
+		game.BlackSearch, game.WhiteSearch = req.WhiteSearch, req.BlackSearch
+		game.BlackDepth, game.WhiteDepth = req.WhiteDepth, req.BlackDepth
+	}
+	return game, openingIndex, swapped
+}
+
+// Validate checks the search limits shared by the games of the batch.
+func (req SimulateBatchRequest) Validate() error {
+	game, _, _ := simulateBatchGameRequest(req, 0)
+	return game.validate()
+}
+
+// SimulateBatch plays the requested games across a worker pool, calling emit
+// (serialized) as each game finishes. It stops handing out games once ctx is
+// cancelled and returns the aggregate of the games that completed.
+func SimulateBatch(ctx context.Context, settings GameSettings, req SimulateBatchRequest, emit func(SimulateBatchGame)) SimulateBatchSummary {
+	start := time.Now()
+	games := simulateBatchSize(req)
+	workers := simulateBatchWorkers(req.Workers, games, runtime.NumCPU())
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var summary SimulateBatchSummary
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				gameReq, openingIndex, swapped := simulateBatchGameRequest(req, index)
+				result, err := Simulate(ctx, settings, gameReq)
+				if err != nil && ctx.Err() != nil {
+					continue
+				}
+				game := SimulateBatchGame{Index: index, OpeningIndex: openingIndex, Swapped: swapped, Result: result}
+				mu.Lock()
+				summary.Games++
+				if err != nil {
+					game.Error = err.Error()
+					summary.Errors++
+				} else {
+					game.Score = summary.record(result, swapped)
+				}
+				if emit != nil {
+					emit(game)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for index := 0; index < games; index++ {
+		select {
+		case indexes <- index:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(indexes)
+	wg.Wait()
+	played := summary.Games - summary.Errors
+	if played > 0 {
+		summary.ScoreRate = summary.Score / float64(played)
+	}
+	summary.ElapsedMs = float64(time.Since(start).Microseconds()) / 1000.0
+	return summary
+}
+
+func (s *SimulateBatchSummary) record(result SimulateResult, swapped bool) float64 {
+	firstColor := 1
+	if swapped {
+		firstColor = 2
+	}
+	score := 0.5
+	switch {
+	case result.Status == "running":
+		s.Unfinished++
+	case result.Winner == 0:
+		s.Draws++
+	case result.Winner == firstColor:
+		s.Wins++
+		score = 1
+	default:
+		s.Losses++
+		score = 0
+	}
+	switch result.Winner {
+	case 1:
+		s.BlackWins++
+	case 2:
+		s.WhiteWins++
+	}
+	s.Score += score
+	return score
+}
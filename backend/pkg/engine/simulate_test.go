@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSimulateFinishesImmediateWin(t *testing.T) {
+	settings := DefaultGameSettings()
+	opening := []Move{}
+	for i := 0; i < 4; i++ {
+		opening = append(opening, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	result, err := Simulate(context.Background(), settings, SimulateRequest{Opening: opening, Depth: 2, MoveBudgetMs: 200, MaxMoves: 4})
+	if err != nil {
+		t.Fatalf("unexpected simulate error: %v", err)
+	}
+	if result.Status != "black_won" || result.Winner != 1 {
+		t.Fatalf("expected black to complete the open four, got %s (%d moves)", result.Status, len(result.Moves))
+	}
+	if len(result.Moves) != len(opening)+1 {
+		t.Fatalf("expected one engine move after the opening, got %d moves", len(result.Moves))
+	}
+	last := result.Moves[len(result.Moves)-1]
+	if !last.IsAi || result.Moves[0].IsAi {
+		t.Fatalf("expected only engine moves to be flagged is_ai, got %+v", result.Moves)
+	}
+}
+
+func TestSimulateRejectsIllegalOpening(t *testing.T) {
+	settings := DefaultGameSettings()
+	if _, err := Simulate(context.Background(), settings, SimulateRequest{Opening: []Move{{X: 9, Y: 9}, {X: 9, Y: 9}}}); err == nil {
+		t.Fatalf("expected error for an opening replaying onto an occupied cell")
+	}
+}
+
+func TestSimulateRejectsNegativeNodeLimit(t *testing.T) {
+	_, err := Simulate(context.Background(), DefaultGameSettings(), SimulateRequest{MaxNodes: -1})
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "max_nodes" {
+		t.Fatalf("expected a max_nodes field error, got %v", err)
+	}
+}
+
+func TestSimulateBatchAlternatesColorsAndAggregates(t *testing.T) {
+	settings := DefaultGameSettings()
+	opening := []Move{}
+	for i := 0; i < 4; i++ {
+		opening = append(opening, Move{X: 5 + i, Y: 5}, Move{X: 5 + i, Y: 12})
+	}
+	req := SimulateBatchRequest{
+		Openings:        [][]Move{opening},
+		AlternateColors: true,
+		Depth:           2,
+		MoveBudgetMs:    200,
+		MaxMoves:        4,
+		Workers:         2,
+	}
+	seen := map[int]bool{}
+	summary := SimulateBatch(context.Background(), settings, req, func(game SimulateBatchGame) {
+		seen[game.Index] = true
+		if game.Swapped != (game.Index == 1) {
+			t.Fatalf("expected only game 1 to be swapped, got %+v", game)
+		}
+	})
+	if len(seen) != 2 || summary.Games != 2 {
+		t.Fatalf("expected two games, got %d emitted and summary %+v", len(seen), summary)
+	}
+	if summary.BlackWins != 2 || summary.Wins != 1 || summary.Losses != 1 {
+		t.Fatalf("expected black to win both games split across configs, got %+v", summary)
+	}
+	if summary.ScoreRate != 0.5 {
+		t.Fatalf("expected a 0.5 score rate, got %v", summary.ScoreRate)
+	}
+}
+
+func TestSimulateBatchWorkersClamp(t *testing.T) {
+	if got := simulateBatchWorkers(0, 10, 8); got != 1 {
+		t.Fatalf("expected default of one worker, got %d", got)
+	}
+	if got := simulateBatchWorkers(16, 10, 4); got != 4 {
+		t.Fatalf("expected workers capped at cpu count, got %d", got)
+	}
+	if got := simulateBatchWorkers(8, 3, 8); got != 3 {
+		t.Fatalf("expected workers capped at game count, got %d", got)
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Target Elo scale of the strength limiter: the engine plays at full
+// strength from strengthFullElo and most loosely at strengthMinElo.
+const (
+	strengthFullElo = 2400
+	strengthMinElo  = 800
+)
+
+// strengthLimit is how much the engine weakens itself for a target Elo:
+// root scores get gaussian noise of Noise standard deviation, and the move
+// is drawn among the TopK best noisy scores, favouring the better ones.
+// Moves scored more than Margin below the best one are never picked, so a
+// won or lost position is not thrown away.
+type strengthLimit struct {
+	Noise  float64
+	TopK   int
+	Margin float64
+}
+
+func strengthLimitFor(targetElo int) (strengthLimit, bool) {
+	if targetElo <= 0 || targetElo >= strengthFullElo {
+		return strengthLimit{}, false
+	}
+	weakness := float64(strengthFullElo-targetElo) / float64(strengthFullElo-strengthMinElo)
+	if weakness > 1 {
+		weakness = 1
+	}
+	return strengthLimit{
+		// An open three at full weakness, so a weak engine confuses
+		// developing moves but still sees fours.
+		Noise:  20000 * weakness,
+		TopK:   1 + int(math.Round(5*weakness)),
+		Margin: 60000 * weakness,
+	}, true
+}
+
+// maybeSelectLimitedMove replaces currentBest by a plausible weaker move
+// when the config sets a target Elo.
+func maybeSelectLimitedMove(scores []float64, state GameState, rules Rules, settings AIScoreSettings, currentBest Move, rng *rand.Rand) (Move, bool) {
+	limit, ok := strengthLimitFor(settings.Config.AiTargetElo)
+	if !ok || !currentBest.IsValid(settings.BoardSize) {
+		return Move{}, false
+	}
+	move, ok := pickLimitedMove(scores, state, rules, settings.BoardSize, limit, rng)
+	if !ok || move == currentBest {
+		return Move{}, false
+	}
+	return move, true
+}
+
+func pickLimitedMove(scores []float64, state GameState, rules Rules, boardSize int, limit strengthLimit, rng *rand.Rand) (Move, bool) {
+	if boardSize <= 0 || len(scores) < boardSize*boardSize {
+		return Move{}, false
+	}
+	// Scores are from black's side; flip them so higher is better for the
+	// side to move.
+	sign := 1.0
+	if state.ToMove == PlayerWhite {
+		sign = -1
+	}
+	type limitedCandidate struct {
+		move  Move
+		score float64
+		noisy float64
+	}
+	var candidates []limitedCandidate
+	best := math.Inf(-1)
+	for y := 0; y < boardSize; y++ {
+		for x := 0; x < boardSize; x++ {
+			score := scores[y*boardSize+x]
+			if score == illegalScore || math.IsInf(score, 0) || math.IsNaN(score) {
+				continue
+			}
+			move := Move{X: x, Y: y}
+			if ok, _ := rules.IsLegal(state, move, state.ToMove); !ok {
+				continue
+			}
+			score *= sign
+			candidates = append(candidates, limitedCandidate{move: move, score: score})
+			if score > best {
+				best = score
+			}
+		}
+	}
+	kept := candidates[:0]
+	for _, cand := range candidates {
+		if best-cand.score > limit.Margin {
+			continue
+		}
+		cand.noisy = cand.score + rng.NormFloat64()*limit.Noise
+		kept = append(kept, cand)
+	}
+	if len(kept) == 0 {
+		return Move{}, false
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].noisy > kept[j].noisy })
+	if len(kept) > limit.TopK {
+		kept = kept[:limit.TopK]
+	}
+	// Favour the better noisy scores: the i-th move weighs 1/(i+1).
+	total := 0.0
+	for i := range kept {
+		total += 1 / float64(i+1)
+	}
+	pick := rng.Float64() * total
+	for i, cand := range kept {
+		pick -= 1 / float64(i+1)
+		if pick <= 0 {
+			return cand.move, true
+		}
+	}
+	return kept[len(kept)-1].move, true
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestStrengthLimitForScalesWithElo(t *testing.T) {
+	if _, ok := strengthLimitFor(0); ok {
+		t.Fatalf("expected no limit without a target Elo")
+	}
+	if _, ok := strengthLimitFor(strengthFullElo); ok {
+		t.Fatalf("expected no limit at full strength")
+	}
+	strong, _ := strengthLimitFor(2000)
+	weak, _ := strengthLimitFor(900)
+	if weak.Noise <= strong.Noise || weak.TopK < strong.TopK || weak.Margin <= strong.Margin {
+		t.Fatalf("expected a lower Elo to weaken more: strong %+v weak %+v", strong, weak)
+	}
+}
+
+func TestPickLimitedMoveStaysWithinMargin(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	rules := NewRules(settings)
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerWhite
+	state.Board.Set(4, 4, CellBlack)
+
+	scores := make([]float64, 81)
+	for i := range scores {
+		scores[i] = illegalScore
+	}
+	// White minimises: (3,3) wins, (5,5) is close, (2,2) loses.
+	scores[3*9+3] = -winScore
+	scores[5*9+5] = 100
+	scores[2*9+2] = winScore
+	limit, _ := strengthLimitFor(strengthMinElo)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		move, ok := pickLimitedMove(scores, state, rules, 9, limit, rng)
+		if !ok || move != (Move{X: 3, Y: 3}) {
+			t.Fatalf("expected the winning move to be kept, got %v", move)
+		}
+	}
+
+	scores[3*9+3] = 0
+	seen := map[Move]bool{}
+	for i := 0; i < 200; i++ {
+		move, _ := pickLimitedMove(scores, state, rules, 9, limit, rng)
+		seen[move] = true
+	}
+	if !seen[Move{X: 3, Y: 3}] || !seen[Move{X: 5, Y: 5}] || seen[Move{X: 2, Y: 2}] {
+		t.Fatalf("expected close moves to be mixed and the losing move skipped, got %v", seen)
+	}
+}
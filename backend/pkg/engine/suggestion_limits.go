@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSuggestDepth is the suggestion depth when AiSuggestDepth is unset.
+const defaultSuggestDepth = 10
+
+func suggestDepth(config Config) int {
+	if config.AiSuggestDepth <= 0 {
+		return defaultSuggestDepth
+	}
+	return config.AiSuggestDepth
+}
+
+// suggestionLimiter caps how many suggestion searches start per minute
+// across every game.
+type suggestionLimiter struct {
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+var suggestionStarts = &suggestionLimiter{}
+
+// pruneLocked drops the starts older than a minute before now.
+func (l *suggestionLimiter) pruneLocked(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	kept := 0
+	for kept < len(l.starts) && !l.starts[kept].After(cutoff) {
+		kept++
+	}
+	l.starts = l.starts[kept:]
+}
+
+// available reports whether a search could start at now under limit (no
+// limit when zero or less).
+func (l *suggestionLimiter) available(now time.Time, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(now)
+	return len(l.starts) < limit
+}
+
+// take records a search starting at now and reports whether limit allowed
+// it.
+func (l *suggestionLimiter) take(now time.Time, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(now)
+	if len(l.starts) >= limit {
+		return false
+	}
+	l.starts = append(l.starts, now)
+	return true
+}
+
+// suggestionsSuspended reports whether suggestions should give way: with
+// AiSuggestYield set, while a live engine move or a backlog board is being
+// searched on the shared workers.
+func suggestionsSuspended(config Config) bool {
+	if !config.AiSuggestYield {
+		return false
+	}
+	return SearchPool.Running(searchJobLive) > 0 || SearchPool.Running(searchJobBacklog) > 0
+}
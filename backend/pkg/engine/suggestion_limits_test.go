@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuggestionLimiterCapsStartsPerMinute(t *testing.T) {
+	limiter := &suggestionLimiter{}
+	now := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		if !limiter.take(now.Add(time.Duration(i)*time.Second), 3) {
+			t.Fatalf("start %d refused under the cap", i)
+		}
+	}
+	if limiter.available(now.Add(10*time.Second), 3) || limiter.take(now.Add(10*time.Second), 3) {
+		t.Fatalf("fourth start within the minute allowed")
+	}
+	if !limiter.take(now.Add(61*time.Second), 3) {
+		t.Fatalf("start refused once the first one left the window")
+	}
+	if !limiter.take(now, 0) {
+		t.Fatalf("start refused without a cap")
+	}
+}
+
+func TestSuggestionsYieldToLiveSearches(t *testing.T) {
+	config := DefaultConfig()
+	config.AiSuggestYield = true
+	started := make(chan struct{})
+	release := make(chan struct{})
+	SearchPool.Submit(searchJobLive, "test", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+	if !suggestionsSuspended(config) {
+		t.Fatalf("suggestions not suspended during a live search")
+	}
+	config.AiSuggestYield = false
+	if suggestionsSuspended(config) {
+		t.Fatalf("suggestions suspended with AiSuggestYield off")
+	}
+}
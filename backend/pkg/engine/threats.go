@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ThreatOpenFour  = "open_four"
+	ThreatFour      = "four"
+	ThreatOpenThree = "open_three"
+	ThreatCapture   = "capture"
+)
+
+// Threat is a shape one side can turn into a win or a capture. Stones are
+// the stones forming it (for a capture, the two stones at risk) and Cells
+// the empty cells that complete it, which are also where it is defended.
+type Threat struct {
+	Player int    `json:"player"`
+	Kind   string `json:"kind"`
+	Stones []Move `json:"stones"`
+	Cells  []Move `json:"cells"`
+}
+
+func (t Threat) key() string {
+	var b strings.Builder
+	b.WriteString(t.Kind)
+	for _, stone := range t.Stones {
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(stone.X))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(stone.Y))
+	}
+	return b.String()
+}
+
+// ThreatReport lists the threats on the board after a move, and which of
+// them the move created for the mover or removed for the opponent.
+type ThreatReport struct {
+	GameID   uint64   `json:"game_id"`
+	Ply      int      `json:"ply"`
+	LastMove *Move    `json:"last_move,omitempty"`
+	Mover    int      `json:"mover"`
+	Black    []Threat `json:"black"`
+	White    []Threat `json:"white"`
+	Created  []Threat `json:"created"`
+	Blocked  []Threat `json:"blocked"`
+}
+
+// FindThreats lists the fours, open threes and capture threats of player.
+func FindThreats(board Board, rules Rules, player PlayerColor) []Threat {
+	size := board.Size()
+	threats := []Threat{}
+	index := make(map[string]int)
+	add := func(threat Threat) {
+		key := threat.key()
+		if i, ok := index[key]; ok {
+			threats[i].Cells = mergeMoves(threats[i].Cells, threat.Cells)
+			return
+		}
+		index[key] = len(threats)
+		threats = append(threats, threat)
+	}
+	toMove := func(idx int) Move {
+		return Move{X: idx % size, Y: idx / size}
+	}
+
+	var buf []byte
+	for _, line := range getLinesForSize(size) {
+		buf = buildTokensInto(board, line, player, buf)
+		tokens := buf[1 : len(buf)-1]
+		// Fours: five cells holding four stones and one empty cell.
+		for start := 0; start+5 <= len(tokens); start++ {
+			stones, cells, ok := windowShape(tokens[start:start+5], line[start:start+5], 4)
+			if ok {
+				add(threatFromWindow(player, ThreatFour, stones, cells, toMove))
+			}
+		}
+		// Open threes: three stones and a gap inside a window whose ends
+		// are empty, so they can still become an open four.
+		for start := 0; start+6 <= len(tokens); start++ {
+			if tokens[start] != '.' || tokens[start+5] != '.' {
+				continue
+			}
+			stones, cells, ok := windowShape(tokens[start+1:start+5], line[start+1:start+5], 3)
+			if ok {
+				cells = append(cells, line[start], line[start+5])
+				add(threatFromWindow(player, ThreatOpenThree, stones, cells, toMove))
+			}
+		}
+	}
+	for i := range threats {
+		if threats[i].Kind == ThreatFour && len(threats[i].Cells) >= 2 {
+			threats[i].Kind = ThreatOpenFour
+		}
+	}
+
+	cell := CellFromPlayer(player)
+	probe := board.Clone()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if !board.IsEmpty(x, y) {
+				continue
+			}
+			move := Move{X: x, Y: y}
+			probe.Set(x, y, cell)
+			captures := rules.FindCaptures(probe, move, cell)
+			probe.Remove(x, y)
+			for i := 0; i+1 < len(captures); i += 2 {
+				stones := []Move{captures[i], captures[i+1]}
+				sortMoves(stones)
+				add(Threat{Player: PlayerToInt(player), Kind: ThreatCapture, Stones: stones, Cells: []Move{move}})
+			}
+		}
+	}
+
+	sort.SliceStable(threats, func(i, j int) bool {
+		return threatRank(threats[i].Kind) < threatRank(threats[j].Kind)
+	})
+	return threats
+}
+
+// windowShape reports whether tokens hold exactly want of the player's
+// stones, no opponent stone, and empty cells for the rest.
+func windowShape(tokens []byte, cells []int, want int) ([]int, []int, bool) {
+	var stones, empty []int
+	for i, token := range tokens {
+		switch token {
+		case 'M':
+			stones = append(stones, cells[i])
+		case '.':
+			empty = append(empty, cells[i])
+		default:
+			return nil, nil, false
+		}
+	}
+	return stones, empty, len(stones) == want
+}
+
+func threatFromWindow(player PlayerColor, kind string, stones, cells []int, toMove func(int) Move) Threat {
+	threat := Threat{Player: PlayerToInt(player), Kind: kind}
+	for _, idx := range stones {
+		threat.Stones = append(threat.Stones, toMove(idx))
+	}
+	for _, idx := range cells {
+		threat.Cells = append(threat.Cells, toMove(idx))
+	}
+	sortMoves(threat.Stones)
+	sortMoves(threat.Cells)
+	return threat
+}
+
+func threatRank(kind string) int {
+	switch kind {
+	case ThreatOpenFour:
+		return 0
+	case ThreatFour:
+		return 1
+	case ThreatOpenThree:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func sortMoves(moves []Move) {
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Y != moves[j].Y {
+			return moves[i].Y < moves[j].Y
+		}
+		return moves[i].X < moves[j].X
+	})
+}
+
+func mergeMoves(a, b []Move) []Move {
+	merged := append([]Move(nil), a...)
+	for _, move := range b {
+		found := false
+		for _, existing := range merged {
+			if existing.Equals(move) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, move)
+		}
+	}
+	sortMoves(merged)
+	return merged
+}
+
+// ThreatTracker remembers the threats after the last reported move so the
+// next report can tell which threats a move created or blocked.
+type ThreatTracker struct {
+	mu     sync.Mutex
+	gameID uint64
+	ply    int
+	black  []Threat
+	white  []Threat
+}
+
+// Update reports the threats of state, the position after ply moves of
+// game gameID. Created and Blocked are only filled in when the previous
+// report was for an earlier position of the same game.
+func (t *ThreatTracker) Update(gameID uint64, ply int, state GameState, settings GameSettings) ThreatReport {
+	rules := NewRules(settings)
+	report := ThreatReport{
+		GameID:  gameID,
+		Ply:     ply,
+		Black:   FindThreats(state.Board, rules, PlayerBlack),
+		White:   FindThreats(state.Board, rules, PlayerWhite),
+		Created: []Threat{},
+		Blocked: []Threat{},
+	}
+	if state.HasLastMove {
+		move := state.LastMove
+		report.LastMove = &move
+		if cell := state.Board.At(move.X, move.Y); cell != CellEmpty {
+			report.Mover = CellToInt(cell)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.gameID == gameID && t.ply < ply && report.Mover != 0 {
+		mine, theirs := report.Black, report.White
+		mineBefore, theirsBefore := t.black, t.white
+		if report.Mover == 2 {
+			mine, theirs = theirs, mine
+			mineBefore, theirsBefore = theirsBefore, mineBefore
+		}
+		report.Created = threatsMissingFrom(mine, mineBefore)
+		report.Blocked = threatsMissingFrom(theirsBefore, theirs)
+	}
+	t.gameID = gameID
+	t.ply = ply
+	t.black = report.Black
+	t.white = report.White
+	return report
+}
+
+// threatsMissingFrom returns the threats of list that other does not hold.
+func threatsMissingFrom(list, other []Threat) []Threat {
+	keys := make(map[string]struct{}, len(other))
+	for _, threat := range other {
+		keys[threat.key()] = struct{}{}
+	}
+	missing := []Threat{}
+	for _, threat := range list {
+		if _, ok := keys[threat.key()]; !ok {
+			missing = append(missing, threat)
+		}
+	}
+	return missing
+}
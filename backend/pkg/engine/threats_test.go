@@ -0,0 +1,62 @@
+package engine
+
+import "testing"
+
+func TestFindThreatsClassifiesShapes(t *testing.T) {
+	settings := DefaultGameSettings()
+	rules := NewRules(settings)
+	board := NewBoard(settings.BoardSize)
+	for x := 5; x < 8; x++ {
+		board.Set(x, 5, CellBlack)
+	}
+	for x := 5; x < 9; x++ {
+		board.Set(x, 10, CellBlack)
+	}
+	board.Set(4, 10, CellWhite)
+	board.Set(12, 2, CellWhite)
+	board.Set(13, 2, CellWhite)
+	board.Set(14, 2, CellBlack)
+
+	kinds := make(map[string]int)
+	for _, threat := range FindThreats(board, rules, PlayerBlack) {
+		kinds[threat.Kind]++
+		if threat.Kind == ThreatFour && (len(threat.Cells) != 1 || threat.Cells[0] != (Move{X: 9, Y: 10})) {
+			t.Fatalf("closed four should only complete at (9,10), got %v", threat.Cells)
+		}
+		if threat.Kind == ThreatCapture && threat.Cells[0] != (Move{X: 11, Y: 2}) {
+			t.Fatalf("capture should be played at (11,2), got %v", threat.Cells)
+		}
+	}
+	if kinds[ThreatOpenThree] != 1 || kinds[ThreatFour] != 1 || kinds[ThreatCapture] != 1 || kinds[ThreatOpenFour] != 0 {
+		t.Fatalf("unexpected threats %v", kinds)
+	}
+}
+
+func TestThreatTrackerReportsCreatedAndBlocked(t *testing.T) {
+	settings := DefaultGameSettings()
+	state := DefaultGameState(settings)
+	tracker := &ThreatTracker{}
+	ply := 0
+	step := func(move Move) ThreatReport {
+		ply++
+		applyMove(&state, NewRules(settings), move, state.ToMove)
+		return tracker.Update(1, ply, state, settings)
+	}
+	step(Move{X: 5, Y: 5})
+	step(Move{X: 0, Y: 0})
+	step(Move{X: 6, Y: 5})
+	step(Move{X: 0, Y: 18})
+	created := step(Move{X: 7, Y: 5})
+	if len(created.Created) != 1 || created.Created[0].Kind != ThreatOpenThree || created.Mover != 1 {
+		t.Fatalf("expected black to create an open three, got %+v", created.Created)
+	}
+	blocked := step(Move{X: 8, Y: 5})
+	if len(blocked.Blocked) != 1 || blocked.Blocked[0].Kind != ThreatOpenThree || blocked.Mover != 2 {
+		t.Fatalf("expected white to block the open three, got %+v", blocked.Blocked)
+	}
+
+	other := tracker.Update(2, 1, state, settings)
+	if len(other.Created) != 0 || len(other.Blocked) != 0 {
+		t.Fatalf("a new game should not be compared with the previous one")
+	}
+}
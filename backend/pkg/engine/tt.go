@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"math"
@@ -64,6 +64,9 @@ type TranspositionTable struct {
 	stripeLocks []sync.RWMutex
 	stripeMask  uint64
 	gen         atomic.Uint32
+	// used counts the valid entries, kept up to date by every write so
+	// Count does not scan the table.
+	used atomic.Int64
 }
 
 func NewTranspositionTable(size uint64, buckets int) *TranspositionTable {
@@ -112,6 +115,7 @@ func (tt *TranspositionTable) Clear() {
 	for i := range tt.entries {
 		tt.entries[i] = TTEntry{}
 	}
+	tt.used.Store(0)
 	tt.gen.Store(1)
 }
 
@@ -143,6 +147,21 @@ func (tt *TranspositionTable) Probe(key uint64, heuristicHash uint64) (TTEntry,
 	return TTEntry{}, false
 }
 
+// Peek is Probe without touching the entry's hit count or age.
+func (tt *TranspositionTable) Peek(key uint64, heuristicHash uint64) (TTEntry, bool) {
+	stripe := tt.stripeIndexForKey(key)
+	tt.stripeLocks[stripe].RLock()
+	defer tt.stripeLocks[stripe].RUnlock()
+	start := tt.bucketIndex(key)
+	for i := 0; i < tt.buckets; i++ {
+		entry := tt.entries[start+i]
+		if entry.Valid && entry.Key == key && entry.HeuristicHash == heuristicHash {
+			return entry, true
+		}
+	}
+	return TTEntry{}, false
+}
+
 func (tt *TranspositionTable) Store(key uint64, heuristicHash uint64, depth int, value float64, flag TTFlag, best Move, meta TTMeta) (replaced bool, overwrote bool) {
 	stripe := tt.stripeIndexForKey(key)
 	tt.stripeLocks[stripe].Lock()
@@ -213,6 +232,7 @@ func (tt *TranspositionTable) Store(key uint64, heuristicHash uint64, depth int,
 			GenLastUsed:   gen,
 			Valid:         true,
 		}
+		tt.used.Add(1)
 		return false, false
 	}
 
@@ -264,9 +284,24 @@ func (tt *TranspositionTable) DeleteByHeuristicHash(heuristicHash uint64) int {
 		tt.entries[i] = TTEntry{}
 		deleted++
 	}
+	tt.used.Add(-int64(deleted))
 	return deleted
 }
 
+// CountByHeuristicHash counts the valid entries stored under each
+// heuristic hash.
+func (tt *TranspositionTable) CountByHeuristicHash() map[uint64]int {
+	tt.lockAllStripesRead()
+	defer tt.unlockAllStripesRead()
+	counts := map[uint64]int{}
+	for i := range tt.entries {
+		if tt.entries[i].Valid {
+			counts[tt.entries[i].HeuristicHash]++
+		}
+	}
+	return counts
+}
+
 func (tt *TranspositionTable) DeleteByKey(key uint64) bool {
 	stripe := tt.stripeIndexForKey(key)
 	tt.stripeLocks[stripe].Lock()
@@ -280,6 +315,7 @@ func (tt *TranspositionTable) DeleteByKey(key uint64) bool {
 			continue
 		}
 		tt.entries[idx] = TTEntry{}
+		tt.used.Add(-1)
 		deleted = true
 	}
 	return deleted
@@ -322,16 +358,9 @@ func (tt *TranspositionTable) TopEntriesByHits(offset int, limit int) ([]TTEntry
 	return valid[offset:end], total
 }
 
+// Count returns the number of valid entries without scanning the table.
 func (tt *TranspositionTable) Count() int {
-	tt.lockAllStripesRead()
-	defer tt.unlockAllStripesRead()
-	count := 0
-	for i := range tt.entries {
-		if tt.entries[i].Valid {
-			count++
-		}
-	}
-	return count
+	return int(tt.used.Load())
 }
 
 func (tt *TranspositionTable) Capacity() int {
@@ -388,13 +417,37 @@ func (tt *TranspositionTable) snapshotEntries() []TTEntry {
 	return entries
 }
 
-func (tt *TranspositionTable) loadEntries(entries []TTEntry) {
-	tt.lockAllStripes()
-	defer tt.unlockAllStripes()
-	if len(entries) > len(tt.entries) {
-		entries = entries[:len(tt.entries)]
+// restoreEntry puts a persisted entry in its bucket: over the same entry if
+// it is not deeper, else in a free slot, else over the shallowest entry
+// when it is deeper. It returns false when the bucket has no room for it.
+func (tt *TranspositionTable) restoreEntry(entry TTEntry) bool {
+	stripe := tt.stripeIndexForKey(entry.Key)
+	tt.stripeLocks[stripe].Lock()
+	defer tt.stripeLocks[stripe].Unlock()
+	start := tt.bucketIndex(entry.Key)
+	slot, same := -1, false
+	for i := 0; i < tt.buckets; i++ {
+		idx := start + i
+		current := tt.entries[idx]
+		if current.Valid && current.Key == entry.Key && current.HeuristicHash == entry.HeuristicHash {
+			if current.Depth > entry.Depth {
+				return false
+			}
+			slot, same = idx, true
+			break
+		}
+		if slot == -1 || (tt.entries[slot].Valid && (!current.Valid || current.Depth < tt.entries[slot].Depth)) {
+			slot = idx
+		}
+	}
+	if !same && tt.entries[slot].Valid && tt.entries[slot].Depth >= entry.Depth {
+		return false
+	}
+	if !tt.entries[slot].Valid && entry.Valid {
+		tt.used.Add(1)
 	}
-	copy(tt.entries[:len(entries)], entries)
+	tt.entries[slot] = entry
+	return true
 }
 
 func replacementClass(entry TTEntry, depth int, flag TTFlag, gen uint32) int {
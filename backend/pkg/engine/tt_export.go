@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	TTExportCSV   = "csv"
+	TTExportJSONL = "jsonl"
+)
+
+// TTExportOptions selects the entries ExportTT writes. HeuristicHash, in
+// FormatHeuristicHash form, keeps the entries of one weight set; entries
+// whose position is not known are left out unless IncludeUnknown.
+type TTExportOptions struct {
+	MinDepth       int
+	HeuristicHash  string
+	IncludeUnknown bool
+}
+
+// TTExportRow is one exact transposition table entry. Position is the
+// canonical position notation of the position it was stored for (see
+// PositionNotation), or "" when no known game reached it. Score is from
+// Black's side, as the table stores it; MatePlies is the distance to a
+// forced win or loss. BestMove is on the canonical orientation, and nil when
+// it cannot be placed on it.
+type TTExportRow struct {
+	Key           string `json:"key"`
+	HeuristicHash string `json:"heuristic_hash"`
+	BoardSize     int    `json:"board_size,omitempty"`
+	Position      string `json:"position"`
+	Depth         int    `json:"depth"`
+	Score         int32  `json:"score"`
+	MatePlies     int    `json:"mate_plies,omitempty"`
+	BestMove      *Move  `json:"best_move"`
+}
+
+var ttExportCSVHeader = []string{"key", "heuristic_hash", "board_size", "position", "depth", "score", "mate_plies", "best_x", "best_y"}
+
+// PositionNotation writes a position on its canonical orientation, the one
+// shared by its rotations and reflections: the rows from the top separated
+// by "/", black stones as "x", white ones as "o" and runs of empty cells as
+// their length, then the side to move ("b" or "w") and the stones captured
+// by black and by white, e.g. "15/15/6xo7/... b 0 2".
+func PositionNotation(state GameState) string {
+	size := state.Board.Size()
+	transform := symmetryTransforms[canonicalSymIndex(state.HashSym)]
+	canonical := NewBoard(size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if cell := state.Board.At(x, y); cell != CellEmpty {
+				cx, cy := transformCoord(x, y, size, transform)
+				canonical.Set(cx, cy, cell)
+			}
+		}
+	}
+	var b strings.Builder
+	for y := 0; y < size; y++ {
+		if y > 0 {
+			b.WriteByte('/')
+		}
+		empty := 0
+		for x := 0; x < size; x++ {
+			cell := canonical.At(x, y)
+			if cell == CellEmpty {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			if cell == CellBlack {
+				b.WriteByte('x')
+			} else {
+				b.WriteByte('o')
+			}
+		}
+		if empty > 0 {
+			b.WriteString(strconv.Itoa(empty))
+		}
+	}
+	side := "b"
+	if state.ToMove == PlayerWhite {
+		side = "w"
+	}
+	fmt.Fprintf(&b, " %s %d %d", side, state.CapturedBlack, state.CapturedWhite)
+	return b.String()
+}
+
+// exactEntries returns the valid exact entries.
+func (tt *TranspositionTable) exactEntries() []TTEntry {
+	tt.lockAllStripesRead()
+	defer tt.unlockAllStripesRead()
+	var entries []TTEntry
+	for i := range tt.entries {
+		if tt.entries[i].Valid && tt.entries[i].Flag == TTExact {
+			entries = append(entries, tt.entries[i])
+		}
+	}
+	return entries
+}
+
+// ttExportRows turns the exact entries of tt into rows, deepest first.
+// Table keys are hashes, so positions are recovered by replaying records:
+// the first position found for a key gives the row its position, and the
+// best move, stored in the orientation the position was searched in, is
+// mapped onto the canonical one when it lands on an empty cell there.
+func ttExportRows(tt *TranspositionTable, records []GameRecord, opts TTExportOptions) []TTExportRow {
+	rows := map[uint64][]*TTExportRow{}
+	var order []*TTExportRow
+	for _, entry := range tt.exactEntries() {
+		hash := FormatHeuristicHash(entry.HeuristicHash)
+		if entry.Depth < opts.MinDepth || (opts.HeuristicHash != "" && hash != opts.HeuristicHash) {
+			continue
+		}
+		row := &TTExportRow{Key: fmt.Sprintf("0x%016x", entry.Key), HeuristicHash: hash, Depth: entry.Depth, Score: entry.Score}
+		if plies, ok := mateDistance(entry.ScoreFloat()); ok {
+			row.MatePlies = plies
+		}
+		if entry.BestMove.X >= 0 && entry.BestMove.Y >= 0 {
+			best := Move{X: entry.BestMove.X, Y: entry.BestMove.Y}
+			row.BestMove = &best
+		}
+		rows[entry.Key] = append(rows[entry.Key], row)
+		order = append(order, row)
+	}
+	unresolved := len(rows)
+	for _, record := range records {
+		if unresolved == 0 {
+			break
+		}
+		state, err := renderStartState(record.Settings, record.Start)
+		if err != nil {
+			continue
+		}
+		rules := NewRules(record.Settings)
+		state.Status = StatusRunning
+		size := record.Settings.BoardSize
+		for ply := 0; ply <= len(record.Entries) && unresolved > 0; ply++ {
+			if ply > 0 {
+				entry := record.Entries[ply-1]
+				if !applyMove(&state, rules, entry.Move, entry.Player) {
+					break
+				}
+			}
+			key := ttKeyFor(state, size)
+			found := rows[key]
+			if len(found) == 0 || found[0].BoardSize != 0 {
+				continue
+			}
+			notation := PositionNotation(state)
+			transform := symmetryTransforms[canonicalSymIndex(state.HashSym)]
+			for _, row := range found {
+				row.BoardSize = size
+				row.Position = notation
+				if row.BestMove == nil {
+					continue
+				}
+				if !row.BestMove.IsValid(size) || !state.Board.IsEmpty(row.BestMove.X, row.BestMove.Y) {
+					row.BestMove = nil
+					continue
+				}
+				cx, cy := transformCoord(row.BestMove.X, row.BestMove.Y, size, transform)
+				row.BestMove = &Move{X: cx, Y: cy}
+			}
+			unresolved--
+		}
+	}
+	result := make([]TTExportRow, 0, len(order))
+	for _, row := range order {
+		if row.Position == "" {
+			if !opts.IncludeUnknown {
+				continue
+			}
+			row.BestMove = nil
+		}
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Depth != result[j].Depth {
+			return result[i].Depth > result[j].Depth
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// ExportTT writes the exact entries of tt as CSV, with a header row, or as
+// JSON lines, recovering their positions from extra (typically the live
+// game and its archive) and the stored games. It returns how many rows it
+// wrote.
+func ExportTT(w io.Writer, format string, tt *TranspositionTable, extra []GameRecord, opts TTExportOptions) (int, error) {
+	if format != TTExportCSV && format != TTExportJSONL {
+		return 0, fieldErrorf("format", "format must be %s or %s", TTExportCSV, TTExportJSONL)
+	}
+	var rows []TTExportRow
+	if tt != nil {
+		rows = ttExportRows(tt, append(extra, storedGames.snapshot().Games...), opts)
+	}
+	if format == TTExportJSONL {
+		encoder := json.NewEncoder(w)
+		for i, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return i, err
+			}
+		}
+		return len(rows), nil
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ttExportCSVHeader); err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		record := []string{row.Key, row.HeuristicHash, "", row.Position, strconv.Itoa(row.Depth), strconv.Itoa(int(row.Score)), "", "", ""}
+		if row.BoardSize > 0 {
+			record[2] = strconv.Itoa(row.BoardSize)
+		}
+		if row.MatePlies > 0 {
+			record[6] = strconv.Itoa(row.MatePlies)
+		}
+		if row.BestMove != nil {
+			record[7], record[8] = strconv.Itoa(row.BestMove.X), strconv.Itoa(row.BestMove.Y)
+		}
+		if err := writer.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	writer.Flush()
+	return len(rows), writer.Error()
+}
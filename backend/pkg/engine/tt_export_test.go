@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportTTRecoversCanonicalPositions(t *testing.T) {
+	settings := DefaultGameSettings()
+	played := func(move Move) GameRecord {
+		return GameRecord{Settings: settings, Entries: []HistoryEntry{{Move: move, Player: PlayerBlack}}, Status: StatusRunning}
+	}
+	state, _, err := replayMoves(settings, []Move{{X: 3, Y: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := settings.BoardSize
+	hash := heuristicHashFromConfig(GetConfig())
+	tt := NewTranspositionTable(1024, 2)
+	tt.Store(ttKeyFor(state, size), hash, 6, 250, TTExact, Move{X: 4, Y: 4}, TTMeta{})
+	tt.Store(0xdead, hash, 9, -40, TTExact, Move{X: 1, Y: 1}, TTMeta{})
+	tt.Store(0xbeef, hash, 8, 10, TTLower, Move{X: 1, Y: 1}, TTMeta{})
+
+	// The mirrored game reaches the same canonical position.
+	rows := ttExportRows(tt, []GameRecord{played(Move{X: size - 1 - 3, Y: 4})}, TTExportOptions{})
+	if len(rows) != 1 || rows[0].Position != PositionNotation(state) || rows[0].Depth != 6 || rows[0].Score != 250 || rows[0].BoardSize != size {
+		t.Fatalf("expected the known exact entry alone, got %+v", rows)
+	}
+	if !strings.HasSuffix(rows[0].Position, " w 0 0") || strings.Count(rows[0].Position, "/") != size-1 {
+		t.Fatalf("unexpected notation %q", rows[0].Position)
+	}
+	if best := rows[0].BestMove; best == nil || !best.IsValid(size) {
+		t.Fatalf("expected a best move on the canonical board, got %+v", best)
+	}
+
+	rows = ttExportRows(tt, nil, TTExportOptions{IncludeUnknown: true, MinDepth: 7})
+	if len(rows) != 1 || rows[0].Key != "0x000000000000dead" || rows[0].Position != "" || rows[0].BestMove != nil {
+		t.Fatalf("expected the deep unknown entry, got %+v", rows)
+	}
+
+	var out bytes.Buffer
+	count, err := ExportTT(&out, TTExportCSV, tt, []GameRecord{played(Move{X: 3, Y: 4})}, TTExportOptions{})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if err != nil || count != 1 || len(lines) != 2 || !strings.HasPrefix(lines[0], "key,heuristic_hash,board_size,position") {
+		t.Fatalf("unexpected CSV export (%d rows, %v):\n%s", count, err, out.String())
+	}
+	if _, err := ExportTT(&out, "xml", tt, nil, TTExportOptions{}); err == nil {
+		t.Fatalf("expected an unknown format to be refused")
+	}
+}
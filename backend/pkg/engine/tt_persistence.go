@@ -0,0 +1,328 @@
+package engine
+
+import (
+	"encoding/gob"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var dockerCacheDir = "/cache_logs"
+
+// ttPersistenceVersion is written into every TT persistence file. Any change
+// to what a stored entry means (its layout, the score encoding, how mate
+// scores are rebased, the key or heuristic hash inputs) must bump it: files
+// of another version are not read. Files from before the header read as
+// version 0.
+const ttPersistenceVersion = 1
+
+type ttPersistenceSnapshot struct {
+	Version int
+	// HeuristicScheme and PositionScheme fingerprint how heuristic hashes and
+	// position keys were computed; entries written under other schemes could
+	// never be found again.
+	HeuristicScheme uint64
+	PositionScheme  uint64
+
+	Size    int
+	Buckets int
+	Entries []TTEntry
+
+	RootTransposeSize    int
+	RootTransposeBuckets int
+	RootTransposeEntries []RootTransposeEntry
+}
+
+// CacheLoadReport is what restoring one persisted cache did at startup.
+// Loaded counts the entries restored, Migrated those of them moved from a
+// table of another size; Skipped counts the entries
+// left out, by reason in SkipReasons.
+type CacheLoadReport struct {
+	Cache       string         `json:"cache"`
+	Path        string         `json:"path"`
+	Version     int            `json:"version"`
+	Loaded      int            `json:"loaded"`
+	Migrated    int            `json:"migrated"`
+	Skipped     int            `json:"skipped"`
+	SkipReasons map[string]int `json:"skip_reasons,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+func (r *CacheLoadReport) skip(reason string, count int) {
+	if count <= 0 {
+		return
+	}
+	if r.SkipReasons == nil {
+		r.SkipReasons = make(map[string]int)
+	}
+	r.Skipped += count
+	r.SkipReasons[reason] += count
+}
+
+var cacheLoadReports struct {
+	mu      sync.Mutex
+	reports []CacheLoadReport
+}
+
+func noteCacheLoad(report CacheLoadReport) {
+	if report.Error != "" {
+		log.Printf("[ai:cache] restored %s persistence: 0 entries (%s)", report.Cache, report.Error)
+	} else {
+		log.Printf("[ai:cache] restored %s persistence from %s (version %d): %d entries loaded, %d migrated, %d skipped %v",
+			report.Cache, report.Path, report.Version, report.Loaded, report.Migrated, report.Skipped, report.SkipReasons)
+	}
+	cacheLoadReports.mu.Lock()
+	defer cacheLoadReports.mu.Unlock()
+	for i, existing := range cacheLoadReports.reports {
+		if existing.Cache == report.Cache {
+			cacheLoadReports.reports[i] = report
+			return
+		}
+	}
+	cacheLoadReports.reports = append(cacheLoadReports.reports, report)
+}
+
+// CacheLoadReports returns what restoring each persisted cache did.
+func CacheLoadReports() []CacheLoadReport {
+	cacheLoadReports.mu.Lock()
+	defer cacheLoadReports.mu.Unlock()
+	return append([]CacheLoadReport(nil), cacheLoadReports.reports...)
+}
+
+func heuristicHashScheme() uint64 {
+	return heuristicHash(HeuristicConfig{})
+}
+
+func positionHashScheme() uint64 {
+	z := GetZobrist(19)
+	return z.cells[0] ^ z.side
+}
+
+// Entries that do not pass these checks come from another entry layout or
+// a damaged file.
+func validTTEntry(entry TTEntry) bool {
+	return entry.Depth >= 0 && entry.Flag <= TTUpper && validPersistedMove(entry.BestMove)
+}
+
+func validRootTransposeEntry(entry RootTransposeEntry) bool {
+	return entry.Depth >= 0 && entry.Flag <= TTUpper && validPersistedMove(entry.BestRel)
+}
+
+func validPersistedMove(move Move) bool {
+	return move.X >= -1 && move.Y >= -1 && move.X <= math.MaxUint8 && move.Y <= math.MaxUint8
+}
+
+func countValidTTEntries(entries []TTEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Valid {
+			count++
+		}
+	}
+	return count
+}
+
+func countValidRootTransposeEntries(entries []RootTransposeEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Valid {
+			count++
+		}
+	}
+	return count
+}
+
+func loadTTPersistence(cfg Config, cache *AISearchCache) {
+	if cache == nil || !cfg.AiEnableTtPersistence || cfg.AiTtPersistencePath == "" {
+		log.Printf("[ai:cache] restored TT persistence: 0 entries (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.AiTtPersistencePath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			noteCacheLoad(CacheLoadReport{Cache: "tt", Path: path, Error: fmt.Sprintf("failed to open: %v", err)})
+			return
+		}
+		log.Printf("[ai:cache] restored TT persistence: 0 entries (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+
+	var snapshot ttPersistenceSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		noteCacheLoad(CacheLoadReport{Cache: "tt", Path: path, Error: fmt.Sprintf("failed to decode: %v", err)})
+		return
+	}
+	ttReport, rootReport := restoreTTSnapshot(cfg, cache, snapshot)
+	ttReport.Path, rootReport.Path = path, path
+	noteCacheLoad(ttReport)
+	if cfg.AiEnableRootTranspose {
+		noteCacheLoad(rootReport)
+	}
+}
+
+// restoreTTSnapshot fills the cache's tables, sized from cfg, with the
+// snapshot's entries. Entries are placed by key, so a snapshot of another
+// table size is migrated rather than dropped; one of another version is not
+// read.
+func restoreTTSnapshot(cfg Config, cache *AISearchCache, snapshot ttPersistenceSnapshot) (CacheLoadReport, CacheLoadReport) {
+	ttReport := CacheLoadReport{Cache: "tt", Version: snapshot.Version}
+	rootReport := CacheLoadReport{Cache: "root_transpose", Version: snapshot.Version}
+	ttEntries := countValidTTEntries(snapshot.Entries)
+	rootEntries := countValidRootTransposeEntries(snapshot.RootTransposeEntries)
+	switch {
+	case snapshot.Version > ttPersistenceVersion:
+		ttReport.Error = fmt.Sprintf("written by a newer build (version %d)", snapshot.Version)
+		rootReport.Error = ttReport.Error
+		ttReport.skip("newer_version", ttEntries)
+		rootReport.skip("newer_version", rootEntries)
+		return ttReport, rootReport
+	case snapshot.Version == 0:
+		// Unversioned files predate the mate score rebasing and the current
+		// heuristic hash inputs, so their scores and hashes cannot be trusted.
+		ttReport.skip("unversioned", ttEntries)
+		rootReport.skip("unversioned", rootEntries)
+		return ttReport, rootReport
+	case snapshot.Version < ttPersistenceVersion:
+		ttReport.skip("older_version", ttEntries)
+		rootReport.skip("older_version", rootEntries)
+		return ttReport, rootReport
+	case snapshot.PositionScheme != positionHashScheme():
+		ttReport.skip("position_hash_changed", ttEntries)
+		rootReport.skip("position_hash_changed", rootEntries)
+		return ttReport, rootReport
+	}
+
+	buckets := cfg.AiTtBuckets
+	if !cfg.AiTtUseSetAssoc {
+		buckets = 1
+	}
+	migrate := snapshot.Size != cfg.AiTtSize || snapshot.Buckets != buckets
+	if snapshot.HeuristicScheme != heuristicHashScheme() {
+		ttReport.skip("heuristic_hash_changed", ttEntries)
+	} else if ttEntries > 0 {
+		tt := NewTranspositionTable(uint64(cfg.AiTtSize), buckets)
+		for _, entry := range snapshot.Entries {
+			switch {
+			case !entry.Valid:
+			case !validTTEntry(entry):
+				ttReport.skip("invalid", 1)
+			case !tt.restoreEntry(entry):
+				ttReport.skip("no_room", 1)
+			default:
+				ttReport.Loaded++
+				if migrate {
+					ttReport.Migrated++
+				}
+			}
+		}
+		cache.mu.Lock()
+		cache.TT = tt
+		cache.TTSize = cfg.AiTtSize
+		cache.TTBuckets = buckets
+		cache.mu.Unlock()
+	}
+
+	rootBuckets := 2
+	if !cfg.AiEnableRootTranspose || rootEntries == 0 {
+		return ttReport, rootReport
+	}
+	migrate = snapshot.RootTransposeSize != cfg.AiRootTransposeSize || snapshot.RootTransposeBuckets != rootBuckets
+	rootTranspose := NewRootTransposeCache(uint64(cfg.AiRootTransposeSize), rootBuckets)
+	for _, entry := range snapshot.RootTransposeEntries {
+		switch {
+		case !entry.Valid:
+		case !validRootTransposeEntry(entry):
+			rootReport.skip("invalid", 1)
+		case !rootTranspose.restoreEntry(entry):
+			rootReport.skip("no_room", 1)
+		default:
+			rootReport.Loaded++
+			if migrate {
+				rootReport.Migrated++
+			}
+		}
+	}
+	cache.mu.Lock()
+	cache.RootTranspose = rootTranspose
+	cache.RootTransposeSize = cfg.AiRootTransposeSize
+	cache.RootTransposeBucks = rootBuckets
+	cache.mu.Unlock()
+	return ttReport, rootReport
+}
+
+func persistTTPersistence(cfg Config, cache *AISearchCache) {
+	if cache == nil || !cfg.AiEnableTtPersistence || cfg.AiTtPersistencePath == "" {
+		log.Printf("[ai:cache] stored TT persistence: 0 entries (disabled or no path)")
+		return
+	}
+	cache.mu.Lock()
+	tt := cache.TT
+	size := cache.TTSize
+	buckets := cache.TTBuckets
+	rootTranspose := cache.RootTranspose
+	rootTransposeSize := cache.RootTransposeSize
+	rootTransposeBuckets := cache.RootTransposeBucks
+	cache.mu.Unlock()
+	if tt == nil || size == 0 || buckets == 0 {
+		log.Printf("[ai:cache] stored TT persistence: 0 entries (TT not initialized)")
+	} else {
+		entries := tt.snapshotEntries()
+		validEntries := countValidTTEntries(entries)
+		path := resolveTTPersistencePath(cfg.AiTtPersistencePath)
+		dir := filepath.Dir(path)
+		if dir != "." && dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				log.Printf("[ai:cache] unable to create TT persistence directory %s: %v", dir, err)
+				return
+			}
+		}
+		rootEntries := []RootTransposeEntry(nil)
+		validRootEntries := 0
+		if cfg.AiEnableRootTranspose && rootTranspose != nil && rootTransposeSize > 0 && rootTransposeBuckets > 0 {
+			rootEntries = rootTranspose.snapshotEntries()
+			validRootEntries = countValidRootTransposeEntries(rootEntries)
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			log.Printf("[ai:cache] failed to create TT persistence %s: %v", path, err)
+			return
+		}
+		defer file.Close()
+		snapshot := ttPersistenceSnapshot{
+			Version:         ttPersistenceVersion,
+			HeuristicScheme: heuristicHashScheme(),
+			PositionScheme:  positionHashScheme(),
+
+			Size:    size,
+			Buckets: buckets,
+			Entries: entries,
+
+			RootTransposeSize:    rootTransposeSize,
+			RootTransposeBuckets: rootTransposeBuckets,
+			RootTransposeEntries: rootEntries,
+		}
+		if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+			log.Printf("[ai:cache] failed to encode TT persistence %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] stored TT persistence to %s (%d/%d valid entries)", path, validEntries, len(entries))
+		log.Printf("[ai:cache] stored root-transpose persistence to %s (%d/%d valid entries)", path, validRootEntries, len(rootEntries))
+		return
+	}
+	log.Printf("[ai:cache] stored root-transpose persistence: 0 entries (TT not initialized)")
+}
+
+func resolveTTPersistencePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if stat, err := os.Stat(dockerCacheDir); err == nil && stat.IsDir() {
+		return filepath.Join(dockerCacheDir, path)
+	}
+	return path
+}
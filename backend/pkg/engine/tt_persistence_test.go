@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"path/filepath"
@@ -53,7 +53,7 @@ func TestRootTransposePersistenceRoundTrip(t *testing.T) {
 	cfg.AiRootTransposeSize = 16
 
 	cache := newAISearchCache()
-	tt := ensureTT(&cache, cfg)
+	tt := EnsureTT(&cache, cfg)
 	if tt == nil {
 		t.Fatalf("expected TT")
 	}
@@ -79,7 +79,7 @@ func TestRootTransposePersistenceRoundTrip(t *testing.T) {
 	loaded := newAISearchCache()
 	loadTTPersistence(cfg, &loaded)
 
-	loadedTT := ensureTT(&loaded, cfg)
+	loadedTT := EnsureTT(&loaded, cfg)
 	if loadedTT == nil {
 		t.Fatalf("expected loaded TT")
 	}
@@ -103,3 +103,56 @@ func TestRootTransposePersistenceRoundTrip(t *testing.T) {
 		t.Fatalf("unexpected restored root transpose entry: %+v", rtEntry)
 	}
 }
+
+func TestRestoreTTSnapshotMigratesAndSkips(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AiTtUseSetAssoc = true
+	cfg.AiTtBuckets = 2
+	cfg.AiTtSize = 64
+	cfg.AiEnableRootTranspose = true
+	cfg.AiRootTransposeSize = 16
+	hash := heuristicHashFromConfig(cfg)
+	legacy := ttPersistenceSnapshot{
+		Size:    8,
+		Buckets: 1,
+		Entries: []TTEntry{
+			{Key: 0x12345, HeuristicHash: hash, Depth: 7, Flag: TTExact, BestMove: Move{X: 3, Y: 3}, Valid: true},
+			{Key: 0x54321, HeuristicHash: hash, Depth: 4, Flag: TTFlag(9), Valid: true},
+			{},
+		},
+		RootTransposeSize:    16,
+		RootTransposeBuckets: 2,
+		RootTransposeEntries: []RootTransposeEntry{{Key: 0x998877, Depth: 9, Flag: TTExact, Valid: true}},
+	}
+
+	cache := newAISearchCache()
+	ttReport, rootReport := restoreTTSnapshot(cfg, &cache, legacy)
+	if ttReport.Loaded != 0 || ttReport.SkipReasons["unversioned"] != 2 || rootReport.Loaded != 0 || rootReport.SkipReasons["unversioned"] != 1 {
+		t.Fatalf("expected an unversioned file to be skipped, got %+v and %+v", ttReport, rootReport)
+	}
+
+	resized := legacy
+	resized.Version = ttPersistenceVersion
+	resized.PositionScheme = positionHashScheme()
+	resized.HeuristicScheme = heuristicHashScheme()
+	ttReport, rootReport = restoreTTSnapshot(cfg, &cache, resized)
+	if ttReport.Loaded != 1 || ttReport.Migrated != 1 || ttReport.SkipReasons["invalid"] != 1 || rootReport.Loaded != 1 || rootReport.Migrated != 0 {
+		t.Fatalf("expected the smaller table to be migrated, got %+v and %+v", ttReport, rootReport)
+	}
+	if entry, ok := EnsureTT(&cache, cfg).Probe(0x12345, hash); !ok || entry.Depth != 7 {
+		t.Fatalf("expected the migrated entry in the configured table, got %+v", entry)
+	}
+
+	current := resized
+	current.HeuristicScheme = heuristicHashScheme() + 1
+	ttReport, rootReport = restoreTTSnapshot(cfg, &cache, current)
+	if ttReport.Loaded != 0 || ttReport.SkipReasons["heuristic_hash_changed"] != 2 || rootReport.Loaded != 1 {
+		t.Fatalf("expected only the TT entries to be skipped, got %+v and %+v", ttReport, rootReport)
+	}
+
+	current.Version = ttPersistenceVersion + 1
+	ttReport, rootReport = restoreTTSnapshot(cfg, &cache, current)
+	if ttReport.Error == "" || ttReport.Loaded != 0 || rootReport.SkipReasons["newer_version"] != 1 {
+		t.Fatalf("expected a newer file to be refused, got %+v and %+v", ttReport, rootReport)
+	}
+}
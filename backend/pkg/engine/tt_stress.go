@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ttStressScoreLimit bounds the scores the stress run stores; an entry
+// outside it was torn or mixed up with another.
+const ttStressScoreLimit = 1 << 20
+
+// ttConcurrentTable is what the search needs from a transposition table
+// shared between goroutines. stressTranspositionTable checks any
+// implementation of it, so a new table can be validated before it replaces
+// TranspositionTable.
+type ttConcurrentTable interface {
+	Probe(key uint64, heuristicHash uint64) (TTEntry, bool)
+	Peek(key uint64, heuristicHash uint64) (TTEntry, bool)
+	Store(key uint64, heuristicHash uint64, depth int, value float64, flag TTFlag, best Move, meta TTMeta) (replaced bool, overwrote bool)
+	DeleteByKey(key uint64) bool
+}
+
+type ttStressConfig struct {
+	Goroutines int
+	Ops        int
+	// Keys is how many distinct positions the goroutines share; fewer keys
+	// than table slots still collide in buckets and stripes.
+	Keys int
+	Seed int64
+}
+
+type ttStressReport struct {
+	Stores     int64
+	Probes     int64
+	Hits       int64
+	Deletes    int64
+	Violations int64
+	// Examples describes the first violations found.
+	Examples []string
+}
+
+// ttStressEntry is what the stress run writes for key under heuristicHash
+// at depth: every field is derived from the three, so a reader can tell
+// whether an entry is one that was written whole for the key it asked for.
+func ttStressEntry(key, heuristicHash uint64, depth int) (int32, TTFlag, Move) {
+	mixed := mixKey(key ^ heuristicHash ^ uint64(depth))
+	score := int32(mixed%(2*ttStressScoreLimit)) - ttStressScoreLimit
+	flag := TTFlag(mixed >> 32 % 3)
+	move := Move{X: int(mixed>>40) % 19, Y: int(mixed>>48) % 19}
+	return score, flag, move
+}
+
+// stressTranspositionTable hammers table with stores, probes, peeks and
+// deletes from many goroutines and checks every entry it reads back: it
+// must carry the key and heuristic hash asked for, a depth that was
+// written, and the score, bound and move written with that depth.
+func stressTranspositionTable(table ttConcurrentTable, cfg ttStressConfig) ttStressReport {
+	if cfg.Goroutines <= 0 {
+		cfg.Goroutines = 8
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 1024
+	}
+	heuristicHashes := []uint64{0xaaaa, 0xbbbb}
+	var report ttStressReport
+	var examplesMu sync.Mutex
+	fail := func(format string, args ...interface{}) {
+		atomic.AddInt64(&report.Violations, 1)
+		examplesMu.Lock()
+		defer examplesMu.Unlock()
+		if len(report.Examples) < 8 {
+			report.Examples = append(report.Examples, fmt.Sprintf(format, args...))
+		}
+	}
+	check := func(key, heuristicHash uint64, entry TTEntry) {
+		if entry.Key != key || entry.HeuristicHash != heuristicHash || !entry.Valid {
+			fail("asked for %#x/%#x, got entry for %#x/%#x (valid %v)", key, heuristicHash, entry.Key, entry.HeuristicHash, entry.Valid)
+			return
+		}
+		if entry.Depth < 1 || entry.Depth > 16 {
+			fail("key %#x: depth %d was never written", key, entry.Depth)
+			return
+		}
+		if entry.Score < -ttStressScoreLimit || entry.Score >= ttStressScoreLimit {
+			fail("key %#x: score %d out of bounds", key, entry.Score)
+			return
+		}
+		score, flag, move := ttStressEntry(key, heuristicHash, entry.Depth)
+		if entry.Score != score || entry.Flag != flag || entry.BestMove != move {
+			fail("key %#x depth %d: got score %d flag %d move %v, wrote %d %d %v", key, entry.Depth, entry.Score, entry.Flag, entry.BestMove, score, flag, move)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < cfg.Goroutines; g++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for i := 0; i < cfg.Ops; i++ {
+				key := mixKey(uint64(rng.Intn(cfg.Keys)))
+				heuristicHash := heuristicHashes[rng.Intn(len(heuristicHashes))]
+				switch op := rng.Intn(10); {
+				case op < 4:
+					depth := 1 + rng.Intn(16)
+					score, flag, move := ttStressEntry(key, heuristicHash, depth)
+					table.Store(key, heuristicHash, depth, float64(score), flag, move, TTMeta{})
+					atomic.AddInt64(&report.Stores, 1)
+				case op < 7:
+					atomic.AddInt64(&report.Probes, 1)
+					if entry, ok := table.Probe(key, heuristicHash); ok {
+						atomic.AddInt64(&report.Hits, 1)
+						check(key, heuristicHash, entry)
+					}
+				case op < 9:
+					atomic.AddInt64(&report.Probes, 1)
+					if entry, ok := table.Peek(key, heuristicHash); ok {
+						atomic.AddInt64(&report.Hits, 1)
+						check(key, heuristicHash, entry)
+					}
+				default:
+					table.DeleteByKey(key)
+					atomic.AddInt64(&report.Deletes, 1)
+				}
+			}
+		}(rand.New(rand.NewSource(cfg.Seed + int64(g))))
+	}
+	wg.Wait()
+	return report
+}
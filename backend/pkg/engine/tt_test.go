@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTTConcurrentProbeStore(t *testing.T) {
+	tt := NewTranspositionTable(1<<12, 2)
+	heuristicHash := heuristicHashFromConfig(DefaultConfig())
+	var wg sync.WaitGroup
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed uint64) {
+			defer wg.Done()
+			for i := 0; i < 4000; i++ {
+				key := mixKey(seed ^ uint64(i))
+				depth := (i % 8) + 1
+				move := Move{X: i % 19, Y: (i / 19) % 19}
+				tt.Store(key, heuristicHash, depth, float64(i), TTExact, move, TTMeta{})
+				tt.Probe(key, heuristicHash)
+				tt.Probe(key^0x9e3779b97f4a7c15, heuristicHash)
+			}
+		}(uint64(g + 1))
+	}
+
+	wg.Wait()
+	if tt.Count() == 0 {
+		t.Fatalf("expected TT to contain entries after concurrent traffic")
+	}
+}
+
+func TestTTCountTracksWrites(t *testing.T) {
+	tt := NewTranspositionTable(1<<6, 2)
+	heuristicHash := heuristicHashFromConfig(DefaultConfig())
+	scan := func() int {
+		count := 0
+		for _, entry := range tt.snapshotEntries() {
+			if entry.Valid {
+				count++
+			}
+		}
+		return count
+	}
+	check := func(stage string) {
+		t.Helper()
+		if got, want := tt.Count(), scan(); got != want {
+			t.Fatalf("%s: Count %d, table holds %d", stage, got, want)
+		}
+	}
+	for i := 0; i < 400; i++ {
+		tt.Store(mixKey(uint64(i)), heuristicHash, i%6+1, float64(i), TTExact, Move{}, TTMeta{})
+	}
+	check("stores and replacements")
+	tt.DeleteByKey(mixKey(7))
+	tt.DeleteByHeuristicHash(heuristicHash + 1)
+	check("deletes")
+	tt.restoreEntry(TTEntry{Key: mixKey(1000), HeuristicHash: heuristicHash, Depth: 20, Valid: true})
+	check("restore")
+	tt.DeleteByHeuristicHash(heuristicHash)
+	check("pruning")
+	tt.Store(mixKey(1), heuristicHash, 3, 0, TTExact, Move{}, TTMeta{})
+	tt.Clear()
+	check("clear")
+}
+
+func TestTTGenerationWrapStaysNonZero(t *testing.T) {
+	tt := NewTranspositionTable(16, 1)
+	tt.gen.Store(^uint32(0))
+	tt.NextGeneration()
+	if got := tt.Generation(); got == 0 {
+		t.Fatalf("generation must never be zero")
+	}
+}
+
+func TestTTScopesEntriesByHeuristicHash(t *testing.T) {
+	tt := NewTranspositionTable(64, 4)
+	key := uint64(0x1234)
+	hashA := uint64(0xaaa)
+	hashB := uint64(0xbbb)
+
+	tt.Store(key, hashA, 4, 100, TTExact, Move{X: 1, Y: 1}, TTMeta{})
+	tt.Store(key, hashB, 4, 200, TTExact, Move{X: 2, Y: 2}, TTMeta{})
+
+	entryA, okA := tt.Probe(key, hashA)
+	if !okA || entryA.BestMove.X != 1 || entryA.BestMove.Y != 1 {
+		t.Fatalf("expected heuristic A entry, got ok=%v entry=%+v", okA, entryA)
+	}
+	entryB, okB := tt.Probe(key, hashB)
+	if !okB || entryB.BestMove.X != 2 || entryB.BestMove.Y != 2 {
+		t.Fatalf("expected heuristic B entry, got ok=%v entry=%+v", okB, entryB)
+	}
+
+	if counts := tt.CountByHeuristicHash(); counts[hashA] != 1 || counts[hashB] != 1 {
+		t.Fatalf("expected one entry per heuristic hash, got %v", counts)
+	}
+
+	deleted := tt.DeleteByHeuristicHash(hashA)
+	if deleted <= 0 {
+		t.Fatalf("expected entries for heuristic A to be pruned")
+	}
+	if _, ok := tt.Probe(key, hashA); ok {
+		t.Fatalf("expected heuristic A entry to be gone after prune")
+	}
+	if _, ok := tt.Probe(key, hashB); !ok {
+		t.Fatalf("expected heuristic B entry to remain after pruning A")
+	}
+}
+
+func TestHeuristicHashResolvesUnsetWeights(t *testing.T) {
+	config := DefaultConfig()
+	unset := config
+	unset.Heuristics = HeuristicConfig{}
+	if HeuristicHash(unset) != HeuristicHash(config) {
+		t.Fatalf("expected unset weights to hash like the defaults")
+	}
+	tuned := config
+	tuned.Heuristics.Open3 *= 2
+	if HeuristicHash(tuned) == HeuristicHash(config) {
+		t.Fatalf("expected a different weight set to hash differently")
+	}
+	if got := HeuristicHash(config); got != FormatHeuristicHash(heuristicHashFromConfig(config)) || len(got) != 16 {
+		t.Fatalf("unexpected hash format %q", got)
+	}
+}
+
+func TestTTStressEntriesMatchTheirKeys(t *testing.T) {
+	for _, buckets := range []int{1, 2, 4} {
+		// A table smaller than the key space keeps replacing entries.
+		tt := NewTranspositionTable(1<<8, buckets)
+		report := stressTranspositionTable(tt, ttStressConfig{Goroutines: 16, Ops: 20000, Keys: 4096, Seed: int64(buckets)})
+		if report.Violations != 0 {
+			t.Fatalf("%d buckets: %d violations, first: %v", buckets, report.Violations, report.Examples)
+		}
+		if report.Hits == 0 || report.Deletes == 0 {
+			t.Fatalf("%d buckets: expected hits and deletes, got %+v", buckets, report)
+		}
+	}
+}
+
+func TestTTStressDetectsMixedUpEntries(t *testing.T) {
+	report := stressTranspositionTable(corruptedDepthTable{NewTranspositionTable(1<<8, 2)}, ttStressConfig{Goroutines: 2, Ops: 2000, Keys: 64, Seed: 1})
+	if report.Violations == 0 {
+		t.Fatalf("expected entries that were never written to be reported")
+	}
+}
+
+// corruptedDepthTable answers probes with the depth of the entry off by one.
+type corruptedDepthTable struct {
+	*TranspositionTable
+}
+
+func (s corruptedDepthTable) Probe(key uint64, heuristicHash uint64) (TTEntry, bool) {
+	entry, ok := s.TranspositionTable.Probe(key, heuristicHash)
+	entry.Depth++
+	return entry, ok
+}
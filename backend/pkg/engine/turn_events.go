@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	TurnEventYourTurn    = "your_turn"
+	TurnEventIdleWarning = "idle_warning"
+)
+
+// TurnClock is the time on the clock when a turn event is sent: how long
+// the current turn has lasted and the time each side spent on its earlier
+// moves.
+type TurnClock struct {
+	NowMs           int64 `json:"now_ms"`
+	TurnStartedAtMs int64 `json:"turn_started_at_ms"`
+	TurnElapsedMs   int64 `json:"turn_elapsed_ms"`
+	BlackUsedMs     int64 `json:"black_used_ms"`
+	WhiteUsedMs     int64 `json:"white_used_ms"`
+}
+
+// TurnEvent tells a human side it is their turn, or that they have not
+// moved for IdleWarningMs. IdleMs is set on idle warnings.
+type TurnEvent struct {
+	Type   string    `json:"type"`
+	GameID uint64    `json:"game_id"`
+	Ply    int       `json:"ply"`
+	Player int       `json:"player"`
+	IdleMs int64     `json:"idle_ms,omitempty"`
+	Clock  TurnClock `json:"clock"`
+}
+
+// TurnWatch follows the live game's turns and sends each human turn's
+// events once.
+type TurnWatch struct {
+	mu       sync.Mutex
+	gameID   uint64
+	ply      int
+	notified bool
+	warned   bool
+}
+
+// Update returns the events due at now for the game controller plays.
+func (w *TurnWatch) Update(controller *GameController, config Config, now time.Time) []TurnEvent {
+	state, history, gameID := controller.Snapshot()
+	settings := controller.Settings()
+	startedAtMs := controller.CurrentTurnStartedAtMs()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ply := history.Size()
+	if w.gameID != gameID || w.ply != ply {
+		w.gameID, w.ply = gameID, ply
+		w.notified, w.warned = false, false
+	}
+	human := settings.BlackType == PlayerHuman
+	if state.ToMove == PlayerWhite {
+		human = settings.WhiteType == PlayerHuman
+	}
+	if state.Status != StatusRunning || !human || startedAtMs == 0 {
+		return nil
+	}
+
+	clock := TurnClock{NowMs: now.UnixMilli(), TurnStartedAtMs: startedAtMs}
+	clock.TurnElapsedMs = clock.NowMs - startedAtMs
+	for _, entry := range history.All() {
+		if entry.Player == PlayerBlack {
+			clock.BlackUsedMs += int64(entry.ElapsedMs)
+		} else {
+			clock.WhiteUsedMs += int64(entry.ElapsedMs)
+		}
+	}
+	event := TurnEvent{GameID: gameID, Ply: ply, Player: PlayerToInt(state.ToMove), Clock: clock}
+	var events []TurnEvent
+	if !w.notified {
+		w.notified = true
+		event.Type = TurnEventYourTurn
+		events = append(events, event)
+	}
+	if !w.warned && config.IdleWarningMs > 0 && clock.TurnElapsedMs >= int64(config.IdleWarningMs) {
+		w.warned = true
+		event.Type = TurnEventIdleWarning
+		event.IdleMs = clock.TurnElapsedMs
+		events = append(events, event)
+	}
+	return events
+}
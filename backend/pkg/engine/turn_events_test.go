@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTurnWatchNotifiesEachHumanTurnOnce(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	config := DefaultConfig()
+	config.IdleWarningMs = 1000
+	watch := &TurnWatch{}
+	now := time.Now()
+
+	events := watch.Update(controller, config, now)
+	if len(events) != 1 || events[0].Type != TurnEventYourTurn || events[0].Player != 1 || events[0].Ply != 0 {
+		t.Fatalf("expected black's turn to be announced, got %+v", events)
+	}
+	if events := watch.Update(controller, config, now); len(events) != 0 {
+		t.Fatalf("expected a turn to be announced once, got %+v", events)
+	}
+	events = watch.Update(controller, config, now.Add(2*time.Second))
+	if len(events) != 1 || events[0].Type != TurnEventIdleWarning || events[0].IdleMs < 1000 {
+		t.Fatalf("expected an idle warning, got %+v", events)
+	}
+	if events := watch.Update(controller, config, now.Add(3*time.Second)); len(events) != 0 {
+		t.Fatalf("expected one idle warning per turn, got %+v", events)
+	}
+
+	if applied, reason := controller.ApplyHumanMove(Move{X: 4, Y: 4}); !applied {
+		t.Fatalf("expected the move to be played: %s", reason)
+	}
+	events = watch.Update(controller, config, time.Now())
+	if len(events) != 1 || events[0].Type != TurnEventYourTurn || events[0].Player != 2 || events[0].Ply != 1 {
+		t.Fatalf("expected white's turn to be announced, got %+v", events)
+	}
+	if events[0].Clock.BlackUsedMs < 0 || events[0].Clock.TurnStartedAtMs == 0 {
+		t.Fatalf("expected a clock snapshot, got %+v", events[0].Clock)
+	}
+}
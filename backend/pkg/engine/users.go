@@ -0,0 +1,378 @@
+package engine
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxUserProfiles = 1000
+
+var ErrUserExists = errors.New("user already exists")
+
+// UserPreferences are applied to games a user starts. Zero values keep the
+// server defaults.
+type UserPreferences struct {
+	BoardSize         int   `json:"board_size,omitempty"`
+	WinLength         int   `json:"win_length,omitempty"`
+	CaptureWinStones  int   `json:"capture_win_stones,omitempty"`
+	ForbidDoubleThree *bool `json:"forbid_double_three,omitempty"`
+	Color             int   `json:"color,omitempty"`
+}
+
+// UserResults counts games from the user's side.
+type UserResults struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Draws   int     `json:"draws"`
+	Losses  int     `json:"losses"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// UserLevelResults are the results against the engine at one search depth.
+type UserLevelResults struct {
+	Depth int `json:"depth"`
+	UserResults
+}
+
+// UserStats are lifetime statistics. AverageAccuracy is the mean of the
+// user's per-game accuracy over their reviewed games.
+type UserStats struct {
+	UserResults
+	VsAI            []UserLevelResults `json:"vs_ai"`
+	Reviews         int                `json:"reviews"`
+	AverageAccuracy float64            `json:"average_accuracy"`
+}
+
+type UserProfile struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	CreatedAtMs int64           `json:"created_at_ms"`
+	Preferences UserPreferences `json:"preferences"`
+	Stats       UserStats       `json:"stats"`
+	// Model is what the engine learned about the user's play.
+	Model OpponentModel `json:"model"`
+}
+
+// Validate checks preferences against the supported board sizes and rules.
+func (p UserPreferences) Validate() error {
+	size := p.BoardSize
+	if size == 0 {
+		size = DefaultGameSettings().BoardSize
+	}
+	if size < 5 || size > 19 {
+		return fieldErrorf("board_size", "board_size must be between 5 and 19")
+	}
+	if p.WinLength != 0 && (p.WinLength < 3 || p.WinLength > size) {
+		return fieldErrorf("win_length", "win_length must be between 3 and %d", size)
+	}
+	if p.CaptureWinStones != 0 && (p.CaptureWinStones < 2 || p.CaptureWinStones > 20 || p.CaptureWinStones%2 != 0) {
+		return fieldErrorf("capture_win_stones", "capture_win_stones must be an even number between 2 and 20")
+	}
+	if p.Color < 0 || p.Color > 2 {
+		return fieldErrorf("color", "color must be 1 (black) or 2 (white)")
+	}
+	return nil
+}
+
+// Apply returns settings with the preferences applied. human is the colour
+// the user plays; the double-three rule preference applies to that colour.
+func (p UserPreferences) Apply(settings GameSettings, human int) GameSettings {
+	if p.BoardSize != 0 {
+		settings.BoardSize = p.BoardSize
+	}
+	if p.WinLength != 0 {
+		settings.WinLength = p.WinLength
+	}
+	if p.CaptureWinStones != 0 {
+		settings.CaptureWinStones = p.CaptureWinStones
+	}
+	if p.ForbidDoubleThree != nil {
+		if human == 2 {
+			settings.ForbidDoubleThreeWhite = *p.ForbidDoubleThree
+		} else {
+			settings.ForbidDoubleThreeBlack = *p.ForbidDoubleThree
+		}
+	}
+	return settings
+}
+
+// UserStore keeps the user profiles; it is persisted with the other caches.
+type UserStore struct {
+	mu       sync.Mutex
+	profiles map[string]*UserProfile
+}
+
+type userStoreSnapshot struct {
+	Profiles []UserProfile
+}
+
+var UserProfiles = NewUserStore()
+
+func NewUserStore() *UserStore {
+	return &UserStore{profiles: make(map[string]*UserProfile)}
+}
+
+// userID derives a profile id from a display name: lower case letters,
+// digits, '-' and '_', with spaces turned into '-'.
+func userID(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 32 {
+		return "", fieldErrorf("name", "name must be 1 to 32 characters")
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		default:
+			return "", fieldErrorf("name", "name may only use letters, digits, spaces, '-' and '_'")
+		}
+	}
+	return b.String(), nil
+}
+
+// Create adds a profile. It fails when the name maps to an existing id.
+func (s *UserStore) Create(name string, preferences UserPreferences) (UserProfile, error) {
+	id, err := userID(name)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	if err := preferences.Validate(); err != nil {
+		return UserProfile{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.profiles[id]; ok {
+		return UserProfile{}, ErrUserExists
+	}
+	if len(s.profiles) >= maxUserProfiles {
+		return UserProfile{}, fmt.Errorf("at most %d users", maxUserProfiles)
+	}
+	profile := &UserProfile{
+		ID:          id,
+		Name:        strings.TrimSpace(name),
+		CreatedAtMs: time.Now().UnixMilli(),
+		Preferences: preferences,
+		Stats:       UserStats{VsAI: []UserLevelResults{}},
+	}
+	s.profiles[id] = profile
+	return copyUserProfile(profile), nil
+}
+
+func (s *UserStore) Get(id string) (UserProfile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[id]
+	if !ok {
+		return UserProfile{}, false
+	}
+	return copyUserProfile(profile), true
+}
+
+// List returns the profiles ordered by id.
+func (s *UserStore) List() []UserProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles := make([]UserProfile, 0, len(s.profiles))
+	for _, profile := range s.profiles {
+		profiles = append(profiles, copyUserProfile(profile))
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+	return profiles
+}
+
+func (s *UserStore) SetPreferences(id string, preferences UserPreferences) (UserProfile, bool, error) {
+	if err := preferences.Validate(); err != nil {
+		return UserProfile{}, true, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[id]
+	if !ok {
+		return UserProfile{}, false, nil
+	}
+	profile.Preferences = preferences
+	return copyUserProfile(profile), true, nil
+}
+
+func (s *UserStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.profiles[id]; !ok {
+		return false
+	}
+	delete(s.profiles, id)
+	return true
+}
+
+// RecordGame credits a finished game against the engine to its user.
+func (s *UserStore) RecordGame(record GameRecord) {
+	color := record.HumanColor()
+	if record.User == "" || color == 0 || !record.Finished() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[record.User]
+	if !ok {
+		return
+	}
+	result := 0
+	switch winner := WinnerFromStatus(record.Status); {
+	case winner == color:
+		result = 1
+	case winner != 0:
+		result = -1
+	}
+	profile.Stats.UserResults.add(result)
+	levels := profile.Stats.VsAI
+	at := sort.Search(len(levels), func(i int) bool { return levels[i].Depth >= record.AIDepth })
+	if at == len(levels) || levels[at].Depth != record.AIDepth {
+		levels = append(levels, UserLevelResults{})
+		copy(levels[at+1:], levels[at:])
+		levels[at] = UserLevelResults{Depth: record.AIDepth}
+	}
+	levels[at].UserResults.add(result)
+	profile.Stats.VsAI = levels
+	profile.Model.recordGame(record, color)
+}
+
+// OpponentModel returns what the engine learned about the user's play.
+func (s *UserStore) OpponentModel(id string) (OpponentModel, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[id]
+	if !ok {
+		return OpponentModel{}, false
+	}
+	return profile.Model, true
+}
+
+// RecordReview folds the user's side of a finished review into their
+// average accuracy.
+func (s *UserStore) RecordReview(review Review) {
+	if review.User == "" || review.Status != "done" {
+		return
+	}
+	side := review.Black
+	if review.UserColor == 2 {
+		side = review.White
+	}
+	if side.Moves == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[review.User]
+	if !ok {
+		return
+	}
+	stats := &profile.Stats
+	stats.AverageAccuracy = (stats.AverageAccuracy*float64(stats.Reviews) + side.Accuracy) / float64(stats.Reviews+1)
+	stats.Reviews++
+	profile.Model.recordReview(review, review.UserColor)
+}
+
+func (r *UserResults) add(result int) {
+	r.Games++
+	switch result {
+	case 1:
+		r.Wins++
+	case -1:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+	r.WinRate = (float64(r.Wins) + float64(r.Draws)/2) / float64(r.Games)
+}
+
+func copyUserProfile(profile *UserProfile) UserProfile {
+	copied := *profile
+	copied.Stats.VsAI = append([]UserLevelResults{}, profile.Stats.VsAI...)
+	return copied
+}
+
+func (s *UserStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.profiles)
+}
+
+func (s *UserStore) snapshot() userStoreSnapshot {
+	return userStoreSnapshot{Profiles: s.List()}
+}
+
+func (s *UserStore) load(snapshot userStoreSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = make(map[string]*UserProfile, len(snapshot.Profiles))
+	for i := range snapshot.Profiles {
+		profile := snapshot.Profiles[i]
+		if profile.Stats.VsAI == nil {
+			profile.Stats.VsAI = []UserLevelResults{}
+		}
+		s.profiles[profile.ID] = &profile
+	}
+}
+
+func loadUserProfiles(cfg Config, store *UserStore) {
+	if store == nil || cfg.UsersPath == "" {
+		log.Printf("[ai:cache] restored user profiles: 0 users (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.UsersPath)
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ai:cache] failed to open user profiles %s: %v", path, err)
+			return
+		}
+		log.Printf("[ai:cache] restored user profiles: 0 users (file not found: %s)", path)
+		return
+	}
+	defer file.Close()
+	var snapshot userStoreSnapshot
+	if err := gob.NewDecoder(file).Decode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to decode user profiles %s: %v", path, err)
+		return
+	}
+	store.load(snapshot)
+	log.Printf("[ai:cache] restored user profiles from %s (%d users)", path, store.Len())
+}
+
+func persistUserProfiles(cfg Config, store *UserStore) {
+	if store == nil || cfg.UsersPath == "" {
+		log.Printf("[ai:cache] stored user profiles: 0 users (disabled or no path)")
+		return
+	}
+	path := resolveTTPersistencePath(cfg.UsersPath)
+	dir := filepath.Dir(path)
+	if dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[ai:cache] unable to create user profile directory %s: %v", dir, err)
+			return
+		}
+	}
+	snapshot := store.snapshot()
+	file, err := os.Create(path)
+	if err != nil {
+		log.Printf("[ai:cache] failed to create user profiles %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if err := gob.NewEncoder(file).Encode(&snapshot); err != nil {
+		log.Printf("[ai:cache] failed to encode user profiles %s: %v", path, err)
+		return
+	}
+	log.Printf("[ai:cache] stored user profiles to %s (%d users)", path, len(snapshot.Profiles))
+}
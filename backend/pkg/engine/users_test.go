@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestUserStoreCreateRejectsDuplicates(t *testing.T) {
+	store := NewUserStore()
+	profile, err := store.Create("Ada Lovelace", UserPreferences{BoardSize: 15})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if profile.ID != "ada-lovelace" {
+		t.Fatalf("expected slug id, got %q", profile.ID)
+	}
+	if _, err := store.Create("ada lovelace", UserPreferences{}); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("expected duplicate to be rejected, got %v", err)
+	}
+	if _, err := store.Create("bad/name", UserPreferences{}); err == nil {
+		t.Fatalf("expected invalid name to be rejected")
+	}
+	if _, err := store.Create("bob", UserPreferences{CaptureWinStones: 3}); err == nil {
+		t.Fatalf("expected odd capture count to be rejected")
+	}
+}
+
+func TestUserStoreRecordsGamesPerDepth(t *testing.T) {
+	store := NewUserStore()
+	if _, err := store.Create("ada", UserPreferences{}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerAI
+	record := GameRecord{Settings: settings, Status: StatusBlackWon, User: "ada", AIDepth: 6}
+	store.RecordGame(record)
+	record.Status = StatusWhiteWon
+	store.RecordGame(record)
+	record.AIDepth = 4
+	store.RecordGame(record)
+	record.Status = StatusRunning
+	store.RecordGame(record)
+
+	profile, _ := store.Get("ada")
+	if profile.Stats.Games != 3 || profile.Stats.Wins != 1 || profile.Stats.Losses != 2 {
+		t.Fatalf("unexpected totals: %+v", profile.Stats.UserResults)
+	}
+	if len(profile.Stats.VsAI) != 2 || profile.Stats.VsAI[0].Depth != 4 || profile.Stats.VsAI[1].Depth != 6 {
+		t.Fatalf("expected per-depth results sorted by depth, got %+v", profile.Stats.VsAI)
+	}
+	if got := profile.Stats.VsAI[1].WinRate; got != 0.5 {
+		t.Fatalf("expected 50%% win rate at depth 6, got %v", got)
+	}
+}
+
+func TestUserStoreAveragesReviewAccuracy(t *testing.T) {
+	store := NewUserStore()
+	if _, err := store.Create("ada", UserPreferences{}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	store.RecordReview(Review{Status: "done", User: "ada", UserColor: 2, White: ReviewSideSummary{Moves: 10, Accuracy: 90}})
+	store.RecordReview(Review{Status: "done", User: "ada", UserColor: 2, White: ReviewSideSummary{Moves: 10, Accuracy: 70}})
+	store.RecordReview(Review{Status: "running", User: "ada", UserColor: 2, White: ReviewSideSummary{Moves: 10}})
+	profile, _ := store.Get("ada")
+	if profile.Stats.Reviews != 2 {
+		t.Fatalf("expected 2 reviews, got %d", profile.Stats.Reviews)
+	}
+	if got := profile.Stats.AverageAccuracy; got < 79.99 || got > 80.01 {
+		t.Fatalf("expected 80%% accuracy, got %v", got)
+	}
+}
+
+func TestUserProfilesPersistenceRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UsersPath = filepath.Join(t.TempDir(), "users.gob")
+	store := NewUserStore()
+	if _, err := store.Create("ada", UserPreferences{BoardSize: 13, Color: 2}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	persistUserProfiles(cfg, store)
+
+	restored := NewUserStore()
+	loadUserProfiles(cfg, restored)
+	profile, ok := restored.Get("ada")
+	if !ok || profile.Preferences.BoardSize != 13 || profile.Preferences.Color != 2 {
+		t.Fatalf("expected profile to round-trip, got %+v (found %v)", profile, ok)
+	}
+}
+
+func TestGameControllerSetUserNeedsAIOpponent(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	if err := controller.SetUser("ada"); err == nil {
+		t.Fatalf("expected human vs human game to be rejected")
+	}
+	settings.WhiteType = PlayerAI
+	controller.StartGame(settings)
+	if err := controller.SetUser("ada"); err != nil {
+		t.Fatalf("expected human vs AI game to accept a user: %v", err)
+	}
+	if controller.User() != "ada" {
+		t.Fatalf("expected user to be linked, got %q", controller.User())
+	}
+	controller.StartGame(settings)
+	if controller.User() != "" {
+		t.Fatalf("expected a new game to start without a user")
+	}
+}
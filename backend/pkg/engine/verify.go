@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// VerifyDivergence is one way a rebuilt game differs from the live one.
+// Source is what was rebuilt: "history" (the recorded moves), "events" (the
+// event log) or "live" (the live state checked against itself). Ply is the
+// move after which it shows, 0 for the starting position.
+type VerifyDivergence struct {
+	Source  string `json:"source"`
+	Ply     int    `json:"ply"`
+	Field   string `json:"field"`
+	Rebuilt string `json:"rebuilt"`
+	Live    string `json:"live"`
+}
+
+// VerifyReport compares the live game with the game rebuilt from its start
+// position and moves, and from the event log.
+type VerifyReport struct {
+	GameID        uint64             `json:"game_id"`
+	Moves         int                `json:"moves"`
+	Status        string             `json:"status"`
+	LiveHash      string             `json:"live_hash"`
+	HistoryHash   string             `json:"history_hash"`
+	EventsChecked bool               `json:"events_checked"`
+	EventsHash    string             `json:"events_hash,omitempty"`
+	EventsSkipped string             `json:"events_skipped,omitempty"`
+	OK            bool               `json:"ok"`
+	Divergences   []VerifyDivergence `json:"divergences"`
+}
+
+func (r *VerifyReport) diverge(source string, ply int, field string, rebuilt, live interface{}) {
+	r.Divergences = append(r.Divergences, VerifyDivergence{
+		Source:  source,
+		Ply:     ply,
+		Field:   field,
+		Rebuilt: fmt.Sprint(rebuilt),
+		Live:    fmt.Sprint(live),
+	})
+}
+
+// Verify rebuilds the current game from its start position and move
+// history, and from the event log, with the search's own move code rather
+// than the game's, and reports every difference with the live state: the
+// board, capture counts, side to move and hash after each move. It also
+// checks the live hash against one computed from scratch and the stones on
+// the board against the moves played and captured.
+func (gc *GameController) Verify() VerifyReport {
+	gc.mu.Lock()
+	live := gc.game.State()
+	history := gc.game.History().All()
+	settings := gc.game.settings
+	start, hasStart := gc.game.StartPosition()
+	gameID := gc.gameID
+	gc.mu.Unlock()
+
+	report := VerifyReport{
+		GameID:      gameID,
+		Moves:       len(history),
+		Status:      StatusToString(live.Status),
+		LiveHash:    eventHash(live),
+		Divergences: []VerifyDivergence{},
+	}
+	var startPosition *StartPosition
+	if hasStart {
+		startPosition = &start
+	}
+	initial, err := verifyStart(settings, startPosition)
+	if err != nil {
+		report.diverge("history", 0, "start_position", err, "valid")
+		return report
+	}
+	verifyLive(&report, initial, live, history)
+
+	moves := make([]Move, len(history))
+	for i, entry := range history {
+		moves[i] = entry.Move
+	}
+	over := live.Status != StatusRunning && live.Status != StatusNotStarted
+	rebuilt := verifyReplay(&report, "history", settings, initial, moves, over, func(ply int, state GameState, captured int) {
+		if entry := history[ply-1]; entry.CapturedCount != captured {
+			report.diverge("history", ply, "captured", captured, entry.CapturedCount)
+		}
+	})
+	report.HistoryHash = eventHash(rebuilt)
+	compareStates(&report, "history", len(moves), rebuilt, live)
+
+	verifyEvents(&report, gameID, settings, moves, live)
+	report.OK = len(report.Divergences) == 0
+	return report
+}
+
+func verifyStart(settings GameSettings, start *StartPosition) (GameState, error) {
+	if start == nil {
+		return DefaultGameState(settings), nil
+	}
+	return start.State(settings)
+}
+
+// verifyReplay plays moves on initial and calls check after each one; a
+// move that does not apply ends the replay. When over, the last move ended
+// the game and, as in the game, its player stays to move.
+func verifyReplay(report *VerifyReport, source string, settings GameSettings, initial GameState, moves []Move, over bool, check func(ply int, state GameState, captured int)) GameState {
+	rules := NewRules(settings)
+	state := initial.Clone()
+	state.Status = StatusRunning
+	for i, move := range moves {
+		ply := i + 1
+		if !move.IsValid(state.Board.Size()) || state.Board.At(move.X, move.Y) != CellEmpty {
+			report.diverge(source, ply, "move", "unplayable", fmt.Sprintf("%d,%d", move.X, move.Y))
+			return state
+		}
+		before := state.CapturedBlack + state.CapturedWhite
+		mover := state.ToMove
+		applyMove(&state, rules, move, mover)
+		if over && ply == len(moves) {
+			state.ToMove = mover
+			state.recomputeHashes()
+		}
+		if check != nil {
+			check(ply, state, state.CapturedBlack+state.CapturedWhite-before)
+		}
+	}
+	return state
+}
+
+// compareStates reports where rebuilt and live differ after ply.
+func compareStates(report *VerifyReport, source string, ply int, rebuilt, live GameState) {
+	size := rebuilt.Board.Size()
+	if size != live.Board.Size() {
+		report.diverge(source, ply, "board_size", size, live.Board.Size())
+		return
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if rebuilt.Board.At(x, y) != live.Board.At(x, y) {
+				report.diverge(source, ply, fmt.Sprintf("board[%d,%d]", x, y), CellToInt(rebuilt.Board.At(x, y)), CellToInt(live.Board.At(x, y)))
+			}
+		}
+	}
+	if rebuilt.CapturedBlack != live.CapturedBlack {
+		report.diverge(source, ply, "captured_black", rebuilt.CapturedBlack, live.CapturedBlack)
+	}
+	if rebuilt.CapturedWhite != live.CapturedWhite {
+		report.diverge(source, ply, "captured_white", rebuilt.CapturedWhite, live.CapturedWhite)
+	}
+	if rebuilt.ToMove != live.ToMove {
+		report.diverge(source, ply, "next_player", PlayerToInt(rebuilt.ToMove), PlayerToInt(live.ToMove))
+	}
+	if rebuilt.Hash != live.Hash {
+		report.diverge(source, ply, "hash", eventHash(rebuilt), eventHash(live))
+	}
+}
+
+// verifyLive checks the live state against itself: its incremental hash
+// against one computed from the board, and the stones of each colour
+// against the moves it played and the stones it lost to captures.
+func verifyLive(report *VerifyReport, initial, live GameState, history []HistoryEntry) {
+	hash, _ := computeSymmetricHashes(live)
+	if hash != live.Hash {
+		report.diverge("live", len(history), "hash", fmt.Sprintf("%016x", hash), eventHash(live))
+	}
+	played := map[PlayerColor]int{}
+	for _, entry := range history {
+		played[entry.Player]++
+	}
+	lost := map[PlayerColor]int{
+		PlayerBlack: live.CapturedWhite - initial.CapturedWhite,
+		PlayerWhite: live.CapturedBlack - initial.CapturedBlack,
+	}
+	for _, color := range []PlayerColor{PlayerBlack, PlayerWhite} {
+		want := countColorStones(initial.Board, color) + played[color] - lost[color]
+		if got := countColorStones(live.Board, color); got != want {
+			report.diverge("live", len(history), "stones_"+strconv.Itoa(PlayerToInt(color)), want, got)
+		}
+	}
+}
+
+func countColorStones(board Board, color PlayerColor) int {
+	cell := CellFromPlayer(color)
+	count := 0
+	size := board.Size()
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if board.At(x, y) == cell {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// verifyEvents rebuilds the game from the event log: the latest start or
+// reset of gameID and the moves logged after it. The logged moves must be
+// the recorded ones, and the hash logged with a move must be the rebuilt
+// one.
+func verifyEvents(report *VerifyReport, gameID uint64, settings GameSettings, moves []Move, live GameState) {
+	// Game ids start over with the process, so only this run's events count.
+	events, _ := Events.Query(EventQuery{Since: Events.RestoredSeq(), GameID: gameID})
+	first := -1
+	for i, event := range events {
+		if event.Kind == EventGameStarted || event.Kind == EventGameReset {
+			first = i
+		}
+	}
+	if first < 0 {
+		report.EventsSkipped = "the game's start is not in the event log"
+		return
+	}
+	opening := events[first]
+	if opening.Settings != nil {
+		settings = opening.Settings.Settings()
+	}
+	initial, err := verifyStart(settings, opening.Position)
+	if err != nil {
+		report.diverge("events", 0, "start_position", err, "valid")
+		return
+	}
+	if opening.Hash != "" && opening.Hash != eventHash(initial) {
+		report.diverge("events", 0, "hash", eventHash(initial), opening.Hash)
+	}
+	var logged []Event
+	for _, event := range events[first+1:] {
+		if event.Kind == EventMoveApplied && event.Move != nil {
+			logged = append(logged, event)
+		}
+	}
+	report.EventsChecked = true
+	loggedMoves := make([]Move, len(logged))
+	for i, event := range logged {
+		loggedMoves[i] = Move{X: event.Move.X, Y: event.Move.Y}
+		if event.Ply != i+1 {
+			report.diverge("events", i+1, "ply", i+1, event.Ply)
+		}
+		if i < len(moves) && (moves[i].X != event.Move.X || moves[i].Y != event.Move.Y) {
+			report.diverge("events", i+1, "move", fmt.Sprintf("%d,%d", event.Move.X, event.Move.Y), fmt.Sprintf("%d,%d", moves[i].X, moves[i].Y))
+		}
+	}
+	if len(logged) != len(moves) {
+		report.diverge("events", len(logged), "moves", len(logged), len(moves))
+	}
+	over := live.Status != StatusRunning && live.Status != StatusNotStarted
+	rebuilt := verifyReplay(report, "events", settings, initial, loggedMoves, over, func(ply int, state GameState, captured int) {
+		event := logged[ply-1]
+		if event.Captured != captured {
+			report.diverge("events", ply, "captured", captured, event.Captured)
+		}
+		if event.Hash != "" && event.Hash != eventHash(state) {
+			report.diverge("events", ply, "hash", eventHash(state), event.Hash)
+		}
+	})
+	report.EventsHash = eventHash(rebuilt)
+	compareStates(report, "events", len(loggedMoves), rebuilt, live)
+}
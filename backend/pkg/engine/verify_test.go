@@ -0,0 +1,71 @@
+package engine
+
+import "testing"
+
+func verifyTestController(t *testing.T) *GameController {
+	t.Helper()
+	settings := DefaultGameSettings()
+	settings.BlackType = PlayerHuman
+	settings.WhiteType = PlayerHuman
+	controller := NewGameController(settings)
+	controller.StartGame(settings)
+	// Black captures the white pair at (1,0) and (2,0).
+	for _, move := range []Move{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 9, Y: 9}, {X: 2, Y: 0}, {X: 3, Y: 0}} {
+		if ok, reason := controller.ApplyHumanMove(move); !ok {
+			t.Fatalf("move %v rejected: %s", move, reason)
+		}
+	}
+	if state := controller.State(); state.CapturedBlack != 2 {
+		t.Fatalf("black captured %d stones, want 2", state.CapturedBlack)
+	}
+	return controller
+}
+
+func TestVerifyAcceptsAConsistentGame(t *testing.T) {
+	controller := verifyTestController(t)
+	report := controller.Verify()
+	if !report.OK || len(report.Divergences) != 0 {
+		t.Fatalf("report = %+v, want no divergence", report)
+	}
+	if !report.EventsChecked || report.EventsHash != report.LiveHash || report.HistoryHash != report.LiveHash {
+		t.Fatalf("report = %+v, want both rebuilds to hash like the live game", report)
+	}
+}
+
+func TestVerifyAcceptsAFinishedGame(t *testing.T) {
+	controller := verifyTestController(t)
+	for _, move := range []Move{{X: 9, Y: 10}, {X: 15, Y: 15}, {X: 9, Y: 11}, {X: 15, Y: 13}, {X: 9, Y: 12}, {X: 15, Y: 11}, {X: 9, Y: 13}, {X: 17, Y: 17}, {X: 9, Y: 14}} {
+		if ok, reason := controller.ApplyHumanMove(move); !ok {
+			t.Fatalf("move %v rejected: %s", move, reason)
+		}
+	}
+	if state := controller.State(); state.Status != StatusWhiteWon {
+		t.Fatalf("status = %v, want white to have won", state.Status)
+	}
+	if report := controller.Verify(); !report.OK {
+		t.Fatalf("report = %+v, want no divergence", report)
+	}
+}
+
+func TestVerifyFlagsMissedCaptureBookkeeping(t *testing.T) {
+	controller := verifyTestController(t)
+	controller.mu.Lock()
+	// The pair is back on the board as if the capture had not been applied.
+	controller.game.state.Board.Set(1, 0, CellWhite)
+	controller.game.state.Board.Set(2, 0, CellWhite)
+	controller.mu.Unlock()
+
+	report := controller.Verify()
+	if report.OK {
+		t.Fatalf("report = %+v, want divergences", report)
+	}
+	found := map[string]bool{}
+	for _, divergence := range report.Divergences {
+		found[divergence.Source+":"+divergence.Field] = true
+	}
+	for _, want := range []string{"live:hash", "live:stones_2", "history:board[1,0]", "events:board[2,0]"} {
+		if !found[want] {
+			t.Fatalf("divergences %+v miss %s", report.Divergences, want)
+		}
+	}
+}
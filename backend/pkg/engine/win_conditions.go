@@ -0,0 +1,113 @@
+package engine
+
+import "fmt"
+
+// WinConditions describes how close each side is to winning: the capture
+// countdown, any alignment that only stands if the side to move fails to
+// break it by capture, and a capture win left for the side to move to play.
+type WinConditions struct {
+	CapturedBlack          int    `json:"captured_black"`
+	CapturedWhite          int    `json:"captured_white"`
+	CaptureWinStones       int    `json:"capture_win_stones"`
+	BlackCapturesToWin     int    `json:"black_captures_to_win"`
+	WhiteCapturesToWin     int    `json:"white_captures_to_win"`
+	PendingAlignmentPlayer int    `json:"pending_alignment_player"`
+	PendingAlignment       []Move `json:"pending_alignment"`
+	BreakingCaptures       []Move `json:"breaking_captures"`
+	CaptureWinPlayer       int    `json:"capture_win_player"`
+	CaptureWinMove         *Move  `json:"capture_win_move,omitempty"`
+	CaptureWinCaptures     []Move `json:"capture_win_captures"`
+	Summary                string `json:"summary"`
+}
+
+func WinConditionsFromState(state GameState, settings GameSettings) WinConditions {
+	conditions := WinConditions{
+		CapturedBlack:      state.CapturedBlack,
+		CapturedWhite:      state.CapturedWhite,
+		CaptureWinStones:   settings.CaptureWinStones,
+		BlackCapturesToWin: max(0, settings.CaptureWinStones-state.CapturedBlack),
+		WhiteCapturesToWin: max(0, settings.CaptureWinStones-state.CapturedWhite),
+		PendingAlignment:   []Move{},
+		BreakingCaptures:   []Move{},
+		CaptureWinCaptures: []Move{},
+	}
+	if state.Status != StatusRunning {
+		return conditions
+	}
+	mover := state.ToMove
+	other := otherPlayer(mover)
+	if state.HasCaptureWin {
+		move := state.CaptureWinMove
+		conditions.CaptureWinPlayer = PlayerToInt(mover)
+		conditions.CaptureWinMove = &move
+		conditions.CaptureWinCaptures = append(conditions.CaptureWinCaptures, state.CaptureWinCaptures...)
+		conditions.Summary = fmt.Sprintf("%s wins by capture at %d,%d", CellFromPlayer(mover), move.X, move.Y)
+		return conditions
+	}
+	if state.MustCapture && len(state.PendingAlignment) > 0 {
+		conditions.PendingAlignmentPlayer = PlayerToInt(other)
+		conditions.PendingAlignment = append(conditions.PendingAlignment, state.PendingAlignment...)
+		conditions.BreakingCaptures = append(conditions.BreakingCaptures, state.ForcedCaptureMoves...)
+		conditions.Summary = fmt.Sprintf("%s wins next turn unless %s captures across the line", CellFromPlayer(other), CellFromPlayer(mover))
+		return conditions
+	}
+	for _, player := range []PlayerColor{mover, other} {
+		remaining := conditions.BlackCapturesToWin
+		if player == PlayerWhite {
+			remaining = conditions.WhiteCapturesToWin
+		}
+		if remaining > 0 && remaining <= 2 {
+			conditions.Summary = fmt.Sprintf("%s wins by capturing one more pair", CellFromPlayer(player))
+			break
+		}
+	}
+	return conditions
+}
+
+// PendingWin is a five that has not won yet because the side to move can
+// still break it by capturing a pair out of it. The side to move must play
+// one of Breaks; the five wins after its next move otherwise, so
+// TurnsRemaining is always 1.
+type PendingWin struct {
+	Player         int            `json:"player"`
+	Line           []Move         `json:"line"`
+	BreakingPlayer int            `json:"breaking_player"`
+	Breaks         []PendingBreak `json:"breaks"`
+	TurnsRemaining int            `json:"turns_remaining"`
+	Explanation    string         `json:"explanation"`
+}
+
+// PendingBreak is one capture that breaks a pending five and the stones it
+// takes.
+type PendingBreak struct {
+	Move     Move   `json:"move"`
+	Captures []Move `json:"captures"`
+}
+
+// PendingWinFromState describes the five waiting on a breaking capture, nil
+// when there is none.
+func PendingWinFromState(state GameState, settings GameSettings) *PendingWin {
+	if state.Status != StatusRunning || !state.MustCapture || len(state.PendingAlignment) == 0 {
+		return nil
+	}
+	rules := NewRules(settings)
+	breaker := state.ToMove
+	owner := otherPlayer(breaker)
+	pending := &PendingWin{
+		Player:         PlayerToInt(owner),
+		Line:           append([]Move{}, state.PendingAlignment...),
+		BreakingPlayer: PlayerToInt(breaker),
+		Breaks:         make([]PendingBreak, 0, len(state.ForcedCaptureMoves)),
+		TurnsRemaining: 1,
+	}
+	for _, move := range state.ForcedCaptureMoves {
+		captures := rules.FindCaptures(state.Board, move, CellFromPlayer(breaker))
+		pending.Breaks = append(pending.Breaks, PendingBreak{Move: move, Captures: append([]Move{}, captures...)})
+	}
+	choice := fmt.Sprintf("one of %d captures", len(pending.Breaks))
+	if len(pending.Breaks) == 1 {
+		choice = fmt.Sprintf("the capture at %d,%d", pending.Breaks[0].Move.X, pending.Breaks[0].Move.Y)
+	}
+	pending.Explanation = fmt.Sprintf("%s has five in a row but only wins if it survives the next move: %s must break it with %s", CellFromPlayer(owner), CellFromPlayer(breaker), choice)
+	return pending
+}
@@ -0,0 +1,79 @@
+package engine
+
+import "testing"
+
+func TestWinConditionsCaptureCountdown(t *testing.T) {
+	settings := DefaultGameSettings()
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.CapturedWhite = settings.CaptureWinStones - 2
+	state.CapturedBlack = 4
+	conditions := WinConditionsFromState(state, settings)
+	if conditions.WhiteCapturesToWin != 2 || conditions.BlackCapturesToWin != settings.CaptureWinStones-4 {
+		t.Fatalf("unexpected countdown: %+v", conditions)
+	}
+	if conditions.Summary != "White wins by capturing one more pair" {
+		t.Fatalf("unexpected summary %q", conditions.Summary)
+	}
+	if conditions.PendingAlignmentPlayer != 0 || conditions.PendingAlignment == nil {
+		t.Fatalf("expected no pending alignment and non-nil slices, got %+v", conditions)
+	}
+}
+
+func TestWinConditionsPendingBreak(t *testing.T) {
+	settings := DefaultGameSettings()
+	state := DefaultGameState(settings)
+	state.Status = StatusRunning
+	state.ToMove = PlayerBlack
+	state.MustCapture = true
+	state.ForcedCaptureMoves = []Move{{X: 2, Y: 6}}
+	state.PendingAlignment = []Move{{X: 1, Y: 4}, {X: 2, Y: 4}, {X: 3, Y: 4}, {X: 4, Y: 4}, {X: 5, Y: 4}}
+	conditions := WinConditionsFromState(state, settings)
+	if conditions.PendingAlignmentPlayer != 2 || len(conditions.BreakingCaptures) != 1 {
+		t.Fatalf("expected White's line to be pending a Black capture, got %+v", conditions)
+	}
+	if conditions.Summary != "White wins next turn unless Black captures across the line" {
+		t.Fatalf("unexpected summary %q", conditions.Summary)
+	}
+}
+
+func TestPendingWinListsBreaksWithTheirCaptures(t *testing.T) {
+	settings := DefaultGameSettings()
+	settings.BoardSize = 9
+	settings.ForbidDoubleThreeBlack = false
+	g := NewGame(settings)
+	g.Start()
+
+	// Black completes (1,4)-(5,4); White breaks it only by playing (2,6),
+	// which takes (2,5) and (2,4).
+	for x := 1; x <= 4; x++ {
+		g.state.Board.Set(x, 4, CellBlack)
+	}
+	g.state.Board.Set(2, 5, CellBlack)
+	g.state.Board.Set(2, 3, CellWhite)
+	g.state.recomputeHashes()
+	if PendingWinFromState(g.State(), settings) != nil {
+		t.Fatalf("expected no pending win before the five")
+	}
+	if applied, reason := g.TryApplyMove(Move{X: 5, Y: 4}); !applied {
+		t.Fatalf("expected move to be applied, got reason: %s", reason)
+	}
+
+	pending := PendingWinFromState(g.State(), settings)
+	if pending == nil {
+		t.Fatalf("expected a pending win")
+	}
+	if pending.Player != 1 || pending.BreakingPlayer != 2 || pending.TurnsRemaining != 1 || len(pending.Line) != 5 {
+		t.Fatalf("unexpected pending win %+v", pending)
+	}
+	if len(pending.Breaks) != 1 || !pending.Breaks[0].Move.Equals(Move{X: 2, Y: 6}) {
+		t.Fatalf("expected the single break at (2,6), got %+v", pending.Breaks)
+	}
+	captures := pending.Breaks[0].Captures
+	if len(captures) != 2 || !containsMove(captures, Move{X: 2, Y: 5}) || !containsMove(captures, Move{X: 2, Y: 4}) {
+		t.Fatalf("expected the break to take (2,5) and (2,4), got %+v", captures)
+	}
+	if pending.Explanation != "Black has five in a row but only wins if it survives the next move: White must break it with the capture at 2,6" {
+		t.Fatalf("unexpected explanation %q", pending.Explanation)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package engine
 
 import "sync"
 
@@ -183,11 +183,15 @@ func xorCaptureHashes(sym *[8]uint64, prevBlack, newBlack, prevWhite, newWhite i
 }
 
 func canonicalSymHash(sym [8]uint64) uint64 {
-	min := sym[0]
+	return sym[canonicalSymIndex(sym)]
+}
+
+func canonicalSymIndex(sym [8]uint64) int {
+	best := 0
 	for i := 1; i < len(sym); i++ {
-		if sym[i] < min {
-			min = sym[i]
+		if sym[i] < sym[best] {
+			best = i
 		}
 	}
-	return min
+	return best
 }
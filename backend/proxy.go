@@ -0,0 +1,227 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight answer.
+const corsMaxAge = 600
+
+// privateProxyRanges is what TRUSTED_PROXIES=private trusts: loopback and
+// the private networks containers and load balancers usually sit in.
+var privateProxyRanges = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// proxyConfig is how the backend sits behind reverse proxies and answers
+// other origins. It is read from the environment at startup.
+type proxyConfig struct {
+	// basePath is the prefix the backend is served under, "" for the root.
+	basePath string
+	// corsOrigins may call the API from a browser; "*" allows any.
+	corsOrigins     []string
+	corsCredentials bool
+	// trustedProxies are the peers whose X-Forwarded-* and X-Real-IP
+	// headers are believed.
+	trustedProxies []netip.Prefix
+	// wsOrigins may open websockets: nil allows any, "same-origin" the
+	// host the request was sent to.
+	wsOrigins []string
+}
+
+func proxyConfigFromEnv() proxyConfig {
+	config := proxyConfig{
+		basePath:    normalizeBasePath(os.Getenv("BASE_PATH")),
+		corsOrigins: splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		wsOrigins:   splitList(os.Getenv("WS_ALLOWED_ORIGINS")),
+	}
+	config.corsCredentials, _ = strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS"))
+	for _, entry := range splitList(os.Getenv("TRUSTED_PROXIES")) {
+		if entry == "private" {
+			for _, cidr := range privateProxyRanges {
+				config.trustedProxies = append(config.trustedProxies, netip.MustParsePrefix(cidr))
+			}
+			continue
+		}
+		prefix, err := parseProxyPrefix(entry)
+		if err != nil {
+			log.Printf("[backend] TRUSTED_PROXIES: ignoring %q: %v", entry, err)
+			continue
+		}
+		config.trustedProxies = append(config.trustedProxies, prefix)
+	}
+	return config
+}
+
+// normalizeBasePath turns "gomoku/" and "/gomoku" into "/gomoku", and "/"
+// into "".
+func normalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+func splitList(raw string) []string {
+	var list []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseProxyPrefix reads a CIDR, or a single address as its own prefix.
+func parseProxyPrefix(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+func (c proxyConfig) trusted(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// handler serves next under the base path, behind the proxy and CORS
+// middlewares.
+func (c proxyConfig) handler(next http.Handler) http.Handler {
+	handler := c.forwarded(c.cors(c.checkWSOrigin(next)))
+	if c.basePath == "" {
+		return handler
+	}
+	return http.StripPrefix(c.basePath, handler)
+}
+
+// forwarded takes the client's address, the requested host and scheme from
+// the headers of a trusted proxy. The client is the last address of
+// X-Forwarded-For that is not a trusted proxy itself, else X-Real-IP.
+func (c proxyConfig) forwarded(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			peer = r.RemoteAddr
+		}
+		if !c.trusted(peer) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := ""
+		hops := splitList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+		for i := len(hops) - 1; i >= 0; i-- {
+			client = hops[i]
+			if !c.trusted(hops[i]) {
+				break
+			}
+		}
+		if client == "" {
+			client = strings.TrimSpace(r.Header.Get("X-Real-IP"))
+		}
+		if _, err := netip.ParseAddr(client); err == nil {
+			r.RemoteAddr = client
+		}
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			r.Host = strings.TrimSpace(strings.Split(host, ",")[0])
+		}
+		if proto := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			r.URL.Scheme = proto
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(candidate, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// cors answers preflight requests and lets the allowed origins read the
+// responses. Without CORS_ALLOWED_ORIGINS only the same origin can.
+func (c proxyConfig) cors(next http.Handler) http.Handler {
+	if len(c.corsOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || !originAllowed(origin, c.corsOrigins) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c.corsCredentials || !originAllowed("*", c.corsOrigins) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
+		if c.corsCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// checkWSOrigin refuses websocket upgrades from origins not in
+// WS_ALLOWED_ORIGINS. Requests without an Origin, which browsers always
+// send, are let through.
+func (c proxyConfig) checkWSOrigin(next http.Handler) http.Handler {
+	if len(c.wsOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || c.wsOriginAllowed(origin, r.Host) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeError(w, http.StatusForbidden, errCodeForbidden, "origin not allowed")
+	})
+}
+
+func (c proxyConfig) wsOriginAllowed(origin, host string) bool {
+	if originAllowed(origin, c.wsOrigins) {
+		return true
+	}
+	for _, candidate := range c.wsOrigins {
+		if candidate != "same-origin" {
+			continue
+		}
+		if i := strings.Index(origin, "://"); i >= 0 && strings.EqualFold(origin[i+3:], host) {
+			return true
+		}
+	}
+	return false
+}
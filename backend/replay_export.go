@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gomoku-backend/pkg/engine"
+)
+
+const replayExportCacheSize = 16
+
+// replayExports renders replay GIFs once per game and options. Finished games
+// never change, so results are cached; concurrent requests for the same
+// export wait for the first render instead of starting their own.
+type replayExports struct {
+	mu    sync.Mutex
+	jobs  map[string]*replayJob
+	order []string
+}
+
+type replayJob struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func newReplayExports() *replayExports {
+	return &replayExports{jobs: make(map[string]*replayJob)}
+}
+
+func (e *replayExports) GIF(record engine.GameRecord, cellSize int, delay time.Duration, numbers bool) ([]byte, error) {
+	key := fmt.Sprintf("%d/%d/%d/%t", record.ID, cellSize, delay, numbers)
+	e.mu.Lock()
+	job, ok := e.jobs[key]
+	if !ok {
+		job = &replayJob{done: make(chan struct{})}
+		e.jobs[key] = job
+		e.order = append(e.order, key)
+		if len(e.order) > replayExportCacheSize {
+			delete(e.jobs, e.order[0])
+			e.order = e.order[1:]
+		}
+	}
+	e.mu.Unlock()
+	if ok {
+		<-job.done
+		return job.data, job.err
+	}
+	job.data, job.err = engine.ReplayGIF(record, cellSize, delay, numbers)
+	close(job.done)
+	if job.err != nil {
+		e.mu.Lock()
+		if e.jobs[key] == job {
+			delete(e.jobs, key)
+		}
+		e.mu.Unlock()
+	}
+	return job.data, job.err
+}
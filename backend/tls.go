@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// serverTLSConfig is the backend's TLS, served over HTTPS and HTTP/2 with
+// TLS_CERT_FILE and TLS_KEY_FILE. With TLS_CLIENT_CA_FILE, clients may show
+// a certificate signed by that CA, and the admin API requires one. It is nil
+// when no certificate is set.
+func serverTLSConfig() (*tls.Config, error) {
+	certFile, keyFile, caFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"), os.Getenv("TLS_CLIENT_CA_FILE")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no PEM certificate", caFile)
+		}
+		config.ClientCAs = pool
+		// Players connect without certificates; only the admin API checks
+		// that one was verified.
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
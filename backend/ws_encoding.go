@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Websocket clients pick the wire format through the subprotocol header:
+// "gomoku.msgpack" gets binary MessagePack frames carrying the same
+// {type, payload} objects as the default JSON text frames.
+const (
+	wsProtocolJSON    = "gomoku.json"
+	wsProtocolMsgpack = "gomoku.msgpack"
+)
+
+// wsCompressMinBytes is the size below which frames go out uncompressed:
+// deflate saves nothing on small messages.
+const wsCompressMinBytes = 256
+
+// wsCompression is the permessage-deflate setting of one websocket endpoint.
+// It is negotiated only with clients that offer the extension.
+type wsCompression struct {
+	enabled bool
+	level   int
+}
+
+// wsCompressionFromEnv reads the setting of one endpoint from name, falling
+// back to WS_COMPRESSION: "off", "on" (the fastest level, the default) or a
+// level from 1 to 9.
+func wsCompressionFromEnv(name string) wsCompression {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		name, raw = "WS_COMPRESSION", strings.TrimSpace(os.Getenv("WS_COMPRESSION"))
+	}
+	switch strings.ToLower(raw) {
+	case "", "on", "true":
+		return wsCompression{enabled: true, level: flate.BestSpeed}
+	case "off", "false", "0":
+		return wsCompression{}
+	}
+	level, err := strconv.Atoi(raw)
+	if err != nil || level < flate.BestSpeed || level > flate.BestCompression {
+		log.Printf("[backend] invalid %s %q, websocket compression left off", name, raw)
+		return wsCompression{}
+	}
+	return wsCompression{enabled: true, level: level}
+}
+
+// apply sets the compression level of a connection upgraded with it.
+func (c wsCompression) apply(conn *websocket.Conn) {
+	if c.enabled {
+		conn.SetCompressionLevel(c.level)
+	}
+}
+
+// newWSUpgrader accepts any origin: WS_ALLOWED_ORIGINS is enforced by
+// proxyConfig.checkWSOrigin before the upgrade.
+func newWSUpgrader(compression wsCompression) websocket.Upgrader {
+	return websocket.Upgrader{
+		CheckOrigin:       func(r *http.Request) bool { return true },
+		Subprotocols:      []string{wsProtocolMsgpack, wsProtocolJSON},
+		EnableCompression: compression.enabled,
+	}
+}
+
+func wsBinary(conn *websocket.Conn) bool {
+	return conn.Subprotocol() == wsProtocolMsgpack
+}
+
+// wsFrame is one outgoing message, encoded lazily and at most once per wire
+// format however many clients receive it.
+type wsFrame struct {
+	msg     wsMessage
+	json    []byte
+	msgpack []byte
+}
+
+func newWSFrame(msg wsMessage) *wsFrame {
+	return &wsFrame{msg: msg}
+}
+
+func (f *wsFrame) encode(binary bool) []byte {
+	if f.json == nil {
+		data, err := json.Marshal(f.msg)
+		if err != nil {
+			return nil
+		}
+		f.json = data
+	}
+	if !binary {
+		return f.json
+	}
+	if f.msgpack == nil {
+		data, err := msgpackFromJSON(f.json)
+		if err != nil {
+			return nil
+		}
+		f.msgpack = data
+	}
+	return f.msgpack
+}
+
+// msgpackFromJSON re-encodes a JSON document as MessagePack. Integers stay
+// integers; map keys are sorted so output is deterministic.
+func msgpackFromJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) / 2)
+	writeMsgpack(&buf, value)
+	return buf.Bytes(), nil
+}
+
+func writeMsgpack(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeMsgpackInt(buf, i)
+			return
+		}
+		f, _ := v.Float64()
+		writeMsgpackFloat(buf, f)
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []any:
+		writeMsgpackHeader(buf, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			writeMsgpack(buf, item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeMsgpackHeader(buf, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			writeMsgpack(buf, key)
+			writeMsgpack(buf, v[key])
+		}
+	}
+}
+
+// writeMsgpackHeader writes a str/array/map length: the fix form up to
+// fixMax, then the 8 (if the type has one), 16 and 32 bit forms.
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fixBase byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fixBase | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(code32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func writeMsgpackFloat(buf *bytes.Buffer, f float64) {
+	if float64(float32(f)) == f {
+		buf.WriteByte(0xca)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))))
+		return
+	}
+	buf.WriteByte(0xcb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
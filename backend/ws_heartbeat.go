@@ -1,34 +1,69 @@
 package main
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-const wsIdlePingInterval = 30 * time.Second
+const (
+	wsIdlePingInterval = 30 * time.Second
+	// wsCloseTimeout bounds writing the close frame to an evicted client.
+	wsCloseTimeout = time.Second
+	// wsWriteTimeout bounds every frame and ping, so a peer that stopped
+	// reading cannot hold the writer forever.
+	wsWriteTimeout = 10 * time.Second
+)
 
-func writeWSWithHeartbeat(conn *websocket.Conn, send <-chan []byte) error {
+// writeWSWithHeartbeat writes the frames queued for a client until its queue
+// closes. An evicted client is told why and its connection is closed, even
+// while a write to it is blocked, which also ends the connection's reader.
+func writeWSWithHeartbeat(conn *websocket.Conn, queue *wsQueue, binary bool) error {
+	var evictOnce sync.Once
+	closeEvicted := func() {
+		evictOnce.Do(func() {
+			closing := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "client too slow")
+			_ = conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(wsCloseTimeout))
+			_ = conn.Close()
+		})
+	}
+	queue.onEvict(closeEvicted)
 	ticker := time.NewTicker(wsIdlePingInterval)
 	defer ticker.Stop()
 	lastWrite := time.Now()
-	pingPayload := mustMarshal(wsMessage{Type: "ping"})
+	messageType := websocket.TextMessage
+	if binary {
+		messageType = websocket.BinaryMessage
+	}
+	pingPayload := newWSFrame(wsMessage{Type: "ping"}).encode(binary)
 
 	for {
 		select {
-		case msg, ok := <-send:
-			if !ok {
+		case <-queue.ready:
+			frames, open, evicted := queue.take()
+			if evicted {
+				closeEvicted()
 				return nil
 			}
-			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-				return err
+			for _, msg := range frames {
+				conn.EnableWriteCompression(len(msg) >= wsCompressMinBytes)
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteMessage(messageType, msg); err != nil {
+					return err
+				}
+			}
+			if !open {
+				return nil
 			}
 			lastWrite = time.Now()
 		case <-ticker.C:
 			if time.Since(lastWrite) < wsIdlePingInterval {
 				continue
 			}
-			if err := conn.WriteMessage(websocket.TextMessage, pingPayload); err != nil {
+			conn.EnableWriteCompression(false)
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(messageType, pingPayload); err != nil {
 				return err
 			}
 			lastWrite = time.Now()
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"gomoku-backend/pkg/engine"
+)
+
+// wsQueueLimit is how many frames a websocket client may have waiting to be
+// written before it counts as slow.
+const wsQueueLimit = 64
+
+type queuedFrame struct {
+	data      []byte
+	droppable bool
+}
+
+// wsQueue holds the frames waiting to be written to one websocket client,
+// in the order they were pushed. When it is full, a droppable frame (a ghost
+// preview, a score update) makes room by dropping the oldest droppable frame
+// queued, or is dropped itself; a frame that must be delivered (status,
+// history...) finds the client too slow, and the queue closes so the client
+// is evicted.
+type wsQueue struct {
+	mu      sync.Mutex
+	frames  []queuedFrame
+	limit   int
+	ready   chan struct{}
+	closed  bool
+	evicted bool
+	// evict, when set, is called in its own goroutine once the client is
+	// evicted, to close the connection the writer may be blocked on.
+	evict func()
+}
+
+func newWSQueue(limit int) *wsQueue {
+	return &wsQueue{limit: limit, ready: make(chan struct{}, 1)}
+}
+
+// push queues data. It returns how many frames were dropped to fit it, and
+// true when it evicted the client instead.
+func (q *wsQueue) push(data []byte, droppable bool) (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, false
+	}
+	if len(q.frames) >= q.limit {
+		oldest := -1
+		for i, frame := range q.frames {
+			if frame.droppable {
+				oldest = i
+				break
+			}
+		}
+		switch {
+		case oldest >= 0:
+			q.frames = append(q.frames[:oldest], q.frames[oldest+1:]...)
+			q.frames = append(q.frames, queuedFrame{data: data, droppable: droppable})
+			q.signal()
+			return 1, false
+		case droppable:
+			return 1, false
+		}
+		q.closeLocked(true)
+		return 0, true
+	}
+	q.frames = append(q.frames, queuedFrame{data: data, droppable: droppable})
+	q.signal()
+	return 0, false
+}
+
+func (q *wsQueue) full() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.frames) >= q.limit
+}
+
+// dropDroppable drops every droppable frame queued and returns how many.
+func (q *wsQueue) dropDroppable() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.frames[:0]
+	for _, frame := range q.frames {
+		if !frame.droppable {
+			kept = append(kept, frame)
+		}
+	}
+	dropped := len(q.frames) - len(kept)
+	q.frames = kept
+	return dropped
+}
+
+// take returns the queued frames, and false once the queue is closed along
+// with whether it was closed by evicting the client.
+func (q *wsQueue) take() ([][]byte, bool, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	frames := make([][]byte, len(q.frames))
+	for i, frame := range q.frames {
+		frames[i] = frame.data
+	}
+	q.frames = nil
+	return frames, !q.closed, q.evicted
+}
+
+// onEvict makes fn the eviction callback, calling it at once when the client
+// was already evicted.
+func (q *wsQueue) onEvict(fn func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.evict = fn
+	if q.evicted {
+		go fn()
+	}
+}
+
+func (q *wsQueue) close() {
+	q.mu.Lock()
+	q.closeLocked(false)
+	q.mu.Unlock()
+}
+
+func (q *wsQueue) closeLocked(evicted bool) {
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.evicted = evicted
+	if evicted {
+		// The frames queued are the ones the client was too slow for.
+		q.frames = nil
+		if q.evict != nil {
+			go q.evict()
+		}
+	}
+	q.signal()
+}
+
+func (q *wsQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// wsClient is the outgoing side of a websocket client of any hub.
+type wsClient struct {
+	queue  *wsQueue
+	binary bool
+}
+
+func newWSClient(binary bool) wsClient {
+	return wsClient{queue: newWSQueue(wsQueueLimit), binary: binary}
+}
+
+// sendFrame queues frame in the client's wire format, with the results of
+// wsQueue.push.
+func (c *wsClient) sendFrame(frame *wsFrame, droppable bool) (int, bool) {
+	data := frame.encode(c.binary)
+	if data == nil {
+		return 0, false
+	}
+	return c.queue.push(data, droppable)
+}
+
+// countSend records the outcome of a hub's sendFrame on its component, and
+// logs an eviction.
+func countSend(component *engine.Component, hub string, dropped int, evicted bool) {
+	component.Add("dropped_frames", int64(dropped))
+	if evicted {
+		component.Add("evicted_clients", 1)
+		log.Printf("[backend] evicted a slow %s client", hub)
+	}
+}
@@ -1,4 +1,5 @@
 import { useEffect, useMemo, useRef, useState } from 'react'
+import { openWebSocket, parseWsMessage } from './msgpack'
 
 const defaultStatus = {
   settings: { mode: 'ai_vs_human', human_player: 1 },
@@ -31,6 +32,19 @@ const defaultStatus = {
   turn_started_at_ms: 0
 }
 
+const threatLabels = {
+  open_four: 'open four',
+  four: 'four',
+  open_three: 'open three',
+  capture: 'capture threat'
+}
+
+function describeThreats(list) {
+  return list
+    .map((threat) => `${threat.player === 1 ? 'Blue' : 'Red'} ${threatLabels[threat.kind] || threat.kind}`)
+    .join(', ')
+}
+
 function wsUrl(path) {
   const protocol = window.location.protocol === 'https:' ? 'wss' : 'ws'
   return `${protocol}://${window.location.host}${path}`
@@ -62,6 +76,12 @@ export default function App() {
   const [analiticsNowMs, setAnaliticsNowMs] = useState(Date.now())
   const [turnNowMs, setTurnNowMs] = useState(Date.now())
   const [moveSuggestion, setMoveSuggestion] = useState(null)
+  const [explorer, setExplorer] = useState(null)
+  const [threats, setThreats] = useState(null)
+  const [commentary, setCommentary] = useState(null)
+  const [turnNotice, setTurnNotice] = useState(null)
+  const [gameReport, setGameReport] = useState(null)
+  const [scoreUpdate, setScoreUpdate] = useState(null)
   const wsRef = useRef(null)
   const ghostWsRef = useRef(null)
   const analiticsWsRef = useRef(null)
@@ -173,15 +193,16 @@ export default function App() {
   }, [status.board_size])
 
   useEffect(() => {
-    const ws = new WebSocket(wsUrl('/ws/'))
+    const ws = openWebSocket(wsUrl('/ws/'))
     wsRef.current = ws
 
     ws.onmessage = (event) => {
-      const msg = JSON.parse(event.data)
+      const msg = parseWsMessage(event.data)
       if (msg.type === 'status') {
         setStatus(msg.payload)
       }
       if (msg.type === 'history') {
+        setTurnNotice(null)
         setStatus((prev) => ({
           ...prev,
           history: [...prev.history, ...(msg.payload.history || [])],
@@ -195,7 +216,33 @@ export default function App() {
           })()
         }))
       }
+      if (msg.type === 'threats') {
+        setThreats(msg.payload)
+      }
+      if (msg.type === 'commentary') {
+        setCommentary(msg.payload)
+      }
+      if (msg.type === 'score_update') {
+        setScoreUpdate(msg.payload)
+      }
+      if (msg.type === 'your_turn' || msg.type === 'idle_warning') {
+        const notice = msg.payload || {}
+        setTurnNotice(notice)
+        // Only bother the player when the tab is in the background.
+        if (document.hidden && typeof Notification !== 'undefined' && Notification.permission === 'granted') {
+          const player = notice.player === 1 ? 'Blue' : 'Red'
+          new Notification(
+            notice.type === 'idle_warning'
+              ? `${player} has not moved for ${Math.round((notice.idle_ms || 0) / 1000)} s`
+              : `${player} to move`
+          )
+        }
+      }
       if (msg.type === 'reset') {
+        setThreats(null)
+        setCommentary(null)
+        setTurnNotice(null)
+        setScoreUpdate(null)
         setStatus((prev) => ({
           ...prev,
           next_player: msg.payload.next_player,
@@ -208,7 +255,9 @@ export default function App() {
           winning_line: msg.payload.winning_line || [],
           winning_capture_pair: msg.payload.winning_capture_pair || [],
           capture_win_stones: msg.payload.capture_win_stones || prev.capture_win_stones || 10,
-          turn_started_at_ms: msg.payload.turn_started_at_ms || prev.turn_started_at_ms || 0
+          turn_started_at_ms: msg.payload.turn_started_at_ms || prev.turn_started_at_ms || 0,
+          start_position: msg.payload.start_position || null,
+          forced_line: null
         }))
       }
       if (msg.type === 'settings') {
@@ -238,10 +287,10 @@ export default function App() {
       }
       return
     }
-    const ghostWs = new WebSocket(wsUrl('/ws/ghost'))
+    const ghostWs = openWebSocket(wsUrl('/ws/ghost'))
     ghostWsRef.current = ghostWs
     ghostWs.onmessage = (event) => {
-      const msg = JSON.parse(event.data)
+      const msg = parseWsMessage(event.data)
       if (msg.type !== 'ghost') {
         return
       }
@@ -260,7 +309,11 @@ export default function App() {
         depth: payload.depth || 0,
         score: payload.score || 0,
         next_player: payload.next_player || 0,
-        history_len: payload.history_len || 0
+        history_len: payload.history_len || 0,
+        alternatives: payload.alternatives || [],
+        threat: payload.threat || null,
+        stability: payload.stability || null,
+        forced_line: payload.forced_line || null
       })
     }
     ghostWs.onerror = () => {}
@@ -292,10 +345,10 @@ export default function App() {
   }, [])
 
   useEffect(() => {
-    const ws = new WebSocket(wsUrl('/ws/analitics'))
+    const ws = openWebSocket(wsUrl('/ws/analitics'))
     analiticsWsRef.current = ws
     ws.onmessage = (event) => {
-      const msg = JSON.parse(event.data)
+      const msg = parseWsMessage(event.data)
       if (msg.type !== 'analitics') {
         return
       }
@@ -307,7 +360,7 @@ export default function App() {
         setAnaliticsTotalInQueue(total)
       }
 
-      if (eventType === 'board_added' || eventType === 'board_hit' || eventType === 'board_left') {
+      if (eventType === 'board_added' || eventType === 'board_hit' || eventType === 'board_left' || eventType === 'board_compacted') {
         refreshAnaliticsQueue().catch(() => {})
         return
       }
@@ -322,6 +375,8 @@ export default function App() {
                 current_depth: entry.current_depth,
                 target_depth: entry.target_depth,
                 hits: entry.hits,
+                frequency: entry.frequency,
+                variants: entry.variants,
                 analyzing: entry.analyzing,
                 analysis_started_at_ms: entry.analysis_started_at_ms
               }
@@ -346,15 +401,66 @@ export default function App() {
       ? selectedHistoryIndex
       : latestHistoryIndex
 
+  const startPosition = status.start_position || null
+
   const liveSnapshot = useMemo(
-    () => buildBoardSnapshot(history, status.board_size || 19, latestHistoryIndex),
-    [history, status.board_size, latestHistoryIndex]
+    () => buildBoardSnapshot(history, status.board_size || 19, latestHistoryIndex, startPosition),
+    [history, status.board_size, latestHistoryIndex, startPosition]
   )
 
   const displayedSnapshot = useMemo(
-    () => buildBoardSnapshot(history, status.board_size || 19, effectiveHistoryIndex),
-    [history, status.board_size, effectiveHistoryIndex]
+    () => buildBoardSnapshot(history, status.board_size || 19, effectiveHistoryIndex, startPosition),
+    [history, status.board_size, effectiveHistoryIndex, startPosition]
+  )
+
+  const explorerMoves = useMemo(
+    () => (startPosition ? null : history.slice(0, effectiveHistoryIndex + 1).map((entry) => ({ x: entry.x, y: entry.y }))),
+    [history, effectiveHistoryIndex, startPosition]
   )
+  const explorerKey = explorerMoves ? explorerMoves.map((move) => `${move.x},${move.y}`).join(';') : null
+
+  useEffect(() => {
+    if (activeRightTab !== 'explorer' || explorerKey === null) {
+      setExplorer(null)
+      return
+    }
+    let cancelled = false
+    fetch('/api/explorer', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ moves: explorerMoves })
+    })
+      .then((res) => res.json())
+      .then((data) => {
+        if (!cancelled) {
+          setExplorer(data)
+        }
+      })
+      .catch(() => {})
+    return () => {
+      cancelled = true
+    }
+  }, [activeRightTab, explorerKey])
+
+  const gameFinished = ['black_won', 'white_won', 'draw'].includes(status.status)
+  useEffect(() => {
+    if (!gameFinished || !(status.game_id > 0)) {
+      setGameReport(null)
+      return
+    }
+    let cancelled = false
+    fetch(`/api/games/${status.game_id}/report`)
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => {
+        if (!cancelled) {
+          setGameReport(data)
+        }
+      })
+      .catch(() => {})
+    return () => {
+      cancelled = true
+    }
+  }, [gameFinished, status.game_id])
 
   useEffect(() => {
     if (selectedHistoryIndex >= history.length) {
@@ -424,6 +530,138 @@ export default function App() {
     latestHistoryIndex,
     lastHistoryEntry
   ])
+  const forcedCaptureSet = useMemo(() => {
+    const set = new Set()
+    if (!status.must_capture || effectiveHistoryIndex !== latestHistoryIndex) {
+      return set
+    }
+    for (const cell of status.forced_capture_moves || []) {
+      set.add(`${cell.x},${cell.y}`)
+    }
+    return set
+  }, [status.must_capture, status.forced_capture_moves, effectiveHistoryIndex, latestHistoryIndex])
+  const pendingAlignmentSet = useMemo(() => {
+    const set = new Set()
+    if (!status.must_capture || effectiveHistoryIndex !== latestHistoryIndex) {
+      return set
+    }
+    for (const cell of status.pending_alignment || []) {
+      set.add(`${cell.x},${cell.y}`)
+    }
+    return set
+  }, [status.must_capture, status.pending_alignment, effectiveHistoryIndex, latestHistoryIndex])
+  const showThreats =
+    !!status.config.teaching_mode &&
+    !!threats &&
+    threats.ply === history.length &&
+    effectiveHistoryIndex === latestHistoryIndex
+  const showCommentary =
+    !!status.config.commentary &&
+    !!commentary &&
+    commentary.ply === history.length &&
+    effectiveHistoryIndex === latestHistoryIndex
+  const threatCellMap = useMemo(() => {
+    const map = new Map()
+    if (!showThreats) {
+      return map
+    }
+    // Threats come most severe first, so the first one marking a cell wins.
+    for (const threat of [...(threats.black || []), ...(threats.white || [])]) {
+      for (const cell of threat.cells || []) {
+        const key = `${cell.x},${cell.y}`
+        if (!map.has(key)) {
+          map.set(key, threat.player)
+        }
+      }
+    }
+    return map
+  }, [showThreats, threats])
+  const showSuggestion =
+    status.config.ghost_mode &&
+    effectiveHistoryIndex === latestHistoryIndex &&
+    !!moveSuggestion &&
+    moveSuggestion.next_player === status.next_player &&
+    moveSuggestion.history_len === history.length
+  const suggestionOverlay = useMemo(() => {
+    const alternatives = new Map()
+    const threatCells = new Set()
+    if (!showSuggestion) {
+      return { alternatives, threatCells }
+    }
+    for (const [index, move] of (moveSuggestion.alternatives || []).entries()) {
+      alternatives.set(`${move.x},${move.y}`, index + 2)
+    }
+    const threat = moveSuggestion.threat
+    if (threat) {
+      for (const cell of [...(threat.stones || []), ...(threat.cells || [])]) {
+        threatCells.add(`${cell.x},${cell.y}`)
+      }
+    }
+    return { alternatives, threatCells }
+  }, [showSuggestion, moveSuggestion])
+  const suggestionArrow = useMemo(() => {
+    const threat = showSuggestion ? moveSuggestion.threat : null
+    if (!threat) {
+      return null
+    }
+    // Point from the far end of the threat to the suggested move.
+    let from = null
+    let farthest = -1
+    for (const cell of [...(threat.stones || []), ...(threat.cells || [])]) {
+      const distance = Math.max(Math.abs(cell.x - moveSuggestion.x), Math.abs(cell.y - moveSuggestion.y))
+      if (distance > farthest) {
+        farthest = distance
+        from = cell
+      }
+    }
+    if (!from || farthest <= 0) {
+      return null
+    }
+    const center = (index) => index * (cellSize + cellGap) + cellSize / 2
+    return {
+      player: threat.player,
+      x1: center(from.x),
+      y1: center(from.y),
+      x2: center(moveSuggestion.x),
+      y2: center(moveSuggestion.y)
+    }
+  }, [showSuggestion, moveSuggestion, cellSize, cellGap])
+  const forcedLine = useMemo(() => {
+    if (effectiveHistoryIndex !== latestHistoryIndex) {
+      return null
+    }
+    if (status.forced_line && (status.forced_line.moves || []).length > 0) {
+      return status.forced_line
+    }
+    if (showSuggestion && moveSuggestion.forced_line && (moveSuggestion.forced_line.moves || []).length > 0) {
+      return moveSuggestion.forced_line
+    }
+    return null
+  }, [status.forced_line, showSuggestion, moveSuggestion, effectiveHistoryIndex, latestHistoryIndex])
+  const forcedLineSteps = useMemo(() => {
+    const steps = new Map()
+    for (const move of forcedLine ? forcedLine.moves : []) {
+      steps.set(`${move.x},${move.y}`, move)
+    }
+    return steps
+  }, [forcedLine])
+  const forcedLineArrows = useMemo(() => {
+    if (!forcedLine) {
+      return []
+    }
+    // One arrow from each move to the next, drawn in turn.
+    const center = (index) => index * (cellSize + cellGap) + cellSize / 2
+    const moves = forcedLine.moves
+    return moves.slice(1).map((move, i) => ({
+      key: `${move.index}`,
+      player: move.player,
+      step: i,
+      x1: center(moves[i].x),
+      y1: center(moves[i].y),
+      x2: center(move.x),
+      y2: center(move.y)
+    }))
+  }, [forcedLine, cellSize, cellGap])
   const boardRows = useMemo(() => {
     if (!displayedSnapshot.board || displayedSnapshot.board.length === 0) {
       return null
@@ -431,18 +669,14 @@ export default function App() {
     const isHumanTurn =
       status.settings.mode === 'human_vs_human' ||
       (status.settings.mode === 'ai_vs_human' && status.next_player === humanPlayer)
-    const showSuggestion =
-      status.config.ghost_mode &&
-      effectiveHistoryIndex === latestHistoryIndex &&
-      !!moveSuggestion &&
-      moveSuggestion.next_player === status.next_player &&
-      moveSuggestion.history_len === history.length
     return displayedSnapshot.board.map((row, rowIndex) => (
         <div className="board-row" key={`row-${rowIndex}`}>
         {row.map((cell, colIndex) => (
           (() => {
             const isWinningLineCell = winningLineSet.has(`${colIndex},${rowIndex}`)
             const isWinningCaptureCell = captureWinPairSet.has(`${colIndex},${rowIndex}`)
+            const isForcedCaptureCell = forcedCaptureSet.has(`${colIndex},${rowIndex}`)
+            const isPendingAlignmentCell = pendingAlignmentSet.has(`${colIndex},${rowIndex}`)
             const isFinalCaptureWin =
               status.win_reason === 'capture' &&
               status.winner > 0 &&
@@ -459,11 +693,16 @@ export default function App() {
               lastHistoryEntry.y === rowIndex &&
               renderedCell !== 0
             const moveNumber = displayedSnapshot.moveNumbers[rowIndex][colIndex]
+            const threatPlayer = renderedCell === 0 ? threatCellMap.get(`${colIndex},${rowIndex}`) : undefined
             const isSuggestionCell =
               showSuggestion &&
               renderedCell === 0 &&
               colIndex === moveSuggestion.x &&
               rowIndex === moveSuggestion.y
+            const alternativeRank =
+              renderedCell === 0 ? suggestionOverlay.alternatives.get(`${colIndex},${rowIndex}`) : undefined
+            const isSuggestionThreatCell = suggestionOverlay.threatCells.has(`${colIndex},${rowIndex}`)
+            const forcedStep = renderedCell === 0 ? forcedLineSteps.get(`${colIndex},${rowIndex}`) : undefined
             return (
           <div
             className={`board-cell player-${renderedCell} ${
@@ -476,15 +715,25 @@ export default function App() {
                 : ''
             } ${isCaptureWinningMoveCell ? 'winning-capture-target winning-capture-fade' : ''} ${
               isCaptureWinningMoveCell ? 'winning-capture-move' : ''
-            } ${isSuggestionCell ? `ghost-suggestion ghost-player-${moveSuggestion.player}` : ''} ${
+            } ${isForcedCaptureCell && renderedCell === 0 ? 'forced-capture' : ''} ${
+              isPendingAlignmentCell && renderedCell !== 0 ? 'pending-alignment' : ''
+            } ${threatPlayer ? `threat-cell threat-player-${threatPlayer}` : ''} ${
+              isSuggestionCell ? `ghost-suggestion ghost-player-${moveSuggestion.player}` : ''
+            } ${
               isSuggestionCell ? 'ghost-suggestion-animated' : ''
+            } ${alternativeRank ? 'ghost-alternative' : ''} ${isSuggestionThreatCell ? 'ghost-threat' : ''} ${
+              forcedStep ? `forced-line-step forced-line-player-${forcedStep.player}` : ''
             }`}
             key={`cell-${rowIndex}-${colIndex}`}
             role="button"
             tabIndex={0}
             onClick={() => handleCellClick(colIndex, rowIndex)}
           >
-            {isSuggestionCell ? '' : renderedCell === 0 || moveNumber <= 0 ? '' : moveNumber}
+            {forcedStep
+              ? forcedStep.index
+              : isSuggestionCell
+                ? ''
+                : alternativeRank || (renderedCell === 0 || moveNumber <= 0 ? '' : moveNumber)}
           </div>
             )
           })()
@@ -500,12 +749,16 @@ export default function App() {
     humanPlayer,
     winningLineSet,
     captureWinPairSet,
+    forcedCaptureSet,
+    pendingAlignmentSet,
     effectiveHistoryIndex,
     latestHistoryIndex,
     lastHistoryEntry,
     moveSuggestion,
-    history.length,
-    status.config.ghost_mode
+    showSuggestion,
+    suggestionOverlay,
+    threatCellMap,
+    forcedLineSteps
   ])
 
   const canStart = status.status !== 'running'
@@ -529,8 +782,8 @@ export default function App() {
   }
 
   const captured = useMemo(() => {
-    let blue = 0
-    let red = 0
+    let blue = startPosition ? startPosition.captured_black || 0 : 0
+    let red = startPosition ? startPosition.captured_white || 0 : 0
     for (const entry of history) {
       if (entry.captured_count) {
         if (entry.player === 1) {
@@ -541,13 +794,21 @@ export default function App() {
       }
     }
     return { blue, red }
-  }, [history])
+  }, [history, startPosition])
 
   const turnInfo = useMemo(() => {
     if (status.winner === 0) {
       if (status.status === 'draw') {
         return { prefix: 'Game ended in a draw', player: '', suffix: '', playerNumber: 0 }
       }
+      if (status.must_capture) {
+        return {
+          prefix: 'Player ',
+          player: nextPlayerLabel,
+          suffix: ' must capture to break the alignment',
+          playerNumber: status.next_player
+        }
+      }
       return { prefix: 'Player ', player: nextPlayerLabel, suffix: "'s turn to play", playerNumber: status.next_player }
     }
     if (status.win_reason === 'alignment') {
@@ -562,7 +823,36 @@ export default function App() {
       }
     }
     return { prefix: 'Player ', player: winnerLabel, suffix: ' won', playerNumber: status.winner }
-  }, [status.winner, status.status, status.win_reason, status.capture_win_stones, status.next_player, nextPlayerLabel, winnerLabel])
+  }, [
+    status.winner,
+    status.status,
+    status.win_reason,
+    status.capture_win_stones,
+    status.next_player,
+    status.must_capture,
+    nextPlayerLabel,
+    winnerLabel
+  ])
+
+  const winConditions = status.win_conditions || null
+  const tensionLabel = useMemo(() => {
+    if (!winConditions || status.status !== 'running') {
+      return ''
+    }
+    const label = (player) => (player === 1 ? 'Blue' : 'Red')
+    if (winConditions.pending_alignment_player > 0) {
+      const owner = winConditions.pending_alignment_player
+      return `${label(owner)} wins next turn unless ${label(owner === 1 ? 2 : 1)} captures across the line`
+    }
+    for (const player of [status.next_player, status.next_player === 1 ? 2 : 1]) {
+      const remaining =
+        player === 1 ? winConditions.black_captures_to_win : winConditions.white_captures_to_win
+      if (remaining > 0 && remaining <= 2) {
+        return `${label(player)} wins by capturing one more pair`
+      }
+    }
+    return ''
+  }, [winConditions, status.status, status.next_player])
 
   const statusLabel = useMemo(() => {
     if (status.status === 'black_won') return 'blue_won'
@@ -580,6 +870,10 @@ export default function App() {
       return
     }
     setStartBusy(true)
+    // Asked on the click, as browsers only allow it from a user gesture.
+    if (status.settings.mode !== 'ai_vs_ai' && typeof Notification !== 'undefined' && Notification.permission === 'default') {
+      Notification.requestPermission()
+    }
     try {
       const res = await fetch('/api/start', {
         method: 'POST',
@@ -745,7 +1039,51 @@ export default function App() {
               </div>
             <div>
               <strong>Captured (Blue/Red):</strong> {captured.blue} / {captured.red}
+              {winConditions && (
+                <span className="capture-countdown">
+                  {' '}
+                  ({winConditions.black_captures_to_win} / {winConditions.white_captures_to_win} to win)
+                </span>
+              )}
             </div>
+            {status.phase && status.phase.phase && (
+              <div>
+                <strong>Phase:</strong> {status.phase.phase}
+              </div>
+            )}
+            {tensionLabel && (
+              <div className="win-tension">
+                <strong>Threat:</strong> {tensionLabel}
+              </div>
+            )}
+            {status.game_id > 0 && ['black_won', 'white_won', 'draw'].includes(status.status) && (
+              <div>
+                <strong>Replay:</strong>{' '}
+                <a href={`/api/games/${status.game_id}/replay.gif`} target="_blank" rel="noreferrer">
+                  animated GIF
+                </a>
+              </div>
+            )}
+            {gameReport && gameReport.game_id === status.game_id && (
+              <div className="game-report-card">
+                <strong>Game report</strong>
+                {[gameReport.black, gameReport.white].map((side) => (
+                  <div key={side.player}>
+                    {side.player === 1 ? 'Blue' : 'Red'} ({side.type}): {side.moves} moves,{' '}
+                    {(side.time_ms / 1000).toFixed(1)}s
+                    {side.nodes > 0 && `, depth ${side.average_depth.toFixed(1)}, ${Math.round(side.nodes_per_second)} nodes/s`}
+                    {side.accuracy != null && `, accuracy ${side.accuracy.toFixed(0)}%`}
+                    {side.blunders > 0 && `, ${side.blunders} blunder${side.blunders > 1 ? 's' : ''}`}
+                  </div>
+                ))}
+                {gameReport.cache.tt_probes > 0 && (
+                  <div>
+                    Cache hits: TT {(gameReport.cache.tt_hit_rate * 100).toFixed(0)}%, eval{' '}
+                    {(gameReport.cache.eval_cache_hit_rate * 100).toFixed(0)}%
+                  </div>
+                )}
+              </div>
+            )}
             </div>
             <div className="settings-grid">
               <label>
@@ -797,6 +1135,30 @@ export default function App() {
                 />
                 Move suggestion
               </label>
+              <label className="toggle">
+                <input
+                  type="checkbox"
+                  checked={!!status.config.teaching_mode}
+                  onChange={(event) => handleSettingsChange('teaching_mode', event.target.checked)}
+                />
+                Teaching mode
+              </label>
+              <label className="toggle">
+                <input
+                  type="checkbox"
+                  checked={!!status.config.commentary}
+                  onChange={(event) => handleSettingsChange('commentary', event.target.checked)}
+                />
+                AI vs AI commentary
+              </label>
+              <label className="toggle">
+                <input
+                  type="checkbox"
+                  checked={!!status.config.ai_opponent_model}
+                  onChange={(event) => handleSettingsChange('ai_opponent_model', event.target.checked)}
+                />
+                Exploit player tendencies
+              </label>
               <label className="toggle">
                 <input
                   type="checkbox"
@@ -841,10 +1203,77 @@ export default function App() {
             }}
           >
             {boardRows}
+            {(suggestionArrow || forcedLineArrows.length > 0) && (
+              <svg className="board-overlay">
+                <defs>
+                  <marker id="suggestion-arrow-head" markerWidth="6" markerHeight="6" refX="5" refY="3" orient="auto">
+                    <path d="M0,0 L6,3 L0,6 z" />
+                  </marker>
+                </defs>
+                {suggestionArrow && (
+                  <line
+                    className={`suggestion-arrow suggestion-arrow-player-${suggestionArrow.player}`}
+                    x1={suggestionArrow.x1}
+                    y1={suggestionArrow.y1}
+                    x2={suggestionArrow.x2}
+                    y2={suggestionArrow.y2}
+                    markerEnd="url(#suggestion-arrow-head)"
+                  />
+                )}
+                {forcedLineArrows.map((arrow) => (
+                  <line
+                    key={arrow.key}
+                    className={`forced-line-arrow suggestion-arrow-player-${arrow.player}`}
+                    style={{ animationDelay: `${arrow.step * 0.4}s` }}
+                    x1={arrow.x1}
+                    y1={arrow.y1}
+                    x2={arrow.x2}
+                    y2={arrow.y2}
+                    markerEnd="url(#suggestion-arrow-head)"
+                  />
+                ))}
+              </svg>
+            )}
           </div>
           {status.config.ghost_mode && moveSuggestion && (
             <div className="turn-timer">
               Suggestion: ({moveSuggestion.x}, {moveSuggestion.y}) depth {moveSuggestion.depth}
+              {moveSuggestion.threat && ` — ${describeThreats([moveSuggestion.threat])}`}
+              {moveSuggestion.stability && moveSuggestion.stability.unstable && ` — ${moveSuggestion.stability.summary}`}
+            </div>
+          )}
+          {scoreUpdate && scoreUpdate.game_id === status.game_id && status.status === 'running' && (
+            <div className="eval-bar" title={`Depth ${scoreUpdate.depth}, ${scoreUpdate.elapsed_ms} ms`}>
+              <div className="eval-bar-blue" style={{ width: `${(scoreUpdate.win_prob * 100).toFixed(1)}%` }} />
+              <span className="eval-bar-label">
+                {scoreUpdate.mate_in > 0
+                  ? `${scoreUpdate.score > 0 ? 'Blue' : 'Red'} mates in ${scoreUpdate.mate_in}`
+                  : `Blue ${(scoreUpdate.win_prob * 100).toFixed(0)}%`}
+              </span>
+            </div>
+          )}
+          {forcedLine && (
+            <div className="turn-timer">
+              Forced {forcedLine.winner === 1 ? 'Blue' : 'Red'} win in {forcedLine.mate_in} plies
+              {!forcedLine.complete && ' (line shown in part)'}
+            </div>
+          )}
+          {showThreats && (threats.created.length > 0 || threats.blocked.length > 0) && (
+            <div className="turn-timer">
+              {threats.created.length > 0 && <div>Created: {describeThreats(threats.created)}</div>}
+              {threats.blocked.length > 0 && <div>Blocked: {describeThreats(threats.blocked)}</div>}
+            </div>
+          )}
+          {turnNotice && turnNotice.type === 'idle_warning' && status.status === 'running' && (
+            <div className="turn-timer">
+              {turnNotice.player === 1 ? 'Blue' : 'Red'} has not moved for {formatDuration(turnNotice.idle_ms)}
+            </div>
+          )}
+          {showCommentary && (
+            <div className="turn-timer">
+              {commentary.lines.map((line, index) => (
+                <div key={index}>{line.text}</div>
+              ))}
             </div>
           )}
         </section>
@@ -865,6 +1294,13 @@ export default function App() {
             >
               Analitics
             </button>
+            <button
+              type="button"
+              className={activeRightTab === 'explorer' ? 'tab-btn active' : 'tab-btn'}
+              onClick={() => setActiveRightTab('explorer')}
+            >
+              Explorer
+            </button>
           </div>
           {activeRightTab === 'history' ? (
             <div
@@ -899,9 +1335,17 @@ export default function App() {
                       <span>
                         ({entry.x}, {entry.y})
                       </span>
-                      <span className="history-time">{formatDuration(entry.elapsed_ms)}</span>
+                      <span className="history-time">
+                        {formatDuration(entry.elapsed_ms)}
+                        {entry.is_ai && entry.compute_ms != null && entry.compute_ms < entry.elapsed_ms - 1 && (
+                          <span title="Time the engine actually spent; the rest is move pacing">
+                            {' '}
+                            (computed in {formatDuration(entry.compute_ms)})
+                          </span>
+                        )}
+                      </span>
                       <span className="history-depth">Depth {entry.depth || '-'}</span>
-                      <span className="history-type">{entry.is_ai ? 'AI' : 'Human'}</span>
+                      <span className="history-type">{entry.auto_played ? 'Forced' : entry.is_ai ? 'AI' : 'Human'}</span>
                       {entry.captured_count > 0 && (
                         <span className="history-capture">+{entry.captured_count}</span>
                       )}
@@ -910,6 +1354,34 @@ export default function App() {
                 })()
               ))}
             </div>
+          ) : activeRightTab === 'explorer' ? (
+            <div className="explorer-list">
+              {explorerKey === null && <div className="history-empty">The explorer starts from the empty board.</div>}
+              {explorer && explorer.error && <div className="history-empty">{explorer.error}</div>}
+              {explorer && explorer.continuations && (
+                <>
+                  <div className="explorer-summary">
+                    Move {explorer.ply + 1}, {explorer.next_player === 1 ? 'Blue' : 'Red'} to play: {explorer.games} of{' '}
+                    {explorer.stored_games} stored games
+                  </div>
+                  {explorer.continuations.length === 0 && <div className="history-empty">No known continuation.</div>}
+                  {explorer.continuations.map((entry) => (
+                    <div className="explorer-item" key={`explorer-${entry.move.x}-${entry.move.y}`}>
+                      <span>
+                        ({entry.move.x}, {entry.move.y})
+                      </span>
+                      <span>{entry.games} games</span>
+                      <span>{Math.round((entry.frequency || 0) * 100)}%</span>
+                      <span>{entry.games > 0 ? `win ${Math.round(entry.win_rate * 100)}%` : '-'}</span>
+                      <span className="history-depth">
+                        {entry.eval != null ? `eval ${Math.round(entry.eval)} (d${entry.eval_depth})` : 'eval -'}
+                      </span>
+                      {entry.engine_best && <span className="history-type">engine</span>}
+                    </div>
+                  ))}
+                </>
+              )}
+            </div>
           ) : (
             <div className="analitics-list">
               {analiticsQueue.length === 0 && <div className="history-empty">No board in analysis queue.</div>}
@@ -919,6 +1391,8 @@ export default function App() {
                   <div className="analitics-meta">
                     <div className="analitics-id">{entry.id}</div>
                     <div>Hits: {entry.hits}</div>
+                    <div>Played: {entry.frequency || 0}</div>
+                    {entry.variants > 1 && <div>Variants: {entry.variants}</div>}
                     <div>
                       Depth: {entry.current_depth || 0}/{entry.target_depth || 0}
                     </div>
@@ -941,14 +1415,21 @@ export default function App() {
   )
 }
 
-function buildBoardSnapshot(history, size, upToIndex) {
+function startBoard(size, startPosition) {
+  if (startPosition && Array.isArray(startPosition.board) && startPosition.board.length === size) {
+    return startPosition.board.map((row) => [...row])
+  }
+  return Array.from({ length: size }, () => Array(size).fill(0))
+}
+
+function buildBoardSnapshot(history, size, upToIndex, startPosition) {
   if (upToIndex < 0) {
     return {
-      board: Array.from({ length: size }, () => Array(size).fill(0)),
+      board: startBoard(size, startPosition),
       moveNumbers: Array.from({ length: size }, () => Array(size).fill(0))
     }
   }
-  const board = Array.from({ length: size }, () => Array(size).fill(0))
+  const board = startBoard(size, startPosition)
   const moveNumbers = Array.from({ length: size }, () => Array(size).fill(0))
   const cappedIndex = Math.min(upToIndex, history.length - 1)
   for (let i = 0; i <= cappedIndex; i++) {
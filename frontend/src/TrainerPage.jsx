@@ -1,4 +1,5 @@
 import { useEffect, useMemo, useRef, useState } from 'react'
+import { openWebSocket, parseWsMessage } from './msgpack'
 
 function wsUrl(path) {
   const protocol = window.location.protocol === 'https:' ? 'wss' : 'ws'
@@ -57,10 +58,10 @@ export default function TrainerPage() {
   useEffect(() => {
     loadStatus()
 
-    const gameWs = new WebSocket(wsUrl('/ws/'))
+    const gameWs = openWebSocket(wsUrl('/ws/'))
     gameWsRef.current = gameWs
     gameWs.onmessage = (event) => {
-      const msg = JSON.parse(event.data)
+      const msg = parseWsMessage(event.data)
       if (msg.type === 'status' && msg.payload) {
         setLiveGame(msg.payload)
         return
@@ -0,0 +1,126 @@
+// Websocket messages are negotiated through the subprotocol header: the
+// backend answers with binary MessagePack frames when it accepts
+// 'gomoku.msgpack' and JSON text frames otherwise.
+export const WS_PROTOCOLS = ['gomoku.msgpack', 'gomoku.json']
+
+const textDecoder = new TextDecoder()
+
+export function openWebSocket(url) {
+  const ws = new WebSocket(url, WS_PROTOCOLS)
+  ws.binaryType = 'arraybuffer'
+  return ws
+}
+
+export function parseWsMessage(data) {
+  if (data instanceof ArrayBuffer) {
+    return decodeMsgpack(data)
+  }
+  return JSON.parse(data)
+}
+
+export function decodeMsgpack(buffer) {
+  const view = new DataView(buffer)
+  const bytes = new Uint8Array(buffer)
+  let offset = 0
+
+  const readString = (length) => {
+    const value = textDecoder.decode(bytes.subarray(offset, offset + length))
+    offset += length
+    return value
+  }
+  const readArray = (length) => {
+    const value = new Array(length)
+    for (let i = 0; i < length; i += 1) {
+      value[i] = read()
+    }
+    return value
+  }
+  const readMap = (length) => {
+    const value = {}
+    for (let i = 0; i < length; i += 1) {
+      const key = read()
+      value[key] = read()
+    }
+    return value
+  }
+  const readUint = (size) => {
+    let value
+    if (size === 1) value = view.getUint8(offset)
+    else if (size === 2) value = view.getUint16(offset)
+    else if (size === 4) value = view.getUint32(offset)
+    else value = Number(view.getBigUint64(offset))
+    offset += size
+    return value
+  }
+  const readInt = (size) => {
+    let value
+    if (size === 1) value = view.getInt8(offset)
+    else if (size === 2) value = view.getInt16(offset)
+    else if (size === 4) value = view.getInt32(offset)
+    else value = Number(view.getBigInt64(offset))
+    offset += size
+    return value
+  }
+
+  const read = () => {
+    const code = bytes[offset]
+    offset += 1
+    if (code <= 0x7f) return code
+    if (code >= 0xe0) return code - 0x100
+    if (code >= 0xa0 && code <= 0xbf) return readString(code & 0x1f)
+    if (code >= 0x90 && code <= 0x9f) return readArray(code & 0x0f)
+    if (code >= 0x80 && code <= 0x8f) return readMap(code & 0x0f)
+    switch (code) {
+      case 0xc0:
+        return null
+      case 0xc2:
+        return false
+      case 0xc3:
+        return true
+      case 0xca: {
+        const value = view.getFloat32(offset)
+        offset += 4
+        return value
+      }
+      case 0xcb: {
+        const value = view.getFloat64(offset)
+        offset += 8
+        return value
+      }
+      case 0xcc:
+        return readUint(1)
+      case 0xcd:
+        return readUint(2)
+      case 0xce:
+        return readUint(4)
+      case 0xcf:
+        return readUint(8)
+      case 0xd0:
+        return readInt(1)
+      case 0xd1:
+        return readInt(2)
+      case 0xd2:
+        return readInt(4)
+      case 0xd3:
+        return readInt(8)
+      case 0xd9:
+        return readString(readUint(1))
+      case 0xda:
+        return readString(readUint(2))
+      case 0xdb:
+        return readString(readUint(4))
+      case 0xdc:
+        return readArray(readUint(2))
+      case 0xdd:
+        return readArray(readUint(4))
+      case 0xde:
+        return readMap(readUint(2))
+      case 0xdf:
+        return readMap(readUint(4))
+      default:
+        throw new Error(`unsupported msgpack code 0x${code.toString(16)}`)
+    }
+  }
+
+  return read()
+}